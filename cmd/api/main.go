@@ -11,21 +11,31 @@ import (
 	"syscall"
 	"time"
 
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/scheduler"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
 	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
 	userUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/user"
 
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/handler"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/routes"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/auth"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database/migration"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/faultinject"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
 	timeProvider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/wiring"
 
 	"github.com/gin-gonic/gin"
 )
 
+// serverCloseTimeout bounds closing the HTTP server once the transaction service has drained
+const serverCloseTimeout = 5 * time.Second
+
 func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig()
@@ -45,25 +55,11 @@ func main() {
 
 	// Create logger
 	appLogger := logger.NewZapLogger(cfg.Environment == "production")
+	// Runs last, after the database is closed, so every shutdown message is written out
+	defer func() { _ = appLogger.Flush() }()
 
 	// Setup database configuration
-	dbConfig := &database.Config{
-		Driver:          "postgres",
-		Host:            cfg.Database.Host,
-		Port:            database.ParsePort(cfg.Database.Port),
-		Username:        cfg.Database.Username,
-		Password:        cfg.Database.Password,
-		Database:        cfg.Database.Database,
-		SSLMode:         cfg.Database.SSLMode,
-		MaxOpenConns:    cfg.Database.MaxOpenConns,
-		MaxIdleConns:    cfg.Database.MaxIdleConns,
-		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
-		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
-		QueryTimeout:    cfg.Database.QueryTimeout,
-		LogLevel:        cfg.Logger.Level,
-		RetryAttempts:   3,
-		RetryDelay:      5,
-	}
+	dbConfig := database.NewConfigFromAppConfig(cfg)
 
 	// Initialize time provider
 	tp := timeProvider.NewRealTimeProvider()
@@ -83,16 +79,31 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(dbManager.DB(), tp, appLogger)
-	userLockRepo := repository.NewUserLockRepository(dbManager.DB(), tp, appLogger)
+	userLockRepo := dbManager.CreateUserLockRepository()
 	// transactionRepo is used inside the UnitOfWork
 	_ = repository.NewTransactionRepository(dbManager.DB(), appLogger)
 
-	// Unit of work (transaction manager)
-	uow := database.NewUnitOfWork(dbManager.DB(), appLogger, tp)
+	// Unit of work (transaction manager), chosen by the configured driver
+	uow := dbManager.CreateUnitOfWork()
+
+	// Route persistence calls through an injector so /admin/faults can make them fail; never in production
+	var faultInjector *faultinject.Injector
+	if cfg.FaultInjection.Enabled {
+		faultInjector = faultinject.NewInjector(cfg.FaultInjection.Seed)
+		uow = faultinject.WrapUnitOfWork(uow, faultInjector)
+		userLockRepo = faultinject.WrapUserLockRepository(userLockRepo, faultInjector)
+		appLogger.Warn("Fault injection enabled; persistence faults can be set through /admin/faults", map[string]any{
+			"seed": cfg.FaultInjection.Seed,
+		})
+	}
 
-	// Run migrations
+	// Run migrations, or make sure they were run when the API is not allowed to migrate
 	migrationMgr := migration.NewMigrationManagerWithTimeProvider(dbManager.DB(), appLogger, tp)
-	err = migrationMgr.MigrateAll()
+	if cfg.Database.AutoMigrate {
+		err = migrationMgr.MigrateAll()
+	} else {
+		err = checkSchemaUpToDate(migrationMgr)
+	}
 	if err != nil {
 		appLogger.Error("Failed to run migrations", map[string]any{
 			"error": err.Error(),
@@ -114,6 +125,59 @@ func main() {
 		lockTimeout,
 	)
 
+	// Cap concurrent transactions so overload sheds requests instead of exhausting the DB pool
+	transactionUseCaseImpl.WithAdmissionControl(transactionUseCase.AdmissionPolicy{
+		Limit:        cfg.Transaction.ConcurrencyLevel,
+		QueueSize:    cfg.Transaction.AdmissionQueueSize,
+		QueueTimeout: time.Duration(cfg.Transaction.AdmissionQueueTimeoutMs) * time.Millisecond,
+		RetryAfter:   time.Duration(cfg.Transaction.AdmissionRetryAfterSec) * time.Second,
+	})
+
+	// Serialise work per user in-process so only cross-instance contention reaches the DB lock
+	if cfg.Transaction.QueueWorkers > 0 {
+		transactionUseCaseImpl.WithUserQueue(transactionUseCase.NewUserQueueExecutor(
+			cfg.Transaction.QueueWorkers,
+			cfg.Transaction.QueueDepthPerUser,
+			appLogger,
+		))
+	}
+
+	// Retry attempts that lost a race with a concurrent transaction
+	transactionUseCaseImpl.GetManager().WithRetryPolicy(transactionUseCase.RetryPolicy{
+		MaxRetries:   cfg.Transaction.MaxRetries,
+		BaseBackoff:  time.Duration(cfg.Transaction.RetryBaseBackoffMs) * time.Millisecond,
+		MaxBackoff:   time.Duration(cfg.Transaction.RetryMaxBackoffMs) * time.Millisecond,
+		JitterFactor: cfg.Transaction.RetryJitter,
+	})
+
+	// Let duplicate submissions of a transaction share one execution and replay recent results from memory
+	transactionUseCaseImpl.WithCoalescing(cfg.Transaction.CompletedCacheSize)
+
+	// Skip the user lock and apply each transaction with a single conditional update
+	if cfg.Transaction.ProcessingMode == transactionUseCase.ProcessingModeFast {
+		var atomicRepo persistence.AtomicTransactionRepository = repository.NewAtomicTransactionRepository(dbManager.DB(), tp, appLogger)
+		if faultInjector != nil {
+			atomicRepo = faultinject.WrapAtomicTransactionRepository(atomicRepo, faultInjector)
+		}
+		transactionUseCaseImpl.WithFastPath(atomicRepo)
+	}
+
+	// Keep user lock leases alive while slow transactions are still running
+	transactionUseCaseImpl.GetManager().WithHeartbeatInterval(
+		time.Duration(cfg.Transaction.HeartbeatIntervalMs) * time.Millisecond,
+	)
+
+	// Hold large adjustments and withdrawals for approval, reject amounts and balances outside the
+	// configured limits and replay rebuilds from the configured initial balance
+	policies, err := wiring.NewPolicies(cfg)
+	if err != nil {
+		appLogger.Error("Invalid transaction policy configuration", map[string]any{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	policies.Apply(transactionUseCaseImpl)
+
 	// Create default users
 	err = migration.CreateDefaultUsers(context.Background(), userUseCaseImpl)
 	if err != nil {
@@ -122,9 +186,34 @@ func main() {
 		})
 	}
 
+	// Run periodic maintenance; with several instances each job slot runs on only one of them
+	var jobScheduler *scheduler.Scheduler
+	if cfg.Scheduler.Enabled {
+		jobScheduler, err = newJobScheduler(cfg, dbManager, userLockRepo, transactionUseCaseImpl, tp, appLogger)
+		if err != nil {
+			appLogger.Error("Failed to set up job scheduler", map[string]any{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		jobScheduler.Start()
+	}
+
 	// Initialize API handlers
 	userHandler := handler.NewUserHandler(userUseCaseImpl, appLogger)
 	transactionHandler := handler.NewTransactionHandler(transactionUseCaseImpl, userUseCaseImpl, appLogger)
+	metricsHandler := handler.NewMetricsHandler(transactionUseCaseImpl, appLogger)
+	approvalHandler := handler.NewApprovalHandler(transactionUseCaseImpl, appLogger)
+	rebuildHandler := handler.NewRebuildHandler(transactionUseCaseImpl, appLogger)
+	var faultHandler *handler.FaultHandler
+	if faultInjector != nil {
+		faultHandler = handler.NewFaultHandler(faultInjector, appLogger)
+	}
+	statementHandler := handler.NewStatementHandler(
+		statement.NewExporter(userRepo, repository.NewTransactionRepository(dbManager.DB(), appLogger), tp, appLogger),
+		cfg.Server.WriteTimeout,
+		appLogger,
+	)
 
 	// Initialize Gin router
 	router := gin.New()
@@ -133,7 +222,21 @@ func main() {
 	routes.SetupMiddlewares(router, appLogger)
 
 	// Setup routes
-	routes.SetupRoutes(router, transactionHandler, userHandler)
+	routes.SetupRoutes(router, transactionHandler, userHandler, metricsHandler, statementHandler)
+
+	// Operator endpoints stay off unless operators are configured
+	operators, err := auth.NewOperators(cfg.Admin.Operators)
+	if err != nil {
+		appLogger.Error("Invalid admin operators configuration", map[string]any{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if operators.Enabled() {
+		routes.SetupAdminRoutes(router, operators, approvalHandler, rebuildHandler, faultHandler, appLogger)
+	} else {
+		appLogger.Warn("Admin endpoints disabled; set admin.operators or BP_ADMIN_OPERATORS to enable them", nil)
+	}
 
 	// Create HTTP server with configurable timeout values
 	server := &http.Server{
@@ -171,14 +274,21 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Shutdown TransactionManager cleanly
-	if txManager := transactionUseCaseImpl.GetManager(); txManager != nil {
-		appLogger.Info("Shutting down transaction manager...", nil)
-		txManager.Shutdown()
+	// Stop maintenance jobs first so none starts against a closing database
+	if jobScheduler != nil {
+		appLogger.Info("Shutting down job scheduler...", nil)
+		jobScheduler.Stop()
 	}
 
-	// Shutdown the server
-	if err := server.Shutdown(ctx); err != nil {
+	// Reject new transactions with 503 and let in-flight ones finish; whatever is still running
+	// at the deadline is rolled back and its user lock released before the database is closed
+	appLogger.Info("Draining transaction service...", nil)
+	transactionUseCaseImpl.Drain(ctx)
+
+	// Transaction requests have all returned by now, so the server only needs to close its connections
+	serverCtx, serverCancel := context.WithTimeout(context.Background(), serverCloseTimeout)
+	defer serverCancel()
+	if err := server.Shutdown(serverCtx); err != nil {
 		appLogger.Error("Server forced to shutdown", map[string]any{
 			"error": err.Error(),
 		})
@@ -187,6 +297,53 @@ func main() {
 	appLogger.Info("Server exited gracefully", nil)
 }
 
+// newJobScheduler creates the scheduler for background maintenance jobs and registers them
+func newJobScheduler(
+	cfg *config.Config,
+	dbManager *database.Manager,
+	userLockRepo persistence.UserLockRepository,
+	approvals scheduler.ApprovalExpirer,
+	tp coreport.TimeProvider,
+	appLogger coreport.Logger,
+) (*scheduler.Scheduler, error) {
+	lockCleanupSchedule, err := scheduler.ParseSchedule(cfg.Scheduler.LockCleanupSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler.lockCleanupSchedule: %w", err)
+	}
+	approvalExpirySchedule, err := scheduler.ParseSchedule(cfg.Scheduler.ApprovalExpirySchedule)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler.approvalExpirySchedule: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	jobScheduler := scheduler.NewScheduler(
+		dbManager.CreateJobLockRepository(),
+		repository.NewJobRunRepository(dbManager.DB(), appLogger),
+		tp,
+		appLogger,
+	).WithInstance(fmt.Sprintf("%s-%d", hostname, os.Getpid()))
+
+	if err := jobScheduler.Register(scheduler.NewLockCleanupJob(userLockRepo, lockCleanupSchedule)); err != nil {
+		return nil, err
+	}
+	if err := jobScheduler.Register(scheduler.NewApprovalExpiryJob(approvals, approvalExpirySchedule)); err != nil {
+		return nil, err
+	}
+	return jobScheduler, nil
+}
+
+// checkSchemaUpToDate fails if the database has pending or modified migrations
+func checkSchemaUpToDate(migrationMgr *migration.MigrationManager) error {
+	pending, err := migrationMgr.Plan(context.Background(), migration.DirectionUp, 0)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema has %d pending migrations and database.autoMigrate is off; run `migrate up` first", len(pending))
+	}
+	return nil
+}
+
 // validateConfig ensures all required configuration values are present
 func validateConfig(cfg *config.Config) error {
 	var missingConfigs []string
@@ -209,66 +366,72 @@ func validateConfig(cfg *config.Config) error {
 	}
 
 	// Validate database configuration
-	if cfg.Database.Host == "" {
-		// In production, check if environment variable exists
-		if cfg.Environment == config.Production && os.Getenv("BP_DB_HOST") == "" {
-			missingConfigs = append(missingConfigs, "database.host (or BP_DB_HOST environment variable)")
-		} else if cfg.Environment != config.Production {
-			missingConfigs = append(missingConfigs, "database.host")
+	switch cfg.Database.Driver {
+	case database.DriverSQLite:
+		// SQLite only needs a file path; network settings are ignored
+		if cfg.Database.Database == "" {
+			missingConfigs = append(missingConfigs, "database.database (sqlite file path)")
 		}
+	case database.DriverPostgres:
+		missingConfigs = append(missingConfigs, missingPostgresConfigs(cfg)...)
+	default:
+		return fmt.Errorf("invalid database driver: %s, must be one of: %s or %s",
+			cfg.Database.Driver, database.DriverPostgres, database.DriverSQLite)
 	}
 
-	if cfg.Database.Port == "" {
-		// In production, check if environment variable exists
-		if cfg.Environment == config.Production && os.Getenv("BP_DB_PORT") == "" {
-			missingConfigs = append(missingConfigs, "database.port (or BP_DB_PORT environment variable)")
-		} else if cfg.Environment != config.Production {
-			missingConfigs = append(missingConfigs, "database.port")
-		}
+	if cfg.Database.QueryTimeout == 0 {
+		missingConfigs = append(missingConfigs, "database.queryTimeout")
 	}
 
-	if cfg.Database.Username == "" {
-		// In production, check if environment variable exists
-		if cfg.Environment == config.Production && os.Getenv("BP_DB_USERNAME") == "" {
-			missingConfigs = append(missingConfigs, "database.username (or BP_DB_USERNAME environment variable)")
-		} else if cfg.Environment != config.Production {
-			missingConfigs = append(missingConfigs, "database.username")
+	switch cfg.Database.LockStrategy {
+	case "", database.LockStrategyTable:
+	case database.LockStrategyAdvisory:
+		if cfg.Database.Driver != database.DriverPostgres {
+			return fmt.Errorf("lock strategy %s requires the %s driver", database.LockStrategyAdvisory, database.DriverPostgres)
 		}
+	default:
+		return fmt.Errorf("invalid lock strategy: %s, must be one of: %s or %s",
+			cfg.Database.LockStrategy, database.LockStrategyTable, database.LockStrategyAdvisory)
 	}
 
-	if cfg.Database.Password == "" {
-		// In production, check if environment variable exists
-		if cfg.Environment == config.Production && os.Getenv("BP_DB_PASSWORD") == "" {
-			missingConfigs = append(missingConfigs, "database.password (or BP_DB_PASSWORD environment variable)")
-		} else if cfg.Environment != config.Production {
-			missingConfigs = append(missingConfigs, "database.password")
-		}
+	// Validate transaction configuration
+	if cfg.Transaction.ConcurrencyLevel == 0 {
+		missingConfigs = append(missingConfigs, "transaction.concurrencyLevel")
+	} else if cfg.Transaction.ConcurrencyLevel < 0 {
+		return fmt.Errorf("invalid transaction.concurrencyLevel: %d, must be positive", cfg.Transaction.ConcurrencyLevel)
 	}
 
-	if cfg.Database.Database == "" {
-		// In production, check if environment variable exists
-		if cfg.Environment == config.Production && os.Getenv("BP_DB_NAME") == "" {
-			missingConfigs = append(missingConfigs, "database.database (or BP_DB_NAME environment variable)")
-		} else if cfg.Environment != config.Production {
-			missingConfigs = append(missingConfigs, "database.database")
+	if cfg.Transaction.AdmissionQueueSize < 0 {
+		return fmt.Errorf("invalid transaction.admissionQueueSize: %d, must not be negative", cfg.Transaction.AdmissionQueueSize)
+	}
+
+	// An advisory lock keeps its own pooled connection next to the unit of work's; with fewer than two per
+	// transaction in flight, every holder can end up waiting for a connection to begin its work
+	if cfg.Database.LockStrategy == database.LockStrategyAdvisory && cfg.Database.MaxOpenConns > 0 {
+		if required := 2 * maxLockHolders(cfg); cfg.Database.MaxOpenConns < required {
+			return fmt.Errorf("invalid database.maxOpenConns: %d, lock strategy %s needs at least %d for %d transactions in flight",
+				cfg.Database.MaxOpenConns, database.LockStrategyAdvisory, required, maxLockHolders(cfg))
 		}
 	}
 
-	if cfg.Database.QueryTimeout == 0 {
-		missingConfigs = append(missingConfigs, "database.queryTimeout")
+	if cfg.Transaction.LockTimeoutMs == 0 {
+		missingConfigs = append(missingConfigs, "transaction.lockTimeoutMs")
 	}
 
-	// Validate transaction configuration
-	if cfg.Transaction.ConcurrencyLevel == 0 {
-		missingConfigs = append(missingConfigs, "transaction.concurrencyLevel")
+	// Zero retries is allowed; it makes every failed attempt final
+	if cfg.Transaction.MaxRetries < 0 {
+		return fmt.Errorf("invalid transaction.maxRetries: %d, must not be negative", cfg.Transaction.MaxRetries)
 	}
 
-	if cfg.Transaction.LockTimeoutMs == 0 {
-		missingConfigs = append(missingConfigs, "transaction.lockTimeoutMs")
+	if cfg.Transaction.RetryJitter < 0 || cfg.Transaction.RetryJitter > 1 {
+		return fmt.Errorf("invalid transaction.retryJitter: %g, must be between 0 and 1", cfg.Transaction.RetryJitter)
 	}
 
-	if cfg.Transaction.MaxRetries == 0 {
-		missingConfigs = append(missingConfigs, "transaction.maxRetries")
+	switch cfg.Transaction.ProcessingMode {
+	case "", transactionUseCase.ProcessingModeLocked, transactionUseCase.ProcessingModeFast:
+	default:
+		return fmt.Errorf("invalid processing mode: %s, must be one of: %s or %s",
+			cfg.Transaction.ProcessingMode, transactionUseCase.ProcessingModeLocked, transactionUseCase.ProcessingModeFast)
 	}
 
 	// Environment should be set with a valid value
@@ -281,6 +444,11 @@ func validateConfig(cfg *config.Config) error {
 			cfg.Environment, config.Development, config.Production, config.Test)
 	}
 
+	// Fault injection makes persistence calls fail on purpose, so it must never reach production
+	if cfg.FaultInjection.Enabled && cfg.Environment == config.Production {
+		return fmt.Errorf("faultInjection.enabled must be false in the %s environment", config.Production)
+	}
+
 	// Logger configuration
 	if cfg.Logger.Level == "" {
 		missingConfigs = append(missingConfigs, "logger.level")
@@ -292,7 +460,7 @@ func validateConfig(cfg *config.Config) error {
 	}
 
 	// If we're in production, do additional validation for sensitive settings
-	if cfg.Environment == config.Production {
+	if cfg.Environment == config.Production && cfg.Database.Driver == database.DriverPostgres {
 		var warnings []string
 
 		// Check database security settings
@@ -316,3 +484,65 @@ func validateConfig(cfg *config.Config) error {
 
 	return nil
 }
+
+// maxLockHolders returns how many transactions can hold a user lock at once
+// Admission control caps transactions in flight, and the per-user queue caps them at its worker count.
+func maxLockHolders(cfg *config.Config) int {
+	holders := cfg.Transaction.ConcurrencyLevel
+	if workers := cfg.Transaction.QueueWorkers; workers > 0 && workers < holders {
+		holders = workers
+	}
+	return holders
+}
+
+// missingPostgresConfigs returns the PostgreSQL connection settings that are not set
+func missingPostgresConfigs(cfg *config.Config) []string {
+	var missingConfigs []string
+
+	if cfg.Database.Host == "" {
+		// In production, check if environment variable exists
+		if cfg.Environment == config.Production && os.Getenv("BP_DB_HOST") == "" {
+			missingConfigs = append(missingConfigs, "database.host (or BP_DB_HOST environment variable)")
+		} else if cfg.Environment != config.Production {
+			missingConfigs = append(missingConfigs, "database.host")
+		}
+	}
+
+	if cfg.Database.Port == "" {
+		// In production, check if environment variable exists
+		if cfg.Environment == config.Production && os.Getenv("BP_DB_PORT") == "" {
+			missingConfigs = append(missingConfigs, "database.port (or BP_DB_PORT environment variable)")
+		} else if cfg.Environment != config.Production {
+			missingConfigs = append(missingConfigs, "database.port")
+		}
+	}
+
+	if cfg.Database.Username == "" {
+		// In production, check if environment variable exists
+		if cfg.Environment == config.Production && os.Getenv("BP_DB_USERNAME") == "" {
+			missingConfigs = append(missingConfigs, "database.username (or BP_DB_USERNAME environment variable)")
+		} else if cfg.Environment != config.Production {
+			missingConfigs = append(missingConfigs, "database.username")
+		}
+	}
+
+	if cfg.Database.Password == "" {
+		// In production, check if environment variable exists
+		if cfg.Environment == config.Production && os.Getenv("BP_DB_PASSWORD") == "" {
+			missingConfigs = append(missingConfigs, "database.password (or BP_DB_PASSWORD environment variable)")
+		} else if cfg.Environment != config.Production {
+			missingConfigs = append(missingConfigs, "database.password")
+		}
+	}
+
+	if cfg.Database.Database == "" {
+		// In production, check if environment variable exists
+		if cfg.Environment == config.Production && os.Getenv("BP_DB_NAME") == "" {
+			missingConfigs = append(missingConfigs, "database.database (or BP_DB_NAME environment variable)")
+		} else if cfg.Environment != config.Production {
+			missingConfigs = append(missingConfigs, "database.database")
+		}
+	}
+
+	return missingConfigs
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/importer"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/export"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/importfile"
+)
+
+// defaultRecentTransactions is how many transactions balance shows unless -n is given
+const defaultRecentTransactions = 10
+
+// defaultPendingApprovals is how many held transactions approvals lists unless -n is given
+const defaultPendingApprovals = 100
+
+// usageError reports bad command-line arguments; bpctl prints the usage after it
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// errNotReconciled makes reconcile exit non-zero when a balance does not match its log
+var errNotReconciled = errors.New("balance does not reconcile with the transaction log")
+
+// errRebuildPending makes a rebuild dry run exit non-zero when applying it would change the balance
+var errRebuildPending = errors.New("stored balance differs from the rebuilt one; rerun with -apply to write it")
+
+// runBalance shows a user's balance and recent transactions
+func runBalance(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("balance", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	limit := flags.Int("n", defaultRecentTransactions, "number of recent transactions")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if *limit < 0 {
+		return usageError("-n cannot be negative")
+	}
+	userID, err := parseUserID(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	user, err := a.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	transactions := []*entity.Transaction{}
+	if *limit > 0 {
+		if transactions, err = a.txnRepo.ListByUser(ctx, userID, *limit); err != nil {
+			return err
+		}
+	}
+
+	return a.out.print(newBalanceView(user, transactions))
+}
+
+// runTxn shows one transaction
+func runTxn(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return usageError("txn needs a transaction ID")
+	}
+
+	txn, err := a.txnRepo.GetByTransactionID(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return a.out.print(newTransactionView(txn))
+}
+
+// runLocks lists user locks, or force-releases one
+func runLocks(ctx context.Context, a *app, args []string) error {
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "list"):
+		locks, err := a.lockAdmin.ListLocks(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(newLocksView(locks, a.timeProvider.Now()))
+
+	case args[0] == "release":
+		userID, err := parseUserID(args[1:])
+		if err != nil {
+			return err
+		}
+		if err := a.lockAdmin.ForceReleaseLock(ctx, userID); err != nil {
+			return err
+		}
+		return a.out.print(messageView{Message: fmt.Sprintf("Released the lock on user %d", userID)})
+
+	default:
+		return usageError("locks takes no arguments, or release USER_ID")
+	}
+}
+
+// runUser manages users
+func runUser(ctx context.Context, a *app, args []string) error {
+	if len(args) != 3 || args[0] != "create" {
+		return usageError("user create needs a user ID and an opening balance")
+	}
+	userID, err := parseUserID(args[1:2])
+	if err != nil {
+		return err
+	}
+
+	if err := a.users.CreateUser(ctx, userID, args[2]); err != nil {
+		return err
+	}
+	balance, err := a.users.GetBalance(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return a.out.print(messageView{Message: fmt.Sprintf("Created user %d with a balance of %s", balance.UserID, balance.Balance)})
+}
+
+// runReconcile checks a user's balance against their transaction log
+func runReconcile(ctx context.Context, a *app, args []string) error {
+	userID, err := parseUserID(args)
+	if err != nil {
+		return err
+	}
+
+	report, err := a.reconciler.Reconcile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := a.out.print(newReconciliationView(report)); err != nil {
+		return err
+	}
+	if !report.Consistent() {
+		return errNotReconciled
+	}
+	return nil
+}
+
+// runRebuild replays a user's completed transactions and shows, or with -apply writes, the rebuilt balance
+func runRebuild(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("rebuild", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	initial := flags.String("initial", "", "balance to replay from instead of the configured one")
+	apply := flags.Bool("apply", false, "write the rebuilt balance and transaction count")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	userID, err := parseUserID(flags.Args())
+	if err != nil {
+		return err
+	}
+	// Only an applied rebuild records who made it
+	var operator entity.Principal
+	if *apply {
+		if operator, err = a.operator(); err != nil {
+			return err
+		}
+	}
+
+	report, err := a.transactions.RebuildBalance(ctx, userID, transactionUseCase.RebuildRequest{
+		InitialBalance: *initial,
+		Apply:          *apply,
+		Operator:       operator.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if err := a.out.print(newRebuildView(report)); err != nil {
+		return err
+	}
+	if !*apply && report.Changed() {
+		return errRebuildPending
+	}
+	return nil
+}
+
+// runAdjust credits or debits a user's balance as an admin transaction
+func runAdjust(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("adjust", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	transactionID := flags.String("id", "", "transaction ID; rerunning with the same ID does not adjust twice")
+	reason := flags.String("reason", "", "reason code")
+	note := flags.String("note", "", "why the balance is adjusted, e.g. a ticket reference")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if *transactionID == "" {
+		return usageError("adjust needs -id, so that a retried adjustment is not applied twice")
+	}
+	if flags.NArg() != 3 {
+		return usageError("adjust needs a user ID, credit or debit, and an amount")
+	}
+	userID, err := parseUserID(flags.Args()[:1])
+	if err != nil {
+		return err
+	}
+	operator, err := a.operator()
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.transactions.AdjustBalance(ctx, userID, transactionUseCase.AdjustmentRequest{
+		TransactionID: *transactionID,
+		Direction:     flags.Arg(1),
+		Amount:        flags.Arg(2),
+		Reason:        *reason,
+		Note:          *note,
+		Operator:      operator.Name,
+	}); err != nil {
+		return err
+	}
+
+	// Show what was recorded, which is the earlier adjustment if the ID was already used
+	txn, err := a.txnRepo.GetByTransactionID(ctx, *transactionID)
+	if err != nil {
+		return err
+	}
+	return a.out.print(newTransactionView(txn))
+}
+
+// runApprovals lists, shows, approves or rejects transactions held for approval
+func runApprovals(ctx context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			args = args[1:]
+		case "show":
+			if len(args) != 2 {
+				return usageError("approvals show needs a transaction ID")
+			}
+			txn, err := a.transactions.GetTransaction(ctx, args[1])
+			if err != nil {
+				return err
+			}
+			return printApproval(ctx, a, txn)
+		case "approve":
+			return runApprovalDecision(ctx, a, args, a.transactions.ApproveTransaction)
+		case "reject":
+			return runApprovalDecision(ctx, a, args, a.transactions.RejectTransaction)
+		}
+	}
+
+	flags := flag.NewFlagSet("approvals", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	limit := flags.Int("n", defaultPendingApprovals, "number of held transactions")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if *limit <= 0 || flags.NArg() != 0 {
+		return usageError("approvals takes -n N with a positive N, or show, approve or reject TXN_ID")
+	}
+
+	pending, err := a.transactions.ListPendingApprovals(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	return a.out.print(newApprovalsView(pending, a.transactions.GetManager().ApprovalPolicy().Expiry))
+}
+
+// runApprovalDecision approves or rejects one held transaction and shows the outcome
+func runApprovalDecision(
+	ctx context.Context,
+	a *app,
+	args []string,
+	decide func(ctx context.Context, transactionID string, approver entity.Principal, note string) (*entity.Transaction, error),
+) error {
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	note := flags.String("note", "", "why the transaction is approved or rejected")
+	if err := flags.Parse(args[1:]); err != nil {
+		return usageError(err.Error())
+	}
+	if flags.NArg() != 1 {
+		return usageError(fmt.Sprintf("approvals %s needs a transaction ID", args[0]))
+	}
+	approver, err := a.operator()
+	if err != nil {
+		return err
+	}
+
+	txn, err := decide(ctx, flags.Arg(0), approver, *note)
+	// An approval that came too late or could not be applied still changed the transaction
+	if txn != nil {
+		if printErr := printApproval(ctx, a, txn); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// printApproval shows a held transaction together with its approval history
+func printApproval(ctx context.Context, a *app, txn *entity.Transaction) error {
+	history, err := a.transactions.ApprovalHistory(ctx, txn.TransactionID)
+	if err != nil {
+		return err
+	}
+	return a.out.print(newApprovalView(txn, history, a.transactions.GetManager().ApprovalPolicy().Expiry))
+}
+
+// operator authenticates the operator running bpctl by their token in BP_OPERATOR_TOKEN
+// Commands that record who made a change use it, so an operator cannot act under another's name.
+func (a *app) operator() (entity.Principal, error) {
+	principal, ok := a.operators.Authenticate(a.token)
+	if !ok {
+		return entity.Principal{}, fmt.Errorf("%s must hold the token of one of the configured admin.operators", operatorTokenEnv)
+	}
+	return principal, nil
+}
+
+// parseUserID reads the single user ID argument of a command
+func parseUserID(args []string) (uint64, error) {
+	if len(args) != 1 {
+		return 0, usageError("expected a single user ID")
+	}
+	userID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || userID == 0 {
+		return 0, usageError(fmt.Sprintf("invalid user ID %q: must be a positive integer", args[0]))
+	}
+	return userID, nil
+}
+
+// runStatement exports the account statements of one or more users
+// Users whose statement fails are reported and skipped, so one bad ID does not stop a bulk export.
+func runStatement(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	from := flags.String("from", "", "start of the period, inclusive")
+	to := flags.String("to", "", "end of the period, exclusive unless it is a date")
+	format := flags.String("format", export.FormatCSV, "statement format: csv or ndjson")
+	outDir := flags.String("out", "", "directory to write one file per user to, instead of stdout")
+	usersFile := flags.String("users", "", `file of user IDs, one per line, or "-" for stdin`)
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if *format != export.FormatCSV && *format != export.FormatNDJSON {
+		return usageError(fmt.Sprintf("unknown statement format %q: use csv or ndjson", *format))
+	}
+	period, err := statement.ParsePeriod(*from, *to)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	userIDs, err := statementUserIDs(a, flags.Args(), *usersFile)
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return usageError("statement needs at least one user ID, as arguments or with -users")
+	}
+
+	if *outDir == "" {
+		return writeStatements(ctx, a, userIDs, period, *format)
+	}
+	return writeStatementFiles(ctx, a, userIDs, period, *format, *outDir)
+}
+
+// writeStatements streams the statements of all users to stdout through one writer,
+// so a CSV export has a single header
+func writeStatements(ctx context.Context, a *app, userIDs []uint64, period statement.Period, format string) error {
+	w, err := export.NewStatementWriter(format, a.out.w)
+	if err != nil {
+		return err
+	}
+
+	var failures []error
+	for _, userID := range userIDs {
+		if _, err := a.statements.Export(ctx, userID, period, w); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			failures = append(failures, fmt.Errorf("user %d: %w", userID, err))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// writeStatementFiles writes each user's statement to its own file in dir and lists what was written
+func writeStatementFiles(
+	ctx context.Context,
+	a *app,
+	userIDs []uint64,
+	period statement.Period,
+	format, dir string,
+) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	results := make([]statementResult, 0, len(userIDs))
+	var failures []error
+	for _, userID := range userIDs {
+		path := filepath.Join(dir, fmt.Sprintf("statement-%d.%s", userID, format))
+		summary, err := writeStatementFile(ctx, a, userID, period, format, path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			failures = append(failures, fmt.Errorf("user %d: %w", userID, err))
+			results = append(results, statementResult{UserID: userID, Error: err.Error()})
+			continue
+		}
+		results = append(results, newStatementResult(summary, path))
+	}
+
+	if err := a.out.print(statementsView{Statements: results}); err != nil {
+		return err
+	}
+	return errors.Join(failures...)
+}
+
+// writeStatementFile writes one statement to path; a failed statement leaves no file behind
+func writeStatementFile(
+	ctx context.Context,
+	a *app,
+	userID uint64,
+	period statement.Period,
+	format, path string,
+) (*statement.Summary, error) {
+	// Check the user before creating the file, so unknown users leave nothing behind
+	prepared, err := a.statements.Prepare(ctx, userID, period)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := export.NewStatementWriter(format, file)
+	if err == nil {
+		var summary *statement.Summary
+		summary, err = prepared.WriteTo(ctx, w)
+		if err == nil {
+			if err = file.Close(); err == nil {
+				return summary, nil
+			}
+		}
+	}
+	_ = file.Close()
+	_ = os.Remove(path)
+	return nil, err
+}
+
+// statementUserIDs collects the user IDs given as arguments and, with -users, from a file or stdin
+func statementUserIDs(a *app, args []string, usersFile string) ([]uint64, error) {
+	userIDs := make([]uint64, 0, len(args))
+	for _, arg := range args {
+		userID, err := parseUserID([]string{arg})
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if usersFile == "" {
+		return userIDs, nil
+	}
+
+	var r io.Reader = a.stdin
+	if usersFile != "-" {
+		file, err := os.Open(usersFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		userID, err := parseUserID([]string{line})
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, scanner.Err()
+}
+
+// runImport processes a file of NDJSON transactions through the normal processing rules
+// Lines that the rules reject are reported and skipped; anything else that goes wrong stops the import
+// at its checkpoint and makes bpctl exit non-zero.
+func runImport(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	parallel := flags.Int("parallel", importer.DefaultParallelism, "number of users processed at once")
+	checkpointPath := flags.String("checkpoint", "", "file that records progress, so that a rerun resumes")
+	reportPath := flags.String("report", "-", `file for the per-line results, or "-" for stdout`)
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if *parallel <= 0 || flags.NArg() != 1 {
+		return usageError("import takes -parallel N with a positive N and a single file, or - for stdin")
+	}
+	source := flags.Arg(0)
+
+	var input io.Reader = a.stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+	}
+
+	// The summary follows the results, or goes to stderr when they are on stdout
+	reportOut, summaryOut := a.out.w, a.out
+	if *reportPath != "-" {
+		file, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reportOut = file
+	} else {
+		summaryOut = &printer{format: a.out.format, w: a.stderr}
+	}
+	report := importfile.NewReport(reportOut)
+
+	options := importer.Options{Parallelism: *parallel}
+	if *checkpointPath != "" {
+		options.Checkpoint = importfile.NewFileCheckpoint(*checkpointPath, source, a.timeProvider)
+	}
+	imp := importer.NewImporter(a.transactions.GetProcessor(), options, a.logger)
+
+	summary, err := imp.Run(ctx, input, report)
+	if flushErr := report.Flush(); err == nil {
+		err = flushErr
+	}
+	if summary != nil {
+		if printErr := summaryOut.print(newImportView(summary)); printErr != nil && err == nil {
+			err = printErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+// Command bpctl is the operator tool for inspecting and repairing balances during incidents
+//
+// It connects straight to the database with the same configuration files and BP_ environment
+// variables as the API, and goes through the same repositories and use cases.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	userUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/user"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/auth"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
+	timeProvider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/wiring"
+)
+
+// operatorTokenEnv names the variable holding the token of the operator making a change
+const operatorTokenEnv = "BP_OPERATOR_TOKEN"
+
+// commandTimeout bounds a single command's database work
+const commandTimeout = 30 * time.Second
+
+// longRunningCommands may run for as long as they make progress, such as bulk exports;
+// they stop on an interrupt instead of after commandTimeout
+var longRunningCommands = map[string]bool{
+	"statement": true,
+	"import":    true,
+}
+
+const usage = `Usage: bpctl [-o table|json] [-v] <command> [arguments]
+
+Commands:
+  balance [-n N] USER_ID    Show a user's balance and their N most recent transactions (default 10)
+  txn TRANSACTION_ID        Show a transaction
+  locks                     List user locks
+  locks release USER_ID     Force-release a user's lock
+  user create USER_ID BAL   Create a user with an opening balance, e.g. "100.00"
+  reconcile USER_ID         Check a user's balance against their transaction log
+  rebuild [-initial BAL] [-apply] USER_ID
+                            Replay a user's completed transactions from an initial balance (default
+                            rebuild.initialBalance, else the one the log implies) and show how the
+                            rebuilt balance and count differ from the stored ones; -apply writes them
+                            under the user's lock
+  adjust -id TXN_ID -reason R -note TEXT USER_ID credit|debit AMOUNT
+                            Credit or debit a user's balance as an admin transaction; reasons are
+                            goodwill, correction, compensation and chargeback
+  approvals [-n N]          List transactions awaiting approval, oldest first (default 100)
+  approvals show TXN_ID     Show a held transaction with its approval history
+  approvals approve|reject [-note TEXT] TXN_ID
+                            Approve or reject a held transaction; the approver must not be the
+                            operator who requested it, nor the holder of the account
+  statement [-from T] [-to T] [-format csv|ndjson] [-out DIR] [-users FILE] [USER_ID...]
+                            Export account statements: the opening balance, every transaction in the
+                            period with the running balance, and the closing balance. T is RFC 3339
+                            or YYYY-MM-DD (a date as -to includes that day). Without -out all
+                            statements go to stdout; with it each user gets DIR/statement-ID.FORMAT.
+                            -users reads further user IDs, one per line, from FILE ("-" for stdin)
+  import [-parallel N] [-checkpoint FILE] [-report FILE] FILE
+                            Process the NDJSON transactions in FILE ("-" for stdin) through the normal
+                            rules, N users at a time (default 4), keeping each user's order. With
+                            -checkpoint a rerun resumes where the last one stopped. One result per
+                            line goes to -report (default stdout), then a summary
+
+Flags:
+  -o table|json             Output format (default table)
+  -v                        Log what the repositories do to stderr
+
+rebuild -apply, adjust and approvals approve|reject record the operator whose token is in
+BP_OPERATOR_TOKEN; it must match one of the configured admin.operators.
+`
+
+// app holds what the commands need
+type app struct {
+	out          *printer
+	logger       coreport.Logger
+	timeProvider coreport.TimeProvider
+	userRepo     persistence.UserRepository
+	txnRepo      persistence.TransactionRepository
+	lockAdmin    persistence.UserLockAdminRepository
+	users        *userUseCase.UserUseCase
+	reconciler   *userUseCase.Reconciler
+	transactions *transactionUseCase.Service
+	statements   *statement.Exporter
+	operators    *auth.Operators
+	token        string // BP_OPERATOR_TOKEN, which identifies the operator running bpctl
+	stdin        io.Reader
+	stderr       io.Writer
+}
+
+// command runs one bpctl command with its arguments
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"balance":   runBalance,
+	"txn":       runTxn,
+	"locks":     runLocks,
+	"user":      runUser,
+	"reconcile": runReconcile,
+	"adjust":    runAdjust,
+	"approvals": runApprovals,
+	"statement": runStatement,
+	"import":    runImport,
+	"rebuild":   runRebuild,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes one command and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bpctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	format := flags.String("o", formatTable, "output format: table or json")
+	verbose := flags.Bool("v", false, "log to stderr")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(stderr, "unknown output format %q\n\n%s", *format, usage)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", flags.Arg(0), usage)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	// The repositories log expected outcomes such as a missing user as errors, which would
+	// only bury the command's answer, so logging is opt-in
+	appLogger := logger.NewNoopLogger()
+	if *verbose {
+		appLogger = logger.NewZapLogger(cfg.Environment == "production")
+	}
+	defer func() { _ = appLogger.Flush() }()
+
+	dbConfig := database.NewConfigFromAppConfig(cfg)
+	// Keep GORM from echoing every statement over the command's own output
+	dbConfig.LogLevel = "error"
+
+	tp := timeProvider.NewRealTimeProvider()
+	dbManager := database.NewManager(dbConfig, appLogger, tp)
+	if _, err := dbManager.Connect(); err != nil {
+		fmt.Fprintf(stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer dbManager.Close()
+
+	transactions, err := newTransactionService(cfg, dbManager, tp, appLogger)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+	operators, err := auth.NewOperators(cfg.Admin.Operators)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	userRepo := repository.NewUserRepository(dbManager.DB(), tp, appLogger)
+	txnRepo := repository.NewTransactionRepository(dbManager.DB(), appLogger)
+	a := &app{
+		out:          &printer{format: *format, w: stdout},
+		logger:       appLogger,
+		timeProvider: tp,
+		userRepo:     userRepo,
+		txnRepo:      txnRepo,
+		lockAdmin:    dbManager.CreateUserLockAdminRepository(),
+		users:        userUseCase.NewUserUseCase(userRepo, tp, appLogger),
+		reconciler:   userUseCase.NewReconciler(userRepo, txnRepo, appLogger),
+		transactions: transactions,
+		statements:   statement.NewExporter(userRepo, txnRepo, tp, appLogger),
+		operators:    operators,
+		token:        os.Getenv(operatorTokenEnv),
+		stdin:        os.Stdin,
+		stderr:       stderr,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	if longRunningCommands[flags.Arg(0)] {
+		ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	}
+	defer cancel()
+
+	if err := cmd(ctx, a, flags.Args()[1:]); err != nil {
+		if usageErr, ok := err.(usageError); ok {
+			fmt.Fprintf(stderr, "%v\n\n%s", usageErr, usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "bpctl %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+// newTransactionService creates a transaction service that processes the way the API is configured to
+// Adjustments then take the same user lock, retries and processing mode as provider transactions.
+func newTransactionService(
+	cfg *config.Config,
+	dbManager *database.Manager,
+	tp coreport.TimeProvider,
+	appLogger coreport.Logger,
+) (*transactionUseCase.Service, error) {
+	policies, err := wiring.NewPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	service := transactionUseCase.NewTransactionService(
+		dbManager.CreateUnitOfWork(),
+		dbManager.CreateUserLockRepository(),
+		tp,
+		appLogger,
+		time.Duration(cfg.Transaction.LockTimeoutMs)*time.Millisecond,
+	)
+	service.GetManager().WithRetryPolicy(transactionUseCase.RetryPolicy{
+		MaxRetries:   cfg.Transaction.MaxRetries,
+		BaseBackoff:  time.Duration(cfg.Transaction.RetryBaseBackoffMs) * time.Millisecond,
+		MaxBackoff:   time.Duration(cfg.Transaction.RetryMaxBackoffMs) * time.Millisecond,
+		JitterFactor: cfg.Transaction.RetryJitter,
+	})
+	if cfg.Transaction.ProcessingMode == transactionUseCase.ProcessingModeFast {
+		service.WithFastPath(repository.NewAtomicTransactionRepository(dbManager.DB(), tp, appLogger))
+	}
+	service.GetManager().WithHeartbeatInterval(time.Duration(cfg.Transaction.HeartbeatIntervalMs) * time.Millisecond)
+	return policies.Apply(service), nil
+}
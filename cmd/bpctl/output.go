@@ -0,0 +1,488 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/importer"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	userUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/user"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// view is a command result that can be rendered as a table
+// Views are also marshalled as they are for JSON output.
+type view interface {
+	table(w io.Writer)
+}
+
+// printer writes command results in the selected format
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+// print renders the view as indented JSON or as a table
+func (p *printer) print(v view) error {
+	if p.format == formatJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	v.table(tw)
+	return tw.Flush()
+}
+
+// formatTime formats a timestamp for tables, or "-" if it is not set
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// messageView is the result of a command that changes something
+type messageView struct {
+	Message string `json:"message"`
+}
+
+func (v messageView) table(w io.Writer) {
+	fmt.Fprintln(w, v.Message)
+}
+
+// transactionView is one logged transaction
+type transactionView struct {
+	TransactionID string          `json:"transactionId"`
+	UserID        uint64          `json:"userId"`
+	SourceType    string          `json:"sourceType"`
+	State         string          `json:"state"`
+	Amount        string          `json:"amount"`
+	Status        string          `json:"status"`
+	ResultBalance string          `json:"resultBalance,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	ProcessedAt   *time.Time      `json:"processedAt,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Adjustment    *adjustmentView `json:"adjustment,omitempty"`
+}
+
+// adjustmentView is why and by whom an admin transaction adjusted a balance
+type adjustmentView struct {
+	Reason   string `json:"reason"`
+	Note     string `json:"note"`
+	Operator string `json:"operator"`
+}
+
+func newTransactionView(txn *entity.Transaction) transactionView {
+	v := transactionView{
+		TransactionID: txn.TransactionID,
+		UserID:        txn.UserID,
+		SourceType:    txn.SourceType.String(),
+		State:         txn.State.String(),
+		Amount:        txn.GetAmount(),
+		Status:        txn.Status.String(),
+		CreatedAt:     txn.CreatedAt,
+		ProcessedAt:   txn.ProcessedAt,
+		Error:         txn.ErrorMessage,
+	}
+	// Only completed transactions changed the balance
+	if txn.Status == entity.StatusCompleted {
+		v.ResultBalance = txn.GetResultBalance()
+	}
+	if adj := txn.Adjustment; adj != nil {
+		v.Adjustment = &adjustmentView{Reason: adj.Reason.String(), Note: adj.Note, Operator: adj.Operator}
+	}
+	return v
+}
+
+func (v transactionView) table(w io.Writer) {
+	fmt.Fprintf(w, "Transaction:\t%s\n", v.TransactionID)
+	fmt.Fprintf(w, "User:\t%d\n", v.UserID)
+	fmt.Fprintf(w, "Source:\t%s\n", v.SourceType)
+	fmt.Fprintf(w, "State:\t%s\n", v.State)
+	fmt.Fprintf(w, "Amount:\t%s\n", v.Amount)
+	fmt.Fprintf(w, "Status:\t%s\n", v.Status)
+	if v.ResultBalance != "" {
+		fmt.Fprintf(w, "Result balance:\t%s\n", v.ResultBalance)
+	}
+	fmt.Fprintf(w, "Created at:\t%s\n", formatTime(&v.CreatedAt))
+	fmt.Fprintf(w, "Processed at:\t%s\n", formatTime(v.ProcessedAt))
+	if v.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", v.Error)
+	}
+	if v.Adjustment != nil {
+		fmt.Fprintf(w, "Reason:\t%s\n", v.Adjustment.Reason)
+		fmt.Fprintf(w, "Note:\t%s\n", v.Adjustment.Note)
+		fmt.Fprintf(w, "Operator:\t%s\n", v.Adjustment.Operator)
+	}
+}
+
+// approvalView is a transaction held for approval with its approval history
+type approvalView struct {
+	transactionView
+	RequestedBy string              `json:"requestedBy"`
+	ExpiresAt   *time.Time          `json:"expiresAt,omitempty"`
+	History     []approvalEventView `json:"history"`
+}
+
+// approvalEventView is one step of a transaction's approval history
+type approvalEventView struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newApprovalView(txn *entity.Transaction, history []*entity.ApprovalEvent, expiry time.Duration) approvalView {
+	v := approvalView{
+		transactionView: newTransactionView(txn),
+		RequestedBy:     txn.Requester(),
+		History:         make([]approvalEventView, 0, len(history)),
+	}
+	// Only requests still waiting can expire
+	if expiry > 0 && txn.AwaitsApproval() {
+		expiresAt := txn.CreatedAt.Add(expiry)
+		v.ExpiresAt = &expiresAt
+	}
+	for _, event := range history {
+		v.History = append(v.History, approvalEventView{
+			Action:    event.Action.String(),
+			Actor:     event.Actor,
+			Note:      event.Note,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return v
+}
+
+func (v approvalView) table(w io.Writer) {
+	v.transactionView.table(w)
+	fmt.Fprintf(w, "Requested by:\t%s\n", v.RequestedBy)
+	if v.ExpiresAt != nil {
+		fmt.Fprintf(w, "Expires at:\t%s\n", formatTime(v.ExpiresAt))
+	}
+	if len(v.History) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "ACTION\tACTOR\tAT\tNOTE")
+	for _, event := range v.History {
+		note := event.Note
+		if note == "" {
+			note = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", event.Action, event.Actor, formatTime(&event.CreatedAt), note)
+	}
+}
+
+// approvalsView is the list of transactions awaiting approval
+type approvalsView struct {
+	Approvals []approvalView `json:"approvals"`
+}
+
+func newApprovalsView(pending []*entity.Transaction, expiry time.Duration) approvalsView {
+	v := approvalsView{Approvals: make([]approvalView, 0, len(pending))}
+	for _, txn := range pending {
+		v.Approvals = append(v.Approvals, newApprovalView(txn, nil, expiry))
+	}
+	return v
+}
+
+func (v approvalsView) table(w io.Writer) {
+	if len(v.Approvals) == 0 {
+		fmt.Fprintln(w, "No transactions awaiting approval")
+		return
+	}
+
+	fmt.Fprintln(w, "TRANSACTION\tUSER\tSOURCE\tSTATE\tAMOUNT\tREQUESTED BY\tCREATED AT\tEXPIRES AT")
+	for _, a := range v.Approvals {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.TransactionID, a.UserID, a.SourceType, a.State, a.Amount, a.RequestedBy, formatTime(&a.CreatedAt), formatTime(a.ExpiresAt))
+	}
+}
+
+// balanceView is a user's balance with their recent transactions
+type balanceView struct {
+	UserID             uint64            `json:"userId"`
+	Balance            string            `json:"balance"`
+	TransactionCount   uint64            `json:"transactionCount"`
+	UpdatedAt          time.Time         `json:"updatedAt"`
+	RecentTransactions []transactionView `json:"recentTransactions"`
+}
+
+func newBalanceView(user *entity.User, transactions []*entity.Transaction) balanceView {
+	v := balanceView{
+		UserID:             user.ID,
+		Balance:            user.GetBalance(),
+		TransactionCount:   user.TransactionCount,
+		UpdatedAt:          user.UpdatedAt,
+		RecentTransactions: make([]transactionView, 0, len(transactions)),
+	}
+	for _, txn := range transactions {
+		v.RecentTransactions = append(v.RecentTransactions, newTransactionView(txn))
+	}
+	return v
+}
+
+func (v balanceView) table(w io.Writer) {
+	fmt.Fprintf(w, "User:\t%d\n", v.UserID)
+	fmt.Fprintf(w, "Balance:\t%s\n", v.Balance)
+	fmt.Fprintf(w, "Transactions:\t%d\n", v.TransactionCount)
+	fmt.Fprintf(w, "Updated at:\t%s\n", formatTime(&v.UpdatedAt))
+	if len(v.RecentTransactions) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "TRANSACTION\tSOURCE\tSTATE\tAMOUNT\tSTATUS\tRESULT\tCREATED AT\tADJUSTMENT")
+	for _, txn := range v.RecentTransactions {
+		result := txn.ResultBalance
+		if result == "" {
+			result = "-"
+		}
+		adjustment := "-"
+		if txn.Adjustment != nil {
+			adjustment = fmt.Sprintf("%s by %s", txn.Adjustment.Reason, txn.Adjustment.Operator)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			txn.TransactionID, txn.SourceType, txn.State, txn.Amount, txn.Status, result, formatTime(&txn.CreatedAt), adjustment)
+	}
+}
+
+// lockView is one user lock
+type lockView struct {
+	UserID    uint64     `json:"userId"`
+	Holder    string     `json:"holder"`
+	LockedAt  time.Time  `json:"lockedAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Expired   bool       `json:"expired"`
+}
+
+// locksView is the list of user locks
+type locksView struct {
+	Locks []lockView `json:"locks"`
+}
+
+func newLocksView(locks []*entity.UserLock, now time.Time) locksView {
+	v := locksView{Locks: make([]lockView, 0, len(locks))}
+	for _, l := range locks {
+		lv := lockView{
+			UserID:   l.UserID,
+			Holder:   l.Holder,
+			LockedAt: l.LockedAt,
+			Expired:  l.IsExpired(now),
+		}
+		if !l.ExpiresAt.IsZero() {
+			expiresAt := l.ExpiresAt
+			lv.ExpiresAt = &expiresAt
+		}
+		v.Locks = append(v.Locks, lv)
+	}
+	return v
+}
+
+func (v locksView) table(w io.Writer) {
+	if len(v.Locks) == 0 {
+		fmt.Fprintln(w, "No user locks")
+		return
+	}
+
+	fmt.Fprintln(w, "USER\tHOLDER\tLOCKED AT\tEXPIRES AT\tEXPIRED")
+	for _, l := range v.Locks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", l.UserID, l.Holder, formatTime(&l.LockedAt), formatTime(l.ExpiresAt), l.Expired)
+	}
+}
+
+// reconciliationView is the result of reconciling one user
+type reconciliationView struct {
+	UserID           uint64   `json:"userId"`
+	Consistent       bool     `json:"consistent"`
+	Balance          string   `json:"balance"`
+	LogBalance       string   `json:"logBalance"`
+	OpeningBalance   string   `json:"openingBalance"`
+	TransactionCount uint64   `json:"transactionCount"`
+	Completed        int      `json:"completed"`
+	Failed           int      `json:"failed"`
+	Pending          int      `json:"pending"`
+	Discrepancies    []string `json:"discrepancies"`
+}
+
+func newReconciliationView(report *userUseCase.ReconciliationReport) reconciliationView {
+	discrepancies := report.Discrepancies
+	if discrepancies == nil {
+		discrepancies = []string{}
+	}
+	return reconciliationView{
+		UserID:           report.UserID,
+		Consistent:       report.Consistent(),
+		Balance:          report.Balance.String(),
+		LogBalance:       report.LogBalance.String(),
+		OpeningBalance:   report.OpeningBalance.String(),
+		TransactionCount: report.TransactionCount,
+		Completed:        report.Completed,
+		Failed:           report.Failed,
+		Pending:          report.Pending,
+		Discrepancies:    discrepancies,
+	}
+}
+
+func (v reconciliationView) table(w io.Writer) {
+	fmt.Fprintf(w, "User:\t%d\n", v.UserID)
+	fmt.Fprintf(w, "Consistent:\t%t\n", v.Consistent)
+	fmt.Fprintf(w, "Stored balance:\t%s\n", v.Balance)
+	fmt.Fprintf(w, "Log balance:\t%s\n", v.LogBalance)
+	fmt.Fprintf(w, "Opening balance:\t%s\n", v.OpeningBalance)
+	fmt.Fprintf(w, "Stored count:\t%d\n", v.TransactionCount)
+	fmt.Fprintf(w, "Log:\t%d completed, %d failed, %d pending\n", v.Completed, v.Failed, v.Pending)
+	if len(v.Discrepancies) > 0 {
+		fmt.Fprintf(w, "Discrepancies:\t%s\n", strings.Join(v.Discrepancies, "\n\t"))
+	}
+}
+
+// statementResult is one user's statement written by a bulk export
+type statementResult struct {
+	UserID         uint64 `json:"userId"`
+	Transactions   int    `json:"transactions"`
+	OpeningBalance string `json:"openingBalance,omitempty"`
+	ClosingBalance string `json:"closingBalance,omitempty"`
+	File           string `json:"file,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+func newStatementResult(summary *statement.Summary, file string) statementResult {
+	return statementResult{
+		UserID:         summary.UserID,
+		Transactions:   summary.Transactions,
+		OpeningBalance: summary.OpeningBalance.String(),
+		ClosingBalance: summary.ClosingBalance.String(),
+		File:           file,
+	}
+}
+
+// statementsView lists the statements written by a bulk export
+type statementsView struct {
+	Statements []statementResult `json:"statements"`
+}
+
+func (v statementsView) table(w io.Writer) {
+	fmt.Fprintln(w, "USER\tTRANSACTIONS\tOPENING\tCLOSING\tFILE")
+	for _, s := range v.Statements {
+		if s.Error != "" {
+			fmt.Fprintf(w, "%d\t-\t-\t-\terror: %s\n", s.UserID, s.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", s.UserID, s.Transactions, s.OpeningBalance, s.ClosingBalance, s.File)
+	}
+}
+
+// importView is the summary of an import
+type importView struct {
+	Lines      int `json:"lines"`
+	Skipped    int `json:"skipped"`
+	Processed  int `json:"processed"`
+	Duplicates int `json:"duplicates"`
+	Rejected   int `json:"rejected"`
+	Conflicts  int `json:"conflicts"`
+	Invalid    int `json:"invalid"`
+	Checkpoint int `json:"checkpoint"`
+}
+
+func newImportView(summary *importer.Summary) importView {
+	return importView{
+		Lines:      summary.Lines,
+		Skipped:    summary.Skipped,
+		Processed:  summary.Processed,
+		Duplicates: summary.Duplicates,
+		Rejected:   summary.Rejected,
+		Conflicts:  summary.Conflicts,
+		Invalid:    summary.Invalid,
+		Checkpoint: summary.Checkpoint,
+	}
+}
+
+func (v importView) table(w io.Writer) {
+	fmt.Fprintf(w, "Lines read:\t%d\n", v.Lines)
+	fmt.Fprintf(w, "Before checkpoint:\t%d\n", v.Skipped)
+	fmt.Fprintf(w, "Processed:\t%d\n", v.Processed)
+	fmt.Fprintf(w, "Duplicates:\t%d\n", v.Duplicates)
+	fmt.Fprintf(w, "Rejected:\t%d\n", v.Rejected)
+	fmt.Fprintf(w, "Conflicts:\t%d\n", v.Conflicts)
+	fmt.Fprintf(w, "Invalid:\t%d\n", v.Invalid)
+	fmt.Fprintf(w, "Checkpoint:\tline %d\n", v.Checkpoint)
+}
+
+// rebuildView compares a user's stored balance with the one rebuilt from their log
+type rebuildView struct {
+	UserID               uint64   `json:"userId"`
+	InitialBalance       string   `json:"initialBalance"`
+	InitialBalanceSource string   `json:"initialBalanceSource"`
+	Replayed             int      `json:"replayed"`
+	StoredBalance        string   `json:"storedBalance"`
+	RebuiltBalance       string   `json:"rebuiltBalance"`
+	StoredCount          uint64   `json:"storedTransactionCount"`
+	RebuiltCount         uint64   `json:"rebuiltTransactionCount"`
+	Changed              bool     `json:"changed"`
+	Applied              bool     `json:"applied"`
+	Warnings             []string `json:"warnings"`
+}
+
+func newRebuildView(report *transactionUseCase.RebuildReport) rebuildView {
+	warnings := report.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return rebuildView{
+		UserID:               report.UserID,
+		InitialBalance:       report.InitialBalance.String(),
+		InitialBalanceSource: report.InitialBalanceSource,
+		Replayed:             report.Replayed,
+		StoredBalance:        report.StoredBalance.String(),
+		RebuiltBalance:       report.RebuiltBalance.String(),
+		StoredCount:          report.StoredCount,
+		RebuiltCount:         report.RebuiltCount,
+		Changed:              report.Changed(),
+		Applied:              report.Applied,
+		Warnings:             warnings,
+	}
+}
+
+func (v rebuildView) table(w io.Writer) {
+	fmt.Fprintf(w, "User:\t%d\n", v.UserID)
+	fmt.Fprintf(w, "Initial balance:\t%s (%s)\n", v.InitialBalance, v.InitialBalanceSource)
+	fmt.Fprintf(w, "Replayed:\t%d completed transactions\n", v.Replayed)
+	fmt.Fprintln(w, "\tSTORED\tREBUILT")
+	fmt.Fprintf(w, "Balance:\t%s\t%s%s\n", v.StoredBalance, v.RebuiltBalance, changedMark(v.StoredBalance != v.RebuiltBalance))
+	fmt.Fprintf(w, "Transaction count:\t%d\t%d%s\n", v.StoredCount, v.RebuiltCount, changedMark(v.StoredCount != v.RebuiltCount))
+	switch {
+	case v.Applied:
+		fmt.Fprintln(w, "Applied:\tyes")
+	case v.Changed:
+		fmt.Fprintln(w, "Applied:\tno (dry run)")
+	default:
+		fmt.Fprintln(w, "Applied:\tnothing to change")
+	}
+	if len(v.Warnings) > 0 {
+		fmt.Fprintf(w, "Warnings:\t%s\n", strings.Join(v.Warnings, "\n\t"))
+	}
+}
+
+// changedMark flags a rebuilt value that differs from the stored one
+func changedMark(changed bool) string {
+	if changed {
+		return "  (changed)"
+	}
+	return ""
+}
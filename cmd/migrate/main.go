@@ -0,0 +1,206 @@
+// Command migrate inspects and applies the database schema migrations
+//
+// Usage:
+//
+//	migrate status    List every migration and whether it is applied
+//	migrate up        Apply all pending migrations
+//	migrate down N    Revert the N most recently applied migrations
+//	migrate dry-run   Print the SQL that up would run, without running it
+//	migrate verify    Check applied migrations against their checksums
+//
+// It reads the same configuration files and BP_ environment variables as the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database/migration"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	timeProvider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  status    List every migration and whether it is applied
+  up        Apply all pending migrations
+  down N    Revert the N most recently applied migrations
+  dry-run   Print the SQL that up would run, without running it
+  verify    Check applied migrations against their checksums
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes one command and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	command := args[0]
+	downCount := 0
+	switch command {
+	case "status", "up", "dry-run", "verify":
+		if len(args) != 1 {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+	case "down":
+		n, err := parseDownCount(args[1:])
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n\n%s", err, usage)
+			return 2
+		}
+		downCount = n
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	appLogger := logger.NewZapLogger(cfg.Environment == "production")
+	appLogger.SetLevel(coreport.LogLevelWarn)
+	defer func() { _ = appLogger.Flush() }()
+
+	dbConfig := database.NewConfigFromAppConfig(cfg)
+	// Keep GORM from echoing every statement over the command's own output
+	dbConfig.LogLevel = "error"
+
+	tp := timeProvider.NewRealTimeProvider()
+	dbManager := database.NewManager(dbConfig, appLogger, tp)
+	if _, err := dbManager.Connect(); err != nil {
+		fmt.Fprintf(stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer dbManager.Close()
+
+	mgr := migration.NewMigrationManagerWithTimeProvider(dbManager.DB(), appLogger, tp)
+	ctx := context.Background()
+
+	switch command {
+	case "status":
+		err = printStatus(ctx, mgr, stdout)
+	case "up":
+		err = up(ctx, mgr, stdout)
+	case "down":
+		err = down(ctx, mgr, downCount, stdout)
+	case "dry-run":
+		err = dryRun(ctx, mgr, stdout)
+	case "verify":
+		err = verify(ctx, mgr, stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "migrate %s: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// parseDownCount reads the number of migrations to revert
+func parseDownCount(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("down needs the number of migrations to revert")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid migration count %q: must be a positive integer", args[0])
+	}
+	return n, nil
+}
+
+// printStatus lists every migration with its state
+func printStatus(ctx context.Context, mgr *migration.MigrationManager, out io.Writer) error {
+	statuses, err := mgr.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.Name, s.State, appliedAt)
+	}
+	return w.Flush()
+}
+
+// up applies every pending migration
+func up(ctx context.Context, mgr *migration.MigrationManager, out io.Writer) error {
+	applied, err := mgr.Up(ctx)
+	for _, m := range applied {
+		fmt.Fprintf(out, "applied  %s\n", m)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(out, "Database is up to date")
+	}
+	return nil
+}
+
+// down reverts the n most recently applied migrations
+func down(ctx context.Context, mgr *migration.MigrationManager, n int, out io.Writer) error {
+	reverted, err := mgr.Down(ctx, n)
+	for _, m := range reverted {
+		fmt.Fprintf(out, "reverted %s\n", m)
+	}
+	if err != nil {
+		return err
+	}
+	if len(reverted) == 0 {
+		fmt.Fprintln(out, "No applied migrations to revert")
+	}
+	return nil
+}
+
+// dryRun prints the SQL of every pending migration
+func dryRun(ctx context.Context, mgr *migration.MigrationManager, out io.Writer) error {
+	plan, err := mgr.Plan(ctx, migration.DirectionUp, 0)
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		fmt.Fprintln(out, "-- Database is up to date")
+		return nil
+	}
+
+	for _, step := range plan {
+		fmt.Fprintf(out, "-- %s (%s)\n", step.Migration, step.Direction)
+		for _, stmt := range step.SQL {
+			fmt.Fprintf(out, "%s;\n", stmt)
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// verify checks applied migrations against their recorded checksums
+func verify(ctx context.Context, mgr *migration.MigrationManager, out io.Writer) error {
+	if err := mgr.Verify(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "All applied migrations match their checksums")
+	return nil
+}
@@ -0,0 +1,138 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+)
+
+// SourceAdmin marks manual balance adjustments made by operators
+// Every admin transaction carries an Adjustment saying why it was made and by whom.
+const SourceAdmin SourceType = "admin"
+
+func init() {
+	RegisterSourceType(SourceAdmin)
+}
+
+// Limits on the free-text parts of an adjustment
+const (
+	MaxAdjustmentNoteLength = 1000
+	MaxOperatorLength       = 255
+)
+
+// AdjustmentReason categorises why an operator adjusted a balance
+type AdjustmentReason string
+
+// String returns the string representation of the adjustment reason
+func (r AdjustmentReason) String() string {
+	return string(r)
+}
+
+// Adjustment reasons
+const (
+	ReasonGoodwill     AdjustmentReason = "goodwill"     // Credit given to keep a customer happy
+	ReasonCorrection   AdjustmentReason = "correction"   // Fixes a balance left wrong by an error
+	ReasonCompensation AdjustmentReason = "compensation" // Makes up for an outage or a failed payout
+	ReasonChargeback   AdjustmentReason = "chargeback"   // Reverses funds after a payment dispute
+)
+
+var adjustmentReasonRegistry = NewEnumRegistry(
+	errs.ErrInvalidAdjustment,
+	ReasonGoodwill,
+	ReasonCorrection,
+	ReasonCompensation,
+	ReasonChargeback,
+)
+
+// IsValid checks if the adjustment reason is registered
+func (r AdjustmentReason) IsValid() bool {
+	return adjustmentReasonRegistry.Contains(r)
+}
+
+// Values returns the registered adjustment reasons
+func (r AdjustmentReason) Values() []AdjustmentReason {
+	return adjustmentReasonRegistry.Values()
+}
+
+// RegisterAdjustmentReason adds a reason operators can give for an adjustment
+func RegisterAdjustmentReason(reason AdjustmentReason) {
+	adjustmentReasonRegistry.Register(reason)
+}
+
+// ParseAdjustmentReason converts a string to an adjustment reason
+func ParseAdjustmentReason(reason string) (AdjustmentReason, error) {
+	return adjustmentReasonRegistry.Parse(reason)
+}
+
+// Adjustment records why an operator adjusted a balance and who did it
+type Adjustment struct {
+	Reason   AdjustmentReason // Mandatory reason code
+	Note     string           // Mandatory free-text explanation, e.g. a ticket reference
+	Operator string           // Identity of the operator who made the adjustment
+}
+
+// NewAdjustment creates a validated adjustment
+func NewAdjustment(reason string, note string, operator string) (*Adjustment, error) {
+	parsedReason, err := ParseAdjustmentReason(reason)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment := &Adjustment{
+		Reason:   parsedReason,
+		Note:     strings.TrimSpace(note),
+		Operator: strings.TrimSpace(operator),
+	}
+	if err := adjustment.Validate(); err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// Validate checks that the adjustment has a known reason, a note and an operator
+func (a *Adjustment) Validate() error {
+	switch {
+	case !a.Reason.IsValid():
+		return fmt.Errorf("%w: unknown reason %q", errs.ErrInvalidAdjustment, a.Reason)
+	case strings.TrimSpace(a.Note) == "":
+		return fmt.Errorf("%w: a note is required", errs.ErrInvalidAdjustment)
+	case len(a.Note) > MaxAdjustmentNoteLength:
+		return fmt.Errorf("%w: note is longer than %d characters", errs.ErrInvalidAdjustment, MaxAdjustmentNoteLength)
+	case strings.TrimSpace(a.Operator) == "":
+		return fmt.Errorf("%w: the operator is required", errs.ErrInvalidAdjustment)
+	case len(a.Operator) > MaxOperatorLength:
+		return fmt.Errorf("%w: operator is longer than %d characters", errs.ErrInvalidAdjustment, MaxOperatorLength)
+	}
+	return nil
+}
+
+// ValidateAdjustment checks that admin transactions, and only those, carry a valid adjustment
+func ValidateAdjustment(sourceType SourceType, adjustment *Adjustment) error {
+	switch {
+	case sourceType == SourceAdmin && adjustment == nil:
+		return fmt.Errorf("%w: admin transactions need a reason, note and operator", errs.ErrInvalidAdjustment)
+	case sourceType != SourceAdmin && adjustment != nil:
+		return fmt.Errorf("%w: only admin transactions carry adjustment details", errs.ErrInvalidAdjustment)
+	case adjustment != nil:
+		return adjustment.Validate()
+	}
+	return nil
+}
+
+// WithAdjustment attaches the details of a manual adjustment to an admin transaction
+func WithAdjustment(adjustment *Adjustment) TransactionOption {
+	return func(t *Transaction) error {
+		if adjustment == nil {
+			return nil
+		}
+		copied := *adjustment
+		t.Adjustment = &copied
+		return nil
+	}
+}
+
+// IsAdjustment reports whether the transaction is a manual adjustment made by an operator
+func (t *Transaction) IsAdjustment() bool {
+	return t.SourceType == SourceAdmin
+}
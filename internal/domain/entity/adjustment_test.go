@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAdjustment(t *testing.T) {
+	t.Run("Valid adjustment", func(t *testing.T) {
+		adj, err := NewAdjustment("goodwill", "  ticket #123  ", " alice ")
+		require.NoError(t, err)
+		assert.Equal(t, ReasonGoodwill, adj.Reason)
+		assert.Equal(t, "ticket #123", adj.Note)
+		assert.Equal(t, "alice", adj.Operator)
+	})
+
+	tests := []struct {
+		name     string
+		reason   string
+		note     string
+		operator string
+	}{
+		{"Unknown reason", "bonus", "ticket #123", "alice"},
+		{"Missing note", "goodwill", "   ", "alice"},
+		{"Note too long", "goodwill", strings.Repeat("x", MaxAdjustmentNoteLength+1), "alice"},
+		{"Missing operator", "goodwill", "ticket #123", ""},
+		{"Operator too long", "goodwill", "ticket #123", strings.Repeat("x", MaxOperatorLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAdjustment(tt.reason, tt.note, tt.operator)
+			assert.ErrorIs(t, err, errs.ErrInvalidAdjustment)
+		})
+	}
+}
+
+func TestAdminTransaction(t *testing.T) {
+	mockTime := coremocks.NewMockTimeProvider(t)
+	mockTime.EXPECT().Now().Return(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)).Maybe()
+
+	adj := &Adjustment{Reason: ReasonCorrection, Note: "double charge", Operator: "alice"}
+
+	t.Run("Admin source is registered", func(t *testing.T) {
+		assert.True(t, IsValidSourceType("admin"))
+	})
+
+	t.Run("Admin transaction with an adjustment", func(t *testing.T) {
+		tx, err := NewTransaction(1, "adj-1", string(SourceAdmin), string(StateWin), "5.00", mockTime, WithAdjustment(adj))
+		require.NoError(t, err)
+		assert.True(t, tx.IsAdjustment())
+		assert.Equal(t, *adj, *tx.Adjustment)
+
+		// The transaction keeps its own copy
+		adj.Note = "changed"
+		assert.Equal(t, "double charge", tx.Adjustment.Note)
+		adj.Note = "double charge"
+
+		clone := tx.Clone()
+		clone.Adjustment.Note = "changed"
+		assert.Equal(t, "double charge", tx.Adjustment.Note)
+	})
+
+	t.Run("Admin transaction without an adjustment", func(t *testing.T) {
+		_, err := NewTransaction(1, "adj-2", string(SourceAdmin), string(StateWin), "5.00", mockTime)
+		assert.ErrorIs(t, err, errs.ErrInvalidAdjustment)
+	})
+
+	t.Run("Adjustment on a provider transaction", func(t *testing.T) {
+		_, err := NewTransaction(1, "tx-1", string(SourceGame), string(StateWin), "5.00", mockTime, WithAdjustment(adj))
+		assert.ErrorIs(t, err, errs.ErrInvalidAdjustment)
+	})
+
+	t.Run("Invalid adjustment", func(t *testing.T) {
+		_, err := NewTransaction(1, "adj-3", string(SourceAdmin), string(StateWin), "5.00", mockTime,
+			WithAdjustment(&Adjustment{Reason: ReasonGoodwill, Operator: "alice"}))
+		assert.ErrorIs(t, err, errs.ErrInvalidAdjustment)
+	})
+}
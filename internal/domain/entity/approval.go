@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// Statuses of transactions held for a second person's approval
+// A held transaction has not touched the balance; it is applied only once it is approved.
+const (
+	StatusPendingApproval TransactionStatus = "pending_approval" // Held until a second person approves or rejects it
+	StatusRejected        TransactionStatus = "rejected"         // Rejected by an approver; the balance was not changed
+	StatusExpired         TransactionStatus = "expired"          // Nobody approved it in time; the balance was not changed
+)
+
+func init() {
+	RegisterTransactionStatus(StatusPendingApproval)
+	RegisterTransactionStatus(StatusRejected)
+	RegisterTransactionStatus(StatusExpired)
+}
+
+// ApprovalAction is a step in the life of a transaction held for approval
+type ApprovalAction string
+
+// String returns the string representation of the approval action
+func (a ApprovalAction) String() string {
+	return string(a)
+}
+
+// Approval actions
+const (
+	ApprovalRequested ApprovalAction = "requested" // The transaction was held for approval
+	ApprovalApproved  ApprovalAction = "approved"  // An approver let it through
+	ApprovalRejected  ApprovalAction = "rejected"  // An approver turned it down
+	ApprovalExpired   ApprovalAction = "expired"   // It waited longer than the approval expiry
+)
+
+// SystemActor is the actor recorded for approval steps the service takes by itself, such as expiry
+const SystemActor = "system"
+
+// ApprovalEvent is one entry of the approval audit trail
+type ApprovalEvent struct {
+	ID            uint64
+	TransactionID string
+	UserID        uint64
+	Action        ApprovalAction
+	Actor         string // Operator, or the account holder for provider transactions, or SystemActor
+	Note          string
+	CreatedAt     time.Time
+}
+
+// NewApprovalEvent creates an audit entry for a step taken on the transaction
+func NewApprovalEvent(txn *Transaction, action ApprovalAction, actor string, note string, timeProvider tport.TimeProvider) *ApprovalEvent {
+	return &ApprovalEvent{
+		TransactionID: txn.TransactionID,
+		UserID:        txn.UserID,
+		Action:        action,
+		Actor:         strings.TrimSpace(actor),
+		Note:          strings.TrimSpace(note),
+		CreatedAt:     timeProvider.Now(),
+	}
+}
+
+// Principal is an authenticated operator deciding on held transactions
+type Principal struct {
+	Name   string // Recorded as the actor of the operator's decisions
+	UserID uint64 // The operator's own account, if they hold one; 0 if not
+}
+
+// AccountRequester returns the requester of transactions a user's account asked for, such as withdrawals
+func AccountRequester(userID uint64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// Requester returns who asked for the transaction: the operator of an adjustment,
+// or the account holder of a provider transaction
+func (t *Transaction) Requester() string {
+	if t.Adjustment != nil {
+		return t.Adjustment.Operator
+	}
+	return AccountRequester(t.UserID)
+}
+
+// IsRequestedBy reports whether the principal is the one who asked for the transaction,
+// either as the operator of an adjustment or as the holder of the account
+func (t *Transaction) IsRequestedBy(principal Principal) bool {
+	requester := strings.TrimSpace(t.Requester())
+	if strings.EqualFold(strings.TrimSpace(principal.Name), requester) {
+		return true
+	}
+	return principal.UserID != 0 && requester == AccountRequester(principal.UserID)
+}
+
+// AwaitsApproval checks if the transaction is held for approval
+func (t *Transaction) AwaitsApproval() bool {
+	return t.Status == StatusPendingApproval
+}
+
+// MarkAsPendingApproval holds the transaction for approval without processing it
+func (t *Transaction) MarkAsPendingApproval() {
+	t.Status = StatusPendingApproval
+}
+
+// MarkAsRejected records that an approver turned the transaction down
+func (t *Transaction) MarkAsRejected(timeProvider tport.TimeProvider, reason string) {
+	now := timeProvider.Now()
+	t.ProcessedAt = &now
+	t.Status = StatusRejected
+	t.ErrorMessage = reason
+}
+
+// MarkAsExpired records that nobody approved the transaction in time
+func (t *Transaction) MarkAsExpired(timeProvider tport.TimeProvider) {
+	now := timeProvider.Now()
+	t.ProcessedAt = &now
+	t.Status = StatusExpired
+	t.ErrorMessage = "Approval request expired"
+}
@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalStatuses(t *testing.T) {
+	mockTime := coremocks.NewMockTimeProvider(t)
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockTime.EXPECT().Now().Return(now).Maybe()
+
+	adj := &Adjustment{Reason: ReasonGoodwill, Note: "outage", Operator: "alice"}
+
+	t.Run("Requester is the operator or the account holder", func(t *testing.T) {
+		tx, err := NewTransaction(1, "adj-1", string(SourceAdmin), string(StateWin), "5.00", mockTime, WithAdjustment(adj))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", tx.Requester())
+		assert.True(t, tx.IsRequestedBy(Principal{Name: " Alice "}))
+		assert.False(t, tx.IsRequestedBy(Principal{Name: "bob"}))
+		// Holding the account does not make an operator the requester of an adjustment
+		assert.False(t, tx.IsRequestedBy(Principal{Name: "bob", UserID: 1}))
+
+		tx, err = NewTransaction(1, "wd-1", string(SourcePayment), string(StateLose), "5.00", mockTime)
+		require.NoError(t, err)
+		assert.Equal(t, "user:1", tx.Requester())
+		assert.True(t, tx.IsRequestedBy(Principal{Name: "bob", UserID: 1}))
+		assert.False(t, tx.IsRequestedBy(Principal{Name: "bob", UserID: 2}))
+		assert.False(t, tx.IsRequestedBy(Principal{Name: "bob"}))
+	})
+
+	t.Run("Held transactions wait and then finish", func(t *testing.T) {
+		tx, err := NewTransaction(1, "wd-2", string(SourcePayment), string(StateLose), "5.00", mockTime)
+		require.NoError(t, err)
+
+		tx.MarkAsPendingApproval()
+		assert.True(t, tx.AwaitsApproval())
+		assert.False(t, tx.IsAlreadyProcessed())
+
+		rejected := tx.Clone()
+		rejected.MarkAsRejected(mockTime, "not today")
+		assert.Equal(t, StatusRejected, rejected.Status)
+		assert.Equal(t, "not today", rejected.ErrorMessage)
+		assert.True(t, rejected.IsAlreadyProcessed())
+		require.NotNil(t, rejected.ProcessedAt)
+
+		expired := tx.Clone()
+		expired.MarkAsExpired(mockTime)
+		assert.Equal(t, StatusExpired, expired.Status)
+		assert.True(t, expired.IsAlreadyProcessed())
+		assert.False(t, expired.AwaitsApproval())
+	})
+
+	t.Run("Events trim the actor and note", func(t *testing.T) {
+		tx, err := NewTransaction(7, "adj-2", string(SourceAdmin), string(StateWin), "5.00", mockTime, WithAdjustment(adj))
+		require.NoError(t, err)
+
+		event := NewApprovalEvent(tx, ApprovalApproved, " bob ", " ok ", mockTime)
+		assert.Equal(t, &ApprovalEvent{
+			TransactionID: "adj-2",
+			UserID:        7,
+			Action:        ApprovalApproved,
+			Actor:         "bob",
+			Note:          "ok",
+			CreatedAt:     now,
+		}, event)
+	})
+}
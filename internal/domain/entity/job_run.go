@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+)
+
+// JobRunStatus represents the outcome of a scheduled job run
+type JobRunStatus string
+
+// Job run statuses
+const (
+	JobRunRunning   JobRunStatus = "running"
+	JobRunSucceeded JobRunStatus = "succeeded"
+	JobRunFailed    JobRunStatus = "failed"
+)
+
+// JobRun records one execution of a scheduled job
+// ScheduledAt identifies the schedule slot; at most one run is recorded per job and slot,
+// no matter how many instances are running the scheduler
+type JobRun struct {
+	ID          uint64
+	JobName     string
+	ScheduledAt time.Time
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	Status      JobRunStatus
+	Error       string
+	Instance    string // Identifies the process that ran the job
+}
+
+// Finish marks the run as finished at the given time with the outcome of err
+func (r *JobRun) Finish(finishedAt time.Time, err error) {
+	r.FinishedAt = &finishedAt
+	if err != nil {
+		r.Status = JobRunFailed
+		r.Error = err.Error()
+		return
+	}
+	r.Status = JobRunSucceeded
+}
+
+// Duration returns how long the run took, or zero if it has not finished
+func (r *JobRun) Duration() time.Duration {
+	if r.FinishedAt == nil {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
@@ -0,0 +1,191 @@
+package entity
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+)
+
+// Currency is the ISO 4217 code of the currency an amount is in
+type Currency string
+
+// DefaultCurrency is the currency balances and transactions are kept in
+// The service runs a single currency, so it is not stored; amounts read from the database or
+// the API are in it. Every supported currency has two decimal places.
+const DefaultCurrency Currency = "EUR"
+
+// String returns the currency code
+func (c Currency) String() string {
+	return string(c)
+}
+
+// Money is an amount of money in cents together with its currency
+// Arithmetic is checked: results that do not fit in int64 cents return ErrAmountOverflow instead of
+// wrapping around, and amounts in different currencies are never combined. The zero value is 0.00
+// in DefaultCurrency. Money is a value type and safe to copy and share between goroutines.
+type Money struct {
+	cents    int64
+	currency Currency // Empty means DefaultCurrency
+}
+
+// NewMoney creates an amount of cents in the given currency
+func NewMoney(cents int64, currency Currency) Money {
+	if currency == DefaultCurrency {
+		currency = ""
+	}
+	return Money{cents: cents, currency: currency}
+}
+
+// MoneyFromCents creates an amount of cents in DefaultCurrency
+func MoneyFromCents(cents int64) Money {
+	return Money{cents: cents}
+}
+
+// ParseMoney parses a non-negative decimal amount such as "10.50" in DefaultCurrency
+// It accepts the same formats as ValidateAndConvertAmount.
+//
+// Possible errors:
+//   - ErrInvalidAmount: the amount is empty, not a decimal number or has more than 2 decimal places
+//   - ErrNegativeAmount: the amount is negative
+//   - ErrAmountOverflow: the amount does not fit in int64 cents
+func ParseMoney(amount string) (Money, error) {
+	cents, err := ValidateAndConvertAmount(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return MoneyFromCents(cents), nil
+}
+
+// Cents returns the amount in cents
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+// Currency returns the currency of the amount
+func (m Money) Currency() Currency {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+// String returns the amount with exactly two decimal places, e.g. "10.50" or "-0.05"
+func (m Money) String() string {
+	return AmountInCentsToString(m.cents)
+}
+
+// IsZero reports whether the amount is 0.00
+func (m Money) IsZero() bool {
+	return m.cents == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.cents < 0
+}
+
+// Add returns the sum of m and other
+//
+// Possible errors:
+//   - ErrCurrencyMismatch: the amounts are in different currencies
+//   - ErrAmountOverflow: the sum does not fit in int64 cents
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum := m.cents + other.cents
+	if (other.cents > 0 && sum < m.cents) || (other.cents < 0 && sum > m.cents) {
+		return Money{}, fmt.Errorf("%w: %s + %s", errs.ErrAmountOverflow, m, other)
+	}
+	return Money{cents: sum, currency: m.currency}, nil
+}
+
+// Sub returns m minus other
+//
+// Possible errors:
+//   - ErrCurrencyMismatch: the amounts are in different currencies
+//   - ErrAmountOverflow: the difference does not fit in int64 cents
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	difference := m.cents - other.cents
+	if (other.cents > 0 && difference > m.cents) || (other.cents < 0 && difference < m.cents) {
+		return Money{}, fmt.Errorf("%w: %s - %s", errs.ErrAmountOverflow, m, other)
+	}
+	return Money{cents: difference, currency: m.currency}, nil
+}
+
+// Neg returns the amount with its sign flipped
+//
+// Possible errors:
+//   - ErrAmountOverflow: the amount is the smallest int64, which has no positive counterpart
+func (m Money) Neg() (Money, error) {
+	return Money{currency: m.currency}.Sub(m)
+}
+
+// checkCurrency returns ErrCurrencyMismatch unless other is in the same currency as m
+func (m Money) checkCurrency(other Money) error {
+	if m.currency != other.currency {
+		return fmt.Errorf("%w: %s and %s", errs.ErrCurrencyMismatch, m.Currency(), other.Currency())
+	}
+	return nil
+}
+
+// Value stores the amount as an integer number of cents
+func (m Money) Value() (driver.Value, error) {
+	return m.cents, nil
+}
+
+// Scan reads an integer number of cents in DefaultCurrency
+// Drivers that return integer columns as text are supported too.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*m = MoneyFromCents(v)
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		cents, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: cannot read %q as cents", errs.ErrInvalidAmount, v)
+		}
+		*m = MoneyFromCents(cents)
+	default:
+		return fmt.Errorf("%w: cannot read %T as cents", errs.ErrInvalidAmount, src)
+	}
+	return nil
+}
+
+// MarshalJSON writes the amount as a string with two decimal places, e.g. "10.50"
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON reads a string amount in DefaultCurrency as ParseMoney does
+// Failures wrap ErrInvalidAmount, except an amount too large to hold, which keeps ErrAmountOverflow,
+// so API clients get the same error code for an amount the request body rejects as for one the
+// validator rejects.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	var amount string
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return fmt.Errorf("%w: amount must be a string, e.g. \"10.50\"", errs.ErrInvalidAmount)
+	}
+	parsed, err := ParseMoney(amount)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidAmount) || errors.Is(err, errs.ErrAmountOverflow) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", errs.ErrInvalidAmount, err)
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_Arithmetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		op        func(a, b Money) (Money, error)
+		a, b      int64
+		want      int64
+		overflows bool
+	}{
+		{"Add", Money.Add, 1050, 250, 1300, false},
+		{"Add negative", Money.Add, 1050, -2000, -950, false},
+		{"Add up to the largest amount", Money.Add, math.MaxInt64 - 1, 1, math.MaxInt64, false},
+		{"Add past the largest amount", Money.Add, math.MaxInt64, 1, 0, true},
+		{"Add past the smallest amount", Money.Add, math.MinInt64, -1, 0, true},
+		{"Sub", Money.Sub, 1050, 250, 800, false},
+		{"Sub below zero", Money.Sub, 250, 1050, -800, false},
+		{"Sub past the smallest amount", Money.Sub, math.MinInt64, 1, 0, true},
+		{"Sub a negative past the largest amount", Money.Sub, math.MaxInt64, -1, 0, true},
+		{"Sub the smallest amount from zero", Money.Sub, 0, math.MinInt64, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.op(MoneyFromCents(tt.a), MoneyFromCents(tt.b))
+			if tt.overflows {
+				assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, MoneyFromCents(tt.want), result)
+		})
+	}
+
+	negated, err := MoneyFromCents(-250).Neg()
+	require.NoError(t, err)
+	assert.Equal(t, "2.50", negated.String())
+	_, err = MoneyFromCents(math.MinInt64).Neg()
+	assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+}
+
+func TestMoney_Currency(t *testing.T) {
+	var zero Money
+	assert.Equal(t, DefaultCurrency, zero.Currency())
+	assert.Equal(t, "0.00", zero.String())
+	assert.Equal(t, MoneyFromCents(100), NewMoney(100, DefaultCurrency), "the default currency is the zero value's")
+
+	usd := NewMoney(100, "USD")
+	assert.Equal(t, Currency("USD"), usd.Currency())
+	_, err := MoneyFromCents(100).Add(usd)
+	assert.ErrorIs(t, err, errs.ErrCurrencyMismatch)
+	_, err = usd.Sub(MoneyFromCents(100))
+	assert.ErrorIs(t, err, errs.ErrCurrencyMismatch)
+
+	sum, err := usd.Add(NewMoney(50, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(150, "USD"), sum)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Money{"amount": MoneyFromCents(1015)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"10.15"}`, string(data))
+
+	var body struct {
+		Amount *Money `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":" 010.5 "}`), &body))
+	assert.Equal(t, MoneyFromCents(1050), *body.Amount)
+
+	for _, input := range []string{`10.15`, `"-1.00"`, `"1.001"`, `"abc"`} {
+		t.Run(input, func(t *testing.T) {
+			var body struct {
+				Amount Money `json:"amount"`
+			}
+			err := json.Unmarshal([]byte(`{"amount":`+input+`}`), &body)
+			assert.ErrorIs(t, err, errs.ErrInvalidAmount)
+		})
+	}
+
+	// An amount too large to hold keeps its own error
+	var overflow struct {
+		Amount Money `json:"amount"`
+	}
+	err = json.Unmarshal([]byte(`{"amount":"92233720368547758.08"}`), &overflow)
+	assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+	assert.NotErrorIs(t, err, errs.ErrInvalidAmount)
+}
+
+func TestMoney_SQL(t *testing.T) {
+	value, err := MoneyFromCents(-1234).Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1234), value)
+
+	for _, src := range []any{int64(1234), []byte("1234"), "1234"} {
+		var m Money
+		require.NoError(t, m.Scan(src))
+		assert.Equal(t, MoneyFromCents(1234), m)
+	}
+
+	var m Money
+	assert.ErrorIs(t, m.Scan("12.34"), errs.ErrInvalidAmount)
+	assert.ErrorIs(t, m.Scan(12.34), errs.ErrInvalidAmount)
+}
@@ -2,7 +2,7 @@ package entity
 
 import (
 	"fmt"
-	"strconv"
+	"math"
 	"strings"
 
 	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
@@ -25,7 +25,8 @@ import (
 //    - Status mutation methods (MarkAsProcessed, MarkAsFailed) modify the Transaction
 //      directly and are NOT thread-safe. Proper synchronization must be handled by callers.
 //
-// 3. Money Utilities:
+// 3. Money and Money Utilities:
+//    - Money is an immutable value; its arithmetic returns new values and is thread-safe.
 //    - All money utility functions are pure functions with no side effects and are thread-safe.
 //
 // For high-volume transaction processing, a synchronization strategy at the repository
@@ -39,6 +40,9 @@ const MaxDecimalPlaces = 2
 // This function handles a variety of input formats and ensures precise money handling.
 //
 // Input requirements:
+//   - ASCII digits with an optional decimal point, e.g. "10", "10.5", "010.50", ".5" or "10."
+//   - Leading and trailing whitespace is ignored; signs, exponents, digit grouping and
+//     non-ASCII digits are rejected
 //   - Must be a non-negative number
 //   - Maximum 2 decimal places allowed
 //   - Must not exceed maximum int64 value when converted to cents
@@ -48,157 +52,120 @@ const MaxDecimalPlaces = 2
 //
 // Returns the amount as int64 cents and error if the validation fails.
 func ValidateAndConvertAmount(amount string) (int64, error) {
-	// Trim whitespace and check for empty string
+	negative, digits, err := parseAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		return 0, errs.ErrNegativeAmount
+	}
+	return digitsToCents(digits, false)
+}
+
+// parseAmount checks the format of an amount and returns its digits in cents
+// A leading "-" is reported as negative rather than rejected, so callers decide whether negative
+// amounts are allowed. The digits may have leading zeros.
+func parseAmount(amount string) (negative bool, digits string, err error) {
 	amount = strings.TrimSpace(amount)
 	if len(amount) == 0 {
-		return 0, fmt.Errorf("%w: empty value", errs.ErrInvalidAmount)
+		return false, "", fmt.Errorf("%w: empty value", errs.ErrInvalidAmount)
 	}
 
 	// Check for negative values
-	if strings.HasPrefix(amount, "-") {
-		return 0, errs.ErrNegativeAmount
+	negative = strings.HasPrefix(amount, "-")
+	if negative {
+		amount = amount[1:]
 	}
 
-	// Process based on presence of decimal point
-	parts := strings.Split(amount, ".")
-
-	if len(parts) > 2 {
-		// Multiple decimal points
-		return 0, fmt.Errorf("%w: invalid number format", errs.ErrInvalidAmount)
+	wholePart, decimalPart, _ := strings.Cut(amount, ".")
+	if !isASCIIDigits(wholePart) || !isASCIIDigits(decimalPart) || wholePart+decimalPart == "" {
+		return false, "", fmt.Errorf("%w: invalid number format", errs.ErrInvalidAmount)
 	}
-
-	var integerValue string
-
-	if len(parts) == 1 {
-		// No decimal point - add ".00"
-		integerValue = parts[0] + "00"
-	} else {
-		// Has decimal point
-		switch len(parts[1]) {
-		case 0:
-			// Like "10." - add "00"
-			integerValue = parts[0] + "00"
-		case 1:
-			// One digit after decimal - add one zero
-			integerValue = parts[0] + parts[1] + "0"
-		case 2:
-			// Two digits after decimal - use as is
-			integerValue = parts[0] + parts[1]
-		default:
-			// More than 2 digits - error
-			return 0, fmt.Errorf("%w: maximum %d decimal places allowed", errs.ErrInvalidAmount, MaxDecimalPlaces)
-		}
+	if len(decimalPart) > MaxDecimalPlaces {
+		return false, "", fmt.Errorf("%w: maximum %d decimal places allowed", errs.ErrInvalidAmount, MaxDecimalPlaces)
 	}
 
-	// Overflow check using mathematical approach
-	// Maximum value for int64 is 9,223,372,036,854,775,807
-	// For money values with 2 decimal places, the maximum safe value would be:
-	// 92,233,720,368,547,758.07
+	// Pad the decimal part to whole cents, e.g. "10.5" becomes "1050"
+	return negative, wholePart + decimalPart + strings.Repeat("0", MaxDecimalPlaces-len(decimalPart)), nil
+}
 
-	// First check: simple length-based pre-check
-	if len(integerValue) > 19 {
-		return 0, errs.ErrAmountOverflow
+// isASCIIDigits reports whether s only contains the digits 0-9; an empty string does
+func isASCIIDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
 	}
+	return true
+}
 
-	// Second check: for values with length 19, need precise comparison
-	if len(integerValue) == 19 {
-		// Define max int64 value as a string
-		maxInt64AsString := "9223372036854775807"
-
-		// Direct string comparison for potential overflow
-		if integerValue > maxInt64AsString {
-			return 0, errs.ErrAmountOverflow
-		}
+// digitsToCents converts the digits of an amount in cents to an int64
+// The value is accumulated digit by digit, so leading zeros never count towards an overflow.
+// A negative amount may reach math.MinInt64.
+func digitsToCents(digits string, negative bool) (int64, error) {
+	limit := uint64(math.MaxInt64)
+	if negative {
+		limit++
 	}
 
-	// Convert to integer
-	value, err := strconv.ParseInt(integerValue, 10, 64)
-	if err != nil {
-		// Handle potential overflow errors from ParseInt
-		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+	var magnitude uint64
+	for i := 0; i < len(digits); i++ {
+		digit := uint64(digits[i] - '0')
+		if magnitude > (limit-digit)/10 {
 			return 0, errs.ErrAmountOverflow
 		}
-		return 0, fmt.Errorf("%w: %s", errs.ErrInvalidAmount, err.Error())
+		magnitude = magnitude*10 + digit
 	}
 
-	return value, nil
+	if negative {
+		return int64(-magnitude), nil
+	}
+	return int64(magnitude), nil
 }
 
 // AmountInCentsToString converts integer amount to a decimal string
 // For example:
 // - 1015 becomes "10.15"
 // - 1000 becomes "10.00"
+// - -1 becomes "-0.01"
 //
 // Side effects: None - this is a pure function.
 // Thread safety: This function is thread-safe as it only performs calculations.
 func AmountInCentsToString(amountInCents int64) string {
-	isNegative := amountInCents < 0
-	if isNegative {
-		amountInCents = -amountInCents
-	}
-
-	amountStr := fmt.Sprintf("%d", amountInCents)
-
-	// Ensure minimum length
-	for len(amountStr) < 3 {
-		amountStr = "0" + amountStr
+	// Work on the magnitude as uint64 so that math.MinInt64 does not overflow when negated
+	magnitude := uint64(amountInCents)
+	sign := ""
+	if amountInCents < 0 {
+		magnitude = -magnitude
+		sign = "-"
 	}
-
-	// Extract decimal parts
-	decimalPos := len(amountStr) - 2
-	wholePart := amountStr[:decimalPos]
-	decimalPart := amountStr[decimalPos:]
-
-	// Handle zero whole part
-	if wholePart == "" {
-		wholePart = "0"
-	}
-
-	// Format with sign
-	if isNegative {
-		return "-" + wholePart + "." + decimalPart
-	}
-	return wholePart + "." + decimalPart
+	return fmt.Sprintf("%s%d.%02d", sign, magnitude/100, magnitude%100)
 }
 
 // EnsureTwoDecimalPlaces validates and standardizes a string representation of money to have exactly 2 decimal places
-// It handles strings with different decimal formats and returns an error if more than 2 decimal places are provided
+// It accepts the same format as ValidateAndConvertAmount and negative amounts, and returns
+// the amount as AmountInCentsToString formats it, e.g. "010.5" becomes "10.50"
 //
 // Side effects: None - this is a pure function.
 // Thread safety: This function is thread-safe as it only performs string operations.
 //
 // Returns the formatted string with exactly 2 decimal places or an error if invalid format is detected
+// An empty or blank string is formatted as "0.00".
 func EnsureTwoDecimalPlaces(amount string) (string, error) {
 	// Handle empty strings
 	if len(strings.TrimSpace(amount)) == 0 {
 		return "0.00", nil
 	}
 
-	// Manual handling to avoid floating-point precision issues
-	parts := strings.Split(amount, ".")
-
-	// No decimal point
-	if len(parts) == 1 {
-		return parts[0] + ".00", nil
+	negative, digits, err := parseAmount(amount)
+	if err != nil {
+		return "", err
 	}
-
-	// Has decimal point
-	wholePart := parts[0]
-	decimalPart := parts[1]
-
-	// Handle different decimal part lengths
-	switch {
-	case len(decimalPart) == 0:
-		return wholePart + ".00", nil
-	case len(decimalPart) == 1:
-		return wholePart + "." + decimalPart + "0", nil
-	case len(decimalPart) == 2:
-		// Already has 2 decimal places, return as is
-		return wholePart + "." + decimalPart, nil
-	default:
-		// More than 2 digits, return error
-		return "", fmt.Errorf("%w: maximum %d decimal places allowed", errs.ErrInvalidAmount, MaxDecimalPlaces)
+	cents, err := digitsToCents(digits, negative)
+	if err != nil {
+		return "", err
 	}
+	return AmountInCentsToString(cents), nil
 }
 
 // ValidateDecimalPlaces checks if a string amount has more than MaxDecimalPlaces decimal places
@@ -207,12 +174,12 @@ func EnsureTwoDecimalPlaces(amount string) (string, error) {
 // Side effects: None - this is a pure function.
 // Thread safety: This function is thread-safe as it only performs string operations.
 func ValidateDecimalPlaces(amount string) bool {
-	parts := strings.Split(amount, ".")
-	if len(parts) < 2 {
+	_, decimalPart, found := strings.Cut(strings.TrimSpace(amount), ".")
+	if !found {
 		// No decimal point
 		return true
 	}
 
 	// Check decimal part length
-	return len(parts[1]) <= MaxDecimalPlaces
+	return len(decimalPart) <= MaxDecimalPlaces
 }
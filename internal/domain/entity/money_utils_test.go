@@ -1,10 +1,17 @@
 package entity
 
 import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
 	"testing"
+	"testing/quick"
 
 	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateAndConvertAmount(t *testing.T) {
@@ -161,3 +168,196 @@ func TestEnsureTwoDecimalPlaces(t *testing.T) {
 		}
 	})
 }
+
+func TestAmountFormats(t *testing.T) {
+	testCases := []struct {
+		input     string
+		cents     int64
+		formatted string
+		err       error
+	}{
+		{input: "01.5", cents: 150, formatted: "1.50"},
+		{input: "000.01", cents: 1, formatted: "0.01"},
+		{input: ".5", cents: 50, formatted: "0.50"},
+		{input: "10.", cents: 1000, formatted: "10.00"},
+		{input: " \t1.5\n", cents: 150, formatted: "1.50"},
+		{input: "\u00a01\u2003", cents: 100, formatted: "1.00"},
+		{input: "00000000000000000000000001.00", cents: 100, formatted: "1.00"},
+		{input: "92233720368547758.07", cents: math.MaxInt64, formatted: "92233720368547758.07"},
+		{input: "92233720368547758.08", err: errs.ErrAmountOverflow},
+		{input: "99999999999999999999", err: errs.ErrAmountOverflow},
+		{input: ".", err: errs.ErrInvalidAmount},
+		{input: "+1", err: errs.ErrInvalidAmount},
+		{input: "1e5", err: errs.ErrInvalidAmount},
+		{input: "0x10", err: errs.ErrInvalidAmount},
+		{input: "1_000", err: errs.ErrInvalidAmount},
+		{input: "1 000", err: errs.ErrInvalidAmount},
+		{input: "1.2.3", err: errs.ErrInvalidAmount},
+		{input: "١٢", err: errs.ErrInvalidAmount},
+		{input: "１２", err: errs.ErrInvalidAmount},
+		{input: "--1", err: errs.ErrInvalidAmount},
+		{input: "-abc", err: errs.ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			cents, err := ValidateAndConvertAmount(tc.input)
+			formatted, formatErr := EnsureTwoDecimalPlaces(tc.input)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.ErrorIs(t, formatErr, tc.err, "both functions reject the input the same way")
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, formatErr)
+			assert.Equal(t, tc.cents, cents)
+			assert.Equal(t, tc.formatted, formatted)
+		})
+	}
+
+	t.Run("Negative amounts are refused but can be formatted", func(t *testing.T) {
+		_, err := ValidateAndConvertAmount("-0.5")
+		assert.ErrorIs(t, err, errs.ErrNegativeAmount)
+
+		formatted, err := EnsureTwoDecimalPlaces("-0.5")
+		require.NoError(t, err)
+		assert.Equal(t, "-0.50", formatted)
+
+		formatted, err = EnsureTwoDecimalPlaces("-92233720368547758.08")
+		require.NoError(t, err)
+		assert.Equal(t, AmountInCentsToString(math.MinInt64), formatted)
+	})
+}
+
+func TestAmountRoundTripProperties(t *testing.T) {
+	// Every non-negative number of cents survives formatting and parsing
+	roundTrip := func(cents int64) bool {
+		if cents < 0 {
+			cents = -(cents + 1)
+		}
+		parsed, err := ValidateAndConvertAmount(AmountInCentsToString(cents))
+		return err == nil && parsed == cents
+	}
+	require.NoError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 10000}))
+
+	// Formatting is idempotent and keeps the sign
+	idempotent := func(cents int64) bool {
+		formatted := AmountInCentsToString(cents)
+		again, err := EnsureTwoDecimalPlaces(formatted)
+		return err == nil && again == formatted && strings.HasPrefix(formatted, "-") == (cents < 0)
+	}
+	require.NoError(t, quick.Check(idempotent, &quick.Config{MaxCount: 10000}))
+
+	// Any amount with at most two decimal places parses to whole plus decimal cents
+	parse := func(whole uint32, decimal uint8, places uint8) bool {
+		decimal %= 100
+		amount := fmt.Sprintf("%d.%02d", whole, decimal)
+		if places%3 == 1 && decimal%10 == 0 {
+			amount = fmt.Sprintf("%d.%d", whole, decimal/10)
+		} else if places%3 == 2 && decimal == 0 {
+			amount = fmt.Sprintf("%d", whole)
+		}
+		cents, err := ValidateAndConvertAmount(amount)
+		return err == nil && cents == int64(whole)*100+int64(decimal)
+	}
+	require.NoError(t, quick.Check(parse, &quick.Config{MaxCount: 10000}))
+}
+
+// amountPattern is the accepted amount format: ASCII digits with at most two decimal places
+var amountPattern = regexp.MustCompile(`^([0-9]*)(?:\.([0-9]{0,2}))?$`)
+
+// referenceCents parses an amount with math/big, without any overflow
+// ok is false when the amount is not in the accepted format.
+func referenceCents(amount string) (cents *big.Int, ok bool) {
+	trimmed := strings.TrimSpace(amount)
+	match := amountPattern.FindStringSubmatch(trimmed)
+	if match == nil || match[1]+match[2] == "" {
+		return nil, false
+	}
+	cents, _ = new(big.Int).SetString(match[1]+match[2]+strings.Repeat("0", 2-len(match[2])), 10)
+	return cents, true
+}
+
+// amountSeeds are inputs the parsers used to treat inconsistently
+var amountSeeds = []string{
+	"0", "1", "1.5", "10.", ".5", ".", "01.5", "000.01", "1e5", "1E2", "+1", "-0", "--1", "-abc", "1.2.3",
+	"1,000", " 1 ", "\t1.5\n", " 1.00", "1 2", "١٢", "１２", "0x10", "1_000",
+	"92233720368547758.07", "92233720368547758.08", "92233720368547758.1", "00000000000000000000000001.00",
+	"99999999999999999999",
+}
+
+func FuzzValidateAndConvertAmount(f *testing.F) {
+	for _, seed := range amountSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, amount string) {
+		cents, err := ValidateAndConvertAmount(amount)
+		want, ok := referenceCents(amount)
+
+		switch {
+		case !ok:
+			require.Error(t, err, "%q is not an amount", amount)
+			trimmed := strings.TrimSpace(amount)
+			rest, hasSign := strings.CutPrefix(trimmed, "-")
+			if negated, negative := referenceCents(rest); negative && hasSign && strings.TrimSpace(rest) == rest {
+				// Negative amounts are refused, but EnsureTwoDecimalPlaces formats them
+				assert.ErrorIs(t, err, errs.ErrNegativeAmount)
+				formatted, err := EnsureTwoDecimalPlaces(amount)
+				if negated.Neg(negated).IsInt64() {
+					require.NoError(t, err)
+					assert.Equal(t, AmountInCentsToString(negated.Int64()), formatted)
+				} else {
+					assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+				}
+				return
+			}
+			assert.ErrorIs(t, err, errs.ErrInvalidAmount)
+			if trimmed != "" {
+				_, err = EnsureTwoDecimalPlaces(amount)
+				assert.ErrorIs(t, err, errs.ErrInvalidAmount, "EnsureTwoDecimalPlaces accepted %q", amount)
+			}
+		case !want.IsInt64():
+			assert.ErrorIs(t, err, errs.ErrAmountOverflow, "%q overflows", amount)
+			_, err = EnsureTwoDecimalPlaces(amount)
+			assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+		default:
+			require.NoError(t, err, "%q is an amount", amount)
+			assert.Equal(t, want.Int64(), cents)
+
+			// The canonical form parses back to the same amount
+			canonical := AmountInCentsToString(cents)
+			again, err := ValidateAndConvertAmount(canonical)
+			require.NoError(t, err)
+			assert.Equal(t, cents, again)
+
+			formatted, err := EnsureTwoDecimalPlaces(amount)
+			require.NoError(t, err)
+			assert.Equal(t, canonical, formatted)
+		}
+	})
+}
+
+func FuzzAmountInCentsToString(f *testing.F) {
+	for _, seed := range []int64{0, 1, -1, 99, 100, -100, math.MaxInt64, math.MinInt64, math.MinInt64 + 1} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, cents int64) {
+		formatted := AmountInCentsToString(cents)
+		require.Regexp(t, `^-?(0|[1-9][0-9]*)\.[0-9]{2}$`, formatted)
+
+		// Formatting is exact: the string holds the same number of cents
+		parsed, ok := new(big.Int).SetString(strings.Replace(formatted, ".", "", 1), 10)
+		require.True(t, ok)
+		assert.Equal(t, big.NewInt(cents), parsed)
+
+		again, err := EnsureTwoDecimalPlaces(formatted)
+		require.NoError(t, err)
+		assert.Equal(t, formatted, again)
+
+		if cents >= 0 {
+			back, err := ValidateAndConvertAmount(formatted)
+			require.NoError(t, err)
+			assert.Equal(t, cents, back)
+		}
+	})
+}
@@ -22,7 +22,7 @@ import (
 //   - Function options pattern for optional configuration
 //   - Consistent error handling with domain-specific errors
 //
-// All monetary values are stored as integers (cents) to avoid floating-point precision issues.
+// All monetary values are Money, which keeps integer cents to avoid floating-point precision issues.
 
 // EnumConstraint is a constraint for enum types
 type EnumConstraint interface {
@@ -245,17 +245,18 @@ func ParseTransactionStatus(status string) (TransactionStatus, error) {
 // Methods like MarkAsProcessed and MarkAsFailed modify the transaction state directly
 // and require external synchronization when used in concurrent scenarios.
 type Transaction struct {
-	ID                   uint64            // Unique identifier for the transaction
-	UserID               uint64            // ID of the user this transaction belongs to
-	TransactionID        string            // Unique external transaction identifier
-	SourceType           SourceType        // Source of the transaction
-	State                TransactionState  // State of the transaction (win/lose)
-	AmountInCents        int64             // Amount converted to cents for precise calculations
-	CreatedAt            time.Time         // When the transaction was created
-	ProcessedAt          *time.Time        // When the transaction was processed (nullable)
-	ResultBalanceInCents int64             // Balance after this transaction was processed, in cents
-	Status               TransactionStatus // Status of the transaction
-	ErrorMessage         string            // Error message if transaction failed
+	ID            uint64            // Unique identifier for the transaction
+	UserID        uint64            // ID of the user this transaction belongs to
+	TransactionID string            // Unique external transaction identifier
+	SourceType    SourceType        // Source of the transaction
+	State         TransactionState  // State of the transaction (win/lose)
+	Amount        Money             // Amount of the transaction; never negative
+	CreatedAt     time.Time         // When the transaction was created
+	ProcessedAt   *time.Time        // When the transaction was processed (nullable)
+	ResultBalance Money             // Balance after this transaction was processed
+	Status        TransactionStatus // Status of the transaction
+	ErrorMessage  string            // Error message if transaction failed
+	Adjustment    *Adjustment       // Why and by whom the balance was adjusted; set only for admin transactions
 }
 
 // TransactionOption is a functional option for configuring a Transaction
@@ -300,7 +301,7 @@ func NewTransaction(
 	}
 
 	// Parse and validate amount (ensuring it's a valid money format)
-	amountInCents, err := ValidateAndConvertAmount(amount)
+	parsedAmount, err := ParseMoney(amount)
 	if err != nil {
 		return nil, err
 	}
@@ -311,7 +312,7 @@ func NewTransaction(
 		TransactionID: transactionID,
 		SourceType:    parsedSourceType,
 		State:         parsedState,
-		AmountInCents: amountInCents,
+		Amount:        parsedAmount,
 		CreatedAt:     timeProvider.Now(),
 		Status:        StatusPending,
 	}
@@ -323,15 +324,20 @@ func NewTransaction(
 		}
 	}
 
+	// Admin transactions, and only those, must say why they were made and by whom
+	if err := ValidateAdjustment(txn.SourceType, txn.Adjustment); err != nil {
+		return nil, err
+	}
+
 	return txn, nil
 }
 
 // MarkAsProcessed updates the transaction status to completed with the resulting balance
-func (t *Transaction) MarkAsProcessed(timeProvider tport.TimeProvider, resultBalanceInCents int64) {
+func (t *Transaction) MarkAsProcessed(timeProvider tport.TimeProvider, resultBalance Money) {
 	now := timeProvider.Now()
 	t.ProcessedAt = &now
 	t.Status = StatusCompleted
-	t.ResultBalanceInCents = resultBalanceInCents
+	t.ResultBalance = resultBalance
 }
 
 // MarkAsFailed updates the transaction status to failed with an error message
@@ -344,12 +350,12 @@ func (t *Transaction) MarkAsFailed(timeProvider tport.TimeProvider, errorMessage
 
 // GetAmount returns the transaction amount as a formatted string
 func (t *Transaction) GetAmount() string {
-	return AmountInCentsToString(t.AmountInCents)
+	return t.Amount.String()
 }
 
 // GetResultBalance returns the result balance as a formatted string
 func (t *Transaction) GetResultBalance() string {
-	return AmountInCentsToString(t.ResultBalanceInCents)
+	return t.ResultBalance.String()
 }
 
 // IsCredit checks if the transaction is a credit transaction (increases balance)
@@ -362,9 +368,34 @@ func (t *Transaction) IsDebit() bool {
 	return t.State.GetBalanceEffect() == EffectDecrease
 }
 
+// BalanceChange returns the signed change the transaction makes to the balance
+func (t *Transaction) BalanceChange() Money {
+	if t.IsCredit() {
+		return t.Amount
+	}
+	// Amounts are never negative, so negating one cannot overflow
+	return NewMoney(-t.Amount.Cents(), t.Amount.Currency())
+}
+
+// BookedAt returns when the transaction was settled, or when it was received if it has not been
+// Completed transactions are booked in the order they changed the balance, which for transactions
+// held for approval is later than they were received.
+func (t *Transaction) BookedAt() time.Time {
+	if t.ProcessedAt != nil {
+		return *t.ProcessedAt
+	}
+	return t.CreatedAt
+}
+
 // IsAlreadyProcessed checks if the transaction has already been processed
+// Rejected and expired transactions are final too, although they never touched the balance.
 func (t *Transaction) IsAlreadyProcessed() bool {
-	return t.Status == StatusCompleted || t.Status == StatusFailed
+	switch t.Status {
+	case StatusCompleted, StatusFailed, StatusRejected, StatusExpired:
+		return true
+	default:
+		return false
+	}
 }
 
 // IsFailed checks if the transaction has failed
@@ -398,6 +429,10 @@ func (t *Transaction) Clone() *Transaction {
 		processedAt := *t.ProcessedAt
 		clone.ProcessedAt = &processedAt
 	}
+	if t.Adjustment != nil {
+		adjustment := *t.Adjustment
+		clone.Adjustment = &adjustment
+	}
 
 	return &clone
 }
@@ -30,7 +30,7 @@ func TestNewTransaction(t *testing.T) {
 		assert.Equal(t, "tx123", tx.TransactionID)
 		assert.Equal(t, SourceGame, tx.SourceType)
 		assert.Equal(t, StateWin, tx.State)
-		assert.Equal(t, int64(10000), tx.AmountInCents)
+		assert.Equal(t, MoneyFromCents(10000), tx.Amount)
 		assert.Equal(t, fixedTime, tx.CreatedAt)
 		assert.Nil(t, tx.ProcessedAt)
 		assert.Equal(t, StatusPending, tx.Status)
@@ -185,18 +185,18 @@ func TestTransactionMarkAsProcessed(t *testing.T) {
 
 	// Initial state
 	assert.Nil(t, tx.ProcessedAt)
-	assert.Equal(t, int64(0), tx.ResultBalanceInCents)
+	assert.Equal(t, MoneyFromCents(0), tx.ResultBalance)
 	assert.Equal(t, StatusPending, tx.Status)
 
 	// Mark as processed
 	mockProcessTime := coremocks.NewMockTimeProvider(t)
 	mockProcessTime.EXPECT().Now().Return(processTime).Once()
-	tx.MarkAsProcessed(mockProcessTime, 20000) // 200.00 in cents
+	tx.MarkAsProcessed(mockProcessTime, MoneyFromCents(20000)) // 200.00 in cents
 
 	// Check updated state
 	require.NotNil(t, tx.ProcessedAt)
 	assert.Equal(t, processTime, *tx.ProcessedAt)
-	assert.Equal(t, int64(20000), tx.ResultBalanceInCents)
+	assert.Equal(t, MoneyFromCents(20000), tx.ResultBalance)
 	assert.Equal(t, "200.00", tx.GetResultBalance())
 	assert.Equal(t, StatusCompleted, tx.Status)
 }
@@ -246,12 +246,14 @@ func TestTransactionIsCreditDebit(t *testing.T) {
 		tx, _ := NewTransaction(1, "tx1", string(SourceGame), string(StateWin), "100.00", mockTime)
 		assert.True(t, tx.IsCredit())
 		assert.False(t, tx.IsDebit())
+		assert.Equal(t, MoneyFromCents(10000), tx.BalanceChange())
 	})
 
 	t.Run("Lose transaction is debit", func(t *testing.T) {
 		tx, _ := NewTransaction(1, "tx2", string(SourceGame), string(StateLose), "50.00", mockTime)
 		assert.False(t, tx.IsCredit())
 		assert.True(t, tx.IsDebit())
+		assert.Equal(t, MoneyFromCents(-5000), tx.BalanceChange())
 	})
 }
 
@@ -267,7 +269,7 @@ func TestTransactionGetters(t *testing.T) {
 
 		mockProcessTime := coremocks.NewMockTimeProvider(t)
 		mockProcessTime.EXPECT().Now().Return(fixedTime).Once()
-		tx.MarkAsProcessed(mockProcessTime, 67890) // 678.90 in cents
+		tx.MarkAsProcessed(mockProcessTime, MoneyFromCents(67890)) // 678.90 in cents
 
 		assert.Equal(t, "678.90", tx.GetResultBalance())
 	})
@@ -290,7 +292,7 @@ func TestTransactionStatusChecks(t *testing.T) {
 
 		mockProcessTime := coremocks.NewMockTimeProvider(t)
 		mockProcessTime.EXPECT().Now().Return(fixedTime).Once()
-		tx.MarkAsProcessed(mockProcessTime, 20000)
+		tx.MarkAsProcessed(mockProcessTime, MoneyFromCents(20000))
 
 		assert.True(t, tx.IsAlreadyProcessed())
 		assert.False(t, tx.IsPending())
@@ -323,7 +325,7 @@ func TestTransactionClone(t *testing.T) {
 
 	mockProcessTime := coremocks.NewMockTimeProvider(t)
 	mockProcessTime.EXPECT().Now().Return(processTime).Once()
-	tx.MarkAsProcessed(mockProcessTime, 12345)
+	tx.MarkAsProcessed(mockProcessTime, MoneyFromCents(12345))
 
 	// Create clone
 	clone := tx.Clone()
@@ -334,9 +336,9 @@ func TestTransactionClone(t *testing.T) {
 	assert.Equal(t, tx.TransactionID, clone.TransactionID)
 	assert.Equal(t, tx.SourceType, clone.SourceType)
 	assert.Equal(t, tx.State, clone.State)
-	assert.Equal(t, tx.AmountInCents, clone.AmountInCents)
+	assert.Equal(t, tx.Amount, clone.Amount)
 	assert.Equal(t, tx.CreatedAt, clone.CreatedAt)
-	assert.Equal(t, tx.ResultBalanceInCents, clone.ResultBalanceInCents)
+	assert.Equal(t, tx.ResultBalance, clone.ResultBalance)
 	assert.Equal(t, tx.Status, clone.Status)
 	assert.Equal(t, tx.ErrorMessage, clone.ErrorMessage)
 
@@ -453,18 +455,22 @@ func TestEnumRegistry(t *testing.T) {
 
 	t.Run("Registry contains all source types", func(t *testing.T) {
 		values := SourceGame.Values()
-		assert.Len(t, values, 3)
+		assert.Len(t, values, 4)
 		assert.Contains(t, values, SourceGame)
 		assert.Contains(t, values, SourceServer)
 		assert.Contains(t, values, SourcePayment)
+		assert.Contains(t, values, SourceAdmin)
 	})
 
 	t.Run("Registry contains all statuses", func(t *testing.T) {
 		values := StatusPending.Values()
-		assert.Len(t, values, 3)
+		assert.Len(t, values, 6)
 		assert.Contains(t, values, StatusPending)
 		assert.Contains(t, values, StatusCompleted)
 		assert.Contains(t, values, StatusFailed)
+		assert.Contains(t, values, StatusPendingApproval)
+		assert.Contains(t, values, StatusRejected)
+		assert.Contains(t, values, StatusExpired)
 	})
 
 	t.Run("Can register new values", func(t *testing.T) {
@@ -10,7 +10,7 @@ import (
 // User represents a user entity with a balance
 type User struct {
 	ID               uint64    // Unique identifier for the user
-	balance          int64     // Balance stored in cents to avoid floating point precision issues (private)
+	balance          Money     // Balance kept in cents to avoid floating point precision issues (private)
 	CreatedAt        time.Time // When the user was created
 	UpdatedAt        time.Time // When the user was last updated
 	TransactionCount uint64    // Count of transactions processed for this user
@@ -22,7 +22,7 @@ func NewUser(id uint64, initialBalance string, timeProvider coreport.TimeProvide
 		return nil, errs.ErrInvalidUserID
 	}
 
-	balanceInCents, err := ValidateAndConvertAmount(initialBalance)
+	balance, err := ParseMoney(initialBalance)
 	if err != nil {
 		return nil, err
 	}
@@ -30,32 +30,26 @@ func NewUser(id uint64, initialBalance string, timeProvider coreport.TimeProvide
 	now := timeProvider.Now()
 	return &User{
 		ID:               id,
-		balance:          balanceInCents,
+		balance:          balance,
 		CreatedAt:        now,
 		UpdatedAt:        now,
 		TransactionCount: 0,
 	}, nil
 }
 
-// Balance returns the current balance in cents (for internal use)
-func (u *User) Balance() int64 {
+// Balance returns the current balance (for internal use)
+func (u *User) Balance() Money {
 	return u.balance
 }
 
 // GetBalance returns the balance as a string with 2 decimal places
 func (u *User) GetBalance() string {
-	// Convert balance to string format
-	balanceStr := AmountInCentsToString(u.balance)
-
-	// We can safely ignore error here because AmountInCentsToString always produces
-	// a valid string with exactly 2 decimal places
-	formattedBalance, _ := EnsureTwoDecimalPlaces(balanceStr)
-	return formattedBalance
+	return u.balance.String()
 }
 
 // SetBalance updates the balance directly (for internal use, like repositories)
-func (u *User) SetBalance(balanceInCents int64, timeProvider coreport.TimeProvider) {
-	u.balance = balanceInCents
+func (u *User) SetBalance(balance Money, timeProvider coreport.TimeProvider) {
+	u.balance = balance
 	u.UpdatedAt = timeProvider.Now()
 }
 
@@ -66,29 +60,40 @@ func (u *User) IncrementTransactionCount() {
 
 // CanDeduct checks if the user has enough balance for a deduction
 func (u *User) CanDeduct(amount string) (bool, error) {
-	amountInCents, err := ValidateAndConvertAmount(amount)
+	deduction, err := ParseMoney(amount)
 	if err != nil {
 		return false, err
 	}
 
-	return u.balance >= amountInCents, nil
+	return u.balance.Cents() >= deduction.Cents(), nil
 }
 
 // ApplyWinTransaction adds the amount to the balance
-func (u *User) ApplyWinTransaction(amountInCents int64, timeProvider coreport.TimeProvider) {
-	u.balance += amountInCents
+// Returns ErrAmountOverflow, leaving the user unchanged, if the balance would not fit in int64 cents
+func (u *User) ApplyWinTransaction(amount Money, timeProvider coreport.TimeProvider) error {
+	balance, err := u.balance.Add(amount)
+	if err != nil {
+		return err
+	}
+
+	u.balance = balance
 	u.UpdatedAt = timeProvider.Now()
 	u.IncrementTransactionCount()
+	return nil
 }
 
 // ApplyLoseTransaction subtracts the amount from balance if sufficient balance exists
 // Returns error if insufficient balance
-func (u *User) ApplyLoseTransaction(amountInCents int64, timeProvider coreport.TimeProvider) error {
-	if u.balance < amountInCents {
+func (u *User) ApplyLoseTransaction(amount Money, timeProvider coreport.TimeProvider) error {
+	balance, err := u.balance.Sub(amount)
+	if err != nil {
+		return err
+	}
+	if balance.IsNegative() {
 		return errs.ErrInsufficientBalance
 	}
 
-	u.balance -= amountInCents
+	u.balance = balance
 	u.UpdatedAt = timeProvider.Now()
 	u.IncrementTransactionCount()
 	return nil
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserLock describes a lock currently held on a user, as reported to operators
+type UserLock struct {
+	UserID    uint64
+	Holder    string // Identifies the holder: a token prefix for lock rows, a database session for advisory locks
+	LockedAt  time.Time
+	ExpiresAt time.Time // Zero if the lease is only tracked by the holding process
+}
+
+// IsExpired reports whether the lock's lease has run out at the given time
+// Expired locks no longer block acquisition; they only wait to be cleaned up
+func (l *UserLock) IsExpired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
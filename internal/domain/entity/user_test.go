@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -20,7 +21,7 @@ func TestNewUser(t *testing.T) {
 
 		require.NoError(t, err)
 		assert.Equal(t, uint64(1), user.ID)
-		assert.Equal(t, int64(10000), user.Balance())
+		assert.Equal(t, MoneyFromCents(10000), user.Balance())
 		assert.Equal(t, "100.00", user.GetBalance())
 		assert.Equal(t, fixedTime, user.CreatedAt)
 		assert.Equal(t, fixedTime, user.UpdatedAt)
@@ -56,7 +57,7 @@ func TestNewUser(t *testing.T) {
 		user, err := NewUser(1, "9999999999.99", mockTime)
 
 		require.NoError(t, err)
-		assert.Equal(t, int64(999999999999), user.Balance())
+		assert.Equal(t, MoneyFromCents(999999999999), user.Balance())
 		assert.Equal(t, "9999999999.99", user.GetBalance())
 	})
 }
@@ -71,9 +72,9 @@ func TestUserSetBalance(t *testing.T) {
 	user, _ := NewUser(1, "100.00", mockTime)
 
 	mockTime.EXPECT().Now().Return(updateTime).Once()
-	user.SetBalance(20000, mockTime)
+	user.SetBalance(MoneyFromCents(20000), mockTime)
 
-	assert.Equal(t, int64(20000), user.Balance())
+	assert.Equal(t, MoneyFromCents(20000), user.Balance())
 	assert.Equal(t, "200.00", user.GetBalance())
 	assert.Equal(t, initialTime, user.CreatedAt)
 	assert.Equal(t, updateTime, user.UpdatedAt)
@@ -137,27 +138,45 @@ func TestApplyWinTransaction(t *testing.T) {
 	user, _ := NewUser(1, "100.00", mockTime)
 
 	mockTime.EXPECT().Now().Return(updateTime).Once()
-	user.ApplyWinTransaction(5000, mockTime)
+	assert.NoError(t, user.ApplyWinTransaction(MoneyFromCents(5000), mockTime))
 
-	assert.Equal(t, int64(15000), user.Balance())
+	assert.Equal(t, MoneyFromCents(15000), user.Balance())
 	assert.Equal(t, "150.00", user.GetBalance())
 	assert.Equal(t, uint64(1), user.TransactionCount)
 	assert.Equal(t, updateTime, user.UpdatedAt)
 
 	// Test with zero amount
 	mockTime.EXPECT().Now().Return(updateTime).Once()
-	user.ApplyWinTransaction(0, mockTime)
-	assert.Equal(t, int64(15000), user.Balance())
+	assert.NoError(t, user.ApplyWinTransaction(MoneyFromCents(0), mockTime))
+	assert.Equal(t, MoneyFromCents(15000), user.Balance())
 	assert.Equal(t, uint64(2), user.TransactionCount)
 
 	// Test with large amount
 	mockTime.EXPECT().Now().Return(updateTime).Once()
-	user.ApplyWinTransaction(1000000, mockTime)
-	assert.Equal(t, int64(1015000), user.Balance())
+	assert.NoError(t, user.ApplyWinTransaction(MoneyFromCents(1000000), mockTime))
+	assert.Equal(t, MoneyFromCents(1015000), user.Balance())
 	assert.Equal(t, "10150.00", user.GetBalance())
 	assert.Equal(t, uint64(3), user.TransactionCount)
 }
 
+func TestApplyWinTransaction_Overflow(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockTime := coremocks.NewMockTimeProvider(t)
+	mockTime.EXPECT().Now().Return(fixedTime).Maybe()
+
+	user, err := NewUser(1, "92233720368547758.00", mockTime)
+	require.NoError(t, err)
+
+	// A win the balance cannot hold is refused instead of wrapping it negative
+	err = user.ApplyWinTransaction(MoneyFromCents(8), mockTime)
+	assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+	assert.Equal(t, "92233720368547758.00", user.GetBalance())
+	assert.Equal(t, uint64(0), user.TransactionCount)
+
+	require.NoError(t, user.ApplyWinTransaction(MoneyFromCents(7), mockTime))
+	assert.Equal(t, MoneyFromCents(math.MaxInt64), user.Balance())
+}
+
 func TestApplyLoseTransaction(t *testing.T) {
 	initialTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
 	updateTime := time.Date(2023, 1, 1, 13, 0, 0, 0, time.UTC)
@@ -171,10 +190,10 @@ func TestApplyLoseTransaction(t *testing.T) {
 		mockTimeLocal := coremocks.NewMockTimeProvider(t)
 		mockTimeLocal.EXPECT().Now().Return(updateTime).Once()
 
-		err := user.ApplyLoseTransaction(5000, mockTimeLocal)
+		err := user.ApplyLoseTransaction(MoneyFromCents(5000), mockTimeLocal)
 
 		assert.NoError(t, err)
-		assert.Equal(t, int64(5000), user.Balance())
+		assert.Equal(t, MoneyFromCents(5000), user.Balance())
 		assert.Equal(t, "50.00", user.GetBalance())
 		assert.Equal(t, uint64(1), user.TransactionCount)
 		assert.Equal(t, updateTime, user.UpdatedAt)
@@ -184,19 +203,19 @@ func TestApplyLoseTransaction(t *testing.T) {
 		mockTimeLocal := coremocks.NewMockTimeProvider(t)
 		mockTimeLocal.EXPECT().Now().Return(updateTime).Once()
 
-		err := user.ApplyLoseTransaction(5000, mockTimeLocal)
+		err := user.ApplyLoseTransaction(MoneyFromCents(5000), mockTimeLocal)
 
 		assert.NoError(t, err)
-		assert.Equal(t, int64(0), user.Balance())
+		assert.Equal(t, MoneyFromCents(0), user.Balance())
 		assert.Equal(t, "0.00", user.GetBalance())
 		assert.Equal(t, uint64(2), user.TransactionCount)
 	})
 
 	t.Run("Insufficient balance", func(t *testing.T) {
-		err := user.ApplyLoseTransaction(1, mockTime)
+		err := user.ApplyLoseTransaction(MoneyFromCents(1), mockTime)
 
 		assert.Equal(t, errs.ErrInsufficientBalance, err)
-		assert.Equal(t, int64(0), user.Balance())
+		assert.Equal(t, MoneyFromCents(0), user.Balance())
 		assert.Equal(t, uint64(2), user.TransactionCount)
 	})
 
@@ -207,10 +226,10 @@ func TestApplyLoseTransaction(t *testing.T) {
 		user, _ := NewUser(2, "100.00", mockTimeLocal)
 
 		mockTimeLocal.EXPECT().Now().Return(updateTime).Once()
-		err := user.ApplyLoseTransaction(0, mockTimeLocal)
+		err := user.ApplyLoseTransaction(MoneyFromCents(0), mockTimeLocal)
 
 		assert.NoError(t, err)
-		assert.Equal(t, int64(10000), user.Balance())
+		assert.Equal(t, MoneyFromCents(10000), user.Balance())
 		assert.Equal(t, uint64(1), user.TransactionCount)
 	})
 }
@@ -223,24 +242,24 @@ func TestIntegrationOfTransactions(t *testing.T) {
 	user, _ := NewUser(1, "100.00", mockTime)
 
 	// Series of transactions
-	user.ApplyWinTransaction(5000, mockTime)         // +50.00
-	err := user.ApplyLoseTransaction(2000, mockTime) // -20.00
+	assert.NoError(t, user.ApplyWinTransaction(MoneyFromCents(5000), mockTime)) // +50.00
+	err := user.ApplyLoseTransaction(MoneyFromCents(2000), mockTime)            // -20.00
 	require.NoError(t, err)
-	user.ApplyWinTransaction(1000, mockTime)        // +10.00
-	err = user.ApplyLoseTransaction(3000, mockTime) // -30.00
+	assert.NoError(t, user.ApplyWinTransaction(MoneyFromCents(1000), mockTime)) // +10.00
+	err = user.ApplyLoseTransaction(MoneyFromCents(3000), mockTime)             // -30.00
 	require.NoError(t, err)
 
 	// Final balance should be 100 + 50 - 20 + 10 - 30 = 110
-	assert.Equal(t, int64(11000), user.Balance())
+	assert.Equal(t, MoneyFromCents(11000), user.Balance())
 	assert.Equal(t, "110.00", user.GetBalance())
 	assert.Equal(t, uint64(4), user.TransactionCount)
 
 	// Check edge case with exact deduction
-	err = user.ApplyLoseTransaction(11000, mockTime)
+	err = user.ApplyLoseTransaction(MoneyFromCents(11000), mockTime)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(0), user.Balance())
+	assert.Equal(t, MoneyFromCents(0), user.Balance())
 
 	// Now should fail
-	err = user.ApplyLoseTransaction(1, mockTime)
+	err = user.ApplyLoseTransaction(MoneyFromCents(1), mockTime)
 	assert.Equal(t, errs.ErrInsufficientBalance, err)
 }
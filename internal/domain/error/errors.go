@@ -14,11 +14,25 @@ const (
 	CodeDuplicateTransaction = 4004
 	CodeConstraintViolation  = 4005
 	CodeAmountOverflow       = 4006
+	CodeInvalidAdjustment    = 4007
+	CodeAmountBelowMinimum   = 4008
+	CodeAmountAboveMaximum   = 4009
+	CodeBalanceLimitExceeded = 4010
+	CodeSelfApproval         = 4031
 	CodeUserNotFound         = 4040
+	CodeTransactionNotFound  = 4041
 	CodeUserLocked           = 4230
+	CodeLockLost             = 4231
+	CodeConcurrencyConflict  = 4091
+	CodeApprovalNotPending   = 4092
+	CodeApprovalExpired      = 4093
+	CodeUserQueueFull        = 4290
 
 	// 5xxx - Server errors
-	CodeInternalServer = 5000
+	CodeInternalServer     = 5000
+	CodeServiceUnavailable = 5030
+	CodeShuttingDown       = 5031
+	CodeOverloaded         = 5032
 )
 
 // Base error types
@@ -41,6 +55,18 @@ var (
 	// ErrAmountOverflow is returned when the amount is too large and would cause overflow
 	ErrAmountOverflow = errors.New("amount is too large and would cause overflow")
 
+	// ErrCurrencyMismatch is returned when amounts in different currencies are added or subtracted
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+
+	// ErrAmountBelowMinimum is returned when the amount is smaller than the configured minimum for the transaction
+	ErrAmountBelowMinimum = errors.New("amount is below the minimum allowed")
+
+	// ErrAmountAboveMaximum is returned when the amount is larger than the configured maximum for the transaction
+	ErrAmountAboveMaximum = errors.New("amount is above the maximum allowed")
+
+	// ErrBalanceLimitExceeded is returned when a credit would take the balance above the configured maximum
+	ErrBalanceLimitExceeded = errors.New("balance would exceed the maximum allowed")
+
 	// ErrInvalidTransactionID is returned when the transaction ID is empty or invalid
 	ErrInvalidTransactionID = errors.New("transaction ID cannot be empty")
 
@@ -68,6 +94,13 @@ var (
 	// ErrUserLocked is returned when a user is locked by another operation
 	ErrUserLocked = errors.New("user is locked by another operation")
 
+	// ErrLockLost is returned when a user lock expired and may have been taken over before the work finished
+	ErrLockLost = errors.New("user lock is no longer held")
+
+	// ErrConcurrencyConflict is returned when the database aborted the work because of a concurrent
+	// transaction (serialization failure or deadlock); nothing was written and it can be retried
+	ErrConcurrencyConflict = errors.New("concurrent update conflict")
+
 	// ErrDatabaseConnection is returned when there's a problem connecting to the database
 	ErrDatabaseConnection = errors.New("database connection error")
 
@@ -79,6 +112,37 @@ var (
 
 	// ErrNotFound is returned when a generic resource is not found
 	ErrNotFound = errors.New("resource not found")
+
+	// ErrUserQueueFull is returned when a user has too many transactions waiting to be processed
+	ErrUserQueueFull = errors.New("too many pending transactions for user")
+
+	// ErrExecutorStopped is returned when work is submitted to a stopped executor
+	ErrExecutorStopped = errors.New("transaction executor is stopped")
+
+	// ErrShuttingDown is returned when a transaction arrives, or is cut off, while the service drains for shutdown
+	ErrShuttingDown = errors.New("service is shutting down")
+
+	// ErrOverloaded is returned when a transaction is shed because the instance is at its concurrency limit
+	ErrOverloaded = errors.New("service is overloaded")
+
+	// ErrInvalidAdjustment is returned when a manual balance adjustment lacks its reason, note or operator,
+	// or when adjustment details are attached to a transaction that is not an admin adjustment
+	ErrInvalidAdjustment = errors.New("invalid balance adjustment")
+
+	// ErrApprovalNotPending is returned when approving or rejecting a transaction that is not awaiting approval
+	ErrApprovalNotPending = errors.New("transaction is not awaiting approval")
+
+	// ErrApprovalExpired is returned when approving a request that waited longer than the approval expiry
+	ErrApprovalExpired = errors.New("approval request has expired")
+
+	// ErrSelfApproval is returned when the requester of a transaction tries to approve it
+	ErrSelfApproval = errors.New("transaction must be approved by someone other than its requester")
+
+	// ErrUserNotLocked is returned when an operator releases a lock that is not held
+	ErrUserNotLocked = errors.New("user is not locked")
+
+	// ErrJobRunExists is returned when a scheduled job already has a run recorded for the same slot
+	ErrJobRunExists = errors.New("job run already recorded for this schedule slot")
 )
 
 // ErrorCode returns standardized error codes for known errors
@@ -94,12 +158,40 @@ func ErrorCode(err error) int {
 		return CodeDuplicateTransaction
 	case errors.Is(err, ErrAmountOverflow):
 		return CodeAmountOverflow
+	case errors.Is(err, ErrInvalidAdjustment):
+		return CodeInvalidAdjustment
+	case errors.Is(err, ErrAmountBelowMinimum):
+		return CodeAmountBelowMinimum
+	case errors.Is(err, ErrAmountAboveMaximum):
+		return CodeAmountAboveMaximum
+	case errors.Is(err, ErrBalanceLimitExceeded):
+		return CodeBalanceLimitExceeded
+	case errors.Is(err, ErrSelfApproval):
+		return CodeSelfApproval
+	case errors.Is(err, ErrApprovalNotPending):
+		return CodeApprovalNotPending
+	case errors.Is(err, ErrApprovalExpired):
+		return CodeApprovalExpired
 	case errors.Is(err, ErrUserNotFound):
 		return CodeUserNotFound
+	case errors.Is(err, ErrTransactionNotFound):
+		return CodeTransactionNotFound
 	case errors.Is(err, ErrUserLocked):
 		return CodeUserLocked
+	case errors.Is(err, ErrLockLost):
+		return CodeLockLost
+	case errors.Is(err, ErrConcurrencyConflict):
+		return CodeConcurrencyConflict
 	case errors.Is(err, ErrConstraintViolation):
 		return CodeConstraintViolation
+	case errors.Is(err, ErrUserQueueFull):
+		return CodeUserQueueFull
+	case errors.Is(err, ErrExecutorStopped):
+		return CodeServiceUnavailable
+	case errors.Is(err, ErrShuttingDown):
+		return CodeShuttingDown
+	case errors.Is(err, ErrOverloaded):
+		return CodeOverloaded
 	default:
 		return CodeInternalServer
 	}
@@ -288,3 +380,50 @@ func IsNotFoundError(err error) bool {
 func IsUserLockedError(err error) bool {
 	return errors.Is(err, ErrUserLocked)
 }
+
+// IsLockLostError checks if the error means the caller no longer holds the user lock
+func IsLockLostError(err error) bool {
+	return errors.Is(err, ErrLockLost)
+}
+
+// IsConcurrencyConflictError checks if the error means the work lost a race with a concurrent transaction
+func IsConcurrencyConflictError(err error) bool {
+	return errors.Is(err, ErrConcurrencyConflict)
+}
+
+// IsShuttingDownError checks if the error was caused by the service shutting down
+func IsShuttingDownError(err error) bool {
+	return errors.Is(err, ErrShuttingDown)
+}
+
+// IsUserQueueFullError checks if the error is caused by a full per-user queue
+func IsUserQueueFullError(err error) bool {
+	return errors.Is(err, ErrUserQueueFull)
+}
+
+// IsOverloadedError checks if the error means the transaction was shed under overload
+func IsOverloadedError(err error) bool {
+	return errors.Is(err, ErrOverloaded)
+}
+
+// IsInvalidAdjustmentError checks if the error is caused by an incomplete or misplaced balance adjustment
+func IsInvalidAdjustmentError(err error) bool {
+	return errors.Is(err, ErrInvalidAdjustment)
+}
+
+// IsLimitError checks if the error is caused by an amount or balance outside the configured limits
+func IsLimitError(err error) bool {
+	return errors.Is(err, ErrAmountBelowMinimum) ||
+		errors.Is(err, ErrAmountAboveMaximum) ||
+		errors.Is(err, ErrBalanceLimitExceeded)
+}
+
+// IsApprovalConflictError checks if the error means the transaction can no longer be approved or rejected
+func IsApprovalConflictError(err error) bool {
+	return errors.Is(err, ErrApprovalNotPending) || errors.Is(err, ErrApprovalExpired)
+}
+
+// IsSelfApprovalError checks if the error is caused by a requester approving their own transaction
+func IsSelfApprovalError(err error) bool {
+	return errors.Is(err, ErrSelfApproval)
+}
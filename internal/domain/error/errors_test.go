@@ -28,8 +28,22 @@ func TestErrorCode(t *testing.T) {
 		{"InvalidUserID", ErrInvalidUserID, 4003},
 		{"DuplicateTransaction", ErrDuplicateTransaction, 4004},
 		{"UserNotFound", ErrUserNotFound, 4040},
+		{"TransactionNotFound", ErrTransactionNotFound, 4041},
 		{"UserLocked", ErrUserLocked, 4230},
+		{"LockLost", ErrLockLost, 4231},
+		{"ConcurrencyConflict", fmt.Errorf("commit failed: %w", ErrConcurrencyConflict), 4091},
 		{"ConstraintViolation", ErrConstraintViolation, 4005},
+		{"InvalidAdjustment", fmt.Errorf("%w: note is required", ErrInvalidAdjustment), 4007},
+		{"AmountBelowMinimum", fmt.Errorf("%w: 0.50 is below 1.00", ErrAmountBelowMinimum), 4008},
+		{"AmountAboveMaximum", ErrAmountAboveMaximum, 4009},
+		{"BalanceLimitExceeded", ErrBalanceLimitExceeded, 4010},
+		{"SelfApproval", ErrSelfApproval, 4031},
+		{"ApprovalNotPending", fmt.Errorf("%w: transaction is completed", ErrApprovalNotPending), 4092},
+		{"ApprovalExpired", ErrApprovalExpired, 4093},
+		{"UserQueueFull", ErrUserQueueFull, 4290},
+		{"ExecutorStopped", ErrExecutorStopped, 5030},
+		{"ShuttingDown", fmt.Errorf("aborted: %w", ErrShuttingDown), 5031},
+		{"Overloaded", fmt.Errorf("shed: %w", ErrOverloaded), 5032},
 		{"UnknownError", errors.New("unknown error"), 5000},
 		{"WrappedError", fmt.Errorf("wrapped: %w", ErrInvalidUserID), 4003},
 	}
@@ -0,0 +1,23 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
+// ApprovalAuditRepository stores the audit trail of transactions held for approval
+// Entries are only ever added, never changed or removed.
+type ApprovalAuditRepository interface {
+	// Record adds an entry to the audit trail and assigns its ID
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	Record(ctx context.Context, event *entity.ApprovalEvent) error
+
+	// ListByTransaction returns a transaction's audit trail, oldest first
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	ListByTransaction(ctx context.Context, transactionID string) ([]*entity.ApprovalEvent, error)
+}
@@ -0,0 +1,24 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
+// AtomicTransactionRepository applies transactions without a separate user lock
+// The balance change and the transaction record are written in one short database
+// transaction whose conditional update serialises concurrent writers on the user row
+type AtomicTransactionRepository interface {
+	// ApplyTransaction records the transaction and applies its balance change atomically
+	// On success the processed transaction is returned with its resulting balance. If a
+	// transaction with the same ID was already recorded, nothing changes and the stored
+	// transaction is returned instead, with stored set.
+	//
+	// Possible errors:
+	// - ErrUserNotFound: If user with specified ID doesn't exist
+	// - ErrInsufficientBalance: If the balance would become negative
+	// - ErrAmountOverflow: If the balance cannot hold the result
+	// - ErrDatabaseConnection: If database connection fails
+	ApplyTransaction(ctx context.Context, txn *entity.Transaction) (result *entity.Transaction, stored bool, err error)
+}
@@ -0,0 +1,16 @@
+package persistence
+
+import (
+	"context"
+)
+
+// JobLockRepository elects a single instance to run a scheduled job
+type JobLockRepository interface {
+	// TryLockJob attempts to take the cluster-wide lock for the named job without waiting
+	// When acquired is true the caller runs the job and must call unlock afterwards;
+	// when false another instance holds the lock and the caller skips the run.
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	TryLockJob(ctx context.Context, jobName string) (unlock func(), acquired bool, err error)
+}
@@ -0,0 +1,31 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
+// JobRunRepository stores the run history of scheduled jobs
+type JobRunRepository interface {
+	// StartRun records the start of a job run and assigns its ID
+	// Recording a second run for the same job and schedule slot fails, which is how
+	// instances agree that a slot has already been handled.
+	//
+	// Possible errors:
+	// - ErrJobRunExists: If a run for this job and slot was already recorded
+	// - ErrDatabaseConnection: If database connection fails
+	StartRun(ctx context.Context, run *entity.JobRun) error
+
+	// FinishRun records the outcome of a previously started run
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	FinishRun(ctx context.Context, run *entity.JobRun) error
+
+	// ListRuns returns the most recent runs of a job, newest first
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	ListRuns(ctx context.Context, jobName string, limit int) ([]*entity.JobRun, error)
+}
@@ -2,6 +2,7 @@ package persistence
 
 import (
 	"context"
+	"time"
 
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
 )
@@ -33,6 +34,43 @@ type TransactionRepository interface {
 	// - ErrDatabaseConnection: If database connection fails
 	GetByTransactionID(ctx context.Context, transactionID string) (*entity.Transaction, error)
 
+	// ListByUser returns a user's transactions, newest first
+	// A limit of 0 returns all of them.
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	ListByUser(ctx context.Context, userID uint64, limit int) ([]*entity.Transaction, error)
+
+	// ListByStatus returns all users' transactions with the given status, oldest first
+	// A limit of 0 returns all of them.
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	ListByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error)
+
+	// StreamByUser calls fn for each of a user's transactions booked in [from, to), in booking order
+	// Transactions are booked when they are processed, or when they are received if they have not been.
+	// A zero from or to leaves that end of the period open. Rows are read one at a time, so memory use
+	// does not grow with the history; an error returned by fn stops the stream and is returned.
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	StreamByUser(ctx context.Context, userID uint64, from, to time.Time, fn func(*entity.Transaction) error) error
+
+	// LastCompletedBefore returns the user's last completed transaction booked before the given time
+	//
+	// Possible errors:
+	// - ErrTransactionNotFound: If the user has no completed transaction booked before then
+	// - ErrDatabaseConnection: If database connection fails
+	LastCompletedBefore(ctx context.Context, userID uint64, before time.Time) (*entity.Transaction, error)
+
+	// FirstCompleted returns the user's first completed transaction in booking order
+	//
+	// Possible errors:
+	// - ErrTransactionNotFound: If the user has no completed transaction
+	// - ErrDatabaseConnection: If database connection fails
+	FirstCompleted(ctx context.Context, userID uint64) (*entity.Transaction, error)
+
 	// TransactionExists checks if a transaction with the given ID already exists
 	// Used for idempotency checking
 	//
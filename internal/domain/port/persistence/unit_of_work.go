@@ -21,4 +21,7 @@ type UnitOfWork interface {
 
 	// GetTransactionRepository returns a transaction repository bound to the current transaction
 	GetTransactionRepository(ctx context.Context) TransactionRepository
+
+	// GetApprovalAuditRepository returns an approval audit repository bound to the current transaction
+	GetApprovalAuditRepository(ctx context.Context) ApprovalAuditRepository
 }
@@ -0,0 +1,26 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
+// UserLockAdminRepository lets operators inspect and break user locks
+// It works on the locks of every instance, not only the ones taken by this process.
+type UserLockAdminRepository interface {
+	// ListLocks returns the user locks currently recorded, ordered by user ID
+	// Locks whose lease has expired but that were not cleaned up yet are included
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	ListLocks(ctx context.Context) ([]*entity.UserLock, error)
+
+	// ForceReleaseLock releases the user's lock whoever holds it
+	// The holder finds out when it next renews or verifies the lock and its work is rolled back.
+	//
+	// Possible errors:
+	// - ErrUserNotLocked: If the user is not locked
+	// - ErrDatabaseConnection: If database connection fails
+	ForceReleaseLock(ctx context.Context, userID uint64) error
+}
@@ -5,23 +5,54 @@ import (
 	"time"
 )
 
+// LockToken identifies a single acquisition of a user lock
+// A new token is issued every time a lock is acquired, so a holder whose lock expired
+// and was taken over cannot release or commit under the new holder's lock
+type LockToken string
+
 // UserLockRepository defines methods for managing user locks
 // Simplified version with only essential locking functionality
 type UserLockRepository interface {
 	// AcquireLock attempts to acquire a lock on the user for transaction processing
-	// The lock expires after the given duration
+	// The lock expires after the given duration. The returned token must be presented
+	// to release or verify the lock.
 	//
 	// Possible errors:
 	// - ErrUserNotFound: If user with specified ID doesn't exist
 	// - ErrUserLocked: If user is already locked by another process
 	// - ErrDatabaseConnection: If database connection fails
-	AcquireLock(ctx context.Context, userID uint64, duration time.Duration) error
+	AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (LockToken, error)
 
 	// ReleaseLock releases a previously acquired lock
-	// This should be called after transaction processing completes
+	// This should be called after transaction processing completes. The lock is only
+	// released if it is still held under the given token; otherwise this is a no-op.
 	//
 	// Possible errors:
 	// - ErrUserNotFound: If user with specified ID doesn't exist
 	// - ErrDatabaseConnection: If database connection fails
-	ReleaseLock(ctx context.Context, userID uint64) error
+	ReleaseLock(ctx context.Context, userID uint64, token LockToken) error
+
+	// RenewLock extends the lock so it expires after the given duration from now
+	// Long-running work calls this periodically to keep its lease alive.
+	//
+	// Possible errors:
+	// - ErrLockLost: If the lock is no longer held under the given token
+	// - ErrDatabaseConnection: If database connection fails
+	RenewLock(ctx context.Context, userID uint64, token LockToken, duration time.Duration) error
+
+	// VerifyLock checks that the lock is still held under the given token
+	// It must be called with the unit of work's transactional context right before commit;
+	// the lock then cannot be taken over until that transaction ends.
+	//
+	// Possible errors:
+	// - ErrLockLost: If the lock expired and was released or taken over
+	// - ErrDatabaseConnection: If database connection fails
+	VerifyLock(ctx context.Context, userID uint64, token LockToken) error
+
+	// CleanupExpiredLocks removes locks whose lease has run out
+	// Expired locks never block acquisition, but their rows are left behind until cleaned up
+	//
+	// Possible errors:
+	// - ErrDatabaseConnection: If database connection fails
+	CleanupExpiredLocks(ctx context.Context) error
 }
@@ -42,7 +42,8 @@ type UserRepository interface {
 	// Possible errors:
 	// - ErrUserNotFound: If user doesn't exist
 	// - ErrInsufficientBalance: If balance would become negative (for deductions)
+	// - ErrAmountOverflow: If the balance cannot hold the result (for credits)
 	// - ErrUserLocked: If user is locked by another operation
 	// - ErrDatabaseConnection: If database connection fails
-	ProcessTransaction(ctx context.Context, userID uint64, balanceChange int64) (*entity.User, error)
+	ProcessTransaction(ctx context.Context, userID uint64, balanceChange entity.Money) (*entity.User, error)
 }
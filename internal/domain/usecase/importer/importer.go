@@ -0,0 +1,438 @@
+// Package importer replays files of transactions through the normal processing rules
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+)
+
+// Defaults for Options left at zero
+const (
+	DefaultParallelism     = 4
+	DefaultCheckpointEvery = 100
+)
+
+// maxLineSize is the longest line an import file may have
+const maxLineSize = 1 << 20
+
+// workerQueueSize is how many lines may wait for each worker
+// It also bounds how far lines can complete ahead of the checkpoint.
+const workerQueueSize = 64
+
+// Record is one line of an import file
+type Record struct {
+	UserID        uint64 `json:"userId"`
+	SourceType    string `json:"sourceType"`
+	State         string `json:"state"`
+	Amount        string `json:"amount"`
+	TransactionID string `json:"transactionId"`
+}
+
+// Outcome says what happened to one line
+type Outcome string
+
+// Line outcomes
+const (
+	OutcomeProcessed Outcome = "processed" // Processed by this run; Status says how it ended
+	OutcomeDuplicate Outcome = "duplicate" // Already processed by an earlier run
+	OutcomeConflict  Outcome = "conflict"  // The transaction ID was already used for a different transaction
+	OutcomeRejected  Outcome = "rejected"  // Refused by the processing rules, e.g. an unknown user, insufficient balance or a limit
+	OutcomeInvalid   Outcome = "invalid"   // Not a valid record
+	OutcomeError     Outcome = "error"     // Could not be processed; the import stopped and retries it on resume
+)
+
+// Result is the outcome of one line
+type Result struct {
+	Line          int
+	TransactionID string
+	UserID        uint64
+	Outcome       Outcome
+	Status        entity.TransactionStatus // Status of the stored transaction, if there is one
+	ResultBalance string                   // Balance after a completed transaction
+	Error         string
+}
+
+// Summary counts what an import did
+type Summary struct {
+	Lines      int // Lines read, including those before the checkpoint
+	Skipped    int // Lines before the checkpoint, done by an earlier run
+	Processed  int
+	Duplicates int
+	Conflicts  int
+	Rejected   int
+	Invalid    int
+	Checkpoint int // Last line up to which every line is done
+}
+
+// Processor processes one transaction; TransactionProcessor is the one used in practice
+type Processor interface {
+	// Process returns the processed transaction, or the stored one with stored set if the ID was processed before
+	Process(ctx context.Context, req transaction.ProcessTransactionRequest) (txn *entity.Transaction, stored bool, err error)
+}
+
+// Checkpoint remembers how far an import got
+type Checkpoint interface {
+	// Load returns the last line up to which every line is done, or 0 for a new import
+	Load() (int, error)
+	// Save records that every line up to line is done
+	Save(line int) error
+}
+
+// Reporter receives the result of each line, in the order lines finish
+type Reporter interface {
+	Report(result Result) error
+}
+
+// Options tune an import
+type Options struct {
+	Parallelism     int        // Users processed at once
+	CheckpointEvery int        // Lines between checkpoint saves
+	Checkpoint      Checkpoint // Optional; without one every run starts at the first line
+}
+
+// Importer submits the transactions of an NDJSON file through the processor
+// Lines of one user are processed in file order by the same worker, and up to Parallelism users
+// are processed at once. Transaction IDs make a rerun safe: lines that were already processed are
+// reported as duplicates instead of being applied twice.
+type Importer struct {
+	processor Processor
+	options   Options
+	logger    coreport.Logger
+}
+
+// NewImporter creates a new importer
+func NewImporter(processor Processor, options Options, logger coreport.Logger) *Importer {
+	if options.Parallelism <= 0 {
+		options.Parallelism = DefaultParallelism
+	}
+	if options.CheckpointEvery <= 0 {
+		options.CheckpointEvery = DefaultCheckpointEvery
+	}
+	return &Importer{
+		processor: processor,
+		options:   options,
+		logger:    logger,
+	}
+}
+
+// job is a line handed to a worker
+type job struct {
+	line   int
+	record Record
+}
+
+// lineDone is a finished line on its way to the collector
+type lineDone struct {
+	result Result
+	report bool  // Blank lines finish without a report
+	fatal  error // Set when the line could not be processed and the import must stop
+}
+
+// Run imports the lines of r after the checkpoint and reports each one
+// Cancelling ctx stops the import; lines being processed finish first, so the checkpoint stays
+// accurate. A line that fails for a reason other than the processing rules, such as a lost
+// database connection, stops the import before any later line of that user.
+//
+// Possible errors:
+// - The error that stopped the import, or the cause of the cancellation
+// - Errors reading the input, writing the report or saving the checkpoint
+func (i *Importer) Run(ctx context.Context, r io.Reader, reporter Reporter) (*Summary, error) {
+	start := 0
+	if i.options.Checkpoint != nil {
+		var err error
+		if start, err = i.options.Checkpoint.Load(); err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+	}
+
+	// Lines handed to a worker finish even if the import is cancelled
+	processCtx := context.WithoutCancel(ctx)
+	stopCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+
+	done := make(chan lineDone, i.options.Parallelism*workerQueueSize)
+	queues := make([]chan job, i.options.Parallelism)
+	var workers sync.WaitGroup
+	for n := range queues {
+		queues[n] = make(chan job, workerQueueSize)
+		workers.Add(1)
+		go func(queue <-chan job) {
+			defer workers.Done()
+			i.work(processCtx, stopCtx, queue, done)
+		}(queues[n])
+	}
+
+	collected := make(chan collectorResult, 1)
+	go func() {
+		collected <- i.collect(start, done, reporter, stop)
+	}()
+
+	summary := &Summary{Skipped: start}
+	readErr := i.dispatch(stopCtx, r, start, queues, done, summary)
+	for _, queue := range queues {
+		close(queue)
+	}
+	workers.Wait()
+	close(done)
+	result := <-collected
+
+	summary.Processed = result.counts[OutcomeProcessed]
+	summary.Duplicates = result.counts[OutcomeDuplicate]
+	summary.Conflicts = result.counts[OutcomeConflict]
+	summary.Rejected = result.counts[OutcomeRejected]
+	summary.Invalid = result.counts[OutcomeInvalid]
+	summary.Checkpoint = result.checkpoint
+
+	err := result.err
+	if err == nil && readErr != nil {
+		err = fmt.Errorf("failed to read import file: %w", readErr)
+	}
+	if err == nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	if saveErr := i.saveCheckpoint(result.checkpoint); err == nil && saveErr != nil {
+		err = saveErr
+	}
+
+	fields := map[string]any{
+		"lines":      summary.Lines,
+		"processed":  summary.Processed,
+		"duplicates": summary.Duplicates,
+		"rejected":   summary.Rejected,
+		"conflicts":  summary.Conflicts,
+		"invalid":    summary.Invalid,
+		"checkpoint": summary.Checkpoint,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		i.logger.Error("Import stopped", fields)
+		return summary, err
+	}
+	i.logger.Info("Import finished", fields)
+	return summary, nil
+}
+
+// dispatch reads the input and hands each line after the checkpoint to the worker of its user
+func (i *Importer) dispatch(
+	stopCtx context.Context,
+	r io.Reader,
+	start int,
+	queues []chan job,
+	done chan<- lineDone,
+	summary *Summary,
+) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line <= start {
+			summary.Lines = line
+			continue
+		}
+		if stopCtx.Err() != nil {
+			return nil
+		}
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			done <- lineDone{result: Result{Line: line}}
+			summary.Lines = line
+			continue
+		}
+		record, err := parseRecord(text)
+		if err != nil {
+			done <- lineDone{result: Result{Line: line, Outcome: OutcomeInvalid, Error: err.Error()}, report: true}
+			summary.Lines = line
+			continue
+		}
+
+		select {
+		case queues[record.UserID%uint64(len(queues))] <- job{line: line, record: record}:
+		case <-stopCtx.Done():
+			return nil
+		}
+		summary.Lines = line
+	}
+	return scanner.Err()
+}
+
+// parseRecord decodes one line; fields are checked by the processor, like those of any other request
+func parseRecord(text string) (Record, error) {
+	var record Record
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&record); err != nil {
+		return Record{}, fmt.Errorf("%w: %s", errs.ErrInvalidRequest, err.Error())
+	}
+	if record.UserID == 0 {
+		return Record{}, errs.ErrInvalidUserID
+	}
+	return record, nil
+}
+
+// work processes the lines of the users assigned to one worker, in order
+// Once the import stops the remaining lines are left for the next run. A worker stops at its own
+// failed line without waiting for the collector, so no later line of that user overtakes it.
+func (i *Importer) work(processCtx, stopCtx context.Context, queue <-chan job, done chan<- lineDone) {
+	failed := false
+	for j := range queue {
+		if failed || stopCtx.Err() != nil {
+			continue
+		}
+		d := i.process(processCtx, j)
+		failed = d.fatal != nil
+		done <- d
+	}
+}
+
+// process submits one record and classifies the outcome
+func (i *Importer) process(ctx context.Context, j job) lineDone {
+	record := j.record
+	result := Result{Line: j.line, TransactionID: record.TransactionID, UserID: record.UserID}
+
+	txn, stored, err := i.processor.Process(ctx, transaction.ProcessTransactionRequest{
+		UserID:        record.UserID,
+		TransactionID: record.TransactionID,
+		SourceType:    record.SourceType,
+		State:         record.State,
+		Amount:        record.Amount,
+	})
+	if txn != nil {
+		result.Status = txn.Status
+		if txn.Status == entity.StatusCompleted {
+			result.ResultBalance = txn.GetResultBalance()
+		}
+	}
+
+	switch {
+	case err != nil && isRejection(err):
+		result.Outcome = OutcomeRejected
+		if errs.IsDuplicateTransactionError(err) {
+			result.Outcome = OutcomeConflict
+		}
+		result.Error = err.Error()
+	case err != nil:
+		result.Outcome = OutcomeError
+		result.Error = err.Error()
+		return lineDone{result: result, report: true, fatal: err}
+	case stored:
+		result.Outcome = OutcomeDuplicate
+		if !matches(txn, record) {
+			result.Outcome = OutcomeConflict
+			result.Error = fmt.Sprintf("transaction ID already used for %s %s of %s by user %d",
+				txn.SourceType, txn.State, txn.GetAmount(), txn.UserID)
+		}
+	default:
+		result.Outcome = OutcomeProcessed
+	}
+	return lineDone{result: result, report: true}
+}
+
+// isRejection reports whether the processing rules refused a transaction, so that retrying it would not help
+func isRejection(err error) bool {
+	for _, rejection := range []error{
+		errs.ErrInvalidUserID,
+		errs.ErrInvalidTransactionID,
+		errs.ErrInvalidSourceType,
+		errs.ErrInvalidState,
+		errs.ErrInvalidAmount,
+		errs.ErrNegativeAmount,
+		errs.ErrAmountOverflow,
+		errs.ErrInvalidAdjustment,
+		errs.ErrInvalidRequest,
+		errs.ErrInsufficientBalance,
+		errs.ErrUserNotFound,
+		errs.ErrDuplicateTransaction,
+	} {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+	return errs.IsLimitError(err)
+}
+
+// matches reports whether a stored transaction is the one the record describes
+func matches(txn *entity.Transaction, record Record) bool {
+	amount, err := entity.ParseMoney(record.Amount)
+	return err == nil &&
+		txn.UserID == record.UserID &&
+		string(txn.SourceType) == record.SourceType &&
+		string(txn.State) == record.State &&
+		txn.Amount == amount
+}
+
+// collectorResult is what the collector found once every line has finished
+type collectorResult struct {
+	counts     map[Outcome]int
+	checkpoint int
+	err        error
+}
+
+// collect reports finished lines and moves the checkpoint past every line that is done
+// It stops the import at the first fatal line; the checkpoint stays before that line.
+func (i *Importer) collect(start int, done <-chan lineDone, reporter Reporter, stop context.CancelCauseFunc) collectorResult {
+	result := collectorResult{counts: make(map[Outcome]int), checkpoint: start}
+	finished := make(map[int]struct{})
+	saved := start
+
+	for d := range done {
+		if d.report && result.err == nil {
+			if err := reporter.Report(d.result); err != nil {
+				result.err = fmt.Errorf("failed to write import report: %w", err)
+				stop(result.err)
+			}
+		}
+		if d.fatal != nil {
+			if result.err == nil {
+				result.err = fmt.Errorf("import stopped at line %d: %w", d.result.Line, d.fatal)
+				stop(result.err)
+			}
+			continue
+		}
+		if d.report {
+			result.counts[d.result.Outcome]++
+		}
+
+		finished[d.result.Line] = struct{}{}
+		for {
+			if _, ok := finished[result.checkpoint+1]; !ok {
+				break
+			}
+			delete(finished, result.checkpoint+1)
+			result.checkpoint++
+		}
+		if result.checkpoint-saved >= i.options.CheckpointEvery && result.err == nil {
+			if err := i.saveCheckpoint(result.checkpoint); err != nil {
+				result.err = err
+				stop(err)
+				continue
+			}
+			saved = result.checkpoint
+		}
+	}
+	return result
+}
+
+// saveCheckpoint records progress when the import has a checkpoint
+func (i *Importer) saveCheckpoint(line int) error {
+	if i.options.Checkpoint == nil {
+		return nil
+	}
+	if err := i.options.Checkpoint.Save(line); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
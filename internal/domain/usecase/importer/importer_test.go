@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcessor applies wins and losses to in-memory balances and remembers transaction IDs
+type fakeProcessor struct {
+	mu       sync.Mutex
+	clock    *coremocks.MockTimeProvider
+	balances map[uint64]int64
+	stored   map[string]*entity.Transaction
+	order    map[uint64][]string // Transaction IDs in the order each user's were processed
+	failOn   map[string]error    // Errors to return for a transaction ID, once
+	waitFor  map[string]string   // Transaction IDs held, once, until another ID has been processed
+	released map[string]chan struct{}
+	limits   transaction.LimitPolicy
+}
+
+func newFakeProcessor(t *testing.T) *fakeProcessor {
+	var ticks atomic.Int64
+	clock := coremocks.NewMockTimeProvider(t)
+	clock.EXPECT().Now().RunAndReturn(func() time.Time {
+		return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(ticks.Add(1)) * time.Millisecond)
+	}).Maybe()
+
+	return &fakeProcessor{
+		clock:    clock,
+		balances: map[uint64]int64{1: 10000, 2: 10000, 3: 10000},
+		stored:   make(map[string]*entity.Transaction),
+		order:    make(map[uint64][]string),
+		failOn:   make(map[string]error),
+		waitFor:  make(map[string]string),
+		released: make(map[string]chan struct{}),
+	}
+}
+
+// holdUntil makes the processing of id wait until before has been processed
+func (p *fakeProcessor) holdUntil(id, before string) {
+	p.waitFor[id] = before
+	p.released[before] = make(chan struct{})
+}
+
+func (p *fakeProcessor) Process(_ context.Context, req transaction.ProcessTransactionRequest) (*entity.Transaction, bool, error) {
+	p.mu.Lock()
+	if before, ok := p.waitFor[req.TransactionID]; ok {
+		delete(p.waitFor, req.TransactionID)
+		if released, pending := p.released[before]; pending {
+			p.mu.Unlock()
+			<-released
+			p.mu.Lock()
+		}
+	}
+	defer p.mu.Unlock()
+	if released, ok := p.released[req.TransactionID]; ok {
+		delete(p.released, req.TransactionID)
+		defer close(released)
+	}
+
+	if err, ok := p.failOn[req.TransactionID]; ok {
+		delete(p.failOn, req.TransactionID)
+		return nil, false, err
+	}
+	if txn, ok := p.stored[req.TransactionID]; ok {
+		return txn, true, nil
+	}
+	txn, err := entity.NewTransaction(req.UserID, req.TransactionID, req.SourceType, req.State, req.Amount, p.clock)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid transaction: %w", err)
+	}
+	if err := p.limits.CheckAmount(txn); err != nil {
+		return nil, false, err
+	}
+	balance, ok := p.balances[req.UserID]
+	if !ok {
+		return nil, false, errs.ErrUserNotFound
+	}
+
+	p.order[req.UserID] = append(p.order[req.UserID], req.TransactionID)
+	if txn.State == entity.StateLose && balance < txn.Amount.Cents() {
+		txn.MarkAsFailed(p.clock, "Insufficient balance")
+		p.stored[req.TransactionID] = txn
+		return txn, false, errs.ErrInsufficientBalance
+	}
+	balance += txn.BalanceChange().Cents()
+	p.balances[req.UserID] = balance
+	txn.MarkAsProcessed(p.clock, entity.MoneyFromCents(balance))
+	p.stored[req.TransactionID] = txn
+	return txn, false, nil
+}
+
+// memoryCheckpoint keeps a checkpoint in memory
+type memoryCheckpoint struct {
+	mu    sync.Mutex
+	line  int
+	saves int
+}
+
+func (c *memoryCheckpoint) Load() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.line, nil
+}
+
+func (c *memoryCheckpoint) Save(line int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.line = line
+	c.saves++
+	return nil
+}
+
+// resultLog collects the reported results by line
+type resultLog struct {
+	mu      sync.Mutex
+	results map[int]Result
+}
+
+func (l *resultLog) Report(result Result) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.results == nil {
+		l.results = make(map[int]Result)
+	}
+	l.results[result.Line] = result
+	return nil
+}
+
+func line(userID uint64, id, state, amount string) string {
+	return fmt.Sprintf(`{"userId":%d,"sourceType":"game","state":%q,"amount":%q,"transactionId":%q}`, userID, state, amount, id)
+}
+
+// newTestImporter creates an importer for the processor
+func newTestImporter(t *testing.T, processor *fakeProcessor, options Options) *Importer {
+	logger := coremocks.NewMockLogger(t)
+	logger.EXPECT().Info(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Error(mock.Anything, mock.Anything).Maybe()
+	return NewImporter(processor, options, logger)
+}
+
+func TestImporter_Run(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Lines are processed in order per user and each is reported", func(t *testing.T) {
+		var lines []string
+		for i := 0; i < 300; i++ {
+			lines = append(lines, line(uint64(i%3+1), fmt.Sprintf("t%d", i), "win", "1.00"))
+		}
+		lines = append(lines,
+			"",
+			line(1, "broke", "lose", "999.00"),
+			line(9, "nobody", "win", "1.00"),
+			`{"userId":1,"amount":`,
+			`{"userId":1,"sourceType":"game","state":"win","amount":"1.00","transactionId":"x","extra":1}`,
+			line(2, "bad-amount", "win", "1.001"),
+		)
+
+		processor := newFakeProcessor(t)
+		checkpoint := &memoryCheckpoint{}
+		log := &resultLog{}
+		summary, err := newTestImporter(t, processor, Options{Parallelism: 3, Checkpoint: checkpoint}).
+			Run(ctx, strings.NewReader(strings.Join(lines, "\n")), log)
+		require.NoError(t, err)
+
+		assert.Equal(t, &Summary{Lines: 306, Processed: 300, Rejected: 3, Invalid: 2, Checkpoint: 306}, summary)
+		assert.Equal(t, 306, checkpoint.line)
+		assert.Len(t, log.results, 305)
+		for userID, ids := range processor.order {
+			for n, id := range ids {
+				if strings.HasPrefix(id, "t") {
+					assert.Equal(t, fmt.Sprintf("t%d", n*3+int(userID)-1), id)
+				}
+			}
+		}
+		// Line 298 is the last of user 1's hundred wins
+		assert.Equal(t, "200.00", log.results[298].ResultBalance)
+		assert.Equal(t, OutcomeRejected, log.results[302].Outcome)
+		assert.Equal(t, entity.StatusFailed, log.results[302].Status)
+		assert.Equal(t, OutcomeRejected, log.results[303].Outcome)
+		assert.Equal(t, OutcomeInvalid, log.results[304].Outcome)
+		assert.Equal(t, OutcomeInvalid, log.results[305].Outcome)
+		assert.Equal(t, OutcomeRejected, log.results[306].Outcome)
+	})
+
+	t.Run("A line outside the limits is rejected and the import continues", func(t *testing.T) {
+		maxAmount, err := transaction.NewLimitRule(0, "", "", "", "50.00", "")
+		require.NoError(t, err)
+		processor := newFakeProcessor(t)
+		processor.limits, err = transaction.NewLimitPolicy(maxAmount)
+		require.NoError(t, err)
+		input := strings.Join([]string{
+			line(1, "a1", "win", "1.00"),
+			line(1, "a2", "win", "100.00"),
+			line(1, "a3", "win", "2.00"),
+		}, "\n")
+
+		checkpoint := &memoryCheckpoint{}
+		log := &resultLog{}
+		summary, err := newTestImporter(t, processor, Options{Checkpoint: checkpoint}).
+			Run(ctx, strings.NewReader(input), log)
+		require.NoError(t, err)
+		assert.Equal(t, &Summary{Lines: 3, Processed: 2, Rejected: 1, Checkpoint: 3}, summary)
+		assert.Equal(t, 3, checkpoint.line)
+		assert.Equal(t, OutcomeRejected, log.results[2].Outcome)
+		assert.Contains(t, log.results[2].Error, errs.ErrAmountAboveMaximum.Error())
+		assert.Equal(t, []string{"a1", "a3"}, processor.order[1])
+		assert.Equal(t, int64(10000+100+200), processor.balances[1])
+	})
+
+	t.Run("A failure stops the import at its checkpoint and a rerun resumes safely", func(t *testing.T) {
+		input := strings.Join([]string{
+			line(1, "a1", "win", "1.00"),
+			line(2, "b1", "win", "1.00"),
+			line(1, "a2", "lose", "5.00"),
+			line(1, "a3", "win", "2.00"),
+			line(2, "b2", "win", "1.00"),
+		}, "\n")
+
+		processor := newFakeProcessor(t)
+		processor.failOn["a2"] = errs.ErrDatabaseConnection
+		// b1 is done before a2 stops the import, so the checkpoint does not depend on the other worker's timing
+		processor.holdUntil("a2", "b1")
+		checkpoint := &memoryCheckpoint{}
+
+		summary, err := newTestImporter(t, processor, Options{Parallelism: 2, Checkpoint: checkpoint}).
+			Run(ctx, strings.NewReader(input), &resultLog{})
+		assert.ErrorIs(t, err, errs.ErrDatabaseConnection)
+		assert.Equal(t, 2, summary.Checkpoint)
+		assert.Equal(t, 2, checkpoint.line)
+		// Nothing of user 1 after the failed line was processed
+		assert.Equal(t, []string{"a1"}, processor.order[1])
+
+		log := &resultLog{}
+		summary, err = newTestImporter(t, processor, Options{Parallelism: 2, Checkpoint: checkpoint}).
+			Run(ctx, strings.NewReader(input), log)
+		require.NoError(t, err)
+		assert.Equal(t, 2, summary.Skipped)
+		assert.Equal(t, 5, summary.Checkpoint)
+		assert.Equal(t, []string{"a1", "a2", "a3"}, processor.order[1])
+		assert.Equal(t, int64(10000+100-500+200), processor.balances[1])
+		// b2 may or may not have been processed before the failure stopped the import
+		assert.Equal(t, 3, summary.Processed+summary.Duplicates)
+	})
+
+	t.Run("Reruns without a checkpoint report duplicates and conflicts", func(t *testing.T) {
+		processor := newFakeProcessor(t)
+		input := line(1, "a1", "win", "1.00") + "\n" + line(1, "a2", "win", "1.00")
+		_, err := newTestImporter(t, processor, Options{}).Run(ctx, strings.NewReader(input), &resultLog{})
+		require.NoError(t, err)
+
+		log := &resultLog{}
+		input = line(1, "a1", "win", "1.00") + "\n" + line(1, "a2", "win", "2.00")
+		summary, err := newTestImporter(t, processor, Options{}).Run(ctx, strings.NewReader(input), log)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Duplicates)
+		assert.Equal(t, 1, summary.Conflicts)
+		assert.Equal(t, OutcomeDuplicate, log.results[1].Outcome)
+		assert.Equal(t, OutcomeConflict, log.results[2].Outcome)
+		assert.Contains(t, log.results[2].Error, "win of 1.00 by user 1")
+		assert.Equal(t, int64(10200), processor.balances[1])
+	})
+
+	t.Run("Duplicates do not depend on when the stored transaction was created", func(t *testing.T) {
+		processor := newFakeProcessor(t)
+		_, err := newTestImporter(t, processor, Options{}).Run(ctx, strings.NewReader(line(1, "a1", "win", "1.00")), &resultLog{})
+		require.NoError(t, err)
+		// Recorded by an instance whose clock runs ahead
+		processor.stored["a1"].CreatedAt = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		log := &resultLog{}
+		summary, err := newTestImporter(t, processor, Options{}).Run(ctx, strings.NewReader(line(1, "a1", "win", "1.00")), log)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Duplicates)
+		assert.Equal(t, OutcomeDuplicate, log.results[1].Outcome)
+	})
+
+	t.Run("Cancellation keeps the checkpoint before unprocessed lines", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		checkpoint := &memoryCheckpoint{}
+		summary, err := newTestImporter(t, newFakeProcessor(t), Options{Checkpoint: checkpoint}).
+			Run(ctx, strings.NewReader(line(1, "a1", "win", "1.00")), &resultLog{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, summary.Checkpoint)
+		assert.Equal(t, 0, checkpoint.line)
+	})
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+)
+
+const (
+	// LockCleanupJobName is the name of the job that removes expired user locks
+	LockCleanupJobName = "user_lock_cleanup"
+
+	// lockCleanupTimeout bounds a single lock cleanup run
+	lockCleanupTimeout = 30 * time.Second
+
+	// ApprovalExpiryJobName is the name of the job that expires transactions nobody approved in time
+	ApprovalExpiryJobName = "approval_expiry"
+
+	// approvalExpiryTimeout bounds a single approval expiry run
+	approvalExpiryTimeout = time.Minute
+)
+
+// ApprovalExpirer expires transactions that waited for approval longer than allowed
+type ApprovalExpirer interface {
+	ExpireStaleApprovals(ctx context.Context) (int, error)
+}
+
+// NewLockCleanupJob creates a job that deletes expired rows from the user lock table
+func NewLockCleanupJob(lockRepo persistence.UserLockRepository, schedule Schedule) Job {
+	return Job{
+		Name:     LockCleanupJobName,
+		Schedule: schedule,
+		Timeout:  lockCleanupTimeout,
+		Run: func(ctx context.Context) error {
+			return lockRepo.CleanupExpiredLocks(ctx)
+		},
+	}
+}
+
+// NewApprovalExpiryJob creates a job that expires held transactions past their approval expiry
+func NewApprovalExpiryJob(expirer ApprovalExpirer, schedule Schedule) Job {
+	return Job{
+		Name:     ApprovalExpiryJobName,
+		Schedule: schedule,
+		Timeout:  approvalExpiryTimeout,
+		Run: func(ctx context.Context) error {
+			_, err := expirer.ExpireStaleApprovals(ctx)
+			return err
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvery_AlignsToEpoch(t *testing.T) {
+	schedule := Every(5 * time.Minute)
+
+	from := time.Date(2024, 1, 1, 12, 3, 20, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC), schedule.Next(from))
+
+	// A time exactly on a slot moves on to the following slot
+	onSlot := time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 10, 0, 0, time.UTC), schedule.Next(onSlot))
+}
+
+func TestParseSchedule_Next(t *testing.T) {
+	// 2024-01-01 is a Monday
+	from := time.Date(2024, 1, 1, 12, 3, 20, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 12, 4, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 12, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 1, 2, 2, 30, 0, 0, time.UTC)},
+		{"0 9-17 * * 1-5", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 6,7", time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * *", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// With both day fields restricted, either one matching is enough
+		{"0 0 15 * 3", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 1, 1, 12, 4, 30, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@every",
+		"@every -1m",
+		"@every soon",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
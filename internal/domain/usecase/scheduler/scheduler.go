@@ -0,0 +1,307 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+)
+
+const (
+	// maxWait bounds how long the loop sleeps, so clock adjustments are noticed
+	maxWait = time.Minute
+)
+
+// Job is a unit of periodic maintenance work
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Timeout  time.Duration // Upper bound for a single run; zero means no limit
+	Run      func(ctx context.Context) error
+}
+
+// scheduledJob is a registered job and its scheduling state
+type scheduledJob struct {
+	Job
+	next    time.Time   // Next slot the job is due; guarded by Scheduler.mu
+	running atomic.Bool // A run started by this instance has not finished yet
+}
+
+// Scheduler runs registered jobs on their schedules
+// Any number of instances can run a scheduler with the same jobs: each slot of a job
+// is run by whichever instance takes the job's lock first, and recorded in the run history
+// so instances that get there later skip it.
+type Scheduler struct {
+	jobLocks     persistence.JobLockRepository
+	runs         persistence.JobRunRepository
+	timeProvider coreport.TimeProvider
+	logger       coreport.Logger
+	instance     string
+
+	mu      sync.Mutex
+	jobs    []*scheduledJob
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(
+	jobLocks persistence.JobLockRepository,
+	runs persistence.JobRunRepository,
+	timeProvider coreport.TimeProvider,
+	logger coreport.Logger,
+) *Scheduler {
+	return &Scheduler{
+		jobLocks:     jobLocks,
+		runs:         runs,
+		timeProvider: timeProvider,
+		logger:       logger,
+	}
+}
+
+// WithInstance sets the name recorded in the run history for runs started by this scheduler
+func (s *Scheduler) WithInstance(instance string) *Scheduler {
+	s.instance = instance
+	return s
+}
+
+// Register adds a job to the scheduler
+// Jobs must be registered before Start
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("job %q needs a name, schedule and run function", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.jobs {
+		if existing.Name == job.Name {
+			return fmt.Errorf("job %q is already registered", job.Name)
+		}
+	}
+
+	s.jobs = append(s.jobs, &scheduledJob{
+		Job:  job,
+		next: job.Schedule.Next(s.timeProvider.Now()),
+	})
+
+	s.logger.Info("Scheduled job registered", map[string]any{
+		"job":      job.Name,
+		"next_run": s.jobs[len(s.jobs)-1].next,
+	})
+	return nil
+}
+
+// Start runs the scheduling loop in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil || s.stopped {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.loop(ctx)
+
+	s.logger.Info("Scheduler started", map[string]any{
+		"jobs":     len(s.jobs),
+		"instance": s.instance,
+	})
+}
+
+// Stop cancels running jobs and waits for them and the scheduling loop to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+
+	s.logger.Info("Scheduler stopped", nil)
+}
+
+// loop dispatches due jobs and sleeps until the next one is due
+func (s *Scheduler) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		s.dispatch(ctx)
+
+		wait := s.timeProvider.Until(s.nextDue()).Std()
+		if wait > maxWait {
+			wait = maxWait
+		}
+
+		// Sleep on the time provider, so the loop wakes when its clock reaches the slot
+		waitCtx, cancel := s.timeProvider.WithTimeout(ctx, coreport.Duration(wait))
+		<-waitCtx.Done()
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// nextDue returns the earliest time any job is due, or the zero time if there are no jobs
+func (s *Scheduler) nextDue() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, job := range s.jobs {
+		if next.IsZero() || job.next.Before(next) {
+			next = job.next
+		}
+	}
+	if next.IsZero() {
+		return s.timeProvider.Now().Add(maxWait)
+	}
+	return next
+}
+
+// RunDue runs every job that is due now and waits for those runs to finish
+func (s *Scheduler) RunDue(ctx context.Context) {
+	s.dispatch(ctx).Wait()
+}
+
+// dispatch starts a run for every job that is due and moves each one on to its next slot
+// Slots missed while a previous run was still going, or while the process was down, are skipped.
+func (s *Scheduler) dispatch(ctx context.Context) *sync.WaitGroup {
+	var dispatched sync.WaitGroup
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timeProvider.Now()
+	for _, job := range s.jobs {
+		if now.Before(job.next) {
+			continue
+		}
+
+		slot := job.next
+		job.next = job.Schedule.Next(now)
+
+		if !job.running.CompareAndSwap(false, true) {
+			s.logger.Warn("Skipping scheduled job, previous run still in progress", map[string]any{
+				"job":  job.Name,
+				"slot": slot,
+			})
+			continue
+		}
+
+		dispatched.Add(1)
+		s.wg.Add(1)
+		go func(job *scheduledJob) {
+			defer s.wg.Done()
+			defer dispatched.Done()
+			defer job.running.Store(false)
+			s.runJob(ctx, job, slot)
+		}(job)
+	}
+
+	return &dispatched
+}
+
+// runJob runs one slot of a job if this instance wins the job's lock and the slot is still unclaimed
+func (s *Scheduler) runJob(ctx context.Context, job *scheduledJob, slot time.Time) {
+	unlock, acquired, err := s.jobLocks.TryLockJob(ctx, job.Name)
+	if err != nil {
+		s.logger.Error("Failed to acquire scheduled job lock", map[string]any{
+			"job":   job.Name,
+			"error": err.Error(),
+		})
+		return
+	}
+	if !acquired {
+		s.logger.Debug("Scheduled job is running on another instance", map[string]any{
+			"job":  job.Name,
+			"slot": slot,
+		})
+		return
+	}
+	defer unlock()
+
+	run := &entity.JobRun{
+		JobName:     job.Name,
+		ScheduledAt: slot,
+		StartedAt:   s.timeProvider.Now(),
+		Status:      entity.JobRunRunning,
+		Instance:    s.instance,
+	}
+	if err := s.runs.StartRun(ctx, run); err != nil {
+		if errors.Is(err, errs.ErrJobRunExists) {
+			s.logger.Debug("Scheduled job slot already handled", map[string]any{
+				"job":  job.Name,
+				"slot": slot,
+			})
+			return
+		}
+		// Without a history record other instances could not tell the slot was handled
+		s.logger.Error("Failed to record scheduled job run", map[string]any{
+			"job":   job.Name,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	runCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = s.timeProvider.WithTimeout(ctx, coreport.Duration(job.Timeout))
+		defer cancel()
+	}
+
+	runErr := s.safeRun(runCtx, job)
+	run.Finish(s.timeProvider.Now(), runErr)
+
+	// Record the outcome even if the scheduler is being stopped
+	if err := s.runs.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		s.logger.Error("Failed to record scheduled job outcome", map[string]any{
+			"job":   job.Name,
+			"error": err.Error(),
+		})
+	}
+
+	fields := map[string]any{
+		"job":         job.Name,
+		"slot":        slot,
+		"duration_ms": run.Duration().Milliseconds(),
+	}
+	if runErr != nil {
+		fields["error"] = runErr.Error()
+		s.logger.Error("Scheduled job failed", fields)
+		return
+	}
+	s.logger.Info("Scheduled job completed", fields)
+}
+
+// safeRun runs the job, turning a panic into an error so the scheduler keeps going
+func (s *Scheduler) safeRun(ctx context.Context, job *scheduledJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: job panicked: %v", errs.ErrInternalServer, r)
+		}
+	}()
+	return job.Run(ctx)
+}
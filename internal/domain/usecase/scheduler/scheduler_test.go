@@ -0,0 +1,279 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// schedulerFixture wires a Scheduler to mocked ports and a clock the test moves by hand
+type schedulerFixture struct {
+	scheduler *Scheduler
+	jobLocks  *persistencemocks.MockJobLockRepository
+	runs      *persistencemocks.MockJobRunRepository
+
+	mu      sync.Mutex
+	now     time.Time
+	timers  []fixtureTimer
+	waiting chan struct{} // Receives each time a timeout is started
+}
+
+// fixtureTimer cancels a context once the fixture's clock reaches its deadline
+type fixtureTimer struct {
+	deadline time.Time
+	cancel   context.CancelFunc
+}
+
+func newSchedulerFixture(t *testing.T) *schedulerFixture {
+	f := &schedulerFixture{
+		jobLocks: persistencemocks.NewMockJobLockRepository(t),
+		runs:     persistencemocks.NewMockJobRunRepository(t),
+		now:      time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC),
+		waiting:  make(chan struct{}, 10),
+	}
+
+	timeProvider := coremocks.NewMockTimeProvider(t)
+	timeProvider.EXPECT().Now().RunAndReturn(func() time.Time {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.now
+	}).Maybe()
+	timeProvider.EXPECT().Until(mock.Anything).RunAndReturn(func(until time.Time) coreport.Duration {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return coreport.Duration(until.Sub(f.now))
+	}).Maybe()
+	timeProvider.EXPECT().WithTimeout(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, d coreport.Duration) (context.Context, context.CancelFunc) {
+		timeoutCtx, cancel := context.WithCancel(ctx)
+		f.mu.Lock()
+		f.timers = append(f.timers, fixtureTimer{deadline: f.now.Add(d.Std()), cancel: cancel})
+		f.mu.Unlock()
+		f.waiting <- struct{}{}
+		return timeoutCtx, cancel
+	}).Maybe()
+
+	logger := coremocks.NewMockLogger(t)
+	logger.EXPECT().Debug(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Info(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Warn(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Error(mock.Anything, mock.Anything).Maybe()
+
+	f.scheduler = NewScheduler(f.jobLocks, f.runs, timeProvider, logger).WithInstance("test-1")
+	return f
+}
+
+// advance moves the clock forward and ends the timeouts it passes
+func (f *schedulerFixture) advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	var fired []fixtureTimer
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if f.now.Before(timer.deadline) {
+			pending = append(pending, timer)
+		} else {
+			fired = append(fired, timer)
+		}
+	}
+	f.timers = pending
+	f.mu.Unlock()
+
+	for _, timer := range fired {
+		timer.cancel()
+	}
+}
+
+// countingJob returns a job on a one-minute schedule that counts its runs
+func countingJob(name string, runErr error) (Job, *int) {
+	var runs int
+	return Job{
+		Name:     name,
+		Schedule: Every(time.Minute),
+		Run: func(ctx context.Context) error {
+			runs++
+			return runErr
+		},
+	}, &runs
+}
+
+func TestScheduler_RunsDueJobAndRecordsHistory(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, runs := countingJob("cleanup", nil)
+	require.NoError(t, f.scheduler.Register(job))
+
+	// Not due yet
+	f.scheduler.RunDue(context.Background())
+	assert.Equal(t, 0, *runs)
+
+	unlocked := false
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "cleanup").Return(func() { unlocked = true }, true, nil).Once()
+	f.runs.EXPECT().StartRun(mock.Anything, mock.MatchedBy(func(run *entity.JobRun) bool {
+		return run.ScheduledAt.Equal(time.Date(2024, 1, 1, 12, 1, 0, 0, time.UTC)) &&
+			run.Status == entity.JobRunRunning && run.Instance == "test-1"
+	})).Return(nil).Once()
+	f.runs.EXPECT().FinishRun(mock.Anything, mock.MatchedBy(func(run *entity.JobRun) bool {
+		return run.Status == entity.JobRunSucceeded && run.FinishedAt != nil
+	})).Return(nil).Once()
+
+	f.advance(time.Minute)
+	f.scheduler.RunDue(context.Background())
+
+	assert.Equal(t, 1, *runs)
+	assert.True(t, unlocked)
+
+	// The slot has been handled; nothing runs again until the next one
+	f.scheduler.RunDue(context.Background())
+	assert.Equal(t, 1, *runs)
+}
+
+func TestScheduler_LoopWakesWhenTheClockReachesTheSlot(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, runs := countingJob("cleanup", nil)
+	require.NoError(t, f.scheduler.Register(job))
+
+	finished := make(chan struct{})
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "cleanup").Return(func() {}, true, nil).Once()
+	f.runs.EXPECT().StartRun(mock.Anything, mock.Anything).Return(nil).Once()
+	f.runs.EXPECT().FinishRun(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, *entity.JobRun) error {
+		close(finished)
+		return nil
+	}).Once()
+
+	f.scheduler.Start()
+	defer f.scheduler.Stop()
+
+	// The loop sleeps until the slot 30s away; time passing short of it wakes nothing
+	<-f.waiting
+	f.advance(29 * time.Second)
+	select {
+	case <-f.waiting:
+		t.Fatal("the loop woke before the slot")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	f.advance(time.Second)
+	<-finished
+	assert.Equal(t, 1, *runs)
+}
+
+func TestScheduler_SkipsWhenAnotherInstanceHoldsTheLock(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, runs := countingJob("cleanup", nil)
+	require.NoError(t, f.scheduler.Register(job))
+
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "cleanup").Return(nil, false, nil).Once()
+
+	f.advance(time.Minute)
+	f.scheduler.RunDue(context.Background())
+	assert.Equal(t, 0, *runs)
+}
+
+func TestScheduler_SkipsSlotAlreadyHandled(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, runs := countingJob("cleanup", nil)
+	require.NoError(t, f.scheduler.Register(job))
+
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "cleanup").Return(func() {}, true, nil).Once()
+	f.runs.EXPECT().StartRun(mock.Anything, mock.Anything).Return(errs.ErrJobRunExists).Once()
+
+	f.advance(time.Minute)
+	f.scheduler.RunDue(context.Background())
+	assert.Equal(t, 0, *runs)
+}
+
+func TestScheduler_RecordsFailure(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, runs := countingJob("cleanup", errors.New("boom"))
+	require.NoError(t, f.scheduler.Register(job))
+
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "cleanup").Return(func() {}, true, nil).Once()
+	f.runs.EXPECT().StartRun(mock.Anything, mock.Anything).Return(nil).Once()
+	f.runs.EXPECT().FinishRun(mock.Anything, mock.MatchedBy(func(run *entity.JobRun) bool {
+		return run.Status == entity.JobRunFailed && run.Error == "boom"
+	})).Return(nil).Once()
+
+	f.advance(time.Minute)
+	f.scheduler.RunDue(context.Background())
+	assert.Equal(t, 1, *runs)
+}
+
+func TestScheduler_SkipsMissedSlots(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, runs := countingJob("cleanup", nil)
+	require.NoError(t, f.scheduler.Register(job))
+
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "cleanup").Return(func() {}, true, nil).Once()
+	f.runs.EXPECT().StartRun(mock.Anything, mock.Anything).Return(nil).Once()
+	f.runs.EXPECT().FinishRun(mock.Anything, mock.Anything).Return(nil).Once()
+
+	// Ten slots pass while the process is paused; only one run catches up
+	f.advance(10 * time.Minute)
+	f.scheduler.RunDue(context.Background())
+	f.scheduler.RunDue(context.Background())
+	assert.Equal(t, 1, *runs)
+}
+
+func TestScheduler_RecoversPanics(t *testing.T) {
+	f := newSchedulerFixture(t)
+	require.NoError(t, f.scheduler.Register(Job{
+		Name:     "panicky",
+		Schedule: Every(time.Minute),
+		Run:      func(ctx context.Context) error { panic("boom") },
+	}))
+
+	f.jobLocks.EXPECT().TryLockJob(mock.Anything, "panicky").Return(func() {}, true, nil).Once()
+	f.runs.EXPECT().StartRun(mock.Anything, mock.Anything).Return(nil).Once()
+	f.runs.EXPECT().FinishRun(mock.Anything, mock.MatchedBy(func(run *entity.JobRun) bool {
+		return run.Status == entity.JobRunFailed
+	})).Return(nil).Once()
+
+	f.advance(time.Minute)
+	f.scheduler.RunDue(context.Background())
+}
+
+func TestScheduler_RegisterValidates(t *testing.T) {
+	f := newSchedulerFixture(t)
+	job, _ := countingJob("cleanup", nil)
+
+	require.NoError(t, f.scheduler.Register(job))
+	assert.Error(t, f.scheduler.Register(job), "duplicate name")
+	assert.Error(t, f.scheduler.Register(Job{Name: "no-schedule", Run: job.Run}))
+}
+
+func TestLockCleanupJob(t *testing.T) {
+	lockRepo := persistencemocks.NewMockUserLockRepository(t)
+	lockRepo.EXPECT().CleanupExpiredLocks(mock.Anything).Return(nil).Once()
+
+	job := NewLockCleanupJob(lockRepo, Every(time.Minute))
+	assert.Equal(t, LockCleanupJobName, job.Name)
+	assert.NoError(t, job.Run(context.Background()))
+}
+
+// approvalExpirerFunc adapts a function to ApprovalExpirer
+type approvalExpirerFunc func(ctx context.Context) (int, error)
+
+func (f approvalExpirerFunc) ExpireStaleApprovals(ctx context.Context) (int, error) {
+	return f(ctx)
+}
+
+func TestApprovalExpiryJob(t *testing.T) {
+	calls := 0
+	job := NewApprovalExpiryJob(approvalExpirerFunc(func(ctx context.Context) (int, error) {
+		calls++
+		return 2, nil
+	}), Every(time.Minute))
+	assert.Equal(t, ApprovalExpiryJobName, job.Name)
+	assert.NoError(t, job.Run(context.Background()))
+	assert.Equal(t, 1, calls)
+}
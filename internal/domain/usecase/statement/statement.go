@@ -0,0 +1,270 @@
+// Package statement exports account statements: a user's opening balance, every transaction booked
+// in a period with the running balance after it, and the closing balance.
+package statement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+)
+
+// flushInterval is how many records are written between flushes, so that a long statement
+// reaches the reader as it is produced
+const flushInterval = 100
+
+// dateLayout is the layout of a period bound given as a calendar date
+const dateLayout = "2006-01-02"
+
+// RecordType says what a statement record holds
+type RecordType string
+
+// Record types, in the order they appear in a statement
+const (
+	RecordOpening     RecordType = "opening"     // Balance at the start of the period
+	RecordTransaction RecordType = "transaction" // One transaction booked in the period
+	RecordClosing     RecordType = "closing"     // Balance at the end of the period
+)
+
+// Record is one line of a statement
+type Record struct {
+	Type        RecordType
+	UserID      uint64
+	Time        time.Time           // Booking time of a transaction, or the period bound; zero for an open start
+	Transaction *entity.Transaction // Set for transaction records only
+	Balance     entity.Money        // Running balance after the record
+}
+
+// Description explains a transaction record: the reason of an adjustment, or why a
+// transaction did not change the balance
+func (r Record) Description() string {
+	txn := r.Transaction
+	switch {
+	case txn == nil:
+		return ""
+	case txn.Status != entity.StatusCompleted:
+		if txn.ErrorMessage != "" {
+			return txn.ErrorMessage
+		}
+		return txn.Status.String()
+	case txn.Adjustment != nil:
+		return txn.Adjustment.Reason.String()
+	default:
+		return ""
+	}
+}
+
+// Writer renders statement records in some format
+type Writer interface {
+	// WriteRecord writes one record; records may be buffered until Flush
+	WriteRecord(record Record) error
+	// Flush writes buffered records through to the reader
+	Flush() error
+}
+
+// Period is the range [From, To) of booking times a statement covers
+// A zero bound leaves that end of the period open.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParsePeriod parses period bounds given as RFC 3339 timestamps or as dates (YYYY-MM-DD)
+// A date as the end of the period includes that whole day. Empty bounds are left open.
+//
+// Possible errors:
+// - ErrInvalidRequest: If a bound cannot be parsed, or the period ends before it starts
+func ParsePeriod(from, to string) (Period, error) {
+	var period Period
+	var err error
+	if period.From, err = parseBound(from, false); err != nil {
+		return Period{}, fmt.Errorf("%w: invalid from %q: use RFC 3339 or YYYY-MM-DD", errs.ErrInvalidRequest, from)
+	}
+	if period.To, err = parseBound(to, true); err != nil {
+		return Period{}, fmt.Errorf("%w: invalid to %q: use RFC 3339 or YYYY-MM-DD", errs.ErrInvalidRequest, to)
+	}
+	if !period.From.IsZero() && !period.To.IsZero() && !period.From.Before(period.To) {
+		return Period{}, fmt.Errorf("%w: the period must end after it starts", errs.ErrInvalidRequest)
+	}
+	return period, nil
+}
+
+// parseBound parses one period bound; a date as the end bound moves it to the end of that day
+func parseBound(value string, end bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// Summary is what an exported statement contained
+type Summary struct {
+	UserID         uint64
+	OpeningBalance entity.Money
+	ClosingBalance entity.Money
+	Transactions   int // Transaction records written
+}
+
+// Exporter produces account statements from the transaction log
+type Exporter struct {
+	userRepo     persistence.UserRepository
+	txnRepo      persistence.TransactionRepository
+	timeProvider coreport.TimeProvider
+	logger       coreport.Logger
+}
+
+// NewExporter creates a new statement exporter
+func NewExporter(
+	userRepo persistence.UserRepository,
+	txnRepo persistence.TransactionRepository,
+	timeProvider coreport.TimeProvider,
+	logger coreport.Logger,
+) *Exporter {
+	return &Exporter{
+		userRepo:     userRepo,
+		txnRepo:      txnRepo,
+		timeProvider: timeProvider,
+		logger:       logger,
+	}
+}
+
+// Statement is a prepared statement for one user and period, ready to be written
+type Statement struct {
+	exporter       *Exporter
+	userID         uint64
+	period         Period
+	openingBalance entity.Money
+}
+
+// Prepare checks that the user exists and finds their opening balance, before anything is written
+// Users are created with an opening balance that is not logged, so the balance at the start of the
+// period is the result of the last completed transaction before it or, if there is none, the balance
+// before the user's first completed transaction.
+//
+// Possible errors:
+// - ErrUserNotFound: If user with specified ID doesn't exist
+// - ErrDatabaseConnection: If database connection fails
+func (e *Exporter) Prepare(ctx context.Context, userID uint64, period Period) (*Statement, error) {
+	user, err := e.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := e.openingBalance(ctx, user, period.From)
+	if err != nil {
+		return nil, err
+	}
+	return &Statement{exporter: e, userID: userID, period: period, openingBalance: opening}, nil
+}
+
+// openingBalance returns the user's balance before the first transaction booked at or after from
+func (e *Exporter) openingBalance(ctx context.Context, user *entity.User, from time.Time) (entity.Money, error) {
+	if !from.IsZero() {
+		txn, err := e.txnRepo.LastCompletedBefore(ctx, user.ID, from)
+		if err == nil {
+			return txn.ResultBalance, nil
+		}
+		if !errors.Is(err, errs.ErrTransactionNotFound) {
+			return entity.Money{}, err
+		}
+	}
+
+	txn, err := e.txnRepo.FirstCompleted(ctx, user.ID)
+	if errors.Is(err, errs.ErrTransactionNotFound) {
+		// Nothing ever changed the balance
+		return user.Balance(), nil
+	}
+	if err != nil {
+		return entity.Money{}, err
+	}
+	return txn.ResultBalance.Sub(txn.BalanceChange())
+}
+
+// Export prepares and writes a statement in one step
+func (e *Exporter) Export(ctx context.Context, userID uint64, period Period, w Writer) (*Summary, error) {
+	statement, err := e.Prepare(ctx, userID, period)
+	if err != nil {
+		return nil, err
+	}
+	return statement.WriteTo(ctx, w)
+}
+
+// WriteTo streams the statement to w: the opening balance, each transaction booked in the period
+// with the running balance after it, and the closing balance
+// Transactions that did not complete are listed without changing the running balance. A statement
+// cut short by an error has no closing record.
+func (s *Statement) WriteTo(ctx context.Context, w Writer) (*Summary, error) {
+	summary := &Summary{UserID: s.userID, OpeningBalance: s.openingBalance}
+	balance := s.openingBalance
+
+	if err := w.WriteRecord(Record{Type: RecordOpening, UserID: s.userID, Time: s.period.From, Balance: balance}); err != nil {
+		return nil, err
+	}
+
+	err := s.exporter.txnRepo.StreamByUser(ctx, s.userID, s.period.From, s.period.To, func(txn *entity.Transaction) error {
+		if txn.Status == entity.StatusCompleted {
+			balance = txn.ResultBalance
+		}
+		if err := w.WriteRecord(Record{
+			Type:        RecordTransaction,
+			UserID:      s.userID,
+			Time:        txn.BookedAt(),
+			Transaction: txn,
+			Balance:     balance,
+		}); err != nil {
+			return err
+		}
+		summary.Transactions++
+		if summary.Transactions%flushInterval == 0 {
+			return w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		s.exporter.logger.Error("Statement export failed", map[string]any{
+			"user_id":      s.userID,
+			"transactions": summary.Transactions,
+			"error":        err.Error(),
+		})
+		return nil, err
+	}
+
+	// An open period closes now, or at its start if that is still to come
+	closingTime := s.period.To
+	if closingTime.IsZero() {
+		closingTime = s.exporter.timeProvider.Now()
+		if closingTime.Before(s.period.From) {
+			closingTime = s.period.From
+		}
+	}
+	if err := w.WriteRecord(Record{Type: RecordClosing, UserID: s.userID, Time: closingTime, Balance: balance}); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	summary.ClosingBalance = balance
+	s.exporter.logger.Info("Statement exported", map[string]any{
+		"user_id":      s.userID,
+		"transactions": summary.Transactions,
+	})
+	return summary, nil
+}
@@ -0,0 +1,230 @@
+package statement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWriter keeps the records it is given and counts flushes
+type recordingWriter struct {
+	records []Record
+	flushes int
+	err     error
+}
+
+func (w *recordingWriter) WriteRecord(record Record) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.records = append(w.records, record)
+	return nil
+}
+
+func (w *recordingWriter) Flush() error {
+	w.flushes++
+	return nil
+}
+
+// statementTxn builds a logged transaction that left the given result balance
+func statementTxn(id, state string, cents, result int64, status entity.TransactionStatus) *entity.Transaction {
+	return &entity.Transaction{
+		UserID:        1,
+		TransactionID: id,
+		SourceType:    entity.SourceGame,
+		State:         entity.TransactionState(state),
+		Amount:        entity.MoneyFromCents(cents),
+		ResultBalance: entity.MoneyFromCents(result),
+		Status:        status,
+	}
+}
+
+type exporterFixture struct {
+	exporter *Exporter
+	userRepo *persistencemocks.MockUserRepository
+	txnRepo  *persistencemocks.MockTransactionRepository
+	now      time.Time
+}
+
+func newExporterFixture(t *testing.T) *exporterFixture {
+	now := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	timeProvider := coremocks.NewMockTimeProvider(t)
+	timeProvider.EXPECT().Now().Return(now).Maybe()
+
+	logger := coremocks.NewMockLogger(t)
+	logger.EXPECT().Info(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Error(mock.Anything, mock.Anything).Maybe()
+
+	userRepo := persistencemocks.NewMockUserRepository(t)
+	txnRepo := persistencemocks.NewMockTransactionRepository(t)
+	user, err := entity.NewUser(1, "50.00", timeProvider)
+	require.NoError(t, err)
+	userRepo.EXPECT().GetByID(mock.Anything, uint64(1)).Return(user, nil).Maybe()
+	userRepo.EXPECT().GetByID(mock.Anything, uint64(2)).Return(nil, errs.ErrUserNotFound).Maybe()
+
+	return &exporterFixture{
+		exporter: NewExporter(userRepo, txnRepo, timeProvider, logger),
+		userRepo: userRepo,
+		txnRepo:  txnRepo,
+		now:      now,
+	}
+}
+
+// stream makes the repository stream the given transactions
+func (f *exporterFixture) stream(txns ...*entity.Transaction) {
+	f.txnRepo.EXPECT().StreamByUser(mock.Anything, uint64(1), mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, _ uint64, _, _ time.Time, fn func(*entity.Transaction) error) error {
+			for _, txn := range txns {
+				if err := fn(txn); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
+
+func TestExporter_Export(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Running balance starts from the last transaction before the period", func(t *testing.T) {
+		f := newExporterFixture(t)
+		f.txnRepo.EXPECT().LastCompletedBefore(mock.Anything, uint64(1), from).
+			Return(statementTxn("t0", "win", 1000, 8000, entity.StatusCompleted), nil)
+		failed := statementTxn("t2", "lose", 99900, 0, entity.StatusFailed)
+		failed.ErrorMessage = "insufficient balance"
+		f.stream(
+			statementTxn("t1", "win", 500, 8500, entity.StatusCompleted),
+			failed,
+			statementTxn("t3", "lose", 2500, 6000, entity.StatusCompleted),
+		)
+
+		w := &recordingWriter{}
+		summary, err := f.exporter.Export(ctx, 1, Period{From: from, To: to}, w)
+		require.NoError(t, err)
+		assert.Equal(t, &Summary{UserID: 1, OpeningBalance: entity.MoneyFromCents(8000), ClosingBalance: entity.MoneyFromCents(6000), Transactions: 3}, summary)
+
+		require.Len(t, w.records, 5)
+		assert.Equal(t, Record{Type: RecordOpening, UserID: 1, Time: from, Balance: entity.MoneyFromCents(8000)}, w.records[0])
+		assert.Equal(t, entity.MoneyFromCents(8500), w.records[1].Balance)
+		// A failed transaction leaves the balance as it was
+		assert.Equal(t, entity.MoneyFromCents(8500), w.records[2].Balance)
+		assert.Equal(t, "insufficient balance", w.records[2].Description())
+		assert.Equal(t, entity.MoneyFromCents(6000), w.records[3].Balance)
+		assert.Equal(t, Record{Type: RecordClosing, UserID: 1, Time: to, Balance: entity.MoneyFromCents(6000)}, w.records[4])
+		assert.Equal(t, 1, w.flushes)
+	})
+
+	t.Run("Opening balance precedes the first transaction when nothing came before the period", func(t *testing.T) {
+		f := newExporterFixture(t)
+		f.txnRepo.EXPECT().LastCompletedBefore(mock.Anything, uint64(1), from).Return(nil, errs.ErrTransactionNotFound)
+		f.txnRepo.EXPECT().FirstCompleted(mock.Anything, uint64(1)).
+			Return(statementTxn("t1", "lose", 2500, 7500, entity.StatusCompleted), nil)
+		f.stream(statementTxn("t1", "lose", 2500, 7500, entity.StatusCompleted))
+
+		w := &recordingWriter{}
+		summary, err := f.exporter.Export(ctx, 1, Period{From: from}, w)
+		require.NoError(t, err)
+		assert.Equal(t, entity.MoneyFromCents(10000), summary.OpeningBalance)
+		assert.Equal(t, entity.MoneyFromCents(7500), summary.ClosingBalance)
+		// An open period closes now
+		assert.Equal(t, f.now, w.records[len(w.records)-1].Time)
+	})
+
+	t.Run("Opening balance is the user's balance when nothing ever completed", func(t *testing.T) {
+		f := newExporterFixture(t)
+		f.txnRepo.EXPECT().FirstCompleted(mock.Anything, uint64(1)).Return(nil, errs.ErrTransactionNotFound)
+		f.stream()
+
+		w := &recordingWriter{}
+		summary, err := f.exporter.Export(ctx, 1, Period{}, w)
+		require.NoError(t, err)
+		assert.Equal(t, &Summary{UserID: 1, OpeningBalance: entity.MoneyFromCents(5000), ClosingBalance: entity.MoneyFromCents(5000)}, summary)
+		assert.Len(t, w.records, 2)
+	})
+
+	t.Run("Long statements are flushed as they are written", func(t *testing.T) {
+		f := newExporterFixture(t)
+		f.txnRepo.EXPECT().FirstCompleted(mock.Anything, uint64(1)).Return(nil, errs.ErrTransactionNotFound)
+		txns := make([]*entity.Transaction, 2*flushInterval+1)
+		for i := range txns {
+			txns[i] = statementTxn(fmt.Sprintf("t%d", i), "win", 1, 5001+int64(i), entity.StatusCompleted)
+		}
+		f.stream(txns...)
+
+		w := &recordingWriter{}
+		_, err := f.exporter.Export(ctx, 1, Period{}, w)
+		require.NoError(t, err)
+		assert.Equal(t, 3, w.flushes)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		f := newExporterFixture(t)
+		_, err := f.exporter.Export(ctx, 2, Period{}, &recordingWriter{})
+		assert.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+
+	t.Run("A failed write leaves the statement without a closing record", func(t *testing.T) {
+		f := newExporterFixture(t)
+		f.txnRepo.EXPECT().FirstCompleted(mock.Anything, uint64(1)).Return(nil, errs.ErrTransactionNotFound)
+		f.txnRepo.EXPECT().StreamByUser(mock.Anything, uint64(1), mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrDatabaseConnection)
+
+		w := &recordingWriter{}
+		_, err := f.exporter.Export(ctx, 1, Period{}, w)
+		assert.ErrorIs(t, err, errs.ErrDatabaseConnection)
+		require.Len(t, w.records, 1)
+		assert.Equal(t, RecordOpening, w.records[0].Type)
+
+		w = &recordingWriter{err: errors.New("broken pipe")}
+		_, err = f.exporter.Export(ctx, 1, Period{}, w)
+		assert.EqualError(t, err, "broken pipe")
+	})
+}
+
+func TestParsePeriod(t *testing.T) {
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     Period
+		wantErr  bool
+	}{
+		{name: "Open", want: Period{}},
+		{name: "Dates include the last day", from: "2024-01-01", to: "2024-01-31",
+			want: Period{From: jan1, To: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}},
+		{name: "Timestamps are taken as they are", from: "2024-01-01T00:00:00Z", to: "2024-01-01T12:30:00+02:00",
+			want: Period{From: jan1, To: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)}},
+		{name: "Open end", from: "2024-01-01", want: Period{From: jan1}},
+		{name: "Invalid from", from: "yesterday", wantErr: true},
+		{name: "Invalid to", to: "2024-13-01", wantErr: true},
+		{name: "End before start", from: "2024-01-02", to: "2024-01-01T00:00:00Z", wantErr: true},
+		{name: "Empty period", from: "2024-01-01T00:00:00Z", to: "2024-01-01T00:00:00Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, err := ParsePeriod(tt.from, tt.to)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.From.Equal(period.From), "from: %v", period.From)
+			assert.True(t, tt.want.To.Equal(period.To), "to: %v", period.To)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package transaction
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_AdjustBalance(t *testing.T) {
+	service, fastPath := newDrainTestService(t)
+
+	var applied *entity.Transaction
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+		applied = txn.Clone()
+		processed := txn.Clone()
+		processed.Status = entity.StatusCompleted
+		processed.ResultBalance = entity.MoneyFromCents(2500)
+		return processed, false, nil
+	}).Once()
+
+	resp, err := service.AdjustBalance(context.Background(), 1, AdjustmentRequest{
+		TransactionID: "adj-1",
+		Direction:     AdjustmentDebit,
+		Amount:        "5.00",
+		Reason:        "chargeback",
+		Note:          "dispute #42",
+		Operator:      "alice",
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, entity.MoneyFromCents(2500), resp.ResultBalance)
+
+	require.NotNil(t, applied)
+	assert.Equal(t, entity.SourceAdmin, applied.SourceType)
+	assert.Equal(t, entity.StateLose, applied.State)
+	assert.Equal(t, &entity.Adjustment{Reason: entity.ReasonChargeback, Note: "dispute #42", Operator: "alice"}, applied.Adjustment)
+}
+
+func TestService_AdjustBalanceRejectsInvalidRequests(t *testing.T) {
+	valid := AdjustmentRequest{TransactionID: "adj-1", Direction: AdjustmentCredit, Amount: "5.00", Reason: "goodwill", Note: "outage", Operator: "alice"}
+
+	tests := []struct {
+		name   string
+		modify func(req *AdjustmentRequest)
+	}{
+		{"unknown direction", func(req *AdjustmentRequest) { req.Direction = "sideways" }},
+		{"unknown reason", func(req *AdjustmentRequest) { req.Reason = "bonus" }},
+		{"missing note", func(req *AdjustmentRequest) { req.Note = "" }},
+		{"missing operator", func(req *AdjustmentRequest) { req.Operator = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fast path mock fails the test if anything reaches it
+			service, _ := newDrainTestService(t)
+
+			req := valid
+			tt.modify(&req)
+			resp, err := service.AdjustBalance(context.Background(), 1, req)
+			assert.ErrorIs(t, err, errs.ErrInvalidAdjustment)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
+
+func TestService_AdminTransactionsNeedAnAdjustment(t *testing.T) {
+	service, _ := newDrainTestService(t)
+
+	resp, err := service.ProcessTransaction(context.Background(), 1, TransactionRequest{
+		TransactionID: "tx-1",
+		SourceType:    entity.SourceAdmin,
+		State:         "win",
+		Amount:        "5.00",
+	})
+	assert.ErrorIs(t, err, errs.ErrInvalidAdjustment)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
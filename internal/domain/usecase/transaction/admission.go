@@ -0,0 +1,298 @@
+package transaction
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// Defaults for the admission controller
+const (
+	DefaultAdmissionQueueSize    = 1000
+	DefaultAdmissionQueueTimeout = 500 * time.Millisecond
+	DefaultAdmissionRetryAfter   = time.Second
+)
+
+// Priority orders transactions waiting for admission; higher priorities are admitted first
+type Priority int
+
+// Admission priorities, lowest first
+const (
+	PriorityLow    Priority = iota // game
+	PriorityNormal                 // server
+	PriorityHigh                   // payment, admin
+
+	priorityCount = int(PriorityHigh) + 1
+)
+
+// PriorityFor returns the admission priority of transactions from the given source
+// Payments move real money in and out, so they are admitted ahead of game traffic,
+// and so are operators' adjustments, which are rare and usually made during an incident
+func PriorityFor(source entity.SourceType) Priority {
+	switch source {
+	case entity.SourcePayment, entity.SourceAdmin:
+		return PriorityHigh
+	case entity.SourceServer:
+		return PriorityNormal
+	default:
+		return PriorityLow
+	}
+}
+
+// String returns the priority's name as used in metrics
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	default:
+		return "low"
+	}
+}
+
+// AdmissionPolicy limits how many transactions an instance processes at once
+type AdmissionPolicy struct {
+	Limit        int           // Transactions processed concurrently
+	QueueSize    int           // Transactions allowed to wait for a slot; 0 sheds as soon as the limit is reached
+	QueueTimeout time.Duration // Longest a transaction waits for a slot before it is shed
+	RetryAfter   time.Duration // Delay suggested to shed clients
+}
+
+// AdmissionMetrics is a snapshot of the admission controller's state and counters
+type AdmissionMetrics struct {
+	Limit            int            // Transactions processed concurrently
+	Active           int            // Transactions currently holding a slot
+	Queued           int            // Transactions currently waiting for a slot
+	QueueCapacity    int            // Transactions allowed to wait
+	QueuedByPriority map[string]int // Waiting transactions per priority
+	Admitted         int64          // Transactions that got a slot, directly or after waiting
+	Waited           int64          // Transactions that had to wait for a slot
+	Shed             int64          // Transactions refused because the queue was full or a higher priority took their place
+	TimedOut         int64          // Transactions shed after waiting QueueTimeout
+	Cancelled        int64          // Transactions whose request ended while waiting
+}
+
+// AdmissionController caps the number of transactions processed concurrently by one instance
+// Transactions beyond the limit wait in a bounded queue ordered by priority, then by arrival.
+// When the queue is full a newcomer displaces the newest waiter of a lower priority, or is shed
+// itself if there is none. A freed slot is handed straight to the next waiter, so a waiter can
+// never be overtaken by a transaction that arrives later.
+type AdmissionController struct {
+	policy       AdmissionPolicy
+	timeProvider coreport.TimeProvider
+	logger       coreport.Logger
+
+	mu      sync.Mutex
+	active  int
+	queued  int
+	waiters [priorityCount]*list.List // FIFO of *admissionWaiter per priority
+
+	admitted  atomic.Int64
+	waited    atomic.Int64
+	shed      atomic.Int64
+	timedOut  atomic.Int64
+	cancelled atomic.Int64
+}
+
+// admissionWaiter is a transaction waiting for a slot
+type admissionWaiter struct {
+	priority Priority
+	elem     *list.Element
+	result   chan error // Receives nil once the waiter holds a slot, or the error it was shed with
+}
+
+// NewAdmissionController creates an admission controller with the given policy
+func NewAdmissionController(policy AdmissionPolicy, timeProvider coreport.TimeProvider, logger coreport.Logger) *AdmissionController {
+	if policy.Limit <= 0 {
+		policy.Limit = 1
+	}
+	if policy.QueueSize < 0 {
+		policy.QueueSize = 0
+	}
+	if policy.QueueTimeout <= 0 {
+		policy.QueueTimeout = DefaultAdmissionQueueTimeout
+	}
+	if policy.RetryAfter <= 0 {
+		policy.RetryAfter = DefaultAdmissionRetryAfter
+	}
+
+	a := &AdmissionController{
+		policy:       policy,
+		timeProvider: timeProvider,
+		logger:       logger,
+	}
+	for i := range a.waiters {
+		a.waiters[i] = list.New()
+	}
+	return a
+}
+
+// Acquire waits for a processing slot for a transaction from the given source
+// The returned function releases the slot and must be called once the transaction is done.
+//
+// Possible errors:
+// - ErrOverloaded: If the queue is full, the transaction was displaced, or it waited QueueTimeout
+// - ctx.Err(): If ctx ended while the transaction was waiting
+func (a *AdmissionController) Acquire(ctx context.Context, source entity.SourceType) (func(), error) {
+	priority := PriorityFor(source)
+
+	a.mu.Lock()
+	// Waiters only exist while every slot is taken, so a free slot can be used right away
+	if a.active < a.policy.Limit {
+		a.active++
+		a.mu.Unlock()
+		a.admitted.Add(1)
+		return a.releaseFunc(), nil
+	}
+
+	if a.queued >= a.policy.QueueSize && !a.displaceLocked(priority) {
+		a.mu.Unlock()
+		a.shed.Add(1)
+		a.logger.Warn("Transaction shed, admission queue is full", map[string]any{
+			"priority":   priority.String(),
+			"active":     a.policy.Limit,
+			"queue_size": a.policy.QueueSize,
+		})
+		return nil, fmt.Errorf("%w: %d transactions in flight and %d waiting", errs.ErrOverloaded, a.policy.Limit, a.policy.QueueSize)
+	}
+
+	w := &admissionWaiter{priority: priority, result: make(chan error, 1)}
+	w.elem = a.waiters[priority].PushBack(w)
+	a.queued++
+	a.mu.Unlock()
+	a.waited.Add(1)
+
+	return a.wait(ctx, w)
+}
+
+// wait blocks until the waiter is handed a slot, is displaced, or gives up
+func (a *AdmissionController) wait(ctx context.Context, w *admissionWaiter) (func(), error) {
+	waitCtx, cancel := a.timeProvider.WithTimeout(ctx, coreport.Duration(a.policy.QueueTimeout))
+	defer cancel()
+
+	select {
+	case err := <-w.result:
+		return a.admittedOrShed(err)
+	case <-waitCtx.Done():
+	}
+
+	a.mu.Lock()
+	if w.elem == nil {
+		// Handed a slot or displaced just as the wait ended; the result is already there
+		a.mu.Unlock()
+		return a.admittedOrShed(<-w.result)
+	}
+	a.removeLocked(w)
+	a.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		a.cancelled.Add(1)
+		return nil, err
+	}
+	a.timedOut.Add(1)
+	a.logger.Warn("Transaction shed after waiting for admission", map[string]any{
+		"priority": w.priority.String(),
+		"waited":   a.policy.QueueTimeout.String(),
+	})
+	return nil, fmt.Errorf("%w: no slot within %s", errs.ErrOverloaded, a.policy.QueueTimeout)
+}
+
+// admittedOrShed turns a waiter's result into Acquire's return values
+func (a *AdmissionController) admittedOrShed(err error) (func(), error) {
+	if err != nil {
+		return nil, err
+	}
+	a.admitted.Add(1)
+	return a.releaseFunc(), nil
+}
+
+// displaceLocked sheds the newest waiter with a priority below the given one to make room
+// It reports false if every waiter has at least that priority
+// The caller must hold a.mu
+func (a *AdmissionController) displaceLocked(priority Priority) bool {
+	for p := PriorityLow; p < priority; p++ {
+		elem := a.waiters[p].Back()
+		if elem == nil {
+			continue
+		}
+
+		w := elem.Value.(*admissionWaiter)
+		a.removeLocked(w)
+		a.shed.Add(1)
+		w.result <- fmt.Errorf("%w: displaced by a %s priority transaction", errs.ErrOverloaded, priority)
+		return true
+	}
+	return false
+}
+
+// removeLocked takes a waiter out of its queue
+// The caller must hold a.mu
+func (a *AdmissionController) removeLocked(w *admissionWaiter) {
+	a.waiters[w.priority].Remove(w.elem)
+	w.elem = nil
+	a.queued--
+}
+
+// releaseFunc returns a function that releases a slot once, however often it is called
+func (a *AdmissionController) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(a.release)
+	}
+}
+
+// release hands the slot to the highest-priority waiter, or frees it if nobody is waiting
+func (a *AdmissionController) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for p := PriorityHigh; p >= PriorityLow; p-- {
+		elem := a.waiters[p].Front()
+		if elem == nil {
+			continue
+		}
+
+		w := elem.Value.(*admissionWaiter)
+		a.removeLocked(w)
+		w.result <- nil
+		return
+	}
+	a.active--
+}
+
+// RetryAfter returns the delay suggested to clients whose transaction was shed
+func (a *AdmissionController) RetryAfter() time.Duration {
+	return a.policy.RetryAfter
+}
+
+// Metrics returns a snapshot of the admission controller's state and counters
+func (a *AdmissionController) Metrics() AdmissionMetrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	byPriority := make(map[string]int, priorityCount)
+	for p := PriorityLow; p <= PriorityHigh; p++ {
+		byPriority[p.String()] = a.waiters[p].Len()
+	}
+
+	return AdmissionMetrics{
+		Limit:            a.policy.Limit,
+		Active:           a.active,
+		Queued:           a.queued,
+		QueueCapacity:    a.policy.QueueSize,
+		QueuedByPriority: byPriority,
+		Admitted:         a.admitted.Load(),
+		Waited:           a.waited.Load(),
+		Shed:             a.shed.Load(),
+		TimedOut:         a.timedOut.Load(),
+		Cancelled:        a.cancelled.Load(),
+	}
+}
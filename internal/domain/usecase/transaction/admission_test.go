@@ -0,0 +1,185 @@
+package transaction
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTimeProvider returns a time provider mock whose timeouts use the real clock
+func newTestTimeProvider(t *testing.T) *coremocks.MockTimeProvider {
+	timeProvider := coremocks.NewMockTimeProvider(t)
+	timeProvider.EXPECT().Now().Return(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)).Maybe()
+	timeProvider.EXPECT().Since(mock.Anything).Return(0).Maybe()
+	timeProvider.EXPECT().WithTimeout(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, d coreport.Duration) (context.Context, context.CancelFunc) {
+		return context.WithTimeout(ctx, d.Std())
+	}).Maybe()
+	return timeProvider
+}
+
+// admissionResult is the outcome of an Acquire call made in the background
+type admissionResult struct {
+	release func()
+	err     error
+}
+
+// acquireAsync calls Acquire in the background and waits until the call is queued
+func acquireAsync(t *testing.T, a *AdmissionController, ctx context.Context, source entity.SourceType) <-chan admissionResult {
+	queued := a.Metrics().Queued
+	results := make(chan admissionResult, 1)
+	go func() {
+		release, err := a.Acquire(ctx, source)
+		results <- admissionResult{release: release, err: err}
+	}()
+	require.Eventually(t, func() bool { return a.Metrics().Queued > queued }, time.Second, time.Millisecond)
+	return results
+}
+
+func TestAdmissionController_LimitsConcurrency(t *testing.T) {
+	a := NewAdmissionController(AdmissionPolicy{Limit: 2, QueueSize: 10, QueueTimeout: time.Second}, newTestTimeProvider(t), newTestLogger(t))
+
+	release1, err := a.Acquire(context.Background(), entity.SourceGame)
+	require.NoError(t, err)
+	release2, err := a.Acquire(context.Background(), entity.SourceGame)
+	require.NoError(t, err)
+
+	waiting := acquireAsync(t, a, context.Background(), entity.SourceGame)
+	metrics := a.Metrics()
+	assert.Equal(t, 2, metrics.Active)
+	assert.Equal(t, 1, metrics.Queued)
+	assert.Equal(t, 1, metrics.QueuedByPriority["low"])
+
+	// The freed slot goes to the waiter
+	release1()
+	release1() // Releasing twice must not free a second slot
+	result := <-waiting
+	require.NoError(t, result.err)
+	assert.Equal(t, 2, a.Metrics().Active)
+
+	result.release()
+	release2()
+	metrics = a.Metrics()
+	assert.Zero(t, metrics.Active)
+	assert.Equal(t, int64(3), metrics.Admitted)
+	assert.Equal(t, int64(1), metrics.Waited)
+}
+
+func TestAdmissionController_ShedsWhenQueueIsFull(t *testing.T) {
+	a := NewAdmissionController(AdmissionPolicy{Limit: 1, QueueSize: 1, QueueTimeout: time.Second}, newTestTimeProvider(t), newTestLogger(t))
+
+	release, err := a.Acquire(context.Background(), entity.SourceGame)
+	require.NoError(t, err)
+	waiting := acquireAsync(t, a, context.Background(), entity.SourceGame)
+
+	_, err = a.Acquire(context.Background(), entity.SourceGame)
+	assert.ErrorIs(t, err, errs.ErrOverloaded)
+	assert.Equal(t, int64(1), a.Metrics().Shed)
+
+	release()
+	result := <-waiting
+	require.NoError(t, result.err)
+	result.release()
+}
+
+func TestAdmissionController_AdmitsPaymentsFirst(t *testing.T) {
+	a := NewAdmissionController(AdmissionPolicy{Limit: 1, QueueSize: 10, QueueTimeout: time.Second}, newTestTimeProvider(t), newTestLogger(t))
+
+	release, err := a.Acquire(context.Background(), entity.SourceGame)
+	require.NoError(t, err)
+
+	game := acquireAsync(t, a, context.Background(), entity.SourceGame)
+	server := acquireAsync(t, a, context.Background(), entity.SourceServer)
+	payment := acquireAsync(t, a, context.Background(), entity.SourcePayment)
+
+	// Each release hands the slot to the highest priority still waiting, regardless of arrival order
+	release()
+	result := <-payment
+	require.NoError(t, result.err)
+	assert.Empty(t, server)
+	assert.Empty(t, game)
+
+	result.release()
+	result = <-server
+	require.NoError(t, result.err)
+	assert.Empty(t, game)
+
+	result.release()
+	result = <-game
+	require.NoError(t, result.err)
+	result.release()
+}
+
+func TestAdmissionController_PaymentDisplacesGameWhenQueueIsFull(t *testing.T) {
+	a := NewAdmissionController(AdmissionPolicy{Limit: 1, QueueSize: 1, QueueTimeout: time.Second}, newTestTimeProvider(t), newTestLogger(t))
+
+	release, err := a.Acquire(context.Background(), entity.SourceGame)
+	require.NoError(t, err)
+	game := acquireAsync(t, a, context.Background(), entity.SourceGame)
+
+	// The queued game transaction makes room for the payment
+	payment := make(chan admissionResult, 1)
+	go func() {
+		release, err := a.Acquire(context.Background(), entity.SourcePayment)
+		payment <- admissionResult{release: release, err: err}
+	}()
+	assert.ErrorIs(t, (<-game).err, errs.ErrOverloaded)
+
+	// A game transaction cannot displace the payment
+	_, err = a.Acquire(context.Background(), entity.SourceGame)
+	assert.ErrorIs(t, err, errs.ErrOverloaded)
+
+	release()
+	result := <-payment
+	require.NoError(t, result.err)
+	result.release()
+	assert.Equal(t, int64(2), a.Metrics().Shed)
+}
+
+func TestAdmissionController_GivesUpWaiting(t *testing.T) {
+	a := NewAdmissionController(AdmissionPolicy{Limit: 1, QueueSize: 10, QueueTimeout: 20 * time.Millisecond}, newTestTimeProvider(t), newTestLogger(t))
+
+	release, err := a.Acquire(context.Background(), entity.SourceGame)
+	require.NoError(t, err)
+	defer release()
+
+	// A transaction that waits too long is shed
+	_, err = a.Acquire(context.Background(), entity.SourceGame)
+	assert.ErrorIs(t, err, errs.ErrOverloaded)
+
+	// A transaction whose request ends while waiting gets the context's error
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := acquireAsync(t, a, ctx, entity.SourceGame)
+	cancel()
+	assert.ErrorIs(t, (<-waiting).err, context.Canceled)
+
+	metrics := a.Metrics()
+	assert.Equal(t, int64(1), metrics.TimedOut)
+	assert.Equal(t, int64(1), metrics.Cancelled)
+	assert.Zero(t, metrics.Queued)
+	assert.Equal(t, 1, metrics.Active)
+}
+
+func TestService_ShedTransactionGets503WithRetryAfter(t *testing.T) {
+	service, _ := newDrainTestService(t)
+	service.WithAdmissionControl(AdmissionPolicy{Limit: 1, QueueSize: 0, RetryAfter: 2 * time.Second})
+
+	// Occupy the only slot
+	release, err := service.GetAdmission().Acquire(context.Background(), entity.SourcePayment)
+	require.NoError(t, err)
+	defer release()
+
+	resp, err := service.ProcessTransaction(context.Background(), 1, drainTestRequest("tx-1"))
+	assert.ErrorIs(t, err, errs.ErrOverloaded)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 2*time.Second, resp.RetryAfter)
+	assert.Equal(t, 5032, errs.ErrorCode(err))
+}
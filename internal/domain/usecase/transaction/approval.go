@@ -0,0 +1,337 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+)
+
+// ApprovalPolicy decides which transactions a second person has to approve
+// Thresholds are in cents; a zero threshold holds nothing of that kind.
+type ApprovalPolicy struct {
+	AdjustmentThreshold int64         // Admin adjustments above this amount wait for approval
+	WithdrawalThreshold int64         // Payment withdrawals (lose) above this amount wait for approval
+	Expiry              time.Duration // Requests not approved within this time expire; 0 means never
+}
+
+// NewApprovalPolicy creates a policy from thresholds given as amounts such as "1000.00"
+// An empty or zero threshold disables approvals of that kind.
+func NewApprovalPolicy(adjustmentThreshold, withdrawalThreshold string, expiry time.Duration) (ApprovalPolicy, error) {
+	policy := ApprovalPolicy{Expiry: expiry}
+	for _, threshold := range []struct {
+		name   string
+		amount string
+		target *int64
+	}{
+		{"adjustment threshold", adjustmentThreshold, &policy.AdjustmentThreshold},
+		{"withdrawal threshold", withdrawalThreshold, &policy.WithdrawalThreshold},
+	} {
+		if strings.TrimSpace(threshold.amount) == "" {
+			continue
+		}
+		cents, err := entity.ValidateAndConvertAmount(threshold.amount)
+		if err != nil {
+			return ApprovalPolicy{}, fmt.Errorf("invalid %s %q: %w", threshold.name, threshold.amount, err)
+		}
+		*threshold.target = cents
+	}
+	return policy, nil
+}
+
+// Enabled reports whether the policy holds any transactions at all
+func (p ApprovalPolicy) Enabled() bool {
+	return p.AdjustmentThreshold > 0 || p.WithdrawalThreshold > 0
+}
+
+// Requires reports whether the transaction has to be approved before it is applied
+func (p ApprovalPolicy) Requires(txn *entity.Transaction) bool {
+	switch {
+	case txn.SourceType == entity.SourceAdmin:
+		return p.AdjustmentThreshold > 0 && txn.Amount.Cents() > p.AdjustmentThreshold
+	case txn.SourceType == entity.SourcePayment && txn.IsDebit():
+		return p.WithdrawalThreshold > 0 && txn.Amount.Cents() > p.WithdrawalThreshold
+	default:
+		return false
+	}
+}
+
+// requiresApproval is Requires for a request that has not been turned into a transaction yet
+// Invalid input reports false; creating the transaction rejects it anyway.
+func (p ApprovalPolicy) requiresApproval(sourceType, state, amount string) bool {
+	if !p.Enabled() {
+		return false
+	}
+	parsedAmount, err := entity.ParseMoney(strings.TrimSpace(amount))
+	if err != nil {
+		return false
+	}
+	return p.Requires(&entity.Transaction{
+		SourceType: entity.SourceType(sourceType),
+		State:      entity.TransactionState(state),
+		Amount:     parsedAmount,
+	})
+}
+
+// isExpired reports whether a held transaction waited longer than the expiry
+func (p ApprovalPolicy) isExpired(txn *entity.Transaction, now time.Time) bool {
+	return p.Expiry > 0 && now.Sub(txn.CreatedAt) > p.Expiry
+}
+
+// WithApprovalPolicy holds the transactions the policy selects until a second person approves them
+func (m *TransactionManager) WithApprovalPolicy(policy ApprovalPolicy) *TransactionManager {
+	m.approvalPolicy = policy
+	return m
+}
+
+// ApprovalPolicy returns the policy in use
+func (m *TransactionManager) ApprovalPolicy() ApprovalPolicy {
+	return m.approvalPolicy
+}
+
+// holdForApproval stores the transaction as awaiting approval without touching the balance
+// It runs inside the unit of work of executeTransaction, under the user's lock.
+func (m *TransactionManager) holdForApproval(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, error) {
+	txn.MarkAsPendingApproval()
+	if err := m.unitOfWork.GetTransactionRepository(ctx).Create(ctx, txn); err != nil {
+		return nil, fmt.Errorf("failed to save transaction: %w", err)
+	}
+
+	event := entity.NewApprovalEvent(txn, entity.ApprovalRequested, txn.Requester(), "", m.timeProvider)
+	if txn.Adjustment != nil {
+		event.Note = txn.Adjustment.Note
+	}
+	if err := m.unitOfWork.GetApprovalAuditRepository(ctx).Record(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to record approval request: %w", err)
+	}
+
+	m.logger.Info("Transaction held for approval", map[string]any{
+		"transaction_id": txn.TransactionID,
+		"user_id":        txn.UserID,
+		"amount":         txn.GetAmount(),
+		"requester":      txn.Requester(),
+	})
+	return txn, nil
+}
+
+// Approve applies a held transaction to the balance on behalf of the approver
+// The approver must not be the one who requested the transaction, as its operator or as the account holder.
+//
+// Possible errors:
+// - ErrTransactionNotFound: If the transaction doesn't exist
+// - ErrApprovalNotPending: If the transaction is not awaiting approval
+// - ErrSelfApproval: If the approver requested the transaction
+// - ErrApprovalExpired: If the request waited too long; it is marked expired
+// - ErrInsufficientBalance: If the user can no longer cover a debit; it is marked failed
+// - ErrAmountOverflow: If the balance cannot hold a credit; it is marked failed
+// - ErrBalanceLimitExceeded: If a credit would take the balance above its maximum; it is marked failed
+func (m *TransactionManager) Approve(ctx context.Context, transactionID string, approver entity.Principal, note string) (*entity.Transaction, error) {
+	txn, err := m.resolveApproval(ctx, transactionID, entity.ApprovalApproved, approver, note)
+	if err != nil {
+		return nil, err
+	}
+	switch txn.Status {
+	case entity.StatusExpired:
+		return txn, fmt.Errorf("%w: transaction %s was not approved within %s", errs.ErrApprovalExpired, transactionID, m.approvalPolicy.Expiry)
+	case entity.StatusFailed:
+		reason := errs.ErrInsufficientBalance
+		switch txn.ErrorMessage {
+		case balanceOverflowMessage:
+			reason = errs.ErrAmountOverflow
+		case balanceLimitMessage:
+			reason = errs.ErrBalanceLimitExceeded
+		}
+		return txn, fmt.Errorf("approved transaction %s could not be applied: %w", transactionID, reason)
+	}
+	return txn, nil
+}
+
+// Reject turns a held transaction down; the balance is not changed
+//
+// Possible errors:
+// - ErrTransactionNotFound: If the transaction doesn't exist
+// - ErrApprovalNotPending: If the transaction is not awaiting approval
+func (m *TransactionManager) Reject(ctx context.Context, transactionID string, approver entity.Principal, note string) (*entity.Transaction, error) {
+	return m.resolveApproval(ctx, transactionID, entity.ApprovalRejected, approver, note)
+}
+
+// ExpireStaleApprovals marks held transactions that waited longer than the expiry as expired
+// It returns how many it expired; requests resolved meanwhile by someone else are skipped.
+func (m *TransactionManager) ExpireStaleApprovals(ctx context.Context) (int, error) {
+	if m.approvalPolicy.Expiry <= 0 {
+		return 0, nil
+	}
+
+	pending, err := m.unitOfWork.GetTransactionRepository(ctx).ListByStatus(ctx, entity.StatusPendingApproval, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	now := m.timeProvider.Now()
+	for _, txn := range pending {
+		if !m.approvalPolicy.isExpired(txn, now) {
+			continue
+		}
+		_, err := m.resolveApproval(ctx, txn.TransactionID, entity.ApprovalExpired, entity.Principal{Name: entity.SystemActor}, "")
+		if errs.IsApprovalConflictError(err) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// resolveApproval approves, rejects or expires a held transaction under the user's lock
+// Approving a request that has already expired expires it instead; the caller reports that.
+func (m *TransactionManager) resolveApproval(
+	ctx context.Context,
+	transactionID string,
+	action entity.ApprovalAction,
+	actor entity.Principal,
+	note string,
+) (*entity.Transaction, error) {
+	if m.shutdown.Load() {
+		return nil, errs.ErrShuttingDown
+	}
+	if strings.TrimSpace(actor.Name) == "" {
+		return nil, fmt.Errorf("%w: the approver is required", errs.ErrInvalidRequest)
+	}
+
+	// Find the user to lock; the transaction is read again under the lock
+	txn, err := m.unitOfWork.GetTransactionRepository(ctx).GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !txn.AwaitsApproval() {
+		return nil, fmt.Errorf("%w: transaction %s is %s", errs.ErrApprovalNotPending, transactionID, txn.Status)
+	}
+
+	return m.retry(ctx, transactionID, func(ctx context.Context) (*entity.Transaction, error) {
+		return m.withUserLock(ctx, txn.UserID, transactionID, func(dbCtx context.Context) (*entity.Transaction, error) {
+			return m.applyDecision(dbCtx, transactionID, action, actor, note)
+		})
+	})
+}
+
+// applyDecision records the decision on a held transaction inside the unit of work
+func (m *TransactionManager) applyDecision(
+	ctx context.Context,
+	transactionID string,
+	action entity.ApprovalAction,
+	actor entity.Principal,
+	note string,
+) (*entity.Transaction, error) {
+	txnRepo := m.unitOfWork.GetTransactionRepository(ctx)
+
+	txn, err := txnRepo.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !txn.AwaitsApproval() {
+		return nil, fmt.Errorf("%w: transaction %s is %s", errs.ErrApprovalNotPending, transactionID, txn.Status)
+	}
+
+	if action == entity.ApprovalApproved {
+		if txn.IsRequestedBy(actor) {
+			return nil, fmt.Errorf("%w: %s requested transaction %s", errs.ErrSelfApproval, txn.Requester(), transactionID)
+		}
+		if m.approvalPolicy.isExpired(txn, m.timeProvider.Now()) {
+			note = fmt.Sprintf("approval by %s came too late", strings.TrimSpace(actor.Name))
+			action, actor = entity.ApprovalExpired, entity.Principal{Name: entity.SystemActor}
+		}
+	}
+
+	switch action {
+	case entity.ApprovalApproved:
+		if err := m.applyApproved(ctx, txn); err != nil {
+			return nil, err
+		}
+	case entity.ApprovalRejected:
+		reason := "Rejected by approver"
+		if trimmed := strings.TrimSpace(note); trimmed != "" {
+			reason = "Rejected by approver: " + trimmed
+		}
+		txn.MarkAsRejected(m.timeProvider, reason)
+	case entity.ApprovalExpired:
+		txn.MarkAsExpired(m.timeProvider)
+	default:
+		return nil, fmt.Errorf("unsupported approval action: %s", action)
+	}
+
+	if err := txnRepo.Update(ctx, txn); err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	if err := m.unitOfWork.GetApprovalAuditRepository(ctx).Record(ctx, entity.NewApprovalEvent(txn, action, actor.Name, note, m.timeProvider)); err != nil {
+		return nil, fmt.Errorf("failed to record approval decision: %w", err)
+	}
+
+	m.logger.Info("Approval decision recorded", map[string]any{
+		"transaction_id": transactionID,
+		"user_id":        txn.UserID,
+		"action":         action.String(),
+		"actor":          actor.Name,
+		"status":         txn.Status.String(),
+	})
+	return txn, nil
+}
+
+// applyApproved applies an approved transaction to the user's balance
+// A debit the user can no longer cover, or a credit the balance cannot hold or that would take it above its
+// maximum, marks the transaction failed instead; the approval is still recorded.
+func (m *TransactionManager) applyApproved(ctx context.Context, txn *entity.Transaction) error {
+	userRepo := m.unitOfWork.GetUserRepository(ctx)
+	user, err := userRepo.GetByID(ctx, txn.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	switch txn.State {
+	case entity.StateWin:
+		if err := m.limitPolicy.CheckBalance(txn, user.Balance()); err != nil {
+			txn.MarkAsFailed(m.timeProvider, balanceLimitMessage)
+			return nil
+		}
+		if err := user.ApplyWinTransaction(txn.Amount, m.timeProvider); err != nil {
+			txn.MarkAsFailed(m.timeProvider, balanceOverflowMessage)
+			return nil
+		}
+	case entity.StateLose:
+		if err := user.ApplyLoseTransaction(txn.Amount, m.timeProvider); err != nil {
+			txn.MarkAsFailed(m.timeProvider, "Insufficient balance")
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported transaction state: %s", txn.State)
+	}
+
+	txn.MarkAsProcessed(m.timeProvider, user.Balance())
+	if err := userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// ListPendingApprovals returns the transactions awaiting approval, oldest first
+func (m *TransactionManager) ListPendingApprovals(ctx context.Context, limit int) ([]*entity.Transaction, error) {
+	return m.unitOfWork.GetTransactionRepository(ctx).ListByStatus(ctx, entity.StatusPendingApproval, limit)
+}
+
+// GetTransaction returns a transaction by its ID
+//
+// Possible errors:
+// - ErrTransactionNotFound: If the transaction doesn't exist
+func (m *TransactionManager) GetTransaction(ctx context.Context, transactionID string) (*entity.Transaction, error) {
+	return m.unitOfWork.GetTransactionRepository(ctx).GetByTransactionID(ctx, transactionID)
+}
+
+// ApprovalHistory returns the audit trail of a transaction held for approval, oldest first
+func (m *TransactionManager) ApprovalHistory(ctx context.Context, transactionID string) ([]*entity.ApprovalEvent, error) {
+	return m.unitOfWork.GetApprovalAuditRepository(ctx).ListByTransaction(ctx, transactionID)
+}
@@ -0,0 +1,216 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// actions returns the recorded approval actions of a transaction in order
+func (f *transactionManagerFixture) actions(transactionID string) []entity.ApprovalAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var actions []entity.ApprovalAction
+	for _, event := range f.events {
+		if event.TransactionID == transactionID {
+			actions = append(actions, event.Action)
+		}
+	}
+	return actions
+}
+
+// requestAdjustment submits an admin adjustment made by alice
+func (f *transactionManagerFixture) requestAdjustment(t *testing.T, transactionID, state, amount string) *entity.Transaction {
+	adjustment, err := entity.NewAdjustment("correction", "ticket #7", "alice")
+	require.NoError(t, err)
+	txn, err := f.manager.ProcessTransaction(context.Background(), 1, transactionID, "admin", state, amount, entity.WithAdjustment(adjustment))
+	require.NoError(t, err)
+	return txn
+}
+
+var testApprovalPolicy = ApprovalPolicy{AdjustmentThreshold: 5000, WithdrawalThreshold: 2000, Expiry: time.Hour}
+
+// bob is the second operator, who decides on what alice requests
+var bob = entity.Principal{Name: "bob"}
+
+func TestApprovalPolicy_Requires(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ApprovalPolicy
+		source entity.SourceType
+		state  entity.TransactionState
+		amount int64
+		want   bool
+	}{
+		{"adjustment above threshold", testApprovalPolicy, entity.SourceAdmin, entity.StateWin, 5001, true},
+		{"adjustment at threshold", testApprovalPolicy, entity.SourceAdmin, entity.StateLose, 5000, false},
+		{"withdrawal above threshold", testApprovalPolicy, entity.SourcePayment, entity.StateLose, 2001, true},
+		{"payment deposit", testApprovalPolicy, entity.SourcePayment, entity.StateWin, 9999, false},
+		{"game loss", testApprovalPolicy, entity.SourceGame, entity.StateLose, 9999, false},
+		{"disabled", ApprovalPolicy{}, entity.SourceAdmin, entity.StateWin, 9999, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := &entity.Transaction{SourceType: tt.source, State: tt.state, Amount: entity.MoneyFromCents(tt.amount)}
+			assert.Equal(t, tt.want, tt.policy.Requires(txn))
+		})
+	}
+}
+
+func TestNewApprovalPolicy(t *testing.T) {
+	policy, err := NewApprovalPolicy("1000.00", "0", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalPolicy{AdjustmentThreshold: 100000, Expiry: time.Hour}, policy)
+	assert.True(t, policy.Enabled())
+
+	policy, err = NewApprovalPolicy("", "", 0)
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled())
+
+	_, err = NewApprovalPolicy("lots", "0", 0)
+	assert.ErrorIs(t, err, errs.ErrInvalidAmount)
+}
+
+func TestTransactionManager_HoldsLargeAdjustments(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+
+	txn := f.requestAdjustment(t, "adj-1", "win", "60.00")
+	assert.Equal(t, entity.StatusPendingApproval, txn.Status)
+	assert.Equal(t, "100.00", f.user.GetBalance(), "a held transaction must not touch the balance")
+	assert.Equal(t, []entity.ApprovalAction{entity.ApprovalRequested}, f.actions("adj-1"))
+
+	// Small adjustments are applied at once
+	txn = f.requestAdjustment(t, "adj-2", "win", "10.00")
+	assert.Equal(t, entity.StatusCompleted, txn.Status)
+	assert.Equal(t, "110.00", f.user.GetBalance())
+
+	// Replaying a held transaction returns it unchanged
+	txn = f.requestAdjustment(t, "adj-1", "win", "60.00")
+	assert.Equal(t, entity.StatusPendingApproval, txn.Status)
+	assert.Equal(t, "110.00", f.user.GetBalance())
+}
+
+func TestTransactionManager_ApproveAppliesTheBalance(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.requestAdjustment(t, "adj-1", "win", "60.00")
+
+	txn, err := f.manager.Approve(context.Background(), "adj-1", bob, "checked the ticket")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCompleted, txn.Status)
+	assert.Equal(t, "160.00", txn.GetResultBalance())
+	assert.Equal(t, "160.00", f.user.GetBalance())
+	assert.Equal(t, []entity.ApprovalAction{entity.ApprovalRequested, entity.ApprovalApproved}, f.actions("adj-1"))
+
+	_, err = f.manager.Approve(context.Background(), "adj-1", bob, "")
+	assert.ErrorIs(t, err, errs.ErrApprovalNotPending)
+}
+
+func TestTransactionManager_RequesterCannotApprove(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.requestAdjustment(t, "adj-1", "win", "60.00")
+
+	_, err := f.manager.Approve(context.Background(), "adj-1", entity.Principal{Name: " ALICE "}, "")
+	assert.ErrorIs(t, err, errs.ErrSelfApproval)
+	assert.Equal(t, "100.00", f.user.GetBalance())
+
+	// The requester may still withdraw the request
+	txn, err := f.manager.Reject(context.Background(), "adj-1", entity.Principal{Name: "alice"}, "wrong user")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusRejected, txn.Status)
+	assert.Equal(t, "Rejected by approver: wrong user", txn.ErrorMessage)
+	assert.Equal(t, "100.00", f.user.GetBalance())
+}
+
+func TestTransactionManager_ApprovedDebitWithoutFundsFails(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.requestAdjustment(t, "adj-1", "lose", "150.00")
+
+	txn, err := f.manager.Approve(context.Background(), "adj-1", bob, "")
+	assert.ErrorIs(t, err, errs.ErrInsufficientBalance)
+	require.NotNil(t, txn)
+	assert.Equal(t, entity.StatusFailed, txn.Status)
+	assert.Equal(t, "100.00", f.user.GetBalance())
+	assert.Equal(t, []entity.ApprovalAction{entity.ApprovalRequested, entity.ApprovalApproved}, f.actions("adj-1"))
+}
+
+func TestTransactionManager_LateApprovalExpires(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.requestAdjustment(t, "adj-1", "win", "60.00")
+	f.advance(2 * time.Hour)
+
+	txn, err := f.manager.Approve(context.Background(), "adj-1", bob, "")
+	assert.ErrorIs(t, err, errs.ErrApprovalExpired)
+	require.NotNil(t, txn)
+	assert.Equal(t, entity.StatusExpired, txn.Status)
+	assert.Equal(t, "100.00", f.user.GetBalance())
+	assert.Equal(t, []entity.ApprovalAction{entity.ApprovalRequested, entity.ApprovalExpired}, f.actions("adj-1"))
+}
+
+func TestTransactionManager_ExpireStaleApprovals(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.requestAdjustment(t, "adj-old", "win", "60.00")
+	f.advance(90 * time.Minute)
+	f.requestAdjustment(t, "adj-new", "win", "70.00")
+
+	expired, err := f.manager.ExpireStaleApprovals(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	pending, err := f.manager.ListPendingApprovals(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "adj-new", pending[0].TransactionID)
+
+	txn, err := f.manager.GetTransaction(context.Background(), "adj-old")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusExpired, txn.Status)
+}
+
+func TestTransactionManager_WithdrawalsAboveThresholdAreHeld(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+
+	txn, err := f.manager.ProcessTransaction(context.Background(), 1, "wd-1", "payment", "lose", "25.00")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusPendingApproval, txn.Status)
+	assert.Equal(t, "user:1", txn.Requester())
+
+	// An operator who holds the account cannot approve its withdrawals
+	_, err = f.manager.Approve(context.Background(), "wd-1", entity.Principal{Name: "carol", UserID: 1}, "")
+	assert.ErrorIs(t, err, errs.ErrSelfApproval)
+
+	txn, err = f.manager.Approve(context.Background(), "wd-1", bob, "")
+	require.NoError(t, err)
+	assert.Equal(t, "75.00", txn.GetResultBalance())
+}
+
+func TestTransactionManager_ApprovalNeedsAnApprover(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.requestAdjustment(t, "adj-1", "win", "60.00")
+
+	_, err := f.manager.Approve(context.Background(), "adj-1", entity.Principal{Name: "  "}, "")
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+	_, err = f.manager.Reject(context.Background(), "missing", bob, "")
+	assert.ErrorIs(t, err, errs.ErrTransactionNotFound)
+}
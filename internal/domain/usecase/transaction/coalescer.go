@@ -0,0 +1,190 @@
+package transaction
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// DefaultCompletedCacheSize is the default number of completed transaction IDs remembered per instance
+const DefaultCompletedCacheSize = 10000
+
+// CoalescingMetrics is a snapshot of the coalescer's counters
+type CoalescingMetrics struct {
+	InFlight      int   // Transaction IDs currently being processed
+	Coalesced     int64 // Requests that waited on an in-flight execution instead of running their own
+	CacheHits     int64 // Requests answered from the completed-transaction cache
+	CacheSize     int   // Completed transactions currently cached
+	CacheCapacity int   // Maximum number of completed transactions cached
+}
+
+// RequestCoalescer collapses concurrent submissions of the same transaction ID into one execution
+// The first submission runs; duplicates arriving while it is in flight wait for it and share its
+// result. Successfully completed transactions are kept in a bounded LRU so later replays are
+// answered without touching the database. Completed transactions never change, so serving them
+// from memory returns exactly what the database would.
+type RequestCoalescer struct {
+	capacity int
+	logger   coreport.Logger
+
+	mu       sync.Mutex
+	inFlight map[string]*inFlightCall
+	lru      *list.List               // Most recently used at the front; values are *cachedTransaction
+	entries  map[string]*list.Element // Cached transactions by transaction ID
+
+	coalesced atomic.Int64
+	cacheHits atomic.Int64
+}
+
+// cachedTransaction is an entry in the completed-transaction cache
+type cachedTransaction struct {
+	transactionID string
+	txn           *entity.Transaction
+}
+
+// inFlightCall is an execution that duplicates can wait on
+type inFlightCall struct {
+	done chan struct{} // Closed once txn and err are set
+	txn  *entity.Transaction
+	err  error
+}
+
+// NewRequestCoalescer creates a coalescer that caches up to capacity completed transactions
+// A capacity of zero disables the cache; concurrent duplicates are still coalesced
+func NewRequestCoalescer(capacity int, logger coreport.Logger) *RequestCoalescer {
+	if capacity < 0 {
+		capacity = 0
+	}
+
+	return &RequestCoalescer{
+		capacity: capacity,
+		logger:   logger,
+		inFlight: make(map[string]*inFlightCall),
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Do runs work for the transaction ID unless it is cached or already running
+// If the execution a caller waited on ended only because its own request was cancelled,
+// the waiter runs the work itself rather than failing with someone else's cancellation.
+func (c *RequestCoalescer) Do(ctx context.Context, transactionID string, work UserWork) (*entity.Transaction, error) {
+	for {
+		c.mu.Lock()
+		if txn, ok := c.cachedLocked(transactionID); ok {
+			c.mu.Unlock()
+			c.cacheHits.Add(1)
+			return txn, nil
+		}
+
+		call, running := c.inFlight[transactionID]
+		if !running {
+			call = &inFlightCall{done: make(chan struct{})}
+			c.inFlight[transactionID] = call
+			c.mu.Unlock()
+			return c.run(ctx, transactionID, call, work)
+		}
+		c.mu.Unlock()
+
+		c.coalesced.Add(1)
+		c.logger.Debug("Waiting on in-flight duplicate transaction", map[string]any{
+			"transaction_id": transactionID,
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+		}
+
+		if isContextError(call.err) && ctx.Err() == nil {
+			continue
+		}
+		if call.txn != nil {
+			return call.txn.Clone(), call.err
+		}
+		return nil, call.err
+	}
+}
+
+// run executes work as the leader for the transaction ID and publishes the result to waiters
+func (c *RequestCoalescer) run(ctx context.Context, transactionID string, call *inFlightCall, work UserWork) (txn *entity.Transaction, err error) {
+	// Waiters must be released even if the work panics; they then get an error instead of a result
+	finished := false
+	defer func() {
+		call.txn, call.err = txn, err
+		if !finished {
+			call.err = fmt.Errorf("%w: processing of duplicate transaction aborted", errs.ErrInternalServer)
+		}
+
+		c.mu.Lock()
+		delete(c.inFlight, transactionID)
+		if finished && err == nil && txn != nil && txn.Status == entity.StatusCompleted {
+			c.storeLocked(transactionID, txn)
+		}
+		c.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	txn, err = work(ctx)
+	finished = true
+	return txn, err
+}
+
+// cachedLocked returns a copy of the cached transaction and marks it recently used
+// The caller must hold c.mu
+func (c *RequestCoalescer) cachedLocked(transactionID string) (*entity.Transaction, bool) {
+	elem, ok := c.entries[transactionID]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cachedTransaction).txn.Clone(), true
+}
+
+// storeLocked caches a completed transaction, evicting the least recently used one when full
+// The caller must hold c.mu
+func (c *RequestCoalescer) storeLocked(transactionID string, txn *entity.Transaction) {
+	if c.capacity == 0 {
+		return
+	}
+	if elem, ok := c.entries[transactionID]; ok {
+		elem.Value.(*cachedTransaction).txn = txn.Clone()
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[transactionID] = c.lru.PushFront(&cachedTransaction{transactionID: transactionID, txn: txn.Clone()})
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedTransaction).transactionID)
+	}
+}
+
+// Metrics returns a snapshot of the coalescer's counters
+func (c *RequestCoalescer) Metrics() CoalescingMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CoalescingMetrics{
+		InFlight:      len(c.inFlight),
+		Coalesced:     c.coalesced.Load(),
+		CacheHits:     c.cacheHits.Load(),
+		CacheSize:     c.lru.Len(),
+		CacheCapacity: c.capacity,
+	}
+}
+
+// isContextError reports whether err comes from a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,195 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completedTransaction returns a completed transaction with the given ID and result balance
+func completedTransaction(transactionID string, resultBalance int64) *entity.Transaction {
+	return &entity.Transaction{
+		TransactionID: transactionID,
+		Status:        entity.StatusCompleted,
+		ResultBalance: entity.MoneyFromCents(resultBalance),
+	}
+}
+
+func TestRequestCoalescer_ConcurrentDuplicatesShareOneExecution(t *testing.T) {
+	coalescer := NewRequestCoalescer(10, newTestLogger(t))
+
+	release := make(chan struct{})
+	var executions atomic.Int32
+	work := func(ctx context.Context) (*entity.Transaction, error) {
+		executions.Add(1)
+		<-release
+		return completedTransaction("tx-1", 11000), nil
+	}
+
+	const duplicates = 10
+	var wg sync.WaitGroup
+	results := make(chan *entity.Transaction, duplicates)
+	for i := 0; i < duplicates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			txn, err := coalescer.Do(context.Background(), "tx-1", work)
+			assert.NoError(t, err)
+			results <- txn
+		}()
+	}
+
+	// Hold the first execution until every duplicate is waiting on it
+	require.Eventually(t, func() bool {
+		return coalescer.Metrics().Coalesced == duplicates-1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	assert.Equal(t, int32(1), executions.Load())
+	for txn := range results {
+		assert.Equal(t, entity.MoneyFromCents(11000), txn.ResultBalance)
+	}
+	assert.Zero(t, coalescer.Metrics().InFlight)
+}
+
+func TestRequestCoalescer_ReplaysCompletedFromCache(t *testing.T) {
+	coalescer := NewRequestCoalescer(10, newTestLogger(t))
+
+	var executions atomic.Int32
+	work := func(ctx context.Context) (*entity.Transaction, error) {
+		executions.Add(1)
+		return completedTransaction("tx-1", 11000), nil
+	}
+
+	_, err := coalescer.Do(context.Background(), "tx-1", work)
+	require.NoError(t, err)
+
+	txn, err := coalescer.Do(context.Background(), "tx-1", work)
+	require.NoError(t, err)
+	assert.Equal(t, entity.MoneyFromCents(11000), txn.ResultBalance)
+	assert.Equal(t, int32(1), executions.Load())
+	assert.Equal(t, int64(1), coalescer.Metrics().CacheHits)
+
+	// Callers get their own copy, so changing one cannot change later replays
+	txn.ResultBalance = entity.Money{}
+	replay, err := coalescer.Do(context.Background(), "tx-1", work)
+	require.NoError(t, err)
+	assert.Equal(t, entity.MoneyFromCents(11000), replay.ResultBalance)
+}
+
+func TestRequestCoalescer_DoesNotCacheFailures(t *testing.T) {
+	coalescer := NewRequestCoalescer(10, newTestLogger(t))
+
+	var executions atomic.Int32
+	work := func(ctx context.Context) (*entity.Transaction, error) {
+		executions.Add(1)
+		return nil, errs.ErrInsufficientBalance
+	}
+
+	// A rejected transaction may succeed later once the balance allows it
+	for i := 0; i < 2; i++ {
+		_, err := coalescer.Do(context.Background(), "tx-1", work)
+		assert.ErrorIs(t, err, errs.ErrInsufficientBalance)
+	}
+	assert.Equal(t, int32(2), executions.Load())
+	assert.Zero(t, coalescer.Metrics().CacheSize)
+}
+
+func TestRequestCoalescer_EvictsLeastRecentlyUsed(t *testing.T) {
+	coalescer := NewRequestCoalescer(2, newTestLogger(t))
+
+	var executions atomic.Int32
+	do := func(transactionID string) {
+		_, err := coalescer.Do(context.Background(), transactionID, func(ctx context.Context) (*entity.Transaction, error) {
+			executions.Add(1)
+			return completedTransaction(transactionID, 100), nil
+		})
+		require.NoError(t, err)
+	}
+
+	do("tx-1")
+	do("tx-2")
+	do("tx-1") // Cache hit; tx-2 becomes the least recently used
+	do("tx-3") // Evicts tx-2
+	assert.Equal(t, int32(3), executions.Load())
+
+	do("tx-1")
+	assert.Equal(t, int32(3), executions.Load())
+	do("tx-2")
+	assert.Equal(t, int32(4), executions.Load())
+	assert.Equal(t, 2, coalescer.Metrics().CacheSize)
+}
+
+func TestRequestCoalescer_WaiterRunsWhenLeaderIsCancelled(t *testing.T) {
+	coalescer := NewRequestCoalescer(10, newTestLogger(t))
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := coalescer.Do(leaderCtx, "tx-1", func(ctx context.Context) (*entity.Transaction, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, fmt.Errorf("lock acquisition timeout: %w", ctx.Err())
+		})
+		leaderDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan *entity.Transaction, 1)
+	go func() {
+		txn, err := coalescer.Do(context.Background(), "tx-1", func(ctx context.Context) (*entity.Transaction, error) {
+			return completedTransaction("tx-1", 11000), nil
+		})
+		assert.NoError(t, err)
+		waiterDone <- txn
+	}()
+	require.Eventually(t, func() bool {
+		return coalescer.Metrics().Coalesced == 1
+	}, time.Second, time.Millisecond)
+
+	// The leader's client went away; the waiter's request is still live and runs itself
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderDone, context.Canceled)
+	assert.Equal(t, entity.MoneyFromCents(11000), (<-waiterDone).ResultBalance)
+}
+
+func TestRequestCoalescer_ReleasesWaitersWhenWorkPanics(t *testing.T) {
+	coalescer := NewRequestCoalescer(10, newTestLogger(t))
+
+	release := make(chan struct{})
+	go func() {
+		defer func() { _ = recover() }()
+		_, _ = coalescer.Do(context.Background(), "tx-1", func(ctx context.Context) (*entity.Transaction, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	require.Eventually(t, func() bool {
+		return coalescer.Metrics().InFlight == 1
+	}, time.Second, time.Millisecond)
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err := coalescer.Do(context.Background(), "tx-1", func(ctx context.Context) (*entity.Transaction, error) {
+			return completedTransaction("tx-1", 100), nil
+		})
+		waiterErr <- err
+	}()
+	require.Eventually(t, func() bool {
+		return coalescer.Metrics().Coalesced == 1
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	assert.ErrorIs(t, <-waiterErr, errs.ErrInternalServer)
+}
@@ -0,0 +1,142 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// abortGracePeriod is how long aborted transactions get to roll back and release their locks
+const abortGracePeriod = 5 * time.Second
+
+// DrainReport summarises how in-flight transactions ended when the service was drained
+type DrainReport struct {
+	InFlight int           // Transactions in flight when the drain started
+	Drained  int           // Transactions that finished before the deadline
+	Aborted  int           // Transactions cancelled at the deadline; their work was rolled back
+	Rejected int64         // Transactions refused because the service was draining
+	Duration time.Duration // Time from the start of the drain until the service was shut down
+}
+
+// track registers a transaction as in flight, or rejects it once the service is draining
+// The returned context is cancelled if the drain deadline passes while the transaction is
+// still running. The returned function must be called with the transaction's outcome; it marks
+// the transaction finished and reports an aborted transaction as ErrShuttingDown.
+func (s *Service) track(ctx context.Context) (context.Context, func(error) error, error) {
+	s.drainMu.Lock()
+	if s.draining {
+		s.drainMu.Unlock()
+		s.rejected.Add(1)
+		return nil, nil, errs.ErrShuttingDown
+	}
+	s.active++
+	s.inFlight.Add(1)
+	s.drainMu.Unlock()
+
+	workCtx, cancel := context.WithCancelCause(ctx)
+	stopAbort := context.AfterFunc(s.abortCtx, func() {
+		cancel(context.Cause(s.abortCtx))
+	})
+
+	finish := func(err error) error {
+		stopAbort()
+		aborted := errors.Is(context.Cause(workCtx), errs.ErrShuttingDown)
+		cancel(nil)
+
+		s.drainMu.Lock()
+		s.active--
+		s.drainMu.Unlock()
+		s.inFlight.Done()
+
+		if err != nil && aborted && !errs.IsShuttingDownError(err) {
+			return fmt.Errorf("%w: %w", errs.ErrShuttingDown, err)
+		}
+		return err
+	}
+	return workCtx, finish, nil
+}
+
+// Drain stops accepting transactions and waits for the ones in flight to finish
+// New transactions are rejected with ErrShuttingDown from the moment Drain is called. When ctx
+// ends first, the remaining transactions are cancelled, which rolls back their unit of work and
+// releases their user locks, and they get a short grace period to unwind. The per-user queue and
+// the transaction manager are shut down before Drain returns.
+func (s *Service) Drain(ctx context.Context) DrainReport {
+	start := s.timeProvider.Now()
+
+	s.drainMu.Lock()
+	s.draining = true
+	inFlight := s.active
+	s.drainMu.Unlock()
+
+	s.logger.Info("Draining in-flight transactions", map[string]any{
+		"in_flight": inFlight,
+	})
+
+	// No transaction can start any more, so the wait group only counts down from here
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	report := DrainReport{InFlight: inFlight}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.drainMu.Lock()
+		report.Aborted = s.active
+		s.drainMu.Unlock()
+
+		s.logger.Warn("Drain deadline reached, aborting in-flight transactions", map[string]any{
+			"aborted": report.Aborted,
+		})
+		s.abort(errs.ErrShuttingDown)
+		s.awaitAborted(done)
+	}
+	report.Drained = inFlight - report.Aborted
+
+	// Nothing is running any more, so these return promptly
+	s.Shutdown()
+	s.manager.Shutdown()
+
+	report.Rejected = s.rejected.Load()
+	report.Duration = s.timeProvider.Since(start).Std()
+
+	s.logger.Info("Transaction service drained", map[string]any{
+		"in_flight":   report.InFlight,
+		"drained":     report.Drained,
+		"aborted":     report.Aborted,
+		"rejected":    report.Rejected,
+		"duration_ms": report.Duration.Milliseconds(),
+	})
+	return report
+}
+
+// awaitAborted waits for aborted transactions to unwind, giving up after abortGracePeriod
+func (s *Service) awaitAborted(done <-chan struct{}) {
+	graceCtx, cancel := s.timeProvider.WithTimeout(context.Background(), coreport.Duration(abortGracePeriod))
+	defer cancel()
+
+	select {
+	case <-done:
+	case <-graceCtx.Done():
+		s.drainMu.Lock()
+		stuck := s.active
+		s.drainMu.Unlock()
+		s.logger.Error("Aborted transactions did not finish in time", map[string]any{
+			"stuck": stuck,
+		})
+	}
+}
+
+// Draining reports whether the service has stopped accepting transactions
+func (s *Service) Draining() bool {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+	return s.draining
+}
@@ -0,0 +1,107 @@
+package transaction
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newDrainTestService creates a service whose transactions are applied by the given fast path mock
+func newDrainTestService(t *testing.T) (*Service, *persistencemocks.MockAtomicTransactionRepository) {
+	timeProvider := newTestTimeProvider(t)
+
+	uow := persistencemocks.NewMockUnitOfWork(t)
+	uow.EXPECT().GetTransactionRepository(mock.Anything).Return(persistencemocks.NewMockTransactionRepository(t)).Maybe()
+
+	fastPath := persistencemocks.NewMockAtomicTransactionRepository(t)
+	service := NewTransactionService(uow, persistencemocks.NewMockUserLockRepository(t), timeProvider, newTestLogger(t), time.Second)
+	service.WithFastPath(fastPath)
+	return service, fastPath
+}
+
+// drainTestRequest returns a valid win request with the given transaction ID
+func drainTestRequest(transactionID string) TransactionRequest {
+	return TransactionRequest{TransactionID: transactionID, SourceType: entity.SourceGame, State: "win", Amount: "10.00"}
+}
+
+func TestService_DrainWaitsForInFlightTransactions(t *testing.T) {
+	service, fastPath := newDrainTestService(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+		close(started)
+		<-release
+		processed := txn.Clone()
+		processed.Status = entity.StatusCompleted
+		return processed, false, nil
+	}).Once()
+
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := service.ProcessTransaction(context.Background(), 1, drainTestRequest("tx-1"))
+		inFlight <- err
+	}()
+	<-started
+
+	reports := make(chan DrainReport, 1)
+	go func() {
+		reports <- service.Drain(context.Background())
+	}()
+	require.Eventually(t, service.Draining, time.Second, time.Millisecond)
+
+	// New transactions are turned away while the drain waits
+	resp, err := service.ProcessTransaction(context.Background(), 1, drainTestRequest("tx-2"))
+	assert.ErrorIs(t, err, errs.ErrShuttingDown)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	close(release)
+	require.NoError(t, <-inFlight)
+
+	report := <-reports
+	assert.Equal(t, 1, report.InFlight)
+	assert.Equal(t, 1, report.Drained)
+	assert.Zero(t, report.Aborted)
+	assert.Equal(t, int64(1), report.Rejected)
+}
+
+func TestService_DrainAbortsAtDeadline(t *testing.T) {
+	service, fastPath := newDrainTestService(t)
+
+	// The work only stops once its context is cancelled, like a query blocked on a lock
+	started := make(chan struct{})
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, false, ctx.Err()
+	}).Once()
+
+	responses := make(chan *TransactionResponse, 1)
+	go func() {
+		resp, err := service.ProcessTransaction(context.Background(), 1, drainTestRequest("tx-1"))
+		assert.ErrorIs(t, err, errs.ErrShuttingDown)
+		responses <- resp
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report := service.Drain(ctx)
+
+	assert.Equal(t, 1, report.InFlight)
+	assert.Zero(t, report.Drained)
+	assert.Equal(t, 1, report.Aborted)
+	assert.Equal(t, http.StatusServiceUnavailable, (<-responses).StatusCode)
+
+	// The manager refuses work once the drain is over
+	_, err := service.GetManager().ProcessTransaction(context.Background(), 1, "tx-3", "game", "win", "1.00")
+	assert.ErrorIs(t, err, errs.ErrShuttingDown)
+}
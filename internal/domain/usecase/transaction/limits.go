@@ -0,0 +1,285 @@
+package transaction
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+)
+
+// balanceLimitMessage is the error message of a credit that failed because it would exceed the maximum balance
+const balanceLimitMessage = "Balance limit exceeded"
+
+// NoLimit given as a rule's limit lifts that limit for the transactions the rule matches
+const NoLimit = "none"
+
+// AmountLimits bound the amount of a transaction and the balance a credit may leave
+// A zero limit is not set.
+type AmountLimits struct {
+	MinAmount  entity.Money // Smallest amount allowed
+	MaxAmount  entity.Money // Largest amount allowed
+	MaxBalance entity.Money // Largest balance a credit may leave; debits are never stopped by it
+}
+
+// LiftedLimits are the limits a rule removes, so less specific rules no longer apply them
+type LiftedLimits struct {
+	MinAmount  bool
+	MaxAmount  bool
+	MaxBalance bool
+}
+
+// merge returns the limits with the ones the rule sets taking their place and the ones it lifts removed
+func (l AmountLimits) merge(rule LimitRule) AmountLimits {
+	for _, limit := range []struct {
+		target *entity.Money
+		value  entity.Money
+		lifted bool
+	}{
+		{&l.MinAmount, rule.Limits.MinAmount, rule.Lifted.MinAmount},
+		{&l.MaxAmount, rule.Limits.MaxAmount, rule.Lifted.MaxAmount},
+		{&l.MaxBalance, rule.Limits.MaxBalance, rule.Lifted.MaxBalance},
+	} {
+		switch {
+		case limit.lifted:
+			*limit.target = entity.Money{}
+		case !limit.value.IsZero():
+			*limit.target = limit.value
+		}
+	}
+	return l
+}
+
+// validate checks that the minimum amount is not above the maximum
+//
+// Possible errors:
+//   - ErrInvalidRequest: no amount could pass both limits
+func (l AmountLimits) validate() error {
+	if !l.MinAmount.IsZero() && !l.MaxAmount.IsZero() && l.MinAmount.Cents() > l.MaxAmount.Cents() {
+		return fmt.Errorf("%w: minimum amount %s is above maximum amount %s", errs.ErrInvalidRequest, l.MinAmount, l.MaxAmount)
+	}
+	return nil
+}
+
+// LimitRule sets limits for the transactions it matches
+// An empty source type or state, or a zero user ID, matches any.
+type LimitRule struct {
+	UserID     uint64
+	SourceType entity.SourceType
+	State      entity.TransactionState
+	Limits     AmountLimits
+	Lifted     LiftedLimits
+}
+
+// NewLimitRule creates a rule from limits given as amounts such as "1000.00"
+// Empty limits are left to less specific rules, and NoLimit lifts the one those rules set.
+func NewLimitRule(userID uint64, sourceType, state, minAmount, maxAmount, maxBalance string) (LimitRule, error) {
+	rule := LimitRule{UserID: userID}
+	if strings.TrimSpace(sourceType) != "" {
+		parsed, err := entity.ParseSourceType(sourceType)
+		if err != nil {
+			return LimitRule{}, fmt.Errorf("invalid limit rule: %w", err)
+		}
+		rule.SourceType = parsed
+	}
+	if strings.TrimSpace(state) != "" {
+		parsed, err := entity.ParseTransactionState(state)
+		if err != nil {
+			return LimitRule{}, fmt.Errorf("invalid limit rule: %w", err)
+		}
+		rule.State = parsed
+	}
+
+	for _, limit := range []struct {
+		name   string
+		amount string
+		target *entity.Money
+		lifted *bool
+	}{
+		{"minimum amount", minAmount, &rule.Limits.MinAmount, &rule.Lifted.MinAmount},
+		{"maximum amount", maxAmount, &rule.Limits.MaxAmount, &rule.Lifted.MaxAmount},
+		{"maximum balance", maxBalance, &rule.Limits.MaxBalance, &rule.Lifted.MaxBalance},
+	} {
+		if strings.TrimSpace(limit.amount) == "" {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(limit.amount), NoLimit) {
+			*limit.lifted = true
+			continue
+		}
+		amount, err := entity.ParseMoney(limit.amount)
+		if err != nil {
+			return LimitRule{}, fmt.Errorf("invalid %s %q: %w", limit.name, limit.amount, err)
+		}
+		*limit.target = amount
+	}
+
+	if err := rule.Limits.validate(); err != nil {
+		return LimitRule{}, err
+	}
+	return rule, nil
+}
+
+// matches reports whether the rule applies to a transaction of the user
+func (r LimitRule) matches(userID uint64, sourceType entity.SourceType, state entity.TransactionState) bool {
+	return (r.UserID == 0 || r.UserID == userID) &&
+		(r.SourceType == "" || r.SourceType == sourceType) &&
+		(r.State == "" || r.State == state)
+}
+
+// specificity ranks rules: a user's rules beat everyone's, then a source type beats a state
+func (r LimitRule) specificity() int {
+	rank := 0
+	if r.UserID != 0 {
+		rank += 4
+	}
+	if r.SourceType != "" {
+		rank += 2
+	}
+	if r.State != "" {
+		rank++
+	}
+	return rank
+}
+
+// LimitPolicy caps transaction amounts and the balances credits leave
+// Each limit comes from the most specific matching rule that sets or lifts it, so a rule for one user can
+// raise or lift a single limit and keep the others. The zero value limits nothing.
+type LimitPolicy struct {
+	rules []LimitRule // Least specific first
+}
+
+// NewLimitPolicy creates a policy from its rules
+// Of two equally specific rules that set the same limit, the later one wins. Rules that set and lift no
+// limits are dropped.
+//
+// Possible errors:
+//   - ErrInvalidRequest: the rules leave a minimum amount above the maximum for some transactions
+func NewLimitPolicy(rules ...LimitRule) (LimitPolicy, error) {
+	var sorted []LimitRule
+	for _, rule := range rules {
+		if rule.Limits != (AmountLimits{}) || rule.Lifted != (LiftedLimits{}) {
+			sorted = append(sorted, rule)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].specificity() < sorted[j].specificity()
+	})
+	policy := LimitPolicy{rules: sorted}
+	if err := policy.validate(); err != nil {
+		return LimitPolicy{}, err
+	}
+	return policy, nil
+}
+
+// validate checks the limits of every user, source type and state the rules tell apart
+// Rules are only checked on their own when they are created, but a user's maximum can still fall below a
+// minimum that a less specific rule sets.
+func (p LimitPolicy) validate() error {
+	// User 0 stands for every user without rules of their own
+	users := []uint64{0}
+	for _, rule := range p.rules {
+		if rule.UserID != 0 && !slices.Contains(users, rule.UserID) {
+			users = append(users, rule.UserID)
+		}
+	}
+
+	for _, userID := range users {
+		for _, sourceType := range entity.SourceType("").Values() {
+			for _, state := range entity.TransactionState("").Values() {
+				if err := p.For(userID, sourceType, state).validate(); err != nil {
+					who := "users without rules of their own"
+					if userID != 0 {
+						who = fmt.Sprintf("user %d", userID)
+					}
+					return fmt.Errorf("%w for %s %s transactions of %s", err, sourceType, state, who)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether the policy limits anything at all
+func (p LimitPolicy) Enabled() bool {
+	return len(p.rules) > 0
+}
+
+// For returns the limits that apply to a transaction of the user
+func (p LimitPolicy) For(userID uint64, sourceType entity.SourceType, state entity.TransactionState) AmountLimits {
+	var limits AmountLimits
+	for _, rule := range p.rules {
+		if rule.matches(userID, sourceType, state) {
+			limits = limits.merge(rule)
+		}
+	}
+	return limits
+}
+
+// CheckAmount checks the amount of the transaction against its limits
+//
+// Possible errors:
+//   - ErrAmountBelowMinimum: the amount is smaller than the minimum
+//   - ErrAmountAboveMaximum: the amount is larger than the maximum
+func (p LimitPolicy) CheckAmount(txn *entity.Transaction) error {
+	limits := p.For(txn.UserID, txn.SourceType, txn.State)
+	switch {
+	case !limits.MinAmount.IsZero() && txn.Amount.Cents() < limits.MinAmount.Cents():
+		return fmt.Errorf("%w: %s %s of %s is below %s", errs.ErrAmountBelowMinimum, txn.SourceType, txn.State, txn.Amount, limits.MinAmount)
+	case !limits.MaxAmount.IsZero() && txn.Amount.Cents() > limits.MaxAmount.Cents():
+		return fmt.Errorf("%w: %s %s of %s is above %s", errs.ErrAmountAboveMaximum, txn.SourceType, txn.State, txn.Amount, limits.MaxAmount)
+	}
+	return nil
+}
+
+// CheckBalance checks the balance a credit would leave on top of the current one against the maximum balance
+// Debits always pass, so a user above a lowered maximum can still spend. A credit the balance cannot hold
+// passes too; applying it reports the overflow.
+//
+// Possible errors:
+//   - ErrBalanceLimitExceeded: the credit would leave the balance above the maximum
+func (p LimitPolicy) CheckBalance(txn *entity.Transaction, current entity.Money) error {
+	if !txn.IsCredit() {
+		return nil
+	}
+	limits := p.For(txn.UserID, txn.SourceType, txn.State)
+	if limits.MaxBalance.IsZero() {
+		return nil
+	}
+	balance, err := current.Add(txn.Amount)
+	if err == nil && balance.Cents() > limits.MaxBalance.Cents() {
+		return fmt.Errorf("%w: user %d would have %s, the maximum is %s", errs.ErrBalanceLimitExceeded, txn.UserID, balance, limits.MaxBalance)
+	}
+	return nil
+}
+
+// capsBalance reports whether credits of this kind for the user have a maximum balance
+// The fast path cannot check the balance before applying a credit, so these go through the locked path.
+// Invalid input reports false; creating the transaction rejects it anyway.
+func (p LimitPolicy) capsBalance(userID uint64, sourceType, state string) bool {
+	if !p.Enabled() {
+		return false
+	}
+	source, err := entity.ParseSourceType(sourceType)
+	if err != nil {
+		return false
+	}
+	txnState, err := entity.ParseTransactionState(state)
+	if err != nil || txnState.GetBalanceEffect() != entity.EffectIncrease {
+		return false
+	}
+	return !p.For(userID, source, txnState).MaxBalance.IsZero()
+}
+
+// WithLimitPolicy checks transactions against the policy's amount and balance limits
+func (m *TransactionManager) WithLimitPolicy(policy LimitPolicy) *TransactionManager {
+	m.limitPolicy = policy
+	return m
+}
+
+// LimitPolicy returns the policy in use
+func (m *TransactionManager) LimitPolicy() LimitPolicy {
+	return m.limitPolicy
+}
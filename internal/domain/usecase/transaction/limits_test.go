@@ -0,0 +1,233 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLimitRule creates a rule or fails the test
+func newTestLimitRule(t *testing.T, userID uint64, sourceType, state, minAmount, maxAmount, maxBalance string) LimitRule {
+	t.Helper()
+	rule, err := NewLimitRule(userID, sourceType, state, minAmount, maxAmount, maxBalance)
+	require.NoError(t, err)
+	return rule
+}
+
+// newTestLimitPolicy creates a policy or fails the test
+func newTestLimitPolicy(t *testing.T, rules ...LimitRule) LimitPolicy {
+	t.Helper()
+	policy, err := NewLimitPolicy(rules...)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestNewLimitRule_RejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name                             string
+		sourceType, state                string
+		minAmount, maxAmount, maxBalance string
+	}{
+		{name: "Unknown source type", sourceType: "casino", maxAmount: "10.00"},
+		{name: "Unknown state", state: "draw", maxAmount: "10.00"},
+		{name: "Malformed amount", maxAmount: "ten"},
+		{name: "Negative amount", maxBalance: "-1.00"},
+		{name: "Minimum above maximum", minAmount: "20.00", maxAmount: "10.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLimitRule(0, tt.sourceType, tt.state, tt.minAmount, tt.maxAmount, tt.maxBalance)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLimitPolicy_For(t *testing.T) {
+	policy := newTestLimitPolicy(t,
+		newTestLimitRule(t, 7, "", "", "", "5000.00", ""),
+		newTestLimitRule(t, 8, "", "", "", NoLimit, "None"),
+		newTestLimitRule(t, 0, "", "", "0.10", "1000.00", "10000.00"),
+		newTestLimitRule(t, 0, "payment", "", "", "", "2000.00"),
+		newTestLimitRule(t, 0, "", "win", "", "500.00", ""),
+		newTestLimitRule(t, 0, "", "", "", "", ""),
+	)
+
+	tests := []struct {
+		name       string
+		userID     uint64
+		sourceType entity.SourceType
+		state      entity.TransactionState
+		expected   AmountLimits
+	}{
+		{
+			name:       "Defaults",
+			userID:     1,
+			sourceType: entity.SourceGame,
+			state:      entity.StateLose,
+			expected:   AmountLimits{MinAmount: entity.MoneyFromCents(10), MaxAmount: entity.MoneyFromCents(100000), MaxBalance: entity.MoneyFromCents(1000000)},
+		},
+		{
+			name:       "Source type and state rules override single limits",
+			userID:     1,
+			sourceType: entity.SourcePayment,
+			state:      entity.StateWin,
+			expected:   AmountLimits{MinAmount: entity.MoneyFromCents(10), MaxAmount: entity.MoneyFromCents(50000), MaxBalance: entity.MoneyFromCents(200000)},
+		},
+		{
+			name:       "A user override beats every shared rule and keeps the other limits",
+			userID:     7,
+			sourceType: entity.SourcePayment,
+			state:      entity.StateWin,
+			expected:   AmountLimits{MinAmount: entity.MoneyFromCents(10), MaxAmount: entity.MoneyFromCents(500000), MaxBalance: entity.MoneyFromCents(200000)},
+		},
+		{
+			name:       "A user override lifts limits and keeps the others",
+			userID:     8,
+			sourceType: entity.SourcePayment,
+			state:      entity.StateWin,
+			expected:   AmountLimits{MinAmount: entity.MoneyFromCents(10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, policy.For(tt.userID, tt.sourceType, tt.state))
+		})
+	}
+
+	assert.True(t, policy.Enabled())
+	assert.False(t, LimitPolicy{}.Enabled())
+	assert.False(t, newTestLimitPolicy(t, newTestLimitRule(t, 0, "game", "", "", "", "")).Enabled())
+}
+
+func TestNewLimitPolicy_RejectsMinimumAboveMaximum(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []LimitRule
+		expected string
+	}{
+		{
+			name: "A user maximum below the shared minimum",
+			rules: []LimitRule{
+				newTestLimitRule(t, 0, "", "", "10.00", "", ""),
+				newTestLimitRule(t, 7, "", "", "", "5.00", ""),
+			},
+			expected: "minimum amount 10.00 is above maximum amount 5.00 for game win transactions of user 7",
+		},
+		{
+			name: "A state maximum below a source type minimum",
+			rules: []LimitRule{
+				newTestLimitRule(t, 0, "payment", "", "10.00", "", ""),
+				newTestLimitRule(t, 0, "", "lose", "", "5.00", ""),
+			},
+			expected: "for payment lose transactions of users without rules of their own",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLimitPolicy(tt.rules...)
+			assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+
+	// Lifting the shared minimum makes the user's maximum usable
+	_, err := NewLimitPolicy(
+		newTestLimitRule(t, 0, "", "", "10.00", "", ""),
+		newTestLimitRule(t, 7, "", "", NoLimit, "5.00", ""),
+	)
+	assert.NoError(t, err)
+}
+
+func TestTransactionValidator_RejectsAmountsOutsideLimits(t *testing.T) {
+	validator := NewTransactionValidator().WithLimitPolicy(newTestLimitPolicy(t,
+		newTestLimitRule(t, 0, "game", "", "1.00", "100.00", ""),
+	))
+
+	assert.ErrorIs(t, validator.ValidateTransaction(1, "tx-1", "game", "win", "0.99"), errs.ErrAmountBelowMinimum)
+	assert.ErrorIs(t, validator.ValidateTransaction(1, "tx-1", "game", "win", "100.01"), errs.ErrAmountAboveMaximum)
+	assert.NoError(t, validator.ValidateTransaction(1, "tx-1", "game", "win", "100.00"))
+	// Other source types are not limited
+	assert.NoError(t, validator.ValidateTransaction(1, "tx-1", "payment", "win", "500.00"))
+}
+
+func TestTransactionManager_WinAboveMaxBalanceFails(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.manager.WithLimitPolicy(newTestLimitPolicy(t, newTestLimitRule(t, 0, "", "", "", "", "150.00")))
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "50.01")
+	assert.ErrorIs(t, err, errs.ErrBalanceLimitExceeded)
+	f.txnRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(txn *entity.Transaction) bool {
+		return txn.Status == entity.StatusFailed && txn.ErrorMessage == balanceLimitMessage
+	}))
+	f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	assert.Zero(t, f.manager.RetryMetrics().Retries)
+}
+
+func TestTransactionManager_CappedCreditsSkipTheFastPath(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	fastPath := persistencemocks.NewMockAtomicTransactionRepository(t)
+	f.manager.WithFastPath(fastPath)
+	f.manager.WithLimitPolicy(newTestLimitPolicy(t, newTestLimitRule(t, 0, "", "", "", "", "150.00")))
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Once()
+
+	// The credit is checked against the balance under the lock
+	txn, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "50.00")
+	require.NoError(t, err)
+	assert.Equal(t, "150.00", txn.GetResultBalance())
+
+	// Debits are not capped and keep the fast path
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+		processed := txn.Clone()
+		processed.Status = entity.StatusCompleted
+		processed.ResultBalance = entity.MoneyFromCents(14000)
+		return processed, false, nil
+	}).Once()
+
+	_, err = f.manager.ProcessTransaction(context.Background(), 1, "tx-2", "game", "lose", "10.00")
+	require.NoError(t, err)
+}
+
+func TestTransactionManager_AmountOutsideLimitsIsNotRecorded(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	fastPath := persistencemocks.NewMockAtomicTransactionRepository(t)
+	f.manager.WithFastPath(fastPath)
+	f.manager.WithLimitPolicy(newTestLimitPolicy(t, newTestLimitRule(t, 0, "", "", "", "100.00", "")))
+
+	// Neither the fast path nor the lock is touched: their mocks fail the test on any call
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "lose", "100.01")
+	assert.ErrorIs(t, err, errs.ErrAmountAboveMaximum)
+	f.txnRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestTransactionManager_ApprovedWinAboveMaxBalanceFails(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+	f.manager.WithApprovalPolicy(testApprovalPolicy)
+	f.manager.WithLimitPolicy(newTestLimitPolicy(t, newTestLimitRule(t, 0, "", "", "", "", "150.00")))
+	f.requestAdjustment(t, "adj-1", "win", "60.00")
+
+	txn, err := f.manager.Approve(context.Background(), "adj-1", bob, "")
+	assert.ErrorIs(t, err, errs.ErrBalanceLimitExceeded)
+	require.NotNil(t, txn)
+	assert.Equal(t, entity.StatusFailed, txn.Status)
+	assert.Equal(t, balanceLimitMessage, txn.ErrorMessage)
+	assert.Equal(t, "100.00", f.user.GetBalance())
+	assert.Equal(t, []entity.ApprovalAction{entity.ApprovalRequested, entity.ApprovalApproved}, f.actions("adj-1"))
+}
@@ -13,6 +13,8 @@ type TransactionProcessor struct {
 	transactionManager *TransactionManager
 	validator          *TransactionValidator
 	idempotencyHandler *IdempotencyHandler
+	executor           *UserQueueExecutor // Optional; serialises processing per user when set
+	coalescer          *RequestCoalescer  // Optional; collapses duplicate submissions of a transaction ID when set
 }
 
 // NewTransactionProcessor creates a new TransactionProcessor
@@ -35,40 +37,106 @@ type ProcessTransactionRequest struct {
 	SourceType    string
 	State         string
 	Amount        string
+	Adjustment    *entity.Adjustment // Required for admin adjustments, rejected for everything else
 }
 
 // Process handles the processing of a transaction
 // This method orchestrates the entire process:
 // 1. Validates the transaction input
-// 2. Checks for idempotency
-// 3. Processes the transaction through the transaction manager
+// 2. Joins an in-flight or recently completed execution of the same transaction ID when a coalescer is set
+// 3. Checks for idempotency
+// 4. Processes the transaction through the transaction manager, queued per user when an executor is set
+//
+// stored reports that the transaction ID was processed before, by this request's duplicates or
+// anyone else; the stored transaction is then returned and nothing is applied.
 func (p *TransactionProcessor) Process(
 	ctx context.Context,
 	req ProcessTransactionRequest,
-) (*entity.Transaction, error) {
+) (txn *entity.Transaction, stored bool, err error) {
 	// Step 1: Validate the request
 	if err := p.validator.ValidateTransaction(req.UserID, req.TransactionID, req.SourceType, req.State, req.Amount); err != nil {
-		return nil, fmt.Errorf("invalid transaction: %w", err)
+		return nil, false, fmt.Errorf("invalid transaction: %w", err)
 	}
+	if err := p.validator.ValidateAdjustment(req.SourceType, req.Adjustment); err != nil {
+		return nil, false, fmt.Errorf("invalid transaction: %w", err)
+	}
+
+	// Step 2: Let duplicates share one execution instead of each querying and queueing for the lock
+	if p.coalescer != nil {
+		// The work only runs for the request that leads; the others get a transaction it already stored
+		executed := false
+		txn, err = p.coalescer.Do(ctx, req.TransactionID, func(ctx context.Context) (*entity.Transaction, error) {
+			executed = true
+			result, resultStored, err := p.execute(ctx, req)
+			stored = resultStored
+			return result, err
+		})
+		if err != nil {
+			return txn, false, err
+		}
+		return txn, stored || !executed, nil
+	}
+	return p.execute(ctx, req)
+}
 
-	// Step 2: Check for idempotency
+// execute checks idempotency and processes a validated transaction
+func (p *TransactionProcessor) execute(
+	ctx context.Context,
+	req ProcessTransactionRequest,
+) (*entity.Transaction, bool, error) {
+	// Step 3: Check for idempotency
 	// Note: We also check idempotency in the transaction manager, but doing an initial check here
-	// allows us to return quickly without acquiring database locks for duplicate requests
-	txn, found, err := p.idempotencyHandler.CheckIdempotency(ctx, req.TransactionID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check idempotency: %w", err)
+	// allows us to return quickly without acquiring database locks for duplicate requests.
+	// The fast path takes no lock and detects duplicates itself, so the lookup would only add a round trip.
+	if !p.transactionManager.FastPathEnabled() {
+		txn, found, err := p.idempotencyHandler.CheckIdempotency(ctx, req.TransactionID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to check idempotency: %w", err)
+		}
+		if found {
+			return txn, true, nil
+		}
 	}
-	if found {
-		return txn, nil
+
+	// Step 4: Process the transaction
+	stored := false
+	process := func(ctx context.Context) (*entity.Transaction, error) {
+		txn, processedBefore, err := p.transactionManager.processTransaction(
+			ctx,
+			req.UserID,
+			req.TransactionID,
+			req.SourceType,
+			req.State,
+			req.Amount,
+			entity.WithAdjustment(req.Adjustment),
+		)
+		stored = processedBefore
+		return txn, err
 	}
 
-	// Step 3: Process the transaction
-	return p.transactionManager.ProcessTransaction(
-		ctx,
-		req.UserID,
-		req.TransactionID,
-		req.SourceType,
-		req.State,
-		req.Amount,
-	)
+	// Queue behind earlier transactions for the same user before touching the DB lock
+	var txn *entity.Transaction
+	var err error
+	if p.executor != nil {
+		txn, err = p.executor.Submit(ctx, req.UserID, process)
+	} else {
+		txn, err = process(ctx)
+	}
+	// A job abandoned by the caller may still be running, so stored is only read once its result arrived
+	if err != nil {
+		return txn, false, err
+	}
+	return txn, stored, nil
+}
+
+// WithExecutor routes processing through the given per-user queue executor
+func (p *TransactionProcessor) WithExecutor(executor *UserQueueExecutor) *TransactionProcessor {
+	p.executor = executor
+	return p
+}
+
+// WithCoalescer routes processing through the given duplicate-request coalescer
+func (p *TransactionProcessor) WithCoalescer(coalescer *RequestCoalescer) *TransactionProcessor {
+	p.coalescer = coalescer
+	return p
 }
@@ -0,0 +1,224 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+)
+
+// Where a rebuild's initial balance came from
+const (
+	InitialBalanceRequested  = "request" // Given with the rebuild
+	InitialBalanceConfigured = "config"  // The configured rebuild.initialBalance
+	InitialBalanceFromLog    = "log"     // Implied by the user's first completed transaction
+	InitialBalanceStored     = "stored"  // Nothing was ever completed, so the stored balance stands
+)
+
+// RebuildRequest asks for a user's balance to be rebuilt from their transaction log
+type RebuildRequest struct {
+	InitialBalance string // Optional; overrides the configured initial balance
+	Apply          bool   // Write the rebuilt balance; otherwise only report the difference
+	Operator       string // Who asked for the rebuild, for the log
+}
+
+// RebuildReport compares a user's stored balance with the one replayed from their log
+type RebuildReport struct {
+	UserID               uint64
+	InitialBalance       entity.Money
+	InitialBalanceSource string
+	Replayed             int // Completed transactions replayed
+	StoredBalance        entity.Money
+	RebuiltBalance       entity.Money
+	StoredCount          uint64 // Stored transaction count
+	RebuiltCount         uint64
+	Applied              bool     // The rebuilt balance was written
+	Warnings             []string // Things in the log that look wrong, such as the balance going negative
+}
+
+// Changed reports whether the rebuilt balance or count differs from what is stored
+func (r *RebuildReport) Changed() bool {
+	return r.StoredBalance != r.RebuiltBalance || r.StoredCount != r.RebuiltCount
+}
+
+// RebuildPolicy holds the settings of balance rebuilds
+type RebuildPolicy struct {
+	InitialBalance *entity.Money // Balance replays start from; nil uses the one the log implies
+}
+
+// NewRebuildPolicy creates a rebuild policy from the configured initial balance
+// An empty balance makes rebuilds start from the balance the user's first completed transaction implies.
+//
+// Possible errors:
+// - ErrInvalidRequest: If the initial balance is not a valid amount
+func NewRebuildPolicy(initialBalance string) (RebuildPolicy, error) {
+	if initialBalance == "" {
+		return RebuildPolicy{}, nil
+	}
+	balance, err := parseInitialBalance(initialBalance)
+	if err != nil {
+		return RebuildPolicy{}, err
+	}
+	return RebuildPolicy{InitialBalance: &balance}, nil
+}
+
+// WithRebuildPolicy sets the rebuild policy
+func (m *TransactionManager) WithRebuildPolicy(policy RebuildPolicy) *TransactionManager {
+	m.rebuildPolicy = policy
+	return m
+}
+
+// RebuildBalance replays a user's completed transactions in booking order and compares the result
+// with the stored balance and transaction count
+// Without Apply nothing is written and no lock is taken. With Apply the replay is repeated under the
+// user's lock in one unit of work, so no transaction lands between the replay and the write.
+//
+// Possible errors:
+// - ErrInvalidRequest: If the initial balance is not a valid amount
+// - ErrAmountOverflow: If replaying the log takes the balance out of the range Money can hold
+// - ErrUserNotFound: If user with specified ID doesn't exist
+// - ErrUserLocked: If the user stays locked by another operation
+// - ErrShuttingDown: If the manager is shutting down
+// - ErrDatabaseConnection: If database connection fails
+func (m *TransactionManager) RebuildBalance(ctx context.Context, userID uint64, req RebuildRequest) (*RebuildReport, error) {
+	if m.shutdown.Load() {
+		return nil, errs.ErrShuttingDown
+	}
+
+	initial, source := m.rebuildPolicy.InitialBalance, InitialBalanceConfigured
+	if req.InitialBalance != "" {
+		balance, err := parseInitialBalance(req.InitialBalance)
+		if err != nil {
+			return nil, err
+		}
+		initial, source = &balance, InitialBalanceRequested
+	}
+
+	if !req.Apply {
+		return m.replay(ctx, userID, initial, source)
+	}
+
+	var report *RebuildReport
+	_, err := m.retry(ctx, fmt.Sprintf("rebuild-%d", userID), func(ctx context.Context) (*entity.Transaction, error) {
+		return m.withUserLock(ctx, userID, "", func(dbCtx context.Context) (*entity.Transaction, error) {
+			var err error
+			report, err = m.replay(dbCtx, userID, initial, source)
+			if err != nil {
+				return nil, err
+			}
+			return nil, m.applyRebuild(dbCtx, report)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Warn("User balance rebuilt from the transaction log", map[string]any{
+		"user_id":         userID,
+		"operator":        req.Operator,
+		"stored_balance":  report.StoredBalance.String(),
+		"rebuilt_balance": report.RebuiltBalance.String(),
+		"stored_count":    report.StoredCount,
+		"rebuilt_count":   report.RebuiltCount,
+		"initial_source":  report.InitialBalanceSource,
+	})
+	return report, nil
+}
+
+// replay reads the user and replays their completed transactions, inside the unit of work if ctx carries one
+func (m *TransactionManager) replay(ctx context.Context, userID uint64, initial *entity.Money, source string) (*RebuildReport, error) {
+	user, err := m.unitOfWork.GetUserRepository(ctx).GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	txnRepo := m.unitOfWork.GetTransactionRepository(ctx)
+
+	report := &RebuildReport{
+		UserID:        userID,
+		StoredBalance: user.Balance(),
+		StoredCount:   user.TransactionCount,
+	}
+	if initial == nil {
+		first, err := txnRepo.FirstCompleted(ctx, userID)
+		switch {
+		case errors.Is(err, errs.ErrTransactionNotFound):
+			// Nothing to replay; the balance the user was created with stands
+			report.InitialBalance, report.InitialBalanceSource = user.Balance(), InitialBalanceStored
+		case err != nil:
+			return nil, err
+		default:
+			if report.InitialBalance, err = first.ResultBalance.Sub(first.BalanceChange()); err != nil {
+				return nil, fmt.Errorf("transaction %s: %w", first.TransactionID, err)
+			}
+			report.InitialBalanceSource = InitialBalanceFromLog
+		}
+	} else {
+		report.InitialBalance, report.InitialBalanceSource = *initial, source
+	}
+	if report.InitialBalance.IsNegative() {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"the initial balance of %s is negative", report.InitialBalance))
+	}
+
+	balance := report.InitialBalance
+	err = txnRepo.StreamByUser(ctx, userID, time.Time{}, time.Time{}, func(txn *entity.Transaction) error {
+		if txn.Status != entity.StatusCompleted {
+			return nil
+		}
+		previous := balance
+		var err error
+		if balance, err = balance.Add(txn.BalanceChange()); err != nil {
+			return fmt.Errorf("transaction %s: %w", txn.TransactionID, err)
+		}
+		report.Replayed++
+		// Warn where the balance goes negative rather than for every transaction while it stays there
+		if balance.IsNegative() && !previous.IsNegative() {
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"transaction %s takes the balance to %s", txn.TransactionID, balance))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.RebuiltBalance = balance
+	report.RebuiltCount = uint64(report.Replayed)
+	return report, nil
+}
+
+// applyRebuild writes the rebuilt balance and count; a negative balance is refused
+func (m *TransactionManager) applyRebuild(ctx context.Context, report *RebuildReport) error {
+	if report.RebuiltBalance.IsNegative() {
+		return fmt.Errorf("%w: the log replays to a negative balance of %s",
+			errs.ErrInvalidRequest, report.RebuiltBalance)
+	}
+	if !report.Changed() {
+		return nil
+	}
+
+	userRepo := m.unitOfWork.GetUserRepository(ctx)
+	user, err := userRepo.GetByID(ctx, report.UserID)
+	if err != nil {
+		return err
+	}
+	user.SetBalance(report.RebuiltBalance, m.timeProvider)
+	user.TransactionCount = report.RebuiltCount
+	if err := userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	report.Applied = true
+	return nil
+}
+
+// parseInitialBalance parses a rebuild's initial balance
+func parseInitialBalance(balance string) (entity.Money, error) {
+	parsed, err := entity.ParseMoney(balance)
+	if err != nil {
+		return entity.Money{}, fmt.Errorf("%w: invalid initial balance %q: %s", errs.ErrInvalidRequest, balance, err.Error())
+	}
+	return parsed, nil
+}
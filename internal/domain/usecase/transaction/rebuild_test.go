@@ -0,0 +1,170 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// rebuildTxn builds a logged transaction that left the given result balance
+func rebuildTxn(id, state string, cents, result int64, status entity.TransactionStatus) *entity.Transaction {
+	return &entity.Transaction{
+		UserID:        1,
+		TransactionID: id,
+		SourceType:    entity.SourceGame,
+		State:         entity.TransactionState(state),
+		Amount:        entity.MoneyFromCents(cents),
+		ResultBalance: entity.MoneyFromCents(result),
+		Status:        status,
+	}
+}
+
+// withRebuildLog gives the fixture's user the stored balance and count and the transaction log, in booking order
+func (f *transactionManagerFixture) withRebuildLog(t *testing.T, storedBalance string, storedCount uint64, log ...*entity.Transaction) *transactionManagerFixture {
+	balance, err := entity.ParseMoney(storedBalance)
+	require.NoError(t, err)
+	f.user.SetBalance(balance, f.timeProvider)
+	f.user.TransactionCount = storedCount
+
+	f.txnRepo.EXPECT().FirstCompleted(mock.Anything, uint64(1)).RunAndReturn(func(context.Context, uint64) (*entity.Transaction, error) {
+		for _, txn := range log {
+			if txn.Status == entity.StatusCompleted {
+				return txn, nil
+			}
+		}
+		return nil, errs.ErrTransactionNotFound
+	}).Maybe()
+	f.txnRepo.EXPECT().StreamByUser(mock.Anything, uint64(1), time.Time{}, time.Time{}, mock.Anything).
+		RunAndReturn(func(_ context.Context, _ uint64, _, _ time.Time, fn func(*entity.Transaction) error) error {
+			for _, txn := range log {
+				if err := fn(txn); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Maybe()
+	return f
+}
+
+// expectLock lets the rebuild take and release the user's lock once
+func (f *transactionManagerFixture) expectLock() {
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Maybe()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Maybe()
+}
+
+// corruptedLog is a user that started at 100.00 and whose stored balance and count were later overwritten
+var corruptedLog = []*entity.Transaction{
+	rebuildTxn("t1", "win", 5000, 15000, entity.StatusCompleted),
+	rebuildTxn("t2", "lose", 99900, 0, entity.StatusFailed),
+	rebuildTxn("t3", "lose", 2500, 12500, entity.StatusCompleted),
+	rebuildTxn("t4", "win", 500, 0, entity.StatusPendingApproval),
+}
+
+func TestTransactionManager_RebuildBalance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Dry run reports the difference without locking or writing", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "999.99", 7, corruptedLog...)
+
+		report, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, &RebuildReport{
+			UserID:               1,
+			InitialBalance:       entity.MoneyFromCents(10000),
+			InitialBalanceSource: InitialBalanceFromLog,
+			Replayed:             2,
+			StoredBalance:        entity.MoneyFromCents(99999),
+			RebuiltBalance:       entity.MoneyFromCents(12500),
+			StoredCount:          7,
+			RebuiltCount:         2,
+		}, report)
+		assert.True(t, report.Changed())
+	})
+
+	t.Run("Apply writes the rebuilt balance and count under the lock", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "999.99", 7, corruptedLog...)
+		f.expectLock()
+
+		report, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{Apply: true, Operator: "alice"})
+		require.NoError(t, err)
+		assert.True(t, report.Applied)
+		f.userRepo.AssertCalled(t, "Update", mock.Anything, f.user)
+		assert.Equal(t, entity.MoneyFromCents(12500), f.user.Balance())
+		assert.Equal(t, uint64(2), f.user.TransactionCount)
+	})
+
+	t.Run("Apply leaves a consistent user alone", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "125.00", 2, corruptedLog...)
+		f.expectLock()
+
+		report, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{Apply: true, Operator: "alice"})
+		require.NoError(t, err)
+		assert.False(t, report.Changed())
+		assert.False(t, report.Applied)
+		f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("A requested initial balance overrides the configured one", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "125.00", 2, corruptedLog...)
+		policy, err := NewRebuildPolicy("0")
+		require.NoError(t, err)
+		f.manager.WithRebuildPolicy(policy)
+
+		report, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, InitialBalanceConfigured, report.InitialBalanceSource)
+		assert.Equal(t, entity.MoneyFromCents(2500), report.RebuiltBalance)
+
+		report, err = f.manager.RebuildBalance(ctx, 1, RebuildRequest{InitialBalance: "200.00"})
+		require.NoError(t, err)
+		assert.Equal(t, InitialBalanceRequested, report.InitialBalanceSource)
+		assert.Equal(t, entity.MoneyFromCents(22500), report.RebuiltBalance)
+	})
+
+	t.Run("Without completed transactions the stored balance stands", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "42.00", 0, rebuildTxn("t1", "lose", 99900, 0, entity.StatusFailed))
+
+		report, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, InitialBalanceStored, report.InitialBalanceSource)
+		assert.False(t, report.Changed())
+	})
+
+	t.Run("A log that goes negative is reported and not applied", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "25.00", 2,
+			rebuildTxn("t1", "lose", 2500, 2500, entity.StatusCompleted),
+			rebuildTxn("t2", "lose", 1000, 1500, entity.StatusCompleted))
+
+		report, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{InitialBalance: "20.00"})
+		require.NoError(t, err)
+		assert.Equal(t, entity.MoneyFromCents(-1500), report.RebuiltBalance)
+		assert.Equal(t, []string{"transaction t1 takes the balance to -5.00"}, report.Warnings)
+
+		f.expectLock()
+		_, err = f.manager.RebuildBalance(ctx, 1, RebuildRequest{InitialBalance: "20.00", Apply: true})
+		assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		f := newTransactionManagerFixture(t, 1).withRebuildLog(t, "125.00", 2)
+		f.userRepo.EXPECT().GetByID(mock.Anything, uint64(2)).Return(nil, errs.ErrUserNotFound).Once()
+
+		_, err := f.manager.RebuildBalance(ctx, 1, RebuildRequest{InitialBalance: "-5"})
+		assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+		_, err = f.manager.RebuildBalance(ctx, 2, RebuildRequest{})
+		assert.ErrorIs(t, err, errs.ErrUserNotFound)
+
+		_, err = NewRebuildPolicy("lots")
+		assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+	})
+}
@@ -0,0 +1,93 @@
+package transaction
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// Defaults for the transaction retry policy
+const (
+	DefaultMaxRetries   = 3
+	DefaultBaseBackoff  = 5 * time.Millisecond
+	DefaultMaxBackoff   = 200 * time.Millisecond
+	DefaultJitterFactor = 0.2
+)
+
+// RetryPolicy decides which failed attempts are retried and how long to wait before each retry
+type RetryPolicy struct {
+	MaxRetries   int           // Retries after the first attempt; 0 disables retrying
+	BaseBackoff  time.Duration // Wait before the first retry; doubled for every further retry
+	MaxBackoff   time.Duration // Upper bound on the wait before jitter is added
+	JitterFactor float64       // Up to this fraction of the wait is added at random (0.0-1.0)
+}
+
+// RetryMetrics is a snapshot of transaction retry counters
+type RetryMetrics struct {
+	Retries   int64 // Attempts that were retried after a retryable failure
+	Exhausted int64 // Transactions that failed after using up every retry
+	Aborted   int64 // Retries abandoned because the request context ended during the backoff
+}
+
+// DefaultRetryPolicy returns the default retry policy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:   DefaultMaxRetries,
+		BaseBackoff:  DefaultBaseBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+		JitterFactor: DefaultJitterFactor,
+	}
+}
+
+// MaxAttempts returns the total number of attempts the policy allows
+func (p RetryPolicy) MaxAttempts() int {
+	if p.MaxRetries < 0 {
+		return 1
+	}
+	return p.MaxRetries + 1
+}
+
+// Backoff returns how long to wait before the given retry, counting from 1
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 || p.BaseBackoff <= 0 {
+		return 0
+	}
+
+	// Exponential backoff, capped before it can overflow
+	backoff := p.BaseBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	// Spread retries of transactions that failed together so they do not collide again
+	if p.JitterFactor > 0 {
+		backoff += time.Duration(float64(backoff) * p.JitterFactor * rand.Float64())
+	}
+	return backoff
+}
+
+// IsRetryable reports whether a failed attempt may be retried
+// Only failures that guarantee nothing was committed qualify: a lost lock is detected before
+// commit, and a concurrency conflict means the database rolled the transaction back.
+func (p RetryPolicy) IsRetryable(err error) bool {
+	return errs.IsLockLostError(err) || errs.IsConcurrencyConflictError(err)
+}
+
+// wait sleeps for d using the time provider, returning early with the context's error if it ends first
+func wait(ctx context.Context, timeProvider coreport.TimeProvider, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	waitCtx, cancel := timeProvider.WithTimeout(ctx, coreport.Duration(d))
+	defer cancel()
+	<-waitCtx.Done()
+
+	return ctx.Err()
+}
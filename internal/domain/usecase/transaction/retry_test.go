@@ -0,0 +1,62 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_BackoffGrowsAndIsCapped(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseBackoff: 5 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+
+	assert.Equal(t, time.Duration(0), policy.Backoff(0))
+	assert.Equal(t, 5*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 10*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 20*time.Millisecond, policy.Backoff(3))
+	assert.Equal(t, 40*time.Millisecond, policy.Backoff(4))
+	assert.Equal(t, 40*time.Millisecond, policy.Backoff(100))
+}
+
+func TestRetryPolicy_JitterStaysInRange(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseBackoff: 10 * time.Millisecond, MaxBackoff: time.Second, JitterFactor: 0.5}
+
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(2)
+		assert.GreaterOrEqual(t, backoff, 20*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 30*time.Millisecond)
+	}
+}
+
+func TestRetryPolicy_MaxAttempts(t *testing.T) {
+	assert.Equal(t, 1, RetryPolicy{MaxRetries: 0}.MaxAttempts())
+	assert.Equal(t, 1, RetryPolicy{MaxRetries: -1}.MaxAttempts())
+	assert.Equal(t, 4, DefaultRetryPolicy().MaxAttempts())
+}
+
+func TestRetryPolicy_IsRetryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	testCases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"LockLost", fmt.Errorf("lock lost before commit: %w", errs.ErrLockLost), true},
+		{"ConcurrencyConflict", fmt.Errorf("failed to commit: %w", errs.ErrConcurrencyConflict), true},
+		{"InsufficientBalance", errs.ErrInsufficientBalance, false},
+		{"UserLocked", errs.ErrUserLocked, false},
+		{"DatabaseConnection", errs.ErrDatabaseConnection, false},
+		// Messages alone are not trusted; only typed errors are retried
+		{"UntypedSerializationMessage", errors.New("could not serialize access (SQLSTATE 40001)"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.retryable, policy.IsRetryable(tc.err))
+		})
+	}
+}
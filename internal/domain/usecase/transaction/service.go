@@ -2,8 +2,11 @@ package transaction
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
@@ -12,20 +15,47 @@ import (
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
 )
 
+// Processing modes for transactions
+const (
+	// ProcessingModeLocked takes the user lock and applies the transaction in a SERIALIZABLE unit of work
+	ProcessingModeLocked = "locked"
+	// ProcessingModeFast applies the transaction with one conditional update under READ COMMITTED
+	ProcessingModeFast = "fast"
+)
+
 // TransactionRequest represents a request to process a transaction
 type TransactionRequest struct {
 	TransactionID string
 	SourceType    entity.SourceType
 	State         string
 	Amount        string
+	Adjustment    *entity.Adjustment // Required for admin adjustments, rejected for everything else
+}
+
+// Directions of a manual balance adjustment
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+// AdjustmentRequest represents an operator's request to adjust a user's balance
+type AdjustmentRequest struct {
+	TransactionID string // Makes the adjustment idempotent like any other transaction
+	Direction     string // AdjustmentCredit or AdjustmentDebit
+	Amount        string
+	Reason        string
+	Note          string
+	Operator      string
 }
 
 // TransactionResponse represents the response after processing a transaction
 type TransactionResponse struct {
 	Success       bool
-	ResultBalance string
+	Status        entity.TransactionStatus // Status of the processed transaction; empty when processing failed
+	ResultBalance entity.Money // Set when Success is true
 	ErrorMessage  string
 	StatusCode    int
+	RetryAfter    time.Duration // Set when the client should wait this long before retrying
 }
 
 // Service is the main transaction service implementation that ties together
@@ -35,7 +65,19 @@ type Service struct {
 	processor          *TransactionProcessor
 	validator          *TransactionValidator
 	idempotencyHandler *IdempotencyHandler
+	executor           *UserQueueExecutor
+	coalescer          *RequestCoalescer
+	admission          *AdmissionController
+	timeProvider       coreport.TimeProvider
 	logger             coreport.Logger
+
+	drainMu  sync.Mutex
+	draining bool           // Set once Drain starts; new transactions are rejected
+	active   int            // Transactions in flight
+	inFlight sync.WaitGroup // Counts the same transactions as active, for waiting on them
+	abortCtx context.Context
+	abort    context.CancelCauseFunc // Cancels every in-flight transaction
+	rejected atomic.Int64
 }
 
 // NewTransactionService creates a new transaction service
@@ -57,12 +99,17 @@ func NewTransactionService(
 
 	processor := NewTransactionProcessor(manager, validator, idempotencyHandler)
 
+	abortCtx, abort := context.WithCancelCause(context.Background())
+
 	return &Service{
 		manager:            manager,
 		processor:          processor,
 		validator:          validator,
 		idempotencyHandler: idempotencyHandler,
+		timeProvider:       timeProvider,
 		logger:             logger,
+		abortCtx:           abortCtx,
+		abort:              abort,
 	}
 }
 
@@ -79,18 +126,35 @@ func (s *Service) ProcessTransaction(
 		SourceType:    string(req.SourceType),
 		State:         req.State,
 		Amount:        req.Amount,
+		Adjustment:    req.Adjustment,
 	}
 
-	// Process the transaction
-	txn, err := s.processor.Process(ctx, processReq)
+	// Process the transaction unless the service is draining for shutdown
+	var txn *entity.Transaction
+	workCtx, finish, err := s.track(ctx)
+	if err == nil {
+		txn, err = s.admitAndProcess(workCtx, req.SourceType, processReq)
+		err = finish(err)
+	}
 
 	// Handle the response
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		errorMessage := err.Error()
+		var retryAfter time.Duration
 
 		// Map known errors to appropriate status codes
 		switch {
+		// Checked first: a transaction cut off by shutdown fails with whatever the cancellation caused
+		case errs.IsShuttingDownError(err):
+			statusCode = http.StatusServiceUnavailable
+			errorMessage = "Service is shutting down. Please try again."
+
+		case errs.IsOverloadedError(err):
+			statusCode = http.StatusServiceUnavailable
+			errorMessage = "Service is overloaded. Please try again later."
+			retryAfter = s.admission.RetryAfter()
+
 		case errs.IsUserNotFoundError(err):
 			statusCode = http.StatusNotFound
 			
@@ -99,25 +163,37 @@ func (s *Service) ProcessTransaction(
 			
 		case errs.IsInsufficientBalanceError(err):
 			statusCode = http.StatusBadRequest
+
+		case errors.Is(err, errs.ErrAmountOverflow):
+			statusCode = http.StatusBadRequest
+
+		case errs.IsLimitError(err):
+			statusCode = http.StatusBadRequest
+
+		case errs.IsInvalidAdjustmentError(err):
+			statusCode = http.StatusBadRequest
 			
 		case errs.IsUserLockedError(err):
 			statusCode = http.StatusConflict
+
+		case errs.IsLockLostError(err):
+			statusCode = http.StatusConflict
+			errorMessage = "Transaction was interrupted by a concurrent operation. Please try again."
+
+		case errs.IsUserQueueFullError(err):
+			statusCode = http.StatusTooManyRequests
+			errorMessage = "Too many pending transactions for this user. Please try again later."
+
+		case errors.Is(err, errs.ErrExecutorStopped):
+			statusCode = http.StatusServiceUnavailable
+			errorMessage = "Service is shutting down. Please try again."
 			
 		case errs.IsNotFoundError(err):
 			statusCode = http.StatusNotFound
-			
-		// Identify database concurrency errors specifically
-		case strings.Contains(strings.ToLower(err.Error()), "deadlock"):
-			statusCode = http.StatusConflict
-			errorMessage = "Transaction could not be processed due to concurrent operations. Please try again."
-			
-		case strings.Contains(strings.ToLower(err.Error()), "serialization"):
+
+		case errs.IsConcurrencyConflictError(err):
 			statusCode = http.StatusConflict
 			errorMessage = "Transaction could not be processed due to concurrent operations. Please try again."
-			
-		case strings.Contains(strings.ToLower(err.Error()), "lock timeout"):
-			statusCode = http.StatusConflict
-			errorMessage = "Transaction processing timed out due to lock contention. Please try again."
 		}
 
 		// Log the error with more detail for internal use
@@ -132,25 +208,225 @@ func (s *Service) ProcessTransaction(
 			Success:      false,
 			ErrorMessage: errorMessage,
 			StatusCode:   statusCode,
+			RetryAfter:   retryAfter,
 		}, err
 	}
 
+	switch txn.Status {
+	case entity.StatusPendingApproval:
+		// Accepted, but the balance changes only once a second person approves it
+		return &TransactionResponse{
+			Status:     txn.Status,
+			StatusCode: http.StatusAccepted,
+		}, nil
+
+	case entity.StatusRejected, entity.StatusExpired:
+		// A replay of a transaction that was held and never applied
+		return &TransactionResponse{
+			Status:       txn.Status,
+			ErrorMessage: txn.ErrorMessage,
+			StatusCode:   http.StatusOK,
+		}, nil
+	}
+
 	// Successful transaction
 	return &TransactionResponse{
 		Success:       true,
-		ResultBalance: txn.GetResultBalance(),
+		Status:        txn.Status,
+		ResultBalance: txn.ResultBalance,
 		StatusCode:    http.StatusOK,
 	}, nil
 }
 
+// AdjustBalance credits or debits a user's balance on an operator's behalf
+// The adjustment is an admin transaction, so it takes the same lock, idempotency and admission path
+// as provider transactions and shows up in the user's history with its reason, note and operator.
+func (s *Service) AdjustBalance(ctx context.Context, userID uint64, req AdjustmentRequest) (*TransactionResponse, error) {
+	var state entity.TransactionState
+	switch req.Direction {
+	case AdjustmentCredit:
+		state = entity.StateWin
+	case AdjustmentDebit:
+		state = entity.StateLose
+	default:
+		err := fmt.Errorf("%w: direction must be %s or %s, not %q", errs.ErrInvalidAdjustment, AdjustmentCredit, AdjustmentDebit, req.Direction)
+		return &TransactionResponse{ErrorMessage: err.Error(), StatusCode: http.StatusBadRequest}, err
+	}
+
+	adjustment, err := entity.NewAdjustment(req.Reason, req.Note, req.Operator)
+	if err != nil {
+		return &TransactionResponse{ErrorMessage: err.Error(), StatusCode: http.StatusBadRequest}, err
+	}
+
+	s.logger.Info("Adjusting balance", map[string]any{
+		"transaction_id": req.TransactionID,
+		"user_id":        userID,
+		"direction":      req.Direction,
+		"amount":         req.Amount,
+		"reason":         adjustment.Reason.String(),
+		"operator":       adjustment.Operator,
+	})
+
+	return s.ProcessTransaction(ctx, userID, TransactionRequest{
+		TransactionID: req.TransactionID,
+		SourceType:    entity.SourceAdmin,
+		State:         state.String(),
+		Amount:        req.Amount,
+		Adjustment:    adjustment,
+	})
+}
+
+// ApproveTransaction applies a transaction held for approval, on behalf of an approver other than its requester
+func (s *Service) ApproveTransaction(ctx context.Context, transactionID string, approver entity.Principal, note string) (*entity.Transaction, error) {
+	workCtx, finish, err := s.track(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := s.manager.Approve(workCtx, transactionID, approver, note)
+	return txn, finish(err)
+}
+
+// RejectTransaction turns down a transaction held for approval without changing the balance
+func (s *Service) RejectTransaction(ctx context.Context, transactionID string, approver entity.Principal, note string) (*entity.Transaction, error) {
+	workCtx, finish, err := s.track(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := s.manager.Reject(workCtx, transactionID, approver, note)
+	return txn, finish(err)
+}
+
+// ExpireStaleApprovals expires transactions that waited for approval longer than the policy allows
+func (s *Service) ExpireStaleApprovals(ctx context.Context) (int, error) {
+	workCtx, finish, err := s.track(ctx)
+	if err != nil {
+		return 0, err
+	}
+	expired, err := s.manager.ExpireStaleApprovals(workCtx)
+	return expired, finish(err)
+}
+
+// ListPendingApprovals returns up to limit transactions awaiting approval, oldest first; 0 returns all
+func (s *Service) ListPendingApprovals(ctx context.Context, limit int) ([]*entity.Transaction, error) {
+	return s.manager.ListPendingApprovals(ctx, limit)
+}
+
+// GetTransaction returns a transaction by its ID
+func (s *Service) GetTransaction(ctx context.Context, transactionID string) (*entity.Transaction, error) {
+	return s.manager.GetTransaction(ctx, transactionID)
+}
+
+// ApprovalHistory returns the approval audit trail of a transaction, oldest first
+func (s *Service) ApprovalHistory(ctx context.Context, transactionID string) ([]*entity.ApprovalEvent, error) {
+	return s.manager.ApprovalHistory(ctx, transactionID)
+}
+
+// WithApprovalPolicy holds the transactions the policy selects until a second person approves them
+func (s *Service) WithApprovalPolicy(policy ApprovalPolicy) *Service {
+	s.manager.WithApprovalPolicy(policy)
+	return s
+}
+
+// WithLimitPolicy checks transactions against the policy's amount and balance limits, both when they are
+// validated and when they are applied
+func (s *Service) WithLimitPolicy(policy LimitPolicy) *Service {
+	s.validator.WithLimitPolicy(policy)
+	s.manager.WithLimitPolicy(policy)
+	return s
+}
+
+// RebuildBalance replays a user's completed transactions and compares or replaces their stored balance
+func (s *Service) RebuildBalance(ctx context.Context, userID uint64, req RebuildRequest) (*RebuildReport, error) {
+	return s.manager.RebuildBalance(ctx, userID, req)
+}
+
+// WithRebuildPolicy sets where balance rebuilds start from
+func (s *Service) WithRebuildPolicy(policy RebuildPolicy) *Service {
+	s.manager.WithRebuildPolicy(policy)
+	return s
+}
+
+// admitAndProcess processes the transaction once the admission controller grants it a slot
+func (s *Service) admitAndProcess(ctx context.Context, source entity.SourceType, req ProcessTransactionRequest) (*entity.Transaction, error) {
+	if s.admission != nil {
+		release, err := s.admission.Acquire(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	txn, _, err := s.processor.Process(ctx, req)
+	return txn, err
+}
+
+// WithUserQueue serialises transactions per user through the given executor
+// before they reach the database lock
+func (s *Service) WithUserQueue(executor *UserQueueExecutor) *Service {
+	s.executor = executor
+	s.processor.WithExecutor(executor)
+	return s
+}
+
+// WithCoalescing makes concurrent duplicates of a transaction ID wait for the first
+// submission's result, and answers replays of up to cacheSize recently completed
+// transactions from memory
+func (s *Service) WithCoalescing(cacheSize int) *Service {
+	s.coalescer = NewRequestCoalescer(cacheSize, s.logger)
+	s.processor.WithCoalescer(s.coalescer)
+	return s
+}
+
+// WithAdmissionControl caps the number of transactions processed at once, queueing and
+// shedding the rest according to the policy
+func (s *Service) WithAdmissionControl(policy AdmissionPolicy) *Service {
+	s.admission = NewAdmissionController(policy, s.timeProvider, s.logger)
+	return s
+}
+
+// WithFastPath switches processing to the single-statement fast path
+func (s *Service) WithFastPath(repo persistence.AtomicTransactionRepository) *Service {
+	s.manager.WithFastPath(repo)
+	return s
+}
+
+// FastPathEnabled reports whether transactions are processed on the fast path
+// The fast path reports unknown users itself, so callers can skip their own existence check
+func (s *Service) FastPathEnabled() bool {
+	return s.manager.FastPathEnabled()
+}
+
+// GetExecutor returns the per-user queue executor, or nil if none is configured
+func (s *Service) GetExecutor() *UserQueueExecutor {
+	return s.executor
+}
+
+// GetCoalescer returns the duplicate-request coalescer, or nil if none is configured
+func (s *Service) GetCoalescer() *RequestCoalescer {
+	return s.coalescer
+}
+
+// GetAdmission returns the admission controller, or nil if none is configured
+func (s *Service) GetAdmission() *AdmissionController {
+	return s.admission
+}
+
+// GetProcessor returns the underlying transaction processor
+// Used by bulk imports, which submit transactions without the API's admission control
+func (s *Service) GetProcessor() *TransactionProcessor {
+	return s.processor
+}
+
 // GetManager returns the underlying transaction manager
 // Used for graceful shutdown
 func (s *Service) GetManager() *TransactionManager {
 	return s.manager
 }
 
-// Shutdown performs any cleanup tasks needed
+// Shutdown stops the per-user queue
+// Drain calls it once in-flight transactions have finished; calling it directly skips the drain
 func (s *Service) Shutdown() {
-	// Currently no cleanup needed, but this method can be extended
-	// if we add background workers or connection pools
+	// Let queued transactions finish before the caller closes the database
+	if s.executor != nil {
+		s.executor.Stop()
+	}
 }
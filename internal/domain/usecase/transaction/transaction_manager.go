@@ -3,7 +3,7 @@ package transaction
 import (
 	"context"
 	"fmt"
-	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
@@ -12,21 +12,39 @@ import (
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
 )
 
-// Constants for retry logic
-const (
-	maxRetries = 5     // Increased from 3 to 5
-	baseBackoff = 5 * time.Millisecond
-)
+// balanceOverflowMessage is the error message of a credit that failed because the balance could not hold it
+const balanceOverflowMessage = "Balance would overflow"
+
+// LeaseMetrics is a snapshot of user lock lease renewals
+type LeaseMetrics struct {
+	Extensions      int64 // Successful lease renewals
+	RenewalFailures int64 // Renewal attempts that failed, whether or not the lease was lost
+	LostLeases      int64 // Leases given up on, cancelling the work that held them
+}
 
 // TransactionManager manages the processing of transactions
 // This is a usecase (no interface as per requirements)
 type TransactionManager struct {
-	unitOfWork   persistence.UnitOfWork
-	userLockRepo persistence.UserLockRepository
-	timeProvider coreport.TimeProvider
-	logger       coreport.Logger
-	lockTimeout  time.Duration
-	shutdown     bool
+	unitOfWork        persistence.UnitOfWork
+	userLockRepo      persistence.UserLockRepository
+	timeProvider      coreport.TimeProvider
+	logger            coreport.Logger
+	lockTimeout       time.Duration
+	heartbeatInterval time.Duration                           // 0 means a third of the lock timeout
+	fastPath          persistence.AtomicTransactionRepository // Optional; replaces the locked path when set
+	retryPolicy       RetryPolicy
+	approvalPolicy    ApprovalPolicy // Zero value holds nothing for approval
+	limitPolicy       LimitPolicy    // Zero value limits nothing
+	rebuildPolicy     RebuildPolicy
+	shutdown          atomic.Bool
+
+	leaseExtensions atomic.Int64
+	renewalFailures atomic.Int64
+	lostLeases      atomic.Int64
+
+	retries        atomic.Int64
+	retryExhausted atomic.Int64
+	retryAborted   atomic.Int64
 }
 
 // NewTransactionManager creates a new TransactionManager
@@ -42,7 +60,7 @@ func NewTransactionManager(
 		timeProvider: timeProvider,
 		logger:       logger,
 		lockTimeout:  5 * time.Second, // Default lock timeout
-		shutdown:     false,
+		retryPolicy:  DefaultRetryPolicy(),
 	}
 }
 
@@ -52,9 +70,58 @@ func (m *TransactionManager) WithLockTimeout(timeout time.Duration) *Transaction
 	return m
 }
 
+// WithHeartbeatInterval configures how often held locks are renewed
+// Zero renews at a third of the lock timeout
+func (m *TransactionManager) WithHeartbeatInterval(interval time.Duration) *TransactionManager {
+	m.heartbeatInterval = interval
+	return m
+}
+
+// WithFastPath processes transactions with a single conditional update instead of
+// taking the user lock and running a SERIALIZABLE unit of work
+func (m *TransactionManager) WithFastPath(repo persistence.AtomicTransactionRepository) *TransactionManager {
+	m.fastPath = repo
+	return m
+}
+
+// FastPathEnabled reports whether transactions are processed on the fast path
+func (m *TransactionManager) FastPathEnabled() bool {
+	return m.fastPath != nil
+}
+
+// WithRetryPolicy sets how failed attempts are retried
+func (m *TransactionManager) WithRetryPolicy(policy RetryPolicy) *TransactionManager {
+	m.retryPolicy = policy
+	return m
+}
+
+// RetryPolicy returns the policy used to retry failed attempts
+func (m *TransactionManager) RetryPolicy() RetryPolicy {
+	return m.retryPolicy
+}
+
+// RetryMetrics returns a snapshot of retry counters
+func (m *TransactionManager) RetryMetrics() RetryMetrics {
+	return RetryMetrics{
+		Retries:   m.retries.Load(),
+		Exhausted: m.retryExhausted.Load(),
+		Aborted:   m.retryAborted.Load(),
+	}
+}
+
+// LeaseMetrics returns a snapshot of lock lease renewal counters
+func (m *TransactionManager) LeaseMetrics() LeaseMetrics {
+	return LeaseMetrics{
+		Extensions:      m.leaseExtensions.Load(),
+		RenewalFailures: m.renewalFailures.Load(),
+		LostLeases:      m.lostLeases.Load(),
+	}
+}
+
 // ProcessTransaction processes a transaction for a user
 // This method is safe to be called concurrently from different instances
-// as it uses database locks and transactions to ensure consistency
+// as it uses database locks and transactions to ensure consistency.
+// The options are applied to the transaction entity, e.g. to attach an admin adjustment.
 func (m *TransactionManager) ProcessTransaction(
 	ctx context.Context,
 	userID uint64,
@@ -62,80 +129,111 @@ func (m *TransactionManager) ProcessTransaction(
 	sourceType string,
 	state string,
 	amount string,
+	opts ...entity.TransactionOption,
 ) (*entity.Transaction, error) {
+	txn, _, err := m.processTransaction(ctx, userID, transactionID, sourceType, state, amount, opts...)
+	return txn, err
+}
+
+// processTransaction is ProcessTransaction that also reports whether the transaction ID was stored before,
+// in which case the stored transaction is returned and nothing is applied
+func (m *TransactionManager) processTransaction(
+	ctx context.Context,
+	userID uint64,
+	transactionID string,
+	sourceType string,
+	state string,
+	amount string,
+	opts ...entity.TransactionOption,
+) (*entity.Transaction, bool, error) {
 	// Check if we're shutting down
-	if m.shutdown {
-		return nil, fmt.Errorf("transaction manager is shutting down")
+	if m.shutdown.Load() {
+		return nil, false, errs.ErrShuttingDown
 	}
 
-	// Step 1: Check for idempotency first before acquiring any locks
-	txn, err := m.checkIdempotency(ctx, transactionID)
-	if err == nil {
-		// Transaction exists, return it (idempotent response)
-		return txn, nil
-	} else if err != errs.ErrTransactionNotFound {
-		// Some other error occurred
-		return nil, err
+	// The fast path detects duplicates in its insert, so it skips the lookup and the lock.
+	// Transactions that wait for approval are always held through the locked path, and so are
+	// credits whose resulting balance has a maximum.
+	process := m.tryProcessTransaction
+	if m.fastPath != nil && !m.approvalPolicy.requiresApproval(sourceType, state, amount) &&
+		!m.limitPolicy.capsBalance(userID, sourceType, state) {
+		process = m.tryFastPath
+	} else {
+		// Step 1: Check for idempotency first before acquiring any locks
+		txn, err := m.checkIdempotency(ctx, transactionID)
+		if err == nil {
+			// Transaction exists, return it (idempotent response)
+			return txn, true, nil
+		} else if err != errs.ErrTransactionNotFound {
+			// Some other error occurred
+			return nil, false, err
+		}
+	}
+
+	// Attempts run one after another, so the last one decides whether the transaction was stored
+	stored := false
+	txn, err := m.retry(ctx, transactionID, func(ctx context.Context) (*entity.Transaction, error) {
+		txn, attemptStored, err := process(ctx, userID, transactionID, sourceType, state, amount, opts...)
+		stored = attemptStored
+		return txn, err
+	})
+	if err != nil {
+		return txn, false, err
 	}
+	return txn, stored, nil
+}
 
-	// Implement retry logic for potential concurrency issues
+// retry runs the attempt until it succeeds, fails with an error that is not retryable, or the retry policy gives up
+// Only attempts that failed without committing anything are retried, e.g. after losing the lock or a serialization failure
+func (m *TransactionManager) retry(
+	ctx context.Context,
+	transactionID string,
+	attempt func(ctx context.Context) (*entity.Transaction, error),
+) (*entity.Transaction, error) {
+	maxAttempts := m.retryPolicy.MaxAttempts()
 	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			// Log retry attempt
+	for n := 1; n <= maxAttempts; n++ {
+		if n > 1 {
+			backoff := m.retryPolicy.Backoff(n - 1)
 			m.logger.Info("Retrying transaction processing", map[string]any{
 				"transactionID": transactionID,
-				"attempt":       attempt + 1,
-				"maxAttempts":   maxRetries,
+				"attempt":       n,
+				"maxAttempts":   maxAttempts,
+				"backoff":       backoff.String(),
 				"error":         lastErr.Error(),
 			})
-			
-			// Apply exponential backoff
-			backoffTime := baseBackoff * time.Duration(1<<uint(attempt))
-			time.Sleep(backoffTime)
+
+			// Stop waiting as soon as the caller gives up
+			if err := wait(ctx, m.timeProvider, backoff); err != nil {
+				m.retryAborted.Add(1)
+				return nil, fmt.Errorf("retry abandoned after %d attempts: %w", n-1, lastErr)
+			}
+			m.retries.Add(1)
 		}
 
 		// Try to process the transaction
-		txn, err = m.tryProcessTransaction(ctx, userID, transactionID, sourceType, state, amount)
+		txn, err := attempt(ctx)
 		if err == nil {
-			// Success
 			return txn, nil
 		}
 
-		// Check if the error is retryable
-		if isRetryableError(err) {
-			lastErr = err
-			continue
+		// Business errors and failures that may have committed are returned as they are
+		if !m.retryPolicy.IsRetryable(err) {
+			return nil, err
 		}
-
-		// Non-retryable error, return immediately
-		return nil, err
+		lastErr = err
 	}
 
 	// All retries failed
+	m.retryExhausted.Add(1)
 	m.logger.Error("Failed to process transaction after retries", map[string]any{
 		"transactionID": transactionID,
-		"attempts":      maxRetries,
+		"attempts":      maxAttempts,
 		"error":         lastErr.Error(),
 	})
 	return nil, lastErr
 }
 
-// isRetryableError checks if an error can be retried
-func isRetryableError(err error) bool {
-	errStr := strings.ToLower(err.Error())
-	return strings.Contains(errStr, "deadlock") ||
-		strings.Contains(errStr, "serialization") ||
-		strings.Contains(errStr, "lock timeout") ||
-		strings.Contains(errStr, "could not serialize access") ||
-		strings.Contains(errStr, "concurrent update") ||
-		strings.Contains(errStr, "retry transaction") ||
-		strings.Contains(errStr, "serialization failure") ||
-		strings.Contains(errStr, "transaction rollback") ||
-		strings.Contains(errStr, "current transaction is aborted") ||
-		strings.Contains(errStr, "40001") // PostgreSQL serialization failure code
-}
-
 // tryProcessTransaction attempts to process a transaction with proper locking
 // This separates the retry logic from the transaction processing
 func (m *TransactionManager) tryProcessTransaction(
@@ -145,10 +243,32 @@ func (m *TransactionManager) tryProcessTransaction(
 	sourceType string,
 	state string,
 	amount string,
+	opts ...entity.TransactionOption,
+) (*entity.Transaction, bool, error) {
+	stored := false
+	txn, err := m.withUserLock(ctx, userID, transactionID, func(dbCtx context.Context) (*entity.Transaction, error) {
+		// Check for idempotency again under the lock, in case the ID was processed since the first check
+		existing, err := m.storedTransaction(dbCtx, transactionID)
+		if err != nil || existing != nil {
+			stored = existing != nil
+			return existing, err
+		}
+		return m.executeTransaction(dbCtx, userID, transactionID, sourceType, state, amount, opts...)
+	})
+	return txn, stored, err
+}
+
+// withUserLock runs work in a unit of work while holding the user's lock, and commits if it succeeds
+// The lease is renewed while the work runs and checked before committing, so a stale holder never commits.
+func (m *TransactionManager) withUserLock(
+	ctx context.Context,
+	userID uint64,
+	transactionID string,
+	work func(dbCtx context.Context) (*entity.Transaction, error),
 ) (*entity.Transaction, error) {
 	// Step 2: Acquire lock on user using database row lock
 	// This ensures no other instance can process transactions for this user concurrently
-	err := m.userLockRepo.AcquireLock(ctx, userID, m.lockTimeout)
+	lockToken, err := m.userLockRepo.AcquireLock(ctx, userID, m.lockTimeout)
 	if err != nil {
 		if err == errs.ErrUserLocked {
 			return nil, fmt.Errorf("user %d is locked by another process: %w", userID, err)
@@ -156,36 +276,149 @@ func (m *TransactionManager) tryProcessTransaction(
 		return nil, fmt.Errorf("failed to acquire lock for user %d: %w", userID, err)
 	}
 
+	// Keep the lease alive while we work; losing it cancels workCtx
+	workCtx, stopHeartbeat := m.startHeartbeat(ctx, userID, lockToken)
+
 	// Step 3: Begin a database transaction
-	dbCtx, err := m.unitOfWork.Begin(ctx)
+	dbCtx, err := m.unitOfWork.Begin(workCtx)
 	if err != nil {
 		// Release the lock if we couldn't start a transaction
-		_ = m.userLockRepo.ReleaseLock(ctx, userID)
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		stopHeartbeat()
+		_ = m.userLockRepo.ReleaseLock(context.WithoutCancel(ctx), userID, lockToken)
+		return nil, m.leaseError(workCtx, userID, fmt.Errorf("failed to begin transaction: %w", err))
 	}
 
 	// Ensure we always end the database transaction and release the lock
 	defer func() {
+		// Stop renewing first so a late renewal cannot race the release
+		stopHeartbeat()
 		// Rollback only has an effect if the transaction hasn't been committed
 		_ = m.unitOfWork.Rollback(dbCtx)
-		// Always release the lock
-		_ = m.userLockRepo.ReleaseLock(ctx, userID)
+		// Always release the lock, even when the request was cancelled; this is a no-op if it has been taken over meanwhile
+		_ = m.userLockRepo.ReleaseLock(context.WithoutCancel(ctx), userID, lockToken)
 	}()
 
 	// Try to process the transaction
-	result, err := m.executeTransaction(dbCtx, userID, transactionID, sourceType, state, amount)
+	result, err := work(dbCtx)
 	if err != nil {
-		return nil, err
+		return nil, m.leaseError(workCtx, userID, err)
+	}
+
+	// Make sure the lock was not lost while we worked; a stale holder must not commit
+	if err := m.userLockRepo.VerifyLock(dbCtx, userID, lockToken); err != nil {
+		m.logger.Warn("Aborting transaction, user lock no longer held", map[string]any{
+			"user_id":       userID,
+			"transactionID": transactionID,
+			"error":         err.Error(),
+		})
+		return nil, m.leaseError(workCtx, userID, fmt.Errorf("user %d lock lost before commit: %w", userID, err))
 	}
 
 	// Commit the database transaction
 	if err := m.unitOfWork.Commit(dbCtx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, m.leaseError(workCtx, userID, fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
 	return result, nil
 }
 
+// tryFastPath applies the transaction through the atomic repository
+// No user lock is taken: the conditional update's row lock orders concurrent transactions
+func (m *TransactionManager) tryFastPath(
+	ctx context.Context,
+	userID uint64,
+	transactionID string,
+	sourceType string,
+	state string,
+	amount string,
+	opts ...entity.TransactionOption,
+) (*entity.Transaction, bool, error) {
+	txn, err := entity.NewTransaction(userID, transactionID, sourceType, state, amount, m.timeProvider, opts...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := m.limitPolicy.CheckAmount(txn); err != nil {
+		return nil, false, err
+	}
+	return m.fastPath.ApplyTransaction(ctx, txn)
+}
+
+// startHeartbeat renews the user's lock in the background until the returned stop function is called
+// If the lease cannot be kept, the returned context is cancelled with ErrLockLost as its cause
+// so the work holding it stops instead of running on without mutual exclusion.
+func (m *TransactionManager) startHeartbeat(ctx context.Context, userID uint64, token persistence.LockToken) (context.Context, func()) {
+	workCtx, cancel := context.WithCancelCause(ctx)
+
+	interval := m.heartbeatInterval
+	if interval <= 0 {
+		interval = m.lockTimeout / 3
+	}
+	if interval <= 0 {
+		return workCtx, func() { cancel(nil) }
+	}
+
+	// Ends with the work, or when the work is cancelled
+	beatCtx, stop := context.WithCancel(workCtx)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		lastRenewed := m.timeProvider.Now()
+
+		for {
+			if err := wait(beatCtx, m.timeProvider, interval); err != nil {
+				return
+			}
+
+			err := m.userLockRepo.RenewLock(workCtx, userID, token, m.lockTimeout)
+			if beatCtx.Err() != nil {
+				// Work finished while renewing; the outcome no longer matters
+				return
+			}
+
+			if err == nil {
+				m.leaseExtensions.Add(1)
+				lastRenewed = m.timeProvider.Now()
+				continue
+			}
+			m.renewalFailures.Add(1)
+
+			// A transient failure is tolerated while the lease would outlast the next attempt
+			expiring := m.timeProvider.Since(lastRenewed).Std()+interval >= m.lockTimeout
+			if !errs.IsLockLostError(err) && !expiring {
+				m.logger.Warn("Failed to renew user lock, will retry", map[string]any{
+					"user_id": userID,
+					"error":   err.Error(),
+				})
+				continue
+			}
+
+			m.lostLeases.Add(1)
+			m.logger.Error("User lock lease lost, cancelling work", map[string]any{
+				"user_id": userID,
+				"error":   err.Error(),
+			})
+			cancel(errs.ErrLockLost)
+			return
+		}
+	}()
+
+	return workCtx, func() {
+		stop()
+		cancel(nil)
+		<-stopped
+	}
+}
+
+// leaseError reports a lost lease instead of the error it caused in the cancelled work
+func (m *TransactionManager) leaseError(workCtx context.Context, userID uint64, err error) error {
+	if errs.IsLockLostError(context.Cause(workCtx)) && !errs.IsLockLostError(err) {
+		return fmt.Errorf("user %d lock lease lost during processing: %w (%s)", userID, errs.ErrLockLost, err.Error())
+	}
+	return err
+}
+
 // checkIdempotency checks if the transaction already exists
 // This is separate so we don't have to acquire a lock for duplicate transactions
 func (m *TransactionManager) checkIdempotency(ctx context.Context, transactionID string) (*entity.Transaction, error) {
@@ -193,7 +426,20 @@ func (m *TransactionManager) checkIdempotency(ctx context.Context, transactionID
 	return txnRepo.GetByTransactionID(ctx, transactionID)
 }
 
-// executeTransaction performs the actual transaction processing
+// storedTransaction returns the transaction stored under the ID within the unit of work, or nil if there is none
+func (m *TransactionManager) storedTransaction(ctx context.Context, transactionID string) (*entity.Transaction, error) {
+	txnRepo := m.unitOfWork.GetTransactionRepository(ctx)
+	exists, err := txnRepo.TransactionExists(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if transaction exists: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	return txnRepo.GetByTransactionID(ctx, transactionID)
+}
+
+// executeTransaction performs the actual transaction processing of a transaction ID that is not stored yet
 func (m *TransactionManager) executeTransaction(
 	ctx context.Context,
 	userID uint64,
@@ -201,21 +447,12 @@ func (m *TransactionManager) executeTransaction(
 	sourceType string,
 	state string,
 	amount string,
+	opts ...entity.TransactionOption,
 ) (*entity.Transaction, error) {
 	// Get the repositories
 	userRepo := m.unitOfWork.GetUserRepository(ctx)
 	txnRepo := m.unitOfWork.GetTransactionRepository(ctx)
 
-	// Check for idempotency again within the transaction (double-check)
-	exists, err := txnRepo.TransactionExists(ctx, transactionID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check if transaction exists: %w", err)
-	}
-	if exists {
-		// Transaction already exists, return it
-		return txnRepo.GetByTransactionID(ctx, transactionID)
-	}
-
 	// Create the transaction entity
 	txn, err := entity.NewTransaction(
 		userID,
@@ -224,10 +461,14 @@ func (m *TransactionManager) executeTransaction(
 		state,
 		amount,
 		m.timeProvider,
+		opts...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
+	if err := m.limitPolicy.CheckAmount(txn); err != nil {
+		return nil, err
+	}
 
 	// Get the user
 	user, err := userRepo.GetByID(ctx, userID)
@@ -235,23 +476,29 @@ func (m *TransactionManager) executeTransaction(
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// Large adjustments and withdrawals wait for a second person before they touch the balance
+	if m.approvalPolicy.Requires(txn) {
+		return m.holdForApproval(ctx, txn)
+	}
+
 	// Process the transaction based on its state
 	switch txn.State {
 	case entity.StateWin:
-		// Win transaction increases balance
-		user.ApplyWinTransaction(txn.AmountInCents, m.timeProvider)
+		// Win transaction increases balance, unless that would exceed the maximum balance
+		if err := m.limitPolicy.CheckBalance(txn, user.Balance()); err != nil {
+			m.recordFailure(ctx, txnRepo, txn, balanceLimitMessage)
+			return txn, err
+		}
+		// or the balance could not hold the result
+		if err := user.ApplyWinTransaction(txn.Amount, m.timeProvider); err != nil {
+			m.recordFailure(ctx, txnRepo, txn, balanceOverflowMessage)
+			return txn, err
+		}
 
 	case entity.StateLose:
 		// Lose transaction decreases balance
-		if err := user.ApplyLoseTransaction(txn.AmountInCents, m.timeProvider); err != nil {
-			// Mark the transaction as failed and save it
-			txn.MarkAsFailed(m.timeProvider, "Insufficient balance")
-			if saveErr := txnRepo.Create(ctx, txn); saveErr != nil {
-				m.logger.Error("Failed to save failed transaction", map[string]any{
-					"error":         saveErr,
-					"transactionID": transactionID,
-				})
-			}
+		if err := user.ApplyLoseTransaction(txn.Amount, m.timeProvider); err != nil {
+			m.recordFailure(ctx, txnRepo, txn, "Insufficient balance")
 			return txn, err
 		}
 
@@ -275,11 +522,25 @@ func (m *TransactionManager) executeTransaction(
 	return txn, nil
 }
 
+// recordFailure marks the transaction as failed and saves it, so retries of its ID report the failure
+// A failure to save is only logged; the caller still returns the error that failed the transaction.
+func (m *TransactionManager) recordFailure(
+	ctx context.Context,
+	txnRepo persistence.TransactionRepository,
+	txn *entity.Transaction,
+	message string,
+) {
+	txn.MarkAsFailed(m.timeProvider, message)
+	if err := txnRepo.Create(ctx, txn); err != nil {
+		m.logger.Error("Failed to save failed transaction", map[string]any{
+			"error":         err,
+			"transactionID": txn.TransactionID,
+		})
+	}
+}
+
 // Shutdown gracefully shuts down the TransactionManager
 func (m *TransactionManager) Shutdown() {
 	m.logger.Info("Shutting down TransactionManager", nil)
-	m.shutdown = true
-
-	// Add any additional cleanup here if needed in the future
-	// For example, waiting for pending transactions, closing connections, etc.
+	m.shutdown.Store(true)
 }
@@ -0,0 +1,458 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// transactionManagerFixture wires a TransactionManager to mocked ports for a single user
+// Transactions and approval events are kept in memory, so a stored transaction is found again.
+type transactionManagerFixture struct {
+	manager      *TransactionManager
+	timeProvider *coremocks.MockTimeProvider
+	user         *entity.User
+	uow          *persistencemocks.MockUnitOfWork
+	lockRepo     *persistencemocks.MockUserLockRepository
+	userRepo     *persistencemocks.MockUserRepository
+	txnRepo      *persistencemocks.MockTransactionRepository
+	auditRepo    *persistencemocks.MockApprovalAuditRepository
+
+	// slowWork, when set, runs while the transaction is in flight, before the user is loaded
+	slowWork func(ctx context.Context) error
+
+	mu  sync.Mutex
+	now time.Time
+	// instantTimeouts makes every timeout elapse at once, moving the clock forward by its duration
+	instantTimeouts bool
+	transactions    map[string]*entity.Transaction
+	events          []*entity.ApprovalEvent
+}
+
+func newTransactionManagerFixture(t *testing.T, userID uint64) *transactionManagerFixture {
+	f := &transactionManagerFixture{
+		uow:          persistencemocks.NewMockUnitOfWork(t),
+		lockRepo:     persistencemocks.NewMockUserLockRepository(t),
+		userRepo:     persistencemocks.NewMockUserRepository(t),
+		txnRepo:      persistencemocks.NewMockTransactionRepository(t),
+		auditRepo:    persistencemocks.NewMockApprovalAuditRepository(t),
+		now:          time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		transactions: map[string]*entity.Transaction{},
+	}
+
+	timeProvider := coremocks.NewMockTimeProvider(t)
+	f.timeProvider = timeProvider
+	timeProvider.EXPECT().Now().RunAndReturn(f.clock).Maybe()
+	timeProvider.EXPECT().Since(mock.Anything).RunAndReturn(func(since time.Time) coreport.Duration {
+		return coreport.Duration(f.clock().Sub(since))
+	}).Maybe()
+	timeProvider.EXPECT().WithTimeout(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, d coreport.Duration) (context.Context, context.CancelFunc) {
+		f.mu.Lock()
+		instant := f.instantTimeouts
+		f.mu.Unlock()
+		if !instant {
+			return context.WithTimeout(ctx, d.Std())
+		}
+		f.advance(d.Std())
+		timeoutCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		return timeoutCtx, cancel
+	}).Maybe()
+	f.manager = NewTransactionManager(f.uow, f.lockRepo, timeProvider, newTestLogger(t))
+
+	user, err := entity.NewUser(userID, "100.00", timeProvider)
+	require.NoError(t, err)
+	f.user = user
+
+	f.uow.EXPECT().GetTransactionRepository(mock.Anything).Return(f.txnRepo).Maybe()
+	f.uow.EXPECT().GetUserRepository(mock.Anything).Return(f.userRepo).Maybe()
+	f.uow.EXPECT().GetApprovalAuditRepository(mock.Anything).Return(f.auditRepo).Maybe()
+	f.uow.EXPECT().Begin(mock.Anything).RunAndReturn(func(ctx context.Context) (context.Context, error) {
+		return ctx, nil
+	}).Maybe()
+	f.uow.EXPECT().Rollback(mock.Anything).Return(nil).Maybe()
+	f.txnRepo.EXPECT().GetByTransactionID(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, id string) (*entity.Transaction, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if txn, ok := f.transactions[id]; ok {
+			return txn.Clone(), nil
+		}
+		return nil, errs.ErrTransactionNotFound
+	}).Maybe()
+	f.txnRepo.EXPECT().TransactionExists(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, id string) (bool, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_, ok := f.transactions[id]
+		return ok, nil
+	}).Maybe()
+	store := func(_ context.Context, txn *entity.Transaction) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.transactions[txn.TransactionID] = txn.Clone()
+		return nil
+	}
+	f.txnRepo.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(store).Maybe()
+	f.txnRepo.EXPECT().Update(mock.Anything, mock.Anything).RunAndReturn(store).Maybe()
+	f.txnRepo.EXPECT().ListByStatus(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, status entity.TransactionStatus, _ int) ([]*entity.Transaction, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var found []*entity.Transaction
+		for _, txn := range f.transactions {
+			if txn.Status == status {
+				found = append(found, txn.Clone())
+			}
+		}
+		return found, nil
+	}).Maybe()
+	f.auditRepo.EXPECT().Record(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, event *entity.ApprovalEvent) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.events = append(f.events, event)
+		return nil
+	}).Maybe()
+	f.userRepo.EXPECT().GetByID(mock.Anything, userID).RunAndReturn(func(ctx context.Context, _ uint64) (*entity.User, error) {
+		if f.slowWork != nil {
+			if err := f.slowWork(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}).Maybe()
+	f.userRepo.EXPECT().Update(mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	return f
+}
+
+// clock returns the fixture's current time
+func (f *transactionManagerFixture) clock() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// advance moves the fixture's clock forward
+func (f *transactionManagerFixture) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// allowLocking lets every operation take the user's lock and commit, for tests that do not count them
+func (f *transactionManagerFixture) allowLocking() {
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, f.user.ID, mock.Anything).Return(persistence.LockToken("token"), nil).Maybe()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, f.user.ID, mock.Anything).Return(nil).Maybe()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, f.user.ID, mock.Anything).Return(nil).Maybe()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Maybe()
+}
+
+func TestTransactionManager_ReleasesWithAcquiredToken(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token-1"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), persistence.LockToken("token-1")).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), persistence.LockToken("token-1")).Return(nil).Once()
+
+	txn, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusCompleted, txn.Status)
+}
+
+func TestTransactionManager_StaleHolderDoesNotCommit(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+
+	// The first lock is lost before commit; the retry runs under a fresh lock
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("stale"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), persistence.LockToken("stale")).Return(errs.ErrLockLost).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), persistence.LockToken("stale")).Return(nil).Once()
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("fresh"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), persistence.LockToken("fresh")).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), persistence.LockToken("fresh")).Return(nil).Once()
+
+	// Only the attempt that still held its lock commits
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+}
+
+func TestTransactionManager_HeartbeatExtendsLease(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.instantTimeouts = true
+	f.manager.WithLockTimeout(60 * time.Millisecond).WithHeartbeatInterval(10 * time.Millisecond)
+	start := f.clock()
+
+	// The work outlives the lock timeout: it ends at the sixth renewal
+	outlived := make(chan struct{})
+	f.slowWork = func(ctx context.Context) error {
+		select {
+		case <-outlived:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	token := persistence.LockToken("token-1")
+	renewals := 0
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), 60*time.Millisecond).Return(token, nil).Once()
+	f.lockRepo.EXPECT().RenewLock(mock.Anything, uint64(1), token, 60*time.Millisecond).RunAndReturn(func(ctx context.Context, _ uint64, _ persistence.LockToken, _ time.Duration) error {
+		if renewals++; renewals == 6 {
+			close(outlived)
+			<-ctx.Done()
+		}
+		return nil
+	})
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), token).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), token).Return(nil).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+
+	assert.Equal(t, 60*time.Millisecond, f.clock().Sub(start))
+	assert.Equal(t, LeaseMetrics{Extensions: 5}, f.manager.LeaseMetrics())
+}
+
+func TestTransactionManager_HeartbeatToleratesTransientRenewalFailure(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.instantTimeouts = true
+	f.manager.WithLockTimeout(60 * time.Millisecond).WithHeartbeatInterval(20 * time.Millisecond)
+
+	renewedAgain := make(chan struct{})
+	f.slowWork = func(ctx context.Context) error {
+		select {
+		case <-renewedAgain:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// The first renewal fails with 40ms of the lease left, which outlasts the next attempt
+	renewals := 0
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().RenewLock(mock.Anything, uint64(1), mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, _ uint64, _ persistence.LockToken, _ time.Duration) error {
+		switch renewals++; renewals {
+		case 1:
+			return errs.ErrDatabaseConnection
+		case 2:
+			return nil
+		}
+		close(renewedAgain)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.Equal(t, LeaseMetrics{Extensions: 1, RenewalFailures: 1}, f.manager.LeaseMetrics())
+}
+
+func TestTransactionManager_HeartbeatGivesUpBeforeTheLeaseExpires(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.instantTimeouts = true
+	f.manager.WithLockTimeout(60 * time.Millisecond).WithHeartbeatInterval(20 * time.Millisecond)
+	f.manager.WithRetryPolicy(RetryPolicy{MaxRetries: 0})
+
+	// The work only stops when its context is cancelled
+	f.slowWork = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// The second failure leaves 20ms of the lease, which would run out before the next attempt
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().RenewLock(mock.Anything, uint64(1), mock.Anything, mock.Anything).Return(errs.ErrDatabaseConnection).Times(2)
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	assert.ErrorIs(t, err, errs.ErrLockLost)
+	f.uow.AssertNotCalled(t, "Commit", mock.Anything)
+	assert.Equal(t, LeaseMetrics{RenewalFailures: 2, LostLeases: 1}, f.manager.LeaseMetrics())
+}
+
+func TestTransactionManager_LostLeaseCancelsWork(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.instantTimeouts = true
+	f.manager.WithLockTimeout(60 * time.Millisecond).WithHeartbeatInterval(10 * time.Millisecond)
+
+	// The work only stops when its context is cancelled
+	f.slowWork = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// Every attempt loses its lease at the first renewal
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil)
+	f.lockRepo.EXPECT().RenewLock(mock.Anything, uint64(1), mock.Anything, mock.Anything).Return(errs.ErrLockLost)
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil)
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	assert.ErrorIs(t, err, errs.ErrLockLost)
+
+	// Nothing is committed and every attempt's lease loss is counted
+	f.uow.AssertNotCalled(t, "Commit", mock.Anything)
+	metrics := f.manager.LeaseMetrics()
+	assert.Equal(t, int64(f.manager.RetryPolicy().MaxAttempts()), metrics.LostLeases)
+	assert.Equal(t, metrics.LostLeases, metrics.RenewalFailures)
+}
+
+func TestTransactionManager_FastPathSkipsLockAndLookup(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	fastPath := persistencemocks.NewMockAtomicTransactionRepository(t)
+	f.manager.WithFastPath(fastPath)
+
+	// No lock is taken: the lock repository mock fails the test on any call
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.MatchedBy(func(txn *entity.Transaction) bool {
+		return txn.TransactionID == "tx-1" && txn.Amount == entity.MoneyFromCents(1000) && txn.State == entity.StateWin
+	})).RunAndReturn(func(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+		processed := txn.Clone()
+		processed.Status = entity.StatusCompleted
+		processed.ResultBalance = entity.MoneyFromCents(11000)
+		return processed, false, nil
+	}).Once()
+
+	txn, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.Equal(t, "110.00", txn.GetResultBalance())
+
+	f.txnRepo.AssertNotCalled(t, "GetByTransactionID", mock.Anything, mock.Anything)
+	f.uow.AssertNotCalled(t, "Begin", mock.Anything)
+}
+
+func TestTransactionManager_FastPathDoesNotRetryBusinessErrors(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	fastPath := persistencemocks.NewMockAtomicTransactionRepository(t)
+	f.manager.WithFastPath(fastPath)
+
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.Anything).Return(nil, false, errs.ErrInsufficientBalance).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "lose", "500.00")
+	assert.ErrorIs(t, err, errs.ErrInsufficientBalance)
+}
+
+func TestTransactionManager_ReportsStoredTransactions(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.allowLocking()
+
+	txn, stored, err := f.manager.processTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.False(t, stored)
+	assert.Equal(t, "110.00", txn.GetResultBalance())
+
+	// A resend is answered from the store, whatever its clock says
+	f.advance(-time.Hour)
+	txn, stored, err = f.manager.processTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.True(t, stored)
+	assert.Equal(t, "110.00", txn.GetResultBalance())
+	assert.Equal(t, "110.00", f.user.GetBalance())
+
+	// The fast path reports what its repository found
+	fastPath := persistencemocks.NewMockAtomicTransactionRepository(t)
+	f.manager.WithFastPath(fastPath)
+	fastPath.EXPECT().ApplyTransaction(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+		return txn, true, nil
+	}).Once()
+	_, stored, err = f.manager.processTransaction(context.Background(), 1, "tx-2", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.True(t, stored)
+}
+
+func TestTransactionManager_WinThatOverflowsFails(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+
+	// 100.00 plus the largest amount a request can carry does not fit in the balance
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "92233720368547758.07")
+	assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+	f.txnRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(txn *entity.Transaction) bool {
+		return txn.Status == entity.StatusFailed && txn.ErrorMessage == balanceOverflowMessage
+	}))
+	f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	assert.Zero(t, f.manager.RetryMetrics().Retries)
+}
+
+func TestTransactionManager_RetriesConcurrencyConflicts(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.manager.WithRetryPolicy(RetryPolicy{MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil)
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil)
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil)
+
+	// The first commit loses a serialization race; the retry goes through
+	conflict := fmt.Errorf("%w: could not serialize access", errs.ErrConcurrencyConflict)
+	f.uow.EXPECT().Commit(mock.Anything).Return(conflict).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(nil).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	require.NoError(t, err)
+	assert.Equal(t, RetryMetrics{Retries: 1}, f.manager.RetryMetrics())
+}
+
+func TestTransactionManager_RetriesStopAtPolicyLimit(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.manager.WithRetryPolicy(RetryPolicy{MaxRetries: 2})
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Times(3)
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Times(3)
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Times(3)
+	f.uow.EXPECT().Commit(mock.Anything).Return(errs.ErrConcurrencyConflict).Times(3)
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	assert.ErrorIs(t, err, errs.ErrConcurrencyConflict)
+	assert.Equal(t, RetryMetrics{Retries: 2, Exhausted: 1}, f.manager.RetryMetrics())
+}
+
+func TestTransactionManager_DoesNotRetryDatabaseErrors(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+
+	// A failed commit that is not a known conflict may have been applied, so it is not retried
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(errs.ErrDatabaseConnection).Once()
+
+	_, err := f.manager.ProcessTransaction(context.Background(), 1, "tx-1", "game", "win", "10.00")
+	assert.ErrorIs(t, err, errs.ErrDatabaseConnection)
+	assert.Zero(t, f.manager.RetryMetrics().Retries)
+}
+
+func TestTransactionManager_RetryBackoffStopsWithContext(t *testing.T) {
+	f := newTransactionManagerFixture(t, 1)
+	f.manager.WithRetryPolicy(RetryPolicy{MaxRetries: 3, BaseBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	f.lockRepo.EXPECT().AcquireLock(mock.Anything, uint64(1), mock.Anything).Return(persistence.LockToken("token"), nil).Once()
+	f.lockRepo.EXPECT().VerifyLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.lockRepo.EXPECT().ReleaseLock(mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+	f.uow.EXPECT().Commit(mock.Anything).Return(errs.ErrConcurrencyConflict).Once()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "win", "10.00")
+	assert.ErrorIs(t, err, errs.ErrConcurrencyConflict)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, RetryMetrics{Aborted: 1}, f.manager.RetryMetrics())
+}
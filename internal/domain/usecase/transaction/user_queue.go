@@ -0,0 +1,283 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// Defaults for the per-user queue executor
+const (
+	DefaultQueueWorkers      = 64
+	DefaultQueueDepthPerUser = 100
+)
+
+// UserWork is a unit of work executed on behalf of a single user
+type UserWork func(ctx context.Context) (*entity.Transaction, error)
+
+// QueueMetrics is a snapshot of the executor's queue state
+type QueueMetrics struct {
+	Workers      int   // Number of worker goroutines (shards)
+	QueuedJobs   int   // Jobs waiting or running across all shards
+	MaxUserDepth int   // Deepest single-user queue at the time of the snapshot
+	ShardDepths  []int // Jobs waiting or running per shard
+	Submitted    int64 // Jobs accepted since start
+	Completed    int64 // Jobs that ran to completion (successfully or not)
+	Rejected     int64 // Jobs refused because the user's queue was full or the executor was stopped
+	Cancelled    int64 // Jobs whose context ended before they ran
+}
+
+// UserQueueExecutor serialises work per user inside a single process
+// Users are sharded across a fixed set of worker goroutines by user ID, so all work
+// for one user runs on the same worker in submission order. This keeps requests for a
+// hot user from racing for the database lock; the database lock is still taken by the
+// work itself and remains the guard across instances.
+type UserQueueExecutor struct {
+	shards       []*queueShard
+	maxUserDepth int
+	logger       coreport.Logger
+
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+
+	submitted atomic.Int64
+	completed atomic.Int64
+	rejected  atomic.Int64
+	cancelled atomic.Int64
+}
+
+// queueShard is a single worker goroutine and the queues of the users assigned to it
+type queueShard struct {
+	jobs   chan *queuedJob
+	mu     sync.Mutex
+	depths map[uint64]int
+}
+
+// queuedJob is a unit of work waiting for its shard's worker
+type queuedJob struct {
+	ctx    context.Context
+	userID uint64
+	work   UserWork
+	result chan queuedResult
+}
+
+// queuedResult carries the outcome of a job back to the submitter
+type queuedResult struct {
+	txn *entity.Transaction
+	err error
+}
+
+// NewUserQueueExecutor creates an executor with the given number of workers and
+// maximum number of pending jobs per user, and starts its workers
+func NewUserQueueExecutor(workers, maxDepthPerUser int, logger coreport.Logger) *UserQueueExecutor {
+	if workers <= 0 {
+		workers = DefaultQueueWorkers
+	}
+	if maxDepthPerUser <= 0 {
+		maxDepthPerUser = DefaultQueueDepthPerUser
+	}
+
+	e := &UserQueueExecutor{
+		shards:       make([]*queueShard, workers),
+		maxUserDepth: maxDepthPerUser,
+		logger:       logger,
+	}
+
+	for i := range e.shards {
+		shard := &queueShard{
+			// A shard can hold a full queue for several users before submitters block
+			jobs:   make(chan *queuedJob, maxDepthPerUser*4),
+			depths: make(map[uint64]int),
+		}
+		e.shards[i] = shard
+
+		e.wg.Add(1)
+		go e.runShard(shard)
+	}
+
+	return e
+}
+
+// Submit queues work for a user and waits for its result
+// Work for the same user runs strictly one at a time, in submission order.
+// If ctx ends before the work starts, the work is skipped and ctx.Err() is returned;
+// once started, the work receives ctx and is responsible for honouring it.
+//
+// Possible errors:
+// - ErrUserQueueFull: If the user already has the maximum number of pending jobs
+// - ErrExecutorStopped: If the executor is shutting down
+func (e *UserQueueExecutor) Submit(ctx context.Context, userID uint64, work UserWork) (*entity.Transaction, error) {
+	job := &queuedJob{
+		ctx:    ctx,
+		userID: userID,
+		work:   work,
+		result: make(chan queuedResult, 1),
+	}
+
+	if err := e.enqueue(job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-job.result:
+		return res.txn, res.err
+	case <-ctx.Done():
+		// The worker skips the job if it has not started yet
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue reserves a slot in the user's queue and hands the job to its shard
+func (e *UserQueueExecutor) enqueue(job *queuedJob) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.stopped {
+		e.rejected.Add(1)
+		return errs.ErrExecutorStopped
+	}
+
+	shard := e.shardFor(job.userID)
+
+	shard.mu.Lock()
+	if shard.depths[job.userID] >= e.maxUserDepth {
+		shard.mu.Unlock()
+		e.rejected.Add(1)
+		e.logger.Warn("User queue is full", map[string]any{
+			"user_id":   job.userID,
+			"max_depth": e.maxUserDepth,
+		})
+		return fmt.Errorf("%w: user %d has %d pending transactions", errs.ErrUserQueueFull, job.userID, e.maxUserDepth)
+	}
+	shard.depths[job.userID]++
+	shard.mu.Unlock()
+
+	select {
+	case shard.jobs <- job:
+		e.submitted.Add(1)
+		return nil
+	case <-job.ctx.Done():
+		shard.release(job.userID)
+		e.cancelled.Add(1)
+		return job.ctx.Err()
+	}
+}
+
+// runShard executes the shard's jobs one at a time until the executor is stopped
+func (e *UserQueueExecutor) runShard(shard *queueShard) {
+	defer e.wg.Done()
+
+	for job := range shard.jobs {
+		e.runJob(shard, job)
+	}
+}
+
+// runJob executes a single job, skipping it if its submitter has already gone away
+func (e *UserQueueExecutor) runJob(shard *queueShard, job *queuedJob) {
+	defer shard.release(job.userID)
+
+	if err := job.ctx.Err(); err != nil {
+		e.cancelled.Add(1)
+		job.result <- queuedResult{err: err}
+		return
+	}
+
+	txn, err := e.safeRun(job)
+	e.completed.Add(1)
+	job.result <- queuedResult{txn: txn, err: err}
+}
+
+// safeRun executes the job's work, converting a panic into an error so the worker survives
+func (e *UserQueueExecutor) safeRun(job *queuedJob) (txn *entity.Transaction, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error("Panic recovered in user queue worker", map[string]any{
+				"user_id": job.userID,
+				"panic":   fmt.Sprintf("%v", r),
+			})
+			txn, err = nil, fmt.Errorf("%w: panic while processing transaction", errs.ErrInternalServer)
+		}
+	}()
+	return job.work(job.ctx)
+}
+
+// shardFor returns the shard that owns the given user
+func (e *UserQueueExecutor) shardFor(userID uint64) *queueShard {
+	return e.shards[userID%uint64(len(e.shards))]
+}
+
+// release frees the user's queue slot once a job has finished or been abandoned
+func (s *queueShard) release(userID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.depths[userID] <= 1 {
+		delete(s.depths, userID)
+		return
+	}
+	s.depths[userID]--
+}
+
+// QueueDepth returns the number of pending jobs for a user, including a running one
+func (e *UserQueueExecutor) QueueDepth(userID uint64) int {
+	shard := e.shardFor(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return shard.depths[userID]
+}
+
+// Metrics returns a snapshot of the executor's queue state
+func (e *UserQueueExecutor) Metrics() QueueMetrics {
+	metrics := QueueMetrics{
+		Workers:     len(e.shards),
+		ShardDepths: make([]int, len(e.shards)),
+		Submitted:   e.submitted.Load(),
+		Completed:   e.completed.Load(),
+		Rejected:    e.rejected.Load(),
+		Cancelled:   e.cancelled.Load(),
+	}
+
+	for i, shard := range e.shards {
+		shard.mu.Lock()
+		for _, depth := range shard.depths {
+			metrics.ShardDepths[i] += depth
+			if depth > metrics.MaxUserDepth {
+				metrics.MaxUserDepth = depth
+			}
+		}
+		shard.mu.Unlock()
+		metrics.QueuedJobs += metrics.ShardDepths[i]
+	}
+
+	return metrics
+}
+
+// Stop stops accepting new work and waits for queued jobs to finish
+func (e *UserQueueExecutor) Stop() {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return
+	}
+	e.stopped = true
+	for _, shard := range e.shards {
+		close(shard.jobs)
+	}
+	e.mu.Unlock()
+
+	e.wg.Wait()
+
+	metrics := e.Metrics()
+	e.logger.Info("User queue executor stopped", map[string]any{
+		"submitted": metrics.Submitted,
+		"completed": metrics.Completed,
+		"rejected":  metrics.Rejected,
+		"cancelled": metrics.Cancelled,
+	})
+}
@@ -0,0 +1,200 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(t *testing.T) *coremocks.MockLogger {
+	logger := coremocks.NewMockLogger(t)
+	logger.EXPECT().Debug(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Info(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Warn(mock.Anything, mock.Anything).Maybe()
+	logger.EXPECT().Error(mock.Anything, mock.Anything).Maybe()
+	return logger
+}
+
+func TestUserQueueExecutor_SerialisesPerUser(t *testing.T) {
+	executor := NewUserQueueExecutor(4, 100, newTestLogger(t))
+	defer executor.Stop()
+
+	var running, maxRunning atomic.Int32
+	var order []int
+	var orderMu sync.Mutex
+
+	work := func(i int) UserWork {
+		return func(ctx context.Context) (*entity.Transaction, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			orderMu.Lock()
+			order = append(order, i)
+			orderMu.Unlock()
+			time.Sleep(time.Millisecond)
+			return nil, nil
+		}
+	}
+
+	// Submit sequentially-ordered jobs from separate goroutines, each after the previous is queued
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := executor.Submit(context.Background(), 7, work(i))
+			assert.NoError(t, err)
+		}(i)
+		require.Eventually(t, func() bool {
+			return executor.Metrics().Submitted == int64(i+1)
+		}, time.Second, time.Millisecond)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), maxRunning.Load())
+	for i, got := range order {
+		assert.Equal(t, i, got)
+	}
+	assert.Equal(t, 0, executor.QueueDepth(7))
+}
+
+func TestUserQueueExecutor_QueueFull(t *testing.T) {
+	executor := NewUserQueueExecutor(1, 2, newTestLogger(t))
+	defer executor.Stop()
+
+	release := make(chan struct{})
+	blocking := func(ctx context.Context) (*entity.Transaction, error) {
+		<-release
+		return nil, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = executor.Submit(context.Background(), 1, blocking)
+		}()
+	}
+	require.Eventually(t, func() bool { return executor.QueueDepth(1) == 2 }, time.Second, time.Millisecond)
+
+	_, err := executor.Submit(context.Background(), 1, blocking)
+	assert.True(t, errs.IsUserQueueFullError(err))
+	assert.Equal(t, 4290, errs.ErrorCode(err))
+
+	// Other users on the same shard are not affected by the limit
+	done := make(chan error, 1)
+	go func() {
+		_, err := executor.Submit(context.Background(), 2, blocking)
+		done <- err
+	}()
+
+	close(release)
+	wg.Wait()
+	assert.NoError(t, <-done)
+	assert.Equal(t, int64(1), executor.Metrics().Rejected)
+}
+
+func TestUserQueueExecutor_SkipsCancelledWork(t *testing.T) {
+	executor := NewUserQueueExecutor(1, 10, newTestLogger(t))
+	defer executor.Stop()
+
+	release := make(chan struct{})
+	go func() {
+		_, _ = executor.Submit(context.Background(), 1, func(ctx context.Context) (*entity.Transaction, error) {
+			<-release
+			return nil, nil
+		})
+	}()
+	require.Eventually(t, func() bool { return executor.QueueDepth(1) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran atomic.Bool
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := executor.Submit(ctx, 1, func(ctx context.Context) (*entity.Transaction, error) {
+			ran.Store(true)
+			return nil, nil
+		})
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool { return executor.QueueDepth(1) == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+
+	close(release)
+	require.Eventually(t, func() bool { return executor.QueueDepth(1) == 0 }, time.Second, time.Millisecond)
+	assert.False(t, ran.Load())
+	assert.Equal(t, int64(1), executor.Metrics().Cancelled)
+}
+
+func TestUserQueueExecutor_RecoversPanics(t *testing.T) {
+	executor := NewUserQueueExecutor(1, 10, newTestLogger(t))
+	defer executor.Stop()
+
+	_, err := executor.Submit(context.Background(), 1, func(ctx context.Context) (*entity.Transaction, error) {
+		panic("boom")
+	})
+	assert.ErrorIs(t, err, errs.ErrInternalServer)
+
+	// The worker keeps serving after a panic
+	want := &entity.Transaction{TransactionID: "tx-1"}
+	got, err := executor.Submit(context.Background(), 1, func(ctx context.Context) (*entity.Transaction, error) {
+		return want, nil
+	})
+	assert.NoError(t, err)
+	assert.Same(t, want, got)
+}
+
+func TestUserQueueExecutor_Stop(t *testing.T) {
+	executor := NewUserQueueExecutor(2, 10, newTestLogger(t))
+
+	var finished atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := executor.Submit(context.Background(), 3, func(ctx context.Context) (*entity.Transaction, error) {
+				time.Sleep(2 * time.Millisecond)
+				finished.Add(1)
+				return nil, nil
+			})
+			if err != nil {
+				assert.True(t, errors.Is(err, errs.ErrExecutorStopped))
+			}
+		}()
+	}
+	require.Eventually(t, func() bool { return executor.Metrics().Submitted >= 1 }, time.Second, time.Millisecond)
+
+	executor.Stop()
+	wg.Wait()
+
+	// Everything accepted before Stop ran to completion
+	assert.Equal(t, executor.Metrics().Submitted, int64(finished.Load()))
+
+	_, err := executor.Submit(context.Background(), 3, func(ctx context.Context) (*entity.Transaction, error) {
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, errs.ErrExecutorStopped)
+	assert.Equal(t, 5030, errs.ErrorCode(err))
+
+	// Stopping twice is a no-op
+	executor.Stop()
+}
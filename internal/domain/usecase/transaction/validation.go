@@ -9,7 +9,9 @@ import (
 )
 
 // TransactionValidator provides validation for transaction requests
-type TransactionValidator struct{}
+type TransactionValidator struct {
+	limits LimitPolicy // Zero value limits nothing
+}
 
 // NewTransactionValidator creates a new TransactionValidator
 func NewTransactionValidator() *TransactionValidator {
@@ -49,9 +51,25 @@ func (v *TransactionValidator) ValidateTransaction(
 		return err
 	}
 
+	// Check the amount against the configured limits
+	if err := v.validateLimits(userID, sourceType, state, amount); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// WithLimitPolicy rejects amounts outside the policy's limits
+func (v *TransactionValidator) WithLimitPolicy(policy LimitPolicy) *TransactionValidator {
+	v.limits = policy
+	return v
+}
+
+// ValidateAdjustment checks that admin transactions, and only those, say why they were made and by whom
+func (v *TransactionValidator) ValidateAdjustment(sourceType string, adjustment *entity.Adjustment) error {
+	return entity.ValidateAdjustment(entity.SourceType(sourceType), adjustment)
+}
+
 // validateTransactionID checks if the transaction ID is valid
 func (v *TransactionValidator) validateTransactionID(transactionID string) error {
 	if transactionID == "" {
@@ -107,3 +125,29 @@ func (v *TransactionValidator) validateAmount(amount string) error {
 
 	return nil
 }
+
+// validateLimits checks a valid amount against the minimum and maximum for the transaction
+// The balance limit needs the user's balance, so it is checked when the transaction is processed.
+func (v *TransactionValidator) validateLimits(userID uint64, sourceType, state, amount string) error {
+	if !v.limits.Enabled() {
+		return nil
+	}
+	source, err := entity.ParseSourceType(sourceType)
+	if err != nil {
+		return err
+	}
+	txnState, err := entity.ParseTransactionState(state)
+	if err != nil {
+		return err
+	}
+	parsedAmount, err := entity.ParseMoney(strings.TrimSpace(amount))
+	if err != nil {
+		return fmt.Errorf("%w: %s", errs.ErrInvalidAmount, err.Error())
+	}
+	return v.limits.CheckAmount(&entity.Transaction{
+		UserID:     userID,
+		SourceType: source,
+		State:      txnState,
+		Amount:     parsedAmount,
+	})
+}
@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+)
+
+// ReconciliationReport is the result of checking a user's stored balance against their transaction log
+type ReconciliationReport struct {
+	UserID           uint64
+	Balance          entity.Money // Stored balance
+	TransactionCount uint64       // Stored count of completed transactions
+	Completed        int          // Completed transactions in the log
+	Failed           int          // Failed, rejected and expired transactions in the log
+	Pending          int          // Transactions in the log that never finished or await approval
+	OpeningBalance   entity.Money // Balance before the first completed transaction
+	LogBalance       entity.Money // Balance after the last completed transaction
+	Discrepancies    []string
+}
+
+// Consistent reports whether the stored balance agrees with the transaction log
+func (r *ReconciliationReport) Consistent() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// Reconciler checks users' balances against their transaction logs
+type Reconciler struct {
+	userRepo persistence.UserRepository
+	txnRepo  persistence.TransactionRepository
+	logger   coreport.Logger
+}
+
+// NewReconciler creates a new Reconciler
+func NewReconciler(
+	userRepo persistence.UserRepository,
+	txnRepo persistence.TransactionRepository,
+	logger coreport.Logger,
+) *Reconciler {
+	return &Reconciler{
+		userRepo: userRepo,
+		txnRepo:  txnRepo,
+		logger:   logger,
+	}
+}
+
+// Reconcile checks one user's balance against their transaction log
+// Users are created with an opening balance that is not logged, so the log is checked as a chain:
+// every completed transaction must move the balance recorded by the one before it by its amount,
+// and the last one must leave the balance that is stored. The stored transaction count must match
+// the number of completed transactions.
+//
+// Possible errors:
+// - ErrUserNotFound: If user with specified ID doesn't exist
+// - ErrDatabaseConnection: If database connection fails
+func (r *Reconciler) Reconcile(ctx context.Context, userID uint64) (*ReconciliationReport, error) {
+	user, err := r.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := r.txnRepo.ListByUser(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconciliationReport{
+		UserID:           userID,
+		Balance:          user.Balance(),
+		TransactionCount: user.TransactionCount,
+		OpeningBalance:   user.Balance(),
+		LogBalance:       user.Balance(),
+	}
+
+	// The log is returned newest first
+	var previous *entity.Transaction
+	for i := len(transactions) - 1; i >= 0; i-- {
+		txn := transactions[i]
+		switch {
+		case txn.IsPending() || txn.AwaitsApproval():
+			report.Pending++
+			continue
+		case txn.Status != entity.StatusCompleted:
+			// Failed, rejected or expired; none of them changed the balance
+			report.Failed++
+			continue
+		}
+
+		report.Completed++
+		if previous == nil {
+			opening, err := txn.ResultBalance.Sub(txn.BalanceChange())
+			if err != nil {
+				report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+					"transaction %s implies an opening balance out of range: %s", txn.TransactionID, err.Error()))
+			}
+			report.OpeningBalance = opening
+		} else if expected, err := previous.ResultBalance.Add(txn.BalanceChange()); err != nil {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+				"transaction %s takes the balance out of range after transaction %s: %s",
+				txn.TransactionID, previous.TransactionID, err.Error()))
+		} else if txn.ResultBalance != expected {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+				"transaction %s left a balance of %s, expected %s after transaction %s",
+				txn.TransactionID, txn.GetResultBalance(), expected, previous.TransactionID))
+		}
+		report.LogBalance = txn.ResultBalance
+		previous = txn
+	}
+
+	if report.LogBalance != report.Balance {
+		report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+			"stored balance is %s but the transaction log ends at %s",
+			report.Balance, report.LogBalance))
+	}
+	if report.TransactionCount != uint64(report.Completed) {
+		report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+			"stored transaction count is %d but the log has %d completed transactions",
+			report.TransactionCount, report.Completed))
+	}
+	if report.OpeningBalance.IsNegative() {
+		report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+			"the log implies a negative opening balance of %s", report.OpeningBalance))
+	}
+
+	if !report.Consistent() {
+		r.logger.Warn("User balance does not reconcile with the transaction log", map[string]any{
+			"user_id":       userID,
+			"discrepancies": len(report.Discrepancies),
+		})
+	}
+	return report, nil
+}
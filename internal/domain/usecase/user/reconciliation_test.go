@@ -0,0 +1,114 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// reconcileTxn builds a logged transaction that left the given result balance
+func reconcileTxn(id, state string, cents, result int64, status entity.TransactionStatus) *entity.Transaction {
+	return &entity.Transaction{
+		UserID:        1,
+		TransactionID: id,
+		SourceType:    entity.SourceGame,
+		State:         entity.TransactionState(state),
+		Amount:        entity.MoneyFromCents(cents),
+		ResultBalance: entity.MoneyFromCents(result),
+		Status:        status,
+	}
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	ctx := context.Background()
+
+	timeProvider := coremocks.NewMockTimeProvider(t)
+	timeProvider.EXPECT().Now().Return(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)).Maybe()
+
+	logger := coremocks.NewMockLogger(t)
+	logger.EXPECT().Warn(mock.Anything, mock.Anything).Maybe()
+
+	newUser := func(balance string, count uint64) *entity.User {
+		user, err := entity.NewUser(1, balance, timeProvider)
+		require.NoError(t, err)
+		user.TransactionCount = count
+		return user
+	}
+
+	// Newest first, as the repository returns them
+	log := []*entity.Transaction{
+		reconcileTxn("t4", "win", 500, 0, entity.StatusPending),
+		reconcileTxn("t3", "lose", 2500, 12500, entity.StatusCompleted),
+		reconcileTxn("t2", "lose", 99900, 0, entity.StatusFailed),
+		reconcileTxn("t1", "win", 5000, 15000, entity.StatusCompleted),
+	}
+
+	tests := []struct {
+		name          string
+		user          *entity.User
+		log           []*entity.Transaction
+		discrepancies int
+		opening       int64
+	}{
+		{name: "consistent", user: newUser("125.00", 2), log: log, opening: 10000},
+		{name: "no transactions", user: newUser("100.00", 0), opening: 10000},
+		{name: "balance drifted", user: newUser("130.00", 2), log: log, discrepancies: 1, opening: 10000},
+		{name: "count drifted", user: newUser("125.00", 3), log: log, discrepancies: 1, opening: 10000},
+		{
+			name: "broken chain",
+			user: newUser("125.00", 2),
+			log: []*entity.Transaction{
+				reconcileTxn("t3", "lose", 2500, 12500, entity.StatusCompleted),
+				reconcileTxn("t1", "win", 5000, 16000, entity.StatusCompleted),
+			},
+			discrepancies: 1,
+			opening:       11000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := persistencemocks.NewMockUserRepository(t)
+			txnRepo := persistencemocks.NewMockTransactionRepository(t)
+			userRepo.EXPECT().GetByID(ctx, uint64(1)).Return(tt.user, nil)
+			txnRepo.EXPECT().ListByUser(ctx, uint64(1), 0).Return(tt.log, nil)
+
+			report, err := NewReconciler(userRepo, txnRepo, logger).Reconcile(ctx, 1)
+			require.NoError(t, err)
+			assert.Len(t, report.Discrepancies, tt.discrepancies, report.Discrepancies)
+			assert.Equal(t, tt.discrepancies == 0, report.Consistent())
+			assert.Equal(t, entity.MoneyFromCents(tt.opening), report.OpeningBalance)
+		})
+	}
+
+	t.Run("counts transactions by status", func(t *testing.T) {
+		userRepo := persistencemocks.NewMockUserRepository(t)
+		txnRepo := persistencemocks.NewMockTransactionRepository(t)
+		userRepo.EXPECT().GetByID(ctx, uint64(1)).Return(newUser("125.00", 2), nil)
+		txnRepo.EXPECT().ListByUser(ctx, uint64(1), 0).Return(log, nil)
+
+		report, err := NewReconciler(userRepo, txnRepo, logger).Reconcile(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Completed)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, 1, report.Pending)
+		assert.Equal(t, entity.MoneyFromCents(12500), report.LogBalance)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		userRepo := persistencemocks.NewMockUserRepository(t)
+		txnRepo := persistencemocks.NewMockTransactionRepository(t)
+		userRepo.EXPECT().GetByID(ctx, uint64(1)).Return(nil, errs.ErrUserNotFound)
+
+		_, err := NewReconciler(userRepo, txnRepo, logger).Reconcile(ctx, 1)
+		assert.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+}
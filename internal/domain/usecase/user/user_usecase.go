@@ -103,18 +103,18 @@ func (u *UserUseCase) CreateDefaultUsers(ctx context.Context) error {
 // GetBalanceResponse represents a user balance response
 type GetBalanceResponse struct {
 	UserID  uint64
-	Balance string
+	Balance entity.Money
 }
 
 // GetBalance returns a user's balance as a response object
 func (u *UserUseCase) GetBalance(ctx context.Context, userID uint64) (*GetBalanceResponse, error) {
-	balance, err := u.GetUserBalance(ctx, userID)
+	user, err := u.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &GetBalanceResponse{
 		UserID:  userID,
-		Balance: balance,
+		Balance: user.Balance(),
 	}, nil
 }
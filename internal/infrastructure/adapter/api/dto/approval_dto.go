@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
+// ApprovalDecisionRequest represents the API request for approving or rejecting a held transaction
+// The approver is the operator whose token authenticated the request, never a field of the body.
+type ApprovalDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+// ApprovalResponse represents a transaction held for approval, or the outcome of a decision on it
+type ApprovalResponse struct {
+	TransactionID    string                  `json:"transactionId"`
+	UserID           uint64                  `json:"userId"`
+	SourceType       string                  `json:"sourceType"`
+	State            string                  `json:"state"`
+	Amount           entity.Money            `json:"amount"`
+	Status           string                  `json:"status"`
+	RequestedBy      string                  `json:"requestedBy"`
+	CreatedAt        time.Time               `json:"createdAt"`
+	ExpiresAt        *time.Time              `json:"expiresAt,omitempty"`
+	AdjustmentReason string                  `json:"adjustmentReason,omitempty"`
+	AdjustmentNote   string                  `json:"adjustmentNote,omitempty"`
+	ResultBalance    *entity.Money           `json:"resultBalance,omitempty"`
+	ErrorMessage     string                  `json:"errorMessage,omitempty"`
+	History          []ApprovalEventResponse `json:"history,omitempty"`
+}
+
+// ApprovalEventResponse represents one entry of the approval audit trail
+type ApprovalEventResponse struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ApprovalListResponse represents the API response for the transactions awaiting approval
+type ApprovalListResponse struct {
+	Approvals []ApprovalResponse `json:"approvals"`
+}
+
+// TransactionToApprovalResponse converts a held transaction to an ApprovalResponse DTO
+// A positive expiry sets ExpiresAt while the transaction still awaits approval.
+func TransactionToApprovalResponse(txn *entity.Transaction, expiry time.Duration) ApprovalResponse {
+	response := ApprovalResponse{
+		TransactionID: txn.TransactionID,
+		UserID:        txn.UserID,
+		SourceType:    txn.SourceType.String(),
+		State:         txn.State.String(),
+		Amount:        txn.Amount,
+		Status:        txn.Status.String(),
+		RequestedBy:   txn.Requester(),
+		CreatedAt:     txn.CreatedAt,
+		ErrorMessage:  txn.ErrorMessage,
+	}
+	if expiry > 0 && txn.AwaitsApproval() {
+		expiresAt := txn.CreatedAt.Add(expiry)
+		response.ExpiresAt = &expiresAt
+	}
+	if txn.Adjustment != nil {
+		response.AdjustmentReason = txn.Adjustment.Reason.String()
+		response.AdjustmentNote = txn.Adjustment.Note
+	}
+	if txn.Status == entity.StatusCompleted {
+		response.ResultBalance = &txn.ResultBalance
+	}
+	return response
+}
+
+// ApprovalEventsToResponse converts an approval audit trail to response DTOs
+func ApprovalEventsToResponse(events []*entity.ApprovalEvent) []ApprovalEventResponse {
+	response := make([]ApprovalEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, ApprovalEventResponse{
+			Action:    event.Action.String(),
+			Actor:     event.Actor,
+			Note:      event.Note,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return response
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionToApprovalResponse(t *testing.T) {
+	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockTime := coremocks.NewMockTimeProvider(t)
+	mockTime.EXPECT().Now().Return(fixedTime).Maybe()
+
+	adjustment := &entity.Adjustment{Reason: entity.ReasonCorrection, Note: "ticket #7", Operator: "alice"}
+	txn, err := entity.NewTransaction(42, "adj-1", "admin", "win", "1500.00", mockTime, entity.WithAdjustment(adjustment))
+	require.NoError(t, err)
+
+	t.Run("Held transaction shows when it expires", func(t *testing.T) {
+		txn.MarkAsPendingApproval()
+
+		response := TransactionToApprovalResponse(txn, time.Hour)
+		assert.Equal(t, "adj-1", response.TransactionID)
+		assert.Equal(t, "1500.00", response.Amount.String())
+		assert.Equal(t, "pending_approval", response.Status)
+		assert.Equal(t, "alice", response.RequestedBy)
+		assert.Equal(t, "correction", response.AdjustmentReason)
+		require.NotNil(t, response.ExpiresAt)
+		assert.Equal(t, fixedTime.Add(time.Hour), *response.ExpiresAt)
+		assert.Nil(t, response.ResultBalance)
+	})
+
+	t.Run("Approved transaction shows the result balance", func(t *testing.T) {
+		approved := txn.Clone()
+		approved.MarkAsProcessed(mockTime, entity.MoneyFromCents(160000))
+
+		response := TransactionToApprovalResponse(approved, time.Hour)
+		assert.Equal(t, "completed", response.Status)
+		require.NotNil(t, response.ResultBalance)
+		assert.Equal(t, "1600.00", response.ResultBalance.String())
+		assert.Nil(t, response.ExpiresAt)
+	})
+
+	t.Run("History keeps its order", func(t *testing.T) {
+		history := ApprovalEventsToResponse([]*entity.ApprovalEvent{
+			entity.NewApprovalEvent(txn, entity.ApprovalRequested, "alice", "", mockTime),
+			entity.NewApprovalEvent(txn, entity.ApprovalApproved, "bob", "ok", mockTime),
+		})
+		require.Len(t, history, 2)
+		assert.Equal(t, "requested", history[0].Action)
+		assert.Equal(t, "bob", history[1].Actor)
+		assert.Equal(t, "ok", history[1].Note)
+	})
+}
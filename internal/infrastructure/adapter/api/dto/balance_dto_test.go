@@ -24,7 +24,7 @@ func TestUserToBalanceResponse(t *testing.T) {
 
 		// Verify the conversion
 		assert.Equal(t, uint64(42), response.UserID)
-		assert.Equal(t, "123.45", response.Balance)
+		assert.Equal(t, "123.45", response.Balance.String())
 	})
 
 	t.Run("Handles zero balance", func(t *testing.T) {
@@ -38,7 +38,7 @@ func TestUserToBalanceResponse(t *testing.T) {
 		response := UserToBalanceResponse(user)
 
 		assert.Equal(t, uint64(123), response.UserID)
-		assert.Equal(t, "0.00", response.Balance)
+		assert.Equal(t, "0.00", response.Balance.String())
 	})
 
 	t.Run("Handles large balance values", func(t *testing.T) {
@@ -52,6 +52,6 @@ func TestUserToBalanceResponse(t *testing.T) {
 		response := UserToBalanceResponse(user)
 
 		assert.Equal(t, uint64(999), response.UserID)
-		assert.Equal(t, "9876543.21", response.Balance)
+		assert.Equal(t, "9876543.21", response.Balance.String())
 	})
 }
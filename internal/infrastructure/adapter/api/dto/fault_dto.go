@@ -0,0 +1,97 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/faultinject"
+)
+
+// FaultRequest is one fault to inject into the persistence ports
+type FaultRequest struct {
+	Method      string  `json:"method" binding:"required"` // e.g. "UnitOfWork.Commit" or "UserRepository.*"
+	Error       string  `json:"error,omitempty"`           // e.g. "serialization"; empty only adds latency
+	Phase       string  `json:"phase,omitempty"`           // "before" (default) or "after" the call
+	Latency     string  `json:"latency,omitempty"`         // Duration such as "50ms"
+	Probability float64 `json:"probability,omitempty"`     // 0-1; 0 means always
+	Count       int     `json:"count,omitempty"`           // Times to fire; 0 means no limit
+}
+
+// FaultsRequest represents the API request for replacing or adding faults
+type FaultsRequest struct {
+	Faults []FaultRequest `json:"faults" binding:"dive"`
+}
+
+// FaultResponse is a configured fault and how often it has fired
+type FaultResponse struct {
+	FaultRequest
+	Fired int `json:"fired"`
+}
+
+// FaultStatsResponse counts the calls of one port method
+type FaultStatsResponse struct {
+	Calls    int64 `json:"calls"`
+	Injected int64 `json:"injected"`
+}
+
+// FaultsResponse lists the configured faults and the calls seen so far
+type FaultsResponse struct {
+	Faults     []FaultResponse               `json:"faults"`
+	Stats      map[string]FaultStatsResponse `json:"stats"`
+	Methods    []string                      `json:"methods"`
+	ErrorKinds []string                      `json:"errorKinds"`
+}
+
+// ToFaults converts the request to faults for the injector
+//
+// Possible errors:
+//   - errs.ErrInvalidRequest: a latency is not a valid duration
+func (r FaultsRequest) ToFaults() ([]faultinject.Fault, error) {
+	faults := make([]faultinject.Fault, 0, len(r.Faults))
+	for n, req := range r.Faults {
+		var latency time.Duration
+		if req.Latency != "" {
+			var err error
+			if latency, err = time.ParseDuration(req.Latency); err != nil {
+				return nil, fmt.Errorf("%w: fault %d: invalid latency %q", errs.ErrInvalidRequest, n+1, req.Latency)
+			}
+		}
+		faults = append(faults, faultinject.Fault{
+			Method:      req.Method,
+			Error:       req.Error,
+			Phase:       req.Phase,
+			Latency:     latency,
+			Probability: req.Probability,
+			Count:       req.Count,
+		})
+	}
+	return faults, nil
+}
+
+// InjectorToResponse converts the injector's faults and call counts to the API response
+func InjectorToResponse(injector *faultinject.Injector) FaultsResponse {
+	response := FaultsResponse{
+		Faults:     []FaultResponse{},
+		Stats:      map[string]FaultStatsResponse{},
+		Methods:    faultinject.Methods,
+		ErrorKinds: faultinject.ErrorKinds,
+	}
+	for _, fault := range injector.Faults() {
+		req := FaultRequest{
+			Method:      fault.Method,
+			Error:       fault.Error,
+			Phase:       fault.Phase,
+			Probability: fault.Probability,
+			Count:       fault.Count,
+		}
+		if fault.Latency > 0 {
+			req.Latency = fault.Latency.String()
+		}
+		response.Faults = append(response.Faults, FaultResponse{FaultRequest: req, Fired: fault.Fired})
+	}
+	for method, stats := range injector.Stats() {
+		response.Stats[method] = FaultStatsResponse{Calls: stats.Calls, Injected: stats.Injected}
+	}
+	return response
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/faultinject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultsRequest_ToFaults(t *testing.T) {
+	t.Run("Latency is parsed as a duration", func(t *testing.T) {
+		faults, err := FaultsRequest{Faults: []FaultRequest{
+			{Method: "UnitOfWork.Commit", Error: "serialization", Probability: 0.5, Count: 3},
+			{Method: "UserRepository.*", Latency: "25ms"},
+		}}.ToFaults()
+		require.NoError(t, err)
+		assert.Equal(t, []faultinject.Fault{
+			{Method: "UnitOfWork.Commit", Error: "serialization", Probability: 0.5, Count: 3},
+			{Method: "UserRepository.*", Latency: 25 * time.Millisecond},
+		}, faults)
+	})
+
+	t.Run("Invalid latency", func(t *testing.T) {
+		_, err := FaultsRequest{Faults: []FaultRequest{{Method: "*", Latency: "soon"}}}.ToFaults()
+		assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+	})
+}
+
+func TestInjectorToResponse(t *testing.T) {
+	injector := faultinject.NewInjector(1)
+	response := InjectorToResponse(injector)
+	assert.NotNil(t, response.Faults, "an empty list is sent as [] rather than null")
+	assert.Empty(t, response.Faults)
+	assert.Contains(t, response.Methods, "UnitOfWork.Commit")
+	assert.Contains(t, response.ErrorKinds, "deadlock")
+
+	require.NoError(t, injector.Set(faultinject.Fault{Method: "UnitOfWork.Commit", Latency: time.Second, Error: "deadlock", Phase: "after"}))
+	response = InjectorToResponse(injector)
+	require.Len(t, response.Faults, 1)
+	assert.Equal(t, FaultResponse{
+		FaultRequest: FaultRequest{Method: "UnitOfWork.Commit", Error: "deadlock", Phase: "after", Latency: "1s"},
+	}, response.Faults[0])
+}
@@ -0,0 +1,122 @@
+package dto
+
+import (
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+)
+
+// MetricsResponse represents the API response for the processing metrics snapshot
+type MetricsResponse struct {
+	Lease      LeaseMetricsResponse       `json:"lease"`
+	Retry      RetryMetricsResponse       `json:"retry"`
+	Queue      *QueueMetricsResponse      `json:"queue,omitempty"`
+	Coalescing *CoalescingMetricsResponse `json:"coalescing,omitempty"`
+	Admission  *AdmissionMetricsResponse  `json:"admission,omitempty"`
+}
+
+// LeaseMetricsResponse represents user lock lease renewal counters
+type LeaseMetricsResponse struct {
+	Extensions      int64 `json:"extensions"`
+	RenewalFailures int64 `json:"renewalFailures"`
+	LostLeases      int64 `json:"lostLeases"`
+}
+
+// RetryMetricsResponse represents transaction retry counters
+type RetryMetricsResponse struct {
+	Retries   int64 `json:"retries"`
+	Exhausted int64 `json:"exhausted"`
+	Aborted   int64 `json:"aborted"`
+}
+
+// QueueMetricsResponse represents the per-user queue executor counters
+type QueueMetricsResponse struct {
+	Workers      int   `json:"workers"`
+	QueuedJobs   int   `json:"queuedJobs"`
+	MaxUserDepth int   `json:"maxUserDepth"`
+	ShardDepths  []int `json:"shardDepths"`
+	Submitted    int64 `json:"submitted"`
+	Completed    int64 `json:"completed"`
+	Rejected     int64 `json:"rejected"`
+	Cancelled    int64 `json:"cancelled"`
+}
+
+// CoalescingMetricsResponse represents the duplicate-request coalescer counters
+type CoalescingMetricsResponse struct {
+	InFlight      int   `json:"inFlight"`
+	Coalesced     int64 `json:"coalesced"`
+	CacheHits     int64 `json:"cacheHits"`
+	CacheSize     int   `json:"cacheSize"`
+	CacheCapacity int   `json:"cacheCapacity"`
+}
+
+// AdmissionMetricsResponse represents the admission controller's state and counters
+type AdmissionMetricsResponse struct {
+	Limit            int            `json:"limit"`
+	Active           int            `json:"active"`
+	Queued           int            `json:"queued"`
+	QueueCapacity    int            `json:"queueCapacity"`
+	QueuedByPriority map[string]int `json:"queuedByPriority"`
+	Admitted         int64          `json:"admitted"`
+	Waited           int64          `json:"waited"`
+	Shed             int64          `json:"shed"`
+	TimedOut         int64          `json:"timedOut"`
+	Cancelled        int64          `json:"cancelled"`
+}
+
+// LeaseMetricsToResponse converts lease metrics to a LeaseMetricsResponse DTO
+func LeaseMetricsToResponse(metrics transactionUseCase.LeaseMetrics) LeaseMetricsResponse {
+	return LeaseMetricsResponse{
+		Extensions:      metrics.Extensions,
+		RenewalFailures: metrics.RenewalFailures,
+		LostLeases:      metrics.LostLeases,
+	}
+}
+
+// RetryMetricsToResponse converts retry metrics to a RetryMetricsResponse DTO
+func RetryMetricsToResponse(metrics transactionUseCase.RetryMetrics) RetryMetricsResponse {
+	return RetryMetricsResponse{
+		Retries:   metrics.Retries,
+		Exhausted: metrics.Exhausted,
+		Aborted:   metrics.Aborted,
+	}
+}
+
+// QueueMetricsToResponse converts queue metrics to a QueueMetricsResponse DTO
+func QueueMetricsToResponse(metrics transactionUseCase.QueueMetrics) *QueueMetricsResponse {
+	return &QueueMetricsResponse{
+		Workers:      metrics.Workers,
+		QueuedJobs:   metrics.QueuedJobs,
+		MaxUserDepth: metrics.MaxUserDepth,
+		ShardDepths:  metrics.ShardDepths,
+		Submitted:    metrics.Submitted,
+		Completed:    metrics.Completed,
+		Rejected:     metrics.Rejected,
+		Cancelled:    metrics.Cancelled,
+	}
+}
+
+// CoalescingMetricsToResponse converts coalescer metrics to a CoalescingMetricsResponse DTO
+func CoalescingMetricsToResponse(metrics transactionUseCase.CoalescingMetrics) *CoalescingMetricsResponse {
+	return &CoalescingMetricsResponse{
+		InFlight:      metrics.InFlight,
+		Coalesced:     metrics.Coalesced,
+		CacheHits:     metrics.CacheHits,
+		CacheSize:     metrics.CacheSize,
+		CacheCapacity: metrics.CacheCapacity,
+	}
+}
+
+// AdmissionMetricsToResponse converts admission metrics to an AdmissionMetricsResponse DTO
+func AdmissionMetricsToResponse(metrics transactionUseCase.AdmissionMetrics) *AdmissionMetricsResponse {
+	return &AdmissionMetricsResponse{
+		Limit:            metrics.Limit,
+		Active:           metrics.Active,
+		Queued:           metrics.Queued,
+		QueueCapacity:    metrics.QueueCapacity,
+		QueuedByPriority: metrics.QueuedByPriority,
+		Admitted:         metrics.Admitted,
+		Waited:           metrics.Waited,
+		Shed:             metrics.Shed,
+		TimedOut:         metrics.TimedOut,
+		Cancelled:        metrics.Cancelled,
+	}
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+)
+
+// RebuildRequest represents the API request for rebuilding a user's balance from the transaction log
+// The operator recorded for an applied rebuild is the one whose token authenticated the request.
+type RebuildRequest struct {
+	InitialBalance string `json:"initialBalance"` // Optional; overrides the configured initial balance
+	Apply          bool   `json:"apply"`          // Without it the rebuild is a dry run
+}
+
+// RebuildResponse compares a user's stored balance with the one rebuilt from their log
+type RebuildResponse struct {
+	UserID               uint64       `json:"userId"`
+	InitialBalance       entity.Money `json:"initialBalance"`
+	InitialBalanceSource string       `json:"initialBalanceSource"`
+	Replayed             int          `json:"replayed"`
+	StoredBalance        entity.Money `json:"storedBalance"`
+	RebuiltBalance       entity.Money `json:"rebuiltBalance"`
+	StoredCount          uint64       `json:"storedTransactionCount"`
+	RebuiltCount         uint64       `json:"rebuiltTransactionCount"`
+	Changed              bool         `json:"changed"`
+	Applied              bool         `json:"applied"`
+	Warnings             []string     `json:"warnings,omitempty"`
+}
+
+// RebuildReportToResponse converts a rebuild report to its API response
+func RebuildReportToResponse(report *transactionUseCase.RebuildReport) RebuildResponse {
+	return RebuildResponse{
+		UserID:               report.UserID,
+		InitialBalance:       report.InitialBalance,
+		InitialBalanceSource: report.InitialBalanceSource,
+		Replayed:             report.Replayed,
+		StoredBalance:        report.StoredBalance,
+		RebuiltBalance:       report.RebuiltBalance,
+		StoredCount:          report.StoredCount,
+		RebuiltCount:         report.RebuiltCount,
+		Changed:              report.Changed(),
+		Applied:              report.Applied,
+		Warnings:             report.Warnings,
+	}
+}
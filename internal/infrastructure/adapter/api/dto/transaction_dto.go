@@ -1,17 +1,22 @@
 package dto
 
+import (
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
 // TransactionRequest represents the API request for processing a transaction
 type TransactionRequest struct {
-	State         string `json:"state" binding:"required,oneof=win lose"`
-	Amount        string `json:"amount" binding:"required"`
-	TransactionID string `json:"transactionId" binding:"required"`
+	State         string        `json:"state" binding:"required,oneof=win lose"`
+	Amount        *entity.Money `json:"amount" binding:"required"` // A string such as "10.15"
+	TransactionID string        `json:"transactionId" binding:"required"`
 }
 
 // TransactionResponse represents the API response for a processed transaction
 type TransactionResponse struct {
-	TransactionID string `json:"transactionId"`
-	UserID        uint64 `json:"userId"`
-	Success       bool   `json:"success"`
-	ResultBalance string `json:"resultBalance,omitempty"`
-	ErrorMessage  string `json:"errorMessage,omitempty"`
+	TransactionID string        `json:"transactionId"`
+	UserID        uint64        `json:"userId"`
+	Success       bool          `json:"success"`
+	Status        string        `json:"status,omitempty"`
+	ResultBalance *entity.Money `json:"resultBalance,omitempty"`
+	ErrorMessage  string        `json:"errorMessage,omitempty"`
 }
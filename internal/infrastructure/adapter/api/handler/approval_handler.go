@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultApprovalListLimit caps the pending approvals listed when the request sets no limit
+const defaultApprovalListLimit = 100
+
+// ApprovalHandler handles the admin endpoints for transactions held for approval
+type ApprovalHandler struct {
+	transactionService *transactionUseCase.Service
+	logger             coreport.Logger
+}
+
+// NewApprovalHandler creates a new approval handler instance
+func NewApprovalHandler(
+	transactionService *transactionUseCase.Service,
+	logger coreport.Logger,
+) *ApprovalHandler {
+	return &ApprovalHandler{
+		transactionService: transactionService,
+		logger:             logger,
+	}
+}
+
+// ListPending handles the GET /admin/approvals endpoint
+func (h *ApprovalHandler) ListPending(c *gin.Context) {
+	limit := defaultApprovalListLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+				Message: "limit must be a positive integer",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	pending, err := h.transactionService.ListPendingApprovals(c.Request.Context(), limit)
+	if err != nil {
+		h.respondError(c, "Error listing pending approvals", "", err)
+		return
+	}
+
+	expiry := h.transactionService.GetManager().ApprovalPolicy().Expiry
+	response := dto.ApprovalListResponse{Approvals: make([]dto.ApprovalResponse, 0, len(pending))}
+	for _, txn := range pending {
+		response.Approvals = append(response.Approvals, dto.TransactionToApprovalResponse(txn, expiry))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Get handles the GET /admin/approvals/{transactionId} endpoint
+func (h *ApprovalHandler) Get(c *gin.Context) {
+	transactionID := c.Param("transactionId")
+	txn, err := h.transactionService.GetTransaction(c.Request.Context(), transactionID)
+	if err != nil {
+		h.respondError(c, "Error getting transaction", transactionID, err)
+		return
+	}
+	h.respondWithHistory(c, txn)
+}
+
+// Approve handles the POST /admin/approvals/{transactionId}/approve endpoint
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, h.transactionService.ApproveTransaction)
+}
+
+// Reject handles the POST /admin/approvals/{transactionId}/reject endpoint
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, h.transactionService.RejectTransaction)
+}
+
+// decide records the authenticated operator's decision, with the note of an optional body
+func (h *ApprovalHandler) decide(
+	c *gin.Context,
+	decision func(ctx context.Context, transactionID string, approver entity.Principal, note string) (*entity.Transaction, error),
+) {
+	transactionID := c.Param("transactionId")
+
+	approver, ok := authenticatedOperator(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ApprovalDecisionRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	txn, err := decision(c.Request.Context(), transactionID, approver, req.Note)
+	if err != nil {
+		h.respondError(c, "Approval decision failed", transactionID, err)
+		return
+	}
+	h.respondWithHistory(c, txn)
+}
+
+// respondWithHistory writes the transaction together with its approval audit trail
+func (h *ApprovalHandler) respondWithHistory(c *gin.Context, txn *entity.Transaction) {
+	history, err := h.transactionService.ApprovalHistory(c.Request.Context(), txn.TransactionID)
+	if err != nil {
+		h.respondError(c, "Error getting approval history", txn.TransactionID, err)
+		return
+	}
+
+	response := dto.TransactionToApprovalResponse(txn, h.transactionService.GetManager().ApprovalPolicy().Expiry)
+	response.History = dto.ApprovalEventsToResponse(history)
+	c.JSON(http.StatusOK, response)
+}
+
+// respondError maps domain errors of the approval workflow to HTTP responses
+func (h *ApprovalHandler) respondError(c *gin.Context, msg string, transactionID string, err error) {
+	statusCode := http.StatusInternalServerError
+	errorMessage := "Internal server error"
+
+	switch {
+	case domainerr.IsNotFoundError(err):
+		statusCode = http.StatusNotFound
+		errorMessage = "Transaction not found"
+	case domainerr.IsSelfApprovalError(err):
+		statusCode = http.StatusForbidden
+		errorMessage = err.Error()
+	case domainerr.IsApprovalConflictError(err), domainerr.IsInsufficientBalanceError(err),
+		errors.Is(err, domainerr.ErrAmountOverflow), errors.Is(err, domainerr.ErrBalanceLimitExceeded),
+		domainerr.IsConcurrencyConflictError(err), domainerr.IsUserLockedError(err):
+		statusCode = http.StatusConflict
+		errorMessage = err.Error()
+	case domainerr.IsShuttingDownError(err):
+		statusCode = http.StatusServiceUnavailable
+		errorMessage = "Service is shutting down. Please try again."
+	case errors.Is(err, domainerr.ErrInvalidRequest):
+		statusCode = http.StatusBadRequest
+		errorMessage = err.Error()
+	}
+
+	h.logger.Error(msg, map[string]any{
+		"transactionId": transactionID,
+		"statusCode":    statusCode,
+		"error":         err.Error(),
+	})
+
+	c.JSON(statusCode, dto.ErrorResponse{
+		Code:    domainerr.ErrorCode(err),
+		Message: errorMessage,
+	})
+}
+
+// authenticatedOperator returns the operator the admin middleware authenticated
+// It responds with 401 when there is none, so operator endpoints never act for nobody.
+func authenticatedOperator(c *gin.Context) (entity.Principal, bool) {
+	principal, ok := middleware.Principal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+			Message: "Missing or invalid admin token",
+		})
+	}
+	return principal, ok
+}
+
+// bindOptionalJSON binds a JSON body whose fields are all optional, so an empty body is accepted
+func bindOptionalJSON(c *gin.Context, obj any) error {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+
+	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/faultinject"
+	"github.com/gin-gonic/gin"
+)
+
+// FaultHandler handles the admin endpoints that inject persistence faults
+type FaultHandler struct {
+	injector *faultinject.Injector
+	logger   coreport.Logger
+}
+
+// NewFaultHandler creates a new fault handler instance
+func NewFaultHandler(injector *faultinject.Injector, logger coreport.Logger) *FaultHandler {
+	return &FaultHandler{
+		injector: injector,
+		logger:   logger,
+	}
+}
+
+// List handles the GET /admin/faults endpoint
+func (h *FaultHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.InjectorToResponse(h.injector))
+}
+
+// Replace handles the PUT /admin/faults endpoint
+func (h *FaultHandler) Replace(c *gin.Context) {
+	h.update(c, "Replaced injected faults", h.injector.Set)
+}
+
+// Add handles the POST /admin/faults endpoint
+func (h *FaultHandler) Add(c *gin.Context) {
+	h.update(c, "Added injected faults", h.injector.Add)
+}
+
+// Clear handles the DELETE /admin/faults endpoint
+// It removes every fault and resets the call counts.
+func (h *FaultHandler) Clear(c *gin.Context) {
+	h.injector.Clear()
+	h.logger.Warn("Cleared injected faults", nil)
+	c.JSON(http.StatusOK, dto.InjectorToResponse(h.injector))
+}
+
+// update applies the faults of a request with apply
+func (h *FaultHandler) update(c *gin.Context, message string, apply func(...faultinject.Fault) error) {
+	var req dto.FaultsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	faults, err := req.ToFaults()
+	if err == nil {
+		err = apply(faults...)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(err),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Warn(message, map[string]any{
+		"faults": len(faults),
+	})
+	c.JSON(http.StatusOK, dto.InjectorToResponse(h.injector))
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler serves operational metrics for transaction processing
+type MetricsHandler struct {
+	transactionService *transactionUseCase.Service
+	logger             coreport.Logger
+}
+
+// NewMetricsHandler creates a new metrics handler instance
+func NewMetricsHandler(
+	transactionService *transactionUseCase.Service,
+	logger coreport.Logger,
+) *MetricsHandler {
+	return &MetricsHandler{
+		transactionService: transactionService,
+		logger:             logger,
+	}
+}
+
+// GetMetrics handles the GET /metrics endpoint
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	response := dto.MetricsResponse{
+		Lease: dto.LeaseMetricsToResponse(h.transactionService.GetManager().LeaseMetrics()),
+		Retry: dto.RetryMetricsToResponse(h.transactionService.GetManager().RetryMetrics()),
+	}
+
+	// The queue section is only present when the per-user queue is enabled
+	if executor := h.transactionService.GetExecutor(); executor != nil {
+		response.Queue = dto.QueueMetricsToResponse(executor.Metrics())
+	}
+	if coalescer := h.transactionService.GetCoalescer(); coalescer != nil {
+		response.Coalescing = dto.CoalescingMetricsToResponse(coalescer.Metrics())
+	}
+	if admission := h.transactionService.GetAdmission(); admission != nil {
+		response.Admission = dto.AdmissionMetricsToResponse(admission.Metrics())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// RebuildHandler handles the admin endpoint that rebuilds balances from the transaction log
+type RebuildHandler struct {
+	transactionService *transactionUseCase.Service
+	logger             coreport.Logger
+}
+
+// NewRebuildHandler creates a new rebuild handler instance
+func NewRebuildHandler(
+	transactionService *transactionUseCase.Service,
+	logger coreport.Logger,
+) *RebuildHandler {
+	return &RebuildHandler{
+		transactionService: transactionService,
+		logger:             logger,
+	}
+}
+
+// Rebuild handles the POST /admin/users/{userId}/rebuild endpoint
+// It is a dry run that only reports the difference unless the request sets apply.
+func (h *RebuildHandler) Rebuild(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidUserID),
+			Message: "Invalid user ID format. Must be a positive integer.",
+		})
+		return
+	}
+
+	operator, ok := authenticatedOperator(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RebuildRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	report, err := h.transactionService.RebuildBalance(c.Request.Context(), userID, transactionUseCase.RebuildRequest{
+		InitialBalance: req.InitialBalance,
+		Apply:          req.Apply,
+		Operator:       operator.Name,
+	})
+	if err != nil {
+		h.respondError(c, userID, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RebuildReportToResponse(report))
+}
+
+// respondError maps domain errors of a rebuild to HTTP responses
+func (h *RebuildHandler) respondError(c *gin.Context, userID uint64, err error) {
+	statusCode := http.StatusInternalServerError
+	errorMessage := "Internal server error"
+
+	switch {
+	case domainerr.IsUserNotFoundError(err):
+		statusCode = http.StatusNotFound
+		errorMessage = "User not found"
+	case domainerr.IsConcurrencyConflictError(err), domainerr.IsUserLockedError(err), domainerr.IsLockLostError(err):
+		statusCode = http.StatusConflict
+		errorMessage = err.Error()
+	case domainerr.IsShuttingDownError(err):
+		statusCode = http.StatusServiceUnavailable
+		errorMessage = "Service is shutting down. Please try again."
+	case errors.Is(err, domainerr.ErrInvalidRequest):
+		statusCode = http.StatusBadRequest
+		errorMessage = err.Error()
+	}
+
+	h.logger.Error("Balance rebuild failed", map[string]any{
+		"userId":     userID,
+		"statusCode": statusCode,
+		"error":      err.Error(),
+	})
+
+	c.JSON(statusCode, dto.ErrorResponse{
+		Code:    domainerr.ErrorCode(err),
+		Message: errorMessage,
+	})
+}
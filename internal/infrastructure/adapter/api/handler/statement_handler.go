@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/export"
+	"github.com/gin-gonic/gin"
+)
+
+// StatementHandler handles account statement exports
+type StatementHandler struct {
+	exporter     *statement.Exporter
+	writeTimeout time.Duration
+	logger       coreport.Logger
+}
+
+// NewStatementHandler creates a new statement handler instance
+// The server's write timeout is extended by writeTimeout each time part of a statement is sent,
+// so long statements are not cut off while they keep making progress.
+func NewStatementHandler(
+	exporter *statement.Exporter,
+	writeTimeout time.Duration,
+	logger coreport.Logger,
+) *StatementHandler {
+	return &StatementHandler{
+		exporter:     exporter,
+		writeTimeout: writeTimeout,
+		logger:       logger,
+	}
+}
+
+// GetStatement handles the GET /user/{userId}/statement endpoint
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	// Extract user ID from path
+	userIDParam := c.Param("userId")
+	userID, err := strconv.ParseUint(userIDParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidUserID),
+			Message: "Invalid user ID format",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if format != export.FormatCSV && format != export.FormatNDJSON {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+			Message: "Invalid format. Must be one of: csv, ndjson",
+		})
+		return
+	}
+
+	period, err := statement.ParsePeriod(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(err),
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Everything that can be answered with an error status happens before the first byte is sent
+	prepared, err := h.exporter.Prepare(c.Request.Context(), userID, period)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		errorMessage := "Internal server error"
+		if errors.Is(err, domainerr.ErrUserNotFound) {
+			statusCode = http.StatusNotFound
+			errorMessage = "User not found"
+		}
+
+		h.logger.Error("Error preparing statement", map[string]any{
+			"userId": userID,
+			"error":  err.Error(),
+		})
+		c.JSON(statusCode, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(err),
+			Message: errorMessage,
+		})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%d.%s"`, userID, format))
+	c.Status(http.StatusOK)
+
+	dest := &deadlineExtender{
+		ResponseWriter: c.Writer,
+		controller:     http.NewResponseController(c.Writer),
+		timeout:        h.writeTimeout,
+	}
+	dest.extend()
+	w, _ := export.NewStatementWriter(format, dest)
+	if _, err := prepared.WriteTo(c.Request.Context(), w); err != nil {
+		// The status has been sent; a statement without its closing record tells the client it is incomplete
+		h.logger.Error("Statement cut short", map[string]any{
+			"userId": userID,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// deadlineExtender pushes the write deadline back each time a statement is flushed to the client
+type deadlineExtender struct {
+	gin.ResponseWriter
+	controller *http.ResponseController
+	timeout    time.Duration
+}
+
+// Flush sends buffered output and gives the next part of the statement a fresh write timeout
+func (d *deadlineExtender) Flush() {
+	d.extend()
+	d.ResponseWriter.Flush()
+}
+
+// extend moves the write deadline to the write timeout from now
+func (d *deadlineExtender) extend() {
+	if d.timeout > 0 {
+		_ = d.controller.SetWriteDeadline(time.Now().Add(d.timeout))
+	}
+}
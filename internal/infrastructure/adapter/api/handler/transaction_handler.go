@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
 	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
@@ -75,38 +78,27 @@ func (h *TransactionHandler) ProcessTransaction(c *gin.Context) {
 		h.logger.Error("Invalid transaction request format", map[string]any{
 			"error": err.Error(),
 		})
+		// An amount the body could not be read as keeps its own error code
+		code := domainerr.ErrorCode(domainerr.ErrInvalidRequest)
+		if errors.Is(err, domainerr.ErrInvalidAmount) || errors.Is(err, domainerr.ErrAmountOverflow) {
+			code = domainerr.ErrorCode(err)
+		}
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-			Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+			Code:    code,
 			Message: "Invalid request format: " + err.Error(),
 		})
 		return
 	}
 
-	// Check if user exists
-	exists, err := h.userService.UserExists(c.Request.Context(), userID)
-	if err != nil {
-		h.logger.Error("Error checking user existence", map[string]any{
-			"userId": userID,
-			"error":  err.Error(),
-		})
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Code:    domainerr.ErrorCode(domainerr.ErrInternalServer),
-			Message: "Internal server error",
-		})
-		return
-	}
-	if !exists {
-		c.JSON(http.StatusNotFound, dto.ErrorResponse{
-			Code:    domainerr.ErrorCode(domainerr.ErrUserNotFound),
-			Message: "User not found",
-		})
+	// Check if user exists; the fast path reports unknown users itself without the extra query
+	if !h.transactionService.FastPathEnabled() && !h.checkUserExists(c, userID) {
 		return
 	}
 
 	// Map to domain request
 	transactionReq := transactionUseCase.TransactionRequest{
 		State:         req.State,
-		Amount:        req.Amount,
+		Amount:        req.Amount.String(),
 		TransactionID: req.TransactionID,
 		SourceType:    entity.SourceType(sourceType),
 	}
@@ -118,6 +110,9 @@ func (h *TransactionHandler) ProcessTransaction(c *gin.Context) {
 	if err != nil {
 		// The result already contains the right status code and error message
 		// If we've reached here, the usecase returned a result with an error
+		if result.RetryAfter > 0 {
+			c.Header("Retry-After", retryAfterSeconds(result.RetryAfter))
+		}
 		c.JSON(result.StatusCode, dto.ErrorResponse{
 			Code:    domainerr.ErrorCode(err),
 			Message: result.ErrorMessage,
@@ -125,12 +120,45 @@ func (h *TransactionHandler) ProcessTransaction(c *gin.Context) {
 		return
 	}
 
-	// Success response
-	c.JSON(http.StatusOK, dto.TransactionResponse{
+	// Success response; 202 when the transaction waits for approval
+	response := dto.TransactionResponse{
 		TransactionID: req.TransactionID,
 		UserID:        userID,
 		Success:       result.Success,
-		ResultBalance: result.ResultBalance,
+		Status:        result.Status.String(),
 		ErrorMessage:  result.ErrorMessage,
-	})
+	}
+	if result.Success {
+		response.ResultBalance = &result.ResultBalance
+	}
+	c.JSON(result.StatusCode, response)
+}
+
+// checkUserExists writes an error response and returns false unless the user exists
+func (h *TransactionHandler) checkUserExists(c *gin.Context, userID uint64) bool {
+	exists, err := h.userService.UserExists(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Error checking user existence", map[string]any{
+			"userId": userID,
+			"error":  err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrInternalServer),
+			Message: "Internal server error",
+		})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Code:    domainerr.ErrorCode(domainerr.ErrUserNotFound),
+			Message: "User not found",
+		})
+		return false
+	}
+	return true
+}
+
+// retryAfterSeconds formats a delay as a Retry-After header value, rounding up to whole seconds
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
 }
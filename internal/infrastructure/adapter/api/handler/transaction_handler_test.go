@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionHandler_RejectsUnreadableAmounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := coremocks.NewMockLogger(t)
+	logger.EXPECT().Error(mock.Anything, mock.Anything).Maybe()
+	// The body is rejected before the services are used
+	transactionHandler := NewTransactionHandler(nil, nil, logger)
+
+	router := gin.New()
+	router.POST("/user/:userId/transaction", transactionHandler.ProcessTransaction)
+
+	tests := []struct {
+		name   string
+		amount string
+		code   int
+	}{
+		{name: "Malformed amount", amount: `"1.001"`, code: domainerr.CodeInvalidAmount},
+		{name: "Negative amount", amount: `"-1.00"`, code: domainerr.CodeInvalidAmount},
+		{name: "Amount too large to hold", amount: `"99999999999999999999"`, code: domainerr.CodeAmountOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"state":"win","amount":` + tt.amount + `,"transactionId":"tx-1"}`
+			req := httptest.NewRequest(http.MethodPost, "/user/1/transaction", strings.NewReader(body))
+			req.Header.Set("Source-Type", "game")
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var response dto.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response.Code)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	domainerr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey is the gin context key of the authenticated operator
+const principalKey = "principal"
+
+// Authenticator resolves an operator from their bearer token
+type Authenticator interface {
+	Authenticate(token string) (entity.Principal, bool)
+}
+
+// AdminAuth rejects requests that do not carry an operator's token as "Authorization: Bearer <token>"
+// The operator it authenticates is available to handlers through Principal.
+func AdminAuth(authenticator Authenticator, logger coreport.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		principal, authenticated := authenticator.Authenticate(presented)
+		if !ok || !authenticated {
+			logger.Warn("Rejected admin request without a valid token", map[string]any{
+				"path":     c.Request.URL.Path,
+				"clientIP": c.ClientIP(),
+			})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Code:    domainerr.ErrorCode(domainerr.ErrInvalidRequest),
+				Message: "Missing or invalid admin token",
+			})
+			return
+		}
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// Principal returns the operator AdminAuth authenticated for the request
+func Principal(c *gin.Context) (entity.Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return entity.Principal{}, false
+	}
+	principal, ok := value.(entity.Principal)
+	return principal, ok
+}
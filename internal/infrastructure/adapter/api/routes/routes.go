@@ -12,6 +12,8 @@ func SetupRoutes(
 	router *gin.Engine,
 	transactionHandler *handler.TransactionHandler,
 	userHandler *handler.UserHandler,
+	metricsHandler *handler.MetricsHandler,
+	statementHandler *handler.StatementHandler,
 ) {
 	// User routes
 	userRoutes := router.Group("/user")
@@ -21,6 +23,55 @@ func SetupRoutes(
 
 		// POST /user/:userId/transaction
 		userRoutes.POST("/:userId/transaction", transactionHandler.ProcessTransaction)
+
+		// GET /user/:userId/statement
+		userRoutes.GET("/:userId/statement", statementHandler.GetStatement)
+	}
+
+	// GET /metrics
+	router.GET("/metrics", metricsHandler.GetMetrics)
+}
+
+// SetupAdminRoutes configures the operator endpoints, all guarded by the operators' tokens
+// The fault injection endpoints are only registered when faultHandler is not nil.
+func SetupAdminRoutes(
+	router *gin.Engine,
+	authenticator middleware.Authenticator,
+	approvalHandler *handler.ApprovalHandler,
+	rebuildHandler *handler.RebuildHandler,
+	faultHandler *handler.FaultHandler,
+	logger coreport.Logger,
+) {
+	adminRoutes := router.Group("/admin", middleware.AdminAuth(authenticator, logger))
+	{
+		// GET /admin/approvals
+		adminRoutes.GET("/approvals", approvalHandler.ListPending)
+
+		// GET /admin/approvals/:transactionId
+		adminRoutes.GET("/approvals/:transactionId", approvalHandler.Get)
+
+		// POST /admin/approvals/:transactionId/approve
+		adminRoutes.POST("/approvals/:transactionId/approve", approvalHandler.Approve)
+
+		// POST /admin/approvals/:transactionId/reject
+		adminRoutes.POST("/approvals/:transactionId/reject", approvalHandler.Reject)
+
+		// POST /admin/users/:userId/rebuild
+		adminRoutes.POST("/users/:userId/rebuild", rebuildHandler.Rebuild)
+
+		if faultHandler != nil {
+			// GET /admin/faults
+			adminRoutes.GET("/faults", faultHandler.List)
+
+			// PUT /admin/faults
+			adminRoutes.PUT("/faults", faultHandler.Replace)
+
+			// POST /admin/faults
+			adminRoutes.POST("/faults", faultHandler.Add)
+
+			// DELETE /admin/faults
+			adminRoutes.DELETE("/faults", faultHandler.Clear)
+		}
 	}
 }
 
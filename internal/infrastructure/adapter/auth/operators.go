@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+)
+
+// Operators authenticates operators by their bearer tokens
+// Only the SHA-256 of each token is configured, so the config never holds a usable secret.
+type Operators struct {
+	operators []operator
+}
+
+// operator is a configured operator and the hash of their token
+type operator struct {
+	principal entity.Principal
+	tokenHash []byte
+}
+
+// NewOperators creates an authenticator for the configured operators
+// Possible errors:
+// - an operator has no name, a name that could be mistaken for a requester, or a name used twice
+// - an operator's tokenSha256 is not a hex SHA-256, or two operators share a token
+func NewOperators(configs []config.OperatorConfig) (*Operators, error) {
+	operators := make([]operator, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	hashes := make(map[string]bool, len(configs))
+	for i, cfg := range configs {
+		name := strings.TrimSpace(cfg.Name)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("operator %d has no name", i+1)
+		case strings.Contains(name, ":"):
+			return nil, fmt.Errorf("operator %q: names cannot contain ':'", name)
+		case strings.EqualFold(name, entity.SystemActor):
+			return nil, fmt.Errorf("operator %q: the name is reserved for expiries", name)
+		case names[strings.ToLower(name)]:
+			return nil, fmt.Errorf("operator %q is configured twice", name)
+		}
+		names[strings.ToLower(name)] = true
+
+		hash, err := hex.DecodeString(strings.TrimSpace(cfg.TokenSHA256))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("operator %q: tokenSha256 must be the 64 hex digits of a SHA-256", name)
+		}
+		if hashes[string(hash)] {
+			return nil, fmt.Errorf("operator %q shares a token with another operator", name)
+		}
+		hashes[string(hash)] = true
+
+		operators = append(operators, operator{
+			principal: entity.Principal{Name: name, UserID: cfg.UserID},
+			tokenHash: hash,
+		})
+	}
+	return &Operators{operators: operators}, nil
+}
+
+// Enabled reports whether any operator is configured
+func (o *Operators) Enabled() bool {
+	return len(o.operators) > 0
+}
+
+// Authenticate returns the operator whose token was presented
+// Every operator is compared, so the time taken does not reveal which one matched.
+func (o *Operators) Authenticate(token string) (entity.Principal, bool) {
+	if token == "" {
+		return entity.Principal{}, false
+	}
+	hash := sha256.Sum256([]byte(token))
+
+	var principal entity.Principal
+	found := false
+	for _, op := range o.operators {
+		if subtle.ConstantTimeCompare(hash[:], op.tokenHash) == 1 {
+			principal = op.principal
+			found = true
+		}
+	}
+	return principal, found
+}
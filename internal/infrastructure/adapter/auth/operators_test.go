@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashToken returns the tokenSha256 of a token
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestOperators_Authenticate(t *testing.T) {
+	operators, err := NewOperators([]config.OperatorConfig{
+		{Name: "alice", TokenSHA256: hashToken("alice-token")},
+		{Name: "bob", TokenSHA256: hashToken("bob-token"), UserID: 7},
+	})
+	require.NoError(t, err)
+	assert.True(t, operators.Enabled())
+
+	principal, ok := operators.Authenticate("bob-token")
+	assert.True(t, ok)
+	assert.Equal(t, entity.Principal{Name: "bob", UserID: 7}, principal)
+
+	for _, token := range []string{"", "carol-token", hashToken("alice-token")} {
+		_, ok := operators.Authenticate(token)
+		assert.False(t, ok, token)
+	}
+}
+
+func TestNewOperators_RejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		operators []config.OperatorConfig
+	}{
+		{name: "Missing name", operators: []config.OperatorConfig{{TokenSHA256: hashToken("a")}}},
+		{name: "Requester-like name", operators: []config.OperatorConfig{{Name: "user:1", TokenSHA256: hashToken("a")}}},
+		{name: "Reserved name", operators: []config.OperatorConfig{{Name: "System", TokenSHA256: hashToken("a")}}},
+		{name: "Plain token instead of its hash", operators: []config.OperatorConfig{{Name: "alice", TokenSHA256: "alice-token"}}},
+		{name: "Duplicate name", operators: []config.OperatorConfig{
+			{Name: "alice", TokenSHA256: hashToken("a")},
+			{Name: "Alice", TokenSHA256: hashToken("b")},
+		}},
+		{name: "Shared token", operators: []config.OperatorConfig{
+			{Name: "alice", TokenSHA256: hashToken("a")},
+			{Name: "bob", TokenSHA256: hashToken("a")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOperators(tt.operators)
+			assert.Error(t, err)
+		})
+	}
+
+	operators, err := NewOperators(nil)
+	require.NoError(t, err)
+	assert.False(t, operators.Enabled())
+}
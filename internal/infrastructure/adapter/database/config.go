@@ -5,10 +5,29 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Supported database drivers
+const (
+	// DriverPostgres selects the PostgreSQL adapter (default)
+	DriverPostgres = "postgres"
+	// DriverSQLite selects the embedded SQLite adapter for single-node deployments
+	DriverSQLite = "sqlite"
+)
+
+// Supported user lock strategies
+const (
+	// LockStrategyTable keeps user locks as rows in the user_locks table (default)
+	LockStrategyTable = "table"
+	// LockStrategyAdvisory uses PostgreSQL transaction-scoped advisory locks
+	LockStrategyAdvisory = "advisory"
+)
+
 // Config represents database configuration
+// For the sqlite driver, Database holds the database file path (or ":memory:")
+// and the network settings (host, port, credentials, SSL mode) are ignored
 type Config struct {
 	Driver          string        `mapstructure:"db_driver"`
 	Host            string        `mapstructure:"db_host"`
@@ -26,6 +45,8 @@ type Config struct {
 	LogLevel        string        `mapstructure:"db_log_level"`
 	RetryAttempts   int           `mapstructure:"db_retry_attempts"`
 	RetryDelay      int           `mapstructure:"db_retry_delay"`
+	LockStrategy    string        `mapstructure:"db_lock_strategy"`     // table or advisory; ignored for sqlite
+	LockWaitTimeout time.Duration `mapstructure:"db_lock_wait_timeout"` // advisory only; 0 fails fast when locked
 }
 
 // DefaultConfig returns a Config with default values
@@ -48,6 +69,8 @@ func DefaultConfig() *Config {
 		LogLevel:        configEnvOrDefault("BP_LOGGER_LEVEL", "info"),
 		RetryAttempts:   configEnvAsInt("BP_DB_RETRY_ATTEMPTS", 5),       // Increased from 3
 		RetryDelay:      configEnvAsInt("BP_DB_RETRY_DELAY_SECONDS", 2),  // Decreased from 5 for faster retries
+		LockStrategy:    configEnvOrDefault("BP_DB_LOCK_STRATEGY", LockStrategyTable),
+		LockWaitTimeout: time.Duration(configEnvAsInt("BP_DB_LOCK_WAIT_TIMEOUT_MS", 0)) * time.Millisecond,
 	}
 
 	return config
@@ -55,6 +78,10 @@ func DefaultConfig() *Config {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
+	if c.Driver == DriverSQLite {
+		return c.validateSQLite()
+	}
+
 	if c.Host == "" {
 		return errors.New("database host is required")
 	}
@@ -72,9 +99,8 @@ func (c *Config) Validate() error {
 	}
 
 	validDrivers := map[string]bool{
-		"postgres": true,
-		"mysql":    true,
-		"sqlite":   true,
+		DriverPostgres: true,
+		DriverSQLite:   true,
 	}
 	if !validDrivers[c.Driver] {
 		return fmt.Errorf("unsupported database driver: %s", c.Driver)
@@ -91,6 +117,15 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid SSL mode: %s", c.SSLMode)
 	}
 
+	switch c.LockStrategy {
+	case "", LockStrategyTable, LockStrategyAdvisory:
+	default:
+		return fmt.Errorf("invalid lock strategy: %s", c.LockStrategy)
+	}
+	if c.LockWaitTimeout < 0 {
+		return errors.New("lock wait timeout must be non-negative")
+	}
+
 	if c.MaxOpenConns <= 0 {
 		return fmt.Errorf("max open connections must be positive, got: %d", c.MaxOpenConns)
 	}
@@ -120,14 +155,65 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateSQLite checks the subset of settings that apply to the sqlite driver
+func (c *Config) validateSQLite() error {
+	if c.Database == "" {
+		return errors.New("database file path is required for the sqlite driver")
+	}
+	if c.MaxOpenConns <= 0 {
+		return fmt.Errorf("max open connections must be positive, got: %d", c.MaxOpenConns)
+	}
+	if c.QueryTimeout <= 0 {
+		return errors.New("query timeout must be positive")
+	}
+	if c.LockStrategy == LockStrategyAdvisory {
+		return errors.New("advisory lock strategy requires the postgres driver")
+	}
+	return nil
+}
+
 // DSN returns the database connection string
 func (c *Config) DSN() string {
+	if c.Driver == DriverSQLite {
+		return c.sqliteDSN()
+	}
+
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.Username, c.Password, c.Database, c.SSLMode,
 	)
 }
 
+// sqliteDSN builds the connection string for the sqlite driver
+// Transactions are started with BEGIN IMMEDIATE so the write lock is taken up front,
+// which gives the same serial execution guarantees as SERIALIZABLE in PostgreSQL.
+// WAL mode lets readers proceed while a writer holds the lock, and busy_timeout makes
+// writers wait for the lock instead of failing immediately with SQLITE_BUSY.
+func (c *Config) sqliteDSN() string {
+	path := c.Database
+	if path == ":memory:" {
+		// A shared cache keeps a single in-memory database across pooled connections
+		path = "file::memory:?cache=shared"
+	} else if !strings.HasPrefix(path, "file:") {
+		path = "file:" + path
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	busyTimeout := c.QueryTimeout.Milliseconds()
+	if busyTimeout <= 0 {
+		busyTimeout = 5000
+	}
+
+	return fmt.Sprintf(
+		"%s%s_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate",
+		path, separator, busyTimeout,
+	)
+}
+
 // WithMaxOpenConnections returns a copy of the config with updated max open connections
 func (c *Config) WithMaxOpenConnections(max int) *Config {
 	newConfig := *c
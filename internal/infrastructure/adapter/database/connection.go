@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -42,7 +41,11 @@ func NewConnection(config *Config) (*Connection, error) {
 	}
 
 	// Connect to database
-	db, err := gorm.Open(postgres.Open(config.DSN()), gormConfig)
+	dialector, err := newDialector(config)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -8,7 +8,7 @@ import (
 	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
 	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database/migration"
-	"gorm.io/driver/postgres"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
 	"gorm.io/gorm"
 )
 
@@ -56,22 +56,20 @@ func (m *Manager) Connect() (*gorm.DB, error) {
 			time.Sleep(time.Duration(m.config.RetryDelay) * time.Second)
 		}
 
-		// Get DSN for the selected driver
-		dsn := m.getDSN()
+		// Resolve the dialector for the selected driver
+		dialector, dialectErr := newDialector(m.config)
+		if dialectErr != nil {
+			return nil, dialectErr
+		}
 
 		// Connect with gorm
-		switch m.config.Driver {
-		case "postgres":
-			gormDB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-				Logger: NewGormDatabaseLogger(m.logger),
-				NowFunc: func() time.Time {
-					return m.timeProvider.Now()
-				},
-				PrepareStmt: true, // Prepare statements for better performance
-			})
-		default:
-			return nil, fmt.Errorf("unsupported database driver: %s", m.config.Driver)
-		}
+		gormDB, err = gorm.Open(dialector, &gorm.Config{
+			Logger: NewGormDatabaseLogger(m.logger),
+			NowFunc: func() time.Time {
+				return m.timeProvider.Now()
+			},
+			PrepareStmt: true, // Prepare statements for better performance
+		})
 
 		if err == nil {
 			break
@@ -157,11 +155,47 @@ func (m *Manager) WithTimeout(ctx context.Context) (context.Context, context.Can
 	return context.WithTimeout(ctx, m.config.QueryTimeout)
 }
 
-// CreateUnitOfWork creates a new UnitOfWork instance
+// CreateUnitOfWork creates a new UnitOfWork instance for the configured driver
 func (m *Manager) CreateUnitOfWork() persistence.UnitOfWork {
+	if m.config.Driver == DriverSQLite {
+		return NewSQLiteUnitOfWork(m.db, m.logger, m.timeProvider)
+	}
 	return NewUnitOfWork(m.db, m.logger, m.timeProvider)
 }
 
+// CreateUserLockRepository creates a UserLockRepository for the configured driver and lock strategy
+func (m *Manager) CreateUserLockRepository() persistence.UserLockRepository {
+	if m.config.Driver == DriverSQLite {
+		return repository.NewSQLiteUserLockRepository(m.db, m.timeProvider, m.logger)
+	}
+	if m.config.LockStrategy == LockStrategyAdvisory {
+		return repository.NewAdvisoryUserLockRepository(m.db, m.logger, m.config.LockWaitTimeout)
+	}
+	return repository.NewUserLockRepository(m.db, m.timeProvider, m.logger)
+}
+
+// CreateUserLockAdminRepository creates the UserLockAdminRepository matching CreateUserLockRepository
+func (m *Manager) CreateUserLockAdminRepository() persistence.UserLockAdminRepository {
+	if m.config.Driver != DriverSQLite && m.config.LockStrategy == LockStrategyAdvisory {
+		return repository.NewAdvisoryUserLockAdminRepository(m.db, m.logger)
+	}
+	return repository.NewUserLockAdminRepository(m.db, m.logger)
+}
+
+// CreateJobLockRepository creates the JobLockRepository that elects scheduled job runners
+// PostgreSQL deployments may run several instances, so they elect through advisory locks
+func (m *Manager) CreateJobLockRepository() persistence.JobLockRepository {
+	if m.config.Driver == DriverSQLite {
+		return repository.NewLocalJobLockRepository()
+	}
+	return repository.NewAdvisoryJobLockRepository(m.db, m.logger)
+}
+
+// Driver returns the configured database driver name
+func (m *Manager) Driver() string {
+	return m.config.Driver
+}
+
 // GetErrorMapper returns the error mapper
 func (m *Manager) GetErrorMapper() *ErrorMapper {
 	return m.errorMapper
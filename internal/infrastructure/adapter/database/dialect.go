@@ -0,0 +1,22 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newDialector returns the GORM dialector for the configured driver
+// The sqlite driver is a pure Go implementation, so builds with CGO_ENABLED=0 keep working
+func newDialector(config *Config) (gorm.Dialector, error) {
+	switch config.Driver {
+	case DriverPostgres:
+		return postgres.Open(config.DSN()), nil
+	case DriverSQLite:
+		return sqlite.Open(config.DSN()), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", config.Driver)
+	}
+}
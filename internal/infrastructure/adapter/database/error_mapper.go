@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	domainErr "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
 	"gorm.io/gorm"
 )
 
@@ -40,6 +41,11 @@ func (m *ErrorMapper) MapError(err error, operation string) error {
 		return domainErr.ErrUserNotFound
 	}
 
+	// Serialization failures and deadlocks are identified by SQLSTATE and can be retried
+	if repository.NewErrorClassifier().IsConcurrencyConflict(err) {
+		return domainErr.ErrConcurrencyConflict
+	}
+
 	// Check for PostgreSQL specific errors
 	errMsg := strings.ToLower(err.Error())
 
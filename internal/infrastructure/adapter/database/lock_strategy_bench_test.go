@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
+	timeprovider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
+)
+
+// BenchmarkUserLockStrategies compares the table-based and advisory user lock repositories
+// It needs a PostgreSQL test database and is skipped unless TEST_DB_HOST is set:
+//
+//	TEST_DB_HOST=localhost go test -run '^$' -bench UserLockStrategies ./internal/infrastructure/adapter/database/
+func BenchmarkUserLockStrategies(b *testing.B) {
+	if os.Getenv("TEST_DB_HOST") == "" {
+		b.Skip("TEST_DB_HOST not set; skipping PostgreSQL lock benchmark")
+	}
+
+	appLogger := logger.NewNoopLogger()
+	tp := timeprovider.NewRealTimeProvider()
+
+	config := testConfigFromEnv()
+	config.MaxOpenConns = 50
+	config.MaxIdleConns = 50
+	manager := NewManager(config, appLogger, tp)
+	db, err := manager.Connect()
+	if err != nil {
+		b.Fatalf("Failed to connect to test database: %v", err)
+	}
+	defer manager.Close()
+
+	if err := db.AutoMigrate(&model.UserLock{}); err != nil {
+		b.Fatalf("Failed to create user_locks table: %v", err)
+	}
+
+	strategies := []struct {
+		name string
+		repo persistence.UserLockRepository
+	}{
+		{LockStrategyTable, repository.NewUserLockRepository(db, tp, appLogger)},
+		{LockStrategyAdvisory, repository.NewAdvisoryUserLockRepository(db, appLogger, 0)},
+	}
+
+	for _, strategy := range strategies {
+		if err := db.Exec("TRUNCATE TABLE user_locks").Error; err != nil {
+			b.Fatalf("Failed to truncate user_locks: %v", err)
+		}
+
+		// Every iteration locks a different user, so only the cost of a lock round trip is measured
+		b.Run(strategy.name+"/distinct_users", func(b *testing.B) {
+			var nextUser atomic.Uint64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				for pb.Next() {
+					userID := nextUser.Add(1)
+					token, err := strategy.repo.AcquireLock(ctx, userID, 5*time.Second)
+					if err != nil {
+						b.Errorf("AcquireLock(%d): %v", userID, err)
+						return
+					}
+					if err := strategy.repo.ReleaseLock(ctx, userID, token); err != nil {
+						b.Errorf("ReleaseLock(%d): %v", userID, err)
+						return
+					}
+				}
+			})
+		})
+
+		// All goroutines compete for one user; contended attempts are reported separately
+		b.Run(strategy.name+"/hot_user", func(b *testing.B) {
+			const hotUserID = 1 << 40
+			var contended atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				for pb.Next() {
+					token, err := strategy.repo.AcquireLock(ctx, hotUserID, 5*time.Second)
+					if errors.Is(err, errs.ErrUserLocked) {
+						contended.Add(1)
+						continue
+					}
+					if err != nil {
+						b.Errorf("AcquireLock: %v", err)
+						return
+					}
+					if err := strategy.repo.ReleaseLock(ctx, hotUserID, token); err != nil {
+						b.Errorf("ReleaseLock: %v", err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(contended.Load())/float64(b.N), "contended/op")
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package migration
+
+// approvalAudit adds the audit trail of transactions held for approval, and an index
+// for finding the transactions that still wait for it
+var approvalAudit = Migration{
+	ID:   "0003",
+	Name: "add_approval_audit",
+	Up: Statements{
+		Postgres: []string{
+			`CREATE TABLE IF NOT EXISTS approval_audit (
+				id BIGSERIAL PRIMARY KEY,
+				transaction_id VARCHAR(255) NOT NULL,
+				user_id BIGINT NOT NULL,
+				action VARCHAR(20) NOT NULL,
+				actor VARCHAR(255) NOT NULL,
+				note TEXT,
+				created_at TIMESTAMPTZ NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_approval_audit_transaction_id ON approval_audit (transaction_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_pending_approval ON transactions (id) WHERE status = 'pending_approval'`,
+		},
+		SQLite: []string{
+			"CREATE TABLE IF NOT EXISTS `approval_audit` (`id` integer PRIMARY KEY AUTOINCREMENT,`transaction_id` text NOT NULL,`user_id` integer NOT NULL,`action` text NOT NULL,`actor` text NOT NULL,`note` text,`created_at` datetime NOT NULL)",
+			"CREATE INDEX IF NOT EXISTS idx_approval_audit_transaction_id ON approval_audit (transaction_id)",
+			"CREATE INDEX IF NOT EXISTS idx_transactions_pending_approval ON transactions (id) WHERE status = 'pending_approval'",
+		},
+	},
+	Down: Statements{
+		Postgres: []string{
+			`DROP INDEX IF EXISTS idx_transactions_pending_approval`,
+			`DROP TABLE IF EXISTS approval_audit`,
+		},
+		SQLite: []string{
+			"DROP INDEX IF EXISTS idx_transactions_pending_approval",
+			"DROP TABLE IF EXISTS approval_audit",
+		},
+	},
+}
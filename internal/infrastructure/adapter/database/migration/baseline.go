@@ -0,0 +1,99 @@
+package migration
+
+// baselineSchema creates the schema as it stood at the last release that migrated with AutoMigrate
+// Every statement is idempotent, so databases created by those releases adopt it without changes.
+// On PostgreSQL it also carries the upgrades of the old version-to-version path: timestamp and
+// token columns on user_locks, and dropping locks taken before tokens existed.
+var baselineSchema = Migration{
+	ID:   "0001",
+	Name: "baseline_schema",
+	Up: Statements{
+		Postgres: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id BIGSERIAL PRIMARY KEY,
+				balance BIGINT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL,
+				transaction_count BIGINT DEFAULT 0
+			)`,
+			`CREATE TABLE IF NOT EXISTS user_locks (
+				user_id BIGINT PRIMARY KEY,
+				token VARCHAR(64) NOT NULL DEFAULT '',
+				locked_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`ALTER TABLE user_locks ADD COLUMN IF NOT EXISTS token VARCHAR(64) NOT NULL DEFAULT ''`,
+			`ALTER TABLE user_locks ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+			`ALTER TABLE user_locks ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+			`DELETE FROM user_locks WHERE token = ''`,
+			`CREATE TABLE IF NOT EXISTS transactions (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL,
+				transaction_id VARCHAR(255) NOT NULL,
+				source_type VARCHAR(50) NOT NULL,
+				state VARCHAR(50) NOT NULL,
+				amount VARCHAR(50) NOT NULL,
+				amount_in_cents BIGINT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL,
+				processed_at TIMESTAMPTZ,
+				result_balance VARCHAR(50),
+				status VARCHAR(50) NOT NULL,
+				error_message TEXT,
+				CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS job_runs (
+				id BIGSERIAL PRIMARY KEY,
+				job_name VARCHAR(100) NOT NULL,
+				scheduled_at TIMESTAMPTZ NOT NULL,
+				started_at TIMESTAMPTZ NOT NULL,
+				finished_at TIMESTAMPTZ,
+				status VARCHAR(20) NOT NULL,
+				error TEXT,
+				instance VARCHAR(255)
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_job_slot ON job_runs (job_name, scheduled_at)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions (transaction_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id_unique ON transactions (transaction_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions (user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_user_state ON transactions (user_id, state)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_successful ON transactions (user_id, created_at) WHERE status = 'success'`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_created_at_brin ON transactions USING BRIN (created_at) WITH (pages_per_range = 32)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_source_type ON transactions (source_type)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_locks_user_id ON user_locks (user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_user_locks_expires_at ON user_locks (expires_at)`,
+			// Leave room on each page for HOT updates and give the planner detailed statistics on user_id
+			`ALTER TABLE transactions SET (fillfactor = 90)`,
+			`ALTER TABLE transactions ALTER COLUMN user_id SET STATISTICS 1000`,
+		},
+		SQLite: []string{
+			"CREATE TABLE IF NOT EXISTS `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`balance` integer NOT NULL,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL,`transaction_count` integer DEFAULT 0)",
+			"CREATE TABLE IF NOT EXISTS `user_locks` (`user_id` integer PRIMARY KEY AUTOINCREMENT NOT NULL,`token` text NOT NULL DEFAULT \"\",`locked_at` datetime NOT NULL,`expires_at` datetime NOT NULL,`created_at` datetime NOT NULL,`updated_at` datetime NOT NULL)",
+			"CREATE TABLE IF NOT EXISTS `transactions` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`transaction_id` text NOT NULL,`source_type` text NOT NULL,`state` text NOT NULL,`amount` text NOT NULL,`amount_in_cents` integer NOT NULL,`created_at` datetime NOT NULL,`processed_at` datetime,`result_balance` text,`status` text NOT NULL,`error_message` text,CONSTRAINT `fk_transactions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`))",
+			"CREATE TABLE IF NOT EXISTS `job_runs` (`id` integer PRIMARY KEY AUTOINCREMENT,`job_name` text NOT NULL,`scheduled_at` datetime NOT NULL,`started_at` datetime NOT NULL,`finished_at` datetime,`status` text NOT NULL,`error` text,`instance` text)",
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_job_slot ON job_runs (job_name, scheduled_at)",
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions (transaction_id)",
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id_unique ON transactions (transaction_id)",
+			"CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions (user_id)",
+			"CREATE INDEX IF NOT EXISTS idx_transactions_user_state ON transactions (user_id, state)",
+			"CREATE INDEX IF NOT EXISTS idx_transactions_user_created_at ON transactions (user_id, created_at)",
+			"CREATE INDEX IF NOT EXISTS idx_transactions_source_type ON transactions (source_type)",
+			"CREATE INDEX IF NOT EXISTS idx_user_locks_expires_at ON user_locks (expires_at)",
+		},
+	},
+	Down: Statements{
+		Postgres: []string{
+			`DROP TABLE IF EXISTS job_runs`,
+			`DROP TABLE IF EXISTS transactions`,
+			`DROP TABLE IF EXISTS user_locks`,
+			`DROP TABLE IF EXISTS users`,
+		},
+		SQLite: []string{
+			"DROP TABLE IF EXISTS job_runs",
+			"DROP TABLE IF EXISTS transactions",
+			"DROP TABLE IF EXISTS user_locks",
+			"DROP TABLE IF EXISTS users",
+		},
+	},
+}
@@ -3,6 +3,9 @@ package migration
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
@@ -10,278 +13,405 @@ import (
 	"gorm.io/gorm"
 )
 
+// Advisory lock held on PostgreSQL while a migration is applied or reverted, so that
+// instances starting together do not apply the same migration twice
+// The two-int key form keeps it apart from the single-bigint user locks
 const (
-	// CurrentSchemaVersion represents the current database schema version
-	CurrentSchemaVersion = "1.0.1"
+	migrationLockClass = 0x4D494752 // "MIGR"
+	migrationLockKey   = 0
 )
 
+// ErrChecksumMismatch is returned when an applied migration's SQL has changed since it was applied
+var ErrChecksumMismatch = errors.New("applied migration does not match its checksum")
+
+// Direction selects whether a plan applies or reverts migrations
+type Direction string
+
+// Migration directions
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Migration states reported by Status
+const (
+	StatePending  = "pending"
+	StateApplied  = "applied"
+	StateModified = "modified" // Applied, but its SQL has changed since
+	StateUnknown  = "unknown"  // Recorded in the database but not in this build's registry
+)
+
+// MigrationStatus describes one migration and whether it is applied
+type MigrationStatus struct {
+	ID        string
+	Name      string
+	State     string
+	AppliedAt *time.Time
+	Checksum  string // Checksum recorded when the migration was applied; empty if pending
+}
+
+// Step is a migration together with the SQL that applying or reverting it runs
+type Step struct {
+	Migration Migration
+	Direction Direction
+	SQL       []string
+}
+
 // MigrationManager manages database migrations
 type MigrationManager struct {
-	db               *gorm.DB
-	logger           coreport.Logger
-	timeProvider     coreport.TimeProvider
-	advancedIndexMgr *AdvancedIndexManager
+	db           *gorm.DB
+	logger       coreport.Logger
+	timeProvider coreport.TimeProvider
+	migrations   []Migration
 }
 
 // NewMigrationManager creates a new migration manager
 func NewMigrationManager(db *gorm.DB, logger coreport.Logger) *MigrationManager {
 	return &MigrationManager{
-		db:               db,
-		logger:           logger,
-		advancedIndexMgr: NewAdvancedIndexManager(db, logger),
+		db:         db,
+		logger:     logger,
+		migrations: Migrations(),
 	}
 }
 
 // NewMigrationManagerWithTimeProvider creates a new migration manager with time provider
 func NewMigrationManagerWithTimeProvider(db *gorm.DB, logger coreport.Logger, timeProvider coreport.TimeProvider) *MigrationManager {
-	return &MigrationManager{
-		db:               db,
-		logger:           logger,
-		timeProvider:     timeProvider,
-		advancedIndexMgr: NewAdvancedIndexManager(db, logger),
-	}
+	m := NewMigrationManager(db, logger)
+	m.timeProvider = timeProvider
+	return m
 }
 
-// MigrateAll performs all migrations
+// MigrateAll applies every pending migration
 func (m *MigrationManager) MigrateAll() error {
-	m.logger.Info("Starting database migrations", map[string]any{
-		"target_version": CurrentSchemaVersion,
-	})
+	_, err := m.Up(context.Background())
+	return err
+}
 
-	// Create migration version table first
-	if err := m.db.AutoMigrate(&model.MigrationVersion{}); err != nil {
-		m.logger.Error("Failed to create migration version table", map[string]any{
-			"error": err.Error(),
-		})
-		return err
+// Up applies every pending migration in order and returns the ones it applied
+// Nothing is applied if an already applied migration fails verification.
+func (m *MigrationManager) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
 	}
-
-	// Check current version
-	currentVersion, err := m.GetCurrentVersion(context.Background())
+	plan, err := m.Plan(ctx, DirectionUp, 0)
 	if err != nil {
-		m.logger.Error("Failed to check current schema version", map[string]any{
-			"error": err.Error(),
-		})
-		return err
-	}
-
-	if currentVersion == CurrentSchemaVersion {
-		m.logger.Info("Database already at target version, skipping migration", map[string]any{
-			"version": currentVersion,
-		})
-		return nil
+		return nil, err
 	}
 
-	m.logger.Info("Current database version", map[string]any{
-		"version": currentVersion,
+	m.logger.Info("Starting database migrations", map[string]any{
+		"pending": len(plan),
+		"dialect": m.Dialect(),
 	})
 
-	// Auto-migrate models
-	if err := m.autoMigrateModels(); err != nil {
-		m.logger.Error("Failed to auto-migrate models", map[string]any{
-			"error": err.Error(),
-		})
-		return err
+	applied := make([]Migration, 0, len(plan))
+	for _, step := range plan {
+		ran, err := m.apply(ctx, step)
+		if err != nil {
+			m.logger.Error("Failed to apply migration", map[string]any{
+				"migration": step.Migration.String(),
+				"error":     err.Error(),
+			})
+			return applied, fmt.Errorf("apply migration %s: %w", step.Migration, err)
+		}
+		if ran {
+			applied = append(applied, step.Migration)
+		}
 	}
 
-	// Run custom migrations based on version
-	if err := m.runVersionedMigrations(currentVersion); err != nil {
-		m.logger.Error("Failed to run versioned migrations", map[string]any{
-			"error":           err.Error(),
-			"current_version": currentVersion,
-			"target_version":  CurrentSchemaVersion,
-		})
-		return err
-	}
+	m.logger.Info("Database migrations completed successfully", map[string]any{
+		"applied": len(applied),
+		"version": m.latestID(),
+	})
+	return applied, nil
+}
 
-	// Create basic indexes
-	if err := m.createIndexes(); err != nil {
-		m.logger.Error("Failed to create indexes", map[string]any{
-			"error": err.Error(),
-		})
-		return err
+// Down reverts the n most recently applied migrations and returns the ones it reverted
+func (m *MigrationManager) Down(ctx context.Context, n int) ([]Migration, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
 	}
-
-	// Create advanced PostgreSQL indexes for better performance
-	if err := m.createAdvancedIndexes(); err != nil {
-		m.logger.Error("Failed to create advanced indexes", map[string]any{
-			"error": err.Error(),
-		})
-		return err
+	plan, err := m.Plan(ctx, DirectionDown, n)
+	if err != nil {
+		return nil, err
 	}
 
-	// Apply performance tweaks
-	if err := m.applyPerformanceTweaks(); err != nil {
-		m.logger.Error("Failed to apply performance tweaks", map[string]any{
-			"error": err.Error(),
-		})
-		return err
+	reverted := make([]Migration, 0, len(plan))
+	for _, step := range plan {
+		ran, err := m.apply(ctx, step)
+		if err != nil {
+			m.logger.Error("Failed to revert migration", map[string]any{
+				"migration": step.Migration.String(),
+				"error":     err.Error(),
+			})
+			return reverted, fmt.Errorf("revert migration %s: %w", step.Migration, err)
+		}
+		if ran {
+			reverted = append(reverted, step.Migration)
+		}
 	}
+	return reverted, nil
+}
 
-	// Update migration version
-	if err := m.setVersion(context.Background(), CurrentSchemaVersion, "Full schema migration"); err != nil {
-		m.logger.Error("Failed to update schema version", map[string]any{
-			"error":   err.Error(),
-			"version": CurrentSchemaVersion,
-		})
-		return err
+// Plan returns the steps Up (direction up) or Down (direction down, n migrations) would run
+// It fails with ErrChecksumMismatch if an applied migration it depends on has been modified.
+func (m *MigrationManager) Plan(ctx context.Context, direction Direction, n int) ([]Step, error) {
+	if err := validateRegistry(m.migrations); err != nil {
+		return nil, err
 	}
 
-	m.logger.Info("Database migrations completed successfully", map[string]any{
-		"version": CurrentSchemaVersion,
-	})
-	return nil
-}
-
-// GetCurrentVersion gets the current migration version
-func (m *MigrationManager) GetCurrentVersion(ctx context.Context) (string, error) {
-	if ctx.Err() != nil {
-		return "", ctx.Err()
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
 	}
+	dialect := m.Dialect()
 
-	var version model.MigrationVersion
-	result := m.db.WithContext(ctx).Order("applied_at desc").First(&version)
+	var plan []Step
+	switch direction {
+	case DirectionUp:
+		if err := m.verifyApplied(applied); err != nil {
+			return nil, err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.ID]; !ok {
+				plan = append(plan, Step{Migration: mig, Direction: DirectionUp, SQL: mig.Up.For(dialect)})
+			}
+		}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return "", nil // No version found
+	case DirectionDown:
+		if n < 0 {
+			return nil, fmt.Errorf("cannot revert %d migrations", n)
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(plan) < n; i-- {
+			mig := m.migrations[i]
+			version, ok := applied[mig.ID]
+			if !ok {
+				continue
+			}
+			// Reverting a modified migration would run down SQL that no longer matches what was applied
+			if version.Checksum != mig.Checksum(dialect) {
+				return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+			}
+			plan = append(plan, Step{Migration: mig, Direction: DirectionDown, SQL: mig.Down.For(dialect)})
 		}
-		return "", result.Error
+
+	default:
+		return nil, fmt.Errorf("unknown migration direction: %s", direction)
 	}
 
-	return version.Version, nil
+	return plan, nil
 }
 
-// setVersion records a new migration version
-func (m *MigrationManager) setVersion(ctx context.Context, version string, details string) error {
-	var appliedAt time.Time
-	if m.timeProvider != nil {
-		appliedAt = m.timeProvider.Now()
-	} else {
-		appliedAt = time.Now()
+// Status returns every registered migration with its state, followed by any migrations
+// recorded in the database that this build does not know
+func (m *MigrationManager) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dialect := m.Dialect()
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	known := make(map[string]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.ID] = true
+		status := MigrationStatus{ID: mig.ID, Name: mig.Name, State: StatePending}
+		if version, ok := applied[mig.ID]; ok {
+			appliedAt := version.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Checksum = version.Checksum
+			status.State = StateApplied
+			if version.Checksum != mig.Checksum(dialect) {
+				status.State = StateModified
+			}
+		}
+		statuses = append(statuses, status)
 	}
 
-	migrationVersion := model.MigrationVersion{
-		Version:   version,
-		AppliedAt: appliedAt,
-		Details:   details,
+	for _, id := range sortedIDs(applied) {
+		if known[id] {
+			continue
+		}
+		version := applied[id]
+		appliedAt := version.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			ID:        id,
+			Name:      version.Details,
+			State:     StateUnknown,
+			AppliedAt: &appliedAt,
+			Checksum:  version.Checksum,
+		})
 	}
 
-	result := m.db.WithContext(ctx).Create(&migrationVersion)
-	return result.Error
+	return statuses, nil
 }
 
-// autoMigrateModels auto-migrates database models
-func (m *MigrationManager) autoMigrateModels() error {
-	m.logger.Info("Auto-migrating database models", nil)
-
-	// Auto-migrate all models
-	return m.db.AutoMigrate(
-		&model.User{},
-		&model.UserLock{},
-		&model.Transaction{},
-	)
+// Verify checks every applied migration against the checksum recorded when it was applied
+// Migrations recorded in the database but missing from this build are reported as well, since
+// the build could neither revert them nor vouch for the schema they produced.
+func (m *MigrationManager) Verify(ctx context.Context) error {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+	return m.verifyApplied(applied)
 }
 
-// runVersionedMigrations runs migrations specific to version transitions
-func (m *MigrationManager) runVersionedMigrations(currentVersion string) error {
-	m.logger.Info("Running versioned migrations", map[string]any{
-		"from": currentVersion,
-		"to":   CurrentSchemaVersion,
-	})
+// verifyApplied reports applied migrations that were modified or are not registered
+func (m *MigrationManager) verifyApplied(applied map[string]model.MigrationVersion) error {
+	dialect := m.Dialect()
+	known := make(map[string]bool, len(m.migrations))
 
-	// If starting from scratch
-	if currentVersion == "" {
-		return m.runBaseMigrations()
-	}
-
-	// Apply migrations based on current version
-	switch currentVersion {
-	case "0.9.0":
-		if err := m.migrateFrom0_9_0To1_0_0(); err != nil {
-			return err
-		}
-		fallthrough
-	case "0.9.5":
-		if err := m.migrateFrom0_9_5To1_0_0(); err != nil {
-			return err
+	var modified []string
+	for _, mig := range m.migrations {
+		known[mig.ID] = true
+		if version, ok := applied[mig.ID]; ok && version.Checksum != mig.Checksum(dialect) {
+			modified = append(modified, mig.String())
 		}
-		fallthrough
-	case "1.0.0":
-		if err := m.migrateFrom1_0_0To1_0_1(); err != nil {
-			return err
+	}
+	var unknown []string
+	for _, id := range sortedIDs(applied) {
+		if !known[id] {
+			unknown = append(unknown, id)
 		}
 	}
 
+	switch {
+	case len(modified) > 0:
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	case len(unknown) > 0:
+		return fmt.Errorf("database has migrations this build does not know: %s", strings.Join(unknown, ", "))
+	}
 	return nil
 }
 
-// runBaseMigrations runs the base migrations for a new database
-func (m *MigrationManager) runBaseMigrations() error {
-	m.logger.Info("Running base migrations", nil)
-
-	// Add any SQL that needs to run for fresh migrations
-	// Example: setting up sequences, special constraints, etc.
+// GetCurrentVersion returns the ID of the latest applied migration, or "" if none is applied
+func (m *MigrationManager) GetCurrentVersion(ctx context.Context) (string, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return "", err
+	}
 
-	return nil
+	current := ""
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.ID]; ok {
+			current = mig.ID
+		}
+	}
+	return current, nil
 }
 
-// migrateFrom0_9_0To1_0_0 migrates from version 0.9.0 to 1.0.0
-func (m *MigrationManager) migrateFrom0_9_0To1_0_0() error {
-	m.logger.Info("Migrating from v0.9.0 to v1.0.0", nil)
-
-	// Add migration steps specific to this version transition
-	// Example: Adding columns, changing types, etc.
-
-	return nil
-}
+// apply runs one step and records it in a single database transaction
+// It reports false if another instance ran the step first.
+func (m *MigrationManager) apply(ctx context.Context, step Step) (bool, error) {
+	ran := false
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if !m.isSQLite() {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", migrationLockClass, migrationLockKey).Error; err != nil {
+				return fmt.Errorf("lock migrations: %w", err)
+			}
+		}
 
-// migrateFrom0_9_5To1_0_0 migrates from version 0.9.5 to 1.0.0
-func (m *MigrationManager) migrateFrom0_9_5To1_0_0() error {
-	m.logger.Info("Migrating from v0.9.5 to v1.0.0", nil)
+		// Re-check under the lock
+		var count int64
+		if err := tx.Model(&model.MigrationVersion{}).Where("version = ?", step.Migration.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if (step.Direction == DirectionUp) == (count > 0) {
+			return nil
+		}
 
-	// Add migration steps specific to this version transition
+		m.logger.Info("Running migration", map[string]any{
+			"migration": step.Migration.String(),
+			"direction": string(step.Direction),
+		})
+		for _, stmt := range step.SQL {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
 
-	return nil
+		ran = true
+		if step.Direction == DirectionDown {
+			return tx.Unscoped().Where("version = ?", step.Migration.ID).Delete(&model.MigrationVersion{}).Error
+		}
+		return tx.Create(&model.MigrationVersion{
+			Version:   step.Migration.ID,
+			AppliedAt: m.now(),
+			Details:   step.Migration.Name,
+			Checksum:  step.Migration.Checksum(m.Dialect()),
+		}).Error
+	})
+	return ran, err
 }
 
-// migrateFrom1_0_0To1_0_1 migrates from version 1.0.0 to 1.0.1
-func (m *MigrationManager) migrateFrom1_0_0To1_0_1() error {
-	m.logger.Info("Migrating from v1.0.0 to v1.0.1", nil)
+// appliedVersions returns the applied migrations recorded in migration_versions, by ID
+// Rows written by the releases before versioned migrations carry no checksum; they name a
+// schema version rather than a migration and are ignored. Nothing is written, so status
+// checks and dry runs leave the database untouched.
+func (m *MigrationManager) appliedVersions(ctx context.Context) (map[string]model.MigrationVersion, error) {
+	migrator := m.db.WithContext(ctx).Migrator()
+	if !migrator.HasTable(&model.MigrationVersion{}) || !migrator.HasColumn(&model.MigrationVersion{}, "Checksum") {
+		return map[string]model.MigrationVersion{}, nil
+	}
 
-	// Add timestamps to user_locks table
-	migration := NewAddTimestampsToUserLocks(m.db, m.logger)
-	return migration.Run(context.Background())
-}
+	var versions []model.MigrationVersion
+	if err := m.db.WithContext(ctx).Where("checksum <> ''").Order("version").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("read applied migrations: %w", err)
+	}
 
-// createIndexes creates basic database indexes
-func (m *MigrationManager) createIndexes() error {
-	m.logger.Info("Creating database indexes", nil)
+	applied := make(map[string]model.MigrationVersion, len(versions))
+	for _, version := range versions {
+		applied[version.Version] = version
+	}
+	return applied, nil
+}
 
-	// Create unique index for transaction ID to prevent duplicate transactions
-	if err := m.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id_unique ON transactions (transaction_id)").Error; err != nil {
+// ensureVersionTable creates or updates the migration_versions table
+func (m *MigrationManager) ensureVersionTable(ctx context.Context) error {
+	if err := m.db.WithContext(ctx).AutoMigrate(&model.MigrationVersion{}); err != nil {
+		m.logger.Error("Failed to create migration version table", map[string]any{
+			"error": err.Error(),
+		})
 		return err
 	}
+	return nil
+}
 
-	// Create user ID index for transactions
-	if err := m.db.Exec("CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions (user_id)").Error; err != nil {
-		return err
+// sortedIDs returns the IDs of the applied migrations in order
+func sortedIDs(applied map[string]model.MigrationVersion) []string {
+	ids := make([]string, 0, len(applied))
+	for id := range applied {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
+	return ids
+}
 
-	// Create index for user locks
-	if err := m.db.Exec("CREATE INDEX IF NOT EXISTS idx_user_locks_expires_at ON user_locks (expires_at)").Error; err != nil {
-		return err
+// latestID returns the ID of the last registered migration
+func (m *MigrationManager) latestID() string {
+	if len(m.migrations) == 0 {
+		return ""
 	}
+	return m.migrations[len(m.migrations)-1].ID
+}
 
-	return nil
+// now returns the current time from the time provider, if one was given
+func (m *MigrationManager) now() time.Time {
+	if m.timeProvider != nil {
+		return m.timeProvider.Now()
+	}
+	return time.Now()
 }
 
-// createAdvancedIndexes creates advanced PostgreSQL indexes
-func (m *MigrationManager) createAdvancedIndexes() error {
-	return m.advancedIndexMgr.CreateAdvancedIndexes()
+// Dialect returns the SQL dialect of the database being migrated
+func (m *MigrationManager) Dialect() string {
+	if m.isSQLite() {
+		return DialectSQLite
+	}
+	return DialectPostgres
 }
 
-// applyPerformanceTweaks applies PostgreSQL performance tweaks
-func (m *MigrationManager) applyPerformanceTweaks() error {
-	return m.advancedIndexMgr.CreatePerformanceTweaks()
+// isSQLite reports whether migrations run against an SQLite database
+func (m *MigrationManager) isSQLite() bool {
+	return m.db.Dialector.Name() == "sqlite"
 }
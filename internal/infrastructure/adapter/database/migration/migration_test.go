@@ -0,0 +1,224 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+)
+
+func newSQLiteTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+// secondMigration is a follow-up migration used to exercise ordering and down steps
+var secondMigration = Migration{
+	ID:   "0002",
+	Name: "add_notes",
+	Up: Statements{
+		SQLite: []string{"CREATE TABLE notes (id integer PRIMARY KEY, body text)"},
+	},
+	Down: Statements{
+		SQLite: []string{"DROP TABLE notes"},
+	},
+}
+
+func newTestManager(t *testing.T, db *gorm.DB, migrations ...Migration) *MigrationManager {
+	t.Helper()
+
+	m := NewMigrationManager(db, logger.NewNoopLogger())
+	if len(migrations) > 0 {
+		m.migrations = migrations
+	}
+	return m
+}
+
+func TestMigrationManager_UpAndStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	m := newTestManager(t, db, baselineSchema, secondMigration)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.Equal(t, "0001", applied[0].ID)
+	assert.Equal(t, "0002", applied[1].ID)
+
+	for _, table := range []string{"users", "user_locks", "transactions", "job_runs", "notes"} {
+		assert.True(t, db.Migrator().HasTable(table), table)
+	}
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	for _, s := range statuses {
+		assert.Equal(t, StateApplied, s.State, s.ID)
+		assert.NotNil(t, s.AppliedAt, s.ID)
+	}
+
+	version, err := m.GetCurrentVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0002", version)
+
+	// A second run has nothing left to do
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	require.NoError(t, m.Verify(ctx))
+}
+
+func TestMigrationManager_Down(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	m := newTestManager(t, db, baselineSchema, secondMigration)
+
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, "0002", reverted[0].ID)
+	assert.False(t, db.Migrator().HasTable("notes"))
+	assert.True(t, db.Migrator().HasTable("users"))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, StateApplied, statuses[0].State)
+	assert.Equal(t, StatePending, statuses[1].State)
+
+	// Asking for more than is applied reverts what there is
+	reverted, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	// Everything can be applied again
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+}
+
+func TestMigrationManager_ChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+
+	_, err := newTestManager(t, db, baselineSchema, secondMigration).Up(ctx)
+	require.NoError(t, err)
+
+	modified := secondMigration
+	modified.Up = Statements{SQLite: []string{"CREATE TABLE notes (id integer PRIMARY KEY, body text, author text)"}}
+	m := newTestManager(t, db, baselineSchema, modified)
+
+	assert.ErrorIs(t, m.Verify(ctx), ErrChecksumMismatch)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, err = m.Down(ctx, 1)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, StateModified, statuses[1].State)
+}
+
+func TestMigrationManager_UnknownMigration(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+
+	_, err := newTestManager(t, db, baselineSchema, secondMigration).Up(ctx)
+	require.NoError(t, err)
+
+	// An older build that only knows the baseline
+	m := newTestManager(t, db, baselineSchema)
+	err = m.Verify(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "0002")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, StateUnknown, statuses[1].State)
+	assert.Equal(t, "add_notes", statuses[1].Name)
+}
+
+func TestMigrationManager_AdoptsLegacySchema(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+
+	// A database created by a release that migrated with AutoMigrate
+	require.NoError(t, db.Exec("CREATE TABLE migration_versions (id integer PRIMARY KEY AUTOINCREMENT, version text NOT NULL, applied_at datetime NOT NULL, details text, created_at datetime, updated_at datetime, deleted_at datetime)").Error)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.UserLock{}, &model.Transaction{}, &model.JobRun{}))
+	// Those releases predate the columns later migrations add
+	for _, column := range []string{"AdjustmentReason", "AdjustmentNote", "Operator"} {
+		require.NoError(t, db.Migrator().DropColumn(&model.Transaction{}, column))
+	}
+	require.NoError(t, db.Exec("INSERT INTO migration_versions (version, applied_at, details) VALUES ('1.0.3', ?, 'legacy')", time.Now()).Error)
+	require.NoError(t, db.Create(&model.User{ID: 1, Balance: entity.MoneyFromCents(1000)}).Error)
+
+	m := newTestManager(t, db)
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(Migrations()))
+	assert.Equal(t, "0001", applied[0].ID)
+
+	// Existing data survives and the legacy row is left alone
+	var user model.User
+	require.NoError(t, db.First(&user, 1).Error)
+	assert.Equal(t, entity.MoneyFromCents(1000), user.Balance)
+
+	var legacy int64
+	require.NoError(t, db.Model(&model.MigrationVersion{}).Where("version = ?", "1.0.3").Count(&legacy).Error)
+	assert.Equal(t, int64(1), legacy)
+
+	require.NoError(t, m.Verify(ctx))
+}
+
+func TestMigrationManager_PlanDoesNotWrite(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	m := newTestManager(t, db)
+
+	plan, err := m.Plan(ctx, DirectionUp, 0)
+	require.NoError(t, err)
+	require.Len(t, plan, len(Migrations()))
+	assert.Equal(t, DirectionUp, plan[0].Direction)
+	assert.Equal(t, baselineSchema.Up.SQLite, plan[0].SQL)
+
+	assert.False(t, db.Migrator().HasTable(&model.MigrationVersion{}))
+	assert.False(t, db.Migrator().HasTable("users"))
+}
+
+func TestValidateRegistry(t *testing.T) {
+	require.NoError(t, validateRegistry(Migrations()))
+
+	assert.Error(t, validateRegistry([]Migration{secondMigration, baselineSchema}))
+	assert.Error(t, validateRegistry([]Migration{baselineSchema, baselineSchema}))
+	assert.Error(t, validateRegistry([]Migration{{Name: "no_id"}}))
+}
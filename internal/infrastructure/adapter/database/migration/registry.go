@@ -0,0 +1,85 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Dialects that migrations provide SQL for
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// Statements holds the SQL of one migration step for each supported dialect
+type Statements struct {
+	Postgres []string
+	SQLite   []string
+}
+
+// For returns the statements for the given dialect
+func (s Statements) For(dialect string) []string {
+	if dialect == DialectSQLite {
+		return s.SQLite
+	}
+	return s.Postgres
+}
+
+// Migration is one ordered, reversible schema change
+// Once a migration has been applied anywhere its SQL must not change; Verify reports
+// applied migrations whose checksum no longer matches. Add a new migration instead.
+type Migration struct {
+	ID   string // Orders migrations; zero-padded so that string order is application order
+	Name string // Short description stored alongside the ID
+	Up   Statements
+	Down Statements
+}
+
+// String returns the migration's ID and name
+func (m Migration) String() string {
+	return m.ID + "_" + m.Name
+}
+
+// Checksum returns a digest of the migration's SQL for the given dialect
+func (m Migration) Checksum(dialect string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", m.ID)
+	for _, stmt := range m.Up.For(dialect) {
+		fmt.Fprintf(h, "up\n%s\n", strings.TrimSpace(stmt))
+	}
+	for _, stmt := range m.Down.For(dialect) {
+		fmt.Fprintf(h, "down\n%s\n", strings.TrimSpace(stmt))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// registry lists every migration in application order
+// Append new migrations at the end with the next ID
+var registry = []Migration{
+	baselineSchema,
+	transactionAdjustments,
+	approvalAudit,
+	statementIndex,
+}
+
+// Migrations returns the registered migrations in application order
+func Migrations() []Migration {
+	migrations := make([]Migration, len(registry))
+	copy(migrations, registry)
+	return migrations
+}
+
+// validateRegistry checks that migration IDs are unique and strictly increasing
+func validateRegistry(migrations []Migration) error {
+	for i, m := range migrations {
+		if m.ID == "" {
+			return fmt.Errorf("migration %d has no ID", i)
+		}
+		if i > 0 && m.ID <= migrations[i-1].ID {
+			return fmt.Errorf("migration %s is out of order: it must sort after %s", m, migrations[i-1])
+		}
+	}
+	return nil
+}
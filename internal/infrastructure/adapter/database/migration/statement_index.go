@@ -0,0 +1,23 @@
+package migration
+
+// statementIndex indexes transactions by user and booking time for statement exports
+var statementIndex = Migration{
+	ID:   "0004",
+	Name: "add_statement_index",
+	Up: Statements{
+		Postgres: []string{
+			`CREATE INDEX IF NOT EXISTS idx_transactions_user_booked_at ON transactions (user_id, (COALESCE(processed_at, created_at)), id)`,
+		},
+		SQLite: []string{
+			"CREATE INDEX IF NOT EXISTS idx_transactions_user_booked_at ON transactions (user_id, COALESCE(processed_at, created_at), id)",
+		},
+	},
+	Down: Statements{
+		Postgres: []string{
+			`DROP INDEX IF EXISTS idx_transactions_user_booked_at`,
+		},
+		SQLite: []string{
+			"DROP INDEX IF EXISTS idx_transactions_user_booked_at",
+		},
+	},
+}
@@ -0,0 +1,32 @@
+package migration
+
+// transactionAdjustments records why an operator adjusted a balance and who did it
+// Only admin transactions fill the columns; everything else keeps the empty defaults.
+var transactionAdjustments = Migration{
+	ID:   "0002",
+	Name: "add_transaction_adjustments",
+	Up: Statements{
+		Postgres: []string{
+			`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS adjustment_reason VARCHAR(50) NOT NULL DEFAULT ''`,
+			`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS adjustment_note TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS operator VARCHAR(255) NOT NULL DEFAULT ''`,
+		},
+		SQLite: []string{
+			"ALTER TABLE `transactions` ADD COLUMN `adjustment_reason` text NOT NULL DEFAULT \"\"",
+			"ALTER TABLE `transactions` ADD COLUMN `adjustment_note` text NOT NULL DEFAULT \"\"",
+			"ALTER TABLE `transactions` ADD COLUMN `operator` text NOT NULL DEFAULT \"\"",
+		},
+	},
+	Down: Statements{
+		Postgres: []string{
+			`ALTER TABLE transactions DROP COLUMN IF EXISTS operator`,
+			`ALTER TABLE transactions DROP COLUMN IF EXISTS adjustment_note`,
+			`ALTER TABLE transactions DROP COLUMN IF EXISTS adjustment_reason`,
+		},
+		SQLite: []string{
+			"ALTER TABLE `transactions` DROP COLUMN `operator`",
+			"ALTER TABLE `transactions` DROP COLUMN `adjustment_note`",
+			"ALTER TABLE `transactions` DROP COLUMN `adjustment_reason`",
+		},
+	},
+}
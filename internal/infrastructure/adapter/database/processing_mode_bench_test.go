@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	userUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/user"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database/migration"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
+	timeprovider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
+)
+
+// benchmarkUsers is the number of users transactions are spread over
+const benchmarkUsers = 16
+
+// BenchmarkProcessingModes compares the locked and fast transaction processing paths end to end,
+// including the user existence check the HTTP handler makes on the locked path.
+// SQLite always runs; PostgreSQL runs when TEST_DB_HOST is set:
+//
+//	go test -run '^$' -bench ProcessingModes ./internal/infrastructure/adapter/database/
+func BenchmarkProcessingModes(b *testing.B) {
+	targets := map[string]*Config{
+		DriverSQLite: {
+			Driver:          DriverSQLite,
+			Database:        filepath.Join(b.TempDir(), "bench.db"),
+			MaxOpenConns:    4,
+			MaxIdleConns:    4,
+			ConnMaxLifetime: 5,
+			ConnMaxIdleTime: 5,
+			QueryTimeout:    5 * time.Second,
+			RetryAttempts:   1,
+		},
+	}
+	if os.Getenv("TEST_DB_HOST") != "" {
+		config := testConfigFromEnv()
+		config.MaxOpenConns = 50
+		config.MaxIdleConns = 50
+		targets[DriverPostgres] = config
+	}
+
+	for _, driver := range []string{DriverSQLite, DriverPostgres} {
+		config, ok := targets[driver]
+		if !ok {
+			continue
+		}
+		for _, mode := range []string{transactionUseCase.ProcessingModeLocked, transactionUseCase.ProcessingModeFast} {
+			b.Run(driver+"/"+mode, func(b *testing.B) {
+				benchmarkProcessingMode(b, config, mode)
+			})
+		}
+	}
+}
+
+func benchmarkProcessingMode(b *testing.B, config *Config, mode string) {
+	appLogger := logger.NewNoopLogger()
+	tp := timeprovider.NewRealTimeProvider()
+
+	manager := NewManager(config, appLogger, tp)
+	db, err := manager.Connect()
+	if err != nil {
+		b.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer manager.Close()
+
+	if err := migration.NewMigrationManagerWithTimeProvider(db, appLogger, tp).MigrateAll(); err != nil {
+		b.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := db.Exec("DELETE FROM transactions").Error; err != nil {
+		b.Fatalf("Failed to clear transactions: %v", err)
+	}
+	if err := db.Exec("DELETE FROM user_locks").Error; err != nil {
+		b.Fatalf("Failed to clear user locks: %v", err)
+	}
+	if err := db.Exec("DELETE FROM users").Error; err != nil {
+		b.Fatalf("Failed to clear users: %v", err)
+	}
+	for id := uint64(1); id <= benchmarkUsers; id++ {
+		now := tp.Now()
+		user := model.User{ID: id, Balance: entity.MoneyFromCents(1_000_000_00), CreatedAt: now, UpdatedAt: now}
+		if err := db.Create(&user).Error; err != nil {
+			b.Fatalf("Failed to create user %d: %v", id, err)
+		}
+	}
+
+	userRepo := repository.NewUserRepository(db, tp, appLogger)
+	users := userUseCase.NewUserUseCase(userRepo, tp, appLogger)
+	service := transactionUseCase.NewTransactionService(
+		manager.CreateUnitOfWork(),
+		manager.CreateUserLockRepository(),
+		tp,
+		appLogger,
+		5*time.Second,
+	)
+	if mode == transactionUseCase.ProcessingModeFast {
+		service.WithFastPath(repository.NewAtomicTransactionRepository(db, tp, appLogger))
+	}
+
+	var seq, failed atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			n := seq.Add(1)
+			userID := uint64(n%benchmarkUsers) + 1
+
+			// The handler checks the user exists before the locked path runs
+			if !service.FastPathEnabled() {
+				if _, err := users.UserExists(ctx, userID); err != nil {
+					b.Errorf("UserExists: %v", err)
+					return
+				}
+			}
+
+			_, err := service.ProcessTransaction(ctx, userID, transactionUseCase.TransactionRequest{
+				TransactionID: fmt.Sprintf("bench-%s-%d", mode, n),
+				SourceType:    entity.SourceGame,
+				State:         string(entity.StateWin),
+				Amount:        "1.00",
+			})
+			if err != nil {
+				// Lock contention is expected on the locked path; count it rather than fail
+				failed.Add(1)
+			}
+		}
+	})
+	b.StopTimer()
+
+	b.ReportMetric(float64(failed.Load())/float64(b.N), "failed/op")
+}
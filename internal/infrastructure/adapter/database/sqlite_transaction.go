@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
+	"gorm.io/gorm"
+)
+
+// SQLiteUnitOfWork implements the unit of work pattern for SQLite databases
+// SQLite has no SET TRANSACTION statement; isolation comes from the connection's
+// _txlock=immediate setting, which takes the database write lock on BEGIN.
+// Commit, Rollback and the repository accessors are shared with UnitOfWork.
+type SQLiteUnitOfWork struct {
+	*UnitOfWork
+}
+
+// NewSQLiteUnitOfWork creates a new SQLiteUnitOfWork instance
+func NewSQLiteUnitOfWork(db *gorm.DB, logger coreport.Logger, timeProvider coreport.TimeProvider) persistence.UnitOfWork {
+	return &SQLiteUnitOfWork{
+		UnitOfWork: &UnitOfWork{
+			db:           db,
+			logger:       logger,
+			timeProvider: timeProvider,
+		},
+	}
+}
+
+// Begin starts a new immediate SQLite transaction
+func (u *SQLiteUnitOfWork) Begin(ctx context.Context) (context.Context, error) {
+	u.logger.Debug("Beginning immediate SQLite transaction", nil)
+
+	tx := u.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		u.logger.Error("Failed to begin transaction", map[string]any{"error": tx.Error.Error()})
+		return ctx, fmt.Errorf("failed to begin transaction: %w", tx.Error)
+	}
+
+	// Store transaction in context
+	return repository.ContextWithTx(ctx, tx), nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
 	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
 	timeprovider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
@@ -27,8 +28,20 @@ func NewTestDBManager(t *testing.T, logger coreport.Logger) *TestDBManager {
 	// Create time provider
 	timeProvider := timeprovider.NewRealTimeProvider()
 
-	// Get test database configuration from environment or use defaults
-	config := &Config{
+	config := testConfigFromEnv()
+	manager := NewManager(config, logger, timeProvider)
+
+	return &TestDBManager{
+		Manager:      manager,
+		Config:       config,
+		Logger:       logger,
+		TimeProvider: timeProvider,
+	}
+}
+
+// testConfigFromEnv returns the test database configuration from environment or defaults
+func testConfigFromEnv() *Config {
+	return &Config{
 		Driver:          "postgres",
 		Host:            getEnvOrDefault("TEST_DB_HOST", "localhost"),
 		Port:            getEnvIntOrDefault("TEST_DB_PORT", 5432),
@@ -45,15 +58,6 @@ func NewTestDBManager(t *testing.T, logger coreport.Logger) *TestDBManager {
 		RetryAttempts:   1,        // One attempt for tests to fail fast
 		RetryDelay:      1,        // 1 second delay
 	}
-
-	manager := NewManager(config, logger, timeProvider)
-
-	return &TestDBManager{
-		Manager:      manager,
-		Config:       config,
-		Logger:       logger,
-		TimeProvider: timeProvider,
-	}
 }
 
 // Connect connects to the test database
@@ -98,6 +102,7 @@ func (m *TestDBManager) SetupTestDB(t *testing.T) {
 		&model.User{},
 		&model.UserLock{},
 		&model.Transaction{},
+		&model.ApprovalEvent{},
 	); err != nil {
 		t.Fatalf("Failed to create tables: %v", err)
 	}
@@ -165,7 +170,7 @@ func (m *TestDBManager) CreateTestUser(t *testing.T, id uint64, balance int64) {
 
 	user := model.User{
 		ID:               id,
-		Balance:          balance,
+		Balance:          entity.MoneyFromCents(balance),
 		TransactionCount: 0,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
@@ -11,12 +11,6 @@ import (
 	"gorm.io/gorm"
 )
 
-// contextKey is a custom type for context keys to avoid collisions
-type contextKey string
-
-// Context keys
-const txKey contextKey = "tx"
-
 // UnitOfWork implements the unit of work pattern for database transactions
 type UnitOfWork struct {
 	db           *gorm.DB
@@ -54,20 +48,21 @@ func (u *UnitOfWork) Begin(ctx context.Context) (context.Context, error) {
 	}
 
 	// Store transaction in context
-	return context.WithValue(ctx, txKey, tx), nil
+	return repository.ContextWithTx(ctx, tx), nil
 }
 
 // Commit commits the current transaction
 func (u *UnitOfWork) Commit(ctx context.Context) error {
-	tx, ok := ctx.Value(txKey).(*gorm.DB)
-	if !ok || tx == nil {
+	tx, ok := repository.TxFromContext(ctx)
+	if !ok {
 		return fmt.Errorf("no transaction found in context")
 	}
 
 	u.logger.Debug("Committing database transaction", nil)
 	if err := tx.Commit().Error; err != nil {
 		u.logger.Error("Failed to commit transaction", map[string]any{"error": err.Error()})
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		// A serialization failure at commit rolls everything back, so the caller may retry
+		return fmt.Errorf("failed to commit transaction: %w", repository.NewErrorClassifier().DomainError(err))
 	}
 
 	return nil
@@ -75,8 +70,8 @@ func (u *UnitOfWork) Commit(ctx context.Context) error {
 
 // Rollback rolls back the current transaction with improved error handling
 func (u *UnitOfWork) Rollback(ctx context.Context) error {
-	tx, ok := ctx.Value(txKey).(*gorm.DB)
-	if !ok || tx == nil {
+	tx, ok := repository.TxFromContext(ctx)
+	if !ok {
 		return fmt.Errorf("no transaction found in context")
 	}
 
@@ -117,10 +112,16 @@ func (u *UnitOfWork) GetTransactionRepository(ctx context.Context) persistence.T
 	return repository.NewTransactionRepository(db, u.logger)
 }
 
+// GetApprovalAuditRepository returns an approval audit repository in the current transaction
+func (u *UnitOfWork) GetApprovalAuditRepository(ctx context.Context) persistence.ApprovalAuditRepository {
+	db := u.getDbFromContext(ctx)
+	return repository.NewApprovalAuditRepository(db, u.logger)
+}
+
 // getDbFromContext retrieves the database instance from context
 func (u *UnitOfWork) getDbFromContext(ctx context.Context) *gorm.DB {
-	tx, ok := ctx.Value(txKey).(*gorm.DB)
-	if ok && tx != nil {
+	tx, ok := repository.TxFromContext(ctx)
+	if ok {
 		return tx
 	}
 	return u.db.WithContext(ctx)
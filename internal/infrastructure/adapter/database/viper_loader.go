@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
 	"github.com/spf13/viper"
@@ -115,6 +116,31 @@ func CreateConfigFromViperConfig(conf *config.Config) *Config {
 	return dbConf
 }
 
+// NewConfigFromAppConfig builds the database configuration used by the service binaries
+// Unlike CreateConfigFromViperConfig it takes every value from the loaded configuration, which
+// has already applied the BP_ environment overrides
+func NewConfigFromAppConfig(conf *config.Config) *Config {
+	return &Config{
+		Driver:          conf.Database.Driver,
+		Host:            conf.Database.Host,
+		Port:            ParsePort(conf.Database.Port),
+		Username:        conf.Database.Username,
+		Password:        conf.Database.Password,
+		Database:        conf.Database.Database,
+		SSLMode:         conf.Database.SSLMode,
+		MaxOpenConns:    conf.Database.MaxOpenConns,
+		MaxIdleConns:    conf.Database.MaxIdleConns,
+		ConnMaxLifetime: conf.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: conf.Database.ConnMaxIdleTime,
+		QueryTimeout:    conf.Database.QueryTimeout,
+		LockStrategy:    conf.Database.LockStrategy,
+		LockWaitTimeout: time.Duration(conf.Database.LockWaitTimeoutMs) * time.Millisecond,
+		LogLevel:        conf.Logger.Level,
+		RetryAttempts:   3,
+		RetryDelay:      5,
+	}
+}
+
 // ParsePort converts a port string to an int
 func ParsePort(port string) int {
 	var p int
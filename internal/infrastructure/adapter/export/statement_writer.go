@@ -0,0 +1,157 @@
+// Package export renders account statements as CSV or newline-delimited JSON
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
+)
+
+// Statement formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// csvHeader names the columns of a CSV statement
+var csvHeader = []string{
+	"type", "user_id", "time", "transaction_id", "source_type", "state", "amount", "status", "balance", "description",
+}
+
+// flusher is implemented by destinations that buffer writes themselves, such as HTTP responses
+type flusher interface {
+	Flush()
+}
+
+// ContentType returns the MIME type of a statement format
+func ContentType(format string) string {
+	if format == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NewStatementWriter creates a writer for the given format; the CSV header is written with the first record,
+// so one writer can hold the statements of several users
+//
+// Possible errors:
+// - ErrInvalidRequest: If the format is neither csv nor ndjson
+func NewStatementWriter(format string, w io.Writer) (statement.Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{csv: csv.NewWriter(w), dest: w}, nil
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buffered, enc: json.NewEncoder(buffered), dest: w}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown statement format %q, use %s or %s", errs.ErrInvalidRequest, format, FormatCSV, FormatNDJSON)
+	}
+}
+
+// csvWriter writes statement records as CSV rows
+type csvWriter struct {
+	csv           *csv.Writer
+	dest          io.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) WriteRecord(record statement.Record) error {
+	if !w.headerWritten {
+		if err := w.csv.Write(csvHeader); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	row := []string{
+		string(record.Type),
+		strconv.FormatUint(record.UserID, 10),
+		formatTime(record.Time),
+		"", "", "", "", "",
+		record.Balance.String(),
+		record.Description(),
+	}
+	if txn := record.Transaction; txn != nil {
+		row[3] = txn.TransactionID
+		row[4] = txn.SourceType.String()
+		row[5] = txn.State.String()
+		row[6] = txn.GetAmount()
+		row[7] = txn.Status.String()
+	}
+	return w.csv.Write(row)
+}
+
+func (w *csvWriter) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	if f, ok := w.dest.(flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// ndjsonRecord is one statement record as a JSON object
+type ndjsonRecord struct {
+	Type          string `json:"type"`
+	UserID        uint64 `json:"userId"`
+	Time          string `json:"time,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+	SourceType    string `json:"sourceType,omitempty"`
+	State         string `json:"state,omitempty"`
+	Amount        string `json:"amount,omitempty"`
+	Status        string `json:"status,omitempty"`
+	Balance       string `json:"balance"`
+	Description   string `json:"description,omitempty"`
+}
+
+// ndjsonWriter writes statement records as one JSON object per line
+type ndjsonWriter struct {
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	dest io.Writer
+}
+
+func (w *ndjsonWriter) WriteRecord(record statement.Record) error {
+	line := ndjsonRecord{
+		Type:        string(record.Type),
+		UserID:      record.UserID,
+		Time:        formatTime(record.Time),
+		Balance:     record.Balance.String(),
+		Description: record.Description(),
+	}
+	if txn := record.Transaction; txn != nil {
+		line.TransactionID = txn.TransactionID
+		line.SourceType = txn.SourceType.String()
+		line.State = txn.State.String()
+		line.Amount = txn.GetAmount()
+		line.Status = txn.Status.String()
+	}
+	return w.enc.Encode(line)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if f, ok := w.dest.(flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// formatTime formats a record time in UTC, or "" for an open period start
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
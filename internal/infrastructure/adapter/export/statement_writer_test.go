@@ -0,0 +1,88 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/statement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flushRecorder counts how often a writer flushes through to it
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+}
+
+// testStatement is a short statement with a completed and a failed transaction
+func testStatement() []statement.Record {
+	booked := time.Date(2024, 1, 2, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	return []statement.Record{
+		{Type: statement.RecordOpening, UserID: 1, Balance: entity.MoneyFromCents(10000)},
+		{Type: statement.RecordTransaction, UserID: 1, Time: booked, Balance: entity.MoneyFromCents(11050), Transaction: &entity.Transaction{
+			TransactionID: "t1", SourceType: entity.SourceGame, State: entity.StateWin,
+			Amount: entity.MoneyFromCents(1050), Status: entity.StatusCompleted,
+		}},
+		{Type: statement.RecordTransaction, UserID: 1, Time: booked, Balance: entity.MoneyFromCents(11050), Transaction: &entity.Transaction{
+			TransactionID: "t2", SourceType: entity.SourcePayment, State: entity.StateLose,
+			Amount: entity.MoneyFromCents(99900), Status: entity.StatusFailed, ErrorMessage: "insufficient balance, really",
+		}},
+		{Type: statement.RecordClosing, UserID: 1, Time: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Balance: entity.MoneyFromCents(11050)},
+	}
+}
+
+func writeAll(t *testing.T, format string, records []statement.Record) *flushRecorder {
+	t.Helper()
+	out := &flushRecorder{}
+	w, err := NewStatementWriter(format, out)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, w.WriteRecord(record))
+	}
+	require.NoError(t, w.Flush())
+	return out
+}
+
+func TestStatementWriter_CSV(t *testing.T) {
+	records := testStatement()
+	out := writeAll(t, FormatCSV, append(records, records...))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	// One header however many statements share the writer
+	require.Len(t, lines, 9)
+	assert.Equal(t, "type,user_id,time,transaction_id,source_type,state,amount,status,balance,description", lines[0])
+	assert.Equal(t, "opening,1,,,,,,,100.00,", lines[1])
+	assert.Equal(t, "transaction,1,2024-01-02T09:00:00Z,t1,game,win,10.50,completed,110.50,", lines[2])
+	assert.Equal(t, `transaction,1,2024-01-02T09:00:00Z,t2,payment,lose,999.00,failed,110.50,"insufficient balance, really"`, lines[3])
+	assert.Equal(t, "closing,1,2024-02-01T00:00:00Z,,,,,,110.50,", lines[4])
+	assert.Equal(t, 1, out.flushes)
+}
+
+func TestStatementWriter_NDJSON(t *testing.T) {
+	out := writeAll(t, FormatNDJSON, testStatement())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.JSONEq(t, `{"type":"opening","userId":1,"balance":"100.00"}`, lines[0])
+	assert.JSONEq(t, `{"type":"transaction","userId":1,"time":"2024-01-02T09:00:00Z","transactionId":"t1",
+		"sourceType":"game","state":"win","amount":"10.50","status":"completed","balance":"110.50"}`, lines[1])
+	assert.JSONEq(t, `{"type":"transaction","userId":1,"time":"2024-01-02T09:00:00Z","transactionId":"t2",
+		"sourceType":"payment","state":"lose","amount":"999.00","status":"failed","balance":"110.50",
+		"description":"insufficient balance, really"}`, lines[2])
+	assert.JSONEq(t, `{"type":"closing","userId":1,"time":"2024-02-01T00:00:00Z","balance":"110.50"}`, lines[3])
+	assert.Equal(t, 1, out.flushes)
+}
+
+func TestNewStatementWriter_UnknownFormat(t *testing.T) {
+	_, err := NewStatementWriter("xml", &bytes.Buffer{})
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+}
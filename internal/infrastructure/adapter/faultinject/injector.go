@@ -0,0 +1,316 @@
+package faultinject
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+)
+
+// Error kinds a fault can inject, each standing in for what the repositories return when the database fails that way
+const (
+	ErrorSerialization = "serialization" // Serialization failure; retryable
+	ErrorDeadlock      = "deadlock"      // Deadlock detected; retryable
+	ErrorLockTimeout   = "lock_timeout"  // The user is locked by someone else
+	ErrorLockLost      = "lock_lost"     // The user lock expired and was taken over; retryable
+	ErrorConnection    = "connection"    // The connection dropped
+	ErrorTimeout       = "timeout"       // The query ran out of time
+	ErrorInternal      = "internal"      // Any other failure
+)
+
+// ErrorKinds lists the error kinds a fault can inject
+var ErrorKinds = []string{
+	ErrorSerialization, ErrorDeadlock, ErrorLockTimeout, ErrorLockLost, ErrorConnection, ErrorTimeout, ErrorInternal,
+}
+
+// Phases of a call a fault's error can be injected in
+const (
+	PhaseBefore = "before" // The call is not made
+	PhaseAfter  = "after"  // The call is made and its result is lost, like a connection dropping before the reply
+)
+
+// Fault describes a failure to inject into calls of the persistence ports
+type Fault struct {
+	// Method is a port method such as "UnitOfWork.Commit", or a pattern such as "UserRepository.*" or "*"
+	Method      string
+	Error       string        // Error kind to fail the call with; empty only adds latency
+	Phase       string        // Whether the error replaces the call or its result; empty means before
+	Latency     time.Duration // Delay before the call
+	Probability float64       // Chance of firing on each matching call; zero means always
+	Count       int           // Times the fault fires before it is spent; zero means no limit
+}
+
+// FaultStatus is a configured fault and how often it has fired
+type FaultStatus struct {
+	Fault
+	Fired int
+}
+
+// MethodStats counts the calls of one port method
+type MethodStats struct {
+	Calls    int64
+	Injected int64 // Calls that were delayed or failed by a fault
+}
+
+// Methods lists the port methods faults can target
+var Methods = []string{
+	"UnitOfWork.Begin",
+	"UnitOfWork.Commit",
+	"UnitOfWork.Rollback",
+	"UserRepository.GetByID",
+	"UserRepository.Create",
+	"UserRepository.Update",
+	"UserRepository.ProcessTransaction",
+	"TransactionRepository.Create",
+	"TransactionRepository.Update",
+	"TransactionRepository.GetByTransactionID",
+	"TransactionRepository.ListByUser",
+	"TransactionRepository.ListByStatus",
+	"TransactionRepository.StreamByUser",
+	"TransactionRepository.LastCompletedBefore",
+	"TransactionRepository.FirstCompleted",
+	"TransactionRepository.TransactionExists",
+	"ApprovalAuditRepository.Record",
+	"ApprovalAuditRepository.ListByTransaction",
+	"UserLockRepository.AcquireLock",
+	"UserLockRepository.ReleaseLock",
+	"UserLockRepository.RenewLock",
+	"UserLockRepository.VerifyLock",
+	"UserLockRepository.CleanupExpiredLocks",
+	"AtomicTransactionRepository.ApplyTransaction",
+}
+
+// rule is a configured fault and its firing count
+type rule struct {
+	fault Fault
+	fired int
+}
+
+// Injector decides which calls of the wrapped ports fail or slow down
+// The faults can be changed while the ports are in use. Calls are decided in the order they arrive, so a
+// seeded injector makes the same decisions for the same sequence of calls.
+type Injector struct {
+	mu    sync.Mutex
+	rng   *rand.Rand
+	rules []*rule
+	stats map[string]*MethodStats
+}
+
+// NewInjector creates an injector without faults; a zero seed picks a random one
+func NewInjector(seed int64) *Injector {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{
+		rng:   rand.New(rand.NewSource(seed)),
+		stats: make(map[string]*MethodStats),
+	}
+}
+
+// Add validates faults and adds them after the configured ones
+//
+// Possible errors:
+//   - errs.ErrInvalidRequest: a fault has an unknown method, error kind or phase, or a value out of range
+func (i *Injector) Add(faults ...Fault) error {
+	rules, err := newRules(faults)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append(i.rules, rules...)
+	return nil
+}
+
+// Set validates faults and replaces the configured ones with them
+//
+// Possible errors:
+//   - errs.ErrInvalidRequest: a fault has an unknown method, error kind or phase, or a value out of range
+func (i *Injector) Set(faults ...Fault) error {
+	rules, err := newRules(faults)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = rules
+	return nil
+}
+
+// Clear removes all faults and resets the call counts
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = nil
+	i.stats = make(map[string]*MethodStats)
+}
+
+// Faults returns the configured faults in the order they are evaluated
+func (i *Injector) Faults() []FaultStatus {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	faults := make([]FaultStatus, len(i.rules))
+	for n, r := range i.rules {
+		faults[n] = FaultStatus{Fault: r.fault, Fired: r.fired}
+	}
+	return faults
+}
+
+// Stats returns the call counts of every method called so far
+func (i *Injector) Stats() map[string]MethodStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	stats := make(map[string]MethodStats, len(i.stats))
+	for method, s := range i.stats {
+		stats[method] = *s
+	}
+	return stats
+}
+
+// decision is what the faults matching one call do to it
+type decision struct {
+	latency time.Duration
+	err     error
+	phase   string
+}
+
+// intercept decides the faults for one call of method and waits out their latency
+// before is the error to fail the call with instead of making it, after the one to replace its result with.
+func (i *Injector) intercept(ctx context.Context, method string) (before, after error) {
+	d := i.decide(method)
+	if d.latency > 0 {
+		timer := time.NewTimer(d.latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err(), nil
+		}
+	}
+	if d.phase == PhaseAfter {
+		return nil, d.err
+	}
+	return d.err, nil
+}
+
+// decide rolls the faults matching method in order
+// The latencies of all faults that fire add up. The first one to fire with an error decides the error;
+// later faults with errors are then skipped without counting as fired.
+func (i *Injector) decide(method string) decision {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats, ok := i.stats[method]
+	if !ok {
+		stats = &MethodStats{}
+		i.stats[method] = stats
+	}
+	stats.Calls++
+
+	d := decision{phase: PhaseBefore}
+	injected := false
+	for _, r := range i.rules {
+		if r.fault.Count > 0 && r.fired >= r.fault.Count {
+			continue
+		}
+		if d.err != nil && r.fault.Error != "" {
+			continue
+		}
+		if matched, _ := path.Match(r.fault.Method, method); !matched {
+			continue
+		}
+		if r.fault.Probability > 0 && i.rng.Float64() >= r.fault.Probability {
+			continue
+		}
+
+		r.fired++
+		injected = true
+		d.latency += r.fault.Latency
+		if r.fault.Error != "" {
+			d.err = injectedError(r.fault.Error, method)
+			if r.fault.Phase != "" {
+				d.phase = r.fault.Phase
+			}
+		}
+	}
+	if injected {
+		stats.Injected++
+	}
+	return d
+}
+
+// injectedError returns the domain error a kind of failure surfaces as
+func injectedError(kind, method string) error {
+	switch kind {
+	case ErrorSerialization:
+		return fmt.Errorf("%w: injected serialization failure in %s", errs.ErrConcurrencyConflict, method)
+	case ErrorDeadlock:
+		return fmt.Errorf("%w: injected deadlock in %s", errs.ErrConcurrencyConflict, method)
+	case ErrorLockTimeout:
+		return fmt.Errorf("%w: injected lock timeout in %s", errs.ErrUserLocked, method)
+	case ErrorLockLost:
+		return fmt.Errorf("%w: injected lock loss in %s", errs.ErrLockLost, method)
+	case ErrorConnection:
+		return fmt.Errorf("%w: injected connection failure in %s", errs.ErrDatabaseConnection, method)
+	case ErrorTimeout:
+		return fmt.Errorf("%w: injected timeout in %s", errs.ErrDatabaseConnection, method)
+	default:
+		return fmt.Errorf("%w: injected failure in %s", errs.ErrInternalServer, method)
+	}
+}
+
+// newRules validates faults
+func newRules(faults []Fault) ([]*rule, error) {
+	rules := make([]*rule, 0, len(faults))
+	for n, fault := range faults {
+		if err := fault.validate(); err != nil {
+			return nil, fmt.Errorf("%w: fault %d: %s", errs.ErrInvalidRequest, n+1, err.Error())
+		}
+		rules = append(rules, &rule{fault: fault})
+	}
+	return rules, nil
+}
+
+// validate checks that a fault targets a known method and does something
+func (f Fault) validate() error {
+	if f.Method == "" {
+		return fmt.Errorf("method is required")
+	}
+	matches := false
+	for _, method := range Methods {
+		matched, err := path.Match(f.Method, method)
+		if err != nil {
+			return fmt.Errorf("invalid method pattern %q", f.Method)
+		}
+		matches = matches || matched
+	}
+	if !matches {
+		return fmt.Errorf("method %q matches no port method", f.Method)
+	}
+
+	if f.Error != "" && !slices.Contains(ErrorKinds, f.Error) {
+		return fmt.Errorf("unknown error %q, want one of %s", f.Error, strings.Join(ErrorKinds, ", "))
+	}
+	switch f.Phase {
+	case "", PhaseBefore, PhaseAfter:
+	default:
+		return fmt.Errorf("unknown phase %q, want %s or %s", f.Phase, PhaseBefore, PhaseAfter)
+	}
+
+	if f.Error == "" && f.Latency <= 0 {
+		return fmt.Errorf("an error or a latency is required")
+	}
+	if f.Latency < 0 || f.Count < 0 {
+		return fmt.Errorf("latency and count cannot be negative")
+	}
+	if f.Probability < 0 || f.Probability > 1 {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package faultinject
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjector_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault Fault
+	}{
+		{"missing method", Fault{Error: ErrorDeadlock}},
+		{"unknown method", Fault{Method: "UserRepository.Delete", Error: ErrorDeadlock}},
+		{"invalid pattern", Fault{Method: "UserRepository.[", Error: ErrorDeadlock}},
+		{"unknown error", Fault{Method: "*", Error: "disk_full"}},
+		{"unknown phase", Fault{Method: "*", Error: ErrorDeadlock, Phase: "during"}},
+		{"neither error nor latency", Fault{Method: "*"}},
+		{"negative latency", Fault{Method: "*", Error: ErrorDeadlock, Latency: -time.Second}},
+		{"negative count", Fault{Method: "*", Error: ErrorDeadlock, Count: -1}},
+		{"probability above one", Fault{Method: "*", Error: ErrorDeadlock, Probability: 1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := NewInjector(1)
+			require.NoError(t, injector.Add(Fault{Method: "*", Latency: time.Millisecond}))
+
+			assert.ErrorIs(t, injector.Add(tt.fault), errs.ErrInvalidRequest)
+			assert.ErrorIs(t, injector.Set(tt.fault), errs.ErrInvalidRequest)
+			assert.Len(t, injector.Faults(), 1, "a rejected fault leaves the configured ones alone")
+		})
+	}
+}
+
+func TestInjector_ErrorKinds(t *testing.T) {
+	tests := []struct {
+		kind string
+		want error
+	}{
+		{ErrorSerialization, errs.ErrConcurrencyConflict},
+		{ErrorDeadlock, errs.ErrConcurrencyConflict},
+		{ErrorLockTimeout, errs.ErrUserLocked},
+		{ErrorLockLost, errs.ErrLockLost},
+		{ErrorConnection, errs.ErrDatabaseConnection},
+		{ErrorTimeout, errs.ErrDatabaseConnection},
+		{ErrorInternal, errs.ErrInternalServer},
+	}
+	require.Len(t, tests, len(ErrorKinds))
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			injector := NewInjector(1)
+			require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Commit", Error: tt.kind}))
+
+			before, after := injector.intercept(context.Background(), "UnitOfWork.Commit")
+			assert.ErrorIs(t, before, tt.want)
+			assert.Contains(t, before.Error(), "UnitOfWork.Commit")
+			assert.NoError(t, after)
+		})
+	}
+}
+
+func TestInjector_Matching(t *testing.T) {
+	ctx := context.Background()
+	injector := NewInjector(1)
+	require.NoError(t, injector.Set(
+		Fault{Method: "UserRepository.*", Error: ErrorDeadlock, Count: 2},
+		Fault{Method: "*.Create", Error: ErrorConnection},
+	))
+
+	before, _ := injector.intercept(ctx, "UserRepository.Update")
+	assert.ErrorIs(t, before, errs.ErrConcurrencyConflict)
+	before, _ = injector.intercept(ctx, "TransactionRepository.Update")
+	assert.NoError(t, before)
+
+	// The first matching fault decides the error; the second is not counted as fired
+	before, _ = injector.intercept(ctx, "UserRepository.Create")
+	assert.ErrorIs(t, before, errs.ErrConcurrencyConflict)
+
+	// Once spent, the first fault no longer applies
+	before, _ = injector.intercept(ctx, "UserRepository.Create")
+	assert.ErrorIs(t, before, errs.ErrDatabaseConnection)
+	before, _ = injector.intercept(ctx, "UserRepository.Update")
+	assert.NoError(t, before)
+
+	faults := injector.Faults()
+	require.Len(t, faults, 2)
+	assert.Equal(t, 2, faults[0].Fired)
+	assert.Equal(t, 1, faults[1].Fired)
+
+	stats := injector.Stats()
+	assert.Equal(t, MethodStats{Calls: 2, Injected: 1}, stats["UserRepository.Update"])
+	assert.Equal(t, MethodStats{Calls: 2, Injected: 2}, stats["UserRepository.Create"])
+	assert.Equal(t, MethodStats{Calls: 1}, stats["TransactionRepository.Update"])
+
+	injector.Clear()
+	assert.Empty(t, injector.Faults())
+	assert.Empty(t, injector.Stats())
+	before, _ = injector.intercept(ctx, "UserRepository.Create")
+	assert.NoError(t, before)
+}
+
+func TestInjector_Probability(t *testing.T) {
+	ctx := context.Background()
+	decisions := func(seed int64) []bool {
+		injector := NewInjector(seed)
+		require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Commit", Error: ErrorSerialization, Probability: 0.3}))
+		failed := make([]bool, 1000)
+		for n := range failed {
+			before, _ := injector.intercept(ctx, "UnitOfWork.Commit")
+			failed[n] = before != nil
+		}
+		return failed
+	}
+
+	first := decisions(42)
+	assert.Equal(t, first, decisions(42), "the same seed makes the same decisions")
+
+	count := 0
+	for _, failed := range first {
+		if failed {
+			count++
+		}
+	}
+	assert.InDelta(t, 300, count, 60)
+}
+
+func TestInjector_Latency(t *testing.T) {
+	injector := NewInjector(1)
+	require.NoError(t, injector.Set(
+		Fault{Method: "UnitOfWork.*", Latency: 20 * time.Millisecond},
+		Fault{Method: "UnitOfWork.Commit", Latency: 20 * time.Millisecond, Error: ErrorTimeout, Phase: PhaseAfter},
+	))
+
+	// Latencies of all firing faults add up
+	start := time.Now()
+	before, after := injector.intercept(context.Background(), "UnitOfWork.Commit")
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.NoError(t, before)
+	assert.ErrorIs(t, after, errs.ErrDatabaseConnection)
+
+	// Waiting ends with the caller's context
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	before, after = injector.intercept(ctx, "UnitOfWork.Begin")
+	assert.ErrorIs(t, before, context.DeadlineExceeded)
+	assert.NoError(t, after)
+}
@@ -0,0 +1,295 @@
+package faultinject
+
+import (
+	"context"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+)
+
+// call runs a port method that returns a value under the injector's faults
+func call[T any](ctx context.Context, injector *Injector, method string, next func() (T, error)) (T, error) {
+	var zero T
+	before, after := injector.intercept(ctx, method)
+	if before != nil {
+		return zero, before
+	}
+	result, err := next()
+	if err != nil {
+		return result, err
+	}
+	if after != nil {
+		return zero, after
+	}
+	return result, nil
+}
+
+// exec runs a port method that only returns an error under the injector's faults
+func exec(ctx context.Context, injector *Injector, method string, next func() error) error {
+	_, err := call(ctx, injector, method, func() (struct{}, error) {
+		return struct{}{}, next()
+	})
+	return err
+}
+
+// UnitOfWork injects faults into a unit of work and the repositories it hands out
+type UnitOfWork struct {
+	next     persistence.UnitOfWork
+	injector *Injector
+}
+
+// WrapUnitOfWork wraps a unit of work with the injector's faults
+func WrapUnitOfWork(next persistence.UnitOfWork, injector *Injector) *UnitOfWork {
+	return &UnitOfWork{next: next, injector: injector}
+}
+
+// Begin starts a transaction unless a fault fails it
+// A fault after the call rolls the started transaction back, since the caller never learns of it.
+func (u *UnitOfWork) Begin(ctx context.Context) (context.Context, error) {
+	before, after := u.injector.intercept(ctx, "UnitOfWork.Begin")
+	if before != nil {
+		return ctx, before
+	}
+	txCtx, err := u.next.Begin(ctx)
+	if err != nil {
+		return txCtx, err
+	}
+	if after != nil {
+		_ = u.next.Rollback(txCtx)
+		return ctx, after
+	}
+	return txCtx, nil
+}
+
+// Commit commits the transaction unless a fault fails it
+// A fault after the call reports a failure for a transaction that was committed.
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	return exec(ctx, u.injector, "UnitOfWork.Commit", func() error {
+		return u.next.Commit(ctx)
+	})
+}
+
+// Rollback rolls the transaction back and then reports any injected error
+// The rollback always runs so an injected failure never leaves a transaction open.
+func (u *UnitOfWork) Rollback(ctx context.Context) error {
+	before, after := u.injector.intercept(ctx, "UnitOfWork.Rollback")
+	if err := u.next.Rollback(ctx); err != nil {
+		return err
+	}
+	if before != nil {
+		return before
+	}
+	return after
+}
+
+// GetUserRepository returns the user repository with the injector's faults
+func (u *UnitOfWork) GetUserRepository(ctx context.Context) persistence.UserRepository {
+	return &userRepository{next: u.next.GetUserRepository(ctx), injector: u.injector}
+}
+
+// GetTransactionRepository returns the transaction repository with the injector's faults
+func (u *UnitOfWork) GetTransactionRepository(ctx context.Context) persistence.TransactionRepository {
+	return &transactionRepository{next: u.next.GetTransactionRepository(ctx), injector: u.injector}
+}
+
+// GetApprovalAuditRepository returns the approval audit repository with the injector's faults
+func (u *UnitOfWork) GetApprovalAuditRepository(ctx context.Context) persistence.ApprovalAuditRepository {
+	return &approvalAuditRepository{next: u.next.GetApprovalAuditRepository(ctx), injector: u.injector}
+}
+
+// userRepository injects faults into a user repository
+type userRepository struct {
+	next     persistence.UserRepository
+	injector *Injector
+}
+
+// GetByID calls the wrapped GetByID under the injector's faults
+func (r *userRepository) GetByID(ctx context.Context, id uint64) (*entity.User, error) {
+	return call(ctx, r.injector, "UserRepository.GetByID", func() (*entity.User, error) {
+		return r.next.GetByID(ctx, id)
+	})
+}
+
+// Create calls the wrapped Create under the injector's faults
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	return exec(ctx, r.injector, "UserRepository.Create", func() error {
+		return r.next.Create(ctx, user)
+	})
+}
+
+// Update calls the wrapped Update under the injector's faults
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	return exec(ctx, r.injector, "UserRepository.Update", func() error {
+		return r.next.Update(ctx, user)
+	})
+}
+
+// ProcessTransaction calls the wrapped ProcessTransaction under the injector's faults
+func (r *userRepository) ProcessTransaction(ctx context.Context, userID uint64, balanceChange entity.Money) (*entity.User, error) {
+	return call(ctx, r.injector, "UserRepository.ProcessTransaction", func() (*entity.User, error) {
+		return r.next.ProcessTransaction(ctx, userID, balanceChange)
+	})
+}
+
+// transactionRepository injects faults into a transaction repository
+type transactionRepository struct {
+	next     persistence.TransactionRepository
+	injector *Injector
+}
+
+// Create calls the wrapped Create under the injector's faults
+func (r *transactionRepository) Create(ctx context.Context, transaction *entity.Transaction) error {
+	return exec(ctx, r.injector, "TransactionRepository.Create", func() error {
+		return r.next.Create(ctx, transaction)
+	})
+}
+
+// Update calls the wrapped Update under the injector's faults
+func (r *transactionRepository) Update(ctx context.Context, transaction *entity.Transaction) error {
+	return exec(ctx, r.injector, "TransactionRepository.Update", func() error {
+		return r.next.Update(ctx, transaction)
+	})
+}
+
+// GetByTransactionID calls the wrapped GetByTransactionID under the injector's faults
+func (r *transactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Transaction, error) {
+	return call(ctx, r.injector, "TransactionRepository.GetByTransactionID", func() (*entity.Transaction, error) {
+		return r.next.GetByTransactionID(ctx, transactionID)
+	})
+}
+
+// ListByUser calls the wrapped ListByUser under the injector's faults
+func (r *transactionRepository) ListByUser(ctx context.Context, userID uint64, limit int) ([]*entity.Transaction, error) {
+	return call(ctx, r.injector, "TransactionRepository.ListByUser", func() ([]*entity.Transaction, error) {
+		return r.next.ListByUser(ctx, userID, limit)
+	})
+}
+
+// ListByStatus calls the wrapped ListByStatus under the injector's faults
+func (r *transactionRepository) ListByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error) {
+	return call(ctx, r.injector, "TransactionRepository.ListByStatus", func() ([]*entity.Transaction, error) {
+		return r.next.ListByStatus(ctx, status, limit)
+	})
+}
+
+// StreamByUser calls the wrapped StreamByUser under the injector's faults
+func (r *transactionRepository) StreamByUser(ctx context.Context, userID uint64, from, to time.Time, fn func(*entity.Transaction) error) error {
+	return exec(ctx, r.injector, "TransactionRepository.StreamByUser", func() error {
+		return r.next.StreamByUser(ctx, userID, from, to, fn)
+	})
+}
+
+// LastCompletedBefore calls the wrapped LastCompletedBefore under the injector's faults
+func (r *transactionRepository) LastCompletedBefore(ctx context.Context, userID uint64, before time.Time) (*entity.Transaction, error) {
+	return call(ctx, r.injector, "TransactionRepository.LastCompletedBefore", func() (*entity.Transaction, error) {
+		return r.next.LastCompletedBefore(ctx, userID, before)
+	})
+}
+
+// FirstCompleted calls the wrapped FirstCompleted under the injector's faults
+func (r *transactionRepository) FirstCompleted(ctx context.Context, userID uint64) (*entity.Transaction, error) {
+	return call(ctx, r.injector, "TransactionRepository.FirstCompleted", func() (*entity.Transaction, error) {
+		return r.next.FirstCompleted(ctx, userID)
+	})
+}
+
+// TransactionExists calls the wrapped TransactionExists under the injector's faults
+func (r *transactionRepository) TransactionExists(ctx context.Context, transactionID string) (bool, error) {
+	return call(ctx, r.injector, "TransactionRepository.TransactionExists", func() (bool, error) {
+		return r.next.TransactionExists(ctx, transactionID)
+	})
+}
+
+// approvalAuditRepository injects faults into an approval audit repository
+type approvalAuditRepository struct {
+	next     persistence.ApprovalAuditRepository
+	injector *Injector
+}
+
+// Record calls the wrapped Record under the injector's faults
+func (r *approvalAuditRepository) Record(ctx context.Context, event *entity.ApprovalEvent) error {
+	return exec(ctx, r.injector, "ApprovalAuditRepository.Record", func() error {
+		return r.next.Record(ctx, event)
+	})
+}
+
+// ListByTransaction calls the wrapped ListByTransaction under the injector's faults
+func (r *approvalAuditRepository) ListByTransaction(ctx context.Context, transactionID string) ([]*entity.ApprovalEvent, error) {
+	return call(ctx, r.injector, "ApprovalAuditRepository.ListByTransaction", func() ([]*entity.ApprovalEvent, error) {
+		return r.next.ListByTransaction(ctx, transactionID)
+	})
+}
+
+// UserLockRepository injects faults into a user lock repository
+type UserLockRepository struct {
+	next     persistence.UserLockRepository
+	injector *Injector
+}
+
+// WrapUserLockRepository wraps a user lock repository with the injector's faults
+// A fault after AcquireLock leaves the lock held until it expires, as a lost reply would.
+func WrapUserLockRepository(next persistence.UserLockRepository, injector *Injector) *UserLockRepository {
+	return &UserLockRepository{next: next, injector: injector}
+}
+
+// AcquireLock calls the wrapped AcquireLock under the injector's faults
+func (r *UserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (persistence.LockToken, error) {
+	return call(ctx, r.injector, "UserLockRepository.AcquireLock", func() (persistence.LockToken, error) {
+		return r.next.AcquireLock(ctx, userID, duration)
+	})
+}
+
+// ReleaseLock calls the wrapped ReleaseLock under the injector's faults
+func (r *UserLockRepository) ReleaseLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	return exec(ctx, r.injector, "UserLockRepository.ReleaseLock", func() error {
+		return r.next.ReleaseLock(ctx, userID, token)
+	})
+}
+
+// RenewLock calls the wrapped RenewLock under the injector's faults
+func (r *UserLockRepository) RenewLock(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration) error {
+	return exec(ctx, r.injector, "UserLockRepository.RenewLock", func() error {
+		return r.next.RenewLock(ctx, userID, token, duration)
+	})
+}
+
+// VerifyLock calls the wrapped VerifyLock under the injector's faults
+func (r *UserLockRepository) VerifyLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	return exec(ctx, r.injector, "UserLockRepository.VerifyLock", func() error {
+		return r.next.VerifyLock(ctx, userID, token)
+	})
+}
+
+// CleanupExpiredLocks calls the wrapped CleanupExpiredLocks under the injector's faults
+func (r *UserLockRepository) CleanupExpiredLocks(ctx context.Context) error {
+	return exec(ctx, r.injector, "UserLockRepository.CleanupExpiredLocks", func() error {
+		return r.next.CleanupExpiredLocks(ctx)
+	})
+}
+
+// AtomicTransactionRepository injects faults into the fast path's repository
+type AtomicTransactionRepository struct {
+	next     persistence.AtomicTransactionRepository
+	injector *Injector
+}
+
+// WrapAtomicTransactionRepository wraps an atomic transaction repository with the injector's faults
+func WrapAtomicTransactionRepository(next persistence.AtomicTransactionRepository, injector *Injector) *AtomicTransactionRepository {
+	return &AtomicTransactionRepository{next: next, injector: injector}
+}
+
+// appliedTransaction is what ApplyTransaction returns besides its error
+type appliedTransaction struct {
+	txn    *entity.Transaction
+	stored bool
+}
+
+// ApplyTransaction calls the wrapped ApplyTransaction under the injector's faults
+func (r *AtomicTransactionRepository) ApplyTransaction(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+	applied, err := call(ctx, r.injector, "AtomicTransactionRepository.ApplyTransaction", func() (appliedTransaction, error) {
+		result, stored, err := r.next.ApplyTransaction(ctx, txn)
+		return appliedTransaction{txn: result, stored: stored}, err
+	})
+	return applied.txn, applied.stored, err
+}
@@ -0,0 +1,264 @@
+package faultinject
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/database"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/repository"
+	timeprovider "github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/time"
+	persistencemocks "github.com/amirhossein-jamali/balance-processor/mocks/port/persistence"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestUnitOfWork_Phases(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Before skips the call", func(t *testing.T) {
+		next := persistencemocks.NewMockUnitOfWork(t)
+		injector := NewInjector(1)
+		require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Commit", Error: ErrorSerialization}))
+
+		err := WrapUnitOfWork(next, injector).Commit(ctx)
+		assert.ErrorIs(t, err, errs.ErrConcurrencyConflict)
+	})
+
+	t.Run("After makes the call and replaces its result", func(t *testing.T) {
+		next := persistencemocks.NewMockUnitOfWork(t)
+		next.EXPECT().Commit(ctx).Return(nil).Once()
+		injector := NewInjector(1)
+		require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Commit", Error: ErrorConnection, Phase: PhaseAfter}))
+
+		err := WrapUnitOfWork(next, injector).Commit(ctx)
+		assert.ErrorIs(t, err, errs.ErrDatabaseConnection)
+	})
+
+	t.Run("A failing call keeps its own error", func(t *testing.T) {
+		next := persistencemocks.NewMockUnitOfWork(t)
+		next.EXPECT().Commit(ctx).Return(errs.ErrConstraintViolation).Once()
+		injector := NewInjector(1)
+		require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Commit", Error: ErrorConnection, Phase: PhaseAfter}))
+
+		err := WrapUnitOfWork(next, injector).Commit(ctx)
+		assert.ErrorIs(t, err, errs.ErrConstraintViolation)
+	})
+
+	t.Run("Begin after the call rolls the transaction back", func(t *testing.T) {
+		txCtx := context.WithValue(ctx, struct{}{}, "tx")
+		next := persistencemocks.NewMockUnitOfWork(t)
+		next.EXPECT().Begin(ctx).Return(txCtx, nil).Once()
+		next.EXPECT().Rollback(txCtx).Return(nil).Once()
+		injector := NewInjector(1)
+		require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Begin", Error: ErrorConnection, Phase: PhaseAfter}))
+
+		got, err := WrapUnitOfWork(next, injector).Begin(ctx)
+		assert.ErrorIs(t, err, errs.ErrDatabaseConnection)
+		assert.Equal(t, ctx, got)
+	})
+
+	t.Run("Rollback always rolls back", func(t *testing.T) {
+		next := persistencemocks.NewMockUnitOfWork(t)
+		next.EXPECT().Rollback(ctx).Return(nil).Once()
+		injector := NewInjector(1)
+		require.NoError(t, injector.Set(Fault{Method: "UnitOfWork.Rollback", Error: ErrorConnection}))
+
+		err := WrapUnitOfWork(next, injector).Rollback(ctx)
+		assert.ErrorIs(t, err, errs.ErrDatabaseConnection)
+	})
+
+	t.Run("Repositories handed out are wrapped", func(t *testing.T) {
+		next := persistencemocks.NewMockUnitOfWork(t)
+		next.EXPECT().GetUserRepository(ctx).Return(persistencemocks.NewMockUserRepository(t)).Once()
+		next.EXPECT().GetTransactionRepository(ctx).Return(persistencemocks.NewMockTransactionRepository(t)).Once()
+		next.EXPECT().GetApprovalAuditRepository(ctx).Return(persistencemocks.NewMockApprovalAuditRepository(t)).Once()
+		injector := NewInjector(1)
+		require.NoError(t, injector.Set(Fault{Method: "*", Error: ErrorInternal}))
+		uow := WrapUnitOfWork(next, injector)
+
+		_, err := uow.GetUserRepository(ctx).GetByID(ctx, 1)
+		assert.ErrorIs(t, err, errs.ErrInternalServer)
+		_, err = uow.GetTransactionRepository(ctx).TransactionExists(ctx, "tx-1")
+		assert.ErrorIs(t, err, errs.ErrInternalServer)
+		err = uow.GetApprovalAuditRepository(ctx).Record(ctx, &entity.ApprovalEvent{})
+		assert.ErrorIs(t, err, errs.ErrInternalServer)
+	})
+}
+
+// sqliteFixture wires a TransactionManager to SQLite-backed ports wrapped by an injector
+type sqliteFixture struct {
+	db       *gorm.DB
+	injector *Injector
+	manager  *transactionUseCase.TransactionManager
+}
+
+func newSQLiteFixture(t *testing.T) *sqliteFixture {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "faults.db") + "?_txlock=immediate&_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Transaction{}, &model.UserLock{}))
+	require.NoError(t, db.Create(&model.User{ID: 1, Balance: entity.MoneyFromCents(10000)}).Error)
+
+	log := logger.NewNoopLogger()
+	tp := timeprovider.NewRealTimeProvider()
+	injector := NewInjector(1)
+
+	uow := WrapUnitOfWork(database.NewSQLiteUnitOfWork(db, log, tp), injector)
+	lockRepo := WrapUserLockRepository(repository.NewSQLiteUserLockRepository(db, tp, log), injector)
+	manager := transactionUseCase.NewTransactionManager(uow, lockRepo, tp, log).
+		WithRetryPolicy(transactionUseCase.RetryPolicy{MaxRetries: 3})
+
+	return &sqliteFixture{db: db, injector: injector, manager: manager}
+}
+
+func (f *sqliteFixture) balance(t *testing.T) int64 {
+	t.Helper()
+	var user model.User
+	require.NoError(t, f.db.First(&user, 1).Error)
+	return user.Balance.Cents()
+}
+
+func (f *sqliteFixture) stored(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	require.NoError(t, f.db.Model(&model.Transaction{}).Count(&count).Error)
+	return count
+}
+
+func TestTransactionManager_InjectedFaults(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Serialization failure on commit is retried", func(t *testing.T) {
+		f := newSQLiteFixture(t)
+		require.NoError(t, f.injector.Set(Fault{Method: "UnitOfWork.Commit", Error: ErrorSerialization, Count: 2}))
+
+		txn, err := f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "win", "10.00")
+		require.NoError(t, err)
+		assert.Equal(t, "110.00", txn.GetResultBalance())
+		assert.Equal(t, int64(11000), f.balance(t))
+		assert.Equal(t, int64(2), f.manager.RetryMetrics().Retries)
+		assert.Equal(t, int64(3), f.injector.Stats()["UnitOfWork.Commit"].Calls)
+	})
+
+	t.Run("Lost commit reply is retried without applying twice", func(t *testing.T) {
+		f := newSQLiteFixture(t)
+		require.NoError(t, f.injector.Set(Fault{Method: "UnitOfWork.Commit", Error: ErrorDeadlock, Phase: PhaseAfter, Count: 1}))
+
+		txn, err := f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "win", "10.00")
+		require.NoError(t, err)
+		assert.Equal(t, "110.00", txn.GetResultBalance())
+		assert.Equal(t, int64(11000), f.balance(t))
+		assert.Equal(t, int64(1), f.stored(t))
+		assert.Equal(t, int64(1), f.manager.RetryMetrics().Retries)
+	})
+
+	t.Run("Lock lost before commit is retried", func(t *testing.T) {
+		f := newSQLiteFixture(t)
+		require.NoError(t, f.injector.Set(Fault{Method: "UserLockRepository.VerifyLock", Error: ErrorLockLost, Count: 1}))
+
+		_, err := f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "lose", "10.00")
+		require.NoError(t, err)
+		assert.Equal(t, int64(9000), f.balance(t))
+		assert.Equal(t, int64(1), f.manager.RetryMetrics().Retries)
+	})
+
+	t.Run("Retries run out", func(t *testing.T) {
+		f := newSQLiteFixture(t)
+		require.NoError(t, f.injector.Set(Fault{Method: "UserRepository.Update", Error: ErrorDeadlock}))
+
+		_, err := f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "win", "10.00")
+		assert.ErrorIs(t, err, errs.ErrConcurrencyConflict)
+		assert.Equal(t, int64(10000), f.balance(t))
+		assert.Zero(t, f.stored(t), "every attempt was rolled back")
+		assert.Equal(t, int64(1), f.manager.RetryMetrics().Exhausted)
+		assert.Equal(t, int64(4), f.injector.Stats()["UserRepository.Update"].Calls)
+
+		// The lock was released after each attempt
+		f.injector.Clear()
+		_, err = f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "win", "10.00")
+		require.NoError(t, err)
+		assert.Equal(t, int64(11000), f.balance(t))
+	})
+
+	t.Run("Failures that are not retryable are returned at once", func(t *testing.T) {
+		tests := []struct {
+			fault Fault
+			want  error
+		}{
+			{Fault{Method: "UserLockRepository.AcquireLock", Error: ErrorLockTimeout}, errs.ErrUserLocked},
+			{Fault{Method: "UnitOfWork.Begin", Error: ErrorConnection}, errs.ErrDatabaseConnection},
+			{Fault{Method: "TransactionRepository.Create", Error: ErrorTimeout}, errs.ErrDatabaseConnection},
+		}
+		for _, tt := range tests {
+			t.Run(tt.fault.Method, func(t *testing.T) {
+				f := newSQLiteFixture(t)
+				require.NoError(t, f.injector.Set(tt.fault))
+
+				_, err := f.manager.ProcessTransaction(ctx, 1, "tx-1", "game", "win", "10.00")
+				assert.ErrorIs(t, err, tt.want)
+				assert.Equal(t, int64(1), f.injector.Stats()[tt.fault.Method].Calls)
+				assert.Zero(t, f.manager.RetryMetrics().Retries)
+				assert.Equal(t, int64(10000), f.balance(t))
+			})
+		}
+	})
+
+	t.Run("Balances add up under random faults", func(t *testing.T) {
+		f := newSQLiteFixture(t)
+		f.manager.WithRetryPolicy(transactionUseCase.RetryPolicy{MaxRetries: 20})
+		require.NoError(t, f.injector.Set(
+			Fault{Method: "UnitOfWork.Commit", Error: ErrorSerialization, Phase: PhaseAfter, Probability: 0.2},
+			Fault{Method: "UnitOfWork.Commit", Error: ErrorSerialization, Probability: 0.2},
+			Fault{Method: "TransactionRepository.TransactionExists", Error: ErrorDeadlock, Probability: 0.1},
+			Fault{Method: "TransactionRepository.Create", Error: ErrorDeadlock, Probability: 0.1},
+			Fault{Method: "UserLockRepository.VerifyLock", Error: ErrorLockLost, Probability: 0.1},
+		))
+
+		for n := range 50 {
+			state := "win"
+			if n%3 == 0 {
+				state = "lose"
+			}
+			_, err := f.manager.ProcessTransaction(ctx, 1, fmt.Sprintf("tx-%d", n), "game", state, "1.00")
+			require.NoError(t, err)
+		}
+		// 33 wins and 17 losses of 1.00
+		assert.Equal(t, int64(10000+3300-1700), f.balance(t))
+		assert.Equal(t, int64(50), f.stored(t))
+		assert.Positive(t, f.manager.RetryMetrics().Retries)
+	})
+}
+
+func TestUserLockRepository_Latency(t *testing.T) {
+	next := persistencemocks.NewMockUserLockRepository(t)
+	next.EXPECT().RenewLock(mock.Anything, uint64(1), mock.Anything, time.Second).Return(nil).Once()
+	injector := NewInjector(1)
+	require.NoError(t, injector.Set(Fault{Method: "UserLockRepository.RenewLock", Latency: 20 * time.Millisecond}))
+
+	start := time.Now()
+	require.NoError(t, WrapUserLockRepository(next, injector).RenewLock(context.Background(), 1, "token", time.Second))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, MethodStats{Calls: 1, Injected: 1}, injector.Stats()["UserLockRepository.RenewLock"])
+}
@@ -0,0 +1,84 @@
+package importfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// checkpointState is the content of a checkpoint file
+type checkpointState struct {
+	Source    string    `json:"source"`
+	Line      int       `json:"line"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FileCheckpoint keeps an import's checkpoint in a JSON file
+// The file names the import it belongs to, so a checkpoint is never applied to a different file.
+type FileCheckpoint struct {
+	path         string
+	source       string
+	timeProvider coreport.TimeProvider
+}
+
+// NewFileCheckpoint creates a checkpoint for importing source, kept at path
+func NewFileCheckpoint(path, source string, timeProvider coreport.TimeProvider) *FileCheckpoint {
+	return &FileCheckpoint{path: path, source: source, timeProvider: timeProvider}
+}
+
+// Load returns the saved line, or 0 if there is no checkpoint file yet
+//
+// Possible errors:
+// - ErrInvalidRequest: If the checkpoint belongs to another import
+func (c *FileCheckpoint) Load() (int, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, fmt.Errorf("invalid checkpoint file %s: %w", c.path, err)
+	}
+	if state.Source != c.source {
+		return 0, fmt.Errorf("%w: checkpoint %s belongs to an import of %s, not %s",
+			errs.ErrInvalidRequest, c.path, state.Source, c.source)
+	}
+	return state.Line, nil
+}
+
+// Save replaces the checkpoint file, so that a crash leaves either the old or the new checkpoint
+func (c *FileCheckpoint) Save(line int) error {
+	data, err := json.Marshal(checkpointState{Source: c.source, Line: line, UpdatedAt: c.timeProvider.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package importfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/importer"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpoint(t *testing.T) {
+	clock := coremocks.NewMockTimeProvider(t)
+	clock.EXPECT().Now().Return(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)).Maybe()
+	path := filepath.Join(t.TempDir(), "import.checkpoint")
+
+	checkpoint := NewFileCheckpoint(path, "/data/players.ndjson", clock)
+	line, err := checkpoint.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 0, line)
+
+	require.NoError(t, checkpoint.Save(120))
+	require.NoError(t, checkpoint.Save(250))
+	line, err = NewFileCheckpoint(path, "/data/players.ndjson", clock).Load()
+	require.NoError(t, err)
+	assert.Equal(t, 250, line)
+
+	// No temporary files are left next to the checkpoint
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = NewFileCheckpoint(path, "/data/other.ndjson", clock).Load()
+	assert.ErrorIs(t, err, errs.ErrInvalidRequest)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+	_, err = checkpoint.Load()
+	assert.Error(t, err)
+}
+
+func TestReport(t *testing.T) {
+	var out bytes.Buffer
+	report := NewReport(&out)
+	require.NoError(t, report.Report(importer.Result{
+		Line: 1, TransactionID: "t1", UserID: 1, Outcome: importer.OutcomeProcessed,
+		Status: entity.StatusCompleted, ResultBalance: "110.50",
+	}))
+	require.NoError(t, report.Report(importer.Result{Line: 2, Outcome: importer.OutcomeInvalid, Error: "invalid request"}))
+	assert.Empty(t, out.String())
+	require.NoError(t, report.Flush())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"line":1,"transactionId":"t1","userId":1,"outcome":"processed","status":"completed","resultBalance":"110.50"}`, lines[0])
+	assert.JSONEq(t, `{"line":2,"outcome":"invalid","error":"invalid request"}`, lines[1])
+}
@@ -0,0 +1,57 @@
+// Package importfile keeps the files of a bulk import: the per-line result report and the checkpoint
+package importfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/importer"
+)
+
+// reportLine is one import result as a JSON object
+type reportLine struct {
+	Line          int    `json:"line"`
+	TransactionID string `json:"transactionId,omitempty"`
+	UserID        uint64 `json:"userId,omitempty"`
+	Outcome       string `json:"outcome"`
+	Status        string `json:"status,omitempty"`
+	ResultBalance string `json:"resultBalance,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Report writes import results as newline-delimited JSON
+type Report struct {
+	mu  sync.Mutex
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewReport creates a report that writes to w; call Flush once the import has finished
+func NewReport(w io.Writer) *Report {
+	buf := bufio.NewWriter(w)
+	return &Report{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+// Report writes the result of one line
+func (r *Report) Report(result importer.Result) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(reportLine{
+		Line:          result.Line,
+		TransactionID: result.TransactionID,
+		UserID:        result.UserID,
+		Outcome:       string(result.Outcome),
+		Status:        result.Status.String(),
+		ResultBalance: result.ResultBalance,
+		Error:         result.Error,
+	})
+}
+
+// Flush writes buffered results through
+func (r *Report) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Flush()
+}
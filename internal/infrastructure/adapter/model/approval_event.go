@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// ApprovalEvent represents one entry of the approval audit trail
+type ApprovalEvent struct {
+	ID            uint64    `gorm:"primaryKey;autoIncrement"`
+	TransactionID string    `gorm:"not null;size:255;index:idx_approval_audit_transaction_id"`
+	UserID        uint64    `gorm:"not null"`
+	Action        string    `gorm:"not null;size:20"`
+	Actor         string    `gorm:"not null;size:255"`
+	Note          string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for ApprovalEvent
+func (ApprovalEvent) TableName() string {
+	return "approval_audit"
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// JobRun represents one execution of a scheduled job
+// The unique index on job name and slot lets only one instance record a run for each slot
+type JobRun struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement"`
+	JobName     string    `gorm:"not null;size:100;uniqueIndex:idx_job_runs_job_slot,priority:1"`
+	ScheduledAt time.Time `gorm:"not null;uniqueIndex:idx_job_runs_job_slot,priority:2"`
+	StartedAt   time.Time `gorm:"not null"`
+	FinishedAt  *time.Time
+	Status      string `gorm:"not null;size:20"`
+	Error       string `gorm:"type:text"`
+	Instance    string `gorm:"size:255"`
+}
+
+// TableName specifies the table name for JobRun
+func (JobRun) TableName() string {
+	return "job_runs"
+}
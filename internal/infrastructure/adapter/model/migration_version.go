@@ -12,6 +12,7 @@ type MigrationVersion struct {
 	Version   string         `gorm:"type:varchar(20);not null;index"`
 	AppliedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
 	Details   string         `gorm:"type:text;null"`
+	Checksum  string         `gorm:"type:varchar(64);not null;default:''"` // Digest of the migration's SQL; empty for schema versions recorded before versioned migrations
 	CreatedAt time.Time      `gorm:"autoCreateTime"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -2,23 +2,30 @@ package model
 
 import (
 	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
 )
 
 // Transaction represents the database model for transactions
 type Transaction struct {
-	ID            uint64    `gorm:"primaryKey;autoIncrement"`
-	UserID        uint64    `gorm:"not null;index"`
-	TransactionID string    `gorm:"uniqueIndex;not null;size:255"`
-	SourceType    string    `gorm:"not null;size:50"`
-	State         string    `gorm:"not null;size:50"`
-	Amount        string    `gorm:"not null;size:50"`
-	AmountInCents int64     `gorm:"not null"`
-	CreatedAt     time.Time `gorm:"not null"`
+	ID            uint64       `gorm:"primaryKey;autoIncrement"`
+	UserID        uint64       `gorm:"not null;index"`
+	TransactionID string       `gorm:"uniqueIndex;not null;size:255"`
+	SourceType    string       `gorm:"not null;size:50"`
+	State         string       `gorm:"not null;size:50"`
+	Amount        string       `gorm:"not null;size:50"`
+	AmountInCents entity.Money `gorm:"not null"` // Stored as cents
+	CreatedAt     time.Time    `gorm:"not null"`
 	ProcessedAt   *time.Time
 	ResultBalance string `gorm:"size:50"`
 	Status        string `gorm:"not null;size:50"`
 	ErrorMessage  string `gorm:"type:text"`
 
+	// Set only for manual adjustments made by operators
+	AdjustmentReason string `gorm:"not null;size:50;default:''"`
+	AdjustmentNote   string `gorm:"not null;type:text;default:''"`
+	Operator         string `gorm:"not null;size:255;default:''"`
+
 	// Define relationships
 	User User `gorm:"foreignKey:UserID;references:ID"`
 }
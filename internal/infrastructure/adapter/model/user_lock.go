@@ -7,6 +7,7 @@ import (
 // UserLock represents a lock on a user record for transaction processing
 type UserLock struct {
 	UserID    uint64    `gorm:"primaryKey;not null"`
+	Token     string    `gorm:"size:64;not null;default:''"` // Identifies the current holder
 	LockedAt  time.Time `gorm:"not null"`
 	ExpiresAt time.Time `gorm:"not null"`
 	CreatedAt time.Time `gorm:"not null"` // Standard GORM timestamp
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// jobLockClass is the first key of the two-int advisory lock form used for scheduled jobs
+// User locks use the single-bigint form, so the two never collide
+const jobLockClass = 0x4A4F4253 // "JOBS"
+
+// AdvisoryJobLockRepository elects the instance that runs a scheduled job with PostgreSQL advisory locks
+// Like user advisory locks, each job lock is held by its own transaction and disappears with it,
+// so an instance that dies mid-run never blocks the job for the others
+type AdvisoryJobLockRepository struct {
+	db     *gorm.DB
+	logger coreport.Logger
+}
+
+// NewAdvisoryJobLockRepository creates a new AdvisoryJobLockRepository instance
+func NewAdvisoryJobLockRepository(db *gorm.DB, logger coreport.Logger) *AdvisoryJobLockRepository {
+	return &AdvisoryJobLockRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// TryLockJob takes the job's advisory lock if no other instance holds it
+// The job name is hashed server-side into the second key
+func (r *AdvisoryJobLockRepository) TryLockJob(ctx context.Context, jobName string) (func(), bool, error) {
+	// The lock must stay held while the job runs, even if ctx ends first; unlock ends it
+	tx := r.db.WithContext(context.WithoutCancel(ctx)).Begin()
+	if tx.Error != nil {
+		return nil, false, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, tx.Error.Error())
+	}
+
+	var acquired bool
+	err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?, hashtext(?))", jobLockClass, jobName).Scan(&acquired).Error
+	if err != nil || !acquired {
+		tx.Rollback()
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+		}
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		if err := tx.Rollback().Error; err != nil {
+			r.logger.Warn("Failed to release job lock", map[string]any{
+				"job":   jobName,
+				"error": err.Error(),
+			})
+		}
+	}
+	return unlock, true, nil
+}
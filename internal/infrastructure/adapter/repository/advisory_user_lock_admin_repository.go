@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"gorm.io/gorm"
+)
+
+// advisoryUserLocksQuery lists the user advisory locks held in the current database
+// User locks use the single-bigint key form, which pg_locks reports with objsubid 1 and the key
+// split across classid (high half) and objid (low half)
+const advisoryUserLocksQuery = `
+SELECT (l.classid::bigint << 32) | l.objid::bigint AS user_id,
+       l.pid AS pid,
+       a.xact_start AS xact_start,
+       a.application_name AS application_name,
+       host(a.client_addr) AS client_addr
+FROM pg_locks l
+JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+  AND l.objsubid = 1
+  AND l.granted
+  AND l.database = (SELECT oid FROM pg_database WHERE datname = current_database())`
+
+// advisoryLockRow is one row of advisoryUserLocksQuery
+type advisoryLockRow struct {
+	UserID          uint64
+	Pid             int
+	XactStart       *time.Time
+	ApplicationName string
+	ClientAddr      *string
+}
+
+// AdvisoryUserLockAdminRepository inspects and breaks user locks held as PostgreSQL advisory locks
+// An advisory lock belongs to the session holding it, so breaking one terminates that session;
+// the holder's transaction is rolled back and its next renewal or verification fails.
+type AdvisoryUserLockAdminRepository struct {
+	db     *gorm.DB
+	logger coreport.Logger
+}
+
+// NewAdvisoryUserLockAdminRepository creates a new AdvisoryUserLockAdminRepository instance
+func NewAdvisoryUserLockAdminRepository(db *gorm.DB, logger coreport.Logger) *AdvisoryUserLockAdminRepository {
+	return &AdvisoryUserLockAdminRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListLocks returns the user advisory locks held by every session, ordered by user ID
+// Their lease is tracked by the holding process, so ExpiresAt is left zero
+func (r *AdvisoryUserLockAdminRepository) ListLocks(ctx context.Context) ([]*entity.UserLock, error) {
+	rows, err := r.lockRows(ctx, "ORDER BY user_id")
+	if err != nil {
+		return nil, err
+	}
+
+	locks := make([]*entity.UserLock, 0, len(rows))
+	for _, row := range rows {
+		lock := &entity.UserLock{
+			UserID: row.UserID,
+			Holder: advisoryLockHolder(row),
+		}
+		if row.XactStart != nil {
+			lock.LockedAt = *row.XactStart
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// ForceReleaseLock terminates the session holding the user's advisory lock
+func (r *AdvisoryUserLockAdminRepository) ForceReleaseLock(ctx context.Context, userID uint64) error {
+	rows, err := r.lockRows(ctx, "AND ((l.classid::bigint << 32) | l.objid::bigint) = ?", int64(userID))
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errs.ErrUserNotLocked
+	}
+
+	var terminated bool
+	if err := r.db.WithContext(ctx).Raw("SELECT pg_terminate_backend(?)", rows[0].Pid).Scan(&terminated).Error; err != nil {
+		r.logger.Error("Failed to terminate advisory lock holder", map[string]any{
+			"user_id": userID,
+			"pid":     rows[0].Pid,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+	if !terminated {
+		// The session ended on its own in the meantime
+		return errs.ErrUserNotLocked
+	}
+
+	r.logger.Warn("User advisory lock force-released", map[string]any{
+		"user_id": userID,
+		"pid":     rows[0].Pid,
+	})
+	return nil
+}
+
+// lockRows runs advisoryUserLocksQuery with the given suffix appended
+func (r *AdvisoryUserLockAdminRepository) lockRows(ctx context.Context, suffix string, args ...any) ([]advisoryLockRow, error) {
+	var rows []advisoryLockRow
+	if err := r.db.WithContext(ctx).Raw(advisoryUserLocksQuery+"\n"+suffix, args...).Scan(&rows).Error; err != nil {
+		r.logger.Error("Failed to list advisory user locks", map[string]any{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+	return rows, nil
+}
+
+// advisoryLockHolder describes the session holding an advisory lock
+func advisoryLockHolder(row advisoryLockRow) string {
+	holder := fmt.Sprintf("pid %d", row.Pid)
+	if row.ApplicationName != "" {
+		holder += " " + row.ApplicationName
+	}
+	if row.ClientAddr != nil {
+		holder += " from " + *row.ClientAddr
+	}
+	return holder
+}
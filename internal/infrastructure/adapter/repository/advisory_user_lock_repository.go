@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"gorm.io/gorm"
+)
+
+// AdvisoryUserLockRepository implements user locking with PostgreSQL transaction-scoped advisory locks
+// Each lock is held by a short-lived transaction on its own connection, so acquiring and releasing
+// writes no rows and leaves no dead tuples behind. The lock disappears when that transaction ends,
+// including when the connection or process dies, so there is nothing to clean up.
+//
+// The lock is deliberately not taken inside the unit of work's transaction: under SERIALIZABLE the
+// snapshot is fixed by the first statement, which would be the lock wait itself, and the work would
+// then read the balance as it was before the previous holder committed.
+type AdvisoryUserLockRepository struct {
+	db              *gorm.DB
+	logger          coreport.Logger
+	errorClassifier *ErrorClassifier
+	waitTimeout     time.Duration
+
+	mu   sync.Mutex
+	held map[uint64]*advisoryLock
+}
+
+// advisoryLock is the transaction holding an advisory lock for one user
+type advisoryLock struct {
+	token  persistence.LockToken
+	tx     *gorm.DB
+	expiry *time.Timer
+
+	mu     sync.Mutex
+	lease  uint64 // Incremented on every renewal so a superseded expiry timer does nothing
+	ended  bool   // The holding transaction has been ended and the lock released
+	pinned bool   // The holder passed VerifyLock and is committing; expiry no longer applies
+}
+
+// NewAdvisoryUserLockRepository creates a new AdvisoryUserLockRepository instance
+// A zero waitTimeout fails immediately when the user is locked; a positive one waits
+// up to that long for the current holder to finish
+func NewAdvisoryUserLockRepository(db *gorm.DB, logger coreport.Logger, waitTimeout time.Duration) *AdvisoryUserLockRepository {
+	return &AdvisoryUserLockRepository{
+		db:              db,
+		logger:          logger,
+		errorClassifier: NewErrorClassifier(),
+		waitTimeout:     waitTimeout,
+		held:            make(map[uint64]*advisoryLock),
+	}
+}
+
+// AcquireLock attempts to acquire the advisory lock for the user
+// The lock is released by ReleaseLock, or automatically once duration has elapsed
+func (r *AdvisoryUserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (persistence.LockToken, error) {
+	r.logger.Debug("Attempting to acquire advisory lock", map[string]any{
+		"user_id":      userID,
+		"duration":     duration.String(),
+		"wait_timeout": r.waitTimeout.String(),
+	})
+
+	token, err := newLockToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
+	// The holding transaction must outlive the request context; ReleaseLock or the expiry timer ends it
+	tx := r.db.WithContext(context.WithoutCancel(ctx)).Begin()
+	if tx.Error != nil {
+		return "", r.lockError(userID, tx.Error)
+	}
+
+	acquired, err := r.tryLock(ctx, tx, userID)
+	if err != nil || !acquired {
+		tx.Rollback()
+		if err != nil {
+			return "", r.lockError(userID, err)
+		}
+		r.logger.Warn("User is already locked", map[string]any{
+			"user_id": userID,
+		})
+		return "", errs.ErrUserLocked
+	}
+
+	lock := &advisoryLock{token: token, tx: tx}
+	r.scheduleExpiry(userID, lock, duration)
+
+	r.mu.Lock()
+	r.held[userID] = lock
+	r.mu.Unlock()
+
+	r.logger.Debug("Advisory lock acquired", map[string]any{
+		"user_id": userID,
+	})
+	return token, nil
+}
+
+// tryLock takes the advisory lock inside tx, waiting up to the configured timeout
+// The single-bigint key space is reserved for user locks; other advisory locks use the two-int form
+func (r *AdvisoryUserLockRepository) tryLock(ctx context.Context, tx *gorm.DB, userID uint64) (bool, error) {
+	key := int64(userID)
+
+	if r.waitTimeout <= 0 {
+		var acquired bool
+		err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&acquired).Error
+		return acquired, err
+	}
+
+	// lock_timeout makes the server give up waiting with SQLSTATE 55P03
+	timeout := fmt.Sprintf("SET LOCAL lock_timeout = %d", r.waitTimeout.Milliseconds())
+	if err := tx.WithContext(ctx).Exec(timeout).Error; err != nil {
+		return false, err
+	}
+
+	err := tx.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(?)", key).Error
+	if err != nil && r.errorClassifier.IsLockError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// lockError maps a failure to acquire the advisory lock to a domain error
+func (r *AdvisoryUserLockRepository) lockError(userID uint64, err error) error {
+	if isContextError(err) {
+		r.logger.Warn("Context timeout acquiring advisory lock", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("lock acquisition timeout: %w", err)
+	}
+
+	r.logger.Error("Database error acquiring advisory lock", map[string]any{
+		"user_id": userID,
+		"error":   err.Error(),
+	})
+	return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+}
+
+// ReleaseLock releases the advisory lock by ending its holding transaction
+// Nothing happens unless the lock is still held under the given token
+func (r *AdvisoryUserLockRepository) ReleaseLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	lock, ok := r.lookup(userID, token)
+	if !ok {
+		r.logger.Debug("No advisory lock held under this token - may have already expired", map[string]any{
+			"user_id": userID,
+		})
+		return nil
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if lock.expiry != nil {
+		lock.expiry.Stop()
+	}
+	if !lock.ended {
+		r.endLocked(userID, lock)
+	}
+
+	r.logger.Debug("Advisory lock released", map[string]any{
+		"user_id": userID,
+	})
+	return nil
+}
+
+// RenewLock pushes the lock's expiry back to duration from now
+// It also checks that the holding connection is still alive, since the lock goes with it
+func (r *AdvisoryUserLockRepository) RenewLock(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration) error {
+	lock, ok := r.lookup(userID, token)
+	if !ok {
+		return r.renewLost(userID)
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if lock.ended {
+		return r.renewLost(userID)
+	}
+
+	if err := lock.tx.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		if isContextError(err) {
+			return fmt.Errorf("lock renewal timeout: %w", err)
+		}
+		r.logger.Warn("Advisory lock connection failed", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return errs.ErrLockLost
+	}
+
+	r.scheduleExpiry(userID, lock, duration)
+	return nil
+}
+
+// renewLost logs and returns ErrLockLost for a failed renewal
+func (r *AdvisoryUserLockRepository) renewLost(userID uint64) error {
+	r.logger.Warn("Cannot renew lock, no longer held by this holder", map[string]any{
+		"user_id": userID,
+	})
+	return errs.ErrLockLost
+}
+
+// scheduleExpiry (re)starts the timer that ends the lock once duration has elapsed
+// Advisory locks never expire on their own, so this bounds how long a stuck holder can keep one.
+// The caller must hold lock.mu unless the lock is not yet shared.
+func (r *AdvisoryUserLockRepository) scheduleExpiry(userID uint64, lock *advisoryLock, duration time.Duration) {
+	if lock.expiry != nil {
+		lock.expiry.Stop()
+	}
+
+	lock.lease++
+	if duration <= 0 {
+		return
+	}
+
+	lease := lock.lease
+	lock.expiry = time.AfterFunc(duration, func() {
+		lock.mu.Lock()
+		defer lock.mu.Unlock()
+
+		// A renewal, release or pending commit since this timer was started takes precedence
+		if lock.ended || lock.pinned || lock.lease != lease {
+			return
+		}
+		r.endLocked(userID, lock)
+
+		r.logger.Warn("Advisory lock expired before release", map[string]any{
+			"user_id":  userID,
+			"duration": duration.String(),
+		})
+	})
+}
+
+// VerifyLock checks that the advisory lock is still held under the given token
+// A successful check pins the lock: it can no longer expire, only be released, so it
+// stays held until the caller's commit has finished
+func (r *AdvisoryUserLockRepository) VerifyLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	lock, ok := r.lookup(userID, token)
+	if !ok {
+		return r.lockLost(userID)
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if lock.ended {
+		return r.lockLost(userID)
+	}
+
+	// The lock vanishes with its connection, so make sure the holding session is still alive
+	if err := lock.tx.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		r.logger.Warn("Advisory lock connection failed", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return errs.ErrLockLost
+	}
+
+	lock.pinned = true
+	return nil
+}
+
+// lookup returns the lock held for the user if it was acquired under the given token
+func (r *AdvisoryUserLockRepository) lookup(userID uint64, token persistence.LockToken) (*advisoryLock, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lock, ok := r.held[userID]
+	if !ok || lock.token != token {
+		return nil, false
+	}
+	return lock, true
+}
+
+// lockLost logs and returns ErrLockLost
+func (r *AdvisoryUserLockRepository) lockLost(userID uint64) error {
+	r.logger.Warn("Lock lost before commit", map[string]any{
+		"user_id": userID,
+	})
+	return errs.ErrLockLost
+}
+
+// endLocked ends the lock's transaction, releasing the advisory lock
+// The caller must hold lock.mu
+func (r *AdvisoryUserLockRepository) endLocked(userID uint64, lock *advisoryLock) {
+	lock.ended = true
+
+	r.mu.Lock()
+	if r.held[userID] == lock {
+		delete(r.held, userID)
+	}
+	r.mu.Unlock()
+
+	// Nothing was written, so rolling back is as good as committing and never fails on conflicts
+	if err := lock.tx.Rollback().Error; err != nil {
+		r.logger.Warn("Failed to end advisory lock transaction", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// CleanupExpiredLocks is a no-op: advisory locks end with their transaction and leave nothing behind
+func (r *AdvisoryUserLockRepository) CleanupExpiredLocks(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+)
+
+// ApprovalAuditRepository implements persistence.ApprovalAuditRepository interface
+// It works unchanged on PostgreSQL and SQLite
+type ApprovalAuditRepository struct {
+	db     *gorm.DB
+	logger coreport.Logger
+}
+
+// NewApprovalAuditRepository creates a new ApprovalAuditRepository instance
+func NewApprovalAuditRepository(db *gorm.DB, logger coreport.Logger) *ApprovalAuditRepository {
+	return &ApprovalAuditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Record inserts an entry into the audit trail
+func (r *ApprovalAuditRepository) Record(ctx context.Context, event *entity.ApprovalEvent) error {
+	eventModel := model.ApprovalEvent{
+		TransactionID: event.TransactionID,
+		UserID:        event.UserID,
+		Action:        event.Action.String(),
+		Actor:         event.Actor,
+		Note:          event.Note,
+		CreatedAt:     event.CreatedAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&eventModel).Error; err != nil {
+		r.logger.Error("Failed to record approval event", map[string]any{
+			"transaction_id": event.TransactionID,
+			"action":         event.Action.String(),
+			"error":          err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	event.ID = eventModel.ID
+	return nil
+}
+
+// ListByTransaction returns a transaction's audit trail, oldest first
+func (r *ApprovalAuditRepository) ListByTransaction(ctx context.Context, transactionID string) ([]*entity.ApprovalEvent, error) {
+	var eventModels []model.ApprovalEvent
+	err := r.db.WithContext(ctx).
+		Where("transaction_id = ?", transactionID).
+		Order("id ASC").
+		Find(&eventModels).Error
+	if err != nil {
+		r.logger.Error("Failed to list approval events", map[string]any{
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	events := make([]*entity.ApprovalEvent, 0, len(eventModels))
+	for _, m := range eventModels {
+		events = append(events, &entity.ApprovalEvent{
+			ID:            m.ID,
+			TransactionID: m.TransactionID,
+			UserID:        m.UserID,
+			Action:        entity.ApprovalAction(m.Action),
+			Actor:         m.Actor,
+			Note:          m.Note,
+			CreatedAt:     m.CreatedAt,
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalAuditRepository(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mockTime := coremocks.NewMockTimeProvider(t)
+	mockTime.EXPECT().Now().Return(now).Maybe()
+
+	db := newSQLiteTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Transaction{}, &model.ApprovalEvent{}))
+	require.NoError(t, db.Create(&model.User{ID: 1, Balance: entity.MoneyFromCents(10000), CreatedAt: now, UpdatedAt: now}).Error)
+
+	txnRepo := NewTransactionRepository(db, logger.NewNoopLogger())
+	auditRepo := NewApprovalAuditRepository(db, logger.NewNoopLogger())
+
+	held := func(id string) *entity.Transaction {
+		txn, err := entity.NewTransaction(1, id, "payment", "lose", "50.00", mockTime)
+		require.NoError(t, err)
+		txn.MarkAsPendingApproval()
+		require.NoError(t, txnRepo.Create(ctx, txn))
+		return txn
+	}
+
+	t.Run("ListByStatus returns held transactions oldest first", func(t *testing.T) {
+		first, second := held("wd-1"), held("wd-2")
+		done, err := entity.NewTransaction(1, "tx-1", "game", "win", "1.00", mockTime)
+		require.NoError(t, err)
+		done.MarkAsProcessed(mockTime, entity.MoneyFromCents(10100))
+		require.NoError(t, txnRepo.Create(ctx, done))
+
+		pending, err := txnRepo.ListByStatus(ctx, entity.StatusPendingApproval, 0)
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+		assert.Equal(t, first.TransactionID, pending[0].TransactionID)
+		assert.Equal(t, second.TransactionID, pending[1].TransactionID)
+
+		pending, err = txnRepo.ListByStatus(ctx, entity.StatusPendingApproval, 1)
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+
+		// A rejected transaction leaves the queue
+		first.MarkAsRejected(mockTime, "no")
+		require.NoError(t, txnRepo.Update(ctx, first))
+		pending, err = txnRepo.ListByStatus(ctx, entity.StatusPendingApproval, 0)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, second.TransactionID, pending[0].TransactionID)
+	})
+
+	t.Run("Record and list the audit trail of one transaction", func(t *testing.T) {
+		txn := held("wd-3")
+		require.NoError(t, auditRepo.Record(ctx, entity.NewApprovalEvent(txn, entity.ApprovalRequested, "payment", "", mockTime)))
+		require.NoError(t, auditRepo.Record(ctx, entity.NewApprovalEvent(txn, entity.ApprovalApproved, " bob ", "looks fine", mockTime)))
+		require.NoError(t, auditRepo.Record(ctx, entity.NewApprovalEvent(held("wd-4"), entity.ApprovalRequested, "payment", "", mockTime)))
+
+		events, err := auditRepo.ListByTransaction(ctx, "wd-3")
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, entity.ApprovalRequested, events[0].Action)
+		assert.Equal(t, entity.ApprovalApproved, events[1].Action)
+		assert.Equal(t, "bob", events[1].Actor)
+		assert.Equal(t, "looks fine", events[1].Note)
+		assert.Equal(t, uint64(1), events[1].UserID)
+		assert.True(t, now.Equal(events[1].CreatedAt))
+
+		events, err = auditRepo.ListByTransaction(ctx, "unknown")
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+)
+
+// applyBalanceChangeSQL adds the change to the balance unless that would make it negative or
+// overflow it. The bounds come from balanceBounds, so the condition never computes a sum that
+// overflows; SQLite would turn it into a float rather than fail.
+// READ COMMITTED re-evaluates the condition against the latest committed row after waiting
+// for a concurrent writer, so the check and the update cannot be separated
+const applyBalanceChangeSQL = `UPDATE users
+SET balance = balance + ?, transaction_count = transaction_count + 1, updated_at = ?
+WHERE id = ? AND balance >= ? AND balance <= ?
+RETURNING balance`
+
+// balanceBounds returns the lowest and highest balance change can be added to
+// The result stays between zero and the largest balance int64 cents hold.
+func balanceBounds(change int64) (low, high int64) {
+	low, high = -change, math.MaxInt64
+	if change > 0 {
+		high -= change
+	}
+	return low, high
+}
+
+// AtomicTransactionRepository implements persistence.AtomicTransactionRepository interface
+// A transaction costs one conditional UPDATE and one INSERT inside a READ COMMITTED
+// transaction, instead of the lock table round trips and SERIALIZABLE reads of the locked path
+type AtomicTransactionRepository struct {
+	db              *gorm.DB
+	timeProvider    coreport.TimeProvider
+	logger          coreport.Logger
+	transactions    *TransactionRepository
+	errorClassifier *ErrorClassifier
+}
+
+// NewAtomicTransactionRepository creates a new AtomicTransactionRepository instance
+func NewAtomicTransactionRepository(db *gorm.DB, timeProvider coreport.TimeProvider, logger coreport.Logger) *AtomicTransactionRepository {
+	return &AtomicTransactionRepository{
+		db:              db,
+		timeProvider:    timeProvider,
+		logger:          logger,
+		transactions:    NewTransactionRepository(db, logger),
+		errorClassifier: NewErrorClassifier(),
+	}
+}
+
+// ApplyTransaction updates the balance first, which takes the user's row lock, then inserts
+// the transaction row with ON CONFLICT DO NOTHING. A conflicting insert means the same
+// transaction ID was already applied, so the balance update is rolled back.
+func (r *AtomicTransactionRepository) ApplyTransaction(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+	change := txn.BalanceChange().Cents()
+	low, high := balanceBounds(change)
+
+	var applied *entity.Transaction
+	var duplicate bool
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var balances []int64
+		now := r.timeProvider.Now()
+		if err := tx.Raw(applyBalanceChangeSQL, change, now, txn.UserID, low, high).Scan(&balances).Error; err != nil {
+			return err
+		}
+		if len(balances) == 0 {
+			err := r.rejectionError(tx, txn)
+			duplicate = errors.Is(err, errs.ErrDuplicateTransaction)
+			return err
+		}
+
+		processed := txn.Clone()
+		processed.MarkAsProcessed(r.timeProvider, entity.MoneyFromCents(balances[0]))
+
+		txnModel := r.transactions.entityToModel(processed)
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "transaction_id"}},
+			DoNothing: true,
+		}).Create(&txnModel)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return errs.ErrDuplicateTransaction
+		}
+
+		processed.ID = txnModel.ID
+		applied = processed
+		return nil
+	}, r.txOptions())
+
+	if duplicate {
+		// An earlier request with the same ID won; answer with its outcome
+		r.logger.Info("Transaction already applied, returning stored result", map[string]any{
+			"transaction_id": txn.TransactionID,
+			"user_id":        txn.UserID,
+		})
+		stored, err := r.transactions.GetByTransactionID(ctx, txn.TransactionID)
+		if err != nil {
+			return nil, false, err
+		}
+		return stored, true, nil
+	}
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) || errors.Is(err, errs.ErrInsufficientBalance) || errors.Is(err, errs.ErrAmountOverflow) {
+			return nil, false, err
+		}
+		if isContextError(err) {
+			return nil, false, err
+		}
+		r.logger.Error("Database error applying transaction", map[string]any{
+			"transaction_id": txn.TransactionID,
+			"user_id":        txn.UserID,
+			"error":          err.Error(),
+		})
+		return nil, false, r.errorClassifier.DomainError(err)
+	}
+
+	r.logger.Debug("Transaction applied", map[string]any{
+		"transaction_id": txn.TransactionID,
+		"user_id":        txn.UserID,
+		"new_balance":    applied.GetResultBalance(),
+	})
+	return applied, false, nil
+}
+
+// rejectionError works out why the conditional update matched no row
+// These extra reads only happen for rejected transactions. A transaction ID that is already
+// stored is reported as a duplicate: a resent debit must get the outcome it was applied with,
+// not a rejection against the balance it left behind.
+func (r *AtomicTransactionRepository) rejectionError(tx *gorm.DB, txn *entity.Transaction) error {
+	var stored int64
+	if err := tx.Model(&model.Transaction{}).Where("transaction_id = ?", txn.TransactionID).Count(&stored).Error; err != nil {
+		return err
+	}
+	if stored > 0 {
+		return errs.ErrDuplicateTransaction
+	}
+
+	var user model.User
+	if err := tx.Select("balance").First(&user, txn.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.ErrUserNotFound
+		}
+		return err
+	}
+
+	if _, err := user.Balance.Add(txn.BalanceChange()); err != nil {
+		r.logger.Warn("Balance overflow for transaction", map[string]any{
+			"transaction_id":  txn.TransactionID,
+			"user_id":         txn.UserID,
+			"current_balance": user.Balance.String(),
+			"amount":          txn.GetAmount(),
+		})
+		return err
+	}
+
+	r.logger.Warn("Insufficient balance for transaction", map[string]any{
+		"transaction_id":  txn.TransactionID,
+		"user_id":         txn.UserID,
+		"current_balance": user.Balance.String(),
+		"amount":          txn.GetAmount(),
+	})
+	return errs.NewInsufficientBalanceError(txn.UserID, txn.GetAmount(), user.Balance.String())
+}
+
+// txOptions returns READ COMMITTED on PostgreSQL; SQLite transactions are always serialisable
+func (r *AtomicTransactionRepository) txOptions() *sql.TxOptions {
+	if r.db.Dialector.Name() == "sqlite" {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomicTransactionRepository(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mockTime := coremocks.NewMockTimeProvider(t)
+	mockTime.EXPECT().Now().Return(now).Maybe()
+
+	db := newSQLiteTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Transaction{}))
+	require.NoError(t, db.Create(&model.User{ID: 1, Balance: entity.MoneyFromCents(10000), CreatedAt: now, UpdatedAt: now}).Error)
+
+	repo := NewAtomicTransactionRepository(db, mockTime, logger.NewNoopLogger())
+
+	newTxn := func(id, state, amount string, userID uint64) *entity.Transaction {
+		txn, err := entity.NewTransaction(userID, id, "game", state, amount, mockTime)
+		require.NoError(t, err)
+		return txn
+	}
+	balance := func() int64 {
+		var user model.User
+		require.NoError(t, db.First(&user, 1).Error)
+		return user.Balance.Cents()
+	}
+
+	t.Run("Win and lose update balance and record the transaction", func(t *testing.T) {
+		txn, stored, err := repo.ApplyTransaction(ctx, newTxn("tx-1", "win", "10.15", 1))
+		require.NoError(t, err)
+		assert.False(t, stored)
+		assert.Equal(t, "110.15", txn.GetResultBalance())
+		assert.Equal(t, entity.StatusCompleted, txn.Status)
+
+		txn, _, err = repo.ApplyTransaction(ctx, newTxn("tx-2", "lose", "0.15", 1))
+		require.NoError(t, err)
+		assert.Equal(t, "110.00", txn.GetResultBalance())
+		assert.Equal(t, int64(11000), balance())
+
+		var user model.User
+		require.NoError(t, db.First(&user, 1).Error)
+		assert.Equal(t, uint64(2), user.TransactionCount)
+	})
+
+	t.Run("Duplicate returns the stored result without changing the balance", func(t *testing.T) {
+		txn, stored, err := repo.ApplyTransaction(ctx, newTxn("tx-1", "win", "50.00", 1))
+		require.NoError(t, err)
+		assert.True(t, stored)
+		assert.Equal(t, "110.15", txn.GetResultBalance())
+		assert.Equal(t, "10.15", txn.GetAmount())
+		assert.Equal(t, int64(11000), balance())
+	})
+
+	t.Run("Insufficient balance leaves nothing behind", func(t *testing.T) {
+		_, _, err := repo.ApplyTransaction(ctx, newTxn("tx-3", "lose", "110.01", 1))
+		assert.ErrorIs(t, err, errs.ErrInsufficientBalance)
+		assert.Equal(t, int64(11000), balance())
+
+		var count int64
+		require.NoError(t, db.Model(&model.Transaction{}).Where("transaction_id = ?", "tx-3").Count(&count).Error)
+		assert.Zero(t, count)
+
+		// The whole balance can still be spent
+		txn, _, err := repo.ApplyTransaction(ctx, newTxn("tx-4", "lose", "110.00", 1))
+		require.NoError(t, err)
+		assert.Equal(t, "0.00", txn.GetResultBalance())
+	})
+
+	t.Run("Duplicate of a debit the balance no longer covers returns the stored result", func(t *testing.T) {
+		txn, stored, err := repo.ApplyTransaction(ctx, newTxn("tx-4", "lose", "110.00", 1))
+		require.NoError(t, err)
+		assert.True(t, stored)
+		assert.Equal(t, "0.00", txn.GetResultBalance())
+		assert.Equal(t, int64(0), balance())
+	})
+
+	t.Run("A credit the balance cannot hold leaves nothing behind", func(t *testing.T) {
+		require.NoError(t, db.Create(&model.User{ID: 2, Balance: entity.MoneyFromCents(math.MaxInt64 - 100), CreatedAt: now, UpdatedAt: now}).Error)
+
+		_, _, err := repo.ApplyTransaction(ctx, newTxn("tx-6", "win", "1.01", 2))
+		assert.ErrorIs(t, err, errs.ErrAmountOverflow)
+		var user model.User
+		require.NoError(t, db.First(&user, 2).Error)
+		assert.Equal(t, entity.MoneyFromCents(math.MaxInt64-100), user.Balance)
+
+		txn, _, err := repo.ApplyTransaction(ctx, newTxn("tx-7", "win", "1.00", 2))
+		require.NoError(t, err)
+		assert.Equal(t, entity.MoneyFromCents(math.MaxInt64), txn.ResultBalance)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		_, _, err := repo.ApplyTransaction(ctx, newTxn("tx-5", "win", "1.00", 99))
+		assert.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+}
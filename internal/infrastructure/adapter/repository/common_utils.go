@@ -1,20 +1,79 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
 )
 
+// txContextKey is the context key under which a unit of work stores its transaction
+type txContextKey struct{}
+
+// ContextWithTx returns a context carrying the given database transaction
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the database transaction stored in the context, if any
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// dbFromContext returns the transaction from the context, or db bound to ctx when there is none
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+// newLockToken generates a random token identifying one lock acquisition
+func newLockToken() (persistence.LockToken, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return persistence.LockToken(hex.EncodeToString(buf)), nil
+}
+
 // ErrorType represents the type of database error that occurred
 type ErrorType string
 
 const (
-	DuplicateKeyError ErrorType = "duplicate_key"
-	TransientError    ErrorType = "transient"
-	LockError         ErrorType = "lock"
-	ConnectionError   ErrorType = "connection"
-	ConstraintError   ErrorType = "constraint"
+	DuplicateKeyError        ErrorType = "duplicate_key"
+	TransientError           ErrorType = "transient"
+	LockError                ErrorType = "lock"
+	ConnectionError          ErrorType = "connection"
+	ConstraintError          ErrorType = "constraint"
+	ConcurrencyConflictError ErrorType = "concurrency_conflict"
+)
+
+// PostgreSQL SQLSTATE codes the classifier recognises
+const (
+	SQLStateUniqueViolation      = "23505"
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+	SQLStateLockNotAvailable     = "55P03"
 )
 
+// SQLState returns the PostgreSQL SQLSTATE code of err, or "" if it is not a PostgreSQL error
+func SQLState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
 // ErrorClassifier provides methods to classify database errors
 type ErrorClassifier struct{}
 
@@ -32,6 +91,9 @@ func (c *ErrorClassifier) Classify(err error) ErrorType {
 	if c.IsDuplicateKeyError(err) {
 		return DuplicateKeyError
 	}
+	if c.IsConcurrencyConflict(err) {
+		return ConcurrencyConflictError
+	}
 	if c.IsLockError(err) {
 		return LockError
 	}
@@ -53,6 +115,9 @@ func (c *ErrorClassifier) IsDuplicateKeyError(err error) bool {
 	if err == nil {
 		return false
 	}
+	if SQLState(err) == SQLStateUniqueViolation {
+		return true
+	}
 	return strings.Contains(err.Error(), "duplicate key") ||
 		strings.Contains(err.Error(), "UNIQUE constraint") ||
 		strings.Contains(err.Error(), "Duplicate entry")
@@ -71,15 +136,34 @@ func (c *ErrorClassifier) IsTransientError(err error) bool {
 		strings.Contains(err.Error(), "broken pipe")
 }
 
+// IsConcurrencyConflict checks if the database aborted the transaction because of a concurrent one
+// Serialization failures and deadlocks roll the whole transaction back, so it can safely be retried.
+// Only PostgreSQL reports these; the SQLSTATE code is checked rather than the message.
+func (c *ErrorClassifier) IsConcurrencyConflict(err error) bool {
+	switch SQLState(err) {
+	case SQLStateSerializationFailure, SQLStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
 // IsLockError checks if the error is due to locking
 func (c *ErrorClassifier) IsLockError(err error) bool {
 	if err == nil {
 		return false
 	}
+	switch SQLState(err) {
+	case SQLStateLockNotAvailable, SQLStateSerializationFailure, SQLStateDeadlockDetected:
+		return true
+	}
 	return strings.Contains(err.Error(), "deadlock") ||
 		strings.Contains(err.Error(), "lock wait timeout") ||
+		strings.Contains(err.Error(), "due to lock timeout") ||
+		strings.Contains(err.Error(), "55P03") ||
 		strings.Contains(err.Error(), "could not serialize access") ||
-		strings.Contains(err.Error(), "serialization failure")
+		strings.Contains(err.Error(), "serialization failure") ||
+		strings.Contains(err.Error(), "database is locked") ||
+		strings.Contains(err.Error(), "SQLITE_BUSY")
 }
 
 // IsConnectionError checks if the error is related to database connectivity
@@ -104,3 +188,13 @@ func (c *ErrorClassifier) IsConstraintError(err error) bool {
 		strings.Contains(err.Error(), "not null") ||
 		c.IsDuplicateKeyError(err)
 }
+
+// DomainError wraps an unexpected database error in the matching domain error
+// Concurrency conflicts become ErrConcurrencyConflict so callers can retry them;
+// everything else is reported as ErrDatabaseConnection.
+func (c *ErrorClassifier) DomainError(err error) error {
+	if c.IsConcurrencyConflict(err) {
+		return fmt.Errorf("%w: %s", errs.ErrConcurrencyConflict, err.Error())
+	}
+	return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClassifier_ClassifiesBySQLState(t *testing.T) {
+	classifier := NewErrorClassifier()
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected ErrorType
+	}{
+		{"SerializationFailure", &pgconn.PgError{Code: SQLStateSerializationFailure}, ConcurrencyConflictError},
+		{"DeadlockDetected", &pgconn.PgError{Code: SQLStateDeadlockDetected}, ConcurrencyConflictError},
+		{"WrappedSerializationFailure", fmt.Errorf("commit: %w", &pgconn.PgError{Code: SQLStateSerializationFailure}), ConcurrencyConflictError},
+		{"LockNotAvailable", &pgconn.PgError{Code: SQLStateLockNotAvailable}, LockError},
+		{"UniqueViolation", &pgconn.PgError{Code: SQLStateUniqueViolation}, DuplicateKeyError},
+		{"SQLiteUniqueConstraint", errors.New("UNIQUE constraint failed: transactions.transaction_id"), DuplicateKeyError},
+		{"SQLiteBusy", errors.New("database is locked (SQLITE_BUSY)"), LockError},
+		// Without a SQLSTATE the message alone does not make an error a retryable conflict
+		{"UntypedSerializationMessage", errors.New("could not serialize access due to concurrent update"), LockError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifier.Classify(tc.err))
+		})
+	}
+}
+
+func TestErrorClassifier_DomainError(t *testing.T) {
+	classifier := NewErrorClassifier()
+
+	conflict := classifier.DomainError(&pgconn.PgError{Code: SQLStateSerializationFailure, Message: "could not serialize access"})
+	assert.ErrorIs(t, conflict, errs.ErrConcurrencyConflict)
+	assert.Contains(t, conflict.Error(), "could not serialize access")
+
+	other := classifier.DomainError(errors.New("connection refused"))
+	assert.ErrorIs(t, other, errs.ErrDatabaseConnection)
+	assert.NotErrorIs(t, other, errs.ErrConcurrencyConflict)
+}
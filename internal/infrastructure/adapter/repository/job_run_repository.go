@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+)
+
+// JobRunRepository implements persistence.JobRunRepository interface
+// It works unchanged on PostgreSQL and SQLite
+type JobRunRepository struct {
+	db              *gorm.DB
+	logger          coreport.Logger
+	errorClassifier *ErrorClassifier
+}
+
+// NewJobRunRepository creates a new JobRunRepository instance
+func NewJobRunRepository(db *gorm.DB, logger coreport.Logger) *JobRunRepository {
+	return &JobRunRepository{
+		db:              db,
+		logger:          logger,
+		errorClassifier: NewErrorClassifier(),
+	}
+}
+
+// StartRun inserts the run; the unique (job_name, scheduled_at) index rejects a second run for the slot
+func (r *JobRunRepository) StartRun(ctx context.Context, run *entity.JobRun) error {
+	runModel := r.entityToModel(run)
+
+	if err := r.db.WithContext(ctx).Create(&runModel).Error; err != nil {
+		if r.errorClassifier.IsDuplicateKeyError(err) {
+			return errs.ErrJobRunExists
+		}
+		r.logger.Error("Failed to record job run", map[string]any{
+			"job":   run.JobName,
+			"error": err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	run.ID = runModel.ID
+	return nil
+}
+
+// FinishRun updates the run's outcome
+func (r *JobRunRepository) FinishRun(ctx context.Context, run *entity.JobRun) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.JobRun{}).
+		Where("id = ?", run.ID).
+		Updates(map[string]any{
+			"finished_at": run.FinishedAt,
+			"status":      string(run.Status),
+			"error":       run.Error,
+		})
+
+	if result.Error != nil {
+		r.logger.Error("Failed to record job run outcome", map[string]any{
+			"job":    run.JobName,
+			"run_id": run.ID,
+			"error":  result.Error.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+	}
+	return nil
+}
+
+// ListRuns returns the most recent runs of a job, newest first
+func (r *JobRunRepository) ListRuns(ctx context.Context, jobName string, limit int) ([]*entity.JobRun, error) {
+	var runModels []model.JobRun
+	err := r.db.WithContext(ctx).
+		Where("job_name = ?", jobName).
+		Order("scheduled_at DESC").
+		Limit(limit).
+		Find(&runModels).Error
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	runs := make([]*entity.JobRun, 0, len(runModels))
+	for i := range runModels {
+		runs = append(runs, r.modelToEntity(&runModels[i]))
+	}
+	return runs, nil
+}
+
+// entityToModel converts a job run entity to a database model
+func (r *JobRunRepository) entityToModel(run *entity.JobRun) model.JobRun {
+	return model.JobRun{
+		ID:          run.ID,
+		JobName:     run.JobName,
+		ScheduledAt: run.ScheduledAt,
+		StartedAt:   run.StartedAt,
+		FinishedAt:  run.FinishedAt,
+		Status:      string(run.Status),
+		Error:       run.Error,
+		Instance:    run.Instance,
+	}
+}
+
+// modelToEntity converts a database model to a job run entity
+func (r *JobRunRepository) modelToEntity(runModel *model.JobRun) *entity.JobRun {
+	return &entity.JobRun{
+		ID:          runModel.ID,
+		JobName:     runModel.JobName,
+		ScheduledAt: runModel.ScheduledAt,
+		StartedAt:   runModel.StartedAt,
+		FinishedAt:  runModel.FinishedAt,
+		Status:      entity.JobRunStatus(runModel.Status),
+		Error:       runModel.Error,
+		Instance:    runModel.Instance,
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRunRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.JobRun{}))
+	repo := NewJobRunRepository(db, logger.NewNoopLogger())
+
+	slot := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newRun := func(slot time.Time, instance string) *entity.JobRun {
+		return &entity.JobRun{
+			JobName:     "cleanup",
+			ScheduledAt: slot,
+			StartedAt:   slot,
+			Status:      entity.JobRunRunning,
+			Instance:    instance,
+		}
+	}
+
+	first := newRun(slot, "a")
+	require.NoError(t, repo.StartRun(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	// A second instance cannot claim the same slot
+	assert.ErrorIs(t, repo.StartRun(ctx, newRun(slot, "b")), errs.ErrJobRunExists)
+
+	first.Finish(slot.Add(time.Second), errors.New("boom"))
+	require.NoError(t, repo.FinishRun(ctx, first))
+
+	require.NoError(t, repo.StartRun(ctx, newRun(slot.Add(time.Minute), "b")))
+
+	runs, err := repo.ListRuns(ctx, "cleanup", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "b", runs[0].Instance)
+	assert.Equal(t, entity.JobRunRunning, runs[0].Status)
+	assert.Equal(t, entity.JobRunFailed, runs[1].Status)
+	assert.Equal(t, "boom", runs[1].Error)
+	assert.Equal(t, time.Second, runs[1].Duration())
+}
+
+func TestLocalJobLockRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewLocalJobLockRepository()
+
+	unlock, acquired, err := repo.TryLockJob(ctx, "cleanup")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	_, acquired, err = repo.TryLockJob(ctx, "cleanup")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Other jobs are independent
+	otherUnlock, acquired, err := repo.TryLockJob(ctx, "archive")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	otherUnlock()
+
+	unlock()
+	_, acquired, err = repo.TryLockJob(ctx, "cleanup")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
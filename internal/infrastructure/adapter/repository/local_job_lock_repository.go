@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// LocalJobLockRepository elects job runners within a single process
+// It is used with SQLite, where only one instance can share the database file anyway
+type LocalJobLockRepository struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewLocalJobLockRepository creates a new LocalJobLockRepository instance
+func NewLocalJobLockRepository() *LocalJobLockRepository {
+	return &LocalJobLockRepository{
+		held: make(map[string]bool),
+	}
+}
+
+// TryLockJob takes the job's lock if it is not already held in this process
+func (r *LocalJobLockRepository) TryLockJob(ctx context.Context, jobName string) (func(), bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.held[jobName] {
+		return nil, false, nil
+	}
+	r.held[jobName] = true
+
+	unlock := func() {
+		r.mu.Lock()
+		delete(r.held, jobName)
+		r.mu.Unlock()
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"gorm.io/gorm"
+)
+
+// SQLiteUserLockRepository implements user locking on top of the user_locks table for SQLite
+// SQLite serialises writers, so a conditional upsert is enough to take a lock atomically
+type SQLiteUserLockRepository struct {
+	db              *gorm.DB
+	timeProvider    coreport.TimeProvider
+	logger          coreport.Logger
+	errorClassifier *ErrorClassifier
+}
+
+// NewSQLiteUserLockRepository creates a new SQLiteUserLockRepository instance
+func NewSQLiteUserLockRepository(db *gorm.DB, timeProvider coreport.TimeProvider, logger coreport.Logger) *SQLiteUserLockRepository {
+	return &SQLiteUserLockRepository{
+		db:              db,
+		timeProvider:    timeProvider,
+		logger:          logger,
+		errorClassifier: NewErrorClassifier(),
+	}
+}
+
+// AcquireLock attempts to acquire a lock on the user for transaction processing
+// An existing lock is only taken over once it has expired; otherwise ErrUserLocked is returned
+func (r *SQLiteUserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (persistence.LockToken, error) {
+	r.logger.Debug("Attempting to acquire lock", map[string]any{
+		"user_id":  userID,
+		"duration": duration.String(),
+	})
+
+	token, err := newLockToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
+	now := r.timeProvider.Now()
+	expiresAt := now.Add(duration)
+
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO user_locks (user_id, token, locked_at, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token = excluded.token,
+		    locked_at = excluded.locked_at,
+		    expires_at = excluded.expires_at,
+		    updated_at = excluded.updated_at
+		WHERE user_locks.expires_at <= ?`,
+		userID, token, now, expiresAt, now, now,
+		now,
+	)
+
+	if result.Error != nil {
+		if r.errorClassifier.IsLockError(result.Error) {
+			r.logger.Warn("Database busy while acquiring lock", map[string]any{
+				"user_id": userID,
+				"error":   result.Error.Error(),
+			})
+			return "", errs.ErrUserLocked
+		}
+
+		if isContextError(result.Error) {
+			r.logger.Warn("Context timeout acquiring lock", map[string]any{
+				"user_id": userID,
+				"error":   result.Error.Error(),
+			})
+			return "", fmt.Errorf("lock acquisition timeout: %w", result.Error)
+		}
+
+		r.logger.Error("Database error acquiring lock", map[string]any{
+			"user_id": userID,
+			"error":   result.Error.Error(),
+		})
+		return "", fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+	}
+
+	// The conflict clause leaves the row untouched while an unexpired lock exists
+	if result.RowsAffected == 0 {
+		r.logger.Warn("User is already locked", map[string]any{
+			"user_id": userID,
+		})
+		return "", errs.ErrUserLocked
+	}
+
+	r.logger.Info("Lock acquired successfully", map[string]any{
+		"user_id":    userID,
+		"locked_at":  now,
+		"expires_at": expiresAt,
+	})
+	return token, nil
+}
+
+// ReleaseLock releases a previously acquired lock if it is still held under the given token
+func (r *SQLiteUserLockRepository) ReleaseLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	r.logger.Debug("Releasing lock", map[string]any{
+		"user_id": userID,
+	})
+
+	result := r.db.WithContext(ctx).Where("user_id = ? AND token = ?", userID, token).Delete(&model.UserLock{})
+
+	// The lock will expire automatically after its timeout
+	if result.Error != nil && isContextError(result.Error) {
+		r.logger.Warn("Context timeout when releasing lock, lock will expire automatically", map[string]any{
+			"user_id": userID,
+			"error":   result.Error.Error(),
+		})
+		return nil
+	}
+
+	if result.Error != nil {
+		r.logger.Error("Failed to release lock", map[string]any{
+			"user_id": userID,
+			"error":   result.Error.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+	}
+
+	if result.RowsAffected == 0 {
+		r.logger.Warn("Lock no longer held by this holder - expired or taken over", map[string]any{
+			"user_id": userID,
+		})
+		return nil
+	}
+
+	r.logger.Info("Lock released successfully", map[string]any{
+		"user_id": userID,
+	})
+	return nil
+}
+
+// RenewLock extends the lock's expiry while it is still held under the given token
+func (r *SQLiteUserLockRepository) RenewLock(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration) error {
+	now := r.timeProvider.Now()
+
+	result := r.db.WithContext(ctx).
+		Model(&model.UserLock{}).
+		Where("user_id = ? AND token = ?", userID, token).
+		Updates(map[string]any{
+			"expires_at": now.Add(duration),
+			"updated_at": now,
+		})
+
+	if result.Error != nil {
+		r.logger.Error("Failed to renew lock", map[string]any{
+			"user_id": userID,
+			"error":   result.Error.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+	}
+
+	if result.RowsAffected == 0 {
+		r.logger.Warn("Cannot renew lock, no longer held by this holder", map[string]any{
+			"user_id": userID,
+		})
+		return errs.ErrLockLost
+	}
+
+	r.logger.Debug("Lock renewed", map[string]any{
+		"user_id":    userID,
+		"expires_at": now.Add(duration),
+	})
+	return nil
+}
+
+// VerifyLock checks that the lock is still held under the given token
+// Inside the unit of work's transaction SQLite already holds the database write lock,
+// so no other connection can take the lock over before that transaction ends
+func (r *SQLiteUserLockRepository) VerifyLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	var count int64
+	err := dbFromContext(ctx, r.db).
+		Model(&model.UserLock{}).
+		Where("user_id = ? AND token = ?", userID, token).
+		Count(&count).Error
+	if err != nil {
+		r.logger.Error("Failed to verify lock", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	if count == 0 {
+		r.logger.Warn("Lock lost before commit", map[string]any{
+			"user_id": userID,
+		})
+		return errs.ErrLockLost
+	}
+	return nil
+}
+
+// CleanupExpiredLocks removes all expired locks from the database
+func (r *SQLiteUserLockRepository) CleanupExpiredLocks(ctx context.Context) error {
+	now := r.timeProvider.Now()
+
+	result := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&model.UserLock{})
+	if result.Error != nil {
+		r.logger.Error("Failed to clean up expired locks", map[string]any{
+			"error": result.Error.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+	}
+
+	r.logger.Info("Expired locks cleanup completed", map[string]any{
+		"locks_removed": result.RowsAffected,
+	})
+	return nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newSQLiteTestDB opens an isolated in-memory SQLite database with the user_locks table
+func newSQLiteTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.UserLock{}))
+
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+func TestSQLiteUserLockRepository(t *testing.T) {
+	ctx := context.Background()
+	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newRepo := func(t *testing.T, now *time.Time) *SQLiteUserLockRepository {
+		mockTime := coremocks.NewMockTimeProvider(t)
+		mockTime.EXPECT().Now().RunAndReturn(func() time.Time { return *now }).Maybe()
+		return NewSQLiteUserLockRepository(newSQLiteTestDB(t), mockTime, logger.NewNoopLogger())
+	}
+
+	t.Run("Acquire and release", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		token, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+		assert.NotEmpty(t, token)
+		require.NoError(t, repo.ReleaseLock(ctx, 1, token))
+
+		next, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+		assert.NotEqual(t, token, next)
+	})
+
+	t.Run("Held lock rejects second acquisition", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		_, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+		_, err = repo.AcquireLock(ctx, 1, 5*time.Second)
+		assert.ErrorIs(t, err, errs.ErrUserLocked)
+
+		// Other users are unaffected
+		_, err = repo.AcquireLock(ctx, 2, 5*time.Second)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Expired lock can be taken over", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		_, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+		now = baseTime.Add(6 * time.Second)
+		_, err = repo.AcquireLock(ctx, 1, 5*time.Second)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Stale holder cannot release or verify a taken-over lock", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		stale, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, repo.VerifyLock(ctx, 1, stale))
+
+		now = baseTime.Add(6 * time.Second)
+		current, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, repo.VerifyLock(ctx, 1, stale), errs.ErrLockLost)
+		require.NoError(t, repo.ReleaseLock(ctx, 1, stale))
+
+		// The new holder's lock survives the stale release
+		assert.NoError(t, repo.VerifyLock(ctx, 1, current))
+		_, err = repo.AcquireLock(ctx, 1, 5*time.Second)
+		assert.ErrorIs(t, err, errs.ErrUserLocked)
+	})
+
+	t.Run("Verify fails once the lock is released", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		token, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, repo.ReleaseLock(ctx, 1, token))
+		assert.ErrorIs(t, repo.VerifyLock(ctx, 1, token), errs.ErrLockLost)
+	})
+
+	t.Run("Renewal keeps the lock from being taken over", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		token, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+
+		now = baseTime.Add(4 * time.Second)
+		require.NoError(t, repo.RenewLock(ctx, 1, token, 5*time.Second))
+
+		// Past the original expiry, but within the renewed lease
+		now = baseTime.Add(8 * time.Second)
+		_, err = repo.AcquireLock(ctx, 1, 5*time.Second)
+		assert.ErrorIs(t, err, errs.ErrUserLocked)
+		assert.NoError(t, repo.VerifyLock(ctx, 1, token))
+	})
+
+	t.Run("Stale holder cannot renew a taken-over lock", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		stale, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+
+		now = baseTime.Add(6 * time.Second)
+		current, err := repo.AcquireLock(ctx, 1, 5*time.Second)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, repo.RenewLock(ctx, 1, stale, 5*time.Second), errs.ErrLockLost)
+		assert.NoError(t, repo.RenewLock(ctx, 1, current, 5*time.Second))
+
+		require.NoError(t, repo.ReleaseLock(ctx, 1, current))
+		assert.ErrorIs(t, repo.RenewLock(ctx, 1, current, 5*time.Second), errs.ErrLockLost)
+	})
+
+	t.Run("Cleanup removes only expired locks", func(t *testing.T) {
+		now := baseTime
+		repo := newRepo(t, &now)
+
+		_, err := repo.AcquireLock(ctx, 1, time.Second)
+		require.NoError(t, err)
+		_, err = repo.AcquireLock(ctx, 2, time.Minute)
+		require.NoError(t, err)
+
+		now = baseTime.Add(10 * time.Second)
+		require.NoError(t, repo.CleanupExpiredLocks(ctx))
+
+		var remaining []model.UserLock
+		require.NoError(t, repo.db.Find(&remaining).Error)
+		require.Len(t, remaining, 1)
+		assert.Equal(t, uint64(2), remaining[0].UserID)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -31,19 +32,25 @@ func NewTransactionRepository(db *gorm.DB, logger coreport.Logger) *TransactionR
 
 // entityToModel converts a transaction entity to a database model
 func (r *TransactionRepository) entityToModel(transaction *entity.Transaction) model.Transaction {
-	return model.Transaction{
+	transactionModel := model.Transaction{
 		UserID:        transaction.UserID,
 		TransactionID: transaction.TransactionID,
 		SourceType:    string(transaction.SourceType),
 		State:         string(transaction.State),
 		Amount:        transaction.GetAmount(),
-		AmountInCents: transaction.AmountInCents,
+		AmountInCents: transaction.Amount,
 		CreatedAt:     transaction.CreatedAt,
 		ProcessedAt:   transaction.ProcessedAt,
 		ResultBalance: transaction.GetResultBalance(),
 		Status:        string(transaction.Status),
 		ErrorMessage:  transaction.ErrorMessage,
 	}
+	if adjustment := transaction.Adjustment; adjustment != nil {
+		transactionModel.AdjustmentReason = adjustment.Reason.String()
+		transactionModel.AdjustmentNote = adjustment.Note
+		transactionModel.Operator = adjustment.Operator
+	}
+	return transactionModel
 }
 
 // Create saves a new transaction with optimized retry mechanism
@@ -72,7 +79,7 @@ func (r *TransactionRepository) Create(ctx context.Context, transaction *entity.
 			"user_id":        transaction.UserID,
 			"error":          result.Error.Error(),
 		})
-		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+		return r.errorClassifier.DomainError(result.Error)
 	}
 
 	r.logger.Info("Transaction created successfully", map[string]any{
@@ -169,23 +176,29 @@ func (r *TransactionRepository) modelToEntity(model *model.Transaction) *entity.
 
 	// Initialize a transaction struct directly since we're mapping from DB
 	transaction := &entity.Transaction{
-		ID:                   model.ID,
-		UserID:               model.UserID,
-		TransactionID:        model.TransactionID,
-		SourceType:           sourceType,
-		State:                state,
-		AmountInCents:        model.AmountInCents,
-		CreatedAt:            model.CreatedAt,
-		ProcessedAt:          model.ProcessedAt,
-		ResultBalanceInCents: 0, // Will parse from ResultBalance string
-		Status:               status,
-		ErrorMessage:         model.ErrorMessage,
+		ID:            model.ID,
+		UserID:        model.UserID,
+		TransactionID: model.TransactionID,
+		SourceType:    sourceType,
+		State:         state,
+		Amount:        model.AmountInCents,
+		CreatedAt:     model.CreatedAt,
+		ProcessedAt:   model.ProcessedAt,
+		Status:        status,
+		ErrorMessage:  model.ErrorMessage,
 	}
 
 	// Parse result balance if available
 	if model.ResultBalance != "" {
-		resultBalanceInCents, _ := entity.ValidateAndConvertAmount(model.ResultBalance)
-		transaction.ResultBalanceInCents = resultBalanceInCents
+		transaction.ResultBalance, _ = entity.ParseMoney(model.ResultBalance)
+	}
+
+	if model.AdjustmentReason != "" {
+		transaction.Adjustment = &entity.Adjustment{
+			Reason:   entity.AdjustmentReason(model.AdjustmentReason),
+			Note:     model.AdjustmentNote,
+			Operator: model.Operator,
+		}
 	}
 
 	return transaction
@@ -227,3 +240,139 @@ func (r *TransactionRepository) GetByTransactionID(ctx context.Context, transact
 
 	return transaction, nil
 }
+
+// ListByUser returns a user's transactions, newest first; a limit of 0 returns all of them
+func (r *TransactionRepository) ListByUser(ctx context.Context, userID uint64, limit int) ([]*entity.Transaction, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var transactionModels []model.Transaction
+	if err := query.Find(&transactionModels).Error; err != nil {
+		r.logger.Error("Failed to list transactions", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	transactions := make([]*entity.Transaction, 0, len(transactionModels))
+	for i := range transactionModels {
+		transactions = append(transactions, r.modelToEntity(&transactionModels[i]))
+	}
+	return transactions, nil
+}
+
+// ListByStatus returns all users' transactions with the given status, oldest first; a limit of 0 returns all of them
+func (r *TransactionRepository) ListByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error) {
+	query := r.db.WithContext(ctx).
+		Where("status = ?", string(status)).
+		Order("id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var transactionModels []model.Transaction
+	if err := query.Find(&transactionModels).Error; err != nil {
+		r.logger.Error("Failed to list transactions by status", map[string]any{
+			"status": string(status),
+			"error":  err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	transactions := make([]*entity.Transaction, 0, len(transactionModels))
+	for i := range transactionModels {
+		transactions = append(transactions, r.modelToEntity(&transactionModels[i]))
+	}
+	return transactions, nil
+}
+
+// bookedAt is the SQL for when a transaction was booked: processed, or received if it has not been
+const bookedAt = "COALESCE(processed_at, created_at)"
+
+// StreamByUser calls fn for each of a user's transactions booked in [from, to), in booking order
+func (r *TransactionRepository) StreamByUser(
+	ctx context.Context,
+	userID uint64,
+	from, to time.Time,
+	fn func(*entity.Transaction) error,
+) error {
+	query := r.db.WithContext(ctx).
+		Model(&model.Transaction{}).
+		Where("user_id = ?", userID).
+		Order(bookedAt + " ASC").
+		Order("id ASC")
+	if !from.IsZero() {
+		query = query.Where(bookedAt+" >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where(bookedAt+" < ?", to)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		r.logger.Error("Failed to stream transactions", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionModel model.Transaction
+		if err := r.db.ScanRows(rows, &transactionModel); err != nil {
+			return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+		}
+		if err := fn(r.modelToEntity(&transactionModel)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to stream transactions", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+	return nil
+}
+
+// LastCompletedBefore returns the user's last completed transaction booked before the given time
+func (r *TransactionRepository) LastCompletedBefore(ctx context.Context, userID uint64, before time.Time) (*entity.Transaction, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, string(entity.StatusCompleted)).
+		Where(bookedAt+" < ?", before).
+		Order(bookedAt + " DESC").
+		Order("id DESC")
+	return r.firstCompleted(query, userID)
+}
+
+// FirstCompleted returns the user's first completed transaction in booking order
+func (r *TransactionRepository) FirstCompleted(ctx context.Context, userID uint64) (*entity.Transaction, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, string(entity.StatusCompleted)).
+		Order(bookedAt + " ASC").
+		Order("id ASC")
+	return r.firstCompleted(query, userID)
+}
+
+// firstCompleted returns the first row of a query over a user's completed transactions
+func (r *TransactionRepository) firstCompleted(query *gorm.DB, userID uint64) (*entity.Transaction, error) {
+	var transactionModel model.Transaction
+	if err := query.Limit(1).Find(&transactionModel).Error; err != nil {
+		r.logger.Error("Failed to find completed transaction", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+	if transactionModel.ID == 0 {
+		return nil, errs.ErrTransactionNotFound
+	}
+	return r.modelToEntity(&transactionModel), nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	coremocks "github.com/amirhossein-jamali/balance-processor/mocks/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionRepository_Statements(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+
+	db := newSQLiteTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Transaction{}))
+	require.NoError(t, db.Create(&model.User{ID: 1, Balance: entity.MoneyFromCents(10000), CreatedAt: day(1), UpdatedAt: day(1)}).Error)
+	require.NoError(t, db.Create(&model.User{ID: 2, Balance: entity.MoneyFromCents(10000), CreatedAt: day(1), UpdatedAt: day(1)}).Error)
+	repo := NewTransactionRepository(db, logger.NewNoopLogger())
+
+	var now time.Time
+	clock := coremocks.NewMockTimeProvider(t)
+	clock.EXPECT().Now().RunAndReturn(func() time.Time { return now }).Maybe()
+
+	// create logs a transaction created on one day and, unless booked is zero, completed on another
+	create := func(userID uint64, id string, created, booked time.Time, result int64) {
+		now = created
+		txn, err := entity.NewTransaction(userID, id, "game", "win", "10.00", clock)
+		require.NoError(t, err)
+		if booked.IsZero() {
+			txn.MarkAsFailed(clock, "insufficient balance")
+		} else {
+			now = booked
+			txn.MarkAsProcessed(clock, entity.MoneyFromCents(result))
+		}
+		require.NoError(t, repo.Create(ctx, txn))
+	}
+
+	create(1, "t1", day(2), day(2), 11000)
+	create(1, "held", day(3), day(6), 13000) // Held for approval, booked when approved
+	create(1, "t2", day(4), day(4), 12000)
+	create(1, "failed", day(5), time.Time{}, 0)
+	create(2, "other", day(4), day(4), 11000)
+
+	stream := func(from, to time.Time) []string {
+		var ids []string
+		require.NoError(t, repo.StreamByUser(ctx, 1, from, to, func(txn *entity.Transaction) error {
+			ids = append(ids, txn.TransactionID)
+			return nil
+		}))
+		return ids
+	}
+
+	t.Run("StreamByUser streams in booking order within the period", func(t *testing.T) {
+		assert.Equal(t, []string{"t1", "t2", "failed", "held"}, stream(time.Time{}, time.Time{}))
+		assert.Equal(t, []string{"t2", "failed"}, stream(day(3), day(6)))
+		assert.Equal(t, []string{"failed", "held"}, stream(day(5), time.Time{}))
+		assert.Empty(t, stream(day(7), time.Time{}))
+	})
+
+	t.Run("StreamByUser stops at the first callback error", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := repo.StreamByUser(ctx, 1, time.Time{}, time.Time{}, func(*entity.Transaction) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("LastCompletedBefore skips failed and later transactions", func(t *testing.T) {
+		txn, err := repo.LastCompletedBefore(ctx, 1, day(6))
+		require.NoError(t, err)
+		assert.Equal(t, "t2", txn.TransactionID)
+
+		txn, err = repo.LastCompletedBefore(ctx, 1, day(7))
+		require.NoError(t, err)
+		assert.Equal(t, "held", txn.TransactionID)
+
+		_, err = repo.LastCompletedBefore(ctx, 1, day(2))
+		assert.ErrorIs(t, err, errs.ErrTransactionNotFound)
+	})
+
+	t.Run("FirstCompleted", func(t *testing.T) {
+		txn, err := repo.FirstCompleted(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "t1", txn.TransactionID)
+
+		_, err = repo.FirstCompleted(ctx, 3)
+		assert.ErrorIs(t, err, errs.ErrTransactionNotFound)
+	})
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"gorm.io/gorm"
+)
+
+// lockHolderTokenPrefix is how much of a lock token is shown to operators
+const lockHolderTokenPrefix = 8
+
+// UserLockAdminRepository inspects and breaks the locks kept in the user_locks table
+// It serves both the PostgreSQL table strategy and SQLite, which share the table layout
+type UserLockAdminRepository struct {
+	db     *gorm.DB
+	logger coreport.Logger
+}
+
+// NewUserLockAdminRepository creates a new UserLockAdminRepository instance
+func NewUserLockAdminRepository(db *gorm.DB, logger coreport.Logger) *UserLockAdminRepository {
+	return &UserLockAdminRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListLocks returns every row of the user lock table, ordered by user ID
+func (r *UserLockAdminRepository) ListLocks(ctx context.Context) ([]*entity.UserLock, error) {
+	var lockModels []model.UserLock
+	if err := r.db.WithContext(ctx).Order("user_id").Find(&lockModels).Error; err != nil {
+		r.logger.Error("Failed to list user locks", map[string]any{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	locks := make([]*entity.UserLock, 0, len(lockModels))
+	for _, l := range lockModels {
+		holder := l.Token
+		if len(holder) > lockHolderTokenPrefix {
+			holder = holder[:lockHolderTokenPrefix]
+		}
+		locks = append(locks, &entity.UserLock{
+			UserID:    l.UserID,
+			Holder:    "token " + holder,
+			LockedAt:  l.LockedAt,
+			ExpiresAt: l.ExpiresAt,
+		})
+	}
+	return locks, nil
+}
+
+// ForceReleaseLock deletes the user's lock row, expired or not
+func (r *UserLockAdminRepository) ForceReleaseLock(ctx context.Context, userID uint64) error {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserLock{})
+	if result.Error != nil {
+		r.logger.Error("Failed to force-release user lock", map[string]any{
+			"user_id": userID,
+			"error":   result.Error.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errs.ErrUserNotLocked
+	}
+
+	r.logger.Warn("User lock force-released", map[string]any{
+		"user_id": userID,
+	})
+	return nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserLockAdminRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	repo := NewUserLockAdminRepository(db, logger.NewNoopLogger())
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, lock := range []model.UserLock{
+		{UserID: 7, Token: "0123456789abcdef", LockedAt: now, ExpiresAt: now.Add(30 * time.Second), CreatedAt: now, UpdatedAt: now},
+		{UserID: 3, Token: "fedcba9876543210", LockedAt: now.Add(-time.Minute), ExpiresAt: now.Add(-30 * time.Second), CreatedAt: now, UpdatedAt: now},
+	} {
+		require.NoError(t, db.Create(&lock).Error)
+	}
+
+	locks, err := repo.ListLocks(ctx)
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	assert.Equal(t, uint64(3), locks[0].UserID)
+	assert.Equal(t, "token fedcba98", locks[0].Holder)
+	assert.True(t, locks[0].IsExpired(now))
+	assert.Equal(t, uint64(7), locks[1].UserID)
+	assert.False(t, locks[1].IsExpired(now))
+
+	require.NoError(t, repo.ForceReleaseLock(ctx, 7))
+	assert.ErrorIs(t, repo.ForceReleaseLock(ctx, 7), errs.ErrUserNotLocked)
+
+	locks, err = repo.ListLocks(ctx)
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	assert.Equal(t, uint64(3), locks[0].UserID)
+}
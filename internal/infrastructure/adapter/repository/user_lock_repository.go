@@ -9,8 +9,10 @@ import (
 
 	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
 	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
 	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserLockRepository implements user locking functionality using GORM
@@ -32,38 +34,44 @@ func NewUserLockRepository(db *gorm.DB, timeProvider coreport.TimeProvider, logg
 }
 
 // AcquireLock attempts to acquire a lock on the user for transaction processing
-// Streamlined version with simplified error handling for better performance
-func (r *UserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) error {
+// An existing lock is only taken over once it has expired; otherwise ErrUserLocked is returned
+func (r *UserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (persistence.LockToken, error) {
 	r.logger.Debug("Attempting to acquire lock", map[string]any{
 		"user_id":  userID,
 		"duration": duration.String(),
 	})
 
+	token, err := newLockToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
 	now := r.timeProvider.Now()
 	expiresAt := now.Add(duration)
 
 	// Use SQL directly for better performance with upsert logic
 	// This performs an insert or update in a single operation
-	err := r.db.WithContext(ctx).Exec(`
-		INSERT INTO user_locks (user_id, locked_at, expires_at, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?)
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO user_locks (user_id, token, locked_at, expires_at, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT (user_id) DO UPDATE 
-		SET locked_at = EXCLUDED.locked_at, 
+		SET token = EXCLUDED.token,
+		    locked_at = EXCLUDED.locked_at, 
 		    expires_at = EXCLUDED.expires_at, 
 		    updated_at = EXCLUDED.updated_at
 		WHERE user_locks.expires_at <= ?`,
-		userID, now, expiresAt, now, now, // INSERT values
+		userID, token, now, expiresAt, now, now, // INSERT values
 		now, // WHERE condition for the ON CONFLICT clause
-	).Error
+	)
 
-	if err != nil {
+	if err := result.Error; err != nil {
 		// Check if this is a unique constraint violation that wasn't caught by the ON CONFLICT clause
 		// This indicates the lock exists and hasn't expired
 		if r.errorClassifier.IsDuplicateKeyError(err) {
 			r.logger.Warn("User is already locked", map[string]any{
 				"user_id": userID,
 			})
-			return errs.ErrUserLocked
+			return "", errs.ErrUserLocked
 		}
 
 		// For context errors, return a more specific error
@@ -72,7 +80,7 @@ func (r *UserLockRepository) AcquireLock(ctx context.Context, userID uint64, dur
 				"user_id": userID,
 				"error":   err.Error(),
 			})
-			return fmt.Errorf("lock acquisition timeout: %w", err)
+			return "", fmt.Errorf("lock acquisition timeout: %w", err)
 		}
 
 		// For other database errors
@@ -80,16 +88,23 @@ func (r *UserLockRepository) AcquireLock(ctx context.Context, userID uint64, dur
 			"user_id": userID,
 			"error":   err.Error(),
 		})
-		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+		return "", fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	}
+
+	// The conflict clause leaves the row untouched while an unexpired lock exists
+	if result.RowsAffected == 0 {
+		r.logger.Warn("User is already locked", map[string]any{
+			"user_id": userID,
+		})
+		return "", errs.ErrUserLocked
 	}
 
-	// If the row was affected, we got the lock
 	r.logger.Info("Lock acquired successfully", map[string]any{
 		"user_id":    userID,
 		"locked_at":  now,
 		"expires_at": expiresAt,
 	})
-	return nil
+	return token, nil
 }
 
 // isContextError checks if an error is related to context timeout or cancellation
@@ -121,61 +136,104 @@ func isContextError(err error) bool {
 // 	return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
 // }
 
-// ReleaseLock releases a previously acquired lock with simplified approach
-func (r *UserLockRepository) ReleaseLock(ctx context.Context, userID uint64) error {
+// ReleaseLock releases a previously acquired lock
+// The row is only deleted while it still carries the caller's token, so a holder whose
+// lock expired cannot remove the lock of whoever took it over
+func (r *UserLockRepository) ReleaseLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
 	r.logger.Debug("Releasing lock", map[string]any{
 		"user_id": userID,
 	})
 
-	// First check if the lock exists - this allows us to distinguish between
-	// "lock wasn't there" vs "delete failed" cases
-	var lock model.UserLock
-	findResult := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&lock)
+	result := r.db.WithContext(ctx).Where("user_id = ? AND token = ?", userID, token).Delete(&model.UserLock{})
 
-	// If lock doesn't exist (already released or expired)
-	if errors.Is(findResult.Error, gorm.ErrRecordNotFound) {
-		r.logger.Debug("No lock found to release - may have already expired", map[string]any{
+	// If there's an error but it's a context error, don't treat it as critical
+	// The lock will expire automatically after its timeout
+	if result.Error != nil && isContextError(result.Error) {
+		r.logger.Warn("Context timeout when releasing lock, lock will expire automatically", map[string]any{
 			"user_id": userID,
+			"error":   result.Error.Error(),
 		})
 		return nil
 	}
 
-	// If there was another error finding the lock
-	if findResult.Error != nil && !isContextError(findResult.Error) {
-		r.logger.Error("Error checking lock status", map[string]any{
+	// For other errors
+	if result.Error != nil {
+		r.logger.Error("Failed to release lock", map[string]any{
 			"user_id": userID,
-			"error":   findResult.Error.Error(),
+			"error":   result.Error.Error(),
 		})
-		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, findResult.Error.Error())
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
 	}
 
-	// Delete the lock if it exists
-	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserLock{})
-
-	// If there's an error but it's a context error, don't treat it as critical
-	// The lock will expire automatically after its timeout
-	if result.Error != nil && isContextError(result.Error) {
-		r.logger.Warn("Context timeout when releasing lock, lock will expire automatically", map[string]any{
+	if result.RowsAffected == 0 {
+		r.logger.Warn("Lock no longer held by this holder - expired or taken over", map[string]any{
 			"user_id": userID,
-			"error":   result.Error.Error(),
 		})
 		return nil
 	}
 
-	// For other errors
+	r.logger.Info("Lock released successfully", map[string]any{
+		"user_id": userID,
+	})
+	return nil
+}
+
+// RenewLock extends the lock's expiry while it is still held under the given token
+func (r *UserLockRepository) RenewLock(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration) error {
+	now := r.timeProvider.Now()
+
+	result := r.db.WithContext(ctx).
+		Model(&model.UserLock{}).
+		Where("user_id = ? AND token = ?", userID, token).
+		Updates(map[string]any{
+			"expires_at": now.Add(duration),
+			"updated_at": now,
+		})
+
 	if result.Error != nil {
-		r.logger.Error("Failed to release lock", map[string]any{
+		r.logger.Error("Failed to renew lock", map[string]any{
 			"user_id": userID,
 			"error":   result.Error.Error(),
 		})
 		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, result.Error.Error())
 	}
 
-	// Success log only if a lock was actually deleted
-	if result.RowsAffected > 0 {
-		r.logger.Info("Lock released successfully", map[string]any{
+	if result.RowsAffected == 0 {
+		r.logger.Warn("Cannot renew lock, no longer held by this holder", map[string]any{
 			"user_id": userID,
 		})
+		return errs.ErrLockLost
+	}
+
+	r.logger.Debug("Lock renewed", map[string]any{
+		"user_id":    userID,
+		"expires_at": now.Add(duration),
+	})
+	return nil
+}
+
+// VerifyLock checks that the lock is still held under the given token
+// Inside the unit of work's transaction the row is read FOR SHARE, which blocks any
+// takeover until that transaction commits or rolls back
+func (r *UserLockRepository) VerifyLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	var lock model.UserLock
+	err := dbFromContext(ctx, r.db).
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("user_id = ?", userID).
+		Take(&lock).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && lock.Token != string(token)) {
+		r.logger.Warn("Lock lost before commit", map[string]any{
+			"user_id": userID,
+		})
+		return errs.ErrLockLost
+	}
+	if err != nil {
+		r.logger.Error("Failed to verify lock", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
 	}
 
 	return nil
@@ -13,8 +13,8 @@ import (
 )
 
 // getOperationType returns "credit" for positive or zero changes and "debit" for negative changes
-func getOperationType(balanceChange int64) string {
-	if balanceChange >= 0 {
+func getOperationType(balanceChange entity.Money) string {
+	if !balanceChange.IsNegative() {
 		return "credit"
 	}
 	return "debit"
@@ -40,7 +40,7 @@ func NewUserRepository(db *gorm.DB, timeProvider coreport.TimeProvider, logger c
 
 // modelToEntity converts a user model to an entity
 func (r *UserRepository) modelToEntity(userModel *model.User) (*entity.User, error) {
-	user, err := entity.NewUser(userModel.ID, entity.AmountInCentsToString(userModel.Balance), r.timeProvider)
+	user, err := entity.NewUser(userModel.ID, userModel.Balance.String(), r.timeProvider)
 	if err != nil {
 		r.logger.Error("Failed to create user entity", map[string]any{
 			"user_id": userModel.ID,
@@ -78,6 +78,14 @@ func (r *UserRepository) handleDatabaseError(operation string, err error, userID
 		return errs.ErrDuplicateUser
 	}
 
+	if r.errorClassifier.IsConcurrencyConflict(err) {
+		r.logger.Warn("Concurrent transaction conflict", map[string]any{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return r.errorClassifier.DomainError(err)
+	}
+
 	if r.errorClassifier.IsLockError(err) {
 		r.logger.Warn("User is locked by another transaction", map[string]any{
 			"user_id": userID,
@@ -86,7 +94,7 @@ func (r *UserRepository) handleDatabaseError(operation string, err error, userID
 		return errs.ErrUserLocked
 	}
 
-	return fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+	return r.errorClassifier.DomainError(err)
 }
 
 // GetByID retrieves a user by ID
@@ -125,12 +133,9 @@ func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
 		"balance": user.GetBalance(),
 	})
 
-	// Get balance in cents from user entity
-	balanceCents := user.Balance()
-
 	userModel := model.User{
 		ID:               user.ID,
-		Balance:          balanceCents,
+		Balance:          user.Balance(),
 		CreatedAt:        user.CreatedAt,
 		UpdatedAt:        user.UpdatedAt,
 		TransactionCount: user.TransactionCount,
@@ -157,13 +162,10 @@ func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
 		"tx_count": user.TransactionCount,
 	})
 
-	// Get balance in cents from user entity
-	balanceCents := user.Balance()
-
 	result := r.db.WithContext(ctx).Model(&model.User{}).
 		Where("id = ?", user.ID).
 		Updates(map[string]interface{}{
-			"balance":           balanceCents,
+			"balance":           user.Balance(),
 			"updated_at":        user.UpdatedAt,
 			"transaction_count": user.TransactionCount,
 		})
@@ -188,12 +190,12 @@ func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
 }
 
 // ProcessTransaction updates user balance atomically within a transaction
-func (r *UserRepository) ProcessTransaction(ctx context.Context, userID uint64, balanceChange int64) (*entity.User, error) {
+func (r *UserRepository) ProcessTransaction(ctx context.Context, userID uint64, balanceChange entity.Money) (*entity.User, error) {
 	r.logger.Debug("Processing transaction", map[string]any{
 		"user_id":        userID,
-		"balance_change": balanceChange,
+		"balance_change": balanceChange.Cents(),
 		"operation_type": getOperationType(balanceChange),
-		"change_amount":  entity.AmountInCentsToString(balanceChange),
+		"change_amount":  balanceChange.String(),
 	})
 
 	var user *entity.User
@@ -219,14 +221,22 @@ func (r *UserRepository) ProcessTransaction(ctx context.Context, userID uint64,
 		}
 
 		// Calculate new balance
-		newBalance := userModel.Balance + balanceChange
+		newBalance, err := userModel.Balance.Add(balanceChange)
+		if err != nil {
+			r.logger.Warn("Balance overflow for transaction", map[string]any{
+				"user_id":          userID,
+				"current_balance":  userModel.Balance.String(),
+				"requested_change": balanceChange.String(),
+			})
+			return err
+		}
 
 		// Check for negative balance
-		if newBalance < 0 {
+		if newBalance.IsNegative() {
 			r.logger.Warn("Insufficient balance for transaction", map[string]any{
 				"user_id":          userID,
-				"current_balance":  entity.AmountInCentsToString(userModel.Balance),
-				"requested_change": entity.AmountInCentsToString(balanceChange),
+				"current_balance":  userModel.Balance.String(),
+				"requested_change": balanceChange.String(),
 				"operation_type":   "debit",
 			})
 			return errs.ErrInsufficientBalance
@@ -253,7 +263,6 @@ func (r *UserRepository) ProcessTransaction(ctx context.Context, userID uint64,
 		}
 
 		// Convert model to entity
-		var err error
 		user, err = r.modelToEntity(&userModel)
 		if err != nil {
 			return err
@@ -269,10 +278,17 @@ func (r *UserRepository) ProcessTransaction(ctx context.Context, userID uint64,
 	})
 
 	if err != nil {
-		if errors.Is(err, errs.ErrUserNotFound) || errors.Is(err, errs.ErrInsufficientBalance) {
+		if errors.Is(err, errs.ErrUserNotFound) || errors.Is(err, errs.ErrInsufficientBalance) || errors.Is(err, errs.ErrAmountOverflow) {
 			// These errors are already logged above
 			return nil, err
 		}
+		if r.errorClassifier.IsConcurrencyConflict(err) {
+			r.logger.Warn("Concurrent transaction conflict", map[string]any{
+				"user_id": userID,
+				"error":   err.Error(),
+			})
+			return nil, r.errorClassifier.DomainError(err)
+		}
 		if r.errorClassifier.IsLockError(err) {
 			r.logger.Warn("User is locked by another transaction", map[string]any{
 				"user_id": userID,
@@ -284,12 +300,12 @@ func (r *UserRepository) ProcessTransaction(ctx context.Context, userID uint64,
 			"user_id": userID,
 			"error":   err.Error(),
 		})
-		return nil, fmt.Errorf("%w: %s", errs.ErrDatabaseConnection, err.Error())
+		return nil, r.errorClassifier.DomainError(err)
 	}
 
 	r.logger.Info("Transaction processed successfully", map[string]any{
 		"user_id":        userID,
-		"balance_change": entity.AmountInCentsToString(balanceChange),
+		"balance_change": balanceChange.String(),
 		"new_balance":    user.GetBalance(),
 		"operation_type": getOperationType(balanceChange),
 		"tx_count":       user.TransactionCount,
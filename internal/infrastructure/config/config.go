@@ -9,6 +9,12 @@ type Config struct {
 	Database    DatabaseConfig   `mapstructure:"database"`
 	Logger      LoggerConfig     `mapstructure:"logger"`
 	Transaction TransactionConfig `mapstructure:"transaction"`
+	Scheduler   SchedulerConfig   `mapstructure:"scheduler"`
+	Approval    ApprovalConfig    `mapstructure:"approval"`
+	Limits      LimitsConfig      `mapstructure:"limits"`
+	Admin       AdminConfig       `mapstructure:"admin"`
+	Rebuild     RebuildConfig     `mapstructure:"rebuild"`
+	FaultInjection FaultInjectionConfig `mapstructure:"faultInjection"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -24,20 +30,23 @@ type ServerConfig struct {
 
 // DatabaseConfig contains database connection settings
 type DatabaseConfig struct {
-	Driver          string        `mapstructure:"driver"`
-	Host            string        `mapstructure:"host"`
-	Port            string        `mapstructure:"port"`
-	Username        string        `mapstructure:"username"`
-	Password        string        `mapstructure:"password"`
-	Database        string        `mapstructure:"database"`
-	SSLMode         string        `mapstructure:"sslMode"`
-	MaxOpenConns    int           `mapstructure:"maxOpenConns"`
-	MaxIdleConns    int           `mapstructure:"maxIdleConns"`
-	ConnMaxLifetime time.Duration `mapstructure:"connMaxLifetime"` // minutes
-	ConnMaxIdleTime time.Duration `mapstructure:"connMaxIdleTime"` // minutes
-	QueryTimeout    time.Duration `mapstructure:"queryTimeout"`    // seconds
-	RetryAttempts   int           `mapstructure:"retryAttempts"`
-	RetryDelay      time.Duration `mapstructure:"retryDelay"` // seconds
+	Driver            string        `mapstructure:"driver"` // postgres or sqlite
+	Host              string        `mapstructure:"host"`
+	Port              string        `mapstructure:"port"`
+	Username          string        `mapstructure:"username"`
+	Password          string        `mapstructure:"password"`
+	Database          string        `mapstructure:"database"` // database name, or file path for sqlite
+	SSLMode           string        `mapstructure:"sslMode"`
+	MaxOpenConns      int           `mapstructure:"maxOpenConns"`
+	MaxIdleConns      int           `mapstructure:"maxIdleConns"`
+	ConnMaxLifetime   time.Duration `mapstructure:"connMaxLifetime"` // minutes
+	ConnMaxIdleTime   time.Duration `mapstructure:"connMaxIdleTime"` // minutes
+	QueryTimeout      time.Duration `mapstructure:"queryTimeout"`    // seconds
+	RetryAttempts     int           `mapstructure:"retryAttempts"`
+	RetryDelay        time.Duration `mapstructure:"retryDelay"`        // seconds
+	LockStrategy      string        `mapstructure:"lockStrategy"`      // table or advisory (postgres only)
+	LockWaitTimeoutMs int64         `mapstructure:"lockWaitTimeoutMs"` // advisory only; 0 fails fast when locked
+	AutoMigrate       bool          `mapstructure:"autoMigrate"`       // apply pending migrations on API startup
 }
 
 // LoggerConfig contains logger settings
@@ -51,8 +60,76 @@ type LoggerConfig struct {
 
 // TransactionConfig contains transaction processing settings
 type TransactionConfig struct {
-	ConcurrencyLevel         int   `mapstructure:"concurrencyLevel"`
-	LockTimeoutMs            int64 `mapstructure:"lockTimeoutMs"`
-	MaxRetries               int   `mapstructure:"maxRetries"`
-	UserBalanceDecimalPlaces int   `mapstructure:"userBalanceDecimalPlaces"`
+	ConcurrencyLevel         int     `mapstructure:"concurrencyLevel"` // Transactions processed at once; the rest wait for admission or are shed
+	LockTimeoutMs            int64   `mapstructure:"lockTimeoutMs"`
+	MaxRetries               int     `mapstructure:"maxRetries"` // Retries after the first attempt; 0 disables retrying
+	UserBalanceDecimalPlaces int     `mapstructure:"userBalanceDecimalPlaces"`
+	QueueWorkers             int     `mapstructure:"queueWorkers"`            // In-process per-user queue shards; 0 disables the queue
+	QueueDepthPerUser        int     `mapstructure:"queueDepthPerUser"`       // Pending transactions allowed per user before rejecting
+	HeartbeatIntervalMs      int64   `mapstructure:"heartbeatIntervalMs"`     // Lock lease renewal interval; 0 renews every third of lockTimeoutMs
+	ProcessingMode           string  `mapstructure:"processingMode"`          // "locked" (user lock + SERIALIZABLE) or "fast" (conditional update)
+	CompletedCacheSize       int     `mapstructure:"completedCacheSize"`      // Recently completed transactions answered from memory; 0 disables the cache
+	RetryBaseBackoffMs       int64   `mapstructure:"retryBaseBackoffMs"`      // Wait before the first retry; doubled for each further retry
+	RetryMaxBackoffMs        int64   `mapstructure:"retryMaxBackoffMs"`       // Upper bound on the wait between retries
+	RetryJitter              float64 `mapstructure:"retryJitter"`             // Fraction of the wait added at random (0.0-1.0)
+	AdmissionQueueSize       int     `mapstructure:"admissionQueueSize"`      // Transactions allowed to wait for admission; 0 sheds once concurrencyLevel is reached
+	AdmissionQueueTimeoutMs  int64   `mapstructure:"admissionQueueTimeoutMs"` // Longest a transaction waits for admission before it is shed
+	AdmissionRetryAfterSec   int     `mapstructure:"admissionRetryAfterSec"`  // Retry-After sent with 503 responses for shed transactions
+}
+
+// SchedulerConfig contains background job scheduling settings
+type SchedulerConfig struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	LockCleanupSchedule    string `mapstructure:"lockCleanupSchedule"`    // Cron expression or "@every <duration>"
+	ApprovalExpirySchedule string `mapstructure:"approvalExpirySchedule"` // How often held transactions past their expiry are expired
+}
+
+// ApprovalConfig contains the maker-checker settings for large transactions
+type ApprovalConfig struct {
+	AdjustmentThreshold string `mapstructure:"adjustmentThreshold"` // Admin adjustments above this amount wait for approval; "0" disables
+	WithdrawalThreshold string `mapstructure:"withdrawalThreshold"` // Payment withdrawals above this amount wait for approval; "0" disables
+	ExpiryMinutes       int    `mapstructure:"expiryMinutes"`       // Held transactions not approved within this time expire; 0 never expires them
+}
+
+// LimitsConfig contains the amount and balance limits transactions are checked against
+// The top-level limits apply to every transaction; rules override them for a source type, a state or a user.
+type LimitsConfig struct {
+	MinAmount  string            `mapstructure:"minAmount"`  // Smallest amount of any transaction; empty for no minimum
+	MaxAmount  string            `mapstructure:"maxAmount"`  // Largest amount of any transaction; empty for no maximum
+	MaxBalance string            `mapstructure:"maxBalance"` // Largest balance a credit may leave; empty for no maximum
+	Rules      []LimitRuleConfig `mapstructure:"rules"`      // The most specific rule that sets a limit wins
+}
+
+// LimitRuleConfig sets limits for the transactions it matches; empty fields match, or limit, nothing
+// A limit of "none" lifts the one a less specific rule or the top-level limits set.
+type LimitRuleConfig struct {
+	UserID     uint64 `mapstructure:"userId"`     // Override for a single user; 0 applies to everyone
+	SourceType string `mapstructure:"sourceType"` // game, server, payment or admin
+	State      string `mapstructure:"state"`      // win or lose
+	MinAmount  string `mapstructure:"minAmount"`
+	MaxAmount  string `mapstructure:"maxAmount"`
+	MaxBalance string `mapstructure:"maxBalance"`
+}
+
+// AdminConfig contains the operators allowed to use the endpoints under /admin and to record changes with bpctl
+type AdminConfig struct {
+	Operators []OperatorConfig `mapstructure:"operators"` // None disables the endpoints
+}
+
+// OperatorConfig identifies an operator by their bearer token
+type OperatorConfig struct {
+	Name        string `mapstructure:"name"`        // Recorded as the requester or approver of what the operator does
+	TokenSHA256 string `mapstructure:"tokenSha256"` // Hex SHA-256 of the operator's token; the token itself is never configured
+	UserID      uint64 `mapstructure:"userId"`      // The operator's own account, whose withdrawals they cannot approve; 0 if none
+}
+
+// RebuildConfig contains settings for rebuilding balances from the transaction log
+type RebuildConfig struct {
+	InitialBalance string `mapstructure:"initialBalance"` // Balance replays start from; empty uses the one the log implies
+}
+
+// FaultInjectionConfig contains settings for injecting persistence faults to test resilience
+type FaultInjectionConfig struct {
+	Enabled bool  `mapstructure:"enabled"` // Wrap the persistence ports so faults can be set through /admin/faults; never in production
+	Seed    int64 `mapstructure:"seed"`    // Seeds probabilistic faults; 0 picks a random seed
 }
@@ -40,8 +40,8 @@ var DotEnvPaths = []string{
 func LoadConfig() (*Config, error) {
 	// Load environment variables from .env file first
 	if err := loadDotEnvFile(); err != nil {
-		// Don't return error, just log it or continue
-		fmt.Println("Warning: Could not load .env file:", err)
+		// Don't return error, just warn on stderr so tools printing JSON keep a clean stdout
+		fmt.Fprintln(os.Stderr, "Warning: Could not load .env file:", err)
 	}
 
 	// Get environment
@@ -130,6 +130,9 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("database.queryTimeout", 5)     // seconds - Decreased for faster responses
 	v.SetDefault("database.retryAttempts", 3)    // Optimized for performance
 	v.SetDefault("database.retryDelay", 1)       // seconds - Decreased for faster recovery
+	v.SetDefault("database.lockStrategy", "table")
+	v.SetDefault("database.lockWaitTimeoutMs", 0)
+	v.SetDefault("database.autoMigrate", true)
 
 	// Logger defaults
 	v.SetDefault("logger.level", "info")        // Changed to info for better performance
@@ -142,6 +145,36 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("transaction.lockTimeoutMs", 5000)    // Optimized lock timeout
 	v.SetDefault("transaction.maxRetries", 3)
 	v.SetDefault("transaction.userBalanceDecimalPlaces", 2)
+	v.SetDefault("transaction.queueWorkers", 64)
+	v.SetDefault("transaction.queueDepthPerUser", 100)
+	v.SetDefault("transaction.heartbeatIntervalMs", 0)
+	v.SetDefault("transaction.processingMode", "locked")
+	v.SetDefault("transaction.completedCacheSize", 10000)
+	v.SetDefault("transaction.retryBaseBackoffMs", 5)
+	v.SetDefault("transaction.retryMaxBackoffMs", 200)
+	v.SetDefault("transaction.retryJitter", 0.2)
+	v.SetDefault("transaction.admissionQueueSize", 1000)
+	v.SetDefault("transaction.admissionQueueTimeoutMs", 500)
+	v.SetDefault("transaction.admissionRetryAfterSec", 1)
+
+	// Scheduler defaults
+	v.SetDefault("scheduler.enabled", true)
+	v.SetDefault("scheduler.lockCleanupSchedule", "@every 1m")
+	v.SetDefault("scheduler.approvalExpirySchedule", "@every 5m")
+
+	// Approval defaults
+	v.SetDefault("approval.adjustmentThreshold", "1000.00")
+	v.SetDefault("approval.withdrawalThreshold", "0")
+	v.SetDefault("approval.expiryMinutes", 1440)
+
+	// Limit defaults; nothing is limited unless configured
+	v.SetDefault("limits.minAmount", "")
+	v.SetDefault("limits.maxAmount", "")
+	v.SetDefault("limits.maxBalance", "")
+
+	// Fault injection defaults
+	v.SetDefault("faultInjection.enabled", false)
+	v.SetDefault("faultInjection.seed", 0)
 }
 
 // getEnvironment determines the environment to use based on BP_ENV environment variable
@@ -157,6 +190,24 @@ func getEnvironment() string {
 // processEnvOverrides ensures environment variables override config values
 // This function prioritizes environment variables over configuration file values
 func processEnvOverrides(v *viper.Viper) {
+	// Database driver selection
+	if dbDriver := os.Getenv("BP_DB_DRIVER"); dbDriver != "" {
+		v.Set("database.driver", dbDriver)
+	}
+
+	// User lock strategy
+	if lockStrategy := os.Getenv("BP_DB_LOCK_STRATEGY"); lockStrategy != "" {
+		v.Set("database.lockStrategy", lockStrategy)
+	}
+	if lockWait := getEnvInt("BP_DB_LOCK_WAIT_TIMEOUT_MS", -1); lockWait >= 0 {
+		v.Set("database.lockWaitTimeoutMs", lockWait)
+	}
+
+	// Schema migrations on startup
+	if autoMigrate := os.Getenv("BP_DB_AUTO_MIGRATE"); autoMigrate != "" {
+		v.Set("database.autoMigrate", autoMigrate == "true" || autoMigrate == "1")
+	}
+
 	// Database sensitive information
 	if dbHost := os.Getenv("BP_DB_HOST"); dbHost != "" {
 		v.Set("database.host", dbHost)
@@ -217,11 +268,100 @@ func processEnvOverrides(v *viper.Viper) {
 	if concurrencyLevel := getEnvInt("BP_TRANSACTION_CONCURRENCY_LEVEL", 0); concurrencyLevel > 0 {
 		v.Set("transaction.concurrencyLevel", concurrencyLevel)
 	}
+	if queueSize := getEnvInt("BP_TRANSACTION_ADMISSION_QUEUE_SIZE", -1); queueSize >= 0 {
+		v.Set("transaction.admissionQueueSize", queueSize)
+	}
+	if queueTimeout := getEnvInt("BP_TRANSACTION_ADMISSION_QUEUE_TIMEOUT_MS", 0); queueTimeout > 0 {
+		v.Set("transaction.admissionQueueTimeoutMs", queueTimeout)
+	}
+	if retryAfter := getEnvInt("BP_TRANSACTION_ADMISSION_RETRY_AFTER_SEC", 0); retryAfter > 0 {
+		v.Set("transaction.admissionRetryAfterSec", retryAfter)
+	}
 	if lockTimeout := getEnvInt("BP_TRANSACTION_LOCK_TIMEOUT_MS", 0); lockTimeout > 0 {
 		v.Set("transaction.lockTimeoutMs", lockTimeout)
 	}
-	if maxRetries := getEnvInt("BP_TRANSACTION_MAX_RETRIES", 0); maxRetries >= 0 {
-		v.Set("transaction.maxRetries", maxRetries) 
+	// Zero is a valid setting (no retries), so only an unset variable leaves the file value alone
+	if maxRetries := getEnvInt("BP_TRANSACTION_MAX_RETRIES", -1); maxRetries >= 0 {
+		v.Set("transaction.maxRetries", maxRetries)
+	}
+	if baseBackoff := getEnvInt("BP_TRANSACTION_RETRY_BASE_BACKOFF_MS", 0); baseBackoff > 0 {
+		v.Set("transaction.retryBaseBackoffMs", baseBackoff)
+	}
+	if maxBackoff := getEnvInt("BP_TRANSACTION_RETRY_MAX_BACKOFF_MS", 0); maxBackoff > 0 {
+		v.Set("transaction.retryMaxBackoffMs", maxBackoff)
+	}
+	if jitter := os.Getenv("BP_TRANSACTION_RETRY_JITTER"); jitter != "" {
+		if value, err := strconv.ParseFloat(jitter, 64); err == nil {
+			v.Set("transaction.retryJitter", value)
+		}
+	}
+	if queueWorkers := getEnvInt("BP_TRANSACTION_QUEUE_WORKERS", -1); queueWorkers >= 0 {
+		v.Set("transaction.queueWorkers", queueWorkers)
+	}
+	if queueDepth := getEnvInt("BP_TRANSACTION_QUEUE_DEPTH_PER_USER", 0); queueDepth > 0 {
+		v.Set("transaction.queueDepthPerUser", queueDepth)
+	}
+	if heartbeat := getEnvInt("BP_TRANSACTION_HEARTBEAT_INTERVAL_MS", 0); heartbeat > 0 {
+		v.Set("transaction.heartbeatIntervalMs", heartbeat)
+	}
+	if processingMode := os.Getenv("BP_TRANSACTION_PROCESSING_MODE"); processingMode != "" {
+		v.Set("transaction.processingMode", processingMode)
+	}
+	if cacheSize := getEnvInt("BP_TRANSACTION_COMPLETED_CACHE_SIZE", -1); cacheSize >= 0 {
+		v.Set("transaction.completedCacheSize", cacheSize)
+	}
+
+	// Scheduler settings
+	if enabled := os.Getenv("BP_SCHEDULER_ENABLED"); enabled != "" {
+		v.Set("scheduler.enabled", enabled == "true" || enabled == "1")
+	}
+	if schedule := os.Getenv("BP_SCHEDULER_LOCK_CLEANUP_SCHEDULE"); schedule != "" {
+		v.Set("scheduler.lockCleanupSchedule", schedule)
+	}
+	if schedule := os.Getenv("BP_SCHEDULER_APPROVAL_EXPIRY_SCHEDULE"); schedule != "" {
+		v.Set("scheduler.approvalExpirySchedule", schedule)
+	}
+
+	// Approval settings
+	if threshold := os.Getenv("BP_APPROVAL_ADJUSTMENT_THRESHOLD"); threshold != "" {
+		v.Set("approval.adjustmentThreshold", threshold)
+	}
+	if threshold := os.Getenv("BP_APPROVAL_WITHDRAWAL_THRESHOLD"); threshold != "" {
+		v.Set("approval.withdrawalThreshold", threshold)
+	}
+	if expiry := getEnvInt("BP_APPROVAL_EXPIRY_MINUTES", -1); expiry >= 0 {
+		v.Set("approval.expiryMinutes", expiry)
+	}
+
+	// Limit settings; rules are only read from the config file
+	if minAmount := os.Getenv("BP_LIMITS_MIN_AMOUNT"); minAmount != "" {
+		v.Set("limits.minAmount", minAmount)
+	}
+	if maxAmount := os.Getenv("BP_LIMITS_MAX_AMOUNT"); maxAmount != "" {
+		v.Set("limits.maxAmount", maxAmount)
+	}
+	if maxBalance := os.Getenv("BP_LIMITS_MAX_BALANCE"); maxBalance != "" {
+		v.Set("limits.maxBalance", maxBalance)
+	}
+
+	// Admin settings
+	if operators := os.Getenv("BP_ADMIN_OPERATORS"); operators != "" {
+		v.Set("admin.operators", parseOperators(operators))
+	}
+
+	// Rebuild settings
+	if balance := os.Getenv("BP_REBUILD_INITIAL_BALANCE"); balance != "" {
+		v.Set("rebuild.initialBalance", balance)
+	}
+
+	// Fault injection settings
+	if enabled := os.Getenv("BP_FAULT_INJECTION_ENABLED"); enabled != "" {
+		v.Set("faultInjection.enabled", enabled == "true" || enabled == "1")
+	}
+	if seed := os.Getenv("BP_FAULT_INJECTION_SEED"); seed != "" {
+		if value, err := strconv.ParseInt(seed, 10, 64); err == nil {
+			v.Set("faultInjection.seed", value)
+		}
 	}
 }
 
@@ -239,6 +379,27 @@ func getEnvInt(name string, defaultVal int) int {
 	return val
 }
 
+// parseOperators reads operators given as "name:tokenSha256[:userId]", separated by commas
+// Malformed entries are kept as far as they parse, so building the authenticator rejects them.
+func parseOperators(value string) []map[string]any {
+	var operators []map[string]any
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		operator := map[string]any{"name": parts[0]}
+		if len(parts) > 1 {
+			operator["tokenSha256"] = parts[1]
+		}
+		if len(parts) > 2 {
+			operator["userId"] = parts[2]
+		}
+		operators = append(operators, operator)
+	}
+	return operators
+}
+
 // processDurations converts time.Duration fields from their raw values to actual durations
 func processDurations(config *Config) {
 	// Convert seconds to time.Duration
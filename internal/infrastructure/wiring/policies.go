@@ -0,0 +1,69 @@
+package wiring
+
+import (
+	"fmt"
+	"time"
+
+	transactionUseCase "github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+)
+
+// Policies are the transaction policies built from the configuration
+// The API and bpctl share them, so adjustments and approvals obey the same rules either way.
+type Policies struct {
+	Approval transactionUseCase.ApprovalPolicy
+	Limit    transactionUseCase.LimitPolicy
+	Rebuild  transactionUseCase.RebuildPolicy
+}
+
+// NewPolicies builds the approval, limit and rebuild policies from the configuration
+// Possible errors:
+// - a threshold, limit or initial balance is not a valid amount; the error names the setting
+func NewPolicies(cfg *config.Config) (Policies, error) {
+	approval, err := transactionUseCase.NewApprovalPolicy(
+		cfg.Approval.AdjustmentThreshold,
+		cfg.Approval.WithdrawalThreshold,
+		time.Duration(cfg.Approval.ExpiryMinutes)*time.Minute,
+	)
+	if err != nil {
+		return Policies{}, fmt.Errorf("approval: %w", err)
+	}
+	limit, err := newLimitPolicy(cfg.Limits)
+	if err != nil {
+		return Policies{}, err
+	}
+	rebuild, err := transactionUseCase.NewRebuildPolicy(cfg.Rebuild.InitialBalance)
+	if err != nil {
+		return Policies{}, fmt.Errorf("rebuild: %w", err)
+	}
+	return Policies{Approval: approval, Limit: limit, Rebuild: rebuild}, nil
+}
+
+// Apply sets the policies on a transaction service
+func (p Policies) Apply(service *transactionUseCase.Service) *transactionUseCase.Service {
+	return service.
+		WithApprovalPolicy(p.Approval).
+		WithLimitPolicy(p.Limit).
+		WithRebuildPolicy(p.Rebuild)
+}
+
+// newLimitPolicy builds the amount and balance limits from the top-level limits and the rules that override them
+func newLimitPolicy(limits config.LimitsConfig) (transactionUseCase.LimitPolicy, error) {
+	defaults, err := transactionUseCase.NewLimitRule(0, "", "", limits.MinAmount, limits.MaxAmount, limits.MaxBalance)
+	if err != nil {
+		return transactionUseCase.LimitPolicy{}, fmt.Errorf("limits: %w", err)
+	}
+	rules := []transactionUseCase.LimitRule{defaults}
+	for i, r := range limits.Rules {
+		rule, err := transactionUseCase.NewLimitRule(r.UserID, r.SourceType, r.State, r.MinAmount, r.MaxAmount, r.MaxBalance)
+		if err != nil {
+			return transactionUseCase.LimitPolicy{}, fmt.Errorf("limits.rules[%d]: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	policy, err := transactionUseCase.NewLimitPolicy(rules...)
+	if err != nil {
+		return transactionUseCase.LimitPolicy{}, fmt.Errorf("limits: %w", err)
+	}
+	return policy, nil
+}
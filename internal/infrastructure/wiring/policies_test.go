@@ -0,0 +1,59 @@
+package wiring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPolicies(t *testing.T) {
+	cfg := &config.Config{
+		Approval: config.ApprovalConfig{AdjustmentThreshold: "100.00", ExpiryMinutes: 30},
+		Limits: config.LimitsConfig{
+			MaxAmount: "500.00",
+			Rules:     []config.LimitRuleConfig{{SourceType: "admin", MaxAmount: "1000.00"}},
+		},
+		Rebuild: config.RebuildConfig{InitialBalance: "10.00"},
+	}
+
+	policies, err := NewPolicies(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(10000), policies.Approval.AdjustmentThreshold)
+	assert.Equal(t, 30*time.Minute, policies.Approval.Expiry)
+	assert.Equal(t, "500.00", policies.Limit.For(1, entity.SourceGame, entity.StateWin).MaxAmount.String())
+	assert.Equal(t, "1000.00", policies.Limit.For(1, entity.SourceAdmin, entity.StateWin).MaxAmount.String())
+	require.NotNil(t, policies.Rebuild.InitialBalance)
+	assert.Equal(t, "10.00", policies.Rebuild.InitialBalance.String())
+}
+
+func TestNewPolicies_NamesTheInvalidSetting(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.Config
+		prefix string
+	}{
+		{name: "Approval threshold", cfg: config.Config{Approval: config.ApprovalConfig{WithdrawalThreshold: "abc"}}, prefix: "approval: "},
+		{name: "Top-level limit", cfg: config.Config{Limits: config.LimitsConfig{MinAmount: "-1"}}, prefix: "limits: "},
+		{name: "Limit rule", cfg: config.Config{Limits: config.LimitsConfig{
+			Rules: []config.LimitRuleConfig{{SourceType: "game"}, {SourceType: "game", MaxBalance: "abc"}},
+		}}, prefix: "limits.rules[1]: "},
+		{name: "Limit rules that leave no valid amount", cfg: config.Config{Limits: config.LimitsConfig{
+			MinAmount: "10.00",
+			Rules:     []config.LimitRuleConfig{{UserID: 7, MaxAmount: "5.00"}},
+		}}, prefix: "limits: "},
+		{name: "Initial balance", cfg: config.Config{Rebuild: config.RebuildConfig{InitialBalance: "abc"}}, prefix: "rebuild: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicies(&tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.prefix)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package simulation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+)
+
+// Clock is a TimeProvider whose time only moves when it is advanced
+// Sleep advances the clock instead of blocking, and the contexts returned by WithTimeout
+// are cancelled once the clock passes their deadline, so timeouts depend on the schedule
+// of the simulation rather than on how fast the machine running it is.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer
+}
+
+// clockTimer cancels a context when the clock reaches its deadline
+type clockTimer struct {
+	deadline time.Time
+	cancel   context.CancelCauseFunc
+}
+
+// NewClock creates a clock standing at start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the current virtual time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the virtual time elapsed since t
+func (c *Clock) Since(t time.Time) core.Duration {
+	return core.Duration(c.Now().Sub(t))
+}
+
+// Until returns the virtual time left until t
+func (c *Clock) Until(t time.Time) core.Duration {
+	return core.Duration(t.Sub(c.Now()))
+}
+
+// Sleep advances the clock by d without blocking
+func (c *Clock) Sleep(d core.Duration) {
+	c.Advance(d.Std())
+}
+
+// WithTimeout returns a context that is cancelled once the clock has advanced by timeout
+// Its cause is context.DeadlineExceeded; Err reports context.Canceled as for any cancelled context.
+func (c *Clock) WithTimeout(ctx context.Context, timeout core.Duration) (context.Context, context.CancelFunc) {
+	timeoutCtx, cancel := context.WithCancelCause(ctx)
+	if timeout <= 0 {
+		cancel(context.DeadlineExceeded)
+		return timeoutCtx, func() { cancel(context.Canceled) }
+	}
+
+	c.mu.Lock()
+	timer := &clockTimer{deadline: c.now.Add(timeout.Std()), cancel: cancel}
+	c.timers = append(c.timers, timer)
+	c.mu.Unlock()
+
+	return timeoutCtx, func() {
+		c.stop(timer)
+		cancel(context.Canceled)
+	}
+}
+
+// ParseDuration parses a duration string
+func (c *Clock) ParseDuration(s string) (core.Duration, error) {
+	d, err := time.ParseDuration(s)
+	return core.Duration(d), err
+}
+
+// Advance moves the clock forward by d and fires the timeouts that have passed
+func (c *Clock) Advance(d time.Duration) {
+	if d < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var fired []*clockTimer
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if c.now.Before(timer.deadline) {
+			pending = append(pending, timer)
+		} else {
+			fired = append(fired, timer)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	// Cancel outside the lock; context callbacks may read the clock
+	for _, timer := range fired {
+		timer.cancel(context.DeadlineExceeded)
+	}
+}
+
+// stop forgets a timer that was cancelled before its deadline
+func (c *Clock) stop(timer *clockTimer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, t := range c.timers {
+		if t == timer {
+			c.timers = append(c.timers[:n], c.timers[n+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// stuckTimeout is how long the scheduler waits in real time for a resumed actor to yield
+// Actors only do in-memory work between yields, so hitting it means an actor blocked on
+// something the scheduler does not control, such as a channel or a real timer.
+const stuckTimeout = 10 * time.Second
+
+// actor is a goroutine run by the scheduler
+type actor struct {
+	id     int
+	resume chan struct{}
+	wakeAt time.Time // The actor is not picked before the clock reaches this
+}
+
+// actorEvent is sent by an actor when it yields or finishes
+type actorEvent struct {
+	actor    *actor
+	finished bool
+}
+
+// actorKey is the context key of the actor a call runs on
+type actorKey struct{}
+
+// scheduler runs actors one at a time and picks the next one with a seeded random source
+// Actors only give up control at yield points, so for a given seed every run interleaves
+// them the same way regardless of how the Go runtime schedules goroutines.
+type scheduler struct {
+	rng     *rand.Rand
+	clock   *Clock
+	maxStep time.Duration // Virtual time that passes at most between two steps
+	events  chan actorEvent
+	trace   []int // Actor picked at each step
+}
+
+// newScheduler creates a scheduler
+func newScheduler(rng *rand.Rand, clock *Clock, maxStep time.Duration) *scheduler {
+	return &scheduler{
+		rng:     rng,
+		clock:   clock,
+		maxStep: maxStep,
+		events:  make(chan actorEvent),
+	}
+}
+
+// yield hands control back to the scheduler and waits until the actor is picked again
+// Calls that do not run on an actor, e.g. from setup code, return immediately.
+func (s *scheduler) yield(ctx context.Context) {
+	a, ok := ctx.Value(actorKey{}).(*actor)
+	if !ok {
+		return
+	}
+	s.events <- actorEvent{actor: a}
+	<-a.resume
+}
+
+// sleep yields and keeps the actor from being picked until the clock has advanced by d
+// The clock is moved forward when every actor is asleep.
+func (s *scheduler) sleep(ctx context.Context, d time.Duration) {
+	a, ok := ctx.Value(actorKey{}).(*actor)
+	if !ok {
+		return
+	}
+	a.wakeAt = s.clock.Now().Add(d)
+	s.yield(ctx)
+}
+
+// pick removes a random actor that is awake from runnable
+// If all of them sleep, the clock is first advanced to the earliest wake-up.
+func (s *scheduler) pick(runnable []*actor) (*actor, []*actor) {
+	now := s.clock.Now()
+	var awake []int
+	earliest := runnable[0].wakeAt
+	for n, a := range runnable {
+		if !now.Before(a.wakeAt) {
+			awake = append(awake, n)
+		}
+		if a.wakeAt.Before(earliest) {
+			earliest = a.wakeAt
+		}
+	}
+	if len(awake) == 0 {
+		s.clock.Advance(earliest.Sub(now))
+		return s.pick(runnable)
+	}
+
+	n := awake[s.rng.Intn(len(awake))]
+	a := runnable[n]
+	return a, append(runnable[:n], runnable[n+1:]...)
+}
+
+// run starts an actor for each body and steps them until all have finished
+// afterStep is called after every step with the number of the step.
+//
+// Possible errors:
+//   - an actor did not yield or finish within stuckTimeout; its goroutine is leaked
+func (s *scheduler) run(ctx context.Context, bodies []func(ctx context.Context), afterStep func(step int)) error {
+	runnable := make([]*actor, 0, len(bodies))
+	for id, body := range bodies {
+		a := &actor{id: id, resume: make(chan struct{})}
+		go func() {
+			<-a.resume
+			body(context.WithValue(ctx, actorKey{}, a))
+			s.events <- actorEvent{actor: a, finished: true}
+		}()
+		runnable = append(runnable, a)
+	}
+
+	timer := time.NewTimer(stuckTimeout)
+	defer timer.Stop()
+
+	for step := 1; len(runnable) > 0; step++ {
+		var a *actor
+		a, runnable = s.pick(runnable)
+		s.trace = append(s.trace, a.id)
+
+		if s.maxStep > 0 {
+			s.clock.Advance(time.Duration(s.rng.Int63n(int64(s.maxStep) + 1)))
+		}
+
+		timer.Reset(stuckTimeout)
+		a.resume <- struct{}{}
+		select {
+		case event := <-s.events:
+			if !event.finished {
+				runnable = append(runnable, event.actor)
+			}
+		case <-timer.C:
+			return fmt.Errorf("actor %d did not yield within %s at step %d", a.id, stuckTimeout, step)
+		}
+
+		afterStep(step)
+	}
+	return nil
+}
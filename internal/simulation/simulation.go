@@ -0,0 +1,360 @@
+// Package simulation runs the transaction service against in-memory persistence under a
+// deterministic, seeded schedule of concurrent requests and injected faults, and checks the
+// balance invariants after every step. A failing run is reproduced exactly by its seed.
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/usecase/transaction"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/faultinject"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/logger"
+)
+
+// maxViolations bounds how many violations a run records
+const maxViolations = 10
+
+// Config describes one simulated run
+type Config struct {
+	Seed           int64         // Decides the workload, the schedule and the injected faults
+	Users          int           // Users 1..Users, each starting with InitialBalance
+	InitialBalance string        // e.g. "100.00"
+	Clients        int           // Concurrent clients sending requests
+	Requests       int           // Requests generated in total, spread over the clients
+	DuplicateRate  float64       // Share of requests that resend an earlier transaction ID (0-1)
+	LoseRate       float64       // Share of new transactions that debit the balance (0-1)
+	MaxAmount      int64         // Largest transaction amount in cents
+	FaultRate      float64       // Probability of each kind of injected fault per call (0-1); 0 injects none
+	FastPath       bool          // Process transactions on the fast path instead of the locked path
+	LockTimeout    time.Duration // Virtual; a lock held longer than this can be taken over
+	MaxStep        time.Duration // Virtual time that passes at most between two steps
+	MaxRetries     int           // Retries of the transaction manager after the first attempt
+	Resends        int           // Times a client resends a request whose outcome is unknown or conflicted
+	ResendBackoff  time.Duration // Virtual; a client waits this long times the attempt before resending
+
+	weakIsolation bool // Commit without validating reads; see Store.weakIsolation
+}
+
+// DefaultConfig returns a configuration with a small number of users for many clients,
+// so lock contention, lock expiry, duplicates and faults all happen in most runs
+func DefaultConfig(seed int64) Config {
+	return Config{
+		Seed:           seed,
+		Users:          3,
+		InitialBalance: "100.00",
+		Clients:        6,
+		Requests:       60,
+		DuplicateRate:  0.2,
+		LoseRate:       0.5,
+		MaxAmount:      5000,
+		FaultRate:      0.05,
+		LockTimeout:    50 * time.Millisecond,
+		MaxStep:        5 * time.Millisecond,
+		MaxRetries:     3,
+		Resends:        5,
+		ResendBackoff:  20 * time.Millisecond,
+	}
+}
+
+// Result describes the outcome of a run
+type Result struct {
+	Seed       int64
+	Steps      int      // Times the scheduler resumed a client
+	Sent       int      // Requests sent, including resends
+	Applied    int      // Requests answered with a completed transaction
+	Rejected   int      // Requests answered with a final error, e.g. insufficient balance
+	Unresolved int      // Requests given up on after the last resend
+	Retries    int64    // Attempts retried by the transaction manager
+	Faults     int64    // Faults injected
+	Violations []string // Broken invariants, each with the step it was found at
+	Err        error    // Set when the run could not finish, e.g. a client blocked outside the scheduler
+	Trace      uint64   // Hash of the schedule and the final balances; equal seeds give equal traces
+}
+
+// Failed reports whether the run broke an invariant or could not finish
+func (r Result) Failed() bool {
+	return len(r.Violations) > 0 || r.Err != nil
+}
+
+// String summarises the run and, for a failed run, lists what went wrong
+func (r Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "seed %d: %d steps, %d sent, %d applied, %d rejected, %d unresolved, %d retries, %d faults",
+		r.Seed, r.Steps, r.Sent, r.Applied, r.Rejected, r.Unresolved, r.Retries, r.Faults)
+	if r.Err != nil {
+		fmt.Fprintf(&b, "\n  error: %v", r.Err)
+	}
+	for _, violation := range r.Violations {
+		fmt.Fprintf(&b, "\n  violation: %s", violation)
+	}
+	return b.String()
+}
+
+// request is a transaction a client sends
+type request struct {
+	userID        uint64
+	transactionID string
+	state         string
+	amount        string
+}
+
+// answer is the first successful response to a transaction ID
+type answer struct {
+	resultBalance entity.Money
+	step          int
+}
+
+// run holds the state of a simulation in progress
+// Only the client the scheduler resumed runs at any time, so none of it needs locking.
+type run struct {
+	config  Config
+	sched   *scheduler
+	store   *Store
+	service *transaction.Service
+	initial int64
+	step    int
+	answers map[string]answer
+	result  Result
+}
+
+// Run simulates the configuration and checks the invariants after every step
+//
+// Invariants:
+//   - no balance is negative
+//   - each balance equals the initial balance plus the changes of the user's completed transactions
+//   - each user's transaction count equals the number of their completed transactions
+//   - every successful response for a transaction ID reports the same result balance, the stored one
+func Run(config Config) Result {
+	rng := rand.New(rand.NewSource(config.Seed))
+	clock := NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sched := newScheduler(rng, clock, config.MaxStep)
+
+	store := NewStore(clock).WithHook(sched.yield)
+	store.weakIsolation = config.weakIsolation
+
+	r := &run{
+		config:  config,
+		sched:   sched,
+		store:   store,
+		answers: make(map[string]answer),
+		result:  Result{Seed: config.Seed},
+	}
+	initial, err := entity.ValidateAndConvertAmount(config.InitialBalance)
+	if err != nil {
+		r.result.Err = fmt.Errorf("invalid initial balance: %w", err)
+		return r.result
+	}
+	r.initial = initial
+	for id := 1; id <= config.Users; id++ {
+		user, err := entity.NewUser(uint64(id), config.InitialBalance, clock)
+		if err != nil {
+			r.result.Err = fmt.Errorf("failed to create user %d: %w", id, err)
+			return r.result
+		}
+		store.AddUser(user)
+	}
+
+	// Faults are decided by their own source so the schedule does not depend on how many were drawn
+	var uow persistence.UnitOfWork = NewUnitOfWork(store)
+	var lockRepo persistence.UserLockRepository = NewUserLockRepository(store)
+	var atomicRepo persistence.AtomicTransactionRepository = NewAtomicTransactionRepository(store)
+	var injector *faultinject.Injector
+	if config.FaultRate > 0 {
+		injector = faultinject.NewInjector(config.Seed)
+		if err := injector.Set(faults(config.FaultRate)...); err != nil {
+			r.result.Err = fmt.Errorf("invalid faults: %w", err)
+			return r.result
+		}
+		uow = faultinject.WrapUnitOfWork(uow, injector)
+		lockRepo = faultinject.WrapUserLockRepository(lockRepo, injector)
+		atomicRepo = faultinject.WrapAtomicTransactionRepository(atomicRepo, injector)
+	}
+
+	// Backoff and heartbeats wait on real timers, which would stall the schedule
+	r.service = transaction.NewTransactionService(uow, lockRepo, clock, logger.NewNoopLogger(), config.LockTimeout)
+	r.service.GetManager().
+		WithRetryPolicy(transaction.RetryPolicy{MaxRetries: config.MaxRetries}).
+		WithHeartbeatInterval(24 * time.Hour)
+	if config.FastPath {
+		r.service.WithFastPath(atomicRepo)
+	}
+	defer r.service.Shutdown()
+
+	queues := r.workload(rng)
+	bodies := make([]func(ctx context.Context), len(queues))
+	for n, queue := range queues {
+		bodies[n] = func(ctx context.Context) {
+			for _, req := range queue {
+				r.send(ctx, req)
+			}
+		}
+	}
+
+	r.result.Err = sched.run(context.Background(), bodies, func(step int) {
+		r.step = step
+		if len(r.result.Violations) == 0 {
+			r.checkBalances()
+		}
+	})
+	r.result.Steps = r.step
+	r.result.Retries = r.service.GetManager().RetryMetrics().Retries
+	if injector != nil {
+		for _, stats := range injector.Stats() {
+			r.result.Faults += stats.Injected
+		}
+	}
+	r.result.Trace = r.trace(sched.trace)
+	return r.result
+}
+
+// faults returns the faults injected into a run, each firing with the given probability
+// They cover conflicts the manager retries, failures it must not retry, and replies lost after a commit.
+func faults(rate float64) []faultinject.Fault {
+	return []faultinject.Fault{
+		{Method: "UnitOfWork.Commit", Error: faultinject.ErrorSerialization, Probability: rate},
+		{Method: "UnitOfWork.Commit", Error: faultinject.ErrorConnection, Phase: faultinject.PhaseAfter, Probability: rate},
+		{Method: "UnitOfWork.Begin", Error: faultinject.ErrorConnection, Probability: rate},
+		{Method: "UserLockRepository.AcquireLock", Error: faultinject.ErrorLockTimeout, Probability: rate},
+		{Method: "UserLockRepository.VerifyLock", Error: faultinject.ErrorLockLost, Probability: rate},
+		{Method: "UserRepository.GetByID", Error: faultinject.ErrorConnection, Probability: rate},
+		{Method: "TransactionRepository.Create", Error: faultinject.ErrorDeadlock, Probability: rate},
+		{Method: "TransactionRepository.GetByTransactionID", Error: faultinject.ErrorTimeout, Probability: rate},
+		{Method: "AtomicTransactionRepository.ApplyTransaction", Error: faultinject.ErrorSerialization, Probability: rate},
+		{Method: "AtomicTransactionRepository.ApplyTransaction", Error: faultinject.ErrorConnection, Phase: faultinject.PhaseAfter, Probability: rate},
+	}
+}
+
+// workload generates the requests and deals them out to the clients
+func (r *run) workload(rng *rand.Rand) [][]request {
+	queues := make([][]request, max(r.config.Clients, 1))
+	var sent []request
+	for n := 0; n < r.config.Requests; n++ {
+		var req request
+		if len(sent) > 0 && rng.Float64() < r.config.DuplicateRate {
+			// Resent by the provider, possibly while the first copy is still in flight
+			req = sent[rng.Intn(len(sent))]
+		} else {
+			req = request{
+				userID:        uint64(rng.Intn(max(r.config.Users, 1)) + 1),
+				transactionID: fmt.Sprintf("sim-%d", n+1),
+				state:         string(entity.StateWin),
+				amount:        entity.AmountInCentsToString(rng.Int63n(max(r.config.MaxAmount, 1)) + 1),
+			}
+			if rng.Float64() < r.config.LoseRate {
+				req.state = string(entity.StateLose)
+			}
+		}
+		sent = append(sent, req)
+		client := rng.Intn(len(queues))
+		queues[client] = append(queues[client], req)
+	}
+	return queues
+}
+
+// send processes a request, resending it while its outcome is unknown or conflicted
+func (r *run) send(ctx context.Context, req request) {
+	for attempt := 0; attempt <= r.config.Resends; attempt++ {
+		r.result.Sent++
+		resp, _ := r.service.ProcessTransaction(ctx, req.userID, transaction.TransactionRequest{
+			TransactionID: req.transactionID,
+			SourceType:    entity.SourceGame,
+			State:         req.state,
+			Amount:        req.amount,
+		})
+
+		switch {
+		case resp.Success:
+			r.result.Applied++
+			r.checkAnswer(req, resp)
+			return
+		case resp.StatusCode == http.StatusConflict || resp.StatusCode >= http.StatusInternalServerError:
+			// Back off like a provider would, giving the lock holder time to finish
+			r.sched.sleep(ctx, time.Duration(attempt+1)*r.config.ResendBackoff)
+			continue
+		default:
+			r.result.Rejected++
+			if first, ok := r.answers[req.transactionID]; ok {
+				r.violation("transaction %s rejected with status %d after it was applied at step %d",
+					req.transactionID, resp.StatusCode, first.step)
+			}
+			return
+		}
+	}
+	r.result.Unresolved++
+}
+
+// checkAnswer checks a successful response against earlier ones and the stored transaction
+func (r *run) checkAnswer(req request, resp *transaction.TransactionResponse) {
+	if first, ok := r.answers[req.transactionID]; ok {
+		if first.resultBalance != resp.ResultBalance {
+			r.violation("transaction %s answered with result balance %s, but with %s at step %d",
+				req.transactionID, resp.ResultBalance, first.resultBalance, first.step)
+		}
+	} else {
+		r.answers[req.transactionID] = answer{resultBalance: resp.ResultBalance, step: r.step}
+	}
+
+	stored, ok := r.store.Transaction(req.transactionID)
+	switch {
+	case !ok:
+		r.violation("transaction %s answered as applied but not stored", req.transactionID)
+	case stored.Status != entity.StatusCompleted:
+		r.violation("transaction %s answered as applied but stored as %s", req.transactionID, stored.Status)
+	case stored.ResultBalance != resp.ResultBalance:
+		r.violation("transaction %s answered with result balance %s but stored with %s",
+			req.transactionID, resp.ResultBalance, stored.GetResultBalance())
+	}
+}
+
+// checkBalances checks the committed balances against the committed transactions
+func (r *run) checkBalances() {
+	changes := make(map[uint64]int64)
+	counts := make(map[uint64]uint64)
+	for _, txn := range r.store.Transactions() {
+		if txn.Status == entity.StatusCompleted {
+			changes[txn.UserID] += txn.BalanceChange().Cents()
+			counts[txn.UserID]++
+		}
+	}
+
+	for _, user := range r.store.Users() {
+		if user.Balance().IsNegative() {
+			r.violation("user %d has a negative balance of %s", user.ID, user.GetBalance())
+		}
+		if want := r.initial + changes[user.ID]; user.Balance().Cents() != want {
+			r.violation("user %d has a balance of %s, but its completed transactions add up to %s",
+				user.ID, user.GetBalance(), entity.AmountInCentsToString(want))
+		}
+		if user.TransactionCount != counts[user.ID] {
+			r.violation("user %d counts %d transactions, but %d are completed",
+				user.ID, user.TransactionCount, counts[user.ID])
+		}
+	}
+}
+
+// violation records a broken invariant
+func (r *run) violation(format string, args ...any) {
+	if len(r.result.Violations) < maxViolations {
+		r.result.Violations = append(r.result.Violations, fmt.Sprintf("step %d: ", r.step)+fmt.Sprintf(format, args...))
+	}
+}
+
+// trace hashes the schedule and the final balances
+func (r *run) trace(schedule []int) uint64 {
+	h := fnv.New64a()
+	for _, id := range schedule {
+		fmt.Fprintf(h, "%d,", id)
+	}
+	for _, user := range r.store.Users() {
+		fmt.Fprintf(h, "%d=%d/%d;", user.ID, user.Balance().Cents(), user.TransactionCount)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,92 @@
+package simulation
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	simSeed = flag.Int64("sim.seed", 0, "run only the simulation with this seed")
+	simRuns = flag.Int("sim.runs", 100, "number of seeds to simulate per processing mode")
+)
+
+// seeds returns the seeds to simulate
+func seeds() []int64 {
+	if *simSeed != 0 {
+		return []int64{*simSeed}
+	}
+	seeds := make([]int64, *simRuns)
+	for n := range seeds {
+		seeds[n] = int64(n + 1)
+	}
+	return seeds
+}
+
+func TestSimulation(t *testing.T) {
+	modes := []struct {
+		name     string
+		fastPath bool
+	}{
+		{"Locked path", false},
+		{"Fast path", true},
+	}
+	for _, mode := range modes {
+		t.Run(mode.name, func(t *testing.T) {
+			var applied, retries, faults int64
+			for _, seed := range seeds() {
+				config := DefaultConfig(seed)
+				config.FastPath = mode.fastPath
+
+				result := Run(config)
+				if result.Failed() {
+					t.Errorf("%s\nreproduce with: go test ./internal/simulation -run 'TestSimulation/%s' -sim.seed=%d",
+						result, strings.ReplaceAll(mode.name, " ", "_"), seed)
+				}
+				applied += int64(result.Applied)
+				retries += result.Retries
+				faults += result.Faults
+			}
+
+			// Runs that never get as far as a retry or a fault would check very little
+			assert.Positive(t, applied)
+			assert.Positive(t, faults)
+			if !mode.fastPath && len(seeds()) > 1 {
+				assert.Positive(t, retries)
+			}
+		})
+	}
+}
+
+func TestSimulation_Deterministic(t *testing.T) {
+	for _, seed := range []int64{1, 7, 42} {
+		config := DefaultConfig(seed)
+		first := Run(config)
+		require.False(t, first.Failed(), first.String())
+		assert.Equal(t, first, Run(config), "the same seed replays the same run")
+	}
+
+	assert.NotEqual(t, Run(DefaultConfig(1)).Trace, Run(DefaultConfig(2)).Trace)
+}
+
+func TestSimulation_CatchesLostUpdates(t *testing.T) {
+	// Without commit validation, a holder whose lock expires after VerifyLock commits over the
+	// balance written by the client that took the lock over
+	var failed *Result
+	for seed := int64(1); seed <= 50 && failed == nil; seed++ {
+		config := DefaultConfig(seed)
+		config.weakIsolation = true
+		if result := Run(config); result.Failed() {
+			failed = &result
+		}
+	}
+	require.NotNil(t, failed, "weak isolation went unnoticed")
+	assert.Contains(t, failed.String(), "violation")
+	assert.Contains(t, failed.String(), "seed ")
+
+	// The same seed passes once commits are validated again
+	assert.False(t, Run(DefaultConfig(failed.Seed)).Failed())
+}
@@ -0,0 +1,808 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	coreport "github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
+)
+
+// Store keeps users, transactions, approval events and user locks in memory
+// It implements the persistence ports the transaction service runs on. Units of work buffer
+// their writes and remember the version of every row they touch; Commit fails with
+// ErrConcurrencyConflict when one of those rows was changed by someone else in the meantime,
+// which gives the same guarantee as the SERIALIZABLE transactions of the database adapters.
+// Range queries are not validated.
+type Store struct {
+	mu           sync.Mutex
+	timeProvider coreport.TimeProvider
+	hook         func(ctx context.Context) // Called when a port method is entered; nil does nothing
+
+	// weakIsolation commits without validating what was read, so lost updates go unnoticed
+	// It exists to show that the simulation catches the anomalies serializable commits prevent.
+	weakIsolation bool
+
+	users   map[uint64]*userRow
+	txns    map[string]*txnRow
+	locks   map[uint64]*lockRow
+	events  []*entity.ApprovalEvent
+	version uint64 // Last version given to a committed write
+	nextID  uint64 // Last row ID given to a transaction or approval event
+	tokens  uint64 // Lock tokens handed out so far
+}
+
+// userRow is a committed user and the version of its last write
+type userRow struct {
+	user    entity.User
+	version uint64
+}
+
+// txnRow is a committed transaction and the version of its last write
+type txnRow struct {
+	txn     *entity.Transaction
+	version uint64
+}
+
+// lockRow is a held user lock and the version of its last write
+type lockRow struct {
+	token     persistence.LockToken
+	expiresAt time.Time
+	version   uint64
+}
+
+// memTx is a unit of work in progress
+type memTx struct {
+	reads  map[rowKey]uint64 // Version of each row when first touched; 0 if it did not exist
+	users  map[uint64]entity.User
+	txns   map[string]*entity.Transaction
+	order  []string // Transaction IDs in the order they were first written
+	events []*entity.ApprovalEvent
+	done   bool
+}
+
+// Tables a unit of work can depend on
+const (
+	tableUsers = iota
+	tableTransactions
+	tableLocks
+)
+
+// rowKey identifies a row by its table and key
+type rowKey struct {
+	table         int
+	userID        uint64
+	transactionID string
+}
+
+// txKey is the context key of the unit of work in progress
+type txKey struct{}
+
+// NewStore creates an empty store
+func NewStore(timeProvider coreport.TimeProvider) *Store {
+	return &Store{
+		timeProvider: timeProvider,
+		users:        make(map[uint64]*userRow),
+		txns:         make(map[string]*txnRow),
+		locks:        make(map[uint64]*lockRow),
+	}
+}
+
+// WithHook calls hook whenever a port method is entered, before it touches any data
+func (s *Store) WithHook(hook func(ctx context.Context)) *Store {
+	s.hook = hook
+	return s
+}
+
+// AddUser stores a user outside of any unit of work
+func (s *Store) AddUser(user *entity.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	s.users[user.ID] = &userRow{user: *user, version: s.version}
+}
+
+// Users returns the committed users ordered by ID
+func (s *Store) Users() []*entity.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]*entity.User, 0, len(s.users))
+	for _, row := range s.users {
+		user := row.user
+		users = append(users, &user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// Transactions returns the committed transactions ordered by row ID
+func (s *Store) Transactions() []*entity.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.visibleTransactions(nil)
+}
+
+// Transaction returns the committed transaction with the external ID
+func (s *Store) Transaction(transactionID string) (*entity.Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transaction(nil, transactionID)
+}
+
+// enter runs the hook and reports whether the caller's context is still alive
+func (s *Store) enter(ctx context.Context) error {
+	if s.hook != nil {
+		s.hook(ctx)
+	}
+	return ctx.Err()
+}
+
+// txFromContext returns the unit of work in ctx, if any
+func txFromContext(ctx context.Context) *memTx {
+	tx, _ := ctx.Value(txKey{}).(*memTx)
+	return tx
+}
+
+// touch remembers the version a row had when the unit of work first used it
+func (s *Store) touch(tx *memTx, key rowKey, version uint64) {
+	if tx == nil {
+		return
+	}
+	if _, ok := tx.reads[key]; !ok {
+		tx.reads[key] = version
+	}
+}
+
+// currentVersion returns the committed version of a row, or 0 if it does not exist
+func (s *Store) currentVersion(key rowKey) uint64 {
+	switch key.table {
+	case tableUsers:
+		if row, ok := s.users[key.userID]; ok {
+			return row.version
+		}
+	case tableLocks:
+		if row, ok := s.locks[key.userID]; ok {
+			return row.version
+		}
+	case tableTransactions:
+		if row, ok := s.txns[key.transactionID]; ok {
+			return row.version
+		}
+	}
+	return 0
+}
+
+// user returns the user as the unit of work sees it
+func (s *Store) user(tx *memTx, id uint64) (entity.User, bool) {
+	if tx != nil {
+		if user, ok := tx.users[id]; ok {
+			return user, true
+		}
+	}
+	row, ok := s.users[id]
+	var version uint64
+	if ok {
+		version = row.version
+	}
+	s.touch(tx, rowKey{table: tableUsers, userID: id}, version)
+	if !ok {
+		return entity.User{}, false
+	}
+	return row.user, true
+}
+
+// putUser writes the user in the unit of work, or commits it right away without one
+func (s *Store) putUser(tx *memTx, user entity.User) {
+	if tx != nil {
+		tx.users[user.ID] = user
+		return
+	}
+	s.version++
+	s.users[user.ID] = &userRow{user: user, version: s.version}
+}
+
+// transaction returns the transaction as the unit of work sees it
+func (s *Store) transaction(tx *memTx, transactionID string) (*entity.Transaction, bool) {
+	if tx != nil {
+		if txn, ok := tx.txns[transactionID]; ok {
+			return txn.Clone(), true
+		}
+	}
+	row, ok := s.txns[transactionID]
+	var version uint64
+	if ok {
+		version = row.version
+	}
+	s.touch(tx, rowKey{table: tableTransactions, transactionID: transactionID}, version)
+	if !ok {
+		return nil, false
+	}
+	return row.txn.Clone(), true
+}
+
+// putTransaction writes the transaction in the unit of work, or commits it right away without one
+func (s *Store) putTransaction(tx *memTx, txn *entity.Transaction) {
+	if tx != nil {
+		if _, ok := tx.txns[txn.TransactionID]; !ok {
+			tx.order = append(tx.order, txn.TransactionID)
+		}
+		tx.txns[txn.TransactionID] = txn.Clone()
+		return
+	}
+	s.version++
+	s.txns[txn.TransactionID] = &txnRow{txn: txn.Clone(), version: s.version}
+}
+
+// visibleTransactions returns the transactions the unit of work sees, ordered by row ID
+func (s *Store) visibleTransactions(tx *memTx) []*entity.Transaction {
+	txns := make([]*entity.Transaction, 0, len(s.txns))
+	for id, row := range s.txns {
+		if tx != nil {
+			if _, ok := tx.txns[id]; ok {
+				continue
+			}
+		}
+		txns = append(txns, row.txn.Clone())
+	}
+	if tx != nil {
+		for _, txn := range tx.txns {
+			txns = append(txns, txn.Clone())
+		}
+	}
+	sort.Slice(txns, func(i, j int) bool { return txns[i].ID < txns[j].ID })
+	return txns
+}
+
+// commit validates the unit of work and applies its writes
+//
+// Possible errors:
+//   - errs.ErrConcurrencyConflict: a row the unit of work used was changed after it was first used
+func (s *Store) commit(tx *memTx) error {
+	if !s.weakIsolation {
+		for key, version := range tx.reads {
+			if s.currentVersion(key) != version {
+				return fmt.Errorf("%w: a row it used was changed by a concurrent transaction", errs.ErrConcurrencyConflict)
+			}
+		}
+	}
+
+	s.version++
+	for id, user := range tx.users {
+		s.users[id] = &userRow{user: user, version: s.version}
+	}
+	for _, id := range tx.order {
+		s.txns[id] = &txnRow{txn: tx.txns[id], version: s.version}
+	}
+	s.events = append(s.events, tx.events...)
+	return nil
+}
+
+// UnitOfWork implements persistence.UnitOfWork on the store
+type UnitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork creates a unit of work factory for the store
+func NewUnitOfWork(store *Store) *UnitOfWork {
+	return &UnitOfWork{store: store}
+}
+
+// Begin starts a unit of work and stores it in the returned context
+func (u *UnitOfWork) Begin(ctx context.Context) (context.Context, error) {
+	if err := u.store.enter(ctx); err != nil {
+		return ctx, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	tx := &memTx{
+		reads: make(map[rowKey]uint64),
+		users: make(map[uint64]entity.User),
+		txns:  make(map[string]*entity.Transaction),
+	}
+	return context.WithValue(ctx, txKey{}, tx), nil
+}
+
+// Commit applies the unit of work in ctx if nothing it used has changed since
+//
+// Possible errors:
+//   - errs.ErrConcurrencyConflict: a concurrent unit of work changed a row this one used
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return fmt.Errorf("no transaction found in context")
+	}
+	if err := u.store.enter(ctx); err != nil {
+		tx.done = true
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	if tx.done {
+		return fmt.Errorf("failed to commit transaction: already committed or rolled back")
+	}
+	tx.done = true
+	if err := u.store.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Rollback discards the unit of work in ctx; it is a no-op once the unit of work has ended
+func (u *UnitOfWork) Rollback(ctx context.Context) error {
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return fmt.Errorf("no transaction found in context")
+	}
+	_ = u.store.enter(ctx)
+
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	tx.done = true
+	return nil
+}
+
+// GetUserRepository returns a user repository in the unit of work in ctx, if any
+func (u *UnitOfWork) GetUserRepository(ctx context.Context) persistence.UserRepository {
+	return &userRepository{store: u.store, tx: txFromContext(ctx)}
+}
+
+// GetTransactionRepository returns a transaction repository in the unit of work in ctx, if any
+func (u *UnitOfWork) GetTransactionRepository(ctx context.Context) persistence.TransactionRepository {
+	return &transactionRepository{store: u.store, tx: txFromContext(ctx)}
+}
+
+// GetApprovalAuditRepository returns an approval audit repository in the unit of work in ctx, if any
+func (u *UnitOfWork) GetApprovalAuditRepository(ctx context.Context) persistence.ApprovalAuditRepository {
+	return &approvalAuditRepository{store: u.store, tx: txFromContext(ctx)}
+}
+
+// userRepository implements persistence.UserRepository on the store
+type userRepository struct {
+	store *Store
+	tx    *memTx // nil commits every write right away
+}
+
+// GetByID returns a user by ID
+func (r *userRepository) GetByID(ctx context.Context, id uint64) (*entity.User, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return nil, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.user(r.tx, id)
+	if !ok {
+		return nil, errs.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// Create stores a new user
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.user(r.tx, user.ID); ok {
+		return errs.ErrDuplicateUser
+	}
+	r.store.putUser(r.tx, *user)
+	return nil
+}
+
+// Update stores the user's balance and transaction count
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.user(r.tx, user.ID); !ok {
+		return errs.ErrUserNotFound
+	}
+	r.store.putUser(r.tx, *user)
+	return nil
+}
+
+// ProcessTransaction adds balanceChange to the user's balance unless it would become negative or overflow
+func (r *userRepository) ProcessTransaction(ctx context.Context, userID uint64, balanceChange entity.Money) (*entity.User, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return nil, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.user(r.tx, userID)
+	if !ok {
+		return nil, errs.ErrUserNotFound
+	}
+	balance, err := user.Balance().Add(balanceChange)
+	if err != nil {
+		return nil, err
+	}
+	if balance.IsNegative() {
+		return nil, errs.ErrInsufficientBalance
+	}
+	user.SetBalance(balance, r.store.timeProvider)
+	user.IncrementTransactionCount()
+	r.store.putUser(r.tx, user)
+	return &user, nil
+}
+
+// transactionRepository implements persistence.TransactionRepository on the store
+type transactionRepository struct {
+	store *Store
+	tx    *memTx // nil commits every write right away
+}
+
+// Create stores a new transaction and assigns its row ID
+func (r *transactionRepository) Create(ctx context.Context, txn *entity.Transaction) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.transaction(r.tx, txn.TransactionID); ok && !r.store.weakIsolation {
+		return errs.ErrDuplicateTransaction
+	}
+	r.store.nextID++
+	txn.ID = r.store.nextID
+	r.store.putTransaction(r.tx, txn)
+	return nil
+}
+
+// Update stores the transaction's new state
+func (r *transactionRepository) Update(ctx context.Context, txn *entity.Transaction) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.transaction(r.tx, txn.TransactionID); !ok {
+		return errs.ErrTransactionNotFound
+	}
+	r.store.putTransaction(r.tx, txn)
+	return nil
+}
+
+// GetByTransactionID returns a transaction by its external ID
+func (r *transactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Transaction, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return nil, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	txn, ok := r.store.transaction(r.tx, transactionID)
+	if !ok {
+		return nil, errs.ErrTransactionNotFound
+	}
+	return txn, nil
+}
+
+// TransactionExists reports whether a transaction with the external ID is stored
+func (r *transactionRepository) TransactionExists(ctx context.Context, transactionID string) (bool, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return false, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	_, ok := r.store.transaction(r.tx, transactionID)
+	return ok, nil
+}
+
+// ListByUser returns the user's most recent transactions first; limit <= 0 returns all of them
+func (r *transactionRepository) ListByUser(ctx context.Context, userID uint64, limit int) ([]*entity.Transaction, error) {
+	return r.list(ctx, limit, true, func(txn *entity.Transaction) bool { return txn.UserID == userID })
+}
+
+// ListByStatus returns the oldest transactions with the status first; limit <= 0 returns all of them
+func (r *transactionRepository) ListByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error) {
+	return r.list(ctx, limit, false, func(txn *entity.Transaction) bool { return txn.Status == status })
+}
+
+// StreamByUser calls fn for each of a user's transactions booked in [from, to), in booking order
+func (r *transactionRepository) StreamByUser(
+	ctx context.Context,
+	userID uint64,
+	from, to time.Time,
+	fn func(*entity.Transaction) error,
+) error {
+	txns, err := r.booked(ctx, userID, false)
+	if err != nil {
+		return err
+	}
+	for _, txn := range txns {
+		if !from.IsZero() && txn.BookedAt().Before(from) {
+			continue
+		}
+		if !to.IsZero() && !txn.BookedAt().Before(to) {
+			continue
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastCompletedBefore returns the user's last completed transaction booked before the given time
+func (r *transactionRepository) LastCompletedBefore(ctx context.Context, userID uint64, before time.Time) (*entity.Transaction, error) {
+	txns, err := r.booked(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	for n := len(txns) - 1; n >= 0; n-- {
+		if txns[n].BookedAt().Before(before) {
+			return txns[n], nil
+		}
+	}
+	return nil, errs.ErrTransactionNotFound
+}
+
+// FirstCompleted returns the user's first completed transaction in booking order
+func (r *transactionRepository) FirstCompleted(ctx context.Context, userID uint64) (*entity.Transaction, error) {
+	txns, err := r.booked(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(txns) == 0 {
+		return nil, errs.ErrTransactionNotFound
+	}
+	return txns[0], nil
+}
+
+// list returns the visible transactions that match, by row ID
+func (r *transactionRepository) list(ctx context.Context, limit int, newestFirst bool, match func(*entity.Transaction) bool) ([]*entity.Transaction, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return nil, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	all := r.store.visibleTransactions(r.tx)
+	if newestFirst {
+		for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+			all[i], all[j] = all[j], all[i]
+		}
+	}
+	txns := make([]*entity.Transaction, 0)
+	for _, txn := range all {
+		if limit > 0 && len(txns) == limit {
+			break
+		}
+		if match(txn) {
+			txns = append(txns, txn)
+		}
+	}
+	return txns, nil
+}
+
+// booked returns the user's visible transactions in booking order
+func (r *transactionRepository) booked(ctx context.Context, userID uint64, completedOnly bool) ([]*entity.Transaction, error) {
+	txns, err := r.list(ctx, 0, false, func(txn *entity.Transaction) bool {
+		return txn.UserID == userID && (!completedOnly || txn.Status == entity.StatusCompleted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].BookedAt().Before(txns[j].BookedAt()) })
+	return txns, nil
+}
+
+// approvalAuditRepository implements persistence.ApprovalAuditRepository on the store
+type approvalAuditRepository struct {
+	store *Store
+	tx    *memTx // nil commits every write right away
+}
+
+// Record stores an approval event
+func (r *approvalAuditRepository) Record(ctx context.Context, event *entity.ApprovalEvent) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextID++
+	event.ID = r.store.nextID
+	stored := *event
+	if r.tx != nil {
+		r.tx.events = append(r.tx.events, &stored)
+		return nil
+	}
+	r.store.events = append(r.store.events, &stored)
+	return nil
+}
+
+// ListByTransaction returns the events of a transaction, oldest first
+func (r *approvalAuditRepository) ListByTransaction(ctx context.Context, transactionID string) ([]*entity.ApprovalEvent, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return nil, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	events := append([]*entity.ApprovalEvent{}, r.store.events...)
+	if r.tx != nil {
+		events = append(events, r.tx.events...)
+	}
+	var matching []*entity.ApprovalEvent
+	for _, event := range events {
+		if event.TransactionID == transactionID {
+			stored := *event
+			matching = append(matching, &stored)
+		}
+	}
+	return matching, nil
+}
+
+// UserLockRepository implements persistence.UserLockRepository on the store
+// Locks follow the SQLite adapter: an existing lock is only taken over once it has expired,
+// and releasing or renewing a lock that is no longer held under the token does nothing.
+// VerifyLock inside a unit of work makes the lock part of what the commit validates, so a
+// lock taken over between the check and the commit still fails the commit.
+type UserLockRepository struct {
+	store *Store
+}
+
+// NewUserLockRepository creates a user lock repository for the store
+func NewUserLockRepository(store *Store) *UserLockRepository {
+	return &UserLockRepository{store: store}
+}
+
+// AcquireLock takes the user's lock for the duration
+//
+// Possible errors:
+//   - errs.ErrUserLocked: the lock is held and has not expired
+func (r *UserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (persistence.LockToken, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return "", err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.timeProvider.Now()
+	if row, ok := r.store.locks[userID]; ok && now.Before(row.expiresAt) {
+		return "", errs.ErrUserLocked
+	}
+	r.store.tokens++
+	r.store.version++
+	token := persistence.LockToken(fmt.Sprintf("sim-%d", r.store.tokens))
+	r.store.locks[userID] = &lockRow{token: token, expiresAt: now.Add(duration), version: r.store.version}
+	return token, nil
+}
+
+// ReleaseLock releases the lock if it is still held under the token
+func (r *UserLockRepository) ReleaseLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	_ = r.store.enter(ctx)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if row, ok := r.store.locks[userID]; ok && row.token == token {
+		delete(r.store.locks, userID)
+	}
+	return nil
+}
+
+// RenewLock extends the lock's expiry while it is still held under the token
+//
+// Possible errors:
+//   - errs.ErrLockLost: the lock is no longer held under the token
+func (r *UserLockRepository) RenewLock(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	row, ok := r.store.locks[userID]
+	if !ok || row.token != token {
+		return errs.ErrLockLost
+	}
+	r.store.version++
+	row.expiresAt = r.store.timeProvider.Now().Add(duration)
+	row.version = r.store.version
+	return nil
+}
+
+// VerifyLock checks that the lock is still held under the token
+//
+// Possible errors:
+//   - errs.ErrLockLost: the lock is no longer held under the token
+func (r *UserLockRepository) VerifyLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	row, ok := r.store.locks[userID]
+	var version uint64
+	if ok {
+		version = row.version
+	}
+	r.store.touch(txFromContext(ctx), rowKey{table: tableLocks, userID: userID}, version)
+	if !ok || row.token != token {
+		return errs.ErrLockLost
+	}
+	return nil
+}
+
+// CleanupExpiredLocks removes all expired locks
+func (r *UserLockRepository) CleanupExpiredLocks(ctx context.Context) error {
+	if err := r.store.enter(ctx); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.timeProvider.Now()
+	for userID, row := range r.store.locks {
+		if !now.Before(row.expiresAt) {
+			delete(r.store.locks, userID)
+		}
+	}
+	return nil
+}
+
+// AtomicTransactionRepository implements persistence.AtomicTransactionRepository on the store
+type AtomicTransactionRepository struct {
+	store *Store
+}
+
+// NewAtomicTransactionRepository creates an atomic transaction repository for the store
+func NewAtomicTransactionRepository(store *Store) *AtomicTransactionRepository {
+	return &AtomicTransactionRepository{store: store}
+}
+
+// ApplyTransaction applies the transaction to the balance and stores it in one step
+// A transaction ID that is already stored returns the stored transaction, with stored set.
+//
+// Possible errors:
+//   - errs.ErrUserNotFound: the user does not exist
+//   - errs.ErrInsufficientBalance: the balance would become negative
+//   - errs.ErrAmountOverflow: the balance cannot hold the result
+func (r *AtomicTransactionRepository) ApplyTransaction(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+	if err := r.store.enter(ctx); err != nil {
+		return nil, false, err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if stored, ok := r.store.transaction(nil, txn.TransactionID); ok {
+		return stored, true, nil
+	}
+	user, ok := r.store.user(nil, txn.UserID)
+	if !ok {
+		return nil, false, errs.ErrUserNotFound
+	}
+	balance, err := user.Balance().Add(txn.BalanceChange())
+	if err != nil {
+		return nil, false, err
+	}
+	if balance.IsNegative() {
+		return nil, false, errs.NewInsufficientBalanceError(txn.UserID, txn.GetAmount(), user.GetBalance())
+	}
+
+	user.SetBalance(balance, r.store.timeProvider)
+	user.IncrementTransactionCount()
+	r.store.putUser(nil, user)
+
+	processed := txn.Clone()
+	processed.MarkAsProcessed(r.store.timeProvider, user.Balance())
+	r.store.nextID++
+	processed.ID = r.store.nextID
+	r.store.putTransaction(nil, processed)
+	return processed.Clone(), false, nil
+}
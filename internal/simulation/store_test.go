@@ -0,0 +1,174 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	errs "github.com/amirhossein-jamali/balance-processor/internal/domain/error"
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/port/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, *Clock) {
+	clock := NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := NewStore(clock)
+	user, err := entity.NewUser(1, "100.00", clock)
+	require.NoError(t, err)
+	store.AddUser(user)
+	return store, clock
+}
+
+func TestStore_UnitOfWork(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Writes are only visible after commit", func(t *testing.T) {
+		store, clock := newTestStore(t)
+		uow := NewUnitOfWork(store)
+
+		txCtx, err := uow.Begin(ctx)
+		require.NoError(t, err)
+		users := uow.GetUserRepository(txCtx)
+		_, err = users.ProcessTransaction(txCtx, 1, entity.MoneyFromCents(2500))
+		require.NoError(t, err)
+		txn, err := entity.NewTransaction(1, "tx-1", "game", "win", "25.00", clock)
+		require.NoError(t, err)
+		require.NoError(t, uow.GetTransactionRepository(txCtx).Create(txCtx, txn))
+
+		outside, err := uow.GetUserRepository(ctx).GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "100.00", outside.GetBalance())
+		_, ok := store.Transaction("tx-1")
+		assert.False(t, ok)
+
+		require.NoError(t, uow.Commit(txCtx))
+		assert.Equal(t, "125.00", store.Users()[0].GetBalance())
+		_, ok = store.Transaction("tx-1")
+		assert.True(t, ok)
+		assert.NoError(t, uow.Rollback(txCtx), "rollback after commit is a no-op")
+	})
+
+	t.Run("Rollback discards writes", func(t *testing.T) {
+		store, _ := newTestStore(t)
+		uow := NewUnitOfWork(store)
+
+		txCtx, err := uow.Begin(ctx)
+		require.NoError(t, err)
+		_, err = uow.GetUserRepository(txCtx).ProcessTransaction(txCtx, 1, entity.MoneyFromCents(-2500))
+		require.NoError(t, err)
+		require.NoError(t, uow.Rollback(txCtx))
+		assert.Equal(t, "100.00", store.Users()[0].GetBalance())
+	})
+
+	t.Run("Commit fails when a row it read changed", func(t *testing.T) {
+		store, _ := newTestStore(t)
+		uow := NewUnitOfWork(store)
+
+		first, err := uow.Begin(ctx)
+		require.NoError(t, err)
+		second, err := uow.Begin(ctx)
+		require.NoError(t, err)
+
+		_, err = uow.GetUserRepository(first).ProcessTransaction(first, 1, entity.MoneyFromCents(1000))
+		require.NoError(t, err)
+		_, err = uow.GetUserRepository(second).ProcessTransaction(second, 1, entity.MoneyFromCents(2000))
+		require.NoError(t, err)
+
+		require.NoError(t, uow.Commit(first))
+		assert.ErrorIs(t, uow.Commit(second), errs.ErrConcurrencyConflict)
+		assert.Equal(t, "110.00", store.Users()[0].GetBalance())
+	})
+
+	t.Run("Commit fails when a transaction ID it found missing was inserted", func(t *testing.T) {
+		store, clock := newTestStore(t)
+		uow := NewUnitOfWork(store)
+
+		txCtx, err := uow.Begin(ctx)
+		require.NoError(t, err)
+		exists, err := uow.GetTransactionRepository(txCtx).TransactionExists(txCtx, "tx-1")
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		txn, err := entity.NewTransaction(1, "tx-1", "game", "win", "1.00", clock)
+		require.NoError(t, err)
+		require.NoError(t, uow.GetTransactionRepository(ctx).Create(ctx, txn))
+		assert.ErrorIs(t, uow.GetTransactionRepository(ctx).Create(ctx, txn), errs.ErrDuplicateTransaction)
+
+		assert.ErrorIs(t, uow.Commit(txCtx), errs.ErrConcurrencyConflict)
+	})
+}
+
+func TestStore_UserLocks(t *testing.T) {
+	ctx := context.Background()
+	store, clock := newTestStore(t)
+	locks := NewUserLockRepository(store)
+	uow := NewUnitOfWork(store)
+
+	token, err := locks.AcquireLock(ctx, 1, time.Second)
+	require.NoError(t, err)
+	_, err = locks.AcquireLock(ctx, 1, time.Second)
+	assert.ErrorIs(t, err, errs.ErrUserLocked)
+
+	// A lock checked inside a unit of work and taken over before the commit fails the commit
+	txCtx, err := uow.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, locks.VerifyLock(txCtx, 1, token))
+
+	clock.Advance(time.Second)
+	takeover, err := locks.AcquireLock(ctx, 1, time.Second)
+	require.NoError(t, err)
+	assert.ErrorIs(t, locks.VerifyLock(ctx, 1, token), errs.ErrLockLost)
+	assert.ErrorIs(t, locks.RenewLock(ctx, 1, token, time.Second), errs.ErrLockLost)
+	assert.ErrorIs(t, uow.Commit(txCtx), errs.ErrConcurrencyConflict)
+
+	// Releasing under a stale token leaves the new holder's lock alone
+	require.NoError(t, locks.ReleaseLock(ctx, 1, token))
+	assert.NoError(t, locks.VerifyLock(ctx, 1, takeover))
+	require.NoError(t, locks.ReleaseLock(ctx, 1, takeover))
+	assert.ErrorIs(t, locks.VerifyLock(ctx, 1, takeover), errs.ErrLockLost)
+}
+
+func TestStore_AtomicTransactionRepository(t *testing.T) {
+	ctx := context.Background()
+	store, clock := newTestStore(t)
+	repo := NewAtomicTransactionRepository(store)
+
+	newTxn := func(id, state, amount string) *entity.Transaction {
+		txn, err := entity.NewTransaction(1, id, "game", state, amount, clock)
+		require.NoError(t, err)
+		return txn
+	}
+
+	txn, stored, err := repo.ApplyTransaction(ctx, newTxn("tx-1", "lose", "100.00"))
+	require.NoError(t, err)
+	assert.False(t, stored)
+	assert.Equal(t, "0.00", txn.GetResultBalance())
+
+	// A resend gets the stored outcome even though the balance no longer covers it
+	txn, stored, err = repo.ApplyTransaction(ctx, newTxn("tx-1", "lose", "100.00"))
+	require.NoError(t, err)
+	assert.True(t, stored)
+	assert.Equal(t, "0.00", txn.GetResultBalance())
+
+	_, _, err = repo.ApplyTransaction(ctx, newTxn("tx-2", "lose", "0.01"))
+	assert.ErrorIs(t, err, errs.ErrInsufficientBalance)
+	assert.Equal(t, uint64(1), store.Users()[0].TransactionCount)
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	timeoutCtx, cancel := clock.WithTimeout(context.Background(), core.Duration(time.Second))
+	defer cancel()
+	clock.Sleep(core.Duration(999 * time.Millisecond))
+	assert.NoError(t, timeoutCtx.Err())
+	assert.Equal(t, start.Add(999*time.Millisecond), clock.Now())
+
+	clock.Advance(time.Millisecond)
+	<-timeoutCtx.Done()
+	assert.ErrorIs(t, context.Cause(timeoutCtx), context.DeadlineExceeded)
+	assert.Equal(t, core.Duration(time.Second), clock.Since(start))
+}
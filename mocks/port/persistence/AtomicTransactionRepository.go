@@ -0,0 +1,103 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package persistence
+
+import (
+	context "context"
+
+	entity "github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockAtomicTransactionRepository is an autogenerated mock type for the AtomicTransactionRepository type
+type MockAtomicTransactionRepository struct {
+	mock.Mock
+}
+
+type MockAtomicTransactionRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockAtomicTransactionRepository) EXPECT() *MockAtomicTransactionRepository_Expecter {
+	return &MockAtomicTransactionRepository_Expecter{mock: &_m.Mock}
+}
+
+// ApplyTransaction provides a mock function with given fields: ctx, txn
+func (_m *MockAtomicTransactionRepository) ApplyTransaction(ctx context.Context, txn *entity.Transaction) (*entity.Transaction, bool, error) {
+	ret := _m.Called(ctx, txn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyTransaction")
+	}
+
+	var r0 *entity.Transaction
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Transaction) (*entity.Transaction, bool, error)); ok {
+		return rf(ctx, txn)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Transaction) *entity.Transaction); ok {
+		r0 = rf(ctx, txn)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *entity.Transaction) bool); ok {
+		r1 = rf(ctx, txn)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *entity.Transaction) error); ok {
+		r2 = rf(ctx, txn)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockAtomicTransactionRepository_ApplyTransaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplyTransaction'
+type MockAtomicTransactionRepository_ApplyTransaction_Call struct {
+	*mock.Call
+}
+
+// ApplyTransaction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - txn *entity.Transaction
+func (_e *MockAtomicTransactionRepository_Expecter) ApplyTransaction(ctx interface{}, txn interface{}) *MockAtomicTransactionRepository_ApplyTransaction_Call {
+	return &MockAtomicTransactionRepository_ApplyTransaction_Call{Call: _e.mock.On("ApplyTransaction", ctx, txn)}
+}
+
+func (_c *MockAtomicTransactionRepository_ApplyTransaction_Call) Run(run func(ctx context.Context, txn *entity.Transaction)) *MockAtomicTransactionRepository_ApplyTransaction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*entity.Transaction))
+	})
+	return _c
+}
+
+func (_c *MockAtomicTransactionRepository_ApplyTransaction_Call) Return(result *entity.Transaction, stored bool, err error) *MockAtomicTransactionRepository_ApplyTransaction_Call {
+	_c.Call.Return(result, stored, err)
+	return _c
+}
+
+func (_c *MockAtomicTransactionRepository_ApplyTransaction_Call) RunAndReturn(run func(context.Context, *entity.Transaction) (*entity.Transaction, bool, error)) *MockAtomicTransactionRepository_ApplyTransaction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockAtomicTransactionRepository creates a new instance of MockAtomicTransactionRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockAtomicTransactionRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockAtomicTransactionRepository {
+	mock := &MockAtomicTransactionRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,102 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package persistence
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockJobLockRepository is an autogenerated mock type for the JobLockRepository type
+type MockJobLockRepository struct {
+	mock.Mock
+}
+
+type MockJobLockRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockJobLockRepository) EXPECT() *MockJobLockRepository_Expecter {
+	return &MockJobLockRepository_Expecter{mock: &_m.Mock}
+}
+
+// TryLockJob provides a mock function with given fields: ctx, jobName
+func (_m *MockJobLockRepository) TryLockJob(ctx context.Context, jobName string) (func(), bool, error) {
+	ret := _m.Called(ctx, jobName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TryLockJob")
+	}
+
+	var r0 func()
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (func(), bool, error)); ok {
+		return rf(ctx, jobName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) func()); ok {
+		r0 = rf(ctx, jobName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(func())
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, jobName)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, jobName)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockJobLockRepository_TryLockJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TryLockJob'
+type MockJobLockRepository_TryLockJob_Call struct {
+	*mock.Call
+}
+
+// TryLockJob is a helper method to define mock.On call
+//   - ctx context.Context
+//   - jobName string
+func (_e *MockJobLockRepository_Expecter) TryLockJob(ctx interface{}, jobName interface{}) *MockJobLockRepository_TryLockJob_Call {
+	return &MockJobLockRepository_TryLockJob_Call{Call: _e.mock.On("TryLockJob", ctx, jobName)}
+}
+
+func (_c *MockJobLockRepository_TryLockJob_Call) Run(run func(ctx context.Context, jobName string)) *MockJobLockRepository_TryLockJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockJobLockRepository_TryLockJob_Call) Return(unlock func(), acquired bool, err error) *MockJobLockRepository_TryLockJob_Call {
+	_c.Call.Return(unlock, acquired, err)
+	return _c
+}
+
+func (_c *MockJobLockRepository_TryLockJob_Call) RunAndReturn(run func(context.Context, string) (func(), bool, error)) *MockJobLockRepository_TryLockJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockJobLockRepository creates a new instance of MockJobLockRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockJobLockRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockJobLockRepository {
+	mock := &MockJobLockRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
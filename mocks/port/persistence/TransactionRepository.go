@@ -7,6 +7,8 @@ import (
 
 	entity "github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // MockTransactionRepository is an autogenerated mock type for the TransactionRepository type
@@ -69,6 +71,65 @@ func (_c *MockTransactionRepository_Create_Call) RunAndReturn(run func(context.C
 	return _c
 }
 
+// FirstCompleted provides a mock function with given fields: ctx, userID
+func (_m *MockTransactionRepository) FirstCompleted(ctx context.Context, userID uint64) (*entity.Transaction, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FirstCompleted")
+	}
+
+	var r0 *entity.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (*entity.Transaction, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) *entity.Transaction); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockTransactionRepository_FirstCompleted_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FirstCompleted'
+type MockTransactionRepository_FirstCompleted_Call struct {
+	*mock.Call
+}
+
+// FirstCompleted is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uint64
+func (_e *MockTransactionRepository_Expecter) FirstCompleted(ctx interface{}, userID interface{}) *MockTransactionRepository_FirstCompleted_Call {
+	return &MockTransactionRepository_FirstCompleted_Call{Call: _e.mock.On("FirstCompleted", ctx, userID)}
+}
+
+func (_c *MockTransactionRepository_FirstCompleted_Call) Run(run func(ctx context.Context, userID uint64)) *MockTransactionRepository_FirstCompleted_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepository_FirstCompleted_Call) Return(_a0 *entity.Transaction, _a1 error) *MockTransactionRepository_FirstCompleted_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockTransactionRepository_FirstCompleted_Call) RunAndReturn(run func(context.Context, uint64) (*entity.Transaction, error)) *MockTransactionRepository_FirstCompleted_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetByTransactionID provides a mock function with given fields: ctx, transactionID
 func (_m *MockTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entity.Transaction, error) {
 	ret := _m.Called(ctx, transactionID)
@@ -128,6 +189,236 @@ func (_c *MockTransactionRepository_GetByTransactionID_Call) RunAndReturn(run fu
 	return _c
 }
 
+// LastCompletedBefore provides a mock function with given fields: ctx, userID, before
+func (_m *MockTransactionRepository) LastCompletedBefore(ctx context.Context, userID uint64, before time.Time) (*entity.Transaction, error) {
+	ret := _m.Called(ctx, userID, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LastCompletedBefore")
+	}
+
+	var r0 *entity.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Time) (*entity.Transaction, error)); ok {
+		return rf(ctx, userID, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Time) *entity.Transaction); ok {
+		r0 = rf(ctx, userID, before)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, time.Time) error); ok {
+		r1 = rf(ctx, userID, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockTransactionRepository_LastCompletedBefore_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LastCompletedBefore'
+type MockTransactionRepository_LastCompletedBefore_Call struct {
+	*mock.Call
+}
+
+// LastCompletedBefore is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uint64
+//   - before time.Time
+func (_e *MockTransactionRepository_Expecter) LastCompletedBefore(ctx interface{}, userID interface{}, before interface{}) *MockTransactionRepository_LastCompletedBefore_Call {
+	return &MockTransactionRepository_LastCompletedBefore_Call{Call: _e.mock.On("LastCompletedBefore", ctx, userID, before)}
+}
+
+func (_c *MockTransactionRepository_LastCompletedBefore_Call) Run(run func(ctx context.Context, userID uint64, before time.Time)) *MockTransactionRepository_LastCompletedBefore_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepository_LastCompletedBefore_Call) Return(_a0 *entity.Transaction, _a1 error) *MockTransactionRepository_LastCompletedBefore_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockTransactionRepository_LastCompletedBefore_Call) RunAndReturn(run func(context.Context, uint64, time.Time) (*entity.Transaction, error)) *MockTransactionRepository_LastCompletedBefore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByStatus provides a mock function with given fields: ctx, status, limit
+func (_m *MockTransactionRepository) ListByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error) {
+	ret := _m.Called(ctx, status, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByStatus")
+	}
+
+	var r0 []*entity.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.TransactionStatus, int) ([]*entity.Transaction, error)); ok {
+		return rf(ctx, status, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.TransactionStatus, int) []*entity.Transaction); ok {
+		r0 = rf(ctx, status, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.TransactionStatus, int) error); ok {
+		r1 = rf(ctx, status, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockTransactionRepository_ListByStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByStatus'
+type MockTransactionRepository_ListByStatus_Call struct {
+	*mock.Call
+}
+
+// ListByStatus is a helper method to define mock.On call
+//   - ctx context.Context
+//   - status entity.TransactionStatus
+//   - limit int
+func (_e *MockTransactionRepository_Expecter) ListByStatus(ctx interface{}, status interface{}, limit interface{}) *MockTransactionRepository_ListByStatus_Call {
+	return &MockTransactionRepository_ListByStatus_Call{Call: _e.mock.On("ListByStatus", ctx, status, limit)}
+}
+
+func (_c *MockTransactionRepository_ListByStatus_Call) Run(run func(ctx context.Context, status entity.TransactionStatus, limit int)) *MockTransactionRepository_ListByStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.TransactionStatus), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepository_ListByStatus_Call) Return(_a0 []*entity.Transaction, _a1 error) *MockTransactionRepository_ListByStatus_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockTransactionRepository_ListByStatus_Call) RunAndReturn(run func(context.Context, entity.TransactionStatus, int) ([]*entity.Transaction, error)) *MockTransactionRepository_ListByStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByUser provides a mock function with given fields: ctx, userID, limit
+func (_m *MockTransactionRepository) ListByUser(ctx context.Context, userID uint64, limit int) ([]*entity.Transaction, error) {
+	ret := _m.Called(ctx, userID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByUser")
+	}
+
+	var r0 []*entity.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int) ([]*entity.Transaction, error)); ok {
+		return rf(ctx, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int) []*entity.Transaction); ok {
+		r0 = rf(ctx, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, int) error); ok {
+		r1 = rf(ctx, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockTransactionRepository_ListByUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByUser'
+type MockTransactionRepository_ListByUser_Call struct {
+	*mock.Call
+}
+
+// ListByUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uint64
+//   - limit int
+func (_e *MockTransactionRepository_Expecter) ListByUser(ctx interface{}, userID interface{}, limit interface{}) *MockTransactionRepository_ListByUser_Call {
+	return &MockTransactionRepository_ListByUser_Call{Call: _e.mock.On("ListByUser", ctx, userID, limit)}
+}
+
+func (_c *MockTransactionRepository_ListByUser_Call) Run(run func(ctx context.Context, userID uint64, limit int)) *MockTransactionRepository_ListByUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepository_ListByUser_Call) Return(_a0 []*entity.Transaction, _a1 error) *MockTransactionRepository_ListByUser_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockTransactionRepository_ListByUser_Call) RunAndReturn(run func(context.Context, uint64, int) ([]*entity.Transaction, error)) *MockTransactionRepository_ListByUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StreamByUser provides a mock function with given fields: ctx, userID, from, to, fn
+func (_m *MockTransactionRepository) StreamByUser(ctx context.Context, userID uint64, from time.Time, to time.Time, fn func(*entity.Transaction) error) error {
+	ret := _m.Called(ctx, userID, from, to, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StreamByUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Time, time.Time, func(*entity.Transaction) error) error); ok {
+		r0 = rf(ctx, userID, from, to, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockTransactionRepository_StreamByUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StreamByUser'
+type MockTransactionRepository_StreamByUser_Call struct {
+	*mock.Call
+}
+
+// StreamByUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uint64
+//   - from time.Time
+//   - to time.Time
+//   - fn func(*entity.Transaction) error
+func (_e *MockTransactionRepository_Expecter) StreamByUser(ctx interface{}, userID interface{}, from interface{}, to interface{}, fn interface{}) *MockTransactionRepository_StreamByUser_Call {
+	return &MockTransactionRepository_StreamByUser_Call{Call: _e.mock.On("StreamByUser", ctx, userID, from, to, fn)}
+}
+
+func (_c *MockTransactionRepository_StreamByUser_Call) Run(run func(ctx context.Context, userID uint64, from time.Time, to time.Time, fn func(*entity.Transaction) error)) *MockTransactionRepository_StreamByUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64), args[2].(time.Time), args[3].(time.Time), args[4].(func(*entity.Transaction) error))
+	})
+	return _c
+}
+
+func (_c *MockTransactionRepository_StreamByUser_Call) Return(_a0 error) *MockTransactionRepository_StreamByUser_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockTransactionRepository_StreamByUser_Call) RunAndReturn(run func(context.Context, uint64, time.Time, time.Time, func(*entity.Transaction) error) error) *MockTransactionRepository_StreamByUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // TransactionExists provides a mock function with given fields: ctx, transactionID
 func (_m *MockTransactionRepository) TransactionExists(ctx context.Context, transactionID string) (bool, error) {
 	ret := _m.Called(ctx, transactionID)
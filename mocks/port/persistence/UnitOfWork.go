@@ -126,6 +126,54 @@ func (_c *MockUnitOfWork_Commit_Call) RunAndReturn(run func(context.Context) err
 	return _c
 }
 
+// GetApprovalAuditRepository provides a mock function with given fields: ctx
+func (_m *MockUnitOfWork) GetApprovalAuditRepository(ctx context.Context) persistence.ApprovalAuditRepository {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetApprovalAuditRepository")
+	}
+
+	var r0 persistence.ApprovalAuditRepository
+	if rf, ok := ret.Get(0).(func(context.Context) persistence.ApprovalAuditRepository); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(persistence.ApprovalAuditRepository)
+		}
+	}
+
+	return r0
+}
+
+// MockUnitOfWork_GetApprovalAuditRepository_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetApprovalAuditRepository'
+type MockUnitOfWork_GetApprovalAuditRepository_Call struct {
+	*mock.Call
+}
+
+// GetApprovalAuditRepository is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockUnitOfWork_Expecter) GetApprovalAuditRepository(ctx interface{}) *MockUnitOfWork_GetApprovalAuditRepository_Call {
+	return &MockUnitOfWork_GetApprovalAuditRepository_Call{Call: _e.mock.On("GetApprovalAuditRepository", ctx)}
+}
+
+func (_c *MockUnitOfWork_GetApprovalAuditRepository_Call) Run(run func(ctx context.Context)) *MockUnitOfWork_GetApprovalAuditRepository_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockUnitOfWork_GetApprovalAuditRepository_Call) Return(_a0 persistence.ApprovalAuditRepository) *MockUnitOfWork_GetApprovalAuditRepository_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockUnitOfWork_GetApprovalAuditRepository_Call) RunAndReturn(run func(context.Context) persistence.ApprovalAuditRepository) *MockUnitOfWork_GetApprovalAuditRepository_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetTransactionRepository provides a mock function with given fields: ctx
 func (_m *MockUnitOfWork) GetTransactionRepository(ctx context.Context) persistence.TransactionRepository {
 	ret := _m.Called(ctx)
@@ -4,10 +4,10 @@ package persistence
 
 import (
 	context "context"
+	time "time"
 
+	persistence "github.com/amirhossein-jamali/balance-processor/internal/domain/port/persistence"
 	mock "github.com/stretchr/testify/mock"
-
-	time "time"
 )
 
 // MockUserLockRepository is an autogenerated mock type for the UserLockRepository type
@@ -24,21 +24,31 @@ func (_m *MockUserLockRepository) EXPECT() *MockUserLockRepository_Expecter {
 }
 
 // AcquireLock provides a mock function with given fields: ctx, userID, duration
-func (_m *MockUserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) error {
+func (_m *MockUserLockRepository) AcquireLock(ctx context.Context, userID uint64, duration time.Duration) (persistence.LockToken, error) {
 	ret := _m.Called(ctx, userID, duration)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AcquireLock")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Duration) error); ok {
+	var r0 persistence.LockToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Duration) (persistence.LockToken, error)); ok {
+		return rf(ctx, userID, duration)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Duration) persistence.LockToken); ok {
 		r0 = rf(ctx, userID, duration)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(persistence.LockToken)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, time.Duration) error); ok {
+		r1 = rf(ctx, userID, duration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // MockUserLockRepository_AcquireLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AcquireLock'
@@ -61,27 +71,73 @@ func (_c *MockUserLockRepository_AcquireLock_Call) Run(run func(ctx context.Cont
 	return _c
 }
 
-func (_c *MockUserLockRepository_AcquireLock_Call) Return(_a0 error) *MockUserLockRepository_AcquireLock_Call {
+func (_c *MockUserLockRepository_AcquireLock_Call) Return(_a0 persistence.LockToken, _a1 error) *MockUserLockRepository_AcquireLock_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockUserLockRepository_AcquireLock_Call) RunAndReturn(run func(context.Context, uint64, time.Duration) (persistence.LockToken, error)) *MockUserLockRepository_AcquireLock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CleanupExpiredLocks provides a mock function with given fields: ctx
+func (_m *MockUserLockRepository) CleanupExpiredLocks(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CleanupExpiredLocks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockUserLockRepository_CleanupExpiredLocks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CleanupExpiredLocks'
+type MockUserLockRepository_CleanupExpiredLocks_Call struct {
+	*mock.Call
+}
+
+// CleanupExpiredLocks is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockUserLockRepository_Expecter) CleanupExpiredLocks(ctx interface{}) *MockUserLockRepository_CleanupExpiredLocks_Call {
+	return &MockUserLockRepository_CleanupExpiredLocks_Call{Call: _e.mock.On("CleanupExpiredLocks", ctx)}
+}
+
+func (_c *MockUserLockRepository_CleanupExpiredLocks_Call) Run(run func(ctx context.Context)) *MockUserLockRepository_CleanupExpiredLocks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockUserLockRepository_CleanupExpiredLocks_Call) Return(_a0 error) *MockUserLockRepository_CleanupExpiredLocks_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockUserLockRepository_AcquireLock_Call) RunAndReturn(run func(context.Context, uint64, time.Duration) error) *MockUserLockRepository_AcquireLock_Call {
+func (_c *MockUserLockRepository_CleanupExpiredLocks_Call) RunAndReturn(run func(context.Context) error) *MockUserLockRepository_CleanupExpiredLocks_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ReleaseLock provides a mock function with given fields: ctx, userID
-func (_m *MockUserLockRepository) ReleaseLock(ctx context.Context, userID uint64) error {
-	ret := _m.Called(ctx, userID)
+// ReleaseLock provides a mock function with given fields: ctx, userID, token
+func (_m *MockUserLockRepository) ReleaseLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	ret := _m.Called(ctx, userID, token)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ReleaseLock")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, persistence.LockToken) error); ok {
+		r0 = rf(ctx, userID, token)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -97,13 +153,14 @@ type MockUserLockRepository_ReleaseLock_Call struct {
 // ReleaseLock is a helper method to define mock.On call
 //   - ctx context.Context
 //   - userID uint64
-func (_e *MockUserLockRepository_Expecter) ReleaseLock(ctx interface{}, userID interface{}) *MockUserLockRepository_ReleaseLock_Call {
-	return &MockUserLockRepository_ReleaseLock_Call{Call: _e.mock.On("ReleaseLock", ctx, userID)}
+//   - token persistence.LockToken
+func (_e *MockUserLockRepository_Expecter) ReleaseLock(ctx interface{}, userID interface{}, token interface{}) *MockUserLockRepository_ReleaseLock_Call {
+	return &MockUserLockRepository_ReleaseLock_Call{Call: _e.mock.On("ReleaseLock", ctx, userID, token)}
 }
 
-func (_c *MockUserLockRepository_ReleaseLock_Call) Run(run func(ctx context.Context, userID uint64)) *MockUserLockRepository_ReleaseLock_Call {
+func (_c *MockUserLockRepository_ReleaseLock_Call) Run(run func(ctx context.Context, userID uint64, token persistence.LockToken)) *MockUserLockRepository_ReleaseLock_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(uint64))
+		run(args[0].(context.Context), args[1].(uint64), args[2].(persistence.LockToken))
 	})
 	return _c
 }
@@ -113,7 +170,104 @@ func (_c *MockUserLockRepository_ReleaseLock_Call) Return(_a0 error) *MockUserLo
 	return _c
 }
 
-func (_c *MockUserLockRepository_ReleaseLock_Call) RunAndReturn(run func(context.Context, uint64) error) *MockUserLockRepository_ReleaseLock_Call {
+func (_c *MockUserLockRepository_ReleaseLock_Call) RunAndReturn(run func(context.Context, uint64, persistence.LockToken) error) *MockUserLockRepository_ReleaseLock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RenewLock provides a mock function with given fields: ctx, userID, token, duration
+func (_m *MockUserLockRepository) RenewLock(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration) error {
+	ret := _m.Called(ctx, userID, token, duration)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, persistence.LockToken, time.Duration) error); ok {
+		r0 = rf(ctx, userID, token, duration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockUserLockRepository_RenewLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenewLock'
+type MockUserLockRepository_RenewLock_Call struct {
+	*mock.Call
+}
+
+// RenewLock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uint64
+//   - token persistence.LockToken
+//   - duration time.Duration
+func (_e *MockUserLockRepository_Expecter) RenewLock(ctx interface{}, userID interface{}, token interface{}, duration interface{}) *MockUserLockRepository_RenewLock_Call {
+	return &MockUserLockRepository_RenewLock_Call{Call: _e.mock.On("RenewLock", ctx, userID, token, duration)}
+}
+
+func (_c *MockUserLockRepository_RenewLock_Call) Run(run func(ctx context.Context, userID uint64, token persistence.LockToken, duration time.Duration)) *MockUserLockRepository_RenewLock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64), args[2].(persistence.LockToken), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *MockUserLockRepository_RenewLock_Call) Return(_a0 error) *MockUserLockRepository_RenewLock_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockUserLockRepository_RenewLock_Call) RunAndReturn(run func(context.Context, uint64, persistence.LockToken, time.Duration) error) *MockUserLockRepository_RenewLock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// VerifyLock provides a mock function with given fields: ctx, userID, token
+func (_m *MockUserLockRepository) VerifyLock(ctx context.Context, userID uint64, token persistence.LockToken) error {
+	ret := _m.Called(ctx, userID, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, persistence.LockToken) error); ok {
+		r0 = rf(ctx, userID, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockUserLockRepository_VerifyLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'VerifyLock'
+type MockUserLockRepository_VerifyLock_Call struct {
+	*mock.Call
+}
+
+// VerifyLock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uint64
+//   - token persistence.LockToken
+func (_e *MockUserLockRepository_Expecter) VerifyLock(ctx interface{}, userID interface{}, token interface{}) *MockUserLockRepository_VerifyLock_Call {
+	return &MockUserLockRepository_VerifyLock_Call{Call: _e.mock.On("VerifyLock", ctx, userID, token)}
+}
+
+func (_c *MockUserLockRepository_VerifyLock_Call) Run(run func(ctx context.Context, userID uint64, token persistence.LockToken)) *MockUserLockRepository_VerifyLock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint64), args[2].(persistence.LockToken))
+	})
+	return _c
+}
+
+func (_c *MockUserLockRepository_VerifyLock_Call) Return(_a0 error) *MockUserLockRepository_VerifyLock_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockUserLockRepository_VerifyLock_Call) RunAndReturn(run func(context.Context, uint64, persistence.LockToken) error) *MockUserLockRepository_VerifyLock_Call {
 	_c.Call.Return(run)
 	return _c
 }
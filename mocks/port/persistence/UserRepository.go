@@ -129,7 +129,7 @@ func (_c *MockUserRepository_GetByID_Call) RunAndReturn(run func(context.Context
 }
 
 // ProcessTransaction provides a mock function with given fields: ctx, userID, balanceChange
-func (_m *MockUserRepository) ProcessTransaction(ctx context.Context, userID uint64, balanceChange int64) (*entity.User, error) {
+func (_m *MockUserRepository) ProcessTransaction(ctx context.Context, userID uint64, balanceChange entity.Money) (*entity.User, error) {
 	ret := _m.Called(ctx, userID, balanceChange)
 
 	if len(ret) == 0 {
@@ -138,10 +138,10 @@ func (_m *MockUserRepository) ProcessTransaction(ctx context.Context, userID uin
 
 	var r0 *entity.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64) (*entity.User, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, entity.Money) (*entity.User, error)); ok {
 		return rf(ctx, userID, balanceChange)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64) *entity.User); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, entity.Money) *entity.User); ok {
 		r0 = rf(ctx, userID, balanceChange)
 	} else {
 		if ret.Get(0) != nil {
@@ -149,7 +149,7 @@ func (_m *MockUserRepository) ProcessTransaction(ctx context.Context, userID uin
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, uint64, int64) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, entity.Money) error); ok {
 		r1 = rf(ctx, userID, balanceChange)
 	} else {
 		r1 = ret.Error(1)
@@ -166,14 +166,14 @@ type MockUserRepository_ProcessTransaction_Call struct {
 // ProcessTransaction is a helper method to define mock.On call
 //   - ctx context.Context
 //   - userID uint64
-//   - balanceChange int64
+//   - balanceChange entity.Money
 func (_e *MockUserRepository_Expecter) ProcessTransaction(ctx interface{}, userID interface{}, balanceChange interface{}) *MockUserRepository_ProcessTransaction_Call {
 	return &MockUserRepository_ProcessTransaction_Call{Call: _e.mock.On("ProcessTransaction", ctx, userID, balanceChange)}
 }
 
-func (_c *MockUserRepository_ProcessTransaction_Call) Run(run func(ctx context.Context, userID uint64, balanceChange int64)) *MockUserRepository_ProcessTransaction_Call {
+func (_c *MockUserRepository_ProcessTransaction_Call) Run(run func(ctx context.Context, userID uint64, balanceChange entity.Money)) *MockUserRepository_ProcessTransaction_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(uint64), args[2].(int64))
+		run(args[0].(context.Context), args[1].(uint64), args[2].(entity.Money))
 	})
 	return _c
 }
@@ -183,7 +183,7 @@ func (_c *MockUserRepository_ProcessTransaction_Call) Return(_a0 *entity.User, _
 	return _c
 }
 
-func (_c *MockUserRepository_ProcessTransaction_Call) RunAndReturn(run func(context.Context, uint64, int64) (*entity.User, error)) *MockUserRepository_ProcessTransaction_Call {
+func (_c *MockUserRepository_ProcessTransaction_Call) RunAndReturn(run func(context.Context, uint64, entity.Money) (*entity.User, error)) *MockUserRepository_ProcessTransaction_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -1,63 +1,18 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
-	"math/rand"
-	"net/http"
+	"os"
+	"os/signal"
+	"sort"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
-)
-
-// Transaction represents the transaction payload
-type Transaction struct {
-	State         string `json:"state"`
-	Amount        string `json:"amount"`
-	TransactionID string `json:"transactionId"`
-}
-
-// Response represents the API response
-type Response struct {
-	TransactionID string `json:"transactionId"`
-	UserID        uint64 `json:"userId"`
-	Success       bool   `json:"success"`
-	ResultBalance string `json:"resultBalance,omitempty"`
-	ErrorMessage  string `json:"errorMessage,omitempty"`
-}
-
-// TestResult contains metrics for a single request
-type TestResult struct {
-	Success      bool
-	ResponseTime time.Duration
-	StatusCode   int
-	Error        error
-}
 
-// TestStats contains aggregated test statistics
-type TestStats struct {
-	TotalRequests      int
-	SuccessfulRequests int
-	FailedRequests     int
-	TotalTime          time.Duration
-	MinResponseTime    time.Duration
-	MaxResponseTime    time.Duration
-	TotalResponseTime  time.Duration
-	ResponseTimes      []time.Duration
-	ErrorCounts        map[string]int
-	UserStats          map[int]int    // Track requests per user
-	ScenarioStats      map[string]int // Track requests per scenario
-	Lock               sync.Mutex
-}
-
-// TransactionScenario defines a transaction scenario
-type TransactionScenario struct {
-	Name   string // For stats tracking
-	State  string
-	Amount string
-}
+	"github.com/amirhossein-jamali/balance-processor/script/loadtest"
+)
 
 func main() {
 
@@ -68,325 +23,311 @@ func main() {
 	baseURL := flag.String("url", "http://localhost:8080", "Base URL for the API")
 	sourceType := flag.String("source", "game", "Source-Type header (game, server, or payment)")
 	delayMs := flag.Int("delay", 100, "Delay between requests in milliseconds")
+	scenarioFile := flag.String("scenario", "", "YAML scenario file; replaces -c, -n, -u, -source and -delay")
+	seed := flag.Int64("seed", 0, "Seed for user and transaction choices (overrides the scenario's; 0 is random)")
+	reportFile := flag.String("report", "", "Write a JSON report to this file")
+	baselineFile := flag.String("baseline", "", "Compare the run with a JSON report of an earlier run")
+	tolerance := flag.Float64("tolerance", 10, "Percent throughput and latencies may worsen against -baseline")
+	verify := flag.Bool("verify", false, "Check the final balances against the responses, with duplicate and replayed transaction IDs")
 	flag.Parse()
 
-	// Parse user IDs
-	var userIDs []int
-	for _, idStr := range strings.Split(*userIDsStr, ",") {
-		var id int
-		if _, err := fmt.Sscanf(idStr, "%d", &id); err == nil && id > 0 {
-			userIDs = append(userIDs, id)
-		}
-	}
-
-	// Default to user ID 1 if no valid IDs provided
-	if len(userIDs) == 0 {
-		userIDs = []int{1}
+	scenario, err := buildScenario(*scenarioFile, *concurrency, *totalRequests, *userIDsStr, *sourceType, *delayMs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load test: %v\n", err)
+		os.Exit(2)
 	}
-
-	// Define transaction scenarios
-	scenarios := []TransactionScenario{
-		{"Win Small", "win", "10.00"},
-		{"Win Medium", "win", "20.00"},
-		{"Win Large", "win", "30.00"},
-		{"Lose Small", "lose", "15.00"},
-		{"Lose Medium", "lose", "40.00"},
-		{"Lose Large", "lose", "60.00"},
+	if *scenarioFile == "" || isFlagSet("url") || scenario.BaseURL == "" {
+		scenario.BaseURL = *baseURL
 	}
-
-	fmt.Printf("Load testing API across %d users: %v\n", len(userIDs), userIDs)
-	fmt.Printf("Transaction scenarios: %d different combinations\n", len(scenarios))
-	fmt.Printf("Concurrency: %d goroutines\n", *concurrency)
-	fmt.Printf("Total requests: %d\n", *totalRequests)
-	fmt.Printf("Delay between requests: %d ms\n", *delayMs)
-
-	// Initialize test statistics
-	stats := &TestStats{
-		TotalRequests:   *totalRequests,
-		MinResponseTime: time.Hour, // Start with a high value that will be replaced
-		ErrorCounts:     make(map[string]int),
-		ResponseTimes:   make([]time.Duration, 0, *totalRequests),
-		UserStats:       make(map[int]int),
-		ScenarioStats:   make(map[string]int),
+	scenario.BaseURL = strings.TrimRight(scenario.BaseURL, "/")
+	if *seed != 0 {
+		scenario.Seed = *seed
 	}
-
-	// Initialize stats for each user
-	for _, id := range userIDs {
-		stats.UserStats[id] = 0
+	if *verify && scenario.Verify == nil {
+		verification := loadtest.DefaultVerification
+		scenario.Verify = &verification
 	}
 
-	// Initialize stats for each scenario
-	for _, scenario := range scenarios {
-		stats.ScenarioStats[scenario.Name] = 0
+	var baseline *loadtest.Report
+	if *baselineFile != "" {
+		if baseline, err = loadtest.ReadReport(*baselineFile); err != nil {
+			fmt.Fprintf(os.Stderr, "load test: %v\n", err)
+			os.Exit(2)
+		}
 	}
 
-	// Channel to collect results
-	results := make(chan TestResult, *totalRequests)
+	printScenario(scenario)
 
-	// Channel to distribute work
-	jobs := make(chan int, *totalRequests)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Start worker goroutines
-	var wg sync.WaitGroup
-	fmt.Println("Starting worker goroutines...")
-	for i := 0; i < *concurrency; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			worker(workerID, *baseURL, *sourceType, *delayMs, userIDs, scenarios, jobs, results, stats)
-		}(i)
+	fmt.Println("Test running...")
+	report, err := loadtest.NewRunner(scenario).WithProgress(func(p loadtest.Progress) {
+		printProgress(scenario, p)
+	}).Run(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load test: %v\n", err)
+		os.Exit(1)
+	}
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted; results cover the requests completed so far")
 	}
 
-	// Fill the jobs channel
-	go func() {
-		for i := 0; i < *totalRequests; i++ {
-			jobs <- i
-		}
-		close(jobs)
-	}()
-
-	// Start a goroutine to collect results
-	go func() {
-		for result := range results {
-			stats.Lock.Lock()
-			if result.Success {
-				stats.SuccessfulRequests++
-			} else {
-				stats.FailedRequests++
-				errMsg := "unknown"
-				if result.Error != nil {
-					errMsg = result.Error.Error()
-				}
-				stats.ErrorCounts[errMsg]++
-			}
-
-			stats.ResponseTimes = append(stats.ResponseTimes, result.ResponseTime)
-			stats.TotalResponseTime += result.ResponseTime
-
-			if result.ResponseTime < stats.MinResponseTime {
-				stats.MinResponseTime = result.ResponseTime
-			}
-			if result.ResponseTime > stats.MaxResponseTime {
-				stats.MaxResponseTime = result.ResponseTime
-			}
-			stats.Lock.Unlock()
-		}
-	}()
-
-	// Start the timer
-	startTime := time.Now()
-	fmt.Println("Test running...")
+	// Print results
+	printResults(report)
 
-	// Print progress periodically
-	ticker := time.NewTicker(1 * time.Second)
-	go func() {
-		for range ticker.C {
-			stats.Lock.Lock()
-			completed := stats.SuccessfulRequests + stats.FailedRequests
-			if completed > 0 {
-				fmt.Printf("Progress: %d/%d requests completed (%.1f%%)\n",
-					completed, stats.TotalRequests, float64(completed)/float64(stats.TotalRequests)*100)
-			}
-			stats.Lock.Unlock()
+	if *reportFile != "" {
+		if err := report.WriteFile(*reportFile); err != nil {
+			fmt.Fprintf(os.Stderr, "load test: failed to write report: %v\n", err)
+			os.Exit(1)
 		}
-	}()
-
-	// Wait for all workers to finish
-	wg.Wait()
-	close(results)
-	ticker.Stop()
-
-	// Calculate the total test time
-	stats.TotalTime = time.Since(startTime)
+		fmt.Printf("Report written to %s\n", *reportFile)
+	}
 
-	// Print results
-	printResults(stats)
+	failed := report.Verification != nil && !report.Verification.Passed
+	if baseline != nil {
+		comparisons := loadtest.Compare(baseline, report, *tolerance)
+		printComparison(*baselineFile, comparisons)
+		failed = failed || loadtest.Regressed(comparisons)
+	}
+	if failed {
+		os.Exit(1)
+	}
 }
 
-func worker(id int, baseURL, sourceType string, delayMs int, userIDs []int,
-	scenarios []TransactionScenario, jobs <-chan int, results chan<- TestResult, stats *TestStats) {
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// buildScenario loads the scenario file, or builds the classic fixed-request scenario from the flags
+func buildScenario(file string, concurrency, requests int, userIDs, sourceType string, delayMs int) (*loadtest.Scenario, error) {
+	if file != "" {
+		return loadtest.LoadScenario(file)
 	}
 
-	for jobID := range jobs {
-		// Optional delay between requests to prevent rate limiting
-		if delayMs > 0 {
-			time.Sleep(time.Duration(delayMs) * time.Millisecond)
-		}
-
-		// Randomly select a user ID
-		userID := userIDs[rand.Intn(len(userIDs))]
-
-		// Randomly select a transaction scenario
-		scenario := scenarios[rand.Intn(len(scenarios))]
-
-		// Update stats for which user and scenario was selected
-		stats.Lock.Lock()
-		stats.UserStats[userID]++
-		stats.ScenarioStats[scenario.Name]++
-		stats.Lock.Unlock()
-
-		// Create API URL for this user
-		apiURL := fmt.Sprintf("%s/user/%d/transaction", baseURL, userID)
-
-		// Create a unique transaction
-		transaction := Transaction{
-			State:         scenario.State,
-			Amount:        scenario.Amount,
-			TransactionID: fmt.Sprintf("test-%d-%d-%d", id, jobID, rand.Intn(1000000)),
-		}
-
-		jsonData, err := json.Marshal(transaction)
-		if err != nil {
-			results <- TestResult{Success: false, Error: err}
-			continue
-		}
-
-		// Create request
-		req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			results <- TestResult{Success: false, Error: err}
-			continue
-		}
-
-		// Set headers
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Source-Type", sourceType)
-
-		// Send the request and measure response time
-		startTime := time.Now()
-		resp, err := client.Do(req)
-		responseTime := time.Since(startTime)
-
-		result := TestResult{
-			ResponseTime: responseTime,
+	// Parse user IDs
+	var ids []uint64
+	for _, idStr := range strings.Split(userIDs, ",") {
+		var id uint64
+		if _, err := fmt.Sscanf(idStr, "%d", &id); err == nil && id > 0 {
+			ids = append(ids, id)
 		}
+	}
 
-		if err != nil {
-			result.Success = false
-			result.Error = err
-		} else {
-			statusCode := resp.StatusCode
-			result.StatusCode = statusCode
-			result.Success = (statusCode >= 200 && statusCode < 300)
-			if !result.Success {
-				result.Error = fmt.Errorf("HTTP status code %d", statusCode)
-			}
-			resp.Body.Close()
-		}
+	// Default to user ID 1 if no valid IDs provided
+	if len(ids) == 0 {
+		ids = []uint64{1}
+	}
 
-		results <- result
+	scenario := &loadtest.Scenario{
+		Name:      "fixed-requests",
+		Model:     loadtest.ModelClosed,
+		Workers:   concurrency,
+		Requests:  requests,
+		ThinkTime: time.Duration(delayMs) * time.Millisecond,
+		Users:     loadtest.Users{IDs: ids},
+		Transactions: []loadtest.TransactionMix{
+			{Name: "Win Small", SourceType: sourceType, State: "win", Amount: "10.00"},
+			{Name: "Win Medium", SourceType: sourceType, State: "win", Amount: "20.00"},
+			{Name: "Win Large", SourceType: sourceType, State: "win", Amount: "30.00"},
+			{Name: "Lose Small", SourceType: sourceType, State: "lose", Amount: "15.00"},
+			{Name: "Lose Medium", SourceType: sourceType, State: "lose", Amount: "40.00"},
+			{Name: "Lose Large", SourceType: sourceType, State: "lose", Amount: "60.00"},
+		},
 	}
+	return scenario, scenario.Validate()
 }
 
-func printResults(stats *TestStats) {
-	// Calculate theoretical TPS (ignores actual delays between requests)
-	rawTps := float64(stats.SuccessfulRequests) / stats.TotalTime.Seconds()
-
-	// Calculate TPS if all requests were successful
-	theoreticalTps := float64(stats.TotalRequests) / stats.TotalTime.Seconds()
+// isFlagSet reports whether a flag was given on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
 
-	// Calculate success rate adjusted TPS
-	adjustedTps := theoreticalTps * (float64(stats.SuccessfulRequests) / float64(stats.TotalRequests))
+func printScenario(scenario *loadtest.Scenario) {
+	fmt.Printf("Scenario: %s (%s model) against %s\n", scenario.Name, scenario.Model, scenario.BaseURL)
+	fmt.Printf("Load testing API across %d users (%s distribution)\n", len(scenario.Users.IDs), scenario.Users.Distribution)
+	fmt.Printf("Transaction scenarios: %d different combinations\n", len(scenario.Transactions))
 
-	// Calculate average response time
-	var avgResponseTime time.Duration
-	if len(stats.ResponseTimes) > 0 {
-		avgResponseTime = stats.TotalResponseTime / time.Duration(len(stats.ResponseTimes))
+	unit := "workers"
+	if scenario.Model == loadtest.ModelOpen {
+		unit = "requests/s"
+		fmt.Printf("Most requests in flight: %d\n", scenario.MaxInFlight)
 	}
+	if len(scenario.Stages) == 0 {
+		fmt.Printf("Concurrency: %d goroutines\n", scenario.Workers)
+	}
+	for i, stage := range scenario.Stages {
+		fmt.Printf("Stage %d: %v to %g %s\n", i+1, stage.Duration, stage.Target, unit)
+	}
+	if scenario.Requests > 0 {
+		fmt.Printf("Total requests: %d\n", scenario.Requests)
+	}
+	if scenario.ThinkTime > 0 {
+		fmt.Printf("Delay between requests: %v\n", scenario.ThinkTime)
+	}
+	if scenario.Verify != nil {
+		fmt.Printf("Verifying balances with %.0f%% duplicates and %.0f%% concurrent replays\n",
+			scenario.Verify.DuplicateRate*100, scenario.Verify.ReplayRate*100)
+	}
+}
 
-	// Calculate percentiles
-	var p50, p90, p95, p99 time.Duration
-	if len(stats.ResponseTimes) > 0 {
-		// Sort the response times
-		sortedTimes := make([]time.Duration, len(stats.ResponseTimes))
-		copy(sortedTimes, stats.ResponseTimes)
-
-		// Simple bubble sort (OK for small datasets)
-		for i := 0; i < len(sortedTimes); i++ {
-			for j := i + 1; j < len(sortedTimes); j++ {
-				if sortedTimes[i] > sortedTimes[j] {
-					sortedTimes[i], sortedTimes[j] = sortedTimes[j], sortedTimes[i]
-				}
-			}
-		}
-
-		p50 = sortedTimes[len(sortedTimes)*50/100]
-		p90 = sortedTimes[len(sortedTimes)*90/100]
-		p95 = sortedTimes[len(sortedTimes)*95/100]
-		p99 = sortedTimes[len(sortedTimes)*99/100]
+func printProgress(scenario *loadtest.Scenario, p loadtest.Progress) {
+	switch {
+	case p.Stage > 0:
+		fmt.Printf("Progress: %s stage %d/%d, target %.1f, %d requests completed, %d failed\n",
+			p.Elapsed.Truncate(time.Second), p.Stage, len(scenario.Stages), p.Target, p.Completed, p.Failed)
+	case scenario.Requests > 0 && p.Completed > 0:
+		fmt.Printf("Progress: %d/%d requests completed (%.1f%%)\n",
+			p.Completed, scenario.Requests, float64(p.Completed)/float64(scenario.Requests)*100)
 	}
+}
 
+func printResults(report *loadtest.Report) {
 	// Print results
 	fmt.Println("\n================= TEST RESULTS =================")
-	fmt.Printf("Total Requests:      %d\n", stats.TotalRequests)
-	fmt.Printf("Successful Requests: %d (%.1f%%)\n", stats.SuccessfulRequests,
-		float64(stats.SuccessfulRequests)/float64(stats.TotalRequests)*100)
-	fmt.Printf("Failed Requests:     %d (%.1f%%)\n", stats.FailedRequests,
-		float64(stats.FailedRequests)/float64(stats.TotalRequests)*100)
-	fmt.Printf("Total Test Time:     %.2f seconds\n", stats.TotalTime.Seconds())
-
+	fmt.Printf("Total Requests:      %d\n", report.Requests)
+	fmt.Printf("Successful Requests: %d (%.1f%%)\n", report.Succeeded, percent(report.Succeeded, report.Requests))
+	fmt.Printf("Failed Requests:     %d (%.1f%%)\n", report.Failed, percent(report.Failed, report.Requests))
+	fmt.Printf("Total Test Time:     %.2f seconds\n", report.DurationSeconds)
+	fmt.Printf("Seed:                %d\n", report.Seed)
+
+	successTps := 0.0
+	if report.DurationSeconds > 0 {
+		successTps = float64(report.Succeeded) / report.DurationSeconds
+	}
 	fmt.Println("\n----------------- PERFORMANCE -----------------")
-	fmt.Printf("Raw TPS:             %.2f (successful requests / total time)\n", rawTps)
-	fmt.Printf("Theoretical TPS:     %.2f (if all requests were successful)\n", theoreticalTps)
-	fmt.Printf("Success-adjusted TPS: %.2f (theoretical * success rate)\n", adjustedTps)
+	fmt.Printf("Throughput:          %.2f requests/s\n", report.Throughput)
+	fmt.Printf("Successful TPS:      %.2f\n", successTps)
+	if report.Queued > 0 {
+		fmt.Printf("Queued:              %d requests waited for an in-flight slot\n", report.Queued)
+	}
 
 	fmt.Println("\n----------------- RESPONSE TIMES -----------------")
-	fmt.Printf("Average Response:    %v\n", avgResponseTime)
-	fmt.Printf("Minimum Response:    %v\n", stats.MinResponseTime)
-	fmt.Printf("Maximum Response:    %v\n", stats.MaxResponseTime)
-	fmt.Printf("P50 Response:        %v\n", p50)
-	fmt.Printf("P90 Response:        %v\n", p90)
-	fmt.Printf("P95 Response:        %v\n", p95)
-	fmt.Printf("P99 Response:        %v\n", p99)
-
-	// Print user distribution
+	printLatency("Latency", report.Latency)
+	if report.ServiceTime != nil {
+		printLatency("Service time", *report.ServiceTime)
+	}
+
+	if len(report.Stages) > 0 {
+		fmt.Println("\n----------------- STAGES -----------------")
+		for _, stage := range report.Stages {
+			fmt.Printf("Stage %d (target %g): %d requests, %d failed, %.2f/s, p50 %.2fms, p99 %.2fms\n",
+				stage.Stage, stage.Target, stage.Requests, stage.Failed, stage.Throughput,
+				stage.Latency.P50, stage.Latency.P99)
+		}
+	}
+
+	// Print user distribution, busiest first
 	fmt.Println("\n----------------- USER DISTRIBUTION -----------------")
-	totalUsers := 0
-	for _, count := range stats.UserStats {
-		totalUsers += count
-	}
-	for userID, count := range stats.UserStats {
-		if count > 0 {
-			fmt.Printf("User %d:    %d requests (%.1f%%)\n", userID, count,
-				float64(count)/float64(totalUsers)*100)
+	users := make([]uint64, 0, len(report.Users))
+	for userID := range report.Users {
+		users = append(users, userID)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if report.Users[users[i]] != report.Users[users[j]] {
+			return report.Users[users[i]] > report.Users[users[j]]
+		}
+		return users[i] < users[j]
+	})
+	for i, userID := range users {
+		if i == 10 {
+			fmt.Printf("... and %d more users\n", len(users)-i)
+			break
 		}
+		fmt.Printf("User %d:    %d requests (%.1f%%)\n", userID, report.Users[userID],
+			percent(report.Users[userID], report.Requests))
 	}
 
 	// Print scenario distribution
 	fmt.Println("\n----------------- SCENARIO DISTRIBUTION -----------------")
-	totalScenarios := 0
-	for _, count := range stats.ScenarioStats {
-		totalScenarios += count
-	}
-	for scenario, count := range stats.ScenarioStats {
-		if count > 0 {
-			fmt.Printf("%-15s: %d requests (%.1f%%)\n", scenario, count,
-				float64(count)/float64(totalScenarios)*100)
-		}
+	for _, name := range sortedKeys(report.Transactions) {
+		fmt.Printf("%-15s: %d requests (%.1f%%)\n", name, report.Transactions[name],
+			percent(report.Transactions[name], report.Requests))
 	}
 
 	// Print error distribution if there were errors
-	if stats.FailedRequests > 0 {
+	if report.Failed > 0 {
 		fmt.Println("\n----------------- ERROR DISTRIBUTION -----------------")
-		for errMsg, count := range stats.ErrorCounts {
-			fmt.Printf("%-40s: %d (%.1f%%)\n", errMsg, count,
-				float64(count)/float64(stats.TotalRequests)*100)
+		for _, errMsg := range sortedKeys(report.Errors) {
+			fmt.Printf("%-40s: %d (%.1f%%)\n", errMsg, report.Errors[errMsg],
+				percent(report.Errors[errMsg], report.Requests))
 		}
 	}
 
+	if report.Verification != nil {
+		printVerification(report.Verification)
+	}
+
 	// Final conclusion
 	fmt.Println("\n================= CONCLUSION =================")
-	if theoreticalTps >= 30 {
-		fmt.Printf("✅ SYSTEM CAN THEORETICALLY EXCEED the required 30 TPS threshold (%.2f TPS)\n", theoreticalTps)
+	if report.Throughput >= 30 {
+		fmt.Printf("✅ SYSTEM CAN THEORETICALLY EXCEED the required 30 TPS threshold (%.2f TPS)\n", report.Throughput)
 
-		if rawTps < 30 {
+		if successTps < 30 {
 			fmt.Println("⚠️ But rate limiting or other issues are preventing full performance")
 		}
 	} else {
-		fmt.Printf("❌ SYSTEM DOES NOT MEET the required 30 TPS threshold (%.2f TPS)\n", theoreticalTps)
+		fmt.Printf("❌ SYSTEM DOES NOT MEET the required 30 TPS threshold (%.2f TPS)\n", report.Throughput)
 	}
 	fmt.Println("================================================")
 }
+
+func printVerification(verification *loadtest.VerificationReport) {
+	fmt.Println("\n----------------- VERIFICATION -----------------")
+	fmt.Printf("Transactions:        %d sent, %d applied\n", verification.Transactions, verification.Applied)
+	fmt.Printf("Duplicates:          %d requests resent an answered transaction ID\n", verification.Duplicates)
+	fmt.Printf("Concurrent replays:  %d transactions sent twice at once\n", verification.Replays)
+	if verification.Resolved > 0 {
+		fmt.Printf("Resolved:            %d transactions resent after the run to learn their outcome\n", verification.Resolved)
+	}
+	for _, user := range verification.Users {
+		if user.Actual != user.Expected {
+			fmt.Printf("User %d:    balance %s, expected %s (from %s and %d applied transactions)\n",
+				user.UserID, user.Actual, user.Expected, user.Initial, user.Applied)
+		}
+	}
+	for i, violation := range verification.Violations {
+		if i == 20 {
+			fmt.Printf("... and %d more violations\n", len(verification.Violations)-i)
+			break
+		}
+		fmt.Printf("❌ %s\n", violation)
+	}
+	if verification.Passed {
+		fmt.Printf("✅ All %d users have the balances their responses imply\n", len(verification.Users))
+	}
+}
+
+func printLatency(label string, summary loadtest.LatencySummary) {
+	fmt.Printf("%s (ms):  min %.2f  mean %.2f  p50 %.2f  p90 %.2f  p99 %.2f  p99.9 %.2f  max %.2f\n",
+		label, summary.Min, summary.Mean, summary.P50, summary.P90, summary.P99, summary.P999, summary.Max)
+}
+
+func printComparison(baselineFile string, comparisons []loadtest.Comparison) {
+	fmt.Printf("\n----------------- AGAINST %s -----------------\n", baselineFile)
+	for _, c := range comparisons {
+		verdict := "ok"
+		if c.Regressed {
+			verdict = "REGRESSED"
+		}
+		fmt.Printf("%-14s %12.2f -> %12.2f  %+8.1f  %s\n", c.Metric, c.Baseline, c.Current, c.Change, verdict)
+	}
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package loadtest
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+)
+
+// Request is one transaction the load test sends
+type Request struct {
+	UserID        uint64
+	Mix           string // Name of the transaction mix entry it came from
+	SourceType    string
+	State         string
+	Amount        entity.Money
+	TransactionID string
+}
+
+// Generator picks users and transactions for a scenario
+// A generator is not safe for concurrent use; give every worker its own.
+type Generator struct {
+	rng        *rand.Rand
+	zipf       *rand.Zipf
+	users      []uint64
+	mix        []TransactionMix
+	cumulative []int // Running total of the mix weights
+	prefix     string
+	next       uint64
+}
+
+// NewGenerator creates a generator for a validated scenario
+// Transaction IDs are the prefix followed by a sequence number, so generators need distinct prefixes.
+func NewGenerator(scenario *Scenario, seed int64, prefix string) *Generator {
+	g := &Generator{
+		rng:    rand.New(rand.NewSource(seed)),
+		users:  scenario.Users.IDs,
+		mix:    scenario.Transactions,
+		prefix: prefix,
+	}
+	if scenario.Users.Distribution == DistributionZipf {
+		g.zipf = rand.NewZipf(g.rng, scenario.Users.ZipfS, scenario.Users.ZipfV, uint64(len(g.users)-1))
+	}
+
+	total := 0
+	for _, mix := range g.mix {
+		total += mix.Weight
+		g.cumulative = append(g.cumulative, total)
+	}
+	return g
+}
+
+// Next returns the next request
+func (g *Generator) Next() Request {
+	var userID uint64
+	if g.zipf != nil {
+		// Ranks follow the order of the user list, so its first users are the hottest
+		userID = g.users[g.zipf.Uint64()]
+	} else {
+		userID = g.users[g.rng.Intn(len(g.users))]
+	}
+
+	pick := g.rng.Intn(g.cumulative[len(g.cumulative)-1])
+	mix := &g.mix[0]
+	for i, bound := range g.cumulative {
+		if pick < bound {
+			mix = &g.mix[i]
+			break
+		}
+	}
+
+	amount := mix.fixed
+	if mix.hasRange {
+		amount = mix.low + g.rng.Int63n(mix.high-mix.low+1)
+	}
+
+	g.next++
+	return Request{
+		UserID:        userID,
+		Mix:           mix.Name,
+		SourceType:    mix.SourceType,
+		State:         mix.State,
+		Amount:        entity.MoneyFromCents(amount),
+		TransactionID: fmt.Sprintf("%s-%d", g.prefix, g.next),
+	}
+}
@@ -0,0 +1,65 @@
+package loadtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator(t *testing.T) {
+	scenario := validScenario()
+	scenario.Users = Users{From: 1, To: 50}
+	scenario.Transactions = []TransactionMix{
+		{Name: "win", Weight: 3, State: "win", Amount: "2.50"},
+		{Name: "lose", Weight: 1, SourceType: "payment", State: "lose", AmountRange: [2]string{"1.00", "1.05"}},
+	}
+	require.NoError(t, scenario.Validate())
+
+	t.Run("Weights, amounts and IDs", func(t *testing.T) {
+		generator := NewGenerator(scenario, 1, "run")
+		counts := map[string]int{}
+		ids := map[string]bool{}
+		for range 4000 {
+			request := generator.Next()
+			counts[request.Mix]++
+			ids[request.TransactionID] = true
+			assert.Contains(t, scenario.Users.IDs, request.UserID)
+			if request.Mix == "win" {
+				assert.Equal(t, "2.50", request.Amount.String())
+				assert.Equal(t, "game", request.SourceType)
+			} else {
+				assert.Contains(t, []string{"1.00", "1.01", "1.02", "1.03", "1.04", "1.05"}, request.Amount.String())
+				assert.Equal(t, "payment", request.SourceType)
+			}
+		}
+		assert.InDelta(t, 3000, counts["win"], 150)
+		assert.Len(t, ids, 4000)
+		assert.True(t, ids["run-1"])
+	})
+
+	t.Run("Same seed, same requests", func(t *testing.T) {
+		a, b := NewGenerator(scenario, 9, "a"), NewGenerator(scenario, 9, "b")
+		for range 100 {
+			x, y := a.Next(), b.Next()
+			assert.Equal(t, x.UserID, y.UserID)
+			assert.Equal(t, x.Amount, y.Amount)
+		}
+	})
+
+	t.Run("Zipf concentrates traffic on the first users", func(t *testing.T) {
+		zipf := *scenario
+		zipf.Users = Users{From: 1, To: 1000, Distribution: DistributionZipf, ZipfS: 1.5}
+		require.NoError(t, zipf.Validate())
+
+		generator := NewGenerator(&zipf, 1, "z")
+		hot := 0
+		for range 10000 {
+			if generator.Next().UserID <= 10 {
+				hot++
+			}
+		}
+		// Uniform would give the first 1% of users about 100 requests
+		assert.Greater(t, hot, 5000)
+	})
+}
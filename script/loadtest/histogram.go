@@ -0,0 +1,128 @@
+package loadtest
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Histogram layout: values below subBucketCount microseconds get a bucket each, and every power of two
+// above that is split into subBucketHalf buckets, which keeps three significant digits
+const (
+	subBucketBits  = 11
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+
+	// MaxTrackable is the largest latency a histogram tells apart; longer ones are recorded as this
+	MaxTrackable = time.Hour
+)
+
+// Histogram is a high dynamic range histogram of latencies
+// It records microseconds with a relative error below 0.1% from one microsecond up to MaxTrackable, in
+// fixed memory however many values are recorded. It is not safe for concurrent use.
+type Histogram struct {
+	counts []uint64
+	total  uint64
+	sum    uint64 // Microseconds
+	min    uint64
+	max    uint64
+}
+
+// NewHistogram creates an empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{
+		counts: make([]uint64, bucketIndex(uint64(MaxTrackable/time.Microsecond))+1),
+		min:    math.MaxUint64,
+	}
+}
+
+// Record adds a latency to the histogram
+func (h *Histogram) Record(d time.Duration) {
+	d = max(d, 0)
+	d = min(d, MaxTrackable)
+	v := uint64(d / time.Microsecond)
+
+	h.counts[bucketIndex(v)]++
+	h.total++
+	h.sum += v
+	h.min = min(h.min, v)
+	h.max = max(h.max, v)
+}
+
+// Merge adds all values recorded in other to the histogram
+func (h *Histogram) Merge(other *Histogram) {
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	h.total += other.total
+	h.sum += other.sum
+	h.min = min(h.min, other.min)
+	h.max = max(h.max, other.max)
+}
+
+// Count returns the number of recorded values
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+// Min returns the smallest recorded value, or zero for an empty histogram
+func (h *Histogram) Min() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min) * time.Microsecond
+}
+
+// Max returns the largest recorded value
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max) * time.Microsecond
+}
+
+// Mean returns the average of the recorded values
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/h.total) * time.Microsecond
+}
+
+// ValueAt returns the value below or at which the given percentage of recorded values fall
+// The result is the upper end of the value's bucket, capped at the largest recorded value, so it never
+// understates a latency.
+func (h *Histogram) ValueAt(percentile float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	percentile = min(max(percentile, 0), 100)
+	rank := max(uint64(math.Ceil(percentile/100*float64(h.total))), 1)
+
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			_, highest := bucketRange(i)
+			return time.Duration(min(highest, h.max)) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// bucketIndex returns the bucket a value in microseconds falls in
+func bucketIndex(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits
+	return subBucketCount + (shift-1)*subBucketHalf + int(v>>shift) - subBucketHalf
+}
+
+// bucketRange returns the lowest and highest value of a bucket
+func bucketRange(index int) (uint64, uint64) {
+	if index < subBucketCount {
+		return uint64(index), uint64(index)
+	}
+	offset := index - subBucketCount
+	shift := offset/subBucketHalf + 1
+	lowest := uint64(offset%subBucketHalf+subBucketHalf) << shift
+	return lowest, lowest + 1<<shift - 1
+}
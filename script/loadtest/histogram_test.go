@@ -0,0 +1,73 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_Buckets(t *testing.T) {
+	// Buckets are contiguous and every value lands in the bucket whose range holds it
+	for _, v := range []uint64{0, 1, 2047, 2048, 2049, 4095, 4096, 123456, 999999, uint64(MaxTrackable / time.Microsecond)} {
+		lowest, highest := bucketRange(bucketIndex(v))
+		assert.LessOrEqual(t, lowest, v, "value %d", v)
+		assert.GreaterOrEqual(t, highest, v, "value %d", v)
+		// Three significant digits
+		assert.LessOrEqual(t, float64(highest-lowest), float64(v)/1000+1, "value %d", v)
+	}
+	for i := 1; i < bucketIndex(uint64(MaxTrackable/time.Microsecond)); i++ {
+		_, previous := bucketRange(i - 1)
+		lowest, _ := bucketRange(i)
+		assert.Equal(t, previous+1, lowest, "bucket %d", i)
+	}
+}
+
+func TestHistogram_Percentiles(t *testing.T) {
+	h := NewHistogram()
+	assert.Zero(t, h.ValueAt(99))
+	assert.Zero(t, h.Min())
+
+	// 1ms to 1000ms, one value each
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, uint64(1000), h.Count())
+	assert.Equal(t, time.Millisecond, h.Min())
+	assert.Equal(t, time.Second, h.Max())
+	assert.Equal(t, 500500*time.Microsecond, h.Mean())
+	assert.InDelta(t, 500*time.Millisecond, h.ValueAt(50), float64(500*time.Microsecond))
+	assert.InDelta(t, 990*time.Millisecond, h.ValueAt(99), float64(990*time.Microsecond))
+	assert.Equal(t, time.Second, h.ValueAt(99.9))
+	assert.Equal(t, time.Second, h.ValueAt(100))
+	assert.Equal(t, time.Millisecond, h.ValueAt(0))
+
+	// Percentiles never understate
+	assert.GreaterOrEqual(t, h.ValueAt(50), 500*time.Millisecond)
+}
+
+func TestHistogram_Clamping(t *testing.T) {
+	h := NewHistogram()
+	h.Record(-time.Second)
+	h.Record(2 * MaxTrackable)
+
+	assert.Equal(t, time.Duration(0), h.Min())
+	assert.Equal(t, MaxTrackable, h.Max())
+	assert.Equal(t, MaxTrackable, h.ValueAt(100))
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	a, b := NewHistogram(), NewHistogram()
+	a.Record(10 * time.Millisecond)
+	b.Record(time.Millisecond)
+	b.Record(100 * time.Millisecond)
+
+	a.Merge(b)
+	a.Merge(NewHistogram())
+
+	assert.Equal(t, uint64(3), a.Count())
+	assert.Equal(t, time.Millisecond, a.Min())
+	assert.Equal(t, 100*time.Millisecond, a.Max())
+	assert.InDelta(t, 10*time.Millisecond, a.ValueAt(50), float64(10*time.Microsecond))
+}
@@ -0,0 +1,141 @@
+package loadtest
+
+import (
+	"sync"
+	"time"
+)
+
+// outcome is the result of one request
+type outcome struct {
+	request Request
+	stage   int           // Index of the stage it was due in, or -1 without stages
+	latency time.Duration // From when it was due
+	service time.Duration // From when it was sent
+	status  int           // Zero when no response arrived
+	failure string        // Empty on success
+	queued  bool
+}
+
+// stageStats is what the recorder keeps per stage
+type stageStats struct {
+	latency  *Histogram
+	requests int
+	failed   int
+}
+
+// recorder collects outcomes from concurrent requests
+type recorder struct {
+	mu           sync.Mutex
+	latency      *Histogram
+	service      *Histogram
+	stages       []stageStats
+	requests     int
+	failed       int
+	queued       int
+	statusCodes  map[int]int
+	errors       map[string]int
+	users        map[uint64]int
+	transactions map[string]int
+}
+
+func newRecorder(stages int) *recorder {
+	r := &recorder{
+		latency:      NewHistogram(),
+		service:      NewHistogram(),
+		stages:       make([]stageStats, stages),
+		statusCodes:  make(map[int]int),
+		errors:       make(map[string]int),
+		users:        make(map[uint64]int),
+		transactions: make(map[string]int),
+	}
+	for i := range r.stages {
+		r.stages[i].latency = NewHistogram()
+	}
+	return r
+}
+
+// record adds the outcome of a request
+func (r *recorder) record(o outcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	r.latency.Record(o.latency)
+	r.service.Record(o.service)
+	r.users[o.request.UserID]++
+	r.transactions[o.request.Mix]++
+	if o.status != 0 {
+		r.statusCodes[o.status]++
+	}
+	if o.failure != "" {
+		r.failed++
+		r.errors[o.failure]++
+	}
+	if o.queued {
+		r.queued++
+	}
+	if o.stage >= 0 && o.stage < len(r.stages) {
+		stage := &r.stages[o.stage]
+		stage.requests++
+		stage.latency.Record(o.latency)
+		if o.failure != "" {
+			stage.failed++
+		}
+	}
+}
+
+// counts returns the number of completed and failed requests so far
+func (r *recorder) counts() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests, r.failed
+}
+
+// report builds the report of a finished run
+func (r *recorder) report(scenario *Scenario, seed int64, startedAt time.Time, elapsed time.Duration) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		Scenario:        scenario.Name,
+		Model:           scenario.Model,
+		BaseURL:         scenario.BaseURL,
+		Seed:            seed,
+		StartedAt:       startedAt,
+		DurationSeconds: elapsed.Seconds(),
+		Requests:        r.requests,
+		Succeeded:       r.requests - r.failed,
+		Failed:          r.failed,
+		Throughput:      perSecond(r.requests, elapsed),
+		Latency:         Summarize(r.latency),
+		Queued:          r.queued,
+		StatusCodes:     r.statusCodes,
+		Errors:          r.errors,
+		Users:           r.users,
+		Transactions:    r.transactions,
+	}
+	if scenario.Model == ModelOpen {
+		service := Summarize(r.service)
+		report.ServiceTime = &service
+	}
+	for i, stage := range r.stages {
+		report.Stages = append(report.Stages, StageReport{
+			Stage:           i + 1,
+			Target:          scenario.Stages[i].Target,
+			DurationSeconds: scenario.Stages[i].Duration.Seconds(),
+			Requests:        stage.requests,
+			Failed:          stage.failed,
+			Throughput:      perSecond(stage.requests, scenario.Stages[i].Duration),
+			Latency:         Summarize(stage.latency),
+		})
+	}
+	return report
+}
+
+// perSecond returns a count per second of the given duration
+func perSecond(count int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(count) / d.Seconds()
+}
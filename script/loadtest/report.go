@@ -0,0 +1,163 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Report is what a load test measured, written as JSON so later runs can be compared with it
+type Report struct {
+	Scenario        string    `json:"scenario"`
+	Model           string    `json:"model"`
+	BaseURL         string    `json:"baseUrl"`
+	Seed            int64     `json:"seed"`
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+
+	Requests   int     `json:"requests"`
+	Succeeded  int     `json:"succeeded"`
+	Failed     int     `json:"failed"`
+	Throughput float64 `json:"throughput"` // Completed requests per second
+
+	// Latency runs from when a request was due to start, so in the open model it includes time spent
+	// waiting for an in-flight slot; ServiceTime runs from when it was actually sent
+	Latency     LatencySummary  `json:"latencyMs"`
+	ServiceTime *LatencySummary `json:"serviceTimeMs,omitempty"`
+	Queued      int             `json:"queued,omitempty"` // Open model: requests that waited for an in-flight slot
+
+	StatusCodes  map[int]int    `json:"statusCodes"`
+	Errors       map[string]int `json:"errors,omitempty"`
+	Users        map[uint64]int `json:"users"`
+	Transactions map[string]int `json:"transactions"`
+	Stages       []StageReport  `json:"stages,omitempty"`
+
+	Verification *VerificationReport `json:"verification,omitempty"`
+}
+
+// StageReport is what one stage of a load test measured
+type StageReport struct {
+	Stage           int            `json:"stage"` // From 1
+	Target          float64        `json:"target"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	Requests        int            `json:"requests"`
+	Failed          int            `json:"failed"`
+	Throughput      float64        `json:"throughput"`
+	Latency         LatencySummary `json:"latencyMs"`
+}
+
+// LatencySummary holds the percentiles of a histogram in milliseconds
+type LatencySummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p99.9"`
+	Max  float64 `json:"max"`
+}
+
+// Summarize returns the percentiles of a histogram
+func Summarize(h *Histogram) LatencySummary {
+	return LatencySummary{
+		Min:  milliseconds(h.Min()),
+		Mean: milliseconds(h.Mean()),
+		P50:  milliseconds(h.ValueAt(50)),
+		P90:  milliseconds(h.ValueAt(90)),
+		P99:  milliseconds(h.ValueAt(99)),
+		P999: milliseconds(h.ValueAt(99.9)),
+		Max:  milliseconds(h.Max()),
+	}
+}
+
+// ErrorRate returns the percentage of requests that failed
+func (r *Report) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Requests) * 100
+}
+
+// WriteFile writes the report as indented JSON
+func (r *Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadReport reads a report written by WriteFile
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// ErrorRateTolerance is how many percentage points the error rate may rise over the baseline
+const ErrorRateTolerance = 1.0
+
+// Comparison is one metric of a run next to the same metric of a baseline run
+type Comparison struct {
+	Metric    string
+	Baseline  float64
+	Current   float64
+	Change    float64 // Percent of the baseline; percentage points for the error rate
+	Regressed bool
+}
+
+// Compare compares a run with a baseline run
+// Throughput may drop and latencies may rise by up to tolerance percent, and the error rate may rise
+// by up to ErrorRateTolerance points, before a metric counts as regressed.
+func Compare(baseline, current *Report, tolerance float64) []Comparison {
+	comparisons := []Comparison{
+		relative("throughput", baseline.Throughput, current.Throughput, -1, tolerance),
+		relative("p50 ms", baseline.Latency.P50, current.Latency.P50, 1, tolerance),
+		relative("p90 ms", baseline.Latency.P90, current.Latency.P90, 1, tolerance),
+		relative("p99 ms", baseline.Latency.P99, current.Latency.P99, 1, tolerance),
+		relative("p99.9 ms", baseline.Latency.P999, current.Latency.P999, 1, tolerance),
+	}
+
+	points := current.ErrorRate() - baseline.ErrorRate()
+	return append(comparisons, Comparison{
+		Metric:    "error rate %",
+		Baseline:  baseline.ErrorRate(),
+		Current:   current.ErrorRate(),
+		Change:    points,
+		Regressed: points > ErrorRateTolerance,
+	})
+}
+
+// relative compares a metric by its change in percent; worse is 1 when higher is worse and -1 when
+// lower is worse
+func relative(metric string, baseline, current, worse, tolerance float64) Comparison {
+	comparison := Comparison{Metric: metric, Baseline: baseline, Current: current}
+	if baseline == 0 {
+		return comparison
+	}
+	comparison.Change = (current - baseline) / baseline * 100
+	comparison.Regressed = comparison.Change*worse > tolerance
+	return comparison
+}
+
+// Regressed reports whether any of the comparisons regressed
+func Regressed(comparisons []Comparison) bool {
+	for _, comparison := range comparisons {
+		if comparison.Regressed {
+			return true
+		}
+	}
+	return false
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
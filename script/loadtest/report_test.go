@@ -0,0 +1,74 @@
+package loadtest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_RoundTrip(t *testing.T) {
+	h := NewHistogram()
+	h.Record(2 * time.Millisecond)
+	report := &Report{
+		Scenario:    "smoke",
+		Requests:    4,
+		Failed:      1,
+		Latency:     Summarize(h),
+		StatusCodes: map[int]int{200: 3, 503: 1},
+		Users:       map[uint64]int{7: 4},
+	}
+	assert.Equal(t, 25.0, report.ErrorRate())
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, report.WriteFile(path))
+	read, err := ReadReport(path)
+	require.NoError(t, err)
+	assert.Equal(t, report, read)
+	assert.Equal(t, 2.0, read.Latency.P999)
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{
+		Requests:   100,
+		Failed:     1,
+		Throughput: 100,
+		Latency:    LatencySummary{P50: 10, P90: 20, P99: 40, P999: 80},
+	}
+
+	t.Run("Within tolerance", func(t *testing.T) {
+		current := &Report{
+			Requests:   100,
+			Failed:     2,
+			Throughput: 95,
+			Latency:    LatencySummary{P50: 9, P90: 21, P99: 44, P999: 60},
+		}
+		comparisons := Compare(baseline, current, 10)
+		assert.False(t, Regressed(comparisons))
+		assert.Equal(t, "throughput", comparisons[0].Metric)
+		assert.InDelta(t, -5, comparisons[0].Change, 0.001)
+		assert.InDelta(t, 1, comparisons[5].Change, 0.001)
+	})
+
+	t.Run("Regressions", func(t *testing.T) {
+		current := &Report{
+			Requests:   100,
+			Failed:     5,
+			Throughput: 80,
+			Latency:    LatencySummary{P50: 10, P90: 20, P99: 60, P999: 80},
+		}
+		regressed := map[string]bool{}
+		for _, c := range Compare(baseline, current, 10) {
+			regressed[c.Metric] = c.Regressed
+		}
+		assert.Equal(t, map[string]bool{
+			"throughput": true, "p50 ms": false, "p90 ms": false, "p99 ms": true, "p99.9 ms": false, "error rate %": true,
+		}, regressed)
+	})
+
+	t.Run("Empty baseline metrics are not compared", func(t *testing.T) {
+		assert.False(t, Regressed(Compare(&Report{}, baseline, 10)))
+	})
+}
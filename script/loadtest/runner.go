@@ -0,0 +1,405 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+)
+
+// arrivalStep is the resolution of the open model's schedule
+const arrivalStep = time.Millisecond
+
+// Progress is a snapshot of a running load test
+type Progress struct {
+	Elapsed   time.Duration
+	Stage     int // From 1; zero without stages
+	Target    float64
+	Completed int
+	Failed    int
+}
+
+// Runner runs a scenario against the API
+type Runner struct {
+	scenario *Scenario
+	client   *http.Client
+	progress func(Progress)
+	recorder *recorder
+	verifier *verifier
+	start    time.Time
+}
+
+// NewRunner creates a runner for a validated scenario
+func NewRunner(scenario *Scenario) *Runner {
+	connections := max(scenario.Workers, int(math.Ceil(scenario.maxTarget())))
+	if scenario.Model == ModelOpen {
+		connections = scenario.MaxInFlight
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = connections
+	transport.MaxIdleConnsPerHost = connections
+
+	return &Runner{
+		scenario: scenario,
+		client:   &http.Client{Timeout: scenario.Timeout, Transport: transport},
+	}
+}
+
+// WithClient sets the HTTP client requests are sent with
+func (r *Runner) WithClient(client *http.Client) *Runner {
+	r.client = client
+	return r
+}
+
+// WithProgress sets a function called every second while the test runs
+func (r *Runner) WithProgress(progress func(Progress)) *Runner {
+	r.progress = progress
+	return r
+}
+
+// Run runs the scenario until its stages end, its request count is reached or ctx is cancelled
+// Requests cut off by cancellation are left out of the report. With verification the users' balances
+// are read before the test and checked after it, even when it was cancelled.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	seed := r.scenario.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r.recorder = newRecorder(len(r.scenario.Stages))
+	if r.scenario.Verify != nil {
+		r.verifier = newVerifier(*r.scenario.Verify, seed)
+		if err := r.verifier.begin(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+	r.start = time.Now()
+	prefix := fmt.Sprintf("lt-%d", r.start.UnixNano())
+
+	done := make(chan struct{})
+	var reporting sync.WaitGroup
+	if r.progress != nil {
+		reporting.Add(1)
+		go func() {
+			defer reporting.Done()
+			r.report(done)
+		}()
+	}
+
+	if r.scenario.Model == ModelOpen {
+		r.runOpen(ctx, seed, prefix)
+	} else {
+		r.runClosed(ctx, seed, prefix)
+	}
+	elapsed := time.Since(r.start)
+	close(done)
+	reporting.Wait()
+
+	report := r.recorder.report(r.scenario, seed, r.start, elapsed)
+	if r.verifier != nil {
+		report.Verification = r.verifier.finish(context.WithoutCancel(ctx), r)
+	}
+	return report, nil
+}
+
+// runClosed runs workers that each send their next request once the previous one is answered
+// With stages a controller raises and lowers the number of active workers to follow the targets.
+func (r *Runner) runClosed(ctx context.Context, seed int64, prefix string) {
+	workers := r.scenario.Workers
+	if len(r.scenario.Stages) > 0 {
+		workers = int(math.Ceil(r.scenario.maxTarget()))
+	}
+
+	var active, issued atomic.Int64
+	active.Store(int64(r.scenario.Workers))
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		generator := NewGenerator(r.scenario, seed+int64(i), fmt.Sprintf("%s-w%d", prefix, i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-stop:
+					return
+				default:
+				}
+				if int64(i) >= active.Load() {
+					sleep(ctx, stop, 10*time.Millisecond)
+					continue
+				}
+				if r.scenario.Requests > 0 && issued.Add(1) > int64(r.scenario.Requests) {
+					return
+				}
+
+				sent := time.Now()
+				r.send(ctx, generator.Next(), r.stageAt(sent), sent, sent, false)
+				if r.scenario.ThinkTime > 0 {
+					sleep(ctx, stop, r.scenario.ThinkTime)
+				}
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	if len(r.scenario.Stages) == 0 {
+		<-finished
+		return
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-finished:
+			return
+		case <-ticker.C:
+			_, target, ok := r.scenario.At(time.Since(r.start))
+			if !ok {
+				close(stop)
+				<-finished
+				return
+			}
+			active.Store(int64(math.Round(target)))
+		}
+	}
+}
+
+// runOpen starts requests on a schedule that follows the stage rates, whether or not earlier requests
+// have been answered
+// A request that cannot get an in-flight slot waits for one, and its latency still counts from when it
+// was due, so a slow server shows up in the percentiles instead of silently lowering the load.
+func (r *Runner) runOpen(ctx context.Context, seed int64, prefix string) {
+	generator := NewGenerator(r.scenario, seed, prefix)
+	schedule := &arrivals{scenario: r.scenario}
+	slots := make(chan struct{}, r.scenario.MaxInFlight)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for issued := 0; r.scenario.Requests == 0 || issued < r.scenario.Requests; issued++ {
+		offset, stage, ok := schedule.next()
+		if !ok {
+			return
+		}
+		due := r.start.Add(offset)
+		if !sleep(ctx, nil, time.Until(due)) {
+			return
+		}
+
+		queued := false
+		select {
+		case slots <- struct{}{}:
+		default:
+			queued = true
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		request := generator.Next()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			r.send(ctx, request, stage, due, time.Now(), queued)
+		}()
+	}
+}
+
+// response is what the API answered to one transaction request
+type response struct {
+	status  int // Zero when no response arrived
+	body    dto.TransactionResponse
+	failure string // Empty on success
+}
+
+// send posts a transaction and records the outcome
+// With verification the request may be swapped for a duplicate of an answered one, or sent twice at once.
+func (r *Runner) send(ctx context.Context, request Request, stage int, due, sent time.Time, queued bool) {
+	kind := sendOriginal
+	if r.verifier != nil {
+		request, kind = r.verifier.choose(request)
+	}
+
+	responses := make([]response, 1, 2)
+	cancelled := make([]bool, 1, 2)
+	if kind == sendReplay {
+		responses, cancelled = responses[:2], cancelled[:2]
+		var wg sync.WaitGroup
+		for i := range responses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				responses[i], cancelled[i] = r.post(ctx, request)
+			}()
+		}
+		wg.Wait()
+	} else {
+		responses[0], cancelled[0] = r.post(ctx, request)
+	}
+	finished := time.Now()
+
+	for i, resp := range responses {
+		if cancelled[i] {
+			continue
+		}
+		r.recorder.record(outcome{
+			request: request,
+			stage:   stage,
+			latency: finished.Sub(due),
+			service: finished.Sub(sent),
+			status:  resp.status,
+			failure: resp.failure,
+			queued:  queued,
+		})
+	}
+	if r.verifier != nil {
+		r.verifier.observe(request, kind, responses)
+	}
+}
+
+// post sends a transaction to the API
+// cancelled is true when ctx ended before an answer arrived.
+func (r *Runner) post(ctx context.Context, request Request) (resp response, cancelled bool) {
+	body, err := json.Marshal(dto.TransactionRequest{
+		State:         request.State,
+		Amount:        &request.Amount,
+		TransactionID: request.TransactionID,
+	})
+	if err != nil {
+		return response{failure: err.Error()}, false
+	}
+	endpoint := fmt.Sprintf("%s/user/%d/transaction", r.scenario.BaseURL, request.UserID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return response{failure: err.Error()}, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Source-Type", request.SourceType)
+
+	httpResp, err := r.client.Do(req)
+	if err != nil {
+		return response{failure: transportError(err)}, ctx.Err() != nil
+	}
+	defer httpResp.Body.Close()
+
+	resp.status = httpResp.StatusCode
+	data, _ := io.ReadAll(httpResp.Body)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		resp.failure = apiError(httpResp.StatusCode, data)
+		return resp, false
+	}
+	_ = json.Unmarshal(data, &resp.body)
+	return resp, false
+}
+
+// stageAt returns the index of the stage running at the given time, or -1 without stages
+func (r *Runner) stageAt(t time.Time) int {
+	if len(r.scenario.Stages) == 0 {
+		return -1
+	}
+	stage, _, _ := r.scenario.At(t.Sub(r.start))
+	return min(stage, len(r.scenario.Stages)-1)
+}
+
+// report calls the progress function every second until done is closed
+func (r *Runner) report(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			elapsed := time.Since(r.start)
+			progress := Progress{Elapsed: elapsed}
+			progress.Completed, progress.Failed = r.recorder.counts()
+			if stage, target, ok := r.scenario.At(elapsed); ok {
+				progress.Stage, progress.Target = stage+1, target
+			}
+			r.progress(progress)
+		}
+	}
+}
+
+// arrivals is the open model's schedule: it accumulates the stage rate in steps of arrivalStep and
+// emits a request each time a whole one is due
+type arrivals struct {
+	scenario *Scenario
+	offset   time.Duration
+	due      float64
+	stage    int
+}
+
+// next returns the offset from the start at which the next request is due and its stage
+// ok is false once the stages have ended.
+func (a *arrivals) next() (time.Duration, int, bool) {
+	for a.due < 1 {
+		stage, rate, ok := a.scenario.At(a.offset)
+		if !ok {
+			return 0, 0, false
+		}
+		a.due += rate * arrivalStep.Seconds()
+		a.offset += arrivalStep
+		a.stage = stage
+	}
+	a.due--
+	return a.offset, a.stage, true
+}
+
+// transportError describes a request that got no response, without the URL so failures group together
+func transportError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return "timeout"
+		}
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// apiError describes an unsuccessful response by its status and the API's error message
+func apiError(status int, body []byte) string {
+	var response dto.ErrorResponse
+	if json.Unmarshal(body, &response) == nil && response.Message != "" {
+		return fmt.Sprintf("HTTP %d: %s", status, response.Message)
+	}
+	return fmt.Sprintf("HTTP %d", status)
+}
+
+// sleep waits for d, returning false if ctx is cancelled or stop is closed first
+func sleep(ctx context.Context, stop <-chan struct{}, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-stop:
+		return false
+	}
+}
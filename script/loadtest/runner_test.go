@@ -0,0 +1,194 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI answers transactions, rejecting losses with 400, after an optional delay
+type fakeAPI struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	ids     map[string]bool
+	sources map[string]int
+}
+
+func newFakeAPI(t *testing.T, delay time.Duration) (*fakeAPI, *httptest.Server) {
+	api := &fakeAPI{delay: delay, ids: map[string]bool{}, sources: map[string]int{}}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request dto.TransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	a.ids[request.TransactionID] = true
+	a.sources[r.Header.Get("Source-Type")]++
+	a.mu.Unlock()
+
+	time.Sleep(a.delay)
+	w.Header().Set("Content-Type", "application/json")
+	if request.State == "lose" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(dto.ErrorResponse{Code: 4001, Message: "Insufficient balance"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(dto.TransactionResponse{TransactionID: request.TransactionID, Success: true})
+}
+
+func (a *fakeAPI) received() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.ids)
+}
+
+func TestRunner_Closed(t *testing.T) {
+	api, server := newFakeAPI(t, 0)
+	scenario := &Scenario{
+		BaseURL:  server.URL,
+		Seed:     3,
+		Workers:  4,
+		Requests: 200,
+		Users:    Users{IDs: []uint64{1, 2}},
+		Transactions: []TransactionMix{
+			{Name: "win", Weight: 3, SourceType: "server", State: "win", Amount: "1.00"},
+			{Name: "lose", State: "lose", Amount: "1.00"},
+		},
+	}
+	require.NoError(t, scenario.Validate())
+
+	report, err := NewRunner(scenario).Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, report.Requests)
+	assert.Equal(t, 200, api.received(), "every request gets a distinct transaction ID")
+	assert.Equal(t, report.Transactions["lose"], report.Failed)
+	assert.Equal(t, report.Transactions["win"], report.StatusCodes[http.StatusOK])
+	assert.Equal(t, report.Failed, report.StatusCodes[http.StatusBadRequest])
+	assert.Equal(t, map[string]int{"HTTP 400: Insufficient balance": report.Failed}, report.Errors)
+	assert.Equal(t, report.Transactions["win"], api.sources["server"])
+	assert.Equal(t, 200, report.Users[1]+report.Users[2])
+	assert.Equal(t, int64(3), report.Seed)
+	assert.Nil(t, report.ServiceTime)
+	assert.Empty(t, report.Stages)
+}
+
+func TestRunner_ClosedStages(t *testing.T) {
+	_, server := newFakeAPI(t, 5*time.Millisecond)
+	scenario := &Scenario{
+		BaseURL:      server.URL,
+		Stages:       []Stage{{200 * time.Millisecond, 4}, {200 * time.Millisecond, 4}},
+		Users:        Users{IDs: []uint64{1}},
+		Transactions: []TransactionMix{{State: "win", Amount: "1.00"}},
+	}
+	require.NoError(t, scenario.Validate())
+
+	started := time.Now()
+	report, err := NewRunner(scenario).Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(started), time.Second, "the run ends with the stages")
+	require.Len(t, report.Stages, 2)
+	assert.Equal(t, report.Requests, report.Stages[0].Requests+report.Stages[1].Requests)
+	// Ramping up gives the first stage about half the workers of the second on average
+	assert.Less(t, report.Stages[0].Requests, report.Stages[1].Requests)
+	assert.Zero(t, report.Failed)
+}
+
+func TestRunner_Open(t *testing.T) {
+	t.Run("Requests follow the arrival rate", func(t *testing.T) {
+		api, server := newFakeAPI(t, 0)
+		scenario := &Scenario{
+			BaseURL:      server.URL,
+			Model:        ModelOpen,
+			Stages:       []Stage{{500 * time.Millisecond, 200}, {500 * time.Millisecond, 200}},
+			Users:        Users{IDs: []uint64{1}},
+			Transactions: []TransactionMix{{State: "win", Amount: "1.00"}},
+		}
+		require.NoError(t, scenario.Validate())
+
+		report, err := NewRunner(scenario).Run(context.Background())
+		require.NoError(t, err)
+
+		// Half of 200/s over the ramp and 200/s over the steady stage
+		assert.InDelta(t, 50, report.Stages[0].Requests, 2)
+		assert.InDelta(t, 100, report.Stages[1].Requests, 2)
+		assert.Equal(t, report.Requests, api.received())
+		require.NotNil(t, report.ServiceTime)
+	})
+
+	t.Run("Latency counts from when a request was due", func(t *testing.T) {
+		_, server := newFakeAPI(t, 20*time.Millisecond)
+		scenario := &Scenario{
+			BaseURL:      server.URL,
+			Model:        ModelOpen,
+			Stages:       []Stage{{200 * time.Millisecond, 0}, {200 * time.Millisecond, 0}},
+			Requests:     10,
+			MaxInFlight:  1,
+			Users:        Users{IDs: []uint64{1}},
+			Transactions: []TransactionMix{{State: "win", Amount: "1.00"}},
+		}
+		// Ten requests due at once from a rate of 10000/s for a millisecond
+		scenario.Stages[0].Target = 10000
+		require.NoError(t, scenario.Validate())
+
+		report, err := NewRunner(scenario).Run(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, 10, report.Requests)
+		assert.Greater(t, report.Queued, 0)
+		// The last request waited for the nine before it
+		assert.Greater(t, report.Latency.Max, 150.0)
+		assert.Less(t, report.ServiceTime.Max, report.Latency.Max)
+	})
+}
+
+func TestRunner_Cancelled(t *testing.T) {
+	_, server := newFakeAPI(t, 0)
+	scenario := &Scenario{
+		BaseURL:      server.URL,
+		Model:        ModelOpen,
+		StartTarget:  100,
+		Stages:       []Stage{{time.Minute, 100}},
+		Users:        Users{IDs: []uint64{1}},
+		Transactions: []TransactionMix{{State: "win", Amount: "1.00"}},
+	}
+	require.NoError(t, scenario.Validate())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	report, err := NewRunner(scenario).Run(ctx)
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(started), 2*time.Second)
+	assert.Zero(t, report.Failed)
+	assert.Greater(t, report.Requests, 0)
+}
+
+func TestRunner_Unreachable(t *testing.T) {
+	scenario := validScenario()
+	scenario.BaseURL = "http://127.0.0.1:1"
+	require.NoError(t, scenario.Validate())
+
+	report, err := NewRunner(scenario).Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, report.Failed)
+	assert.Empty(t, report.StatusCodes)
+	require.Len(t, report.Errors, 1, "failures group together whatever the user")
+}
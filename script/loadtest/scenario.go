@@ -0,0 +1,276 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"gopkg.in/yaml.v3"
+)
+
+// Load models
+const (
+	ModelClosed = "closed" // A number of workers each send a request once the previous one is answered
+	ModelOpen   = "open"   // Requests start at a set rate however long earlier ones take
+)
+
+// User distributions
+const (
+	DistributionUniform = "uniform" // Every user is as likely to be picked
+	DistributionZipf    = "zipf"    // A few hot users get most of the traffic
+)
+
+// maxUsers bounds a user range, which is expanded into a list
+const maxUsers = 1_000_000
+
+// Scenario describes a load test
+type Scenario struct {
+	Name    string `yaml:"name"`
+	BaseURL string `yaml:"baseUrl"`
+	Seed    int64  `yaml:"seed"` // Seeds user and transaction choices; zero picks a random seed
+
+	// Model is closed or open. Stage targets are workers in the closed model and requests per second in
+	// the open model.
+	Model       string  `yaml:"model"`
+	StartTarget float64 `yaml:"startTarget"` // Where the first stage ramps from; a constant load starts at its target
+	Stages      []Stage `yaml:"stages"`
+
+	Workers     int           `yaml:"workers"`     // Closed model without stages: a fixed number of workers
+	Requests    int           `yaml:"requests"`    // Stop after this many requests; zero runs until the stages end
+	ThinkTime   time.Duration `yaml:"thinkTime"`   // Closed model: pause of each worker between requests
+	MaxInFlight int           `yaml:"maxInFlight"` // Open model: most requests outstanding at once
+	Timeout     time.Duration `yaml:"timeout"`     // Per request
+
+	Users        Users            `yaml:"users"`
+	Transactions []TransactionMix `yaml:"transactions"`
+	Verify       *Verification    `yaml:"verify"` // Check the balances the test leaves behind
+}
+
+// Stage moves the load linearly from the previous stage's target, or the start target, to Target over Duration
+// A ramp-up, steady and ramp-down profile is three stages.
+type Stage struct {
+	Duration time.Duration `yaml:"duration"`
+	Target   float64       `yaml:"target"`
+}
+
+// Users selects the users requests are sent for
+type Users struct {
+	IDs          []uint64 `yaml:"ids"`
+	From         uint64   `yaml:"from"` // A range of users, used when IDs is empty
+	To           uint64   `yaml:"to"`
+	Distribution string   `yaml:"distribution"`
+	ZipfS        float64  `yaml:"zipfS"` // Zipf exponent, above 1; higher concentrates traffic on fewer users
+	ZipfV        float64  `yaml:"zipfV"` // Zipf offset, at least 1
+}
+
+// TransactionMix is one kind of transaction, sent in proportion to its weight
+type TransactionMix struct {
+	Name        string    `yaml:"name"`
+	Weight      int       `yaml:"weight"`
+	SourceType  string    `yaml:"sourceType"`
+	State       string    `yaml:"state"`
+	Amount      string    `yaml:"amount"`      // A fixed amount, or
+	AmountRange [2]string `yaml:"amountRange"` // amounts picked uniformly between these two
+
+	fixed    int64 // Parsed amounts in cents
+	low      int64
+	high     int64
+	hasRange bool
+}
+
+// LoadScenario reads a scenario from a YAML file and validates it
+func LoadScenario(path string) (*Scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var scenario Scenario
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+// Validate fills in defaults and checks the scenario
+func (s *Scenario) Validate() error {
+	if s.Name == "" {
+		s.Name = "load-test"
+	}
+	if s.Model == "" {
+		s.Model = ModelClosed
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = 10 * time.Second
+	}
+	if s.MaxInFlight <= 0 {
+		s.MaxInFlight = 256
+	}
+
+	switch s.Model {
+	case ModelClosed:
+		if len(s.Stages) == 0 && (s.Workers <= 0 || s.Requests <= 0) {
+			return errors.New("a closed model without stages needs workers and requests")
+		}
+	case ModelOpen:
+		if len(s.Stages) == 0 {
+			return errors.New("an open model needs stages with request rates")
+		}
+	default:
+		return fmt.Errorf("unknown model %q, want %s or %s", s.Model, ModelClosed, ModelOpen)
+	}
+	for i, stage := range s.Stages {
+		if stage.Duration <= 0 || stage.Target < 0 {
+			return fmt.Errorf("stage %d needs a positive duration and a target of at least zero", i+1)
+		}
+	}
+	if s.Requests < 0 || s.ThinkTime < 0 || s.StartTarget < 0 {
+		return errors.New("requests, thinkTime and startTarget cannot be negative")
+	}
+
+	if err := s.Users.validate(); err != nil {
+		return err
+	}
+
+	if len(s.Transactions) == 0 {
+		return errors.New("at least one transaction is needed")
+	}
+	for i := range s.Transactions {
+		if err := s.Transactions[i].validate(i); err != nil {
+			return err
+		}
+	}
+
+	if s.Verify != nil {
+		return s.Verify.validate()
+	}
+	return nil
+}
+
+// Duration returns how long the stages run
+func (s *Scenario) Duration() time.Duration {
+	var total time.Duration
+	for _, stage := range s.Stages {
+		total += stage.Duration
+	}
+	return total
+}
+
+// At returns the stage running at the given offset from the start and its target at that moment
+// ok is false once the stages have ended.
+func (s *Scenario) At(offset time.Duration) (stage int, target float64, ok bool) {
+	var start time.Duration
+	from := s.StartTarget
+	for i, st := range s.Stages {
+		if offset < start+st.Duration {
+			progress := float64(offset-start) / float64(st.Duration)
+			return i, from + (st.Target-from)*progress, true
+		}
+		start += st.Duration
+		from = st.Target
+	}
+	return len(s.Stages), 0, false
+}
+
+// maxTarget returns the highest target of any stage
+func (s *Scenario) maxTarget() float64 {
+	highest := s.StartTarget
+	for _, stage := range s.Stages {
+		highest = max(highest, stage.Target)
+	}
+	return highest
+}
+
+// validate fills in the user defaults and checks the selection
+func (u *Users) validate() error {
+	if u.Distribution == "" {
+		u.Distribution = DistributionUniform
+	}
+	if u.ZipfS == 0 {
+		u.ZipfS = 1.1
+	}
+	if u.ZipfV == 0 {
+		u.ZipfV = 1
+	}
+
+	if len(u.IDs) == 0 {
+		if u.From == 0 || u.To < u.From {
+			return errors.New("users need ids or a range from 1 or higher")
+		}
+		if u.To-u.From >= maxUsers {
+			return fmt.Errorf("a user range holds at most %d users", maxUsers)
+		}
+		for id := u.From; id <= u.To; id++ {
+			u.IDs = append(u.IDs, id)
+		}
+	}
+	for _, id := range u.IDs {
+		if id == 0 {
+			return errors.New("user IDs start at 1")
+		}
+	}
+
+	switch u.Distribution {
+	case DistributionUniform:
+	case DistributionZipf:
+		if u.ZipfS <= 1 || u.ZipfV < 1 {
+			return errors.New("zipf needs zipfS above 1 and zipfV of at least 1")
+		}
+	default:
+		return fmt.Errorf("unknown user distribution %q, want %s or %s", u.Distribution, DistributionUniform, DistributionZipf)
+	}
+	return nil
+}
+
+// validate fills in the transaction defaults and parses its amounts
+func (t *TransactionMix) validate(index int) error {
+	if t.Name == "" {
+		t.Name = fmt.Sprintf("%s-%d", t.State, index+1)
+	}
+	if t.Weight == 0 {
+		t.Weight = 1
+	}
+	if t.SourceType == "" {
+		t.SourceType = entity.SourceGame.String()
+	}
+
+	if t.Weight < 0 {
+		return fmt.Errorf("transaction %s: weight cannot be negative", t.Name)
+	}
+	if !entity.IsValidSourceType(t.SourceType) {
+		return fmt.Errorf("transaction %s: invalid source type %q", t.Name, t.SourceType)
+	}
+	if !entity.IsValidState(t.State) {
+		return fmt.Errorf("transaction %s: invalid state %q", t.Name, t.State)
+	}
+
+	t.hasRange = t.AmountRange != [2]string{}
+	if (t.Amount == "") == !t.hasRange {
+		return fmt.Errorf("transaction %s: set either amount or amountRange", t.Name)
+	}
+	var err error
+	if !t.hasRange {
+		if t.fixed, err = entity.ValidateAndConvertAmount(t.Amount); err != nil {
+			return fmt.Errorf("transaction %s: %w", t.Name, err)
+		}
+		return nil
+	}
+	if t.low, err = entity.ValidateAndConvertAmount(t.AmountRange[0]); err != nil {
+		return fmt.Errorf("transaction %s: %w", t.Name, err)
+	}
+	if t.high, err = entity.ValidateAndConvertAmount(t.AmountRange[1]); err != nil {
+		return fmt.Errorf("transaction %s: %w", t.Name, err)
+	}
+	if t.high < t.low {
+		return fmt.Errorf("transaction %s: amountRange runs from the lower amount to the higher one", t.Name)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package loadtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validScenario is the smallest scenario Validate accepts
+func validScenario() *Scenario {
+	return &Scenario{
+		Workers:      2,
+		Requests:     10,
+		Users:        Users{IDs: []uint64{1}},
+		Transactions: []TransactionMix{{State: "win", Amount: "1.00"}},
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario(filepath.Join("testdata", "ramp.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, ModelOpen, scenario.Model)
+	assert.Equal(t, []Stage{{10 * time.Second, 50}, {time.Minute, 50}, {10 * time.Second, 0}}, scenario.Stages)
+	assert.Equal(t, 80*time.Second, scenario.Duration())
+	assert.Equal(t, 32, scenario.MaxInFlight)
+	assert.Equal(t, 10*time.Second, scenario.Timeout)
+	assert.Len(t, scenario.Users.IDs, 100)
+	assert.Equal(t, 1.2, scenario.Users.ZipfS)
+	assert.Equal(t, 1.0, scenario.Users.ZipfV)
+
+	assert.Equal(t, "game", scenario.Transactions[0].SourceType)
+	assert.Equal(t, int64(500), scenario.Transactions[0].fixed)
+	assert.Equal(t, int64(100), scenario.Transactions[1].low)
+	assert.Equal(t, int64(2000), scenario.Transactions[1].high)
+
+	t.Run("Unknown fields are rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "typo.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("workers: 1\nrequest: 5\n"), 0o644))
+		_, err := LoadScenario(path)
+		assert.ErrorContains(t, err, "field request not found")
+	})
+}
+
+func TestScenario_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Scenario)
+		err    string
+	}{
+		{"Valid", func(*Scenario) {}, ""},
+		{"Unknown model", func(s *Scenario) { s.Model = "mixed" }, "unknown model"},
+		{"Closed without requests", func(s *Scenario) { s.Requests = 0 }, "needs workers and requests"},
+		{"Open without stages", func(s *Scenario) { s.Model = ModelOpen }, "needs stages"},
+		{"Empty stage", func(s *Scenario) { s.Stages = []Stage{{Target: 5}} }, "stage 1"},
+		{"No users", func(s *Scenario) { s.Users = Users{} }, "users need"},
+		{"Backwards user range", func(s *Scenario) { s.Users = Users{From: 5, To: 2} }, "users need"},
+		{"User zero", func(s *Scenario) { s.Users.IDs = []uint64{0} }, "start at 1"},
+		{"Flat zipf", func(s *Scenario) { s.Users.Distribution, s.Users.ZipfS = DistributionZipf, 1 }, "zipfS above 1"},
+		{"Unknown distribution", func(s *Scenario) { s.Users.Distribution = "normal" }, "unknown user distribution"},
+		{"No transactions", func(s *Scenario) { s.Transactions = nil }, "at least one"},
+		{"Bad state", func(s *Scenario) { s.Transactions[0].State = "draw" }, "invalid state"},
+		{"Bad source", func(s *Scenario) { s.Transactions[0].SourceType = "casino" }, "invalid source type"},
+		{"Amount and range", func(s *Scenario) { s.Transactions[0].AmountRange = [2]string{"1", "2"} }, "either amount or amountRange"},
+		{"Bad amount", func(s *Scenario) { s.Transactions[0].Amount = "1.001" }, "transaction win-1"},
+		{"Backwards range", func(s *Scenario) {
+			s.Transactions[0].Amount, s.Transactions[0].AmountRange = "", [2]string{"5", "1"}
+		}, "lower amount to the higher"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := validScenario()
+			tt.modify(scenario)
+			err := scenario.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestScenario_At(t *testing.T) {
+	scenario := &Scenario{Stages: []Stage{{10 * time.Second, 100}, {10 * time.Second, 100}, {10 * time.Second, 0}}}
+
+	tests := []struct {
+		offset time.Duration
+		stage  int
+		target float64
+		ok     bool
+	}{
+		{0, 0, 0, true},
+		{5 * time.Second, 0, 50, true},
+		{10 * time.Second, 1, 100, true},
+		{25 * time.Second, 2, 50, true},
+		{30 * time.Second, 3, 0, false},
+	}
+	for _, tt := range tests {
+		stage, target, ok := scenario.At(tt.offset)
+		assert.Equal(t, tt.stage, stage, "offset %v", tt.offset)
+		assert.InDelta(t, tt.target, target, 0.001, "offset %v", tt.offset)
+		assert.Equal(t, tt.ok, ok, "offset %v", tt.offset)
+	}
+
+	// A start target makes the first stage ramp from there
+	scenario.StartTarget = 60
+	_, target, _ := scenario.At(5 * time.Second)
+	assert.InDelta(t, 80, target, 0.001)
+	assert.Equal(t, 100.0, scenario.maxTarget())
+}
@@ -0,0 +1,369 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+)
+
+// Verification makes a load test check that the balances it leaves behind add up
+// The users of a verified test must not be touched by anything else while it runs.
+type Verification struct {
+	DuplicateRate float64 `yaml:"duplicateRate"` // Share of requests that resend an already answered transaction ID
+	ReplayRate    float64 `yaml:"replayRate"`    // Share of transactions sent twice at the same moment
+}
+
+// DefaultVerification is used when verification is asked for without settings
+var DefaultVerification = Verification{DuplicateRate: 0.05, ReplayRate: 0.05}
+
+// validate checks the verification rates
+func (v *Verification) validate() error {
+	if v.DuplicateRate < 0 || v.ReplayRate < 0 || v.DuplicateRate+v.ReplayRate >= 1 {
+		return errors.New("verify rates cannot be negative and must add up to less than 1")
+	}
+	return nil
+}
+
+// VerificationReport is what verification found
+type VerificationReport struct {
+	Passed       bool               `json:"passed"`
+	Transactions int                `json:"transactions"` // Distinct transaction IDs sent
+	Applied      int                `json:"applied"`      // Of which changed a balance
+	Duplicates   int                `json:"duplicates"`   // Requests that resent an answered transaction ID
+	Replays      int                `json:"replays"`      // Transactions sent twice at once
+	Resolved     int                `json:"resolved"`     // Transactions resent after the run because their outcome was unknown
+	Users        []UserVerification `json:"users"`
+	Violations   []string           `json:"violations,omitempty"`
+}
+
+// UserVerification compares a user's balance with the one the test's own responses imply
+type UserVerification struct {
+	UserID   uint64 `json:"userId"`
+	Initial  string `json:"initialBalance"`
+	Expected string `json:"expectedBalance"`
+	Actual   string `json:"actualBalance"`
+	Applied  int    `json:"applied"`
+}
+
+// How a request relates to earlier ones
+const (
+	sendOriginal  = iota // A new transaction ID
+	sendDuplicate        // An ID that was already answered
+	sendReplay           // A new ID sent twice at once
+	sendResolve          // A resend after the run of an ID whose outcome is unknown
+)
+
+// What a response says about a transaction
+const (
+	outcomeUnknown    = iota // No answer, or one that leaves open whether it was applied
+	outcomeApplied           // Completed with a result balance
+	outcomeNotApplied        // Rejected, or held without touching the balance
+)
+
+// resolveAttempts is how often a transaction with an unknown outcome is resent after the run
+const resolveAttempts = 3
+
+// trackedTxn is what the verifier knows about one transaction ID
+type trackedTxn struct {
+	request   Request
+	change    int64 // Signed balance change in cents if applied
+	outcome   int
+	result    *entity.Money // Result balance once applied
+	answered  bool          // Got at least one response
+	unsettled bool          // A send got no answer or an inconclusive one, so a refusal does not prove it was not applied
+}
+
+// verifier tracks the outcome of every transaction a test sends
+type verifier struct {
+	config   Verification
+	mu       sync.Mutex
+	rng      *rand.Rand
+	initial  map[uint64]int64
+	txns     map[string]*trackedTxn
+	answered []string // IDs with at least one answer, for duplicates
+	report   VerificationReport
+}
+
+func newVerifier(config Verification, seed int64) *verifier {
+	return &verifier{
+		config:  config,
+		rng:     rand.New(rand.NewSource(seed)),
+		initial: make(map[uint64]int64),
+		txns:    make(map[string]*trackedTxn),
+	}
+}
+
+// begin records the balances of the users before the test
+func (v *verifier) begin(ctx context.Context, r *Runner) error {
+	for _, userID := range r.scenario.Users.IDs {
+		balance, err := r.balance(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to read the starting balance of user %d: %w", userID, err)
+		}
+		v.initial[userID] = balance
+	}
+	return nil
+}
+
+// choose decides whether a generated request is sent as is, twice at once, or replaced by a duplicate
+// of an answered one
+func (v *verifier) choose(request Request) (Request, int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	roll := v.rng.Float64()
+	kind := sendOriginal
+	switch {
+	case roll < v.config.DuplicateRate && len(v.answered) > 0:
+		v.report.Duplicates++
+		return v.txns[v.answered[v.rng.Intn(len(v.answered))]].request, sendDuplicate
+	case roll >= v.config.DuplicateRate && roll < v.config.DuplicateRate+v.config.ReplayRate:
+		v.report.Replays++
+		kind = sendReplay
+	}
+
+	cents := request.Amount.Cents()
+	if request.State == entity.StateLose.String() {
+		cents = -cents
+	}
+	v.txns[request.TransactionID] = &trackedTxn{request: request, change: cents}
+	v.report.Transactions++
+	return request, kind
+}
+
+// observe takes the answers to one send of a transaction
+func (v *verifier) observe(request Request, kind int, responses []response) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	txn := v.txns[request.TransactionID]
+	before := txn.outcome
+	for _, resp := range responses {
+		outcome := classify(resp)
+		switch {
+		case outcome == outcomeApplied && txn.outcome == outcomeApplied && !sameBalance(resp.body.ResultBalance, txn.result):
+			v.violate("transaction %s of user %d was applied twice, leaving %s and then %s",
+				txn.request.TransactionID, txn.request.UserID, txn.result, resp.body.ResultBalance)
+		case outcome == outcomeApplied:
+			txn.outcome, txn.result = outcomeApplied, resp.body.ResultBalance
+		case outcome == outcomeNotApplied && kind == sendDuplicate && before == outcomeApplied:
+			v.violate("duplicate of applied transaction %s of user %d was answered %s",
+				txn.request.TransactionID, txn.request.UserID, describe(resp))
+		case outcome == outcomeNotApplied && txn.outcome != outcomeApplied &&
+			(resp.status < 300 || !txn.unsettled || kind == sendResolve):
+			// Answers are observed in no particular order, so a send with a lost answer may have been
+			// applied after this refusal. A 2xx answer comes from the stored transaction, and after the
+			// run no earlier send is still in flight, so those settle the outcome either way.
+			txn.outcome = outcomeNotApplied
+		case outcome == outcomeUnknown && txn.outcome != outcomeApplied:
+			// A rejection is not stored, so a resend with a lost answer may have applied it after all
+			txn.outcome, txn.unsettled = outcomeUnknown, true
+		}
+		if resp.status != 0 && !txn.answered {
+			txn.answered = true
+			v.answered = append(v.answered, txn.request.TransactionID)
+		}
+	}
+}
+
+// finish resends transactions with unknown outcomes and checks every user's balance
+func (v *verifier) finish(ctx context.Context, r *Runner) *VerificationReport {
+	v.resolve(ctx, r)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	applied := make(map[uint64][]*trackedTxn)
+	for _, txn := range v.txns {
+		switch txn.outcome {
+		case outcomeApplied:
+			applied[txn.request.UserID] = append(applied[txn.request.UserID], txn)
+			v.report.Applied++
+		case outcomeUnknown:
+			v.violate("transaction %s of user %d has no known outcome", txn.request.TransactionID, txn.request.UserID)
+		}
+	}
+
+	users := make([]uint64, 0, len(v.initial))
+	for userID := range v.initial {
+		users = append(users, userID)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+
+	for _, userID := range users {
+		expected := v.initial[userID]
+		for _, txn := range applied[userID] {
+			expected += txn.change
+		}
+		user := UserVerification{
+			UserID:   userID,
+			Initial:  entity.AmountInCentsToString(v.initial[userID]),
+			Expected: entity.AmountInCentsToString(expected),
+			Applied:  len(applied[userID]),
+		}
+
+		actual, err := r.balance(ctx, userID)
+		if err != nil {
+			v.violate("failed to read the final balance of user %d: %v", userID, err)
+			v.report.Users = append(v.report.Users, user)
+			continue
+		}
+		user.Actual = entity.AmountInCentsToString(actual)
+		if actual != expected {
+			v.violate("user %d has a balance of %s, but the %d applied transactions imply %s",
+				userID, user.Actual, user.Applied, user.Expected)
+		}
+		v.checkChain(userID, v.initial[userID], actual, applied[userID])
+		v.report.Users = append(v.report.Users, user)
+	}
+
+	v.report.Passed = len(v.report.Violations) == 0
+	return &v.report
+}
+
+// resolve resends transactions whose outcome is unknown until they get a definite answer
+// Resending is safe because transaction IDs are idempotent: a transaction that was applied answers with
+// its original result, and one that was not is applied now.
+func (v *verifier) resolve(ctx context.Context, r *Runner) {
+	for attempt := range resolveAttempts {
+		v.mu.Lock()
+		var pending []Request
+		for _, txn := range v.txns {
+			if txn.outcome == outcomeUnknown {
+				pending = append(pending, txn.request)
+			}
+		}
+		if attempt == 0 {
+			v.report.Resolved = len(pending)
+		}
+		v.mu.Unlock()
+
+		if len(pending) == 0 {
+			return
+		}
+		if attempt > 0 && !sleep(ctx, nil, time.Duration(attempt)*500*time.Millisecond) {
+			return
+		}
+		for _, request := range pending {
+			resp, _ := r.post(ctx, request)
+			v.observe(request, sendResolve, []response{resp})
+		}
+	}
+}
+
+// checkChain checks that the result balances of a user's applied transactions form one sequence from
+// the initial balance to the final one
+// Each transaction leads from the balance before it to its result balance. In a sequence every balance
+// is left as often as it is reached, except that the initial one is left once more and the final one
+// reached once more; a lost update shows up as a balance left twice.
+func (v *verifier) checkChain(userID uint64, initial, final int64, txns []*trackedTxn) {
+	left := make(map[int64]int)
+	reached := make(map[int64]int)
+	for _, txn := range txns {
+		if txn.result == nil {
+			v.violate("transaction %s of user %d was applied without a result balance", txn.request.TransactionID, userID)
+			return
+		}
+		result := txn.result.Cents()
+		left[result-txn.change]++
+		reached[result]++
+	}
+	if len(txns) == 0 {
+		return
+	}
+
+	seen := map[int64]bool{initial: true, final: true}
+	for balance := range left {
+		seen[balance] = true
+	}
+	for balance := range reached {
+		seen[balance] = true
+	}
+	balances := make([]int64, 0, len(seen))
+	for balance := range seen {
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i] < balances[j] })
+
+	for _, balance := range balances {
+		want := 0
+		if balance == initial {
+			want++
+		}
+		if balance == final {
+			want--
+		}
+		if left[balance]-reached[balance] != want {
+			v.violate("user %d: result balances do not form one sequence; %s is left %d times and reached %d times",
+				userID, entity.AmountInCentsToString(balance), left[balance], reached[balance])
+		}
+	}
+}
+
+// violate records a violation
+func (v *verifier) violate(format string, args ...any) {
+	v.report.Violations = append(v.report.Violations, fmt.Sprintf(format, args...))
+}
+
+// classify reads what a response says about its transaction
+func classify(resp response) int {
+	switch {
+	case resp.status >= 200 && resp.status < 300 && resp.body.Status == entity.StatusCompleted.String():
+		return outcomeApplied
+	case resp.status >= 200 && resp.status < 300:
+		return outcomeNotApplied
+	case resp.status == http.StatusBadRequest, resp.status == http.StatusNotFound:
+		return outcomeNotApplied
+	default:
+		// Conflicts, overload and lost connections say nothing about whether it was applied
+		return outcomeUnknown
+	}
+}
+
+// describe summarizes a response for a violation
+func describe(resp response) string {
+	if resp.failure != "" {
+		return resp.failure
+	}
+	return fmt.Sprintf("HTTP %d with status %s", resp.status, resp.body.Status)
+}
+
+// balance reads a user's balance from the API in cents
+func (r *Runner) balance(ctx context.Context, userID uint64) (int64, error) {
+	endpoint := fmt.Sprintf("%s/user/%d/balance", r.scenario.BaseURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, errors.New(transportError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var body dto.BalanceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	return body.Balance.Cents(), nil
+}
+
+// sameBalance reports whether two result balances are equal, treating missing ones as equal only to each other
+func sameBalance(a, b *entity.Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
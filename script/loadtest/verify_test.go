@@ -0,0 +1,247 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amirhossein-jamali/balance-processor/internal/domain/entity"
+	"github.com/amirhossein-jamali/balance-processor/internal/infrastructure/adapter/api/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLedger is an API with balances and idempotent transaction IDs that can be told to misbehave
+type fakeLedger struct {
+	mu       sync.Mutex
+	balances map[uint64]int64
+	results  map[string]dto.TransactionResponse
+	requests int
+
+	forgetIDs  bool // Apply every request, even with a known transaction ID
+	loseUpdate bool // Read and write balances outside the lock, so concurrent transactions overwrite each other
+	dropEvery  int  // Apply every Nth request but answer 503
+}
+
+func newFakeLedger(t *testing.T, balances map[uint64]int64) (*fakeLedger, *httptest.Server) {
+	ledger := &fakeLedger{balances: balances, results: map[string]dto.TransactionResponse{}}
+	server := httptest.NewServer(ledger)
+	t.Cleanup(server.Close)
+	return ledger, server
+}
+
+func (l *fakeLedger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	userID, _ := strconv.ParseUint(parts[1], 10, 64)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet {
+		l.mu.Lock()
+		balance := l.balances[userID]
+		l.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(dto.BalanceResponse{UserID: userID, Balance: entity.MoneyFromCents(balance)})
+		return
+	}
+
+	var request dto.TransactionRequest
+	_ = json.NewDecoder(r.Body).Decode(&request)
+	amount := request.Amount.Cents()
+	if request.State == "lose" {
+		amount = -amount
+	}
+
+	l.mu.Lock()
+	l.requests++
+	drop := l.dropEvery > 0 && l.requests%l.dropEvery == 0
+	if result, ok := l.results[request.TransactionID]; ok && !l.forgetIDs {
+		l.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(result)
+		return
+	}
+	balance := l.balances[userID]
+	if l.loseUpdate {
+		l.mu.Unlock()
+		time.Sleep(time.Millisecond)
+		l.mu.Lock()
+	}
+	if balance+amount < 0 {
+		l.mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(dto.ErrorResponse{Code: 4001, Message: "insufficient balance"})
+		return
+	}
+	l.balances[userID] = balance + amount
+	resultBalance := entity.MoneyFromCents(balance + amount)
+	result := dto.TransactionResponse{
+		TransactionID: request.TransactionID,
+		UserID:        userID,
+		Success:       true,
+		Status:        entity.StatusCompleted.String(),
+		ResultBalance: &resultBalance,
+	}
+	l.results[request.TransactionID] = result
+	l.mu.Unlock()
+
+	if drop {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// verifiedScenario sends a mix of wins and losses to two users with heavy duplicates and replays
+func verifiedScenario(url string) *Scenario {
+	scenario := &Scenario{
+		BaseURL:  url,
+		Seed:     5,
+		Workers:  8,
+		Requests: 400,
+		Users:    Users{IDs: []uint64{1, 2}},
+		Transactions: []TransactionMix{
+			{State: "win", AmountRange: [2]string{"1.00", "5.00"}},
+			{State: "lose", AmountRange: [2]string{"1.00", "9.00"}},
+		},
+		Verify: &Verification{DuplicateRate: 0.2, ReplayRate: 0.2},
+	}
+	if err := scenario.Validate(); err != nil {
+		panic(err)
+	}
+	return scenario
+}
+
+func TestRunner_Verify(t *testing.T) {
+	t.Run("A correct API passes, including lost answers", func(t *testing.T) {
+		ledger, server := newFakeLedger(t, map[uint64]int64{1: 2000, 2: 0})
+		ledger.dropEvery = 7
+
+		report, err := NewRunner(verifiedScenario(server.URL)).Run(context.Background())
+		require.NoError(t, err)
+
+		verification := report.Verification
+		require.NotNil(t, verification)
+		assert.True(t, verification.Passed, verification.Violations)
+		assert.Greater(t, verification.Duplicates, 0)
+		assert.Greater(t, verification.Replays, 0)
+		assert.Greater(t, verification.Resolved, 0)
+		require.Len(t, verification.Users, 2)
+		assert.Equal(t, "20.00", verification.Users[0].Initial)
+		assert.Equal(t, entity.AmountInCentsToString(ledger.balances[1]), verification.Users[0].Actual)
+		assert.Equal(t, verification.Users[0].Expected, verification.Users[0].Actual)
+	})
+
+	t.Run("Applying a transaction ID twice fails", func(t *testing.T) {
+		ledger, server := newFakeLedger(t, map[uint64]int64{1: 100000, 2: 100000})
+		ledger.forgetIDs = true
+
+		report, err := NewRunner(verifiedScenario(server.URL)).Run(context.Background())
+		require.NoError(t, err)
+
+		assert.False(t, report.Verification.Passed)
+		assert.Contains(t, strings.Join(report.Verification.Violations, "\n"), "was applied twice")
+	})
+
+	t.Run("Lost updates fail", func(t *testing.T) {
+		ledger, server := newFakeLedger(t, map[uint64]int64{1: 100000, 2: 100000})
+		ledger.loseUpdate = true
+
+		report, err := NewRunner(verifiedScenario(server.URL)).Run(context.Background())
+		require.NoError(t, err)
+
+		violations := strings.Join(report.Verification.Violations, "\n")
+		assert.False(t, report.Verification.Passed)
+		assert.Contains(t, violations, "applied transactions imply")
+		assert.Contains(t, violations, "do not form one sequence")
+	})
+
+	t.Run("Unreadable starting balances stop the run before it starts", func(t *testing.T) {
+		// The fake API only knows transactions and answers the balance request with 400
+		api, server := newFakeAPI(t, 0)
+
+		_, err := NewRunner(verifiedScenario(server.URL)).Run(context.Background())
+		assert.ErrorContains(t, err, "starting balance of user 1: HTTP 400")
+		assert.Zero(t, api.received())
+	})
+}
+
+func TestVerifier_Observe(t *testing.T) {
+	request := Request{UserID: 1, State: "lose", Amount: entity.MoneyFromCents(824), TransactionID: "t1"}
+	lost := response{failure: "connection reset"}
+	refused := response{status: http.StatusBadRequest}
+	completed := func(cents int64) response {
+		balance := entity.MoneyFromCents(cents)
+		return response{status: http.StatusOK, body: dto.TransactionResponse{
+			Status:        entity.StatusCompleted.String(),
+			ResultBalance: &balance,
+		}}
+	}
+	newObserved := func() *verifier {
+		v := newVerifier(Verification{}, 1)
+		_, kind := v.choose(request)
+		require.Equal(t, sendOriginal, kind)
+		return v
+	}
+
+	t.Run("A refusal with no lost answers settles the outcome", func(t *testing.T) {
+		v := newObserved()
+		v.observe(request, sendOriginal, []response{refused})
+		assert.Equal(t, outcomeNotApplied, v.txns["t1"].outcome)
+	})
+
+	t.Run("A refusal observed after a lost answer leaves the outcome unknown", func(t *testing.T) {
+		v := newObserved()
+		// The lost send may have been applied after the duplicate was refused
+		v.observe(request, sendOriginal, []response{lost})
+		v.observe(request, sendDuplicate, []response{refused})
+		assert.Equal(t, outcomeUnknown, v.txns["t1"].outcome)
+
+		v.observe(request, sendResolve, []response{completed(56)})
+		assert.Equal(t, outcomeApplied, v.txns["t1"].outcome)
+		assert.Equal(t, "0.56", v.txns["t1"].result.String())
+	})
+
+	t.Run("Replays answered in either order leave the outcome unknown", func(t *testing.T) {
+		for _, responses := range [][]response{{lost, refused}, {refused, lost}} {
+			v := newObserved()
+			v.observe(request, sendReplay, responses)
+			assert.Equal(t, outcomeUnknown, v.txns["t1"].outcome)
+		}
+	})
+
+	t.Run("A refusal of a resend after the run settles the outcome", func(t *testing.T) {
+		v := newObserved()
+		v.observe(request, sendOriginal, []response{lost})
+		v.observe(request, sendResolve, []response{refused})
+		assert.Equal(t, outcomeNotApplied, v.txns["t1"].outcome)
+	})
+}
+
+func TestVerifier_CheckChain(t *testing.T) {
+	applied := func(change int64, result string) *trackedTxn {
+		balance, err := entity.ParseMoney(result)
+		require.NoError(t, err)
+		return &trackedTxn{request: Request{TransactionID: result}, change: change, outcome: outcomeApplied, result: &balance}
+	}
+
+	t.Run("A sequence that returns to an earlier balance", func(t *testing.T) {
+		v := newVerifier(DefaultVerification, 1)
+		// 100.00 -> 110.00 -> 100.00 -> 105.00, in any order
+		v.checkChain(1, 10000, 10500, []*trackedTxn{applied(500, "105.00"), applied(1000, "110.00"), applied(-1000, "100.00")})
+		assert.Empty(t, v.report.Violations)
+	})
+
+	t.Run("Two transactions applied to the same balance", func(t *testing.T) {
+		v := newVerifier(DefaultVerification, 1)
+		// Both started from 100.00 and the second overwrote the first
+		v.checkChain(1, 10000, 9500, []*trackedTxn{applied(1000, "110.00"), applied(-500, "95.00")})
+		assert.Equal(t, []string{
+			"user 1: result balances do not form one sequence; 100.00 is left 2 times and reached 0 times",
+			"user 1: result balances do not form one sequence; 110.00 is left 0 times and reached 1 times",
+		}, v.report.Violations)
+	})
+}